@@ -21,8 +21,18 @@ import (
 	handlersV1 "github.com/h44z/wg-portal/internal/app/api/v1/handlers"
 	"github.com/h44z/wg-portal/internal/app/audit"
 	"github.com/h44z/wg-portal/internal/app/auth"
+	"github.com/h44z/wg-portal/internal/app/backup"
+	"github.com/h44z/wg-portal/internal/app/bulk"
 	"github.com/h44z/wg-portal/internal/app/configfile"
+	"github.com/h44z/wg-portal/internal/app/declarative"
+	"github.com/h44z/wg-portal/internal/app/dns"
+	"github.com/h44z/wg-portal/internal/app/dnsupdate"
+	"github.com/h44z/wg-portal/internal/app/download"
+	"github.com/h44z/wg-portal/internal/app/firewall"
+	"github.com/h44z/wg-portal/internal/app/inactivity"
 	"github.com/h44z/wg-portal/internal/app/mail"
+	"github.com/h44z/wg-portal/internal/app/mesh"
+	"github.com/h44z/wg-portal/internal/app/peerrequest"
 	"github.com/h44z/wg-portal/internal/app/route"
 	"github.com/h44z/wg-portal/internal/app/users"
 	"github.com/h44z/wg-portal/internal/app/webhooks"
@@ -71,6 +81,20 @@ func main() {
 		internal.AssertNoError(err)
 	}
 
+	backupManager, err := backup.NewBackupManager(database)
+	internal.AssertNoError(err)
+
+	shouldExit, err = app.HandleBackupProgramArgs(ctx, backupManager)
+	switch {
+	case shouldExit && err == nil:
+		return
+	case shouldExit:
+		slog.Error("Failed to process backup program args", "error", err)
+		os.Exit(1)
+	default:
+		internal.AssertNoError(err)
+	}
+
 	queueSize := 100
 	eventBus := evbus.New(queueSize)
 
@@ -99,16 +123,49 @@ func main() {
 	internal.AssertNoError(err)
 	statisticsCollector.StartBackgroundJobs(ctx)
 
+	driftDetector, err := wireguard.NewDriftDetector(cfg, database, wireGuard, metricsServer, wireGuardManager)
+	internal.AssertNoError(err)
+	driftDetector.StartBackgroundJobs(ctx)
+
 	cfgFileManager, err := configfile.NewConfigFileManager(cfg, eventBus, database, database, cfgFileSystem)
 	internal.AssertNoError(err)
 
-	mailManager, err := mail.NewMailManager(cfg, mailer, cfgFileManager, database, database)
+	mailManager, err := mail.NewMailManager(cfg, eventBus, mailer, cfgFileManager, database, database)
+	internal.AssertNoError(err)
+
+	inactivityManager, err := inactivity.NewInactivityManager(cfg, database, wireGuardManager, mailManager)
+	internal.AssertNoError(err)
+	inactivityManager.StartBackgroundJobs(ctx)
+
+	downloadManager, err := download.NewDownloadManager(cfg, eventBus, database, cfgFileManager)
+	internal.AssertNoError(err)
+
+	peerRequestManager, err := peerrequest.NewPeerRequestManager(cfg, eventBus, database, wireGuardManager,
+		mailManager)
 	internal.AssertNoError(err)
 
 	routeManager, err := route.NewRouteManager(cfg, eventBus, database, wireGuard)
 	internal.AssertNoError(err)
 	routeManager.StartBackgroundJobs(ctx)
 
+	firewallManager, err := firewall.NewFirewallManager(cfg, eventBus, database, wireGuard)
+	internal.AssertNoError(err)
+	firewallManager.StartBackgroundJobs(ctx)
+
+	meshManager, err := mesh.NewMeshManager(cfg, eventBus, database, wireGuard)
+	internal.AssertNoError(err)
+	meshManager.StartBackgroundJobs(ctx)
+
+	dnsManager, err := dns.NewDnsManager(cfg, eventBus, database)
+	internal.AssertNoError(err)
+	dnsManager.StartBackgroundJobs(ctx)
+
+	dnsUpdateClient, err := adapters.NewDnsUpdateClient(cfg.DnsUpdate)
+	internal.AssertNoError(err)
+	dnsUpdateManager, err := dnsupdate.NewDnsUpdateManager(cfg, eventBus, database, dnsUpdateClient)
+	internal.AssertNoError(err)
+	dnsUpdateManager.StartBackgroundJobs(ctx)
+
 	webhookManager, err := webhooks.NewManager(cfg, eventBus)
 	internal.AssertNoError(err)
 	webhookManager.StartBackgroundJobs(ctx)
@@ -116,6 +173,35 @@ func main() {
 	err = app.Initialize(cfg, wireGuardManager, userManager)
 	internal.AssertNoError(err)
 
+	bulkManager, err := bulk.NewBulkManager(userManager, wireGuardManager)
+	internal.AssertNoError(err)
+
+	shouldExit, err = app.HandleBulkProgramArgs(ctx, bulkManager)
+	switch {
+	case shouldExit && err == nil:
+		return
+	case shouldExit:
+		slog.Error("Failed to process bulk program args", "error", err)
+		os.Exit(1)
+	default:
+		internal.AssertNoError(err)
+	}
+
+	declarativeManager, err := declarative.NewDeclarativeManager(cfg, userManager, wireGuardManager)
+	internal.AssertNoError(err)
+
+	shouldExit, err = app.HandleDeclarativeProgramArgs(ctx, declarativeManager)
+	switch {
+	case shouldExit && err == nil:
+		return
+	case shouldExit:
+		slog.Error("Failed to process declarative state program args", "error", err)
+		os.Exit(1)
+	default:
+		internal.AssertNoError(err)
+	}
+	declarativeManager.StartBackgroundJobs(ctx)
+
 	validatorManager := validator.New()
 
 	// region API v0 (SPA frontend)
@@ -130,6 +216,9 @@ func main() {
 	apiV0EndpointAuth := handlersV0.NewAuthEndpoint(cfg, apiV0Auth, apiV0Session, validatorManager, authenticator,
 		webAuthn)
 	apiV0EndpointAudit := handlersV0.NewAuditEndpoint(cfg, apiV0Auth, auditManager)
+	apiV0EndpointBulk := handlersV0.NewBulkEndpoint(cfg, apiV0Auth, bulkManager)
+	apiV0EndpointInactivity := handlersV0.NewInactivityEndpoint(cfg, apiV0Auth, inactivityManager)
+	apiV0EndpointPeerRequests := handlersV0.NewPeerRequestEndpoint(cfg, apiV0Auth, peerRequestManager)
 	apiV0EndpointUsers := handlersV0.NewUserEndpoint(cfg, apiV0Auth, validatorManager, apiV0BackendUsers)
 	apiV0EndpointInterfaces := handlersV0.NewInterfaceEndpoint(cfg, apiV0Auth, validatorManager, apiV0BackendInterfaces)
 	apiV0EndpointPeers := handlersV0.NewPeerEndpoint(cfg, apiV0Auth, validatorManager, apiV0BackendPeers)
@@ -141,6 +230,9 @@ func main() {
 	apiFrontend := handlersV0.NewRestApi(apiV0Session,
 		apiV0EndpointAuth,
 		apiV0EndpointAudit,
+		apiV0EndpointBulk,
+		apiV0EndpointInactivity,
+		apiV0EndpointPeerRequests,
 		apiV0EndpointUsers,
 		apiV0EndpointInterfaces,
 		apiV0EndpointPeers,
@@ -166,6 +258,15 @@ func main() {
 	apiV1EndpointProvisioning := handlersV1.NewProvisioningEndpoint(apiV1Auth, validatorManager,
 		apiV1BackendProvisioning)
 	apiV1EndpointMetrics := handlersV1.NewMetricsEndpoint(apiV1Auth, validatorManager, apiV1BackendMetrics)
+	apiV1EndpointBulk := handlersV1.NewBulkEndpoint(apiV1Auth, bulkManager)
+	apiV1EndpointInactivity := handlersV1.NewInactivityEndpoint(apiV1Auth, inactivityManager)
+	apiV1EndpointPeerRequests := handlersV1.NewPeerRequestEndpoint(apiV1Auth, validatorManager, peerRequestManager)
+	apiV1EndpointDownloads := handlersV1.NewDownloadEndpoint(cfg, apiV1Auth, validatorManager, downloadManager)
+	apiV1EndpointRevisions := handlersV1.NewRevisionEndpoint(apiV1Auth, wireGuardManager)
+	apiV1EndpointMeshes := handlersV1.NewMeshEndpoint(apiV1Auth, validatorManager, meshManager)
+	apiV1EndpointDrift := handlersV1.NewDriftEndpoint(apiV1Auth, driftDetector)
+	apiV1EndpointDeclarative := handlersV1.NewDeclarativeEndpoint(apiV1Auth, declarativeManager)
+	apiV1EndpointBackup := handlersV1.NewBackupEndpoint(apiV1Auth, backupManager)
 
 	apiV1 := handlersV1.NewRestApi(
 		apiV1EndpointUsers,
@@ -173,6 +274,15 @@ func main() {
 		apiV1EndpointInterfaces,
 		apiV1EndpointProvisioning,
 		apiV1EndpointMetrics,
+		apiV1EndpointBulk,
+		apiV1EndpointInactivity,
+		apiV1EndpointPeerRequests,
+		apiV1EndpointDownloads,
+		apiV1EndpointRevisions,
+		apiV1EndpointMeshes,
+		apiV1EndpointDrift,
+		apiV1EndpointDeclarative,
+		apiV1EndpointBackup,
 	)
 
 	// endregion API v1 (User REST API)
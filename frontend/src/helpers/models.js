@@ -73,6 +73,7 @@ export function freshPeer() {
       Overridable: true,
     },
     ExtraAllowedIPs: [],
+    DelegatedPrefix: "",
     PresharedKey: "",
     PersistentKeepalive: {
       Value: 0,
@@ -69,6 +69,15 @@ export const peerStore = defineStore('peers', {
     ConfigQrUrl: (state) => {
       return (id) => state.peers.find((p) => p.Identifier === id) ? apiWrapper.url(`${baseUrl}/config-qr/${base64_url_encode(id)}`) : ''
     },
+    ConfigBundleUrl: () => {
+      return (selection, style = 'wgquick') => {
+        const params = new URLSearchParams({ style: style })
+        if (selection.user) params.append('user', base64_url_encode(selection.user))
+        if (selection.iface) params.append('iface', base64_url_encode(selection.iface))
+        for (const id of selection.peers || []) params.append('peer', base64_url_encode(id))
+        return apiWrapper.url(`${baseUrl}/config-bundle?${params.toString()}`)
+      }
+    },
     isFetching: (state) => state.fetching,
     hasNextPage: (state) => state.pageOffset < (state.FilteredCount - state.pageSize),
     hasPrevPage: (state) => state.pageOffset > 0,
@@ -231,6 +231,10 @@ func (r *SqlRepo) migrate() error {
 	slog.Debug("running migration: peer status", "result", r.db.AutoMigrate(&domain.PeerStatus{}))
 	slog.Debug("running migration: interface status", "result", r.db.AutoMigrate(&domain.InterfaceStatus{}))
 	slog.Debug("running migration: audit data", "result", r.db.AutoMigrate(&domain.AuditEntry{}))
+	slog.Debug("running migration: peer requests", "result", r.db.AutoMigrate(&domain.PeerRequest{}))
+	slog.Debug("running migration: download tokens", "result", r.db.AutoMigrate(&domain.DownloadToken{}))
+	slog.Debug("running migration: config revisions", "result", r.db.AutoMigrate(&domain.ConfigRevision{}))
+	slog.Debug("running migration: meshes", "result", r.db.AutoMigrate(&domain.Mesh{}))
 
 	var existingSysStat SysStat
 	var err error
@@ -474,6 +478,15 @@ func (r *SqlRepo) SaveInterface(
 			return err
 		}
 
+		snapshot, err := domain.InterfaceSnapshot(in)
+		if err != nil {
+			return err
+		}
+		err = r.addRevision(userInfo, tx, domain.RevisionObjectInterface, string(in.Identifier), snapshot)
+		if err != nil {
+			return err
+		}
+
 		// return nil will commit the whole transaction
 		return nil
 	})
@@ -530,7 +543,7 @@ func (r *SqlRepo) upsertInterface(ui *domain.ContextUserInfo, tx *gorm.DB, in *d
 // DeleteInterface deletes the interface with the given id.
 func (r *SqlRepo) DeleteInterface(ctx context.Context, id domain.InterfaceIdentifier) error {
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		err := tx.Where("interface_identifier = ?", id).Delete(&domain.Peer{}).Error
+		err := tx.Unscoped().Where("interface_identifier = ?", id).Delete(&domain.Peer{}).Error
 		if err != nil {
 			return err
 		}
@@ -545,6 +558,12 @@ func (r *SqlRepo) DeleteInterface(ctx context.Context, id domain.InterfaceIdenti
 			return err
 		}
 
+		err = tx.Where("object_type = ? AND object_id = ?", domain.RevisionObjectInterface, id).
+			Delete(&domain.ConfigRevision{}).Error
+		if err != nil {
+			return err
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -684,6 +703,15 @@ func (r *SqlRepo) SavePeer(
 			return err
 		}
 
+		snapshot, err := domain.PeerSnapshot(peer)
+		if err != nil {
+			return err
+		}
+		err = r.addRevision(userInfo, tx, domain.RevisionObjectPeer, string(peer.Identifier), snapshot)
+		if err != nil {
+			return err
+		}
+
 		// return nil will commit the whole transaction
 		return nil
 	})
@@ -711,6 +739,10 @@ func (r *SqlRepo) getOrCreatePeer(ui *domain.ContextUserInfo, tx *gorm.DB, id do
 		Identifier: id,
 	}
 
+	if err := r.checkRecycleBin(tx, &domain.Peer{}, string(id)); err != nil {
+		return nil, err
+	}
+
 	err := tx.Preload("Addresses").Attrs(interfaceDefaults).FirstOrCreate(&peer, id).Error
 	if err != nil {
 		return nil, err
@@ -736,7 +768,7 @@ func (r *SqlRepo) upsertPeer(ui *domain.ContextUserInfo, tx *gorm.DB, peer *doma
 	return nil
 }
 
-// DeletePeer deletes the peer with the given id.
+// DeletePeer permanently deletes the peer with the given id, even if it is in the recycle bin.
 func (r *SqlRepo) DeletePeer(ctx context.Context, id domain.PeerIdentifier) error {
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		err := tx.Delete(&domain.PeerStatus{PeerId: id}).Error
@@ -744,11 +776,43 @@ func (r *SqlRepo) DeletePeer(ctx context.Context, id domain.PeerIdentifier) erro
 			return err
 		}
 
-		err = tx.Select(clause.Associations).Delete(&domain.Peer{Identifier: id}).Error
+		err = tx.Unscoped().Select(clause.Associations).Delete(&domain.Peer{Identifier: id}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("object_type = ? AND object_id = ?", domain.RevisionObjectPeer, id).
+			Delete(&domain.ConfigRevision{}).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SoftDeletePeer moves the peer with the given id to the recycle bin. The peer is hidden from all regular queries,
+// its addresses stay assigned, so they can not be used by other peers until the peer is purged.
+func (r *SqlRepo) SoftDeletePeer(ctx context.Context, id domain.PeerIdentifier) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Delete(&domain.PeerStatus{PeerId: id}).Error
 		if err != nil {
 			return err
 		}
 
+		result := tx.Delete(&domain.Peer{Identifier: id})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNotFound
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -758,6 +822,45 @@ func (r *SqlRepo) DeletePeer(ctx context.Context, id domain.PeerIdentifier) erro
 	return nil
 }
 
+// RestorePeer moves the peer with the given id out of the recycle bin.
+// If the peer is not in the recycle bin, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) RestorePeer(ctx context.Context, id domain.PeerIdentifier) error {
+	return r.restore(ctx, &domain.Peer{}, string(id))
+}
+
+// GetDeletedPeer returns the peer with the given id from the recycle bin.
+// If the peer is not in the recycle bin, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetDeletedPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	var peer domain.Peer
+
+	err := r.db.WithContext(ctx).Unscoped().Preload("Addresses").
+		Where("deleted_at IS NOT NULL").
+		First(&peer, id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &peer, nil
+}
+
+// GetDeletedPeers returns all peers in the recycle bin, the most recently deleted peers first.
+func (r *SqlRepo) GetDeletedPeers(ctx context.Context) ([]domain.Peer, error) {
+	var peers []domain.Peer
+
+	err := r.db.WithContext(ctx).Unscoped().Preload("Addresses").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at desc").
+		Find(&peers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
+
 // GetPeerIps returns a map of peer identifiers to their respective IP addresses.
 func (r *SqlRepo) GetPeerIps(ctx context.Context) (map[domain.PeerIdentifier][]domain.Cidr, error) {
 	var ips []struct {
@@ -967,7 +1070,7 @@ func (r *SqlRepo) SaveUser(
 	return nil
 }
 
-// DeleteUser deletes the user with the given id.
+// DeleteUser permanently deletes the user with the given id, even if it is in the recycle bin.
 func (r *SqlRepo) DeleteUser(ctx context.Context, id domain.UserIdentifier) error {
 	err := r.db.WithContext(ctx).Unscoped().Select(clause.Associations).Delete(&domain.User{Identifier: id}).Error
 	if err != nil {
@@ -977,12 +1080,53 @@ func (r *SqlRepo) DeleteUser(ctx context.Context, id domain.UserIdentifier) erro
 	return nil
 }
 
+// SoftDeleteUser moves the user with the given id to the recycle bin. Authentications and WebAuthn credentials
+// of the user are kept, so that they are available again once the user is restored.
+func (r *SqlRepo) SoftDeleteUser(ctx context.Context, id domain.UserIdentifier) error {
+	result := r.db.WithContext(ctx).Delete(&domain.User{Identifier: id})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// RestoreUser moves the user with the given id out of the recycle bin.
+// If the user is not in the recycle bin, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) RestoreUser(ctx context.Context, id domain.UserIdentifier) error {
+	return r.restore(ctx, &domain.User{}, string(id))
+}
+
+// GetDeletedUsers returns all users in the recycle bin, the most recently deleted users first.
+func (r *SqlRepo) GetDeletedUsers(ctx context.Context) ([]domain.User, error) {
+	var users []domain.User
+
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at desc").
+		Preload("WebAuthnCredentialList").
+		Preload("Authentications").
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
 func (r *SqlRepo) getOrCreateUser(ui *domain.ContextUserInfo, tx *gorm.DB, id domain.UserIdentifier) (
 	*domain.User,
 	error,
 ) {
 	var user domain.User
 
+	if err := r.checkRecycleBin(tx, &user, string(id)); err != nil {
+		return nil, err
+	}
+
 	result := tx.Model(&user).Preload("WebAuthnCredentialList").Preload("Authentications").Find(&user, id)
 	if result.Error != nil {
 		if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -1037,6 +1181,131 @@ func (r *SqlRepo) upsertUser(ui *domain.ContextUserInfo, tx *gorm.DB, user *doma
 
 // endregion users
 
+// region revisions
+
+// GetConfigRevisions returns all revisions of the given object, the latest revision first.
+func (r *SqlRepo) GetConfigRevisions(
+	ctx context.Context,
+	objectType domain.RevisionObjectType,
+	id string,
+) ([]domain.ConfigRevision, error) {
+	var revisions []domain.ConfigRevision
+
+	err := r.db.WithContext(ctx).
+		Where("object_type = ? AND object_id = ?", objectType, id).
+		Order("version desc").
+		Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// GetConfigRevision returns the revision of the given object with the given version.
+// If no revision is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetConfigRevision(
+	ctx context.Context,
+	objectType domain.RevisionObjectType,
+	id string,
+	version uint64,
+) (*domain.ConfigRevision, error) {
+	var revision domain.ConfigRevision
+
+	err := r.db.WithContext(ctx).
+		Where("object_type = ? AND object_id = ? AND version = ?", objectType, id, version).
+		First(&revision).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// addRevision stores the snapshot as a new revision of the object, unless it equals the latest revision.
+// Revisions that exceed the configured limit are removed, the oldest first.
+func (r *SqlRepo) addRevision(
+	ui *domain.ContextUserInfo,
+	tx *gorm.DB,
+	objectType domain.RevisionObjectType,
+	id string,
+	snapshot string,
+) error {
+	var latest domain.ConfigRevision
+	err := tx.Where("object_type = ? AND object_id = ?", objectType, id).
+		Order("version desc").
+		Limit(1).
+		Find(&latest).Error
+	if err != nil {
+		return fmt.Errorf("failed to load latest revision: %w", err)
+	}
+	if latest.Version > 0 && latest.Snapshot == snapshot {
+		return nil // nothing changed
+	}
+
+	revision := domain.ConfigRevision{
+		ObjectType: objectType,
+		ObjectId:   id,
+		Version:    latest.Version + 1,
+		CreatedAt:  time.Now(),
+		CreatedBy:  ui.UserId(),
+		Snapshot:   snapshot,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return fmt.Errorf("failed to store revision: %w", err)
+	}
+
+	limit := uint64(max(r.cfg.Advanced.ConfigRevisionLimit, 0))
+	if limit > 0 && revision.Version > limit {
+		err = tx.Where("object_type = ? AND object_id = ? AND version <= ?", objectType, id,
+			revision.Version-limit).Delete(&domain.ConfigRevision{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to remove old revisions: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// endregion revisions
+
+// region recycle-bin
+
+// checkRecycleBin returns an error domain.ErrDuplicateEntry if a record with the given identifier is in the recycle
+// bin. Such records would otherwise be hidden from the lookup and creating a new record would fail.
+func (r *SqlRepo) checkRecycleBin(tx *gorm.DB, model any, id string) error {
+	var count int64
+	err := tx.Unscoped().Model(model).Where("identifier = ? AND deleted_at IS NOT NULL", id).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%s is in the recycle bin, restore or purge it first: %w", id, domain.ErrDuplicateEntry)
+	}
+
+	return nil
+}
+
+// restore clears the deletion time of the record with the given identifier.
+func (r *SqlRepo) restore(ctx context.Context, model any, id string) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(model).
+		Where("identifier = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// endregion recycle-bin
+
 // region statistics
 
 // UpdateInterfaceStatus updates the interface status with the given id.
@@ -1197,3 +1466,408 @@ func (r *SqlRepo) GetAllAuditEntries(ctx context.Context) ([]domain.AuditEntry,
 }
 
 // endregion audit
+
+// region peer-requests
+
+// GetPeerRequest returns the peer request with the given id.
+// If no request is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetPeerRequest(ctx context.Context, id domain.PeerRequestIdentifier) (*domain.PeerRequest, error) {
+	var request domain.PeerRequest
+
+	err := r.db.WithContext(ctx).First(&request, "identifier = ?", id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// GetAllPeerRequests returns all peer requests, the newest requests first.
+func (r *SqlRepo) GetAllPeerRequests(ctx context.Context) ([]domain.PeerRequest, error) {
+	var requests []domain.PeerRequest
+
+	err := r.db.WithContext(ctx).Order("created_at desc").Find(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// GetUserPeerRequests returns all peer requests of the given user, the newest requests first.
+func (r *SqlRepo) GetUserPeerRequests(ctx context.Context, id domain.UserIdentifier) ([]domain.PeerRequest, error) {
+	var requests []domain.PeerRequest
+
+	err := r.db.WithContext(ctx).Where("user_identifier = ?", id).Order("created_at desc").Find(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// SavePeerRequest creates or updates the given peer request.
+func (r *SqlRepo) SavePeerRequest(ctx context.Context, request *domain.PeerRequest) error {
+	ui := domain.GetUserInfo(ctx)
+	now := time.Now()
+	if request.CreatedAt.IsZero() {
+		request.CreatedAt = now
+		request.CreatedBy = ui.UserId()
+	}
+	request.UpdatedAt = now
+	request.UpdatedBy = ui.UserId()
+
+	err := r.db.WithContext(ctx).Save(request).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion peer-requests
+
+// region download-tokens
+
+// GetDownloadToken returns the download token with the given id.
+// If no token is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetDownloadToken(ctx context.Context, id domain.DownloadTokenIdentifier) (
+	*domain.DownloadToken,
+	error,
+) {
+	var token domain.DownloadToken
+
+	err := r.db.WithContext(ctx).First(&token, "identifier = ?", id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetAllDownloadTokens returns all download tokens, the newest tokens first.
+func (r *SqlRepo) GetAllDownloadTokens(ctx context.Context) ([]domain.DownloadToken, error) {
+	var tokens []domain.DownloadToken
+
+	err := r.db.WithContext(ctx).Order("created_at desc").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// GetPeerDownloadTokens returns all download tokens of the given peer, the newest tokens first.
+func (r *SqlRepo) GetPeerDownloadTokens(ctx context.Context, id domain.PeerIdentifier) (
+	[]domain.DownloadToken,
+	error,
+) {
+	var tokens []domain.DownloadToken
+
+	err := r.db.WithContext(ctx).Where("peer_identifier = ?", id).Order("created_at desc").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// SaveDownloadToken updates the download token with the given id in a single transaction.
+// If no token exists, the update function receives an empty token with the given identifier.
+func (r *SqlRepo) SaveDownloadToken(
+	ctx context.Context,
+	id domain.DownloadTokenIdentifier,
+	updateFunc func(in *domain.DownloadToken) (*domain.DownloadToken, error),
+) error {
+	ui := domain.GetUserInfo(ctx)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var token domain.DownloadToken
+		err := tx.First(&token, "identifier = ?", id).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			token = domain.DownloadToken{
+				BaseModel: domain.BaseModel{
+					CreatedBy: ui.UserId(),
+					CreatedAt: time.Now(),
+				},
+				Identifier: id,
+			}
+		case err != nil:
+			return err
+		}
+
+		updatedToken, err := updateFunc(&token)
+		if err != nil {
+			return err // return any error will roll back
+		}
+
+		updatedToken.UpdatedBy = ui.UserId()
+		updatedToken.UpdatedAt = time.Now()
+
+		return tx.Save(updatedToken).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion download-tokens
+
+// region meshes
+
+// GetMesh returns the mesh with the given id.
+// If no mesh is found, an error domain.ErrNotFound is returned.
+func (r *SqlRepo) GetMesh(ctx context.Context, id domain.MeshIdentifier) (*domain.Mesh, error) {
+	var mesh domain.Mesh
+
+	err := r.db.WithContext(ctx).First(&mesh, "identifier = ?", id).Error
+	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &mesh, nil
+}
+
+// GetAllMeshes returns all meshes, ordered by their identifier.
+func (r *SqlRepo) GetAllMeshes(ctx context.Context) ([]domain.Mesh, error) {
+	var meshes []domain.Mesh
+
+	err := r.db.WithContext(ctx).Order("identifier").Find(&meshes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return meshes, nil
+}
+
+// SaveMesh updates the mesh with the given id in a single transaction.
+// If no mesh exists, the update function receives an empty mesh with the given identifier.
+func (r *SqlRepo) SaveMesh(
+	ctx context.Context,
+	id domain.MeshIdentifier,
+	updateFunc func(in *domain.Mesh) (*domain.Mesh, error),
+) error {
+	ui := domain.GetUserInfo(ctx)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var mesh domain.Mesh
+		err := tx.First(&mesh, "identifier = ?", id).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			mesh = domain.Mesh{
+				BaseModel: domain.BaseModel{
+					CreatedBy: ui.UserId(),
+					CreatedAt: time.Now(),
+				},
+				Identifier: id,
+			}
+		case err != nil:
+			return err
+		}
+
+		updatedMesh, err := updateFunc(&mesh)
+		if err != nil {
+			return err // return any error will roll back
+		}
+
+		updatedMesh.UpdatedBy = ui.UserId()
+		updatedMesh.UpdatedAt = time.Now()
+
+		return tx.Save(updatedMesh).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteMesh deletes the mesh with the given id.
+func (r *SqlRepo) DeleteMesh(ctx context.Context, id domain.MeshIdentifier) error {
+	err := r.db.WithContext(ctx).Delete(&domain.Mesh{}, "identifier = ?", id).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// endregion meshes
+
+// region backup
+
+// GetSchemaVersion returns the latest applied database schema version.
+func (r *SqlRepo) GetSchemaVersion(ctx context.Context) (uint64, error) {
+	var sysStat SysStat
+	err := r.db.WithContext(ctx).Order("schema_version desc").First(&sysStat).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load schema version: %w", err)
+	}
+
+	return sysStat.SchemaVersion, nil
+}
+
+// GetBackupData loads all data that is part of a backup, including soft-deleted users and peers.
+// Download tokens are short-lived and therefore not part of a backup.
+func (r *SqlRepo) GetBackupData(ctx context.Context) (*domain.Backup, error) {
+	schemaVersion, err := r.GetSchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &domain.Backup{
+		SchemaVersion: schemaVersion,
+		UserPasswords: make(map[domain.UserIdentifier]string),
+	}
+
+	db := r.db.WithContext(ctx).Unscoped().Session(&gorm.Session{}) // safe to reuse for all queries
+	loaders := []struct {
+		name string
+		load func() error
+	}{
+		{"users", func() error {
+			return db.Preload("WebAuthnCredentialList").Preload("Authentications").Find(&backup.Users).Error
+		}},
+		{"interfaces", func() error { return db.Preload("Addresses").Find(&backup.Interfaces).Error }},
+		{"peers", func() error { return db.Preload("Addresses").Find(&backup.Peers).Error }},
+		{"interface statuses", func() error { return db.Find(&backup.InterfaceStatuses).Error }},
+		{"peer statuses", func() error { return db.Find(&backup.PeerStatuses).Error }},
+		{"meshes", func() error { return db.Find(&backup.Meshes).Error }},
+		{"peer requests", func() error { return db.Find(&backup.PeerRequests).Error }},
+		{"config revisions", func() error { return db.Order("id").Find(&backup.ConfigRevisions).Error }},
+		{"audit entries", func() error { return db.Order("id").Find(&backup.AuditEntries).Error }},
+	}
+	for _, loader := range loaders {
+		if err := loader.load(); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", loader.name, err)
+		}
+	}
+
+	for _, user := range backup.Users {
+		if user.Password != "" {
+			backup.UserPasswords[user.Identifier] = string(user.Password)
+		}
+	}
+
+	return backup, nil
+}
+
+// RestoreBackupData replaces all data with the contents of the backup. The database is cleared and filled
+// in a single transaction, a failed restore leaves the existing data untouched.
+func (r *SqlRepo) RestoreBackupData(ctx context.Context, backup *domain.Backup) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := clearBackupTables(tx); err != nil {
+			return fmt.Errorf("failed to clear database: %w", err)
+		}
+
+		for i := range backup.Users {
+			user := backup.Users[i]
+			user.Password = domain.PrivateString(backup.UserPasswords[user.Identifier])
+			if err := tx.Create(&user).Error; err != nil {
+				return fmt.Errorf("failed to restore user %s: %w", user.Identifier, err)
+			}
+		}
+		for i := range backup.Interfaces {
+			if err := tx.Create(&backup.Interfaces[i]).Error; err != nil {
+				return fmt.Errorf("failed to restore interface %s: %w", backup.Interfaces[i].Identifier, err)
+			}
+		}
+		for i := range backup.Peers {
+			peer := backup.Peers[i]
+			if err := tx.Create(&peer).Error; err != nil {
+				return fmt.Errorf("failed to restore peer %s: %w", peer.Identifier, err)
+			}
+			err := tx.Model(&peer).Association("Addresses").Replace(peer.Interface.Addresses)
+			if err != nil {
+				return fmt.Errorf("failed to restore addresses of peer %s: %w", peer.Identifier, err)
+			}
+		}
+
+		// identifiers of auto-increment tables are assigned by the database, explicit values are not portable
+		for i := range backup.ConfigRevisions {
+			backup.ConfigRevisions[i].Id = 0
+		}
+		for i := range backup.AuditEntries {
+			backup.AuditEntries[i].UniqueId = 0
+		}
+
+		if err := createInBatches(tx, "interface statuses", backup.InterfaceStatuses); err != nil {
+			return err
+		}
+		if err := createInBatches(tx, "peer statuses", backup.PeerStatuses); err != nil {
+			return err
+		}
+		if err := createInBatches(tx, "meshes", backup.Meshes); err != nil {
+			return err
+		}
+		if err := createInBatches(tx, "peer requests", backup.PeerRequests); err != nil {
+			return err
+		}
+		if err := createInBatches(tx, "config revisions", backup.ConfigRevisions); err != nil {
+			return err
+		}
+		if err := createInBatches(tx, "audit entries", backup.AuditEntries); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// clearBackupTables deletes all rows of the tables that are part of a backup. Download tokens are deleted
+// as well, as they reference peers.
+func clearBackupTables(tx *gorm.DB) error {
+	for _, table := range []string{"peer_addresses", "interface_addresses"} {
+		if err := tx.Exec("DELETE FROM " + table).Error; err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	models := []any{
+		&domain.DownloadToken{},
+		&domain.AuditEntry{},
+		&domain.ConfigRevision{},
+		&domain.PeerRequest{},
+		&domain.Mesh{},
+		&domain.PeerStatus{},
+		&domain.InterfaceStatus{},
+		&domain.Peer{},
+		&domain.Interface{},
+		&domain.UserWebauthnCredential{},
+		&domain.UserAuthentication{},
+		&domain.User{},
+		&domain.Cidr{},
+	}
+	for _, model := range models {
+		err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(model).Error
+		if err != nil {
+			return fmt.Errorf("failed to clear %T: %w", model, err)
+		}
+	}
+
+	return nil
+}
+
+func createInBatches[T any](tx *gorm.DB, name string, records []T) error {
+	if len(records) == 0 {
+		return nil // gorm refuses to create empty slices
+	}
+
+	if err := tx.CreateInBatches(records, 100).Error; err != nil {
+		return fmt.Errorf("failed to restore %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// endregion backup
@@ -0,0 +1,120 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func newTestRepo(t *testing.T) *SqlRepo {
+	t.Helper()
+	db := newTestDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1) // every connection would open a new in-memory database
+
+	repo, err := NewSqlRepository(db, &config.Config{})
+	require.NoError(t, err)
+	return repo
+}
+
+func TestSqlRepo_BackupRestore(t *testing.T) {
+	source := newTestRepo(t)
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	addr, err := domain.CidrFromString("10.0.0.2/32")
+	require.NoError(t, err)
+	ifaceAddr, err := domain.CidrFromString("10.0.0.1/24")
+	require.NoError(t, err)
+
+	require.NoError(t, source.SaveUser(ctx, "alice", func(u *domain.User) (*domain.User, error) {
+		u.Email = "alice@example.com"
+		u.Password = "$2a$10$hash"
+		u.Authentications = []domain.UserAuthentication{
+			{UserIdentifier: "alice", Source: domain.UserSourceDatabase},
+		}
+		u.WebAuthnCredentialList = []domain.UserWebauthnCredential{
+			{UserIdentifier: "alice", CredentialIdentifier: "cred", SerializedCredential: "data"},
+		}
+		return u, nil
+	}))
+	require.NoError(t, source.SaveInterface(ctx, "wg0", func(in *domain.Interface) (*domain.Interface, error) {
+		in.KeyPair = domain.KeyPair{PrivateKey: "iface-private", PublicKey: "iface-public"}
+		in.Addresses = []domain.Cidr{ifaceAddr}
+		return in, nil
+	}))
+	for _, id := range []domain.PeerIdentifier{"peer1", "peer2"} {
+		require.NoError(t, source.SavePeer(ctx, id, func(p *domain.Peer) (*domain.Peer, error) {
+			p.InterfaceIdentifier = "wg0"
+			p.UserIdentifier = "alice"
+			p.PresharedKey = "psk"
+			p.Interface.Addresses = []domain.Cidr{addr}
+			return p, nil
+		}))
+	}
+	require.NoError(t, source.SoftDeletePeer(ctx, "peer2"))
+	require.NoError(t, source.UpdatePeerStatus(ctx, "peer1", func(s *domain.PeerStatus) (*domain.PeerStatus, error) {
+		s.BytesReceived = 42
+		return s, nil
+	}))
+	require.NoError(t, source.SaveAuditEntry(ctx, &domain.AuditEntry{CreatedAt: time.Now(), Message: "first"}))
+	require.NoError(t, source.SaveAuditEntry(ctx, &domain.AuditEntry{CreatedAt: time.Now(), Message: "second"}))
+
+	backup, err := source.GetBackupData(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), backup.SchemaVersion)
+	assert.Len(t, backup.Peers, 2, "deleted peers are part of the backup")
+	assert.Equal(t, "$2a$10$hash", backup.UserPasswords["alice"])
+
+	// the backup must survive the JSON encoding of the archive
+	data, err := json.Marshal(backup)
+	require.NoError(t, err)
+	var decoded domain.Backup
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	target := newTestRepo(t)
+	require.NoError(t, target.SaveUser(ctx, "bob", func(u *domain.User) (*domain.User, error) { return u, nil }))
+	require.NoError(t, target.RestoreBackupData(ctx, &decoded))
+
+	_, err = target.GetUser(ctx, "bob")
+	assert.ErrorIs(t, err, domain.ErrNotFound, "existing data is replaced")
+
+	alice, err := target.GetUser(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PrivateString("$2a$10$hash"), alice.Password)
+	require.Len(t, alice.Authentications, 1)
+	require.Len(t, alice.WebAuthnCredentialList, 1)
+	assert.Equal(t, "data", alice.WebAuthnCredentialList[0].SerializedCredential)
+
+	iface, err := target.GetInterface(ctx, "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, "iface-private", iface.PrivateKey)
+	assert.Equal(t, []domain.Cidr{ifaceAddr}, iface.Addresses)
+
+	peer, err := target.GetPeer(ctx, "peer1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PreSharedKey("psk"), peer.PresharedKey)
+	assert.Equal(t, []domain.Cidr{addr}, peer.Interface.Addresses)
+	deleted, err := target.GetDeletedPeers(ctx)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, domain.PeerIdentifier("peer2"), deleted[0].Identifier)
+
+	status, err := target.GetPeersStats(ctx, "peer1")
+	require.NoError(t, err)
+	require.Len(t, status, 1)
+	assert.Equal(t, uint64(42), status[0].BytesReceived)
+
+	restored, err := target.GetBackupData(ctx)
+	require.NoError(t, err)
+	require.Len(t, restored.AuditEntries, 2)
+	assert.Equal(t, "first", restored.AuditEntries[0].Message)
+	assert.Equal(t, "second", restored.AuditEntries[1].Message)
+}
@@ -0,0 +1,48 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestSqlRepo_Meshes(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, db.AutoMigrate(&domain.Mesh{}))
+
+	repo := &SqlRepo{db: db}
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+
+	_, err := repo.GetMesh(ctx, "branches")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	err = repo.SaveMesh(ctx, "branches", func(m *domain.Mesh) (*domain.Mesh, error) {
+		m.Topology = domain.MeshTopologyHubAndSpoke
+		m.Nodes = []domain.MeshNode{
+			{InterfaceIdentifier: "hq", Hub: true},
+			{InterfaceIdentifier: "branch1", Networks: []string{"192.168.10.0/24"}},
+		}
+		return m, nil
+	})
+	require.NoError(t, err)
+
+	mesh, err := repo.GetMesh(ctx, "branches")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", mesh.CreatedBy)
+	require.Len(t, mesh.Nodes, 2)
+	assert.True(t, mesh.Nodes[0].Hub)
+	assert.Equal(t, []string{"192.168.10.0/24"}, mesh.Nodes[1].Networks)
+
+	meshes, err := repo.GetAllMeshes(ctx)
+	require.NoError(t, err)
+	assert.Len(t, meshes, 1)
+
+	require.NoError(t, repo.DeleteMesh(ctx, "branches"))
+	meshes, err = repo.GetAllMeshes(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, meshes)
+}
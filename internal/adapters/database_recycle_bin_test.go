@@ -0,0 +1,109 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestSqlRepo_PeerRecycleBin(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, db.AutoMigrate(&domain.Peer{}, &domain.PeerStatus{}, &domain.Cidr{}, &domain.ConfigRevision{}))
+
+	repo := &SqlRepo{db: db, cfg: &config.Config{}}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	addr, err := domain.CidrFromString("10.0.0.2/32")
+	require.NoError(t, err)
+	savePeer := func(peer *domain.Peer) error {
+		return repo.SavePeer(ctx, peer.Identifier, func(_ *domain.Peer) (*domain.Peer, error) {
+			return peer, nil
+		})
+	}
+	require.NoError(t, savePeer(&domain.Peer{
+		Identifier:          "peer1",
+		InterfaceIdentifier: "wg0",
+		Interface:           domain.PeerInterfaceConfig{Addresses: []domain.Cidr{addr}},
+	}))
+
+	require.NoError(t, repo.SoftDeletePeer(ctx, "peer1"))
+	assert.ErrorIs(t, repo.SoftDeletePeer(ctx, "peer1"), domain.ErrNotFound)
+
+	_, err = repo.GetPeer(ctx, "peer1")
+	assert.ErrorIs(t, err, domain.ErrNotFound, "deleted peers are hidden")
+	peers, err := repo.GetInterfacePeers(ctx, "wg0")
+	require.NoError(t, err)
+	assert.Empty(t, peers)
+
+	deleted, err := repo.GetDeletedPeers(ctx)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.True(t, deleted[0].IsDeleted())
+	assert.WithinDuration(t, time.Now(), deleted[0].DeletedAt.Time, time.Minute)
+	require.Len(t, deleted[0].Interface.Addresses, 1)
+
+	ips, err := repo.GetPeerIps(ctx)
+	require.NoError(t, err)
+	assert.Len(t, ips["peer1"], 1, "addresses of deleted peers stay reserved")
+
+	err = savePeer(&domain.Peer{Identifier: "peer1", InterfaceIdentifier: "wg0"})
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry, "keys of deleted peers can not be reused")
+
+	require.NoError(t, repo.RestorePeer(ctx, "peer1"))
+	assert.ErrorIs(t, repo.RestorePeer(ctx, "peer1"), domain.ErrNotFound)
+	peer, err := repo.GetPeer(ctx, "peer1")
+	require.NoError(t, err)
+	assert.False(t, peer.IsDeleted())
+	assert.Len(t, peer.Interface.Addresses, 1)
+
+	require.NoError(t, repo.SoftDeletePeer(ctx, "peer1"))
+	require.NoError(t, repo.DeletePeer(ctx, "peer1"))
+	_, err = repo.GetDeletedPeer(ctx, "peer1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	ips, err = repo.GetPeerIps(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ips["peer1"], "purged peers release their addresses")
+}
+
+func TestSqlRepo_UserRecycleBin(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, db.AutoMigrate(&domain.User{}, &domain.UserAuthentication{}, &domain.UserWebauthnCredential{}))
+
+	repo := &SqlRepo{db: db, cfg: &config.Config{}}
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	require.NoError(t, repo.SaveUser(ctx, "user1", func(u *domain.User) (*domain.User, error) {
+		u.Email = "user1@example.com"
+		u.Authentications = []domain.UserAuthentication{
+			{UserIdentifier: "user1", Source: domain.UserSourceDatabase, ProviderName: ""},
+		}
+		return u, nil
+	}))
+
+	require.NoError(t, repo.SoftDeleteUser(ctx, "user1"))
+
+	_, err := repo.GetUser(ctx, "user1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = repo.GetUserByEmail(ctx, "user1@example.com")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	deleted, err := repo.GetDeletedUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Len(t, deleted[0].Authentications, 1, "authentications are kept")
+
+	err = repo.SaveUser(ctx, "user1", func(u *domain.User) (*domain.User, error) { return u, nil })
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+
+	require.NoError(t, repo.RestoreUser(ctx, "user1"))
+	user, err := repo.GetUser(ctx, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, "user1@example.com", user.Email)
+	assert.Len(t, user.Authentications, 1)
+}
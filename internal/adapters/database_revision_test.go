@@ -0,0 +1,60 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestSqlRepo_PeerRevisions(t *testing.T) {
+	db := newTestDB(t)
+	require.NoError(t, db.AutoMigrate(&domain.Peer{}, &domain.PeerStatus{}, &domain.Cidr{}, &domain.ConfigRevision{}))
+
+	cfg := &config.Config{}
+	cfg.Advanced.ConfigRevisionLimit = 2
+	repo := &SqlRepo{db: db, cfg: cfg}
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+
+	savePeer := func(name string) {
+		require.NoError(t, repo.SavePeer(ctx, "peer1", func(p *domain.Peer) (*domain.Peer, error) {
+			p.InterfaceIdentifier = "wg0"
+			p.DisplayName = name
+			return p, nil
+		}))
+	}
+
+	savePeer("first")
+	savePeer("first") // unchanged, no new revision
+	revisions, err := repo.GetConfigRevisions(ctx, domain.RevisionObjectPeer, "peer1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, uint64(1), revisions[0].Version)
+	assert.Equal(t, "admin", revisions[0].CreatedBy)
+
+	savePeer("second")
+	savePeer("third")
+	revisions, err = repo.GetConfigRevisions(ctx, domain.RevisionObjectPeer, "peer1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2, "old revisions are pruned")
+	assert.Equal(t, uint64(3), revisions[0].Version)
+	assert.Equal(t, uint64(2), revisions[1].Version)
+
+	revision, err := repo.GetConfigRevision(ctx, domain.RevisionObjectPeer, "peer1", 2)
+	require.NoError(t, err)
+	peer, err := revision.Peer()
+	require.NoError(t, err)
+	assert.Equal(t, "second", peer.DisplayName)
+
+	_, err = repo.GetConfigRevision(ctx, domain.RevisionObjectPeer, "peer1", 1)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	require.NoError(t, repo.DeletePeer(ctx, "peer1"))
+	revisions, err = repo.GetConfigRevisions(ctx, domain.RevisionObjectPeer, "peer1")
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+}
@@ -20,6 +20,12 @@ func init() {
 type dummySerializer struct{}
 
 func (dummySerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	switch v := dbValue.(type) {
+	case string:
+		field.ReflectValueOf(ctx, dst).SetString(v)
+	case []byte:
+		field.ReflectValueOf(ctx, dst).SetString(string(v))
+	}
 	return nil
 }
 
@@ -42,7 +48,7 @@ func TestSqlRepo_SaveInterface_Simple(t *testing.T) {
 	require.NoError(t, err)
 
 	// Migrate only what's needed for this test (avoids Peer and its encstr serializer)
-	require.NoError(t, db.AutoMigrate(&domain.Interface{}, &domain.Cidr{}))
+	require.NoError(t, db.AutoMigrate(&domain.Interface{}, &domain.Cidr{}, &domain.ConfigRevision{}))
 
 	repo := &SqlRepo{db: db, cfg: &config.Config{}}
 	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
@@ -0,0 +1,356 @@
+package adapters
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	dnsTypeTsig             = 250
+	dnsClassAny             = 255
+	tsigFudge               = 300 // seconds, as recommended by RFC 8945
+	tsigMaxUnsignedMessages = 99
+)
+
+var tsigAlgorithms = map[string]func() hash.Hash{
+	"hmac-sha1.":   sha1.New,
+	"hmac-sha256.": sha256.New,
+	"hmac-sha384.": sha512.New384,
+	"hmac-sha512.": sha512.New,
+}
+
+// tsigKey signs DNS messages and verifies the signatures of responses as described in RFC 8945.
+type tsigKey struct {
+	name      string // fully qualified key name
+	algorithm string // fully qualified algorithm name
+	secret    []byte
+	hash      func() hash.Hash
+}
+
+func newTsigKey(name, algorithm, secret string) (*tsigKey, error) {
+	algorithm = domain.FqdnDnsName(algorithm)
+	hashFunc, ok := tsigAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TSIG algorithm %s", algorithm)
+	}
+
+	decodedSecret, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TSIG secret: %w", err)
+	}
+
+	return &tsigKey{
+		name:      domain.FqdnDnsName(name),
+		algorithm: algorithm,
+		secret:    decodedSecret,
+		hash:      hashFunc,
+	}, nil
+}
+
+// tsigRecord is the content of a TSIG resource record.
+type tsigRecord struct {
+	algorithm  string
+	timeSigned uint64
+	fudge      uint16
+	mac        []byte
+	originalId uint16
+	error      uint16
+	other      []byte
+}
+
+// sign appends a TSIG record to the message and returns the signed message and its MAC.
+func (k *tsigKey) sign(msg []byte, now time.Time) ([]byte, []byte) {
+	record := tsigRecord{
+		algorithm:  k.algorithm,
+		timeSigned: uint64(now.Unix()),
+		fudge:      tsigFudge,
+		originalId: binary.BigEndian.Uint16(msg),
+	}
+
+	h := hmac.New(k.hash, k.secret)
+	h.Write(msg)
+	h.Write(k.variables(record, false))
+	record.mac = h.Sum(nil)
+
+	return k.appendRecord(msg, record), record.mac
+}
+
+// appendRecord appends the TSIG record to a copy of the message and increments the additional record count.
+func (k *tsigKey) appendRecord(msg []byte, record tsigRecord) []byte {
+	rdata := dnsWireName(record.algorithm)
+	rdata = appendUint48(rdata, record.timeSigned)
+	rdata = binary.BigEndian.AppendUint16(rdata, record.fudge)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(record.mac)))
+	rdata = append(rdata, record.mac...)
+	rdata = binary.BigEndian.AppendUint16(rdata, record.originalId)
+	rdata = binary.BigEndian.AppendUint16(rdata, record.error)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(record.other)))
+	rdata = append(rdata, record.other...)
+
+	signed := make([]byte, len(msg), len(msg)+len(k.name)+len(rdata)+12)
+	copy(signed, msg)
+	signed = append(signed, dnsWireName(k.name)...)
+	signed = binary.BigEndian.AppendUint16(signed, dnsTypeTsig)
+	signed = binary.BigEndian.AppendUint16(signed, dnsClassAny)
+	signed = binary.BigEndian.AppendUint32(signed, 0)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(len(rdata)))
+	signed = append(signed, rdata...)
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(signed[10:])+1) // additional record count
+
+	return signed
+}
+
+// variables returns the TSIG variables that are covered by the MAC. Subsequent messages of a zone transfer only
+// cover the timers.
+func (k *tsigKey) variables(record tsigRecord, timersOnly bool) []byte {
+	var buf []byte
+	if !timersOnly {
+		buf = append(buf, dnsWireName(k.name)...)
+		buf = binary.BigEndian.AppendUint16(buf, dnsClassAny)
+		buf = binary.BigEndian.AppendUint32(buf, 0)
+		buf = append(buf, dnsWireName(record.algorithm)...)
+	}
+	buf = appendUint48(buf, record.timeSigned)
+	buf = binary.BigEndian.AppendUint16(buf, record.fudge)
+	if !timersOnly {
+		buf = binary.BigEndian.AppendUint16(buf, record.error)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(record.other)))
+		buf = append(buf, record.other...)
+	}
+
+	return buf
+}
+
+// tsigVerifier verifies the responses to a signed request. Responses that consist of several messages, like zone
+// transfers, may leave up to 99 messages in a row unsigned, but the last message must be signed.
+type tsigVerifier struct {
+	key      *tsigKey
+	prevMac  []byte // the MAC of the request or the last signed response
+	pending  []byte // unsigned messages since the last signed response
+	unsigned int
+	first    bool
+}
+
+func newTsigVerifier(key *tsigKey, requestMac []byte) *tsigVerifier {
+	return &tsigVerifier{key: key, prevMac: requestMac, first: true}
+}
+
+func (v *tsigVerifier) verify(msg []byte, now time.Time) error {
+	start, record, err := findTsigRecord(msg)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		if v.first {
+			return errors.New("response is not signed")
+		}
+		v.unsigned++
+		if v.unsigned > tsigMaxUnsignedMessages {
+			return errors.New("too many unsigned messages in response")
+		}
+		v.pending = append(v.pending, msg...)
+		return nil
+	}
+
+	if record.error != 0 {
+		return fmt.Errorf("server rejected TSIG signature: %s", tsigErrorName(record.error))
+	}
+	if record.algorithm != v.key.algorithm {
+		return fmt.Errorf("unexpected TSIG algorithm %s in response", record.algorithm)
+	}
+
+	stripped := make([]byte, start)
+	copy(stripped, msg[:start])
+	binary.BigEndian.PutUint16(stripped, record.originalId)
+	binary.BigEndian.PutUint16(stripped[10:], binary.BigEndian.Uint16(stripped[10:])-1)
+
+	h := hmac.New(v.key.hash, v.key.secret)
+	h.Write(binary.BigEndian.AppendUint16(nil, uint16(len(v.prevMac))))
+	h.Write(v.prevMac)
+	h.Write(v.pending)
+	h.Write(stripped)
+	h.Write(v.key.variables(*record, !v.first))
+	if !hmac.Equal(h.Sum(nil), record.mac) {
+		return errors.New("invalid TSIG signature in response")
+	}
+
+	signedAt := time.Unix(int64(record.timeSigned), 0)
+	if d := now.Sub(signedAt).Abs(); d > time.Duration(record.fudge)*time.Second {
+		return fmt.Errorf("TSIG time of response is off by %s", d)
+	}
+
+	v.prevMac = record.mac
+	v.pending = nil
+	v.unsigned = 0
+	v.first = false
+
+	return nil
+}
+
+// complete returns an error if the last message of the response was not signed.
+func (v *tsigVerifier) complete() error {
+	if v.unsigned > 0 {
+		return errors.New("last message of response is not signed")
+	}
+	return nil
+}
+
+// findTsigRecord returns the offset and the content of the TSIG record, which must be the last record of the
+// message. If the message is not signed, the record is nil.
+func findTsigRecord(msg []byte) (int, *tsigRecord, error) {
+	if len(msg) < 12 {
+		return 0, nil, errors.New("message too short")
+	}
+
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	records := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:]))
+	additionals := int(binary.BigEndian.Uint16(msg[10:]))
+	if additionals == 0 {
+		return 0, nil, nil
+	}
+
+	off := 12
+	var err error
+	for range questions {
+		if off, err = skipDnsName(msg, off); err != nil {
+			return 0, nil, err
+		}
+		off += 4
+	}
+
+	var lastStart, lastType, rdata, rdataLen int
+	for range records + additionals {
+		lastStart = off
+		if off, err = skipDnsName(msg, off); err != nil {
+			return 0, nil, err
+		}
+		if off+10 > len(msg) {
+			return 0, nil, errors.New("truncated resource record")
+		}
+		lastType = int(binary.BigEndian.Uint16(msg[off:]))
+		rdataLen = int(binary.BigEndian.Uint16(msg[off+8:]))
+		rdata = off + 10
+		off = rdata + rdataLen
+		if off > len(msg) {
+			return 0, nil, errors.New("truncated resource record")
+		}
+	}
+	if lastType != dnsTypeTsig {
+		return 0, nil, nil
+	}
+
+	record, err := parseTsigRecord(msg[rdata : rdata+rdataLen])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return lastStart, record, nil
+}
+
+func parseTsigRecord(rdata []byte) (*tsigRecord, error) {
+	var labels []string
+	off := 0
+	for {
+		if off >= len(rdata) {
+			return nil, errors.New("invalid TSIG algorithm name")
+		}
+		length := int(rdata[off])
+		off++
+		if length == 0 {
+			break
+		}
+		if length > 63 || off+length > len(rdata) {
+			return nil, errors.New("invalid TSIG algorithm name")
+		}
+		labels = append(labels, string(rdata[off:off+length]))
+		off += length
+	}
+
+	if off+10 > len(rdata) {
+		return nil, errors.New("truncated TSIG record")
+	}
+	record := &tsigRecord{algorithm: domain.FqdnDnsName(strings.Join(labels, "."))}
+	record.timeSigned = uint64(binary.BigEndian.Uint16(rdata[off:]))<<32 | uint64(binary.BigEndian.Uint32(rdata[off+2:]))
+	record.fudge = binary.BigEndian.Uint16(rdata[off+6:])
+	macLen := int(binary.BigEndian.Uint16(rdata[off+8:]))
+	off += 10
+
+	if off+macLen+6 > len(rdata) {
+		return nil, errors.New("truncated TSIG record")
+	}
+	record.mac = rdata[off : off+macLen]
+	off += macLen
+	record.originalId = binary.BigEndian.Uint16(rdata[off:])
+	record.error = binary.BigEndian.Uint16(rdata[off+2:])
+	otherLen := int(binary.BigEndian.Uint16(rdata[off+4:]))
+	off += 6
+
+	if off+otherLen > len(rdata) {
+		return nil, errors.New("truncated TSIG record")
+	}
+	record.other = rdata[off : off+otherLen]
+
+	return record, nil
+}
+
+func skipDnsName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, errors.New("truncated name")
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			return off + 1, nil
+		case length&0xc0 == 0xc0: // compression pointer
+			return off + 2, nil
+		default:
+			off += 1 + length
+		}
+	}
+}
+
+// dnsWireName returns the uncompressed wire format of the name in canonical (lowercase) form.
+func dnsWireName(name string) []byte {
+	var buf []byte
+	for _, label := range strings.Split(strings.Trim(strings.ToLower(name), "."), ".") {
+		if label == "" {
+			continue
+		}
+		buf = append(buf, byte(len(label)))
+		buf = append(buf, label...)
+	}
+
+	return append(buf, 0)
+}
+
+func appendUint48(buf []byte, v uint64) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(v>>32))
+	return binary.BigEndian.AppendUint32(buf, uint32(v))
+}
+
+func tsigErrorName(code uint16) string {
+	switch code {
+	case 16:
+		return "BADSIG"
+	case 17:
+		return "BADKEY"
+	case 18:
+		return "BADTIME"
+	case 22:
+		return "BADTRUNC"
+	default:
+		return fmt.Sprintf("error %d", code)
+	}
+}
@@ -0,0 +1,358 @@
+package adapters
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	dnsOpCodeUpdate = 5
+	dnsClassNone    = 254
+)
+
+// DnsUpdateClient sends dynamic updates (RFC 2136) and zone transfer requests to the primary DNS server.
+// All requests are sent over TCP and signed with TSIG if a key is configured.
+type DnsUpdateClient struct {
+	cfg    *config.DnsUpdateConfig
+	server string
+	key    *tsigKey // nil if requests are not signed
+}
+
+// NewDnsUpdateClient creates a new DnsUpdateClient instance.
+func NewDnsUpdateClient(cfg config.DnsUpdateConfig) (*DnsUpdateClient, error) {
+	c := &DnsUpdateClient{cfg: &cfg, server: cfg.Server}
+
+	if _, _, err := net.SplitHostPort(c.server); err != nil {
+		c.server = net.JoinHostPort(c.server, "53")
+	}
+
+	if cfg.TsigKeyName != "" {
+		key, err := newTsigKey(cfg.TsigKeyName, cfg.TsigAlgorithm, cfg.TsigSecret)
+		if err != nil {
+			return nil, err
+		}
+		c.key = key
+	}
+
+	return c, nil
+}
+
+// Update applies the changes to the zone. Record deletions are applied before additions.
+func (c *DnsUpdateClient) Update(ctx context.Context, update domain.DnsUpdate) error {
+	if update.IsEmpty() {
+		return nil
+	}
+
+	zone, err := dnsmessage.NewName(domain.FqdnDnsName(update.Zone))
+	if err != nil {
+		return fmt.Errorf("invalid zone %s: %w", update.Zone, err)
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: uint16(rand.Uint32()), OpCode: dnsOpCodeUpdate})
+	if err := b.StartQuestions(); err != nil {
+		return err
+	}
+	err = b.Question(dnsmessage.Question{Name: zone, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET})
+	if err != nil {
+		return err
+	}
+	if err := b.StartAuthorities(); err != nil { // the update section
+		return err
+	}
+	for _, record := range update.Deletes {
+		if record.Value == "" {
+			err = addDnsRecordSetDeletion(&b, record)
+		} else {
+			err = addDnsRecord(&b, record, dnsClassNone, 0)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid record %s: %w", record.Key(), err)
+		}
+	}
+	for _, record := range update.Adds {
+		if err := addDnsRecord(&b, record, dnsmessage.ClassINET, record.Ttl); err != nil {
+			return fmt.Errorf("invalid record %s: %w", record.Key(), err)
+		}
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		return err
+	}
+
+	err = c.exchange(ctx, msg, func(response []byte) (bool, error) {
+		return true, checkDnsResponse(response)
+	})
+	if err != nil {
+		return fmt.Errorf("update of zone %s failed: %w", update.Zone, err)
+	}
+
+	return nil
+}
+
+// Transfer returns the A, AAAA, PTR and TXT records of the zone using a zone transfer (AXFR).
+func (c *DnsUpdateClient) Transfer(ctx context.Context, zone string) ([]domain.DnsRecord, error) {
+	zoneName, err := dnsmessage.NewName(domain.FqdnDnsName(zone))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zone %s: %w", zone, err)
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: uint16(rand.Uint32())})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	err = b.Question(dnsmessage.Question{Name: zoneName, Type: dnsmessage.TypeAXFR, Class: dnsmessage.ClassINET})
+	if err != nil {
+		return nil, err
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []domain.DnsRecord
+	soaCount := 0
+	err = c.exchange(ctx, msg, func(response []byte) (bool, error) {
+		if err := checkDnsResponse(response); err != nil {
+			return true, err
+		}
+
+		var soa int
+		records, soa, err = appendDnsRecords(records, response)
+		soaCount += soa
+		return soaCount >= 2, err // the transfer starts and ends with the SOA record
+	})
+	if err != nil {
+		return nil, fmt.Errorf("transfer of zone %s failed: %w", zone, err)
+	}
+
+	return records, nil
+}
+
+// exchange sends the request and passes each response message to the handler until it reports that the response
+// is complete.
+func (c *DnsUpdateClient) exchange(ctx context.Context, msg []byte, handle func([]byte) (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	var verifier *tsigVerifier
+	if c.key != nil {
+		var mac []byte
+		msg, mac = c.key.sign(msg, time.Now())
+		verifier = newTsigVerifier(c.key, mac)
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", c.server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err := writeDnsTcpMessage(conn, msg); err != nil {
+		return err
+	}
+
+	for {
+		response, err := readDnsTcpMessage(conn)
+		if err != nil {
+			return err
+		}
+		if len(response) < 12 || binary.BigEndian.Uint16(response) != binary.BigEndian.Uint16(msg) {
+			return errors.New("response id does not match the request")
+		}
+
+		if verifier != nil {
+			if err := verifier.verify(response, time.Now()); err != nil {
+				if rcodeErr := checkDnsResponse(response); rcodeErr != nil {
+					return rcodeErr // unsigned error responses, for example if the server does not know the key
+				}
+				return err
+			}
+		}
+
+		done, err := handle(response)
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+	}
+
+	if verifier != nil {
+		return verifier.complete()
+	}
+
+	return nil
+}
+
+func addDnsRecord(b *dnsmessage.Builder, record domain.DnsRecord, class dnsmessage.Class, ttl uint32) error {
+	name, err := dnsmessage.NewName(record.Name)
+	if err != nil {
+		return err
+	}
+	header := dnsmessage.ResourceHeader{Name: name, Class: class, TTL: ttl}
+
+	switch record.Type {
+	case domain.DnsRecordTypeA, domain.DnsRecordTypeAAAA:
+		addr, err := netip.ParseAddr(record.Value)
+		if err != nil {
+			return err
+		}
+		if addr.Is4() {
+			return b.AResource(header, dnsmessage.AResource{A: addr.As4()})
+		}
+		return b.AAAAResource(header, dnsmessage.AAAAResource{AAAA: addr.As16()})
+	case domain.DnsRecordTypePTR:
+		target, err := dnsmessage.NewName(record.Value)
+		if err != nil {
+			return err
+		}
+		return b.PTRResource(header, dnsmessage.PTRResource{PTR: target})
+	case domain.DnsRecordTypeTXT:
+		return b.TXTResource(header, dnsmessage.TXTResource{TXT: []string{record.Value}})
+	default:
+		return fmt.Errorf("unsupported record type %s", record.Type)
+	}
+}
+
+// addDnsRecordSetDeletion adds the deletion of all records of the name and type.
+func addDnsRecordSetDeletion(b *dnsmessage.Builder, record domain.DnsRecord) error {
+	name, err := dnsmessage.NewName(record.Name)
+	if err != nil {
+		return err
+	}
+
+	var recordType dnsmessage.Type
+	switch record.Type {
+	case domain.DnsRecordTypeA:
+		recordType = dnsmessage.TypeA
+	case domain.DnsRecordTypeAAAA:
+		recordType = dnsmessage.TypeAAAA
+	case domain.DnsRecordTypePTR:
+		recordType = dnsmessage.TypePTR
+	case domain.DnsRecordTypeTXT:
+		recordType = dnsmessage.TypeTXT
+	default:
+		return fmt.Errorf("unsupported record type %s", record.Type)
+	}
+
+	return b.UnknownResource(dnsmessage.ResourceHeader{Name: name, Class: dnsClassAny},
+		dnsmessage.UnknownResource{Type: recordType})
+}
+
+// appendDnsRecords appends the supported records of the answer section and returns the number of SOA records.
+func appendDnsRecords(records []domain.DnsRecord, msg []byte) ([]domain.DnsRecord, int, error) {
+	var p dnsmessage.Parser
+	if _, err := p.Start(msg); err != nil {
+		return records, 0, err
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return records, 0, err
+	}
+
+	soaCount := 0
+	for {
+		header, err := p.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			return records, soaCount, nil
+		}
+		if err != nil {
+			return records, soaCount, err
+		}
+
+		record := domain.DnsRecord{Name: domain.FqdnDnsName(header.Name.String()), Ttl: header.TTL}
+		switch header.Type {
+		case dnsmessage.TypeSOA:
+			soaCount++
+			if err := p.SkipAnswer(); err != nil {
+				return records, soaCount, err
+			}
+			continue
+		case dnsmessage.TypeA:
+			var r dnsmessage.AResource
+			if r, err = p.AResource(); err == nil {
+				record.Type, record.Value = domain.DnsRecordTypeA, netip.AddrFrom4(r.A).String()
+			}
+		case dnsmessage.TypeAAAA:
+			var r dnsmessage.AAAAResource
+			if r, err = p.AAAAResource(); err == nil {
+				record.Type, record.Value = domain.DnsRecordTypeAAAA, netip.AddrFrom16(r.AAAA).String()
+			}
+		case dnsmessage.TypePTR:
+			var r dnsmessage.PTRResource
+			if r, err = p.PTRResource(); err == nil {
+				record.Type, record.Value = domain.DnsRecordTypePTR, domain.FqdnDnsName(r.PTR.String())
+			}
+		case dnsmessage.TypeTXT:
+			var r dnsmessage.TXTResource
+			if r, err = p.TXTResource(); err == nil {
+				record.Type, record.Value = domain.DnsRecordTypeTXT, strings.Join(r.TXT, "")
+			}
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return records, soaCount, err
+			}
+			continue
+		}
+		if err != nil {
+			return records, soaCount, err
+		}
+
+		records = append(records, record)
+	}
+}
+
+func checkDnsResponse(msg []byte) error {
+	var p dnsmessage.Parser
+	header, err := p.Start(msg)
+	if err != nil {
+		return err
+	}
+	if !header.Response {
+		return errors.New("message is not a response")
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return fmt.Errorf("server responded with %s", strings.TrimPrefix(header.RCode.String(), "RCode"))
+	}
+
+	return nil
+}
+
+func readDnsTcpMessage(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func writeDnsTcpMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+
+	_, err := w.Write(buf)
+	return err
+}
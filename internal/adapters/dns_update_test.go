@@ -0,0 +1,238 @@
+package adapters
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const testTsigSecret = "c2VjcmV0LWtleS1mb3ItdGVzdGluZw=="
+
+// fakeDnsServer is a primary DNS server that verifies the TSIG signature of requests and signs its responses.
+type fakeDnsServer struct {
+	t        *testing.T
+	key      *tsigKey
+	listener net.Listener
+	badMac   bool // sign responses with an invalid MAC
+
+	updates  []dnsmessage.Message
+	transfer [][]dnsmessage.Resource // the messages of a zone transfer, the middle messages are not signed
+}
+
+func newFakeDnsServer(t *testing.T) *fakeDnsServer {
+	key, err := newTsigKey("update-key", "hmac-sha256", testTsigSecret)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	s := &fakeDnsServer{t: t, key: key, listener: listener}
+	go s.serve()
+
+	return s
+}
+
+func (s *fakeDnsServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.handle(conn)
+		_ = conn.Close()
+	}
+}
+
+func (s *fakeDnsServer) handle(conn net.Conn) {
+	request, err := readDnsTcpMessage(conn)
+	if !assert.NoError(s.t, err) {
+		return
+	}
+
+	var msg dnsmessage.Message
+	if !assert.NoError(s.t, msg.Unpack(request)) {
+		return
+	}
+	response := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: msg.Header.ID, Response: true, OpCode: msg.Header.OpCode},
+		Questions: msg.Questions,
+	}
+
+	requestMac, ok := s.verifyRequest(request)
+	if !ok {
+		response.Header.RCode = dnsmessage.RCodeRefused
+		raw, _ := response.Pack()
+		_ = writeDnsTcpMessage(conn, raw)
+		return
+	}
+
+	if msg.Header.OpCode == dnsOpCodeUpdate {
+		s.updates = append(s.updates, msg)
+		raw, err := response.Pack()
+		require.NoError(s.t, err)
+		signed, _ := s.signResponse(nil, raw, requestMac, false)
+		_ = writeDnsTcpMessage(conn, signed)
+		return
+	}
+
+	prevMac := requestMac
+	var pending []byte
+	for i, answers := range s.transfer {
+		response.Answers = answers
+		raw, err := response.Pack()
+		require.NoError(s.t, err)
+		if i == 0 || i == len(s.transfer)-1 {
+			raw, prevMac = s.signResponse(pending, raw, prevMac, i > 0)
+			pending = nil
+		} else {
+			pending = append(pending, raw...)
+		}
+		_ = writeDnsTcpMessage(conn, raw)
+	}
+}
+
+func (s *fakeDnsServer) verifyRequest(request []byte) ([]byte, bool) {
+	start, record, err := findTsigRecord(request)
+	if err != nil || record == nil || record.algorithm != s.key.algorithm {
+		return nil, false
+	}
+
+	stripped := make([]byte, start)
+	copy(stripped, request[:start])
+	binary.BigEndian.PutUint16(stripped, record.originalId)
+	binary.BigEndian.PutUint16(stripped[10:], binary.BigEndian.Uint16(stripped[10:])-1)
+
+	h := hmac.New(s.key.hash, s.key.secret)
+	h.Write(stripped)
+	h.Write(s.key.variables(*record, false))
+
+	return record.mac, hmac.Equal(h.Sum(nil), record.mac)
+}
+
+// signResponse signs the message, the MAC also covers the unsigned messages that were sent since the last signed one.
+func (s *fakeDnsServer) signResponse(unsigned, msg, prevMac []byte, timersOnly bool) ([]byte, []byte) {
+	record := tsigRecord{
+		algorithm:  s.key.algorithm,
+		timeSigned: uint64(time.Now().Unix()),
+		fudge:      tsigFudge,
+		originalId: binary.BigEndian.Uint16(msg),
+	}
+
+	h := hmac.New(s.key.hash, s.key.secret)
+	h.Write(binary.BigEndian.AppendUint16(nil, uint16(len(prevMac))))
+	h.Write(prevMac)
+	h.Write(unsigned)
+	h.Write(msg)
+	h.Write(s.key.variables(record, timersOnly))
+	record.mac = h.Sum(nil)
+	if s.badMac {
+		record.mac[0] ^= 0xff
+	}
+
+	return s.key.appendRecord(msg, record), record.mac
+}
+
+func newTestDnsUpdateClient(t *testing.T, server *fakeDnsServer, secret string) *DnsUpdateClient {
+	client, err := NewDnsUpdateClient(config.DnsUpdateConfig{
+		Server:        server.listener.Addr().String(),
+		TsigKeyName:   "update-key.",
+		TsigSecret:    secret,
+		TsigAlgorithm: "hmac-sha256",
+		Timeout:       5 * time.Second,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestDnsUpdateClient_Update(t *testing.T) {
+	server := newFakeDnsServer(t)
+	client := newTestDnsUpdateClient(t, server, testTsigSecret)
+
+	err := client.Update(context.Background(), domain.DnsUpdate{
+		Zone: "VPN.example.com",
+		Deletes: []domain.DnsRecord{
+			{Name: "old.vpn.example.com.", Type: domain.DnsRecordTypeA, Value: "10.0.0.2"},
+		},
+		Adds: []domain.DnsRecord{
+			{Name: "new.vpn.example.com.", Type: domain.DnsRecordTypeA, Value: "10.0.0.2", Ttl: 300},
+			{Name: "new.vpn.example.com.", Type: domain.DnsRecordTypeTXT, Value: "managed", Ttl: 300},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, server.updates, 1)
+	msg := server.updates[0]
+	assert.Equal(t, "vpn.example.com.", msg.Questions[0].Name.String())
+	assert.Equal(t, dnsmessage.TypeSOA, msg.Questions[0].Type)
+
+	require.Len(t, msg.Authorities, 3)
+	assert.Equal(t, "old.vpn.example.com.", msg.Authorities[0].Header.Name.String())
+	assert.Equal(t, dnsmessage.Class(dnsClassNone), msg.Authorities[0].Header.Class)
+	assert.Equal(t, uint32(0), msg.Authorities[0].Header.TTL)
+	assert.Equal(t, &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}}, msg.Authorities[0].Body)
+	assert.Equal(t, dnsmessage.ClassINET, msg.Authorities[1].Header.Class)
+	assert.Equal(t, uint32(300), msg.Authorities[1].Header.TTL)
+	assert.Equal(t, &dnsmessage.TXTResource{TXT: []string{"managed"}}, msg.Authorities[2].Body)
+
+	assert.NoError(t, client.Update(context.Background(), domain.DnsUpdate{Zone: "vpn.example.com"}))
+	assert.Len(t, server.updates, 1, "empty updates are not sent")
+}
+
+func TestDnsUpdateClient_Transfer(t *testing.T) {
+	server := newFakeDnsServer(t)
+	client := newTestDnsUpdateClient(t, server, testTsigSecret)
+
+	zone := dnsmessage.MustNewName("vpn.example.com.")
+	peer := dnsmessage.MustNewName("peer.vpn.example.com.")
+	header := func(name dnsmessage.Name) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 60}
+	}
+	soa := dnsmessage.Resource{Header: header(zone), Body: &dnsmessage.SOAResource{NS: zone, MBox: zone, Serial: 1}}
+	server.transfer = [][]dnsmessage.Resource{
+		{soa, {Header: header(peer), Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}}}},
+		{
+			{Header: header(peer), Body: &dnsmessage.TXTResource{TXT: []string{"heritage=", "wg-portal"}}},
+			{Header: header(zone), Body: &dnsmessage.MXResource{Pref: 10, MX: peer}},
+		},
+		{{Header: header(peer), Body: &dnsmessage.AAAAResource{AAAA: [16]byte{0xfd, 15: 2}}}, soa},
+	}
+
+	records, err := client.Transfer(context.Background(), "vpn.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.DnsRecord{
+		{Name: "peer.vpn.example.com.", Type: domain.DnsRecordTypeA, Value: "10.0.0.2", Ttl: 60},
+		{Name: "peer.vpn.example.com.", Type: domain.DnsRecordTypeTXT, Value: "heritage=wg-portal", Ttl: 60},
+		{Name: "peer.vpn.example.com.", Type: domain.DnsRecordTypeAAAA, Value: "fd00::2", Ttl: 60},
+	}, records)
+}
+
+func TestDnsUpdateClient_Tsig(t *testing.T) {
+	server := newFakeDnsServer(t)
+	update := domain.DnsUpdate{
+		Zone: "vpn.example.com",
+		Adds: []domain.DnsRecord{{Name: "peer.vpn.example.com.", Type: domain.DnsRecordTypeA, Value: "10.0.0.2"}},
+	}
+
+	client := newTestDnsUpdateClient(t, server, "d3Jvbmcta2V5")
+	err := client.Update(context.Background(), update)
+	assert.ErrorContains(t, err, "Refused", "requests signed with the wrong key are rejected")
+
+	server.badMac = true
+	client = newTestDnsUpdateClient(t, server, testTsigSecret)
+	err = client.Update(context.Background(), update)
+	assert.ErrorContains(t, err, "invalid TSIG signature", "responses with invalid signatures are rejected")
+
+	_, err = NewDnsUpdateClient(config.DnsUpdateConfig{TsigKeyName: "key", TsigAlgorithm: "hmac-md5"})
+	assert.Error(t, err)
+}
@@ -25,12 +25,14 @@ type MetricsServer struct {
 	peerLastHandshakeSeconds *prometheus.GaugeVec
 	peerReceivedBytesTotal   *prometheus.GaugeVec
 	peerSendBytesTotal       *prometheus.GaugeVec
+	ifaceDriftItems          *prometheus.GaugeVec
 }
 
 // Wireguard metrics labels
 var (
 	ifaceLabels = []string{"interface"}
 	peerLabels  = []string{"interface", "addresses", "id", "name", "user"}
+	driftLabels = []string{"interface", "backend", "type"}
 )
 
 // NewMetricsServer returns a new prometheus server
@@ -83,6 +85,13 @@ func NewMetricsServer(cfg *config.Config) *MetricsServer {
 				Help: "Bytes sent to the peer.",
 			}, peerLabels,
 		),
+
+		ifaceDriftItems: promauto.With(reg).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "wireguard_interface_drift_items",
+				Help: "Number of objects that differ between the database and the backend.",
+			}, driftLabels,
+		),
 	}
 }
 
@@ -136,3 +145,15 @@ func (m *MetricsServer) UpdatePeerMetrics(peer *domain.Peer, status domain.PeerS
 	m.peerSendBytesTotal.WithLabelValues(labels...).Set(float64(status.BytesTransmitted))
 	m.peerIsConnected.WithLabelValues(labels...).Set(internal.BoolToFloat64(status.IsConnected))
 }
+
+// UpdateDriftMetrics replaces the drift metrics with the results of the given drift reports
+func (m *MetricsServer) UpdateDriftMetrics(reports []domain.DriftReport) {
+	m.ifaceDriftItems.Reset() // interfaces that are no longer reported must not keep their old values
+
+	for _, report := range reports {
+		for _, driftType := range []domain.DriftType{domain.DriftMissing, domain.DriftExtra, domain.DriftChanged} {
+			labels := []string{string(report.InterfaceIdentifier), string(report.Backend), string(driftType)}
+			m.ifaceDriftItems.WithLabelValues(labels...).Set(float64(report.Count(driftType)))
+		}
+	}
+}
@@ -3,18 +3,26 @@ package wgcontroller
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"net"
 	"os"
 	"os/exec"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
 	probing "github.com/prometheus-community/pro-bing"
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
 	"golang.org/x/sys/unix"
 	"golang.zx2c4.com/wireguard/wgctrl"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
@@ -35,6 +43,12 @@ type WgCtrlRepo interface {
 	ConfigureDevice(name string, cfg wgtypes.Config) error
 }
 
+// AmneziaWgRepo is implemented by WgCtrlRepo clients that control AmneziaWG devices.
+type AmneziaWgRepo interface {
+	Obfuscation(name string) (lowlevel.AmneziaWgConfig, error)
+	ConfigureObfuscation(name string, cfg lowlevel.AmneziaWgConfig) error
+}
+
 // A NetlinkClient is a type which can control a netlink device.
 type NetlinkClient interface {
 	LinkAdd(link netlink.Link) error
@@ -55,6 +69,14 @@ type NetlinkClient interface {
 	RuleAdd(rule *netlink.Rule) error
 	RuleDel(rule *netlink.Rule) error
 	RuleList(family int) ([]netlink.Rule, error)
+	QdiscList(link netlink.Link) ([]netlink.Qdisc, error)
+	QdiscReplace(qdisc netlink.Qdisc) error
+	QdiscDel(qdisc netlink.Qdisc) error
+	ClassReplace(class netlink.Class) error
+	ClassDel(class netlink.Class) error
+	FilterAdd(filter netlink.Filter) error
+	FilterDel(filter netlink.Filter) error
+	FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error)
 }
 
 // endregion dependencies
@@ -67,12 +89,20 @@ type LocalController struct {
 
 	shellCmd              string
 	resolvConfIfacePrefix string
+
+	tc *trafficControlState
 }
 
 // NewLocalController creates a new local controller instance.
 // This repository is used to interact with the WireGuard kernel or userspace module.
 func NewLocalController(cfg *config.Config) (*LocalController, error) {
-	wg, err := wgctrl.New()
+	var wg WgCtrlRepo
+	var err error
+	if cfg.Backend.LocalAmneziaWG {
+		wg, err = lowlevel.NewAmneziaWgClient()
+	} else {
+		wg, err = wgctrl.New()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create wgctrl client: %w", err)
 	}
@@ -87,6 +117,8 @@ func NewLocalController(cfg *config.Config) (*LocalController, error) {
 
 		shellCmd:              "bash",                            // we only support bash at the moment
 		resolvConfIfacePrefix: cfg.Backend.LocalResolvconfPrefix, // WireGuard interfaces have a tun. prefix in resolvconf
+
+		tc: newTrafficControlState(),
 	}
 
 	return repo, nil
@@ -157,6 +189,14 @@ func (c LocalController) convertWireGuardInterface(device *wgtypes.Device) (doma
 	for _, addr := range ipAddresses {
 		iface.Addresses = append(iface.Addresses, domain.CidrFromNetlinkAddr(addr))
 	}
+	if awg, ok := c.wg.(AmneziaWgRepo); ok {
+		obfuscation, err := awg.Obfuscation(device.Name)
+		if err != nil {
+			return domain.PhysicalInterface{}, fmt.Errorf("amneziawg read error for %s: %w", device.Name, err)
+		}
+		iface.AmneziaParams = convertAmneziaWgConfig(obfuscation)
+	}
+
 	iface.Mtu = lowLevelInterface.Attrs().MTU
 	iface.DeviceUp = lowLevelInterface.Attrs().OperState == netlink.OperUnknown // wg only supports unknown
 	if stats := lowLevelInterface.Attrs().Statistics; stats != nil {
@@ -285,6 +325,9 @@ func (c LocalController) createLowLevelInterface(id domain.InterfaceIdentifier)
 		},
 		LinkType: "wireguard",
 	}
+	if c.cfg.Backend.LocalAmneziaWG {
+		link.LinkType = lowlevel.AmneziaWgLinkType
+	}
 	err := c.nl.LinkAdd(link)
 	if err != nil {
 		return fmt.Errorf("link add failed: %w", err)
@@ -371,14 +414,71 @@ func (c LocalController) updateWireGuardInterface(pi *domain.PhysicalInterface)
 		return err
 	}
 
+	awg, ok := c.wg.(AmneziaWgRepo)
+	if !ok {
+		if !pi.AmneziaParams.IsEmpty() {
+			slog.Debug("amneziawg parameters are only written to config files", "interface", pi.Identifier)
+		}
+		return nil
+	}
+	if err := awg.ConfigureObfuscation(string(pi.Identifier), newAmneziaWgConfig(pi.AmneziaParams)); err != nil {
+		return fmt.Errorf("failed to apply amneziawg parameters: %w", err)
+	}
+
 	return nil
 }
 
+// newAmneziaWgConfig converts the parameters of the interface, empty parameters reset the device to plain WireGuard.
+func newAmneziaWgConfig(params *domain.AmneziaParams) lowlevel.AmneziaWgConfig {
+	headers := params.Headers()
+	cfg := lowlevel.AmneziaWgConfig{
+		InitPacketMagicHeader:      headers[0],
+		ResponsePacketMagicHeader:  headers[1],
+		UnderloadPacketMagicHeader: headers[2],
+		TransportPacketMagicHeader: headers[3],
+	}
+	if params != nil {
+		cfg.JunkPacketCount = uint16(params.Jc)
+		cfg.JunkPacketMinSize = uint16(params.Jmin)
+		cfg.JunkPacketMaxSize = uint16(params.Jmax)
+		cfg.InitPacketJunkSize = uint16(params.S1)
+		cfg.ResponsePacketJunkSize = uint16(params.S2)
+	}
+
+	return cfg
+}
+
+func convertAmneziaWgConfig(cfg lowlevel.AmneziaWgConfig) *domain.AmneziaParams {
+	params := &domain.AmneziaParams{
+		Jc:   int(cfg.JunkPacketCount),
+		Jmin: int(cfg.JunkPacketMinSize),
+		Jmax: int(cfg.JunkPacketMaxSize),
+		S1:   int(cfg.InitPacketJunkSize),
+		S2:   int(cfg.ResponsePacketJunkSize),
+	}
+	// the default message types are not stored, so that plain devices have empty parameters
+	headers := []*uint32{&params.H1, &params.H2, &params.H3, &params.H4}
+	values := []uint32{cfg.InitPacketMagicHeader, cfg.ResponsePacketMagicHeader, cfg.UnderloadPacketMagicHeader,
+		cfg.TransportPacketMagicHeader}
+	for i := range headers {
+		if values[i] != uint32(i+1) {
+			*headers[i] = values[i]
+		}
+	}
+	if params.IsEmpty() {
+		return nil
+	}
+
+	return params
+}
+
 func (c LocalController) DeleteInterface(_ context.Context, id domain.InterfaceIdentifier) error {
 	if err := c.deleteLowLevelInterface(id); err != nil {
 		return err
 	}
 
+	c.forgetBandwidthLimits(id) // traffic control settings are removed together with the link
+
 	return nil
 }
 
@@ -432,6 +532,10 @@ func (c LocalController) SavePeer(
 		return err
 	}
 
+	if err := c.applyBandwidthLimit(deviceId, physicalPeer); err != nil {
+		return fmt.Errorf("failed to apply bandwidth limit for peer %s: %w", id, err)
+	}
+
 	return nil
 }
 
@@ -540,6 +644,10 @@ func (c LocalController) deletePeer(deviceId domain.InterfaceIdentifier, id doma
 		return err
 	}
 
+	if err := c.removeBandwidthLimit(deviceId, id); err != nil {
+		return fmt.Errorf("failed to remove bandwidth limit for peer %s: %w", id, err)
+	}
+
 	return nil
 }
 
@@ -1012,6 +1120,837 @@ func (c LocalController) removeRouteRulesForTable(
 
 // endregion routing-related
 
+// region traffic-control-related
+
+const (
+	tcRootMajor       = 0x1    // major handle of the HTB root qdisc that shapes the download of peers
+	tcIngressMajor    = 0xffff // major handle of the ingress qdisc that polices the upload of peers
+	tcFirstClassMinor = 0x10   // minor handles below are reserved
+	tcLastClassMinor  = 0x7fff // the filter priority is derived from the minor handle and must fit into 16 bits
+	tcMinPoliceBurst  = 16 * 1500
+)
+
+// trafficControlState keeps track of the HTB classes that have been assigned to the peers of each interface.
+// The class minor handle is also used to derive the priority of the filters that belong to the peer.
+type trafficControlState struct {
+	mu    sync.Mutex
+	peers map[domain.InterfaceIdentifier]map[domain.PeerIdentifier]uint16
+}
+
+func newTrafficControlState() *trafficControlState {
+	return &trafficControlState{
+		peers: make(map[domain.InterfaceIdentifier]map[domain.PeerIdentifier]uint16),
+	}
+}
+
+// SupportsBandwidthLimits returns true, as the local controller enforces peer bandwidth limits using tc.
+func (c LocalController) SupportsBandwidthLimits() bool {
+	return true
+}
+
+// applyBandwidthLimit programs the bandwidth limit stored in the peer extras. The download of the peer (egress of
+// the WireGuard interface) is shaped by an HTB class, the upload (ingress) is policed. Traffic is matched on the
+// allowed IPs of the peer. Existing settings of the peer are replaced.
+func (c LocalController) applyBandwidthLimit(deviceId domain.InterfaceIdentifier, pp *domain.PhysicalPeer) error {
+	extras, _ := pp.GetExtras().(domain.LocalPeerExtras)
+
+	c.tc.mu.Lock()
+	defer c.tc.mu.Unlock()
+
+	peers, initialized := c.tc.peers[deviceId]
+	if !initialized && extras.UploadKbps == 0 && extras.DownloadKbps == 0 {
+		return nil // nothing to do, the interface does not use traffic control yet
+	}
+
+	link, err := c.nl.LinkByName(string(deviceId))
+	if err != nil {
+		return fmt.Errorf("failed to find physical link for %s: %w", deviceId, err)
+	}
+
+	if !initialized {
+		// settings of a previous run are unknown, so start with a clean state
+		if err := c.removeTrafficControlQdiscs(link); err != nil {
+			return err
+		}
+		peers = make(map[domain.PeerIdentifier]uint16)
+		c.tc.peers[deviceId] = peers
+	}
+
+	if err := c.removePeerTrafficControl(link, peers, pp.Identifier); err != nil {
+		return err
+	}
+
+	if extras.UploadKbps == 0 && extras.DownloadKbps == 0 {
+		return nil
+	}
+
+	minor, err := freeClassMinor(peers)
+	if err != nil {
+		return err
+	}
+	peers[pp.Identifier] = minor // register early, so that partially applied settings are cleaned up later
+
+	if extras.DownloadKbps > 0 {
+		if err := c.shapePeerDownload(link, minor, uint64(extras.DownloadKbps)*1000, pp.AllowedIPs); err != nil {
+			return err
+		}
+	}
+	if extras.UploadKbps > 0 {
+		if err := c.policePeerUpload(link, minor, uint64(extras.UploadKbps)*1000, pp.AllowedIPs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c LocalController) shapePeerDownload(link netlink.Link, minor uint16, rate uint64, cidrs []domain.Cidr) error {
+	root := netlink.NewHtb(netlink.QdiscAttrs{
+		LinkIndex: link.Attrs().Index,
+		Handle:    netlink.MakeHandle(tcRootMajor, 0),
+		Parent:    netlink.HANDLE_ROOT,
+	}) // unclassified traffic is not shaped, as no default class is set
+	if err := c.nl.QdiscReplace(root); err != nil {
+		return fmt.Errorf("failed to set up htb qdisc: %w", err)
+	}
+
+	class := netlink.NewHtbClass(netlink.ClassAttrs{
+		LinkIndex: link.Attrs().Index,
+		Parent:    netlink.MakeHandle(tcRootMajor, 0),
+		Handle:    netlink.MakeHandle(tcRootMajor, minor),
+	}, netlink.HtbClassAttrs{
+		Rate: rate,
+		Ceil: rate,
+	})
+	if err := c.nl.ClassReplace(class); err != nil {
+		return fmt.Errorf("failed to set up htb class: %w", err)
+	}
+
+	for _, cidr := range cidrs {
+		filter := newU32Filter(link, netlink.MakeHandle(tcRootMajor, 0), minor, cidr, false)
+		filter.ClassId = netlink.MakeHandle(tcRootMajor, minor)
+		if err := c.nl.FilterAdd(filter); err != nil {
+			return fmt.Errorf("failed to add download filter for %s: %w", cidr, err)
+		}
+	}
+
+	return nil
+}
+
+func (c LocalController) policePeerUpload(link netlink.Link, minor uint16, rate uint64, cidrs []domain.Cidr) error {
+	ingress := &netlink.Ingress{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(tcIngressMajor, 0),
+			Parent:    netlink.HANDLE_INGRESS,
+		},
+	}
+	if err := c.nl.QdiscReplace(ingress); err != nil {
+		return fmt.Errorf("failed to set up ingress qdisc: %w", err)
+	}
+
+	rateBytes := uint32(min(rate/8, math.MaxUint32))
+	for _, cidr := range cidrs {
+		police := netlink.NewPoliceAction()
+		police.Rate = rateBytes
+		police.Burst = max(rateBytes/10, tcMinPoliceBurst) // allow bursts of about 100ms
+		police.ExceedAction = netlink.TC_POLICE_SHOT
+		police.NotExceedAction = netlink.TC_POLICE_OK
+
+		filter := newU32Filter(link, netlink.MakeHandle(tcIngressMajor, 0), minor, cidr, true)
+		filter.Actions = []netlink.Action{police}
+		if err := c.nl.FilterAdd(filter); err != nil {
+			return fmt.Errorf("failed to add upload filter for %s: %w", cidr, err)
+		}
+	}
+
+	return nil
+}
+
+// removeBandwidthLimit removes all traffic control settings of the given peer.
+func (c LocalController) removeBandwidthLimit(deviceId domain.InterfaceIdentifier, id domain.PeerIdentifier) error {
+	c.tc.mu.Lock()
+	defer c.tc.mu.Unlock()
+
+	peers, ok := c.tc.peers[deviceId]
+	if !ok {
+		return nil
+	}
+	if _, ok := peers[id]; !ok {
+		return nil
+	}
+
+	link, err := c.nl.LinkByName(string(deviceId))
+	if err != nil {
+		var linkNotFoundError netlink.LinkNotFoundError
+		if errors.As(err, &linkNotFoundError) {
+			delete(peers, id) // the settings are gone together with the link
+			return nil
+		}
+		return fmt.Errorf("failed to find physical link for %s: %w", deviceId, err)
+	}
+
+	return c.removePeerTrafficControl(link, peers, id)
+}
+
+func (c LocalController) forgetBandwidthLimits(deviceId domain.InterfaceIdentifier) {
+	c.tc.mu.Lock()
+	defer c.tc.mu.Unlock()
+
+	delete(c.tc.peers, deviceId)
+}
+
+func (c LocalController) removePeerTrafficControl(
+	link netlink.Link,
+	peers map[domain.PeerIdentifier]uint16,
+	id domain.PeerIdentifier,
+) error {
+	minor, ok := peers[id]
+	if !ok {
+		return nil
+	}
+
+	// deleting a filter without handle removes all filters with the same priority
+	for _, parent := range []uint32{netlink.MakeHandle(tcRootMajor, 0), netlink.MakeHandle(tcIngressMajor, 0)} {
+		for _, v4 := range []bool{true, false} {
+			priority, protocol := u32FilterPriority(minor, v4)
+			err := c.nl.FilterDel(&netlink.U32{
+				FilterAttrs: netlink.FilterAttrs{
+					LinkIndex: link.Attrs().Index,
+					Parent:    parent,
+					Priority:  priority,
+					Protocol:  protocol,
+				},
+			})
+			if err != nil && !isTrafficControlNotFound(err) {
+				return fmt.Errorf("failed to remove filters of peer %s: %w", id, err)
+			}
+		}
+	}
+
+	err := c.nl.ClassDel(&netlink.HtbClass{
+		ClassAttrs: netlink.ClassAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.MakeHandle(tcRootMajor, 0),
+			Handle:    netlink.MakeHandle(tcRootMajor, minor),
+		},
+	})
+	if err != nil && !isTrafficControlNotFound(err) {
+		return fmt.Errorf("failed to remove htb class of peer %s: %w", id, err)
+	}
+
+	delete(peers, id)
+
+	return nil
+}
+
+func (c LocalController) removeTrafficControlQdiscs(link netlink.Link) error {
+	qdiscs, err := c.nl.QdiscList(link)
+	if err != nil {
+		return fmt.Errorf("failed to list qdiscs of %s: %w", link.Attrs().Name, err)
+	}
+
+	for _, qdisc := range qdiscs {
+		attrs := qdisc.Attrs()
+		_, isHtb := qdisc.(*netlink.Htb)
+		_, isIngress := qdisc.(*netlink.Ingress)
+		ownHtb := isHtb && attrs.Parent == netlink.HANDLE_ROOT && attrs.Handle == netlink.MakeHandle(tcRootMajor, 0)
+		if isIngress {
+			ownIngress, err := c.removeStaleIngressFilters(link)
+			if err != nil {
+				return err
+			}
+			if !ownIngress {
+				continue
+			}
+		} else if !ownHtb {
+			continue
+		}
+
+		if err := c.nl.QdiscDel(qdisc); err != nil && !isTrafficControlNotFound(err) {
+			return fmt.Errorf("failed to remove stale %s qdisc of %s: %w", qdisc.Type(), link.Attrs().Name, err)
+		}
+	}
+
+	return nil
+}
+
+// removeStaleIngressFilters removes the upload filters of a previous run from the ingress qdisc. The handle of the
+// ingress qdisc is always ffff:, so it does not tell whether the qdisc was created by wg-portal. Only if all filters
+// of the qdisc belong to wg-portal, true is returned and the qdisc may be removed as well.
+func (c LocalController) removeStaleIngressFilters(link netlink.Link) (bool, error) {
+	filters, err := c.nl.FilterList(link, netlink.MakeHandle(tcIngressMajor, 0))
+	if err != nil {
+		return false, fmt.Errorf("failed to list ingress filters of %s: %w", link.Attrs().Name, err)
+	}
+
+	own, foreign := splitOwnIngressFilters(filters)
+	for _, filter := range own {
+		// deleting a filter without handle removes all filters with the same priority
+		err := c.nl.FilterDel(&netlink.U32{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: link.Attrs().Index,
+				Parent:    netlink.MakeHandle(tcIngressMajor, 0),
+				Priority:  filter.Priority,
+				Protocol:  filter.Protocol,
+			},
+		})
+		if err != nil && !isTrafficControlNotFound(err) {
+			return false, fmt.Errorf("failed to remove stale ingress filters of %s: %w", link.Attrs().Name, err)
+		}
+	}
+
+	return len(own) > 0 && foreign == 0, nil
+}
+
+// splitOwnIngressFilters returns the distinct priorities of the ingress filters that were installed by wg-portal
+// and the number of other filters. Filters of wg-portal are u32 filters with a priority and protocol derived from
+// a class minor handle, see u32FilterPriority.
+func splitOwnIngressFilters(filters []netlink.Filter) (own []netlink.FilterAttrs, foreign int) {
+	seen := make(map[uint16]struct{})
+	for _, filter := range filters {
+		attrs := filter.Attrs()
+		_, isU32 := filter.(*netlink.U32)
+		minor, v4 := attrs.Priority/2, attrs.Priority%2 == 0
+		priority, protocol := u32FilterPriority(minor, v4)
+		if !isU32 || minor < tcFirstClassMinor || minor > tcLastClassMinor ||
+			priority != attrs.Priority || protocol != attrs.Protocol {
+			foreign++
+			continue
+		}
+
+		if _, ok := seen[attrs.Priority]; !ok {
+			seen[attrs.Priority] = struct{}{}
+			own = append(own, *attrs)
+		}
+	}
+
+	return own, foreign
+}
+
+func freeClassMinor(peers map[domain.PeerIdentifier]uint16) (uint16, error) {
+	used := make(map[uint16]struct{}, len(peers))
+	for _, minor := range peers {
+		used[minor] = struct{}{}
+	}
+
+	for minor := uint16(tcFirstClassMinor); minor <= tcLastClassMinor; minor++ {
+		if _, ok := used[minor]; !ok {
+			return minor, nil
+		}
+	}
+
+	return 0, errors.New("no free traffic control class available")
+}
+
+// u32FilterPriority returns the filter priority and protocol for the given class minor handle.
+// Each peer uses one priority per address family, so that all filters of a peer can be removed at once.
+func u32FilterPriority(minor uint16, v4 bool) (priority uint16, protocol uint16) {
+	if v4 {
+		return minor * 2, unix.ETH_P_IP
+	}
+	return minor*2 + 1, unix.ETH_P_IPV6
+}
+
+// newU32Filter creates a u32 filter that matches packets with the given source or destination network.
+func newU32Filter(link netlink.Link, parent uint32, minor uint16, cidr domain.Cidr, matchSource bool) *netlink.U32 {
+	priority, protocol := u32FilterPriority(minor, cidr.IsV4())
+
+	return &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    parent,
+			Priority:  priority,
+			Protocol:  protocol,
+		},
+		Sel: &netlink.TcU32Sel{
+			Flags: nl.TC_U32_TERMINAL,
+			Keys:  u32AddressKeys(cidr, matchSource),
+		},
+	}
+}
+
+// u32AddressKeys returns the u32 match keys for the source or destination address of an IPv4 or IPv6 header.
+// The values of the keys are in host byte order, the netlink library takes care of the conversion.
+func u32AddressKeys(cidr domain.Cidr, matchSource bool) []netlink.TcU32Key {
+	prefix := cidr.Prefix().Masked()
+	addr := prefix.Addr().AsSlice()
+	bits := prefix.Bits()
+
+	var offset int32
+	switch {
+	case prefix.Addr().Is4() && matchSource:
+		offset = 12
+	case prefix.Addr().Is4():
+		offset = 16
+	case matchSource:
+		offset = 8
+	default:
+		offset = 24
+	}
+
+	keys := make([]netlink.TcU32Key, 0, len(addr)/4)
+	for i := 0; i < len(addr); i += 4 {
+		wordBits := min(max(bits-i*8, 0), 32)
+		if wordBits == 0 {
+			break
+		}
+
+		keys = append(keys, netlink.TcU32Key{
+			Mask: ^uint32(0) << (32 - wordBits),
+			Val:  binary.BigEndian.Uint32(addr[i : i+4]),
+			Off:  offset + int32(i),
+		})
+	}
+
+	if len(keys) == 0 {
+		keys = append(keys, netlink.TcU32Key{}) // match all, e.g. for default routes
+	}
+
+	return keys
+}
+
+func isTrafficControlNotFound(err error) bool {
+	// the kernel reports EINVAL if the parent qdisc is missing, e.g. after the link has been re-created
+	return errors.Is(err, unix.ENOENT) || errors.Is(err, unix.EINVAL)
+}
+
+// endregion traffic-control-related
+
+// region firewall-related
+
+const (
+	// nftTableName is the dedicated nftables table that contains the forwarding chains of all interfaces
+	nftTableName = "wg-portal"
+	// nftNatTableName is the dedicated nftables table that contains the masquerading chains of all interfaces
+	nftNatTableName = "wg-portal-nat"
+)
+
+// SetAccessRules replaces the forwarding chain of the interface in the wg-portal nftables table.
+// The table, chain and all rules are written in a single batch, so the update is applied atomically.
+func (c LocalController) SetAccessRules(_ context.Context, rules domain.InterfaceAccessRules) error {
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to open nftables connection: %w", err)
+	}
+
+	table := conn.AddTable(&nftables.Table{Family: nftables.TableFamilyINet, Name: nftTableName})
+	policy := nftables.ChainPolicyAccept
+	chain := conn.AddChain(&nftables.Chain{
+		Name:     string(rules.Interface),
+		Table:    table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookForward,
+		Priority: nftables.ChainPriorityFilter,
+		Policy:   &policy,
+	})
+	conn.FlushChain(chain)
+
+	for _, exprs := range nftAccessRuleExprs(rules) {
+		conn.AddRule(&nftables.Rule{Table: table, Chain: chain, Exprs: exprs})
+	}
+
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to apply nftables rules for %s: %w", rules.Interface, err)
+	}
+
+	return nil
+}
+
+// RemoveAccessRules removes the forwarding chain of the interface from the wg-portal nftables table.
+func (c LocalController) RemoveAccessRules(_ context.Context, id domain.InterfaceIdentifier) error {
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to open nftables connection: %w", err)
+	}
+
+	chains, err := conn.ListChainsOfTableFamily(nftables.TableFamilyINet)
+	if err != nil {
+		return fmt.Errorf("failed to list nftables chains: %w", err)
+	}
+
+	for _, chain := range chains {
+		if chain.Table.Name != nftTableName || chain.Name != string(id) {
+			continue
+		}
+
+		conn.FlushChain(chain)
+		conn.DelChain(chain)
+		if err := conn.Flush(); err != nil {
+			return fmt.Errorf("failed to remove nftables rules for %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// nftAccessRuleExprs builds the rules of the forwarding chain of an interface. Traffic that does not enter
+// through the interface is ignored, established connections are always accepted.
+// Afterward, the peer isolation, the peer rules, the interface rules and the default action are evaluated in order.
+// The isolation comes first, so that broad allow rules like 0.0.0.0/0 do not open traffic between the peers.
+func nftAccessRuleExprs(rules domain.InterfaceAccessRules) [][]expr.Any {
+	iface := nftInterfaceName(rules.Interface)
+
+	result := [][]expr.Any{
+		{
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpNeq, Register: 1, Data: iface},
+			&expr.Verdict{Kind: expr.VerdictReturn},
+		},
+		append(nftEstablishedExprs(), &expr.Verdict{Kind: expr.VerdictAccept}),
+	}
+
+	if rules.IsolatePeers {
+		result = append(result, []expr.Any{
+			&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: iface},
+			&expr.Verdict{Kind: expr.VerdictDrop},
+		})
+	}
+
+	for _, peer := range rules.Peers {
+		for i := range peer.Sources {
+			for _, rule := range peer.Rules {
+				result = append(result, nftAclRuleExprs(&peer.Sources[i], rule)...)
+			}
+		}
+	}
+
+	for _, rule := range rules.Rules {
+		result = append(result, nftAclRuleExprs(nil, rule)...)
+	}
+
+	if rules.DefaultAction == domain.AclActionDeny {
+		result = append(result, []expr.Any{&expr.Verdict{Kind: expr.VerdictDrop}})
+	}
+
+	return result
+}
+
+// nftAclRuleExprs translates a single ACL rule. If the source is nil, the rule matches all sources.
+// One rule is returned per address family, an empty result means that source and destination do not share a family.
+func nftAclRuleExprs(source *domain.Cidr, rule domain.AclRule) [][]expr.Any {
+	destination, hasDestination := rule.DestinationCidr()
+
+	var families []byte
+	switch {
+	case source != nil && hasDestination && source.IsV4() != destination.IsV4():
+		return nil
+	case source != nil:
+		families = []byte{nftFamily(*source)}
+	case hasDestination:
+		families = []byte{nftFamily(destination)}
+	case rule.Protocol == domain.AclProtocolIcmp: // icmp and icmpv6 use different protocol numbers
+		families = []byte{unix.NFPROTO_IPV4, unix.NFPROTO_IPV6}
+	default:
+		families = []byte{unix.NFPROTO_UNSPEC} // no address matched, so the rule applies to both families
+	}
+
+	result := make([][]expr.Any, 0, len(families))
+	for _, family := range families {
+		var exprs []expr.Any
+		if family != unix.NFPROTO_UNSPEC {
+			exprs = append(exprs,
+				&expr.Meta{Key: expr.MetaKeyNFPROTO, Register: 1},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{family}})
+		}
+		if source != nil {
+			exprs = append(exprs, nftAddressExprs(*source, true)...)
+		}
+		if hasDestination {
+			exprs = append(exprs, nftAddressExprs(destination, false)...)
+		}
+		exprs = append(exprs, nftProtocolExprs(rule, family)...)
+
+		verdict := expr.VerdictAccept
+		if rule.Action == domain.AclActionDeny {
+			verdict = expr.VerdictDrop
+		}
+		exprs = append(exprs, &expr.Verdict{Kind: verdict})
+
+		result = append(result, exprs)
+	}
+
+	return result
+}
+
+func nftAddressExprs(cidr domain.Cidr, matchSource bool) []expr.Any {
+	prefix := cidr.Prefix().Masked()
+	addr := prefix.Addr().AsSlice()
+
+	var offset uint32
+	switch {
+	case prefix.Addr().Is4() && matchSource:
+		offset = 12
+	case prefix.Addr().Is4():
+		offset = 16
+	case matchSource:
+		offset = 8
+	default:
+		offset = 24
+	}
+
+	if prefix.Bits() == 0 {
+		return nil // the whole address family is matched by the protocol match
+	}
+
+	exprs := []expr.Any{
+		&expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseNetworkHeader,
+			Offset:       offset,
+			Len:          uint32(len(addr)),
+		},
+	}
+	if prefix.Bits() < len(addr)*8 {
+		mask := net.CIDRMask(prefix.Bits(), len(addr)*8)
+		exprs = append(exprs, &expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            uint32(len(addr)),
+			Mask:           mask,
+			Xor:            make([]byte, len(addr)),
+		})
+	}
+	exprs = append(exprs, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: addr})
+
+	return exprs
+}
+
+func nftProtocolExprs(rule domain.AclRule, family byte) []expr.Any {
+	var protocol byte
+	switch {
+	case rule.Protocol == domain.AclProtocolTcp:
+		protocol = unix.IPPROTO_TCP
+	case rule.Protocol == domain.AclProtocolUdp:
+		protocol = unix.IPPROTO_UDP
+	case rule.Protocol == domain.AclProtocolIcmp && family == unix.NFPROTO_IPV6:
+		protocol = unix.IPPROTO_ICMPV6
+	case rule.Protocol == domain.AclProtocolIcmp:
+		protocol = unix.IPPROTO_ICMP
+	default:
+		return nil
+	}
+
+	exprs := []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{protocol}},
+	}
+
+	from, to, err := rule.PortRange()
+	if err != nil || from == 0 {
+		return exprs
+	}
+
+	exprs = append(exprs, &expr.Payload{
+		DestRegister: 1,
+		Base:         expr.PayloadBaseTransportHeader,
+		Offset:       2, // destination port
+		Len:          2,
+	})
+	if from == to {
+		exprs = append(exprs, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.BigEndian.PutUint16(from)})
+	} else {
+		exprs = append(exprs, &expr.Range{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			FromData: binaryutil.BigEndian.PutUint16(from),
+			ToData:   binaryutil.BigEndian.PutUint16(to),
+		})
+	}
+
+	return exprs
+}
+
+// SetNatRules replaces the masquerading and forwarding chains of the interface in the wg-portal-nat nftables table.
+// Like the access rules, both chains are written in a single batch.
+func (c LocalController) SetNatRules(_ context.Context, rules domain.InterfaceNatRules) error {
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to open nftables connection: %w", err)
+	}
+
+	table := conn.AddTable(&nftables.Table{Family: nftables.TableFamilyINet, Name: nftNatTableName})
+	policy := nftables.ChainPolicyAccept
+	postrouting := conn.AddChain(&nftables.Chain{
+		Name:     string(rules.Interface) + "-postrouting",
+		Table:    table,
+		Type:     nftables.ChainTypeNAT,
+		Hooknum:  nftables.ChainHookPostrouting,
+		Priority: nftables.ChainPriorityNATSource,
+		Policy:   &policy,
+	})
+	forward := conn.AddChain(&nftables.Chain{
+		Name:     string(rules.Interface) + "-forward",
+		Table:    table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookForward,
+		Priority: nftables.ChainPriorityFilter,
+		Policy:   &policy,
+	})
+	conn.FlushChain(postrouting)
+	conn.FlushChain(forward)
+
+	masqueradeRules, forwardRules := nftNatRuleExprs(rules)
+	for _, exprs := range masqueradeRules {
+		conn.AddRule(&nftables.Rule{Table: table, Chain: postrouting, Exprs: exprs})
+	}
+	for _, exprs := range forwardRules {
+		conn.AddRule(&nftables.Rule{Table: table, Chain: forward, Exprs: exprs})
+	}
+
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to apply nftables nat rules for %s: %w", rules.Interface, err)
+	}
+
+	if rules.AllowForwarding {
+		// an accept verdict only ends the evaluation of the own chain, a drop policy of another table still applies
+		if chains, err := conn.ListChains(); err == nil {
+			for _, chain := range nftForwardDropChains(chains) {
+				slog.Warn("forward chain with drop policy found, forwarded peer traffic must be accepted there as well",
+					"interface", rules.Interface, "chain", chain)
+			}
+		}
+	}
+
+	return nil
+}
+
+// nftForwardDropChains returns the names of all forward chains of other tables that drop packets by default,
+// for example the FORWARD chain of Docker or ufw when they use iptables-nft.
+func nftForwardDropChains(chains []*nftables.Chain) []string {
+	var names []string
+	for _, chain := range chains {
+		if chain.Table == nil || chain.Table.Name == nftNatTableName || chain.Hooknum == nil ||
+			*chain.Hooknum != *nftables.ChainHookForward || chain.Policy == nil ||
+			*chain.Policy != nftables.ChainPolicyDrop {
+			continue
+		}
+		names = append(names, chain.Table.Name+" "+chain.Name)
+	}
+
+	return names
+}
+
+// RemoveNatRules removes the masquerading and forwarding chains of the interface from the wg-portal-nat nftables table.
+func (c LocalController) RemoveNatRules(_ context.Context, id domain.InterfaceIdentifier) error {
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("failed to open nftables connection: %w", err)
+	}
+
+	chains, err := conn.ListChainsOfTableFamily(nftables.TableFamilyINet)
+	if err != nil {
+		return fmt.Errorf("failed to list nftables chains: %w", err)
+	}
+
+	removed := false
+	for _, chain := range chains {
+		if chain.Table.Name != nftNatTableName {
+			continue
+		}
+		if chain.Name != string(id)+"-postrouting" && chain.Name != string(id)+"-forward" {
+			continue
+		}
+
+		conn.FlushChain(chain)
+		conn.DelChain(chain)
+		removed = true
+	}
+
+	if !removed {
+		return nil
+	}
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to remove nftables nat rules for %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// nftNatRuleExprs builds the rules of the postrouting and the forwarding chain of an interface.
+// Peer traffic that leaves through the outbound interface is masqueraded, forwarding accepts new connections
+// from the peers and the replies to them. If only one address family is selected, the other one is not touched.
+func nftNatRuleExprs(rules domain.InterfaceNatRules) (masquerade, forward [][]expr.Any) {
+	if rules.IsEmpty() {
+		return nil, nil
+	}
+
+	iface := nftInterfaceName(rules.Interface)
+	outbound := nftInterfaceName(domain.InterfaceIdentifier(rules.OutboundInterface))
+
+	var families []byte
+	switch {
+	case rules.IPv4 && rules.IPv6:
+		families = []byte{unix.NFPROTO_UNSPEC}
+	case rules.IPv4:
+		families = []byte{unix.NFPROTO_IPV4}
+	default:
+		families = []byte{unix.NFPROTO_IPV6}
+	}
+
+	for _, family := range families {
+		var match []expr.Any
+		if family != unix.NFPROTO_UNSPEC {
+			match = append(match,
+				&expr.Meta{Key: expr.MetaKeyNFPROTO, Register: 1},
+				&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{family}})
+		}
+		fromPeers := append(slices.Clone(match),
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: iface},
+			&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: outbound})
+		toPeers := append(slices.Clone(match),
+			&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: outbound},
+			&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: iface})
+
+		if rules.Masquerade {
+			masquerade = append(masquerade, append(slices.Clone(fromPeers), &expr.Masq{}))
+		}
+		if rules.AllowForwarding {
+			forward = append(forward,
+				append(fromPeers, &expr.Verdict{Kind: expr.VerdictAccept}),
+				append(append(toPeers, nftEstablishedExprs()...), &expr.Verdict{Kind: expr.VerdictAccept}))
+		}
+	}
+
+	return masquerade, forward
+}
+
+// nftEstablishedExprs matches packets of established and related connections.
+func nftEstablishedExprs() []expr.Any {
+	return []expr.Any{
+		&expr.Ct{Register: 1, Key: expr.CtKeySTATE},
+		&expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            4,
+			Mask:           binaryutil.NativeEndian.PutUint32(expr.CtStateBitESTABLISHED | expr.CtStateBitRELATED),
+			Xor:            binaryutil.NativeEndian.PutUint32(0),
+		},
+		&expr.Cmp{Op: expr.CmpOpNeq, Register: 1, Data: binaryutil.NativeEndian.PutUint32(0)},
+	}
+}
+
+func nftFamily(cidr domain.Cidr) byte {
+	if cidr.IsV4() {
+		return unix.NFPROTO_IPV4
+	}
+	return unix.NFPROTO_IPV6
+}
+
+// nftInterfaceName returns the interface name in the zero-padded format that is used by the kernel.
+func nftInterfaceName(id domain.InterfaceIdentifier) []byte {
+	name := make([]byte, unix.IFNAMSIZ)
+	copy(name, id)
+	return name
+}
+
+// endregion firewall-related
+
 // region statistics-related
 
 func (c LocalController) PingAddresses(
@@ -0,0 +1,186 @@
+package wgcontroller
+
+import (
+	"testing"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/h44z/wg-portal/internal/domain"
+	"github.com/h44z/wg-portal/internal/lowlevel"
+)
+
+func TestU32AddressKeys(t *testing.T) {
+	v4, _ := domain.CidrFromString("10.11.12.13/32")
+	assert.Equal(t, []netlink.TcU32Key{{Mask: 0xffffffff, Val: 0x0a0b0c0d, Off: 16}}, u32AddressKeys(v4, false))
+	assert.Equal(t, []netlink.TcU32Key{{Mask: 0xffffffff, Val: 0x0a0b0c0d, Off: 12}}, u32AddressKeys(v4, true))
+
+	v4Net, _ := domain.CidrFromString("192.168.5.1/20")
+	assert.Equal(t, []netlink.TcU32Key{{Mask: 0xfffff000, Val: 0xc0a80000, Off: 16}}, u32AddressKeys(v4Net, false))
+
+	v6, _ := domain.CidrFromString("fd00:1:2:3::/56")
+	keys := u32AddressKeys(v6, true)
+	require.Len(t, keys, 2)
+	assert.Equal(t, netlink.TcU32Key{Mask: 0xffffffff, Val: 0xfd000001, Off: 8}, keys[0])
+	assert.Equal(t, netlink.TcU32Key{Mask: 0xffffff00, Val: 0x00020000, Off: 12}, keys[1])
+
+	defaultRoute, _ := domain.CidrFromString("0.0.0.0/0")
+	assert.Equal(t, []netlink.TcU32Key{{}}, u32AddressKeys(defaultRoute, false))
+}
+
+func TestFreeClassMinor(t *testing.T) {
+	minor, err := freeClassMinor(map[domain.PeerIdentifier]uint16{"a": tcFirstClassMinor, "b": tcFirstClassMinor + 2})
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tcFirstClassMinor+1), minor)
+
+	v4Prio, _ := u32FilterPriority(tcLastClassMinor, true)
+	v6Prio, _ := u32FilterPriority(tcLastClassMinor, false)
+	assert.NotEqual(t, v4Prio, v6Prio)
+	assert.NotZero(t, v6Prio, "priorities must not overflow")
+}
+
+func TestSplitOwnIngressFilters(t *testing.T) {
+	v4Prio, v4Proto := u32FilterPriority(tcFirstClassMinor, true)
+	v6Prio, v6Proto := u32FilterPriority(tcFirstClassMinor, false)
+	ownV4 := &netlink.U32{FilterAttrs: netlink.FilterAttrs{Priority: v4Prio, Protocol: v4Proto}}
+	ownV4Hashtable := &netlink.U32{FilterAttrs: netlink.FilterAttrs{Priority: v4Prio, Protocol: v4Proto}}
+	ownV6 := &netlink.U32{FilterAttrs: netlink.FilterAttrs{Priority: v6Prio, Protocol: v6Proto}}
+
+	own, foreign := splitOwnIngressFilters([]netlink.Filter{ownV4, ownV4Hashtable, ownV6})
+	assert.Len(t, own, 2)
+	assert.Zero(t, foreign)
+
+	// filters of other tools, e.g. with a low priority or another protocol, are not touched
+	adminPrio := &netlink.U32{FilterAttrs: netlink.FilterAttrs{Priority: 1, Protocol: unix.ETH_P_ALL}}
+	adminProto := &netlink.U32{FilterAttrs: netlink.FilterAttrs{Priority: v4Prio, Protocol: unix.ETH_P_ALL}}
+	adminBpf := &netlink.BpfFilter{FilterAttrs: netlink.FilterAttrs{Priority: v6Prio, Protocol: v6Proto}}
+	own, foreign = splitOwnIngressFilters([]netlink.Filter{ownV4, adminPrio, adminProto, adminBpf})
+	require.Len(t, own, 1)
+	assert.Equal(t, v4Prio, own[0].Priority)
+	assert.Equal(t, 3, foreign)
+
+	own, foreign = splitOwnIngressFilters(nil)
+	assert.Empty(t, own)
+	assert.Zero(t, foreign)
+}
+
+func TestNftAddressExprs(t *testing.T) {
+	v4, _ := domain.CidrFromString("10.11.12.13/32")
+	assert.Equal(t, []expr.Any{
+		&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: 12, Len: 4},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{10, 11, 12, 13}},
+	}, nftAddressExprs(v4, true))
+
+	v6Net, _ := domain.CidrFromString("fd00::1/64")
+	exprs := nftAddressExprs(v6Net, false)
+	require.Len(t, exprs, 3)
+	assert.Equal(t, uint32(24), exprs[0].(*expr.Payload).Offset)
+	assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0},
+		exprs[1].(*expr.Bitwise).Mask)
+	assert.Equal(t, []byte{0xfd, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, exprs[2].(*expr.Cmp).Data)
+
+	defaultRoute, _ := domain.CidrFromString("0.0.0.0/0")
+	assert.Empty(t, nftAddressExprs(defaultRoute, false))
+}
+
+func TestNftAclRuleExprs(t *testing.T) {
+	v4Source, _ := domain.CidrFromString("10.0.0.2/32")
+
+	// families of source and destination do not match
+	assert.Empty(t, nftAclRuleExprs(&v4Source, domain.AclRule{Action: domain.AclActionAllow, Destination: "fd00::/8"}))
+
+	// icmp without addresses needs one rule per family
+	icmp := nftAclRuleExprs(nil, domain.AclRule{Action: domain.AclActionDeny, Protocol: domain.AclProtocolIcmp})
+	require.Len(t, icmp, 2)
+	assert.Equal(t, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{unix.IPPROTO_ICMPV6}}, icmp[1][3])
+	assert.Equal(t, &expr.Verdict{Kind: expr.VerdictDrop}, icmp[1][len(icmp[1])-1])
+
+	portRange := nftAclRuleExprs(&v4Source, domain.AclRule{
+		Action:      domain.AclActionAllow,
+		Destination: "192.168.1.0/24",
+		Protocol:    domain.AclProtocolTcp,
+		Ports:       "8000-8100",
+	})
+	require.Len(t, portRange, 1)
+	rule := portRange[0]
+	assert.Equal(t, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{unix.NFPROTO_IPV4}}, rule[1])
+	assert.Equal(t, &expr.Range{Op: expr.CmpOpEq, Register: 1, FromData: []byte{0x1f, 0x40}, ToData: []byte{0x1f, 0xa4}},
+		rule[len(rule)-2])
+	assert.Equal(t, &expr.Verdict{Kind: expr.VerdictAccept}, rule[len(rule)-1])
+}
+
+func TestNftAccessRuleExprs(t *testing.T) {
+	rules := nftAccessRuleExprs(domain.InterfaceAccessRules{
+		Interface:     "wg0",
+		IsolatePeers:  true,
+		DefaultAction: domain.AclActionDeny,
+		Rules:         []domain.AclRule{{Action: domain.AclActionAllow, Destination: "0.0.0.0/0"}},
+	})
+
+	// interface filter, established connections, isolation, interface rule and default action
+	require.Len(t, rules, 5)
+	assert.Equal(t, &expr.Verdict{Kind: expr.VerdictReturn}, rules[0][2])
+	assert.Equal(t, []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: nftInterfaceName("wg0")},
+		&expr.Verdict{Kind: expr.VerdictDrop},
+	}, rules[2], "isolation is evaluated before the allow rules")
+	assert.Equal(t, &expr.Verdict{Kind: expr.VerdictAccept}, rules[3][len(rules[3])-1])
+	assert.Equal(t, []expr.Any{&expr.Verdict{Kind: expr.VerdictDrop}}, rules[4])
+}
+
+func TestNftNatRuleExprs(t *testing.T) {
+	masquerade, forward := nftNatRuleExprs(domain.InterfaceNatRules{
+		Interface: "wg0",
+		NatPolicy: domain.NatPolicy{OutboundInterface: "eth0", Masquerade: true, AllowForwarding: true, IPv4: true},
+	})
+
+	// the masquerading rule only matches IPv4 traffic from wg0 to eth0
+	require.Len(t, masquerade, 1)
+	assert.Equal(t, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{unix.NFPROTO_IPV4}}, masquerade[0][1])
+	assert.Equal(t, &expr.Masq{}, masquerade[0][len(masquerade[0])-1])
+
+	// outgoing connections and the replies to them
+	require.Len(t, forward, 2)
+	assert.Len(t, forward[0], 7)
+	assert.Equal(t, &expr.Ct{Register: 1, Key: expr.CtKeySTATE}, forward[1][6])
+
+	masquerade, forward = nftNatRuleExprs(domain.InterfaceNatRules{
+		Interface: "wg0",
+		NatPolicy: domain.NatPolicy{OutboundInterface: "eth0", Masquerade: true, IPv4: true, IPv6: true},
+	})
+	require.Len(t, masquerade, 1)
+	assert.Equal(t, &expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1}, masquerade[0][0])
+	assert.Empty(t, forward)
+}
+
+func TestNftForwardDropChains(t *testing.T) {
+	drop := nftables.ChainPolicyDrop
+	accept := nftables.ChainPolicyAccept
+	chains := []*nftables.Chain{
+		{Name: "FORWARD", Table: &nftables.Table{Name: "filter"}, Hooknum: nftables.ChainHookForward, Policy: &drop},
+		{Name: "INPUT", Table: &nftables.Table{Name: "filter"}, Hooknum: nftables.ChainHookInput, Policy: &drop},
+		{Name: "forward", Table: &nftables.Table{Name: "firewall"}, Hooknum: nftables.ChainHookForward, Policy: &accept},
+		{Name: "DOCKER-USER", Table: &nftables.Table{Name: "filter"}}, // regular chain without hook
+		{Name: "wg0-forward", Table: &nftables.Table{Name: nftNatTableName}, Hooknum: nftables.ChainHookForward,
+			Policy: &drop},
+	}
+
+	assert.Equal(t, []string{"filter FORWARD"}, nftForwardDropChains(chains))
+}
+
+func TestAmneziaWgConfigConversion(t *testing.T) {
+	cfg := newAmneziaWgConfig(nil)
+	assert.Equal(t, lowlevel.AmneziaWgConfig{InitPacketMagicHeader: 1, ResponsePacketMagicHeader: 2,
+		UnderloadPacketMagicHeader: 3, TransportPacketMagicHeader: 4}, cfg)
+	assert.Nil(t, convertAmneziaWgConfig(cfg), "plain devices have no parameters")
+
+	params := &domain.AmneziaParams{Jc: 4, Jmin: 8, Jmax: 80, S1: 15, S2: 20, H1: 5, H3: 7}
+	cfg = newAmneziaWgConfig(params)
+	assert.Equal(t, uint32(2), cfg.ResponsePacketMagicHeader)
+	assert.Equal(t, params, convertAmneziaWgConfig(cfg))
+}
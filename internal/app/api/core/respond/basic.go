@@ -92,6 +92,43 @@ func AttachmentReader(
 	Reader(w, code, contentType, contentLength, data)
 }
 
+// AttachmentWriter streams an attachment of unknown length. The headers and the status code are only sent with
+// the first write, so errors that occur before any data was produced can still be answered with an error response.
+type AttachmentWriter struct {
+	w           http.ResponseWriter
+	code        int
+	filename    string
+	contentType string
+	started     bool
+}
+
+// NewAttachmentWriter creates a new AttachmentWriter for the given response writer.
+func NewAttachmentWriter(w http.ResponseWriter, code int, filename, contentType string) *AttachmentWriter {
+	return &AttachmentWriter{
+		w:           w,
+		code:        code,
+		filename:    filename,
+		contentType: contentType,
+	}
+}
+
+// Write sends the attachment headers on the first call and writes the data to the response.
+func (a *AttachmentWriter) Write(p []byte) (int, error) {
+	if !a.started {
+		a.started = true
+		a.w.Header().Set("Content-Disposition", "attachment; filename="+a.filename)
+		a.w.Header().Set("Content-Type", a.contentType)
+		a.w.WriteHeader(a.code)
+	}
+
+	return a.w.Write(p)
+}
+
+// Started returns true if the headers have already been sent.
+func (a *AttachmentWriter) Started() bool {
+	return a.started
+}
+
 // Redirect writes a response with the given status code and redirects to the given URL.
 // The redirect url will always be an absolute URL. If the given URL is relative,
 // the original request URL is used as the base.
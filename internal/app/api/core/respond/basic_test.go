@@ -233,6 +233,41 @@ func TestAttachmentReader(t *testing.T) {
 	}
 }
 
+func TestAttachmentWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	aw := NewAttachmentWriter(rec, http.StatusOK, "example.zip", "application/zip")
+	if aw.Started() {
+		t.Errorf("expected writer to not be started before the first write")
+	}
+
+	_, _ = aw.Write([]byte("Hello, "))
+	_, _ = aw.Write([]byte("World!"))
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	if !aw.Started() {
+		t.Errorf("expected writer to be started after the first write")
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, res.StatusCode)
+	}
+
+	if contentType := res.Header.Get("Content-Type"); contentType != "application/zip" {
+		t.Errorf("expected content type %s, got %s", "application/zip", contentType)
+	}
+
+	if contentDisposition := res.Header.Get("Content-Disposition"); contentDisposition != "attachment; filename=example.zip" {
+		t.Errorf("expected content disposition %s, got %s", "attachment; filename=example.zip", contentDisposition)
+	}
+
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "Hello, World!" {
+		t.Errorf("expected body %s, got %s", "Hello, World!", string(body))
+	}
+}
+
 func TestRedirect(t *testing.T) {
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/old", nil)
@@ -18,8 +18,15 @@ type InterfaceServiceInterfaceManager interface {
 	UpdateInterface(ctx context.Context, in *domain.Interface) (*domain.Interface, []domain.Peer, error)
 	DeleteInterface(ctx context.Context, id domain.InterfaceIdentifier) error
 	PrepareInterface(ctx context.Context) (*domain.Interface, error)
+	CloneInterface(ctx context.Context, id domain.InterfaceIdentifier, req domain.InterfaceCloneRequest) (
+		*domain.Interface,
+		[]domain.Peer,
+		error,
+	)
 	ApplyPeerDefaults(ctx context.Context, in *domain.Interface) error
 	CreateDefaultPeers(ctx context.Context, id domain.InterfaceIdentifier) error
+	GetIpamStatus(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.IpamNetworkStatus, error)
+	GetDelegatedPrefixes(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.DelegatedPrefixAssignment, error)
 }
 
 type InterfaceServiceConfigFileManager interface {
@@ -64,6 +71,14 @@ func (i InterfaceService) CreateInterface(ctx context.Context, in *domain.Interf
 	return i.interfaces.CreateInterface(ctx, in)
 }
 
+func (i InterfaceService) CloneInterface(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	req domain.InterfaceCloneRequest,
+) (*domain.Interface, []domain.Peer, error) {
+	return i.interfaces.CloneInterface(ctx, id, req)
+}
+
 func (i InterfaceService) UpdateInterface(ctx context.Context, in *domain.Interface) (
 	*domain.Interface,
 	[]domain.Peer,
@@ -98,3 +113,17 @@ func (i InterfaceService) CreateDefaultPeers(ctx context.Context, id domain.Inte
 	}
 	return i.interfaces.CreateDefaultPeers(ctx, id)
 }
+
+func (i InterfaceService) GetIpamStatus(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.IpamNetworkStatus,
+	error,
+) {
+	return i.interfaces.GetIpamStatus(ctx, id)
+}
+
+func (i InterfaceService) GetDelegatedPrefixes(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.DelegatedPrefixAssignment,
+	error,
+) {
+	return i.interfaces.GetDelegatedPrefixes(ctx, id)
+}
@@ -15,7 +15,7 @@ type PeerServicePeerManager interface {
 	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
 	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
 	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
-	PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
+	PreparePeerFromPool(ctx context.Context, id domain.InterfaceIdentifier, pool string) (*domain.Peer, error)
 	CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
 	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
 	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
@@ -30,10 +30,23 @@ type PeerServicePeerManager interface {
 type PeerServiceConfigFileManager interface {
 	GetPeerConfig(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error)
 	GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error)
+	GetPeerMobileConfig(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		platform domain.MobileConfigPlatform,
+	) (io.Reader, error)
+	WritePeerConfigBundle(ctx context.Context, w io.Writer, req domain.ConfigBundleRequest) error
 }
 
 type PeerServiceMailManager interface {
-	SendPeerEmail(ctx context.Context, linkOnly bool, style string, peers ...domain.PeerIdentifier) error
+	SendPeerEmail(
+		ctx context.Context,
+		linkOnly bool,
+		style string,
+		mobileConfig domain.MobileConfigPlatform,
+		peers ...domain.PeerIdentifier,
+	) error
+	SendPeerConfigBundleEmail(ctx context.Context, req domain.ConfigBundleRequest) error
 }
 
 // endregion dependencies
@@ -68,8 +81,11 @@ func (p PeerService) GetInterfaceAndPeers(ctx context.Context, id domain.Interfa
 	return p.peers.GetInterfaceAndPeers(ctx, id)
 }
 
-func (p PeerService) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
-	return p.peers.PreparePeer(ctx, id)
+func (p PeerService) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier, pool string) (
+	*domain.Peer,
+	error,
+) {
+	return p.peers.PreparePeerFromPool(ctx, id, pool)
 }
 
 func (p PeerService) GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
@@ -107,13 +123,34 @@ func (p PeerService) GetPeerConfigQrCode(ctx context.Context, id domain.PeerIden
 	return p.configFile.GetPeerConfigQrCode(ctx, id, style)
 }
 
+func (p PeerService) GetPeerMobileConfig(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	platform domain.MobileConfigPlatform,
+) (io.Reader, error) {
+	return p.configFile.GetPeerMobileConfig(ctx, id, platform)
+}
+
 func (p PeerService) SendPeerEmail(
 	ctx context.Context,
 	linkOnly bool,
 	style string,
+	mobileConfig domain.MobileConfigPlatform,
 	peers ...domain.PeerIdentifier,
 ) error {
-	return p.mailer.SendPeerEmail(ctx, linkOnly, style, peers...)
+	return p.mailer.SendPeerEmail(ctx, linkOnly, style, mobileConfig, peers...)
+}
+
+func (p PeerService) WritePeerConfigBundle(
+	ctx context.Context,
+	w io.Writer,
+	req domain.ConfigBundleRequest,
+) error {
+	return p.configFile.WritePeerConfigBundle(ctx, w, req)
+}
+
+func (p PeerService) SendPeerConfigBundleEmail(ctx context.Context, req domain.ConfigBundleRequest) error {
+	return p.mailer.SendPeerConfigBundleEmail(ctx, req)
 }
 
 func (p PeerService) GetPeerStats(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.PeerStatus, error) {
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// maxBulkImportSize limits the size of the uploaded import data.
+const maxBulkImportSize = 16 << 20 // 16 MiB
+
+type BulkService interface {
+	// ExportUsers writes all users in the given format to the writer.
+	ExportUsers(ctx context.Context, format domain.BulkFormat, w io.Writer) error
+	// ExportPeers writes all peers (optionally limited to the given interfaces) in the given format to the writer.
+	ExportPeers(
+		ctx context.Context,
+		format domain.BulkFormat,
+		w io.Writer,
+		opts domain.BulkExportOptions,
+		ifaces ...domain.InterfaceIdentifier,
+	) error
+	// ImportUsers creates or updates users from the given reader.
+	ImportUsers(ctx context.Context, format domain.BulkFormat, r io.Reader, opts domain.BulkImportOptions) (
+		*domain.BulkImportResult,
+		error,
+	)
+	// ImportPeers creates or updates peers from the given reader.
+	ImportPeers(ctx context.Context, format domain.BulkFormat, r io.Reader, opts domain.BulkImportOptions) (
+		*domain.BulkImportResult,
+		error,
+	)
+}
+
+type BulkEndpoint struct {
+	cfg           *config.Config
+	authenticator Authenticator
+	bulkService   BulkService
+}
+
+func NewBulkEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	bulkService BulkService,
+) BulkEndpoint {
+	return BulkEndpoint{
+		cfg:           cfg,
+		authenticator: authenticator,
+		bulkService:   bulkService,
+	}
+}
+
+func (e BulkEndpoint) GetName() string {
+	return "BulkEndpoint"
+}
+
+func (e BulkEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/bulk")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /users/export", e.handleUsersExportGet())
+	apiGroup.HandleFunc("GET /peers/export", e.handlePeersExportGet())
+	apiGroup.HandleFunc("POST /users/import", e.handleUsersImportPost())
+	apiGroup.HandleFunc("POST /peers/import", e.handlePeersImportPost())
+}
+
+// handleUsersExportGet returns a gorm Handler function.
+//
+// @ID bulk_handleUsersExportGet
+// @Tags Bulk
+// @Summary Export all users as CSV or JSON file.
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "The file format, either csv or json (default)."
+// @Success 200 {file} binary
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /bulk/users/export [get]
+func (e BulkEndpoint) handleUsersExportGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.export(w, r, "users", func(format domain.BulkFormat, buf io.Writer) error {
+			return e.bulkService.ExportUsers(r.Context(), format, buf)
+		})
+	}
+}
+
+// handlePeersExportGet returns a gorm Handler function.
+//
+// @ID bulk_handlePeersExportGet
+// @Tags Bulk
+// @Summary Export all peers as CSV or JSON file.
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "The file format, either csv or json (default)."
+// @Param interface query []string false "Only export peers of the given interfaces."
+// @Param includeKeys query bool false "Also export the private and pre-shared keys of the peers in plaintext."
+// @Success 200 {file} binary
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /bulk/peers/export [get]
+func (e BulkEndpoint) handlePeersExportGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		interfaceIds := make([]domain.InterfaceIdentifier, 0)
+		for _, id := range request.QuerySlice(r, "interface") {
+			interfaceIds = append(interfaceIds, domain.InterfaceIdentifier(id))
+		}
+		includeKeys, _ := strconv.ParseBool(request.QueryDefault(r, "includeKeys", "false"))
+
+		e.export(w, r, "peers", func(format domain.BulkFormat, buf io.Writer) error {
+			return e.bulkService.ExportPeers(r.Context(), format, buf,
+				domain.BulkExportOptions{IncludeKeys: includeKeys}, interfaceIds...)
+		})
+	}
+}
+
+// handleUsersImportPost returns a gorm Handler function.
+//
+// @ID bulk_handleUsersImportPost
+// @Tags Bulk
+// @Summary Import users from a CSV or JSON file.
+// @Description Each record is validated and imported on its own, failed records are reported in the result.
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param format query string false "The file format, either csv or json (default)."
+// @Param dryRun query bool false "Only validate the records, do not persist them."
+// @Param upsert query bool false "Update existing users instead of reporting them as duplicates."
+// @Success 200 {object} model.BulkImportResult
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /bulk/users/import [post]
+func (e BulkEndpoint) handleUsersImportPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.doImport(w, r, e.bulkService.ImportUsers)
+	}
+}
+
+// handlePeersImportPost returns a gorm Handler function.
+//
+// @ID bulk_handlePeersImportPost
+// @Tags Bulk
+// @Summary Import peers from a CSV or JSON file.
+// @Description The referenced interfaces and owners must exist. Peers without a public key get a fresh key pair,
+// @Description peers without addresses get fresh addresses from the interface network.
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param format query string false "The file format, either csv or json (default)."
+// @Param dryRun query bool false "Only validate the records, do not persist them."
+// @Param upsert query bool false "Update existing peers instead of reporting them as duplicates."
+// @Success 200 {object} model.BulkImportResult
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /bulk/peers/import [post]
+func (e BulkEndpoint) handlePeersImportPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.doImport(w, r, e.bulkService.ImportPeers)
+	}
+}
+
+func (e BulkEndpoint) export(
+	w http.ResponseWriter,
+	r *http.Request,
+	name string,
+	exportFn func(format domain.BulkFormat, buf io.Writer) error,
+) {
+	format, err := domain.ParseBulkFormat(request.Query(r, "format"))
+	if err != nil {
+		respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := exportFn(format, &buf); err != nil {
+		respond.JSON(w, http.StatusInternalServerError, model.Error{
+			Code: http.StatusInternalServerError, Message: err.Error(),
+		})
+		return
+	}
+
+	contentType := "application/json"
+	if format == domain.BulkFormatCsv {
+		contentType = "text/csv"
+	}
+	respond.Attachment(w, http.StatusOK, fmt.Sprintf("%s.%s", name, format), contentType, buf.Bytes())
+}
+
+func (e BulkEndpoint) doImport(
+	w http.ResponseWriter,
+	r *http.Request,
+	importFn func(context.Context, domain.BulkFormat, io.Reader, domain.BulkImportOptions) (
+		*domain.BulkImportResult,
+		error,
+	),
+) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkImportSize)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	format, err := domain.ParseBulkFormat(request.Query(r, "format"))
+	if err != nil {
+		respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(request.QueryDefault(r, "dryRun", "false"))
+	upsert, _ := strconv.ParseBool(request.QueryDefault(r, "upsert", "false"))
+
+	result, err := importFn(r.Context(), format, r.Body, domain.BulkImportOptions{DryRun: dryRun, Upsert: upsert})
+	if err != nil {
+		code := http.StatusInternalServerError
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			code = http.StatusRequestEntityTooLarge
+		case errors.Is(err, domain.ErrInvalidData):
+			code = http.StatusBadRequest
+		}
+		respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, model.NewBulkImportResult(result))
+}
@@ -23,6 +23,7 @@ var frontendJs embed.FS
 
 type ControllerManager interface {
 	GetControllerNames() []config.BackendBase
+	SupportsBandwidthLimits(backend domain.InterfaceBackend) bool
 }
 
 type ConfigEndpoint struct {
@@ -117,8 +118,9 @@ func (e ConfigEndpoint) handleSettingsGet() http.HandlerFunc {
 					displayName = "modals.interface-edit.backend.local" // use a localized string for the local backend
 				}
 				names = append(names, model.SettingsBackendNames{
-					Id:   controller.Id,
-					Name: displayName,
+					Id:                      controller.Id,
+					Name:                    displayName,
+					SupportsBandwidthLimits: e.controllerMgr.SupportsBandwidthLimits(domain.InterfaceBackend(controller.Id)),
 				})
 			}
 
@@ -140,6 +142,7 @@ func (e ConfigEndpoint) handleSettingsGet() http.HandlerFunc {
 				MailLinkOnly:              e.cfg.Mail.LinkOnly,
 				PersistentConfigSupported: e.cfg.Advanced.ConfigStoragePath != "",
 				SelfProvisioning:          e.cfg.Core.SelfProvisioningAllowed,
+				PeerRequests:              e.cfg.Core.PeerRequestsAllowed,
 				ApiAdminOnly:              e.cfg.Advanced.ApiAdminOnly,
 				WebAuthnEnabled:           e.cfg.Auth.WebAuthn.Enabled,
 				MinPasswordLength:         e.cfg.Auth.MinPasswordLength,
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type InactivityService interface {
+	// GetStalePeers returns all peers of the interface that did not connect for at least minDays days.
+	GetStalePeers(ctx context.Context, id domain.InterfaceIdentifier, minDays int) ([]domain.StalePeer, error)
+}
+
+type InactivityEndpoint struct {
+	cfg               *config.Config
+	authenticator     Authenticator
+	inactivityService InactivityService
+}
+
+func NewInactivityEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	inactivityService InactivityService,
+) InactivityEndpoint {
+	return InactivityEndpoint{
+		cfg:               cfg,
+		authenticator:     authenticator,
+		inactivityService: inactivityService,
+	}
+}
+
+func (e InactivityEndpoint) GetName() string {
+	return "InactivityEndpoint"
+}
+
+func (e InactivityEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/inactivity")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /iface/{iface}/stale", e.handleStalePeersGet())
+}
+
+// handleStalePeersGet returns a gorm Handler function.
+//
+// @ID inactivity_handleStalePeersGet
+// @Tags Inactivity
+// @Summary Get all peers of the given interface that did not connect for a long time.
+// @Description Peers that never connected are counted from their creation date.
+// @Produce json
+// @Param iface path string true "The interface identifier"
+// @Param minDays query int false "Minimum number of inactive days, defaults to the lowest threshold of the interface policy."
+// @Success 200 {object} []model.StalePeer
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /inactivity/iface/{iface}/stale [get]
+func (e InactivityEndpoint) handleStalePeersGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		interfaceId := Base64UrlDecode(request.Path(r, "iface"))
+		if interfaceId == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing iface parameter"})
+			return
+		}
+
+		minDays, err := strconv.Atoi(request.QueryDefault(r, "minDays", "0"))
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "invalid minDays parameter"})
+			return
+		}
+
+		stalePeers, err := e.inactivityService.GetStalePeers(r.Context(), domain.InterfaceIdentifier(interfaceId),
+			minDays)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, domain.ErrInvalidData) {
+				code = http.StatusBadRequest
+			}
+			respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewStalePeers(stalePeers))
+	}
+}
@@ -21,6 +21,12 @@ type InterfaceService interface {
 	PrepareInterface(ctx context.Context) (*domain.Interface, error)
 	// CreateInterface creates a new interface.
 	CreateInterface(ctx context.Context, in *domain.Interface) (*domain.Interface, error)
+	// CloneInterface creates a new interface with the settings of the interface with the given id.
+	CloneInterface(ctx context.Context, id domain.InterfaceIdentifier, req domain.InterfaceCloneRequest) (
+		*domain.Interface,
+		[]domain.Peer,
+		error,
+	)
 	// UpdateInterface updates the interface with the given id.
 	UpdateInterface(ctx context.Context, in *domain.Interface) (*domain.Interface, []domain.Peer, error)
 	// DeleteInterface deletes the interface with the given id.
@@ -35,6 +41,10 @@ type InterfaceService interface {
 	ApplyPeerDefaults(ctx context.Context, in *domain.Interface) error
 	// CreateDefaultPeers creates default peers for all existing users on the given interface.
 	CreateDefaultPeers(ctx context.Context, id domain.InterfaceIdentifier) error
+	// GetIpamStatus returns the used, reserved and free addresses of the peer networks of the given interface.
+	GetIpamStatus(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.IpamNetworkStatus, error)
+	// GetDelegatedPrefixes returns the delegated IPv6 prefixes of all peers of the given interface.
+	GetDelegatedPrefixes(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.DelegatedPrefixAssignment, error)
 }
 
 type InterfaceEndpoint struct {
@@ -75,9 +85,12 @@ func (e InterfaceEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup.HandleFunc("GET /config/{id}", e.handleConfigGet())
 	apiGroup.HandleFunc("POST /{id}/save-config", e.handleSaveConfigPost())
 	apiGroup.HandleFunc("POST /{id}/apply-peer-defaults", e.handleApplyPeerDefaultsPost())
+	apiGroup.HandleFunc("POST /{id}/clone", e.handleClonePost())
 	apiGroup.HandleFunc("POST /{id}/create-default-peers", e.handleCreateDefaultPeersPost())
 
 	apiGroup.HandleFunc("GET /peers/{id}", e.handlePeersGet())
+	apiGroup.HandleFunc("GET /ipam/{id}", e.handleIpamGet())
+	apiGroup.HandleFunc("GET /prefix-delegations/{id}", e.handlePrefixDelegationsGet())
 }
 
 // handlePrepareGet returns a gorm Handler function.
@@ -313,6 +326,72 @@ func (e InterfaceEndpoint) handlePeersGet() http.HandlerFunc {
 	}
 }
 
+// handleIpamGet returns a gorm Handler function.
+//
+// @ID interfaces_handleIpamGet
+// @Tags Interface
+// @Summary Get the address usage of the peer networks of the given interface.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Success 200 {object} []model.IpamNetworkStatus
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /interface/ipam/{id} [get]
+func (e InterfaceEndpoint) handleIpamGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{
+				Code: http.StatusBadRequest, Message: "missing id parameter",
+			})
+			return
+		}
+
+		status, err := e.interfaceService.GetIpamStatus(r.Context(), domain.InterfaceIdentifier(id))
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError, model.Error{
+				Code: http.StatusInternalServerError, Message: err.Error(),
+			})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewIpamNetworkStatuses(status))
+	}
+}
+
+// handlePrefixDelegationsGet returns a gorm Handler function.
+//
+// @ID interfaces_handlePrefixDelegationsGet
+// @Tags Interface
+// @Summary Get the delegated IPv6 prefixes of all peers of the given interface.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Success 200 {object} []model.DelegatedPrefix
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /interface/prefix-delegations/{id} [get]
+func (e InterfaceEndpoint) handlePrefixDelegationsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{
+				Code: http.StatusBadRequest, Message: "missing id parameter",
+			})
+			return
+		}
+
+		prefixes, err := e.interfaceService.GetDelegatedPrefixes(r.Context(), domain.InterfaceIdentifier(id))
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError, model.Error{
+				Code: http.StatusInternalServerError, Message: err.Error(),
+			})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewDelegatedPrefixes(prefixes))
+	}
+}
+
 // handleDelete returns a gorm Handler function.
 //
 // @ID interfaces_handleDelete
@@ -455,3 +534,47 @@ func (e InterfaceEndpoint) handleCreateDefaultPeersPost() http.HandlerFunc {
 		respond.Status(w, http.StatusNoContent)
 	}
 }
+
+// handleClonePost returns a gorm Handler function.
+//
+// @ID interfaces_handleClonePost
+// @Tags Interface
+// @Summary Create a new interface with the settings of the given interface.
+// @Produce json
+// @Param id path string true "The interface identifier"
+// @Param request body model.InterfaceCloneRequest true "The settings of the new interface"
+// @Success 200 {object} model.Interface
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /interface/{id}/clone [post]
+func (e InterfaceEndpoint) handleClonePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		var req model.InterfaceCloneRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		newInterface, peers, err := e.interfaceService.CloneInterface(r.Context(), domain.InterfaceIdentifier(id),
+			model.NewDomainInterfaceCloneRequest(&req))
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError, model.Error{
+				Code: http.StatusInternalServerError, Message: err.Error(),
+			})
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewInterface(newInterface, peers))
+	}
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v0/model"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PeerRequestService interface {
+	// SubmitPeerRequest stores a new pending peer request and notifies all admins.
+	SubmitPeerRequest(ctx context.Context, request *domain.PeerRequest) (*domain.PeerRequest, error)
+	// GetAllPeerRequests returns all peer requests, optionally only the pending ones.
+	GetAllPeerRequests(ctx context.Context, pendingOnly bool) ([]domain.PeerRequest, error)
+	// GetUserPeerRequests returns all peer requests of the given user.
+	GetUserPeerRequests(ctx context.Context, id domain.UserIdentifier) ([]domain.PeerRequest, error)
+	// ApprovePeerRequest creates the requested peer.
+	ApprovePeerRequest(ctx context.Context, id domain.PeerRequestIdentifier, reason string) (
+		*domain.PeerRequest,
+		error,
+	)
+	// RejectPeerRequest rejects the peer request.
+	RejectPeerRequest(ctx context.Context, id domain.PeerRequestIdentifier, reason string) (
+		*domain.PeerRequest,
+		error,
+	)
+}
+
+type PeerRequestEndpoint struct {
+	cfg            *config.Config
+	authenticator  Authenticator
+	requestService PeerRequestService
+}
+
+func NewPeerRequestEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	requestService PeerRequestService,
+) PeerRequestEndpoint {
+	return PeerRequestEndpoint{
+		cfg:            cfg,
+		authenticator:  authenticator,
+		requestService: requestService,
+	}
+}
+
+func (e PeerRequestEndpoint) GetName() string {
+	return "PeerRequestEndpoint"
+}
+
+func (e PeerRequestEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/peer-request")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.With(e.authenticator.UserIdMatch("id")).HandleFunc("GET /user/{id}", e.handleUserGet())
+	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /{id}/approve", e.handleApprovePost())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /{id}/reject", e.handleRejectPost())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID peerRequests_handleAllGet
+// @Tags PeerRequest
+// @Summary Get all peer requests.
+// @Produce json
+// @Param pending query bool false "Only return pending requests"
+// @Success 200 {object} []model.PeerRequest
+// @Failure 500 {object} model.Error
+// @Router /peer-request/all [get]
+func (e PeerRequestEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests, err := e.requestService.GetAllPeerRequests(r.Context(),
+			request.QueryDefault(r, "pending", "") == "true")
+		if err != nil {
+			respondPeerRequestError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerRequests(requests))
+	}
+}
+
+// handleUserGet returns a gorm Handler function.
+//
+// @ID peerRequests_handleUserGet
+// @Tags PeerRequest
+// @Summary Get all peer requests of the given user.
+// @Produce json
+// @Param id path string true "The user identifier"
+// @Success 200 {object} []model.PeerRequest
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer-request/user/{id} [get]
+func (e PeerRequestEndpoint) handleUserGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: "missing user id"})
+			return
+		}
+
+		requests, err := e.requestService.GetUserPeerRequests(r.Context(), domain.UserIdentifier(id))
+		if err != nil {
+			respondPeerRequestError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerRequests(requests))
+	}
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID peerRequests_handleCreatePost
+// @Tags PeerRequest
+// @Summary Request a new peer. The request has to be approved by an admin.
+// @Produce json
+// @Param request body model.PeerRequest true "The peer request data"
+// @Success 200 {object} model.PeerRequest
+// @Failure 400 {object} model.Error
+// @Failure 403 {object} model.Error
+// @Failure 409 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer-request/new [post]
+func (e PeerRequestEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req model.PeerRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		newRequest, err := e.requestService.SubmitPeerRequest(r.Context(), model.NewDomainPeerRequest(&req))
+		if err != nil {
+			respondPeerRequestError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerRequest(newRequest))
+	}
+}
+
+// handleApprovePost returns a gorm Handler function.
+//
+// @ID peerRequests_handleApprovePost
+// @Tags PeerRequest
+// @Summary Approve a pending peer request and create the peer.
+// @Produce json
+// @Param id path string true "The peer request identifier"
+// @Param request body model.PeerRequestDecision false "An optional comment"
+// @Success 200 {object} model.PeerRequest
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer-request/{id}/approve [post]
+func (e PeerRequestEndpoint) handleApprovePost() http.HandlerFunc {
+	return e.handleDecision(e.requestService.ApprovePeerRequest)
+}
+
+// handleRejectPost returns a gorm Handler function.
+//
+// @ID peerRequests_handleRejectPost
+// @Tags PeerRequest
+// @Summary Reject a pending peer request.
+// @Produce json
+// @Param id path string true "The peer request identifier"
+// @Param request body model.PeerRequestDecision false "The reason for the rejection"
+// @Success 200 {object} model.PeerRequest
+// @Failure 400 {object} model.Error
+// @Failure 404 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer-request/{id}/reject [post]
+func (e PeerRequestEndpoint) handleRejectPost() http.HandlerFunc {
+	return e.handleDecision(e.requestService.RejectPeerRequest)
+}
+
+func (e PeerRequestEndpoint) handleDecision(
+	decide func(context.Context, domain.PeerRequestIdentifier, string) (*domain.PeerRequest, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				model.Error{Code: http.StatusBadRequest, Message: "missing request id"})
+			return
+		}
+
+		var decision model.PeerRequestDecision
+		if r.ContentLength != 0 {
+			if err := request.BodyJson(r, &decision); err != nil {
+				respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+				return
+			}
+		}
+
+		peerRequest, err := decide(r.Context(), domain.PeerRequestIdentifier(id), decision.Reason)
+		if err != nil {
+			respondPeerRequestError(w, err)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, model.NewPeerRequest(peerRequest))
+	}
+}
+
+func respondPeerRequestError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrInvalidData):
+		code = http.StatusBadRequest
+	case errors.Is(err, domain.ErrNoPermission):
+		code = http.StatusForbidden
+	case errors.Is(err, domain.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicateEntry):
+		code = http.StatusConflict
+	}
+	respond.JSON(w, code, model.Error{Code: code, Message: err.Error()})
+}
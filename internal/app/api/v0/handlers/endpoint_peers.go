@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"io"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -19,7 +20,8 @@ type PeerService interface {
 	// GetInterfaceAndPeers returns the interface with the given id and all peers associated with it.
 	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
 	// PreparePeer returns a new peer with default values for the given interface.
-	PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
+	// If pool is not empty, the addresses are allocated from the ip pool with that name.
+	PreparePeer(ctx context.Context, id domain.InterfaceIdentifier, pool string) (*domain.Peer, error)
 	// GetPeer returns the peer with the given id.
 	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
 	// CreatePeer creates a new peer.
@@ -38,8 +40,24 @@ type PeerService interface {
 	GetPeerConfig(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error)
 	// GetPeerConfigQrCode returns the peer configuration as qr code for the given id.
 	GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error)
+	// GetPeerMobileConfig returns the Apple configuration profile for the given id.
+	GetPeerMobileConfig(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		platform domain.MobileConfigPlatform,
+	) (io.Reader, error)
 	// SendPeerEmail sends the peer configuration via email.
-	SendPeerEmail(ctx context.Context, linkOnly bool, style string, peers ...domain.PeerIdentifier) error
+	SendPeerEmail(
+		ctx context.Context,
+		linkOnly bool,
+		style string,
+		mobileConfig domain.MobileConfigPlatform,
+		peers ...domain.PeerIdentifier,
+	) error
+	// WritePeerConfigBundle writes a zip archive with the configurations of the selected peers.
+	WritePeerConfigBundle(ctx context.Context, w io.Writer, req domain.ConfigBundleRequest) error
+	// SendPeerConfigBundleEmail sends the configurations of the selected peers as zip attachment via email.
+	SendPeerConfigBundleEmail(ctx context.Context, req domain.ConfigBundleRequest) error
 	// GetPeerStats returns the peer stats for the given interface.
 	GetPeerStats(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.PeerStatus, error)
 	// BulkDelete deletes multiple peers.
@@ -84,7 +102,10 @@ func (e PeerEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /iface/{iface}/multiplenew",
 		e.handleCreateMultiplePost())
 	apiGroup.HandleFunc("GET /config-qr/{id}", e.handleQrCodeGet())
+	apiGroup.HandleFunc("GET /config-mobileconfig/{id}", e.handleMobileConfigGet())
 	apiGroup.HandleFunc("POST /config-mail", e.handleEmailPost())
+	apiGroup.HandleFunc("GET /config-bundle", e.handleConfigBundleGet())
+	apiGroup.HandleFunc("POST /config-bundle-mail", e.handleConfigBundleEmailPost())
 	apiGroup.HandleFunc("GET /config/{id}", e.handleConfigGet())
 	apiGroup.HandleFunc("GET /{id}", e.handleSingleGet())
 	apiGroup.HandleFunc("PUT /{id}", e.handleUpdatePut())
@@ -163,6 +184,7 @@ func (e PeerEndpoint) handleSingleGet() http.HandlerFunc {
 // @Summary Prepare a new peer for the given interface.
 // @Produce json
 // @Param iface path string true "The interface identifier"
+// @Param pool query string false "The ip pool to allocate the addresses from"
 // @Success 200 {object} model.Peer
 // @Failure 400 {object} model.Error
 // @Failure 500 {object} model.Error
@@ -176,7 +198,8 @@ func (e PeerEndpoint) handlePrepareGet() http.HandlerFunc {
 			return
 		}
 
-		peer, err := e.peerService.PreparePeer(r.Context(), domain.InterfaceIdentifier(interfaceId))
+		peer, err := e.peerService.PreparePeer(r.Context(), domain.InterfaceIdentifier(interfaceId),
+			request.Query(r, "pool"))
 		if err != nil {
 			respond.JSON(w, http.StatusInternalServerError,
 				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
@@ -363,7 +386,7 @@ func (e PeerEndpoint) handleDelete() http.HandlerFunc {
 // @Summary Get peer configuration as string.
 // @Produce json
 // @Param id path string true "The peer identifier"
-// @Param style query string false "The configuration style"
+// @Param style query string false "The configuration style: wgquick (default), raw, networkd, networkmanager, openwrt or routeros"
 // @Success 200 {object} string
 // @Failure 400 {object} model.Error
 // @Failure 500 {object} model.Error
@@ -408,7 +431,7 @@ func (e PeerEndpoint) handleConfigGet() http.HandlerFunc {
 // @Produce png
 // @Produce json
 // @Param id path string true "The peer identifier"
-// @Param style query string false "The configuration style"
+// @Param style query string false "The configuration style: wgquick (default), raw, networkd, networkmanager, openwrt or routeros"
 // @Success 200 {file} binary
 // @Failure 400 {object} model.Error
 // @Failure 500 {object} model.Error
@@ -445,6 +468,56 @@ func (e PeerEndpoint) handleQrCodeGet() http.HandlerFunc {
 	}
 }
 
+// handleMobileConfigGet returns a gorm Handler function.
+//
+// @ID peers_handleMobileConfigGet
+// @Tags Peer
+// @Summary Get peer configuration as Apple configuration profile.
+// @Produce application/x-apple-aspen-config
+// @Produce json
+// @Param id path string true "The peer identifier"
+// @Param platform query string false "The target platform: ios (default) or macos"
+// @Success 200 {file} binary
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer/config-mobileconfig/{id} [get]
+func (e PeerEndpoint) handleMobileConfigGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := Base64UrlDecode(request.Path(r, "id"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest, model.Error{
+				Code: http.StatusBadRequest, Message: "missing id parameter",
+			})
+			return
+		}
+
+		platform, err := domain.ParseMobileConfigPlatform(request.Query(r, "platform"))
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		peer, err := e.peerService.GetPeer(r.Context(), domain.PeerIdentifier(id))
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError, model.Error{
+				Code: http.StatusInternalServerError, Message: err.Error(),
+			})
+			return
+		}
+
+		profile, err := e.peerService.GetPeerMobileConfig(r.Context(), peer.Identifier, platform)
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError, model.Error{
+				Code: http.StatusInternalServerError, Message: err.Error(),
+			})
+			return
+		}
+
+		respond.AttachmentReader(w, http.StatusOK, peer.GetMobileConfigFileName(), domain.MobileConfigContentType, 0,
+			profile)
+	}
+}
+
 // handleEmailPost returns a gorm Handler function.
 //
 // @ID peers_handleEmailPost
@@ -452,7 +525,7 @@ func (e PeerEndpoint) handleQrCodeGet() http.HandlerFunc {
 // @Summary Send peer configuration via email.
 // @Produce json
 // @Param request body model.PeerMailRequest true "The peer mail request data"
-// @Param style query string false "The configuration style"
+// @Param style query string false "The configuration style: wgquick (default), raw, networkd, networkmanager, openwrt or routeros"
 // @Success 204 "No content if mail sending was successful"
 // @Failure 400 {object} model.Error
 // @Failure 500 {object} model.Error
@@ -481,7 +554,9 @@ func (e PeerEndpoint) handleEmailPost() http.HandlerFunc {
 		for i := range req.Identifiers {
 			peerIds[i] = domain.PeerIdentifier(req.Identifiers[i])
 		}
-		if err := e.peerService.SendPeerEmail(r.Context(), req.LinkOnly, configStyle, peerIds...); err != nil {
+		err := e.peerService.SendPeerEmail(r.Context(), req.LinkOnly, configStyle,
+			domain.MobileConfigPlatform(req.MobileConfig), peerIds...)
+		if err != nil {
 			respond.JSON(w, http.StatusInternalServerError,
 				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
 			return
@@ -522,9 +597,93 @@ func (e PeerEndpoint) handleStatsGet() http.HandlerFunc {
 	}
 }
 
+// handleConfigBundleGet returns a gorm Handler function.
+//
+// @ID peers_handleConfigBundleGet
+// @Tags Peer
+// @Summary Get the configurations of multiple peers as zip archive.
+// @Description Exactly one of user, iface or peer must be set. The archive contains all configuration files and a QR code image per peer.
+// @Produce application/zip
+// @Produce json
+// @Param user query string false "The user identifier"
+// @Param iface query string false "The interface identifier"
+// @Param peer query []string false "The peer identifiers" collectionFormat(multi)
+// @Param style query string false "The configuration style: wgquick (default), raw, networkd, networkmanager, openwrt or routeros"
+// @Success 200 {file} binary
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer/config-bundle [get]
+func (e PeerEndpoint) handleConfigBundleGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerIds := make([]domain.PeerIdentifier, 0)
+		for _, id := range request.QuerySlice(r, "peer") {
+			peerIds = append(peerIds, domain.PeerIdentifier(Base64UrlDecode(id)))
+		}
+
+		bundleReq := domain.ConfigBundleRequest{
+			UserIdentifier:      domain.UserIdentifier(Base64UrlDecode(request.Query(r, "user"))),
+			InterfaceIdentifier: domain.InterfaceIdentifier(Base64UrlDecode(request.Query(r, "iface"))),
+			PeerIdentifiers:     peerIds,
+			Style:               e.getConfigStyle(r),
+		}
+		if err := bundleReq.Validate(); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		aw := respond.NewAttachmentWriter(w, http.StatusOK, bundleReq.FileName(), domain.ConfigBundleContentType)
+		err := e.peerService.WritePeerConfigBundle(r.Context(), aw, bundleReq)
+		switch {
+		case err != nil && !aw.Started():
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+		case err != nil:
+			slog.Error("failed to stream config bundle", "file", bundleReq.FileName(), "error", err)
+		}
+	}
+}
+
+// handleConfigBundleEmailPost returns a gorm Handler function.
+//
+// @ID peers_handleConfigBundleEmailPost
+// @Tags Peer
+// @Summary Send the configurations of multiple peers as zip attachment via email.
+// @Description Bundles of a user are sent to that user, all other bundles are sent to the requesting user.
+// @Produce json
+// @Param request body model.ConfigBundleRequest true "The peer selection"
+// @Param style query string false "The configuration style: wgquick (default), raw, networkd, networkmanager, openwrt or routeros"
+// @Success 204 "No content if mail sending was successful"
+// @Failure 400 {object} model.Error
+// @Failure 500 {object} model.Error
+// @Router /peer/config-bundle-mail [post]
+func (e PeerEndpoint) handleConfigBundleEmailPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req model.ConfigBundleRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		bundleReq := model.NewDomainConfigBundleRequest(&req, e.getConfigStyle(r))
+		if err := bundleReq.Validate(); err != nil {
+			respond.JSON(w, http.StatusBadRequest, model.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		err := e.peerService.SendPeerConfigBundleEmail(r.Context(), bundleReq)
+		if err != nil {
+			respond.JSON(w, http.StatusInternalServerError,
+				model.Error{Code: http.StatusInternalServerError, Message: err.Error()})
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
+
 func (e PeerEndpoint) getConfigStyle(r *http.Request) string {
 	configStyle := request.QueryDefault(r, "style", domain.ConfigStyleWgQuick)
-	if configStyle != domain.ConfigStyleWgQuick && configStyle != domain.ConfigStyleRaw {
+	if !domain.IsValidConfigStyle(configStyle) {
 		configStyle = domain.ConfigStyleWgQuick // default to wg-quick style
 	}
 	return configStyle
@@ -9,6 +9,7 @@ type Settings struct {
 	MailLinkOnly              bool                   `json:"MailLinkOnly"`
 	PersistentConfigSupported bool                   `json:"PersistentConfigSupported"`
 	SelfProvisioning          bool                   `json:"SelfProvisioning"`
+	PeerRequests              bool                   `json:"PeerRequests"`
 	ApiAdminOnly              bool                   `json:"ApiAdminOnly"`
 	WebAuthnEnabled           bool                   `json:"WebAuthnEnabled"`
 	MinPasswordLength         int                    `json:"MinPasswordLength"`
@@ -18,6 +19,7 @@ type Settings struct {
 }
 
 type SettingsBackendNames struct {
-	Id   string `json:"Id"`
-	Name string `json:"Name"`
+	Id                      string `json:"Id"`
+	Name                    string `json:"Name"`
+	SupportsBandwidthLimits bool   `json:"SupportsBandwidthLimits"` // true if the backend enforces peer bandwidth limits
 }
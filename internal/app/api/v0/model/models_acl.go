@@ -0,0 +1,94 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AclRule struct {
+	Action      string `json:"Action" example:"allow"`            // allow or deny
+	Destination string `json:"Destination" example:"10.0.0.0/24"` // destination network, empty matches all destinations
+	Protocol    string `json:"Protocol" example:"tcp"`            // tcp, udp or icmp, empty matches all protocols
+	Ports       string `json:"Ports" example:"8000-8100"`         // destination port or port range, only for tcp and udp
+}
+
+type AccessControlList struct {
+	Rules []AclRule `json:"Rules"` // the first matching rule wins
+}
+
+type InterfaceAccessPolicy struct {
+	IsolatePeers  bool      `json:"IsolatePeers"`                  // if true, peers are not able to reach each other
+	DefaultAction string    `json:"DefaultAction" example:"allow"` // action for unmatched traffic, empty means allow
+	Rules         []AclRule `json:"Rules"`                         // rules for all peers of the interface
+}
+
+func NewAclRules(src []domain.AclRule) []AclRule {
+	results := make([]AclRule, len(src))
+	for i, rule := range src {
+		results[i] = AclRule{
+			Action:      string(rule.Action),
+			Destination: rule.Destination,
+			Protocol:    rule.Protocol,
+			Ports:       rule.Ports,
+		}
+	}
+
+	return results
+}
+
+func NewDomainAclRules(src []AclRule) []domain.AclRule {
+	results := make([]domain.AclRule, len(src))
+	for i, rule := range src {
+		results[i] = domain.AclRule{
+			Action:      domain.AclAction(rule.Action),
+			Destination: rule.Destination,
+			Protocol:    rule.Protocol,
+			Ports:       rule.Ports,
+		}
+	}
+
+	return results
+}
+
+func NewAccessControlList(src *domain.AccessControlList) *AccessControlList {
+	if src == nil {
+		return nil
+	}
+
+	return &AccessControlList{
+		Rules: NewAclRules(src.Rules),
+	}
+}
+
+func NewDomainAccessControlList(src *AccessControlList) *domain.AccessControlList {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.AccessControlList{
+		Rules: NewDomainAclRules(src.Rules),
+	}
+}
+
+func NewInterfaceAccessPolicy(src *domain.InterfaceAccessPolicy) *InterfaceAccessPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &InterfaceAccessPolicy{
+		IsolatePeers:  src.IsolatePeers,
+		DefaultAction: string(src.DefaultAction),
+		Rules:         NewAclRules(src.Rules),
+	}
+}
+
+func NewDomainInterfaceAccessPolicy(src *InterfaceAccessPolicy) *domain.InterfaceAccessPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.InterfaceAccessPolicy{
+		IsolatePeers:  src.IsolatePeers,
+		DefaultAction: domain.AclAction(src.DefaultAction),
+		Rules:         NewDomainAclRules(src.Rules),
+	}
+}
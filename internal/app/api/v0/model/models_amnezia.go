@@ -0,0 +1,53 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AmneziaParams struct {
+	Jc   int    `json:"Jc"`   // number of junk packets before the handshake
+	Jmin int    `json:"Jmin"` // minimum junk packet size
+	Jmax int    `json:"Jmax"` // maximum junk packet size
+	S1   int    `json:"S1"`   // padding of the handshake initiation
+	S2   int    `json:"S2"`   // padding of the handshake response
+	H1   uint32 `json:"H1"`   // message type headers, zero keeps the WireGuard default
+	H2   uint32 `json:"H2"`
+	H3   uint32 `json:"H3"`
+	H4   uint32 `json:"H4"`
+}
+
+func NewAmneziaParams(src *domain.AmneziaParams) *AmneziaParams {
+	if src == nil {
+		return nil
+	}
+
+	return &AmneziaParams{
+		Jc:   src.Jc,
+		Jmin: src.Jmin,
+		Jmax: src.Jmax,
+		S1:   src.S1,
+		S2:   src.S2,
+		H1:   src.H1,
+		H2:   src.H2,
+		H3:   src.H3,
+		H4:   src.H4,
+	}
+}
+
+func NewDomainAmneziaParams(src *AmneziaParams) *domain.AmneziaParams {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.AmneziaParams{
+		Jc:   src.Jc,
+		Jmin: src.Jmin,
+		Jmax: src.Jmax,
+		S1:   src.S1,
+		S2:   src.S2,
+		H1:   src.H1,
+		H2:   src.H2,
+		H3:   src.H3,
+		H4:   src.H4,
+	}
+}
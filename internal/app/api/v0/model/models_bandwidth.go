@@ -0,0 +1,32 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type BandwidthLimit struct {
+	UploadKbps   int `json:"UploadKbps" example:"10000"`   // maximum rate the peer is allowed to send in kbit/s, 0 means unlimited
+	DownloadKbps int `json:"DownloadKbps" example:"50000"` // maximum rate the peer is allowed to receive in kbit/s, 0 means unlimited
+}
+
+func NewBandwidthLimit(src *domain.BandwidthLimit) *BandwidthLimit {
+	if src == nil {
+		return nil
+	}
+
+	return &BandwidthLimit{
+		UploadKbps:   src.UploadKbps,
+		DownloadKbps: src.DownloadKbps,
+	}
+}
+
+func NewDomainBandwidthLimit(src *BandwidthLimit) *domain.BandwidthLimit {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.BandwidthLimit{
+		UploadKbps:   src.UploadKbps,
+		DownloadKbps: src.DownloadKbps,
+	}
+}
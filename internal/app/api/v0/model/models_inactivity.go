@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type InactivityPolicy struct {
+	WarnAfterDays    int `json:"WarnAfterDays" example:"60"`    // warn the owner after this many days of inactivity, 0 disables the step
+	DisableAfterDays int `json:"DisableAfterDays" example:"90"` // disable the peer after this many days of inactivity, 0 disables the step
+	DeleteAfterDays  int `json:"DeleteAfterDays" example:"180"` // delete the peer after this many days of inactivity, 0 disables the step
+}
+
+func NewInactivityPolicy(src *domain.InactivityPolicy) *InactivityPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &InactivityPolicy{
+		WarnAfterDays:    src.WarnAfterDays,
+		DisableAfterDays: src.DisableAfterDays,
+		DeleteAfterDays:  src.DeleteAfterDays,
+	}
+}
+
+func NewDomainInactivityPolicy(src *InactivityPolicy) *domain.InactivityPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.InactivityPolicy{
+		WarnAfterDays:    src.WarnAfterDays,
+		DisableAfterDays: src.DisableAfterDays,
+		DeleteAfterDays:  src.DeleteAfterDays,
+	}
+}
+
+type StalePeer struct {
+	Identifier          string `json:"Identifier"`          // peer unique identifier
+	DisplayName         string `json:"DisplayName"`         // the display name of the peer
+	UserIdentifier      string `json:"UserIdentifier"`      // the owner
+	InterfaceIdentifier string `json:"InterfaceIdentifier"` // the interface id
+	Disabled            bool   `json:"Disabled"`            // true if the peer is disabled
+	DisabledReason      string `json:"DisabledReason"`      // the reason why the peer has been disabled
+
+	LastActivity   time.Time  `json:"LastActivity"`        // the last handshake, or the creation date if the peer never connected
+	NeverConnected bool       `json:"NeverConnected"`      // true if the peer never connected
+	InactiveDays   int        `json:"InactiveDays"`        // number of full days since the last activity
+	Warned         *time.Time `json:"Warned,omitempty"`    // the time the owner has been warned
+	PendingAction  string     `json:"PendingAction"`       // the action of the next inactivity check (warn, disable, delete) or empty
+	DisableAt      *time.Time `json:"DisableAt,omitempty"` // the earliest time the peer will be disabled
+	DeleteAt       *time.Time `json:"DeleteAt,omitempty"`  // the earliest time the peer will be deleted
+}
+
+func NewStalePeers(src []domain.StalePeer) []StalePeer {
+	results := make([]StalePeer, len(src))
+	for i, p := range src {
+		results[i] = StalePeer{
+			Identifier:          string(p.Peer.Identifier),
+			DisplayName:         p.Peer.DisplayName,
+			UserIdentifier:      string(p.Peer.UserIdentifier),
+			InterfaceIdentifier: string(p.Peer.InterfaceIdentifier),
+			Disabled:            p.Peer.IsDisabled(),
+			DisabledReason:      p.Peer.DisabledReason,
+			LastActivity:        p.LastActivity,
+			NeverConnected:      p.NeverConnected,
+			InactiveDays:        p.InactiveDays,
+			Warned:              p.Peer.InactivityWarned,
+			PendingAction:       string(p.PendingAction),
+			DisableAt:           p.DisableAt,
+			DeleteAt:            p.DeleteAt,
+		}
+	}
+
+	return results
+}
@@ -17,6 +17,7 @@ type Interface struct {
 	PublicKey         string `json:"PublicKey" example:"abcdef=="`  // public Key of the server interface
 	Disabled          bool   `json:"Disabled"`                      // flag that specifies if the interface is enabled (up) or not (down)
 	DisabledReason    string `json:"DisabledReason"`                // the reason why the interface has been disabled
+	ManagedBy         string `json:"ManagedBy"`                     // set if the interface is defined by the declarative state file, read-only
 	SaveConfig        bool   `json:"SaveConfig"`                    // automatically persist config changes to the wgX.conf file
 	CreateDefaultPeer bool   `json:"CreateDefaultPeer"`             // if true, default peers will be created for this interface
 
@@ -48,6 +49,19 @@ type Interface struct {
 	PeerDefPreDown  string `json:"PeerDefPreDown"`  // default action that is executed before the device is down
 	PeerDefPostDown string `json:"PeerDefPostDown"` // default action that is executed after the device is down
 
+	PeerDefBandwidthLimit *BandwidthLimit        `json:"PeerDefBandwidthLimit,omitempty"` // default rate limit for peers, omitted on update keeps the existing limit
+	AccessPolicy          *InterfaceAccessPolicy `json:"AccessPolicy,omitempty"`          // peer isolation and forwarding rules, omitted on update keeps the existing policy
+	OnDemandPolicy        *OnDemandPolicy        `json:"OnDemandPolicy,omitempty"`        // on-demand rules of Apple profiles, omitted on update keeps the existing policy
+
+	InactivityPolicy *InactivityPolicy `json:"InactivityPolicy,omitempty"` // optional policy for inactive peers, omitted on update keeps the existing policy
+	IpamPolicy       *IpamPolicy       `json:"IpamPolicy,omitempty"`       // reserved ranges and address pools, omitted on update keeps the existing policy
+
+	PrefixDelegation *PrefixDelegationPolicy `json:"PrefixDelegation,omitempty"` // IPv6 pool for per-peer prefixes, omitted on update keeps the existing policy
+	NatPolicy        *NatPolicy              `json:"NatPolicy,omitempty"`        // masquerading and forwarding rules, omitted on update keeps the existing policy
+	AmneziaParams    *AmneziaParams          `json:"AmneziaParams,omitempty"`    // AmneziaWG obfuscation, omitted on update keeps the existing parameters
+
+	MaintenanceSchedule *MaintenanceSchedule `json:"MaintenanceSchedule,omitempty"` // scheduled disable/enable actions, omitted on update keeps the existing schedule
+
 	// Calculated values
 
 	EnabledPeers int    `json:"EnabledPeers"`
@@ -65,6 +79,7 @@ func NewInterface(src *domain.Interface, peers []domain.Peer) *Interface {
 		PublicKey:                  src.PublicKey,
 		Disabled:                   src.IsDisabled(),
 		DisabledReason:             src.DisabledReason,
+		ManagedBy:                  src.ManagedBy,
 		SaveConfig:                 src.SaveConfig,
 		CreateDefaultPeer:          src.CreateDefaultPeer,
 		ListenPort:                 src.ListenPort,
@@ -91,6 +106,15 @@ func NewInterface(src *domain.Interface, peers []domain.Peer) *Interface {
 		PeerDefPostUp:              src.PeerDefPostUp,
 		PeerDefPreDown:             src.PeerDefPreDown,
 		PeerDefPostDown:            src.PeerDefPostDown,
+		PeerDefBandwidthLimit:      NewBandwidthLimit(src.PeerDefBandwidthLimit),
+		AccessPolicy:               NewInterfaceAccessPolicy(src.AccessPolicy),
+		OnDemandPolicy:             NewOnDemandPolicy(src.OnDemandPolicy),
+		InactivityPolicy:           NewInactivityPolicy(src.InactivityPolicy),
+		IpamPolicy:                 NewIpamPolicy(src.IpamPolicy),
+		PrefixDelegation:           NewPrefixDelegationPolicy(src.PrefixDelegation),
+		NatPolicy:                  NewNatPolicy(src.NatPolicy),
+		AmneziaParams:              NewAmneziaParams(src.AmneziaParams),
+		MaintenanceSchedule:        NewMaintenanceSchedule(src.MaintenanceSchedule),
 
 		EnabledPeers: 0,
 		TotalPeers:   0,
@@ -173,6 +197,15 @@ func NewDomainInterface(src *Interface) *domain.Interface {
 		PeerDefPostUp:              src.PeerDefPostUp,
 		PeerDefPreDown:             src.PeerDefPreDown,
 		PeerDefPostDown:            src.PeerDefPostDown,
+		PeerDefBandwidthLimit:      NewDomainBandwidthLimit(src.PeerDefBandwidthLimit),
+		AccessPolicy:               NewDomainInterfaceAccessPolicy(src.AccessPolicy),
+		OnDemandPolicy:             NewDomainOnDemandPolicy(src.OnDemandPolicy),
+		InactivityPolicy:           NewDomainInactivityPolicy(src.InactivityPolicy),
+		IpamPolicy:                 NewDomainIpamPolicy(src.IpamPolicy),
+		PrefixDelegation:           NewDomainPrefixDelegationPolicy(src.PrefixDelegation),
+		NatPolicy:                  NewDomainNatPolicy(src.NatPolicy),
+		AmneziaParams:              NewDomainAmneziaParams(src.AmneziaParams),
+		MaintenanceSchedule:        NewDomainMaintenanceSchedule(src.MaintenanceSchedule),
 	}
 
 	if src.Disabled {
@@ -181,3 +214,19 @@ func NewDomainInterface(src *Interface) *domain.Interface {
 
 	return res
 }
+
+type InterfaceCloneRequest struct {
+	Identifier  string `json:"Identifier" binding:"omitempty,max=15"` // optional, a free identifier is generated if empty
+	DisplayName string `json:"DisplayName" binding:"omitempty,max=64"`
+	Backend     string `json:"Backend"` // optional, the backend of the source interface is used if empty
+	ClonePeers  bool   `json:"ClonePeers"`
+}
+
+func NewDomainInterfaceCloneRequest(src *InterfaceCloneRequest) domain.InterfaceCloneRequest {
+	return domain.InterfaceCloneRequest{
+		Identifier:  domain.InterfaceIdentifier(src.Identifier),
+		DisplayName: src.DisplayName,
+		Backend:     domain.InterfaceBackend(src.Backend),
+		ClonePeers:  src.ClonePeers,
+	}
+}
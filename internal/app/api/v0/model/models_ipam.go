@@ -0,0 +1,132 @@
+package model
+
+import (
+	"slices"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type IpamPolicy struct {
+	Reservations []IpReservation `json:"Reservations"` // ranges that are never allocated automatically
+	Pools        []IpPool        `json:"Pools"`        // named ranges that can be selected when creating peers
+}
+
+type IpReservation struct {
+	Range       string `json:"Range"` // single address, network or first-last range
+	Description string `json:"Description"`
+}
+
+type IpPool struct {
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	Ranges      []string `json:"Ranges"`
+}
+
+func NewIpamPolicy(src *domain.IpamPolicy) *IpamPolicy {
+	if src == nil {
+		return nil
+	}
+
+	policy := &IpamPolicy{
+		Reservations: make([]IpReservation, len(src.Reservations)),
+		Pools:        make([]IpPool, len(src.Pools)),
+	}
+	for i, reservation := range src.Reservations {
+		policy.Reservations[i] = IpReservation{Range: reservation.Range, Description: reservation.Description}
+	}
+	for i, pool := range src.Pools {
+		policy.Pools[i] = IpPool{Name: pool.Name, Description: pool.Description, Ranges: slices.Clone(pool.Ranges)}
+	}
+
+	return policy
+}
+
+func NewDomainIpamPolicy(src *IpamPolicy) *domain.IpamPolicy {
+	if src == nil {
+		return nil
+	}
+
+	policy := &domain.IpamPolicy{
+		Reservations: make([]domain.IpReservation, len(src.Reservations)),
+		Pools:        make([]domain.IpPool, len(src.Pools)),
+	}
+	for i, reservation := range src.Reservations {
+		policy.Reservations[i] = domain.IpReservation{Range: reservation.Range, Description: reservation.Description}
+	}
+	for i, pool := range src.Pools {
+		policy.Pools[i] = domain.IpPool{Name: pool.Name, Description: pool.Description, Ranges: slices.Clone(pool.Ranges)}
+	}
+
+	return policy
+}
+
+type IpamAddress struct {
+	Address   string `json:"Address"`
+	Peer      string `json:"Peer,omitempty"`        // set if the address belongs to a peer
+	Interface string `json:"Interface,omitempty"`   // set if the address belongs to an interface
+	Name      string `json:"DisplayName,omitempty"` // only set for peers of the requested interface
+}
+
+type IpamPoolStatus struct {
+	Name   string   `json:"Name"`
+	Ranges []string `json:"Ranges"`
+	Size   string   `json:"Size"` // decimal string, IPv6 networks exceed the range of JSON numbers
+	Free   string   `json:"Free"`
+}
+
+type IpamNetworkStatus struct {
+	Network    string           `json:"Network"`
+	Size       string           `json:"Size"`
+	Free       string           `json:"Free"`
+	Used       []IpamAddress    `json:"Used"`
+	Reserved   []string         `json:"Reserved"`
+	FreeRanges []string         `json:"FreeRanges"`
+	Pools      []IpamPoolStatus `json:"Pools"`
+}
+
+func NewIpamNetworkStatuses(src []domain.IpamNetworkStatus) []IpamNetworkStatus {
+	results := make([]IpamNetworkStatus, len(src))
+	for i := range src {
+		results[i] = NewIpamNetworkStatus(&src[i])
+	}
+
+	return results
+}
+
+func NewIpamNetworkStatus(src *domain.IpamNetworkStatus) IpamNetworkStatus {
+	status := IpamNetworkStatus{
+		Network:    src.Network.String(),
+		Size:       src.Size.String(),
+		Free:       src.Free.String(),
+		Used:       make([]IpamAddress, len(src.Used)),
+		Reserved:   ipRangeStrings(src.Reserved),
+		FreeRanges: ipRangeStrings(src.FreeRanges),
+		Pools:      make([]IpamPoolStatus, len(src.Pools)),
+	}
+	for i, used := range src.Used {
+		status.Used[i] = IpamAddress{
+			Address:   used.Address.String(),
+			Peer:      string(used.PeerIdentifier),
+			Interface: string(used.InterfaceIdentifier),
+			Name:      used.DisplayName,
+		}
+	}
+	for i, pool := range src.Pools {
+		status.Pools[i] = IpamPoolStatus{
+			Name:   pool.Name,
+			Ranges: ipRangeStrings(pool.Ranges),
+			Size:   pool.Size.String(),
+			Free:   pool.Free.String(),
+		}
+	}
+
+	return status
+}
+
+func ipRangeStrings(ranges []domain.IpRange) []string {
+	result := make([]string, len(ranges))
+	for i, r := range ranges {
+		result[i] = r.String()
+	}
+	return result
+}
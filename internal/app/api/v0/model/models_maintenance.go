@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type MaintenanceSchedule struct {
+	Windows []MaintenanceWindow `json:"Windows"`
+}
+
+type MaintenanceWindow struct {
+	Action      string     `json:"Action"`               // disable or enable
+	Start       time.Time  `json:"Start"`                // time of the (first) action
+	End         *time.Time `json:"End,omitempty"`        // a disabled interface is enabled again at this time
+	Recurrence  string     `json:"Recurrence,omitempty"` // daily, weekly, monthly or empty for a single action
+	Description string     `json:"Description,omitempty"`
+
+	NextStart *time.Time `json:"NextStart,omitempty"` // calculated, start of the next occurrence
+	NextEnd   *time.Time `json:"NextEnd,omitempty"`   // calculated, end of the current or next occurrence
+}
+
+func NewMaintenanceSchedule(src *domain.MaintenanceSchedule) *MaintenanceSchedule {
+	if src == nil {
+		return nil
+	}
+
+	now := time.Now()
+	windows := make([]MaintenanceWindow, len(src.Windows))
+	for i, w := range src.Windows {
+		windows[i] = MaintenanceWindow{
+			Action:      string(w.Action),
+			Start:       w.Start,
+			End:         w.End,
+			Recurrence:  string(w.Recurrence),
+			Description: w.Description,
+			NextStart:   w.NextStart(now),
+			NextEnd:     w.NextEnd(now),
+		}
+	}
+
+	return &MaintenanceSchedule{Windows: windows}
+}
+
+func NewDomainMaintenanceSchedule(src *MaintenanceSchedule) *domain.MaintenanceSchedule {
+	if src == nil {
+		return nil
+	}
+
+	windows := make([]domain.MaintenanceWindow, len(src.Windows))
+	for i, w := range src.Windows {
+		windows[i] = domain.MaintenanceWindow{
+			Action:      domain.MaintenanceAction(w.Action),
+			Start:       w.Start,
+			End:         w.End,
+			Recurrence:  domain.MaintenanceRecurrence(w.Recurrence),
+			Description: w.Description,
+		}
+	}
+
+	return &domain.MaintenanceSchedule{Windows: windows}
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"slices"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type OnDemandPolicy struct {
+	TrustedSsids []string `json:"TrustedSsids"` // the tunnel is disconnected on these Wi-Fi networks
+	Wifi         bool     `json:"Wifi"`         // connect on all other Wi-Fi networks
+	Cellular     bool     `json:"Cellular"`     // connect on cellular networks (iOS)
+	Ethernet     bool     `json:"Ethernet"`     // connect on wired networks (macOS)
+}
+
+func NewOnDemandPolicy(src *domain.OnDemandPolicy) *OnDemandPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &OnDemandPolicy{
+		TrustedSsids: slices.Clone(src.TrustedSsids),
+		Wifi:         src.Wifi,
+		Cellular:     src.Cellular,
+		Ethernet:     src.Ethernet,
+	}
+}
+
+func NewDomainOnDemandPolicy(src *OnDemandPolicy) *domain.OnDemandPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.OnDemandPolicy{
+		TrustedSsids: slices.Clone(src.TrustedSsids),
+		Wifi:         src.Wifi,
+		Cellular:     src.Cellular,
+		Ethernet:     src.Ethernet,
+	}
+}
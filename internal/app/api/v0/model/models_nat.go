@@ -0,0 +1,41 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type NatPolicy struct {
+	OutboundInterface string `json:"OutboundInterface"` // uplink of the host, for example eth0
+	Masquerade        bool   `json:"Masquerade"`        // masquerade peer traffic that leaves through the outbound interface
+	AllowForwarding   bool   `json:"AllowForwarding"`   // accept traffic from the peers to the outbound interface and the replies
+	IPv4              bool   `json:"IPv4"`
+	IPv6              bool   `json:"IPv6"`
+}
+
+func NewNatPolicy(src *domain.NatPolicy) *NatPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &NatPolicy{
+		OutboundInterface: src.OutboundInterface,
+		Masquerade:        src.Masquerade,
+		AllowForwarding:   src.AllowForwarding,
+		IPv4:              src.IPv4,
+		IPv6:              src.IPv6,
+	}
+}
+
+func NewDomainNatPolicy(src *NatPolicy) *domain.NatPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.NatPolicy{
+		OutboundInterface: src.OutboundInterface,
+		Masquerade:        src.Masquerade,
+		AllowForwarding:   src.AllowForwarding,
+		IPv4:              src.IPv4,
+		IPv6:              src.IPv6,
+	}
+}
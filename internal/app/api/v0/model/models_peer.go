@@ -47,13 +47,20 @@ type Peer struct {
 	InterfaceIdentifier string     `json:"InterfaceIdentifier"`                  // the interface id
 	Disabled            bool       `json:"Disabled"`                             // flag that specifies if the peer is enabled (up) or not (down)
 	DisabledReason      string     `json:"DisabledReason"`                       // the reason why the peer has been disabled
+	ManagedBy           string     `json:"ManagedBy"`                            // set if the peer is defined by the declarative state file, read-only
 	ExpiresAt           ExpiryDate `json:"ExpiresAt,omitempty"`                  // expiry dates for peers
 	Notes               string     `json:"Notes"`                                // a note field for peers
 
+	AccessSchedule  *AccessSchedule    `json:"AccessSchedule,omitempty"` // optional access schedule, if empty the schedule of the owner is used
+	ScheduleBlocked bool               `json:"ScheduleBlocked"`          // true if the peer is currently outside its access schedule (read-only)
+	BandwidthLimit  *BandwidthLimit    `json:"BandwidthLimit,omitempty"` // optional rate limit, if empty the interface default is used
+	Acl             *AccessControlList `json:"Acl,omitempty"`            // optional destination rules, evaluated before the rules of the owner
+
 	Endpoint            ConfigOption[string]   `json:"Endpoint"`            // the endpoint address
 	EndpointPublicKey   ConfigOption[string]   `json:"EndpointPublicKey"`   // the endpoint public key
 	AllowedIPs          ConfigOption[[]string] `json:"AllowedIPs"`          // all allowed ip subnets, comma seperated
 	ExtraAllowedIPs     []string               `json:"ExtraAllowedIPs"`     // all allowed ip subnets on the server side, comma seperated
+	DelegatedPrefix     string                 `json:"DelegatedPrefix"`     // the IPv6 prefix that is routed to the peer, empty if none
 	PresharedKey        string                 `json:"PresharedKey"`        // the pre-shared Key of the peer
 	PersistentKeepalive ConfigOption[int]      `json:"PersistentKeepalive"` // the persistent keep-alive interval
 
@@ -75,6 +82,8 @@ type Peer struct {
 	PreDown  ConfigOption[string] `json:"PreDown"`  // action that is executed before the device is down
 	PostDown ConfigOption[string] `json:"PostDown"` // action that is executed after the device is down
 
+	AmneziaParams *AmneziaParams `json:"AmneziaParams,omitempty"` // read only, the obfuscation parameters of the interface
+
 	// Calculated values
 
 	Filename string `json:"Filename"` // the filename of the config file, for example: wg_peer_x.conf
@@ -88,12 +97,18 @@ func NewPeer(src *domain.Peer) *Peer {
 		InterfaceIdentifier: string(src.InterfaceIdentifier),
 		Disabled:            src.IsDisabled(),
 		DisabledReason:      src.DisabledReason,
+		ManagedBy:           src.ManagedBy,
 		ExpiresAt:           ExpiryDate{src.ExpiresAt},
 		Notes:               src.Notes,
+		AccessSchedule:      NewAccessSchedule(src.AccessSchedule),
+		BandwidthLimit:      NewBandwidthLimit(src.BandwidthLimit),
+		Acl:                 NewAccessControlList(src.Acl),
+		ScheduleBlocked:     src.IsScheduleBlocked(),
 		Endpoint:            ConfigOptionFromDomain(src.Endpoint),
 		EndpointPublicKey:   ConfigOptionFromDomain(src.EndpointPublicKey),
 		AllowedIPs:          StringSliceConfigOptionFromDomain(src.AllowedIPsStr),
 		ExtraAllowedIPs:     internal.SliceString(src.ExtraAllowedIPsStr),
+		DelegatedPrefix:     src.DelegatedPrefix,
 		PresharedKey:        string(src.PresharedKey),
 		PersistentKeepalive: ConfigOptionFromDomain(src.PersistentKeepalive),
 		PrivateKey:          src.Interface.PrivateKey,
@@ -110,6 +125,7 @@ func NewPeer(src *domain.Peer) *Peer {
 		PostUp:              ConfigOptionFromDomain(src.Interface.PostUp),
 		PreDown:             ConfigOptionFromDomain(src.Interface.PreDown),
 		PostDown:            ConfigOptionFromDomain(src.Interface.PostDown),
+		AmneziaParams:       NewAmneziaParams(src.Interface.AmneziaParams),
 		Filename:            src.GetConfigFileName(),
 	}
 
@@ -140,6 +156,7 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 		EndpointPublicKey:   ConfigOptionToDomain(src.EndpointPublicKey),
 		AllowedIPsStr:       StringSliceConfigOptionToDomain(src.AllowedIPs),
 		ExtraAllowedIPsStr:  internal.SliceToString(src.ExtraAllowedIPs),
+		DelegatedPrefix:     src.DelegatedPrefix,
 		PresharedKey:        domain.PreSharedKey(src.PresharedKey),
 		PersistentKeepalive: ConfigOptionToDomain(src.PersistentKeepalive),
 		DisplayName:         src.DisplayName,
@@ -150,6 +167,9 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 		DisabledReason:      src.DisabledReason,
 		ExpiresAt:           src.ExpiresAt.Time,
 		Notes:               src.Notes,
+		AccessSchedule:      NewDomainAccessSchedule(src.AccessSchedule),
+		BandwidthLimit:      NewDomainBandwidthLimit(src.BandwidthLimit),
+		Acl:                 NewDomainAccessControlList(src.Acl),
 		Interface: domain.PeerInterfaceConfig{
 			KeyPair: domain.KeyPair{
 				PrivateKey: src.PrivateKey,
@@ -180,18 +200,42 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 type MultiPeerRequest struct {
 	Identifiers []string `json:"Identifiers"`
 	Prefix      string   `json:"Prefix"`
+	IpPool      string   `json:"IpPool"` // optional ip pool of the interface to allocate addresses from
 }
 
 func NewDomainPeerCreationRequest(src *MultiPeerRequest) *domain.PeerCreationRequest {
 	return &domain.PeerCreationRequest{
 		UserIdentifiers: src.Identifiers,
 		Prefix:          src.Prefix,
+		IpPool:          src.IpPool,
 	}
 }
 
 type PeerMailRequest struct {
-	Identifiers []string `json:"Identifiers"`
-	LinkOnly    bool     `json:"LinkOnly"`
+	Identifiers  []string `json:"Identifiers"`
+	LinkOnly     bool     `json:"LinkOnly"`
+	MobileConfig string   `json:"MobileConfig" binding:"omitempty,oneof=ios macos"` // attach an Apple profile for this platform
+}
+
+// ConfigBundleRequest selects the peers of a configuration bundle mail. Exactly one selection must be set.
+type ConfigBundleRequest struct {
+	UserIdentifier      string   `json:"UserIdentifier"`
+	InterfaceIdentifier string   `json:"InterfaceIdentifier"`
+	Identifiers         []string `json:"Identifiers"`
+}
+
+func NewDomainConfigBundleRequest(src *ConfigBundleRequest, style string) domain.ConfigBundleRequest {
+	peerIds := make([]domain.PeerIdentifier, len(src.Identifiers))
+	for i := range src.Identifiers {
+		peerIds[i] = domain.PeerIdentifier(src.Identifiers[i])
+	}
+
+	return domain.ConfigBundleRequest{
+		UserIdentifier:      domain.UserIdentifier(src.UserIdentifier),
+		InterfaceIdentifier: domain.InterfaceIdentifier(src.InterfaceIdentifier),
+		PeerIdentifiers:     peerIds,
+		Style:               style,
+	}
 }
 
 type PeerStats struct {
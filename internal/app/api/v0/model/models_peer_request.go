@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PeerRequest struct {
+	Identifier          string `json:"Identifier"`                        // unique identifier of the request
+	UserIdentifier      string `json:"UserIdentifier"`                    // the requesting user, defaults to the authenticated user
+	InterfaceIdentifier string `json:"InterfaceIdentifier" example:"wg0"` // interface of the requested peer
+	DisplayName         string `json:"DisplayName" example:"My Laptop"`   // device name of the requested peer
+	Justification       string `json:"Justification"`                     // why the peer is needed
+
+	State          string     `json:"State" readonly:"true" example:"pending"` // pending, approved or rejected
+	DecidedBy      string     `json:"DecidedBy" readonly:"true"`               // the admin that approved or rejected the request
+	DecidedAt      *time.Time `json:"DecidedAt,omitempty" readonly:"true"`     // time of the decision
+	DecisionReason string     `json:"DecisionReason" readonly:"true"`          // comment of the admin
+	PeerIdentifier string     `json:"PeerIdentifier" readonly:"true"`          // the peer that has been created on approval
+	CreatedAt      time.Time  `json:"CreatedAt" readonly:"true"`               // time of the request
+}
+
+type PeerRequestDecision struct {
+	Reason string `json:"Reason"` // optional comment, sent to the user if the request is rejected
+}
+
+func NewPeerRequest(src *domain.PeerRequest) *PeerRequest {
+	return &PeerRequest{
+		Identifier:          string(src.Identifier),
+		UserIdentifier:      string(src.UserIdentifier),
+		InterfaceIdentifier: string(src.InterfaceIdentifier),
+		DisplayName:         src.DisplayName,
+		Justification:       src.Justification,
+		State:               string(src.State),
+		DecidedBy:           src.DecidedBy,
+		DecidedAt:           src.DecidedAt,
+		DecisionReason:      src.DecisionReason,
+		PeerIdentifier:      string(src.PeerIdentifier),
+		CreatedAt:           src.CreatedAt,
+	}
+}
+
+func NewPeerRequests(src []domain.PeerRequest) []PeerRequest {
+	results := make([]PeerRequest, len(src))
+	for i := range src {
+		results[i] = *NewPeerRequest(&src[i])
+	}
+
+	return results
+}
+
+// NewDomainPeerRequest converts the fields that can be set by the requesting user.
+func NewDomainPeerRequest(src *PeerRequest) *domain.PeerRequest {
+	return &domain.PeerRequest{
+		UserIdentifier:      domain.UserIdentifier(src.UserIdentifier),
+		InterfaceIdentifier: domain.InterfaceIdentifier(src.InterfaceIdentifier),
+		DisplayName:         src.DisplayName,
+		Justification:       src.Justification,
+	}
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PrefixDelegationPolicy struct {
+	Pool         string `json:"Pool"`         // IPv6 network that is split into the delegated prefixes
+	PrefixLength int    `json:"PrefixLength"` // length of the prefix of each peer
+}
+
+func NewPrefixDelegationPolicy(src *domain.PrefixDelegationPolicy) *PrefixDelegationPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &PrefixDelegationPolicy{Pool: src.Pool, PrefixLength: src.PrefixLength}
+}
+
+func NewDomainPrefixDelegationPolicy(src *PrefixDelegationPolicy) *domain.PrefixDelegationPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.PrefixDelegationPolicy{Pool: src.Pool, PrefixLength: src.PrefixLength}
+}
+
+type DelegatedPrefix struct {
+	PeerIdentifier string `json:"PeerIdentifier"`
+	DisplayName    string `json:"DisplayName"`
+	Prefix         string `json:"Prefix"`
+	Index          uint64 `json:"Index"`  // position within the delegation pool, kept if the pool is renumbered
+	InPool         bool   `json:"InPool"` // false if the prefix is outside the current pool
+}
+
+func NewDelegatedPrefixes(src []domain.DelegatedPrefixAssignment) []DelegatedPrefix {
+	results := make([]DelegatedPrefix, len(src))
+	for i, assignment := range src {
+		results[i] = DelegatedPrefix{
+			PeerIdentifier: string(assignment.PeerIdentifier),
+			DisplayName:    assignment.DisplayName,
+			Prefix:         assignment.Prefix.String(),
+			Index:          assignment.Index,
+			InPool:         assignment.InPool,
+		}
+	}
+
+	return results
+}
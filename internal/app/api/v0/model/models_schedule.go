@@ -0,0 +1,56 @@
+package model
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type AccessWindow struct {
+	Weekdays []string `json:"Weekdays" example:"mon,tue"` // lowercase weekday names, empty means every day
+	Start    string   `json:"Start" example:"08:00"`      // start time in 24h format
+	End      string   `json:"End" example:"17:30"`        // end time in 24h format, windows may span midnight
+}
+
+type AccessSchedule struct {
+	TimeZone string         `json:"TimeZone" example:"Europe/Vienna"` // IANA time zone name, empty means UTC
+	Windows  []AccessWindow `json:"Windows"`                          // time windows in which access is allowed
+}
+
+func NewAccessSchedule(src *domain.AccessSchedule) *AccessSchedule {
+	if src == nil {
+		return nil
+	}
+
+	windows := make([]AccessWindow, len(src.Windows))
+	for i, w := range src.Windows {
+		windows[i] = AccessWindow{
+			Weekdays: w.Weekdays,
+			Start:    w.Start,
+			End:      w.End,
+		}
+	}
+
+	return &AccessSchedule{
+		TimeZone: src.TimeZone,
+		Windows:  windows,
+	}
+}
+
+func NewDomainAccessSchedule(src *AccessSchedule) *domain.AccessSchedule {
+	if src == nil {
+		return nil
+	}
+
+	windows := make([]domain.AccessWindow, len(src.Windows))
+	for i, w := range src.Windows {
+		windows[i] = domain.AccessWindow{
+			Weekdays: w.Weekdays,
+			Start:    w.Start,
+			End:      w.End,
+		}
+	}
+
+	return &domain.AccessSchedule{
+		TimeZone: src.TimeZone,
+		Windows:  windows,
+	}
+}
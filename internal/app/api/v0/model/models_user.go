@@ -22,6 +22,7 @@ type User struct {
 	Password       string `json:"Password,omitempty"`
 	Disabled       bool   `json:"Disabled"`       // if this field is set, the user is disabled
 	DisabledReason string `json:"DisabledReason"` // the reason why the user has been disabled
+	ManagedBy      string `json:"ManagedBy"`      // set if the user is defined by the declarative state file, read-only
 	Locked         bool   `json:"Locked"`         // if this field is set, the user is locked
 	LockedReason   string `json:"LockedReason"`   // the reason why the user has been locked
 
@@ -31,6 +32,9 @@ type User struct {
 
 	PersistLocalChanges bool `json:"PersistLocalChanges"`
 
+	AccessSchedule *AccessSchedule    `json:"AccessSchedule,omitempty"` // default access schedule for all peers of the user
+	Acl            *AccessControlList `json:"Acl,omitempty"`            // destination rules for all peers of the user
+
 	// Calculated
 
 	PeerCount int `json:"PeerCount"`
@@ -52,12 +56,15 @@ func NewUser(src *domain.User, exposeCreds bool) *User {
 		Password:            "", // never fill password
 		Disabled:            src.IsDisabled(),
 		DisabledReason:      src.DisabledReason,
+		ManagedBy:           src.ManagedBy,
 		Locked:              src.IsLocked(),
 		LockedReason:        src.LockedReason,
 		ApiToken:            "", // by default, do not expose API token
 		ApiTokenCreated:     src.ApiTokenCreated,
 		ApiEnabled:          src.IsApiEnabled(),
 		PersistLocalChanges: src.PersistLocalChanges,
+		AccessSchedule:      NewAccessSchedule(src.AccessSchedule),
+		Acl:                 NewAccessControlList(src.Acl),
 
 		PeerCount: src.LinkedPeerCount,
 	}
@@ -96,6 +103,8 @@ func NewDomainUser(src *User) *domain.User {
 		LockedReason:        src.LockedReason,
 		LinkedPeerCount:     src.PeerCount,
 		PersistLocalChanges: src.PersistLocalChanges,
+		AccessSchedule:      NewDomainAccessSchedule(src.AccessSchedule),
+		Acl:                 NewDomainAccessControlList(src.Acl),
 	}
 
 	if src.Disabled {
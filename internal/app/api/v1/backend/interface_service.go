@@ -13,8 +13,15 @@ type InterfaceServiceInterfaceManagerRepo interface {
 	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
 	PrepareInterface(ctx context.Context) (*domain.Interface, error)
 	CreateInterface(ctx context.Context, in *domain.Interface) (*domain.Interface, error)
+	CloneInterface(ctx context.Context, id domain.InterfaceIdentifier, req domain.InterfaceCloneRequest) (
+		*domain.Interface,
+		[]domain.Peer,
+		error,
+	)
 	UpdateInterface(ctx context.Context, in *domain.Interface) (*domain.Interface, []domain.Peer, error)
 	DeleteInterface(ctx context.Context, id domain.InterfaceIdentifier) error
+	GetIpamStatus(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.IpamNetworkStatus, error)
+	GetDelegatedPrefixes(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.DelegatedPrefixAssignment, error)
 }
 
 type InterfaceService struct {
@@ -87,6 +94,18 @@ func (s InterfaceService) Create(ctx context.Context, iface *domain.Interface) (
 	return createdInterface, nil
 }
 
+func (s InterfaceService) Clone(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	req *domain.InterfaceCloneRequest,
+) (*domain.Interface, []domain.Peer, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return s.interfaces.CloneInterface(ctx, id, *req)
+}
+
 func (s InterfaceService) Update(ctx context.Context, id domain.InterfaceIdentifier, iface *domain.Interface) (
 	*domain.Interface,
 	[]domain.Peer,
@@ -121,3 +140,25 @@ func (s InterfaceService) Delete(ctx context.Context, id domain.InterfaceIdentif
 
 	return nil
 }
+
+func (s InterfaceService) GetIpamStatus(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.IpamNetworkStatus,
+	error,
+) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.interfaces.GetIpamStatus(ctx, id)
+}
+
+func (s InterfaceService) GetDelegatedPrefixes(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.DelegatedPrefixAssignment,
+	error,
+) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.interfaces.GetDelegatedPrefixes(ctx, id)
+}
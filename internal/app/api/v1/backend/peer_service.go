@@ -13,10 +13,12 @@ type PeerServicePeerManagerRepo interface {
 	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
 	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
 	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
-	PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
+	PreparePeerFromPool(ctx context.Context, id domain.InterfaceIdentifier, pool string) (*domain.Peer, error)
 	CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
 	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
 	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
+	GetDeletedPeers(ctx context.Context) ([]domain.Peer, error)
+	RestorePeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
 }
 
 type PeerServiceUserManagerRepo interface {
@@ -96,12 +98,12 @@ func (s PeerService) GetById(ctx context.Context, id domain.PeerIdentifier) (*do
 	return peer, nil
 }
 
-func (s PeerService) Prepare(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
+func (s PeerService) Prepare(ctx context.Context, id domain.InterfaceIdentifier, pool string) (*domain.Peer, error) {
 	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
 		return nil, err
 	}
 
-	peer, err := s.peers.PreparePeer(ctx, id)
+	peer, err := s.peers.PreparePeerFromPool(ctx, id, pool)
 	if err != nil {
 		return nil, err
 	}
@@ -155,3 +157,29 @@ func (s PeerService) Delete(ctx context.Context, id domain.PeerIdentifier) error
 
 	return nil
 }
+
+func (s PeerService) GetDeleted(ctx context.Context) ([]domain.Peer, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	deletedPeers, err := s.peers.GetDeletedPeers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return deletedPeers, nil
+}
+
+func (s PeerService) Restore(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	restoredPeer, err := s.peers.RestorePeer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return restoredPeer, nil
+}
@@ -25,6 +25,12 @@ type ProvisioningServicePeerManagerRepo interface {
 type ProvisioningServiceConfigFileManagerRepo interface {
 	GetPeerConfig(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error)
 	GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error)
+	GetPeerMobileConfig(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		platform domain.MobileConfigPlatform,
+	) (io.Reader, error)
+	WritePeerConfigBundle(ctx context.Context, w io.Writer, req domain.ConfigBundleRequest) error
 }
 
 type ProvisioningService struct {
@@ -132,6 +138,49 @@ func (p ProvisioningService) GetPeerQrPng(ctx context.Context, peerId domain.Pee
 	return peerCfgQrData, nil
 }
 
+// GetPeerMobileConfig returns the file name and content of the Apple configuration profile of the given peer.
+func (p ProvisioningService) GetPeerMobileConfig(
+	ctx context.Context,
+	peerId domain.PeerIdentifier,
+	platform string,
+) (string, []byte, error) {
+	mobileConfigPlatform, err := domain.ParseMobileConfigPlatform(platform)
+	if err != nil {
+		return "", nil, err
+	}
+
+	peer, err := p.peers.GetPeer(ctx, peerId)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+		return "", nil, err
+	}
+
+	profileReader, err := p.configFiles.GetPeerMobileConfig(ctx, peer.Identifier, mobileConfigPlatform)
+	if err != nil {
+		return "", nil, err
+	}
+
+	profileData, err := io.ReadAll(profileReader)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return peer.GetMobileConfigFileName(), profileData, nil
+}
+
+// WritePeerConfigBundle writes a zip archive with the configurations of the selected peers.
+// Permissions are checked for every peer before the archive is written.
+func (p ProvisioningService) WritePeerConfigBundle(
+	ctx context.Context,
+	w io.Writer,
+	req domain.ConfigBundleRequest,
+) error {
+	return p.configFiles.WritePeerConfigBundle(ctx, w, req)
+}
+
 func (p ProvisioningService) NewPeer(ctx context.Context, req models.ProvisioningRequest) (*domain.Peer, error) {
 	if req.UserIdentifier == "" {
 		req.UserIdentifier = string(domain.GetUserInfo(ctx).Id) // use authenticated user id if not set
@@ -15,6 +15,8 @@ type UserManagerRepo interface {
 	CreateUser(ctx context.Context, user *domain.User) (*domain.User, error)
 	UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error)
 	DeleteUser(ctx context.Context, id domain.UserIdentifier) error
+	GetDeletedUsers(ctx context.Context) ([]domain.User, error)
+	RestoreUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
 }
 
 type UserService struct {
@@ -105,3 +107,29 @@ func (s UserService) Delete(ctx context.Context, id domain.UserIdentifier) error
 
 	return nil
 }
+
+func (s UserService) GetDeleted(ctx context.Context) ([]domain.User, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	deletedUsers, err := s.users.GetDeletedUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return deletedUsers, nil
+}
+
+func (s UserService) Restore(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	restoredUser, err := s.users.RestoreUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return restoredUser, nil
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// backupPassphraseHeader carries the passphrase of backup archives, so that it does not end up in access logs.
+const backupPassphraseHeader = "X-Backup-Passphrase"
+
+type BackupService interface {
+	CreateBackup(ctx context.Context, w io.Writer, passphrase string) (*domain.BackupSummary, error)
+	RestoreBackup(ctx context.Context, r io.Reader, passphrase string) (*domain.BackupSummary, error)
+}
+
+type BackupEndpoint struct {
+	backups       BackupService
+	authenticator Authenticator
+}
+
+func NewBackupEndpoint(
+	authenticator Authenticator,
+	backupService BackupService,
+) *BackupEndpoint {
+	return &BackupEndpoint{
+		authenticator: authenticator,
+		backups:       backupService,
+	}
+}
+
+func (e BackupEndpoint) GetName() string {
+	return "BackupEndpoint"
+}
+
+func (e BackupEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/backup")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("POST /create", e.handleCreatePost())
+	apiGroup.HandleFunc("POST /restore", e.handleRestorePost())
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID backup_handleCreatePost
+// @Tags Backup
+// @Summary Create a backup archive of all data.
+// @Description The archive contains users, interfaces, peers, statuses and audit entries. If no passphrase is given,
+// @Description all private keys are stored in plaintext.
+// @Param X-Backup-Passphrase header string false "The passphrase that encrypts the archive."
+// @Produce json
+// @Success 200 {file} binary
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /backup/create [post]
+// @Security BasicAuth
+func (e BackupEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		_, err := e.backups.CreateBackup(r.Context(), &buf, r.Header.Get(backupPassphraseHeader))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		filename := fmt.Sprintf("wg-portal-backup-%s.json", time.Now().Format("20060102-150405"))
+		respond.Attachment(w, http.StatusOK, filename, "application/json", buf.Bytes())
+	}
+}
+
+// handleRestorePost returns a gorm Handler function.
+//
+// @ID backup_handleRestorePost
+// @Tags Backup
+// @Summary Replace all data with the contents of a backup archive.
+// @Description The backup must have been created with the same database schema version.
+// @Description WireGuard Portal must be restarted after the restore.
+// @Param X-Backup-Passphrase header string false "The passphrase of an encrypted archive."
+// @Param request body string true "The backup archive."
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.BackupSummary
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /backup/restore [post]
+// @Security BasicAuth
+func (e BackupEndpoint) handleRestorePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			_ = r.Body.Close()
+		}()
+
+		summary, err := e.backups.RestoreBackup(r.Context(), r.Body, r.Header.Get(backupPassphraseHeader))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewBackupSummary(summary))
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// maxBulkImportSize limits the size of the uploaded import data.
+const maxBulkImportSize = 16 << 20 // 16 MiB
+
+type BulkService interface {
+	ExportUsers(ctx context.Context, format domain.BulkFormat, w io.Writer) error
+	ExportPeers(
+		ctx context.Context,
+		format domain.BulkFormat,
+		w io.Writer,
+		opts domain.BulkExportOptions,
+		ifaces ...domain.InterfaceIdentifier,
+	) error
+	ImportUsers(ctx context.Context, format domain.BulkFormat, r io.Reader, opts domain.BulkImportOptions) (
+		*domain.BulkImportResult,
+		error,
+	)
+	ImportPeers(ctx context.Context, format domain.BulkFormat, r io.Reader, opts domain.BulkImportOptions) (
+		*domain.BulkImportResult,
+		error,
+	)
+}
+
+type BulkEndpoint struct {
+	bulk          BulkService
+	authenticator Authenticator
+}
+
+func NewBulkEndpoint(
+	authenticator Authenticator,
+	bulkService BulkService,
+) *BulkEndpoint {
+	return &BulkEndpoint{
+		authenticator: authenticator,
+		bulk:          bulkService,
+	}
+}
+
+func (e BulkEndpoint) GetName() string {
+	return "BulkEndpoint"
+}
+
+func (e BulkEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/bulk")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /users/export", e.handleUsersExportGet())
+	apiGroup.HandleFunc("GET /peers/export", e.handlePeersExportGet())
+	apiGroup.HandleFunc("POST /users/import", e.handleUsersImportPost())
+	apiGroup.HandleFunc("POST /peers/import", e.handlePeersImportPost())
+}
+
+// handleUsersExportGet returns a gorm Handler function.
+//
+// @ID bulk_handleUsersExportGet
+// @Tags Bulk
+// @Summary Export all user records as CSV or JSON file.
+// @Description Passwords are never exported. Only admins can export records.
+// @Param format query string false "The file format, either csv or json (default)."
+// @Produce json
+// @Produce text/csv
+// @Success 200 {file} binary
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /bulk/users/export [get]
+// @Security BasicAuth
+func (e BulkEndpoint) handleUsersExportGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.export(w, r, "users", func(format domain.BulkFormat, buf io.Writer) error {
+			return e.bulk.ExportUsers(r.Context(), format, buf)
+		})
+	}
+}
+
+// handlePeersExportGet returns a gorm Handler function.
+//
+// @ID bulk_handlePeersExportGet
+// @Tags Bulk
+// @Summary Export peer records as CSV or JSON file.
+// @Description Each record references its interface and owner by identifier. Only admins can export records.
+// @Param format query string false "The file format, either csv or json (default)."
+// @Param interface query []string false "Only export peers of the given interfaces."
+// @Param includeKeys query bool false "Also export the private and pre-shared keys of the peers in plaintext."
+// @Produce json
+// @Produce text/csv
+// @Success 200 {file} binary
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /bulk/peers/export [get]
+// @Security BasicAuth
+func (e BulkEndpoint) handlePeersExportGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		interfaceIds := make([]domain.InterfaceIdentifier, 0)
+		for _, id := range request.QuerySlice(r, "interface") {
+			interfaceIds = append(interfaceIds, domain.InterfaceIdentifier(id))
+		}
+		includeKeys, _ := strconv.ParseBool(request.QueryDefault(r, "includeKeys", "false"))
+
+		e.export(w, r, "peers", func(format domain.BulkFormat, buf io.Writer) error {
+			return e.bulk.ExportPeers(r.Context(), format, buf,
+				domain.BulkExportOptions{IncludeKeys: includeKeys}, interfaceIds...)
+		})
+	}
+}
+
+// handleUsersImportPost returns a gorm Handler function.
+//
+// @ID bulk_handleUsersImportPost
+// @Tags Bulk
+// @Summary Import user records from a CSV or JSON file.
+// @Description The request body contains the file content. Each record is imported on its own,
+// @Description failed records are listed in the result. Database users require a password.
+// @Param format query string false "The file format, either csv or json (default)."
+// @Param dryRun query bool false "Only validate the records, do not persist them."
+// @Param upsert query bool false "Update existing users instead of reporting them as duplicates."
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Success 200 {object} models.BulkImportResult
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /bulk/users/import [post]
+// @Security BasicAuth
+func (e BulkEndpoint) handleUsersImportPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.doImport(w, r, e.bulk.ImportUsers)
+	}
+}
+
+// handlePeersImportPost returns a gorm Handler function.
+//
+// @ID bulk_handlePeersImportPost
+// @Tags Bulk
+// @Summary Import peer records from a CSV or JSON file.
+// @Description The request body contains the file content. Referenced interfaces and owners must exist.
+// @Description Peers without public key or addresses get fresh values, just like newly prepared peers.
+// @Param format query string false "The file format, either csv or json (default)."
+// @Param dryRun query bool false "Only validate the records, do not persist them."
+// @Param upsert query bool false "Update existing peers instead of reporting them as duplicates."
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Success 200 {object} models.BulkImportResult
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /bulk/peers/import [post]
+// @Security BasicAuth
+func (e BulkEndpoint) handlePeersImportPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e.doImport(w, r, e.bulk.ImportPeers)
+	}
+}
+
+func (e BulkEndpoint) export(
+	w http.ResponseWriter,
+	r *http.Request,
+	name string,
+	exportFn func(format domain.BulkFormat, buf io.Writer) error,
+) {
+	format, err := domain.ParseBulkFormat(request.Query(r, "format"))
+	if err != nil {
+		status, model := ParseServiceError(err)
+		respond.JSON(w, status, model)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := exportFn(format, &buf); err != nil {
+		status, model := ParseServiceError(err)
+		respond.JSON(w, status, model)
+		return
+	}
+
+	contentType := "application/json"
+	if format == domain.BulkFormatCsv {
+		contentType = "text/csv"
+	}
+	respond.Attachment(w, http.StatusOK, fmt.Sprintf("%s.%s", name, format), contentType, buf.Bytes())
+}
+
+func (e BulkEndpoint) doImport(
+	w http.ResponseWriter,
+	r *http.Request,
+	importFn func(context.Context, domain.BulkFormat, io.Reader, domain.BulkImportOptions) (
+		*domain.BulkImportResult,
+		error,
+	),
+) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkImportSize)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	format, err := domain.ParseBulkFormat(request.Query(r, "format"))
+	if err != nil {
+		status, model := ParseServiceError(err)
+		respond.JSON(w, status, model)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(request.QueryDefault(r, "dryRun", "false"))
+	upsert, _ := strconv.ParseBool(request.QueryDefault(r, "upsert", "false"))
+
+	result, err := importFn(r.Context(), format, r.Body, domain.BulkImportOptions{DryRun: dryRun, Upsert: upsert})
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respond.JSON(w, http.StatusRequestEntityTooLarge, models.Error{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("import data exceeds %d bytes", maxBytesErr.Limit),
+		})
+		return
+	}
+	if err != nil {
+		status, model := ParseServiceError(err)
+		respond.JSON(w, status, model)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, models.NewBulkImportResult(result))
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type DeclarativeService interface {
+	Plan(ctx context.Context, data []byte) (*domain.DeclarativePlan, error)
+	Apply(ctx context.Context, data []byte, dryRun bool) (*domain.DeclarativePlan, error)
+}
+
+type DeclarativeEndpoint struct {
+	declarative   DeclarativeService
+	authenticator Authenticator
+}
+
+func NewDeclarativeEndpoint(
+	authenticator Authenticator,
+	declarativeService DeclarativeService,
+) *DeclarativeEndpoint {
+	return &DeclarativeEndpoint{
+		authenticator: authenticator,
+		declarative:   declarativeService,
+	}
+}
+
+func (e DeclarativeEndpoint) GetName() string {
+	return "DeclarativeEndpoint"
+}
+
+func (e DeclarativeEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/state")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("POST /plan", e.handlePlanPost())
+	apiGroup.HandleFunc("POST /apply", e.handleApplyPost())
+}
+
+// handlePlanPost returns a gorm Handler function.
+//
+// @ID state_handlePlanPost
+// @Tags Declarative State
+// @Summary Compute the changes that are required to reach the declarative state.
+// @Description The state is read from the YAML request body. If the body is empty, the configured state file is used.
+// @Accept plain
+// @Param request body string false "The declarative state in YAML format."
+// @Produce json
+// @Success 200 {object} models.DeclarativePlan
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /state/plan [post]
+// @Security BasicAuth
+func (e DeclarativeEndpoint) handlePlanPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := readStateBody(r)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		plan, err := e.declarative.Plan(r.Context(), data)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDeclarativePlan(plan))
+	}
+}
+
+// handleApplyPost returns a gorm Handler function.
+//
+// @ID state_handleApplyPost
+// @Tags Declarative State
+// @Summary Apply the declarative state.
+// @Description The state is read from the YAML request body. If the body is empty, the configured state file is used.
+// @Description Failed changes do not abort the run, they are listed in the returned plan.
+// @Accept plain
+// @Param request body string false "The declarative state in YAML format."
+// @Param dryRun query bool false "Only plan the changes, do not apply them."
+// @Produce json
+// @Success 200 {object} models.DeclarativePlan
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /state/apply [post]
+// @Security BasicAuth
+func (e DeclarativeEndpoint) handleApplyPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dryRun, err := strconv.ParseBool(request.QueryDefault(r, "dryRun", "false"))
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "invalid dryRun flag"})
+			return
+		}
+
+		data, err := readStateBody(r)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		plan, err := e.declarative.Apply(r.Context(), data, dryRun)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDeclarativePlan(plan))
+	}
+}
+
+func readStateBody(r *http.Request) ([]byte, error) {
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	return io.ReadAll(r.Body)
+}
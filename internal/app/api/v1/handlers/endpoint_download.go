@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type DownloadService interface {
+	CreateDownloadToken(ctx context.Context, id domain.PeerIdentifier, opts domain.DownloadTokenOptions) (
+		*domain.DownloadToken,
+		string,
+		error,
+	)
+	GetActiveDownloadTokens(ctx context.Context) ([]domain.DownloadToken, error)
+	GetPeerDownloadTokens(ctx context.Context, id domain.PeerIdentifier) ([]domain.DownloadToken, error)
+	RevokeDownloadToken(ctx context.Context, id domain.DownloadTokenIdentifier) (*domain.DownloadToken, error)
+	Download(ctx context.Context, signedToken, client string) (string, io.Reader, error)
+}
+
+type DownloadEndpoint struct {
+	cfg           *config.Config
+	downloads     DownloadService
+	authenticator Authenticator
+	validator     Validator
+}
+
+func NewDownloadEndpoint(
+	cfg *config.Config,
+	authenticator Authenticator,
+	validator Validator,
+	downloadService DownloadService,
+) *DownloadEndpoint {
+	return &DownloadEndpoint{
+		cfg:           cfg,
+		authenticator: authenticator,
+		validator:     validator,
+		downloads:     downloadService,
+	}
+}
+
+func (e DownloadEndpoint) GetName() string {
+	return "DownloadEndpoint"
+}
+
+func (e DownloadEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	// the download itself is authorized by the signed token
+	downloadGroup := g.Mount("/download")
+	downloadGroup.HandleFunc("GET /{token}", e.handleDownloadGet())
+
+	apiGroup := g.Mount("/download-token")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /active", e.handleActiveGet())
+	apiGroup.HandleFunc("GET /by-peer/{id...}", e.handlePeerTokensGet())
+	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("DELETE /by-id/{id}", e.handleRevokeDelete())
+}
+
+// handleDownloadGet returns a gorm Handler function.
+//
+// @ID download_handleDownloadGet
+// @Tags Downloads
+// @Summary Download a peer configuration using a download link.
+// @Description This endpoint does not require authentication. The signed token grants access to the configuration
+// @Description or QR code of a single peer until it expires, is used up or is revoked by an admin.
+// @Description Every download is recorded in the audit log.
+// @Param token path string true "The signed download token."
+// @Produce plain
+// @Produce png
+// @Produce json
+// @Success 200 {file} binary "The WireGuard configuration file or QR code"
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /download/{token} [get]
+func (e DownloadEndpoint) handleDownloadGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := request.Path(r, "token")
+		if token == "" {
+			respond.JSON(w, http.StatusNotFound,
+				models.Error{Code: http.StatusNotFound, Message: "missing download token"})
+			return
+		}
+
+		filename, data, err := e.downloads.Download(r.Context(), token,
+			request.ClientIp(r, request.CheckPrivateProxy))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			if status == http.StatusBadRequest || status == http.StatusForbidden {
+				// do not reveal why a link is not usable
+				status = http.StatusForbidden
+				model = models.Error{Code: status, Message: "download link is invalid or expired"}
+			}
+			respond.JSON(w, status, model)
+			return
+		}
+
+		contentType := "text/plain"
+		if strings.HasSuffix(filename, ".png") {
+			contentType = "image/png"
+		}
+
+		respond.AttachmentReader(w, http.StatusOK, filename, contentType, 0, data)
+	}
+}
+
+// handleActiveGet returns a gorm Handler function.
+//
+// @ID download_handleActiveGet
+// @Tags Downloads
+// @Summary Get all download tokens that can still be used.
+// @Produce json
+// @Success 200 {object} []models.DownloadToken
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /download-token/active [get]
+// @Security BasicAuth
+func (e DownloadEndpoint) handleActiveGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokens, err := e.downloads.GetActiveDownloadTokens(r.Context())
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDownloadTokens(tokens))
+	}
+}
+
+// handlePeerTokensGet returns a gorm Handler function.
+//
+// @ID download_handlePeerTokensGet
+// @Tags Downloads
+// @Summary Get all download tokens of a peer.
+// @Description Normal users can only access tokens of their own peers. Admins can access all tokens.
+// @Param id path string true "The peer identifier (public key)."
+// @Produce json
+// @Success 200 {object} []models.DownloadToken
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /download-token/by-peer/{id} [get]
+// @Security BasicAuth
+func (e DownloadEndpoint) handlePeerTokensGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		tokens, err := e.downloads.GetPeerDownloadTokens(r.Context(), domain.PeerIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDownloadTokens(tokens))
+	}
+}
+
+// handleCreatePost returns a gorm Handler function.
+//
+// @ID download_handleCreatePost
+// @Tags Downloads
+// @Summary Create a new download link for a peer configuration.
+// @Description The returned link can be opened without authentication, for example by external partners.
+// @Description The signed token is only returned once. Normal users can only create links for their own peers.
+// @Param request body models.DownloadTokenRequest true "The download token settings."
+// @Produce json
+// @Success 200 {object} models.DownloadTokenCreated
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /download-token/new [post]
+// @Security BasicAuth
+func (e DownloadEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.DownloadTokenRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		token, signedToken, err := e.downloads.CreateDownloadToken(r.Context(),
+			domain.PeerIdentifier(req.PeerIdentifier), models.NewDomainDownloadTokenOptions(&req))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.DownloadTokenCreated{
+			Token:       *models.NewDownloadToken(token),
+			SignedToken: signedToken,
+			DownloadUrl: fmt.Sprintf("%s%s/api/v1/download/%s", e.cfg.Web.ExternalUrl, e.cfg.Web.BasePath,
+				signedToken),
+		})
+	}
+}
+
+// handleRevokeDelete returns a gorm Handler function.
+//
+// @ID download_handleRevokeDelete
+// @Tags Downloads
+// @Summary Revoke a download token.
+// @Description The token can no longer be used. The token record is kept for reference.
+// @Param id path string true "The download token identifier."
+// @Produce json
+// @Success 200 {object} models.DownloadToken
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /download-token/by-id/{id} [delete]
+// @Security BasicAuth
+func (e DownloadEndpoint) handleRevokeDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing token id"})
+			return
+		}
+
+		token, err := e.downloads.RevokeDownloadToken(r.Context(), domain.DownloadTokenIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDownloadToken(token))
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type DriftService interface {
+	GetDriftReports(ctx context.Context) ([]domain.DriftReport, error)
+	CheckDrift(ctx context.Context) ([]domain.DriftReport, error)
+	HealDrift(ctx context.Context, id domain.InterfaceIdentifier, importUnknown bool) (*domain.DriftReport, error)
+}
+
+type DriftEndpoint struct {
+	drift         DriftService
+	authenticator Authenticator
+}
+
+func NewDriftEndpoint(
+	authenticator Authenticator,
+	driftService DriftService,
+) *DriftEndpoint {
+	return &DriftEndpoint{
+		authenticator: authenticator,
+		drift:         driftService,
+	}
+}
+
+func (e DriftEndpoint) GetName() string {
+	return "DriftEndpoint"
+}
+
+func (e DriftEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/drift")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /report", e.handleReportGet())
+	apiGroup.HandleFunc("POST /check", e.handleCheckPost())
+	apiGroup.HandleFunc("POST /heal/{id...}", e.handleHealPost())
+}
+
+// handleReportGet returns a gorm Handler function.
+//
+// @ID drift_handleReportGet
+// @Tags Drift Detection
+// @Summary Get the reports of the last drift check.
+// @Description Each report lists the interfaces and peers that differ between the database and the backend.
+// @Produce json
+// @Success 200 {object} []models.DriftReport
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /drift/report [get]
+// @Security BasicAuth
+func (e DriftEndpoint) handleReportGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reports, err := e.drift.GetDriftReports(r.Context())
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDriftReports(reports))
+	}
+}
+
+// handleCheckPost returns a gorm Handler function.
+//
+// @ID drift_handleCheckPost
+// @Tags Drift Detection
+// @Summary Compare all interfaces and peers with the backends.
+// @Description If auto-healing is enabled, the database state is re-applied to all interfaces with drift.
+// @Produce json
+// @Success 200 {object} []models.DriftReport
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /drift/check [post]
+// @Security BasicAuth
+func (e DriftEndpoint) handleCheckPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reports, err := e.drift.CheckDrift(r.Context())
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDriftReports(reports))
+	}
+}
+
+// handleHealPost returns a gorm Handler function.
+//
+// @ID drift_handleHealPost
+// @Tags Drift Detection
+// @Summary Re-apply the database state to an interface.
+// @Description Peers that only exist on the backend are removed, unless import is set.
+// @Param id path string true "The interface identifier."
+// @Param import query bool false "Import unknown peers instead of removing them."
+// @Produce json
+// @Success 200 {object} models.DriftReport
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /drift/heal/{id} [post]
+// @Security BasicAuth
+func (e DriftEndpoint) handleHealPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		importUnknown, err := strconv.ParseBool(request.QueryDefault(r, "import", "false"))
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "invalid import flag"})
+			return
+		}
+
+		report, err := e.drift.HealDrift(r.Context(), domain.InterfaceIdentifier(id), importUnknown)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDriftReport(report))
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type InactivityService interface {
+	GetStalePeers(ctx context.Context, id domain.InterfaceIdentifier, minDays int) ([]domain.StalePeer, error)
+}
+
+type InactivityEndpoint struct {
+	inactivity    InactivityService
+	authenticator Authenticator
+}
+
+func NewInactivityEndpoint(
+	authenticator Authenticator,
+	inactivityService InactivityService,
+) *InactivityEndpoint {
+	return &InactivityEndpoint{
+		authenticator: authenticator,
+		inactivity:    inactivityService,
+	}
+}
+
+func (e InactivityEndpoint) GetName() string {
+	return "InactivityEndpoint"
+}
+
+func (e InactivityEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/inactivity")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /stale-peers/by-interface/{id...}", e.handleStalePeersGet())
+}
+
+// handleStalePeersGet returns a gorm Handler function.
+//
+// @ID inactivity_handleStalePeersGet
+// @Tags Inactivity
+// @Summary Get all peers of a WireGuard interface that did not connect for a long time.
+// @Description The last activity of a peer is its last handshake. Peers that never connected are counted from their
+// @Description creation date. The result is sorted by inactivity, the longest inactive peer first.
+// @Param id path string true "The WireGuard interface identifier."
+// @Param minDays query int false "Minimum number of inactive days. Defaults to the lowest threshold of the interface inactivity policy, or 30 days."
+// @Produce json
+// @Success 200 {object} []models.StalePeer
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /inactivity/stale-peers/by-interface/{id} [get]
+// @Security BasicAuth
+func (e InactivityEndpoint) handleStalePeersGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		minDays, err := strconv.Atoi(request.QueryDefault(r, "minDays", "0"))
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "invalid minDays parameter"})
+			return
+		}
+
+		stalePeers, err := e.inactivity.GetStalePeers(r.Context(), domain.InterfaceIdentifier(id), minDays)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewStalePeers(stalePeers))
+	}
+}
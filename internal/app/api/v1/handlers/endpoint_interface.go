@@ -17,8 +17,15 @@ type InterfaceEndpointInterfaceService interface {
 	GetById(context.Context, domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
 	Prepare(context.Context) (*domain.Interface, error)
 	Create(context.Context, *domain.Interface) (*domain.Interface, error)
+	Clone(context.Context, domain.InterfaceIdentifier, *domain.InterfaceCloneRequest) (
+		*domain.Interface,
+		[]domain.Peer,
+		error,
+	)
 	Update(context.Context, domain.InterfaceIdentifier, *domain.Interface) (*domain.Interface, []domain.Peer, error)
 	Delete(context.Context, domain.InterfaceIdentifier) error
+	GetIpamStatus(context.Context, domain.InterfaceIdentifier) ([]domain.IpamNetworkStatus, error)
+	GetDelegatedPrefixes(context.Context, domain.InterfaceIdentifier) ([]domain.DelegatedPrefixAssignment, error)
 }
 
 type InterfaceEndpoint struct {
@@ -52,8 +59,12 @@ func (e InterfaceEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 
 	apiGroup.HandleFunc("GET /prepare", e.handlePrepareGet())
 	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.HandleFunc("POST /clone/{id...}", e.handleClonePost())
 	apiGroup.HandleFunc("PUT /by-id/{id...}", e.handleUpdatePut())
 	apiGroup.HandleFunc("DELETE /by-id/{id...}", e.handleDelete())
+
+	apiGroup.HandleFunc("GET /ipam/{id...}", e.handleIpamGet())
+	apiGroup.HandleFunc("GET /prefix-delegations/{id...}", e.handlePrefixDelegationsGet())
 }
 
 // handleAllGet returns a gorm Handler function.
@@ -179,6 +190,57 @@ func (e InterfaceEndpoint) handleCreatePost() http.HandlerFunc {
 	}
 }
 
+// handleClonePost returns a gorm handler function.
+//
+// @ID interfaces_handleClonePost
+// @Tags Interfaces
+// @Summary Clone an interface record.
+// @Description This endpoint creates a new interface with the settings of an existing interface, including all peer defaults, hooks, DNS, MTU and routing settings.
+// @Description The new interface gets fresh keys, a free listen port and a free address range. Address reservations, ip pools and prefix delegations are not copied.
+// @Description Optionally, all peers are duplicated with fresh keys and addresses.
+// @Param id path string true "The identifier of the source interface."
+// @Param request body models.InterfaceCloneRequest true "The settings of the new interface."
+// @Produce json
+// @Success 200 {object} models.Interface
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /interface/clone/{id} [post]
+// @Security BasicAuth
+func (e InterfaceEndpoint) handleClonePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		var req models.InterfaceCloneRequest
+		if err := request.BodyJson(r, &req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(req); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		clonedInterface, clonedPeers, err := e.interfaces.Clone(r.Context(), domain.InterfaceIdentifier(id),
+			models.NewDomainInterfaceCloneRequest(&req))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewInterface(clonedInterface, clonedPeers))
+	}
+}
+
 // handleUpdatePut returns a gorm handler function.
 //
 // @ID interfaces_handleUpdatePut
@@ -270,3 +332,77 @@ func (e InterfaceEndpoint) handleDelete() http.HandlerFunc {
 		respond.Status(w, http.StatusNoContent)
 	}
 }
+
+// handleIpamGet returns a gorm handler function.
+//
+// @ID interfaces_handleIpamGet
+// @Tags Interfaces
+// @Summary Get the address usage of the peer networks of an interface.
+// @Description Lists the used, reserved and free addresses of every peer network and the utilization of the ip pools.
+// @Description Addresses that are assigned on other interfaces are reported as used as well.
+// @Param id path string true "The interface identifier."
+// @Produce json
+// @Success 200 {object} []models.IpamNetworkStatus
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /interface/ipam/{id} [get]
+// @Security BasicAuth
+func (e InterfaceEndpoint) handleIpamGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		networks, err := e.interfaces.GetIpamStatus(r.Context(), domain.InterfaceIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewIpamNetworkStatuses(networks))
+	}
+}
+
+// handlePrefixDelegationsGet returns a gorm handler function.
+//
+// @ID interfaces_handlePrefixDelegationsGet
+// @Tags Interfaces
+// @Summary Get the delegated IPv6 prefixes of all peers of an interface.
+// @Description Each entry contains the peer and the index of its prefix within the delegation pool.
+// @Description The index is kept if the pool of the interface is changed, so it identifies the prefix across renumbering.
+// @Param id path string true "The interface identifier."
+// @Produce json
+// @Success 200 {object} []models.DelegatedPrefix
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /interface/prefix-delegations/{id} [get]
+// @Security BasicAuth
+func (e InterfaceEndpoint) handlePrefixDelegationsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		prefixes, err := e.interfaces.GetDelegatedPrefixes(r.Context(), domain.InterfaceIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDelegatedPrefixes(prefixes))
+	}
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type MeshService interface {
+	GetAllMeshes(ctx context.Context) ([]domain.Mesh, error)
+	GetMesh(ctx context.Context, id domain.MeshIdentifier) (*domain.Mesh, error)
+	GetMeshPeers(ctx context.Context, id domain.MeshIdentifier) ([]domain.MeshPeer, error)
+	CreateMesh(ctx context.Context, mesh *domain.Mesh) (*domain.Mesh, error)
+	UpdateMesh(ctx context.Context, mesh *domain.Mesh) (*domain.Mesh, error)
+	DeleteMesh(ctx context.Context, id domain.MeshIdentifier) error
+}
+
+type MeshEndpoint struct {
+	meshes        MeshService
+	authenticator Authenticator
+	validator     Validator
+}
+
+func NewMeshEndpoint(
+	authenticator Authenticator,
+	validator Validator,
+	meshService MeshService,
+) *MeshEndpoint {
+	return &MeshEndpoint{
+		authenticator: authenticator,
+		validator:     validator,
+		meshes:        meshService,
+	}
+}
+
+func (e MeshEndpoint) GetName() string {
+	return "MeshEndpoint"
+}
+
+func (e MeshEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/mesh")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("GET /by-id/{id...}", e.handleByIdGet())
+	apiGroup.HandleFunc("GET /peers/{id...}", e.handlePeersGet())
+	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.HandleFunc("PUT /by-id/{id...}", e.handleUpdatePut())
+	apiGroup.HandleFunc("DELETE /by-id/{id...}", e.handleDelete())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID mesh_handleAllGet
+// @Tags Meshes
+// @Summary Get all site-to-site meshes.
+// @Produce json
+// @Success 200 {object} []models.Mesh
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /mesh/all [get]
+// @Security BasicAuth
+func (e MeshEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		meshes, err := e.meshes.GetAllMeshes(r.Context())
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewMeshes(meshes))
+	}
+}
+
+// handleByIdGet returns a gorm Handler function.
+//
+// @ID mesh_handleByIdGet
+// @Tags Meshes
+// @Summary Get a specific mesh by its identifier.
+// @Param id path string true "The mesh identifier."
+// @Produce json
+// @Success 200 {object} models.Mesh
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /mesh/by-id/{id} [get]
+// @Security BasicAuth
+func (e MeshEndpoint) handleByIdGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing mesh id"})
+			return
+		}
+
+		mesh, err := e.meshes.GetMesh(r.Context(), domain.MeshIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewMesh(mesh))
+	}
+}
+
+// handlePeersGet returns a gorm Handler function.
+//
+// @ID mesh_handlePeersGet
+// @Tags Meshes
+// @Summary Get the generated peer entries of a mesh.
+// @Description For each link between two nodes, a peer entry is created on both node interfaces.
+// @Param id path string true "The mesh identifier."
+// @Produce json
+// @Success 200 {object} []models.MeshPeer
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /mesh/peers/{id} [get]
+// @Security BasicAuth
+func (e MeshEndpoint) handlePeersGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing mesh id"})
+			return
+		}
+
+		peers, err := e.meshes.GetMeshPeers(r.Context(), domain.MeshIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewMeshPeers(peers))
+	}
+}
+
+// handleCreatePost returns a gorm handler function.
+//
+// @ID mesh_handleCreatePost
+// @Tags Meshes
+// @Summary Create a new site-to-site mesh.
+// @Description The peer entries of all links are created on the node interfaces. Peers of the node interfaces
+// @Description that are neither stored in WireGuard Portal nor generated by a mesh are removed.
+// @Param request body models.Mesh true "The mesh data."
+// @Produce json
+// @Success 200 {object} models.Mesh
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /mesh/new [post]
+// @Security BasicAuth
+func (e MeshEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var mesh models.Mesh
+		if err := request.BodyJson(r, &mesh); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(mesh); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		newMesh, err := e.meshes.CreateMesh(r.Context(), models.NewDomainMesh(&mesh))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewMesh(newMesh))
+	}
+}
+
+// handleUpdatePut returns a gorm handler function.
+//
+// @ID mesh_handleUpdatePut
+// @Tags Meshes
+// @Summary Update a site-to-site mesh.
+// @Description The peer entries of all current and former nodes are regenerated.
+// @Param id path string true "The mesh identifier."
+// @Param request body models.Mesh true "The mesh data."
+// @Produce json
+// @Success 200 {object} models.Mesh
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /mesh/by-id/{id} [put]
+// @Security BasicAuth
+func (e MeshEndpoint) handleUpdatePut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing mesh id"})
+			return
+		}
+
+		var mesh models.Mesh
+		if err := request.BodyJson(r, &mesh); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(mesh); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		if id != mesh.Identifier {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "mesh id mismatch"})
+			return
+		}
+
+		updatedMesh, err := e.meshes.UpdateMesh(r.Context(), models.NewDomainMesh(&mesh))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewMesh(updatedMesh))
+	}
+}
+
+// handleDelete returns a gorm handler function.
+//
+// @ID mesh_handleDelete
+// @Tags Meshes
+// @Summary Delete a site-to-site mesh.
+// @Description The generated peer entries are removed from all nodes.
+// @Param id path string true "The mesh identifier."
+// @Produce json
+// @Success 204 "No content if deletion was successful."
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /mesh/by-id/{id} [delete]
+// @Security BasicAuth
+func (e MeshEndpoint) handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing mesh id"})
+			return
+		}
+
+		err := e.meshes.DeleteMesh(r.Context(), domain.MeshIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.Status(w, http.StatusNoContent)
+	}
+}
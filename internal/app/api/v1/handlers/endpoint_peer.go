@@ -16,10 +16,12 @@ type PeerService interface {
 	GetForInterface(context.Context, domain.InterfaceIdentifier) ([]domain.Peer, error)
 	GetForUser(context.Context, domain.UserIdentifier) ([]domain.Peer, error)
 	GetById(context.Context, domain.PeerIdentifier) (*domain.Peer, error)
-	Prepare(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
+	Prepare(ctx context.Context, id domain.InterfaceIdentifier, pool string) (*domain.Peer, error)
 	Create(context.Context, *domain.Peer) (*domain.Peer, error)
 	Update(context.Context, domain.PeerIdentifier, *domain.Peer) (*domain.Peer, error)
 	Delete(context.Context, domain.PeerIdentifier) error
+	GetDeleted(context.Context) ([]domain.Peer, error)
+	Restore(context.Context, domain.PeerIdentifier) (*domain.Peer, error)
 }
 
 type PeerEndpoint struct {
@@ -56,6 +58,9 @@ func (e PeerEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /new", e.handleCreatePost())
 	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("PUT /by-id/{id...}", e.handleUpdatePut())
 	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("DELETE /by-id/{id...}", e.handleDelete())
+
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /deleted", e.handleDeletedGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /restore/{id...}", e.handleRestorePost())
 }
 
 // handleAllForInterfaceGet returns a gorm Handler function.
@@ -165,6 +170,7 @@ func (e PeerEndpoint) handleByIdGet() http.HandlerFunc {
 // @Summary Prepare a new peer record for the given WireGuard interface.
 // @Description This endpoint is used to prepare a new peer record. The returned data contains a fresh key pair and valid ip address.
 // @Param id path string true "The interface identifier."
+// @Param Pool query string false "The ip pool of the interface to allocate the addresses from."
 // @Produce json
 // @Success 200 {object} models.Peer
 // @Failure 400 {object} models.Error
@@ -183,7 +189,7 @@ func (e PeerEndpoint) handlePrepareGet() http.HandlerFunc {
 			return
 		}
 
-		peer, err := e.peers.Prepare(r.Context(), domain.InterfaceIdentifier(id))
+		peer, err := e.peers.Prepare(r.Context(), domain.InterfaceIdentifier(id), request.Query(r, "Pool"))
 		if err != nil {
 			status, model := ParseServiceError(err)
 			respond.JSON(w, status, model)
@@ -314,3 +320,64 @@ func (e PeerEndpoint) handleDelete() http.HandlerFunc {
 		respond.Status(w, http.StatusNoContent)
 	}
 }
+
+// handleDeletedGet returns a gorm Handler function.
+//
+// @ID peers_handleDeletedGet
+// @Tags Peers
+// @Summary Get all peer records in the recycle bin.
+// @Description Deleted peers are kept until the configured deletion retention is over. Their addresses stay reserved.
+// @Produce json
+// @Success 200 {object} []models.DeletedPeer
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /peer/deleted [get]
+// @Security BasicAuth
+func (e PeerEndpoint) handleDeletedGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deletedPeers, err := e.peers.GetDeleted(r.Context())
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDeletedPeers(deletedPeers))
+	}
+}
+
+// handleRestorePost returns a gorm Handler function.
+//
+// @ID peers_handleRestorePost
+// @Tags Peers
+// @Summary Restore a peer from the recycle bin.
+// @Description The peer is re-created on its WireGuard interface with its previous keys and addresses.
+// @Param id path string true "The peer identifier."
+// @Produce json
+// @Success 200 {object} models.Peer
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /peer/restore/{id} [post]
+// @Security BasicAuth
+func (e PeerEndpoint) handleRestorePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		restoredPeer, err := e.peers.Restore(r.Context(), domain.PeerIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewPeer(restoredPeer))
+	}
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type PeerRequestService interface {
+	SubmitPeerRequest(ctx context.Context, request *domain.PeerRequest) (*domain.PeerRequest, error)
+	GetAllPeerRequests(ctx context.Context, pendingOnly bool) ([]domain.PeerRequest, error)
+	GetUserPeerRequests(ctx context.Context, id domain.UserIdentifier) ([]domain.PeerRequest, error)
+	GetPeerRequest(ctx context.Context, id domain.PeerRequestIdentifier) (*domain.PeerRequest, error)
+	ApprovePeerRequest(ctx context.Context, id domain.PeerRequestIdentifier, reason string) (
+		*domain.PeerRequest,
+		error,
+	)
+	RejectPeerRequest(ctx context.Context, id domain.PeerRequestIdentifier, reason string) (
+		*domain.PeerRequest,
+		error,
+	)
+}
+
+type PeerRequestEndpoint struct {
+	requests      PeerRequestService
+	authenticator Authenticator
+	validator     Validator
+}
+
+func NewPeerRequestEndpoint(
+	authenticator Authenticator,
+	validator Validator,
+	peerRequestService PeerRequestService,
+) *PeerRequestEndpoint {
+	return &PeerRequestEndpoint{
+		authenticator: authenticator,
+		validator:     validator,
+		requests:      peerRequestService,
+	}
+}
+
+func (e PeerRequestEndpoint) GetName() string {
+	return "PeerRequestEndpoint"
+}
+
+func (e PeerRequestEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/peer-request")
+	apiGroup.Use(e.authenticator.LoggedIn())
+
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /all", e.handleAllGet())
+	apiGroup.HandleFunc("GET /by-user/{id...}", e.handleAllForUserGet())
+	apiGroup.HandleFunc("GET /by-id/{id}", e.handleByIdGet())
+	apiGroup.HandleFunc("POST /new", e.handleCreatePost())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /by-id/{id}/approve", e.handleApprovePost())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /by-id/{id}/reject", e.handleRejectPost())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID peer_requests_handleAllGet
+// @Tags Peer Requests
+// @Summary Get all peer requests.
+// @Description The newest requests are returned first.
+// @Param pending query bool false "If true, only pending requests are returned."
+// @Produce json
+// @Success 200 {object} []models.PeerRequest
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /peer-request/all [get]
+// @Security BasicAuth
+func (e PeerRequestEndpoint) handleAllGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests, err := e.requests.GetAllPeerRequests(r.Context(), request.QueryDefault(r, "pending", "") == "true")
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewPeerRequests(requests))
+	}
+}
+
+// handleAllForUserGet returns a gorm Handler function.
+//
+// @ID peer_requests_handleAllForUserGet
+// @Tags Peer Requests
+// @Summary Get all peer requests of a given user.
+// @Description Normal users can only access their own requests. Admins can access all requests.
+// @Param id path string true "The user identifier."
+// @Produce json
+// @Success 200 {object} []models.PeerRequest
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /peer-request/by-user/{id} [get]
+// @Security BasicAuth
+func (e PeerRequestEndpoint) handleAllForUserGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing user id"})
+			return
+		}
+
+		requests, err := e.requests.GetUserPeerRequests(r.Context(), domain.UserIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewPeerRequests(requests))
+	}
+}
+
+// handleByIdGet returns a gorm Handler function.
+//
+// @ID peer_requests_handleByIdGet
+// @Tags Peer Requests
+// @Summary Get a specific peer request by its identifier.
+// @Description Normal users can only access their own requests. Admins can access all requests.
+// @Param id path string true "The peer request identifier."
+// @Produce json
+// @Success 200 {object} models.PeerRequest
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /peer-request/by-id/{id} [get]
+// @Security BasicAuth
+func (e PeerRequestEndpoint) handleByIdGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing request id"})
+			return
+		}
+
+		peerRequest, err := e.requests.GetPeerRequest(r.Context(), domain.PeerRequestIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewPeerRequest(peerRequest))
+	}
+}
+
+// handleCreatePost returns a gorm handler function.
+//
+// @ID peer_requests_handleCreatePost
+// @Tags Peer Requests
+// @Summary Request a new peer.
+// @Description The request is stored in a pending state and all admins are notified. Once an admin approves the
+// @Description request, the peer is created and its configuration is mailed to the user.
+// @Description Normal users can only request peers for themselves, and only if peer requests are enabled.
+// @Param request body models.PeerRequest true "The peer request data."
+// @Produce json
+// @Success 200 {object} models.PeerRequest
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /peer-request/new [post]
+// @Security BasicAuth
+func (e PeerRequestEndpoint) handleCreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var peerRequest models.PeerRequest
+		if err := request.BodyJson(r, &peerRequest); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		if err := e.validator.Struct(peerRequest); err != nil {
+			respond.JSON(w, http.StatusBadRequest, models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+
+		newRequest, err := e.requests.SubmitPeerRequest(r.Context(), models.NewDomainPeerRequest(&peerRequest))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewPeerRequest(newRequest))
+	}
+}
+
+// handleApprovePost returns a gorm handler function.
+//
+// @ID peer_requests_handleApprovePost
+// @Tags Peer Requests
+// @Summary Approve a pending peer request.
+// @Description The requested peer is created and its configuration is mailed to the requesting user.
+// @Param id path string true "The peer request identifier."
+// @Param request body models.PeerRequestDecision false "An optional comment."
+// @Produce json
+// @Success 200 {object} models.PeerRequest
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /peer-request/by-id/{id}/approve [post]
+// @Security BasicAuth
+func (e PeerRequestEndpoint) handleApprovePost() http.HandlerFunc {
+	return e.handleDecision(e.requests.ApprovePeerRequest)
+}
+
+// handleRejectPost returns a gorm handler function.
+//
+// @ID peer_requests_handleRejectPost
+// @Tags Peer Requests
+// @Summary Reject a pending peer request.
+// @Description The requesting user is notified by email, including the optional reason.
+// @Param id path string true "The peer request identifier."
+// @Param request body models.PeerRequestDecision false "The reason for the rejection."
+// @Produce json
+// @Success 200 {object} models.PeerRequest
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /peer-request/by-id/{id}/reject [post]
+// @Security BasicAuth
+func (e PeerRequestEndpoint) handleRejectPost() http.HandlerFunc {
+	return e.handleDecision(e.requests.RejectPeerRequest)
+}
+
+func (e PeerRequestEndpoint) handleDecision(
+	decide func(context.Context, domain.PeerRequestIdentifier, string) (*domain.PeerRequest, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing request id"})
+			return
+		}
+
+		var decision models.PeerRequestDecision
+		if r.ContentLength != 0 {
+			if err := request.BodyJson(r, &decision); err != nil {
+				respond.JSON(w, http.StatusBadRequest,
+					models.Error{Code: http.StatusBadRequest, Message: err.Error()})
+				return
+			}
+		}
+
+		peerRequest, err := decide(r.Context(), domain.PeerRequestIdentifier(id), decision.Reason)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewPeerRequest(peerRequest))
+	}
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -21,6 +23,8 @@ type ProvisioningEndpointProvisioningService interface {
 	)
 	GetPeerConfig(ctx context.Context, peerId domain.PeerIdentifier) ([]byte, error)
 	GetPeerQrPng(ctx context.Context, peerId domain.PeerIdentifier) ([]byte, error)
+	GetPeerMobileConfig(ctx context.Context, peerId domain.PeerIdentifier, platform string) (string, []byte, error)
+	WritePeerConfigBundle(ctx context.Context, w io.Writer, req domain.ConfigBundleRequest) error
 	NewPeer(ctx context.Context, req models.ProvisioningRequest) (*domain.Peer, error)
 }
 
@@ -53,6 +57,8 @@ func (e ProvisioningEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup.HandleFunc("GET /data/user-info", e.handleUserInfoGet())
 	apiGroup.HandleFunc("GET /data/peer-config", e.handlePeerConfigGet())
 	apiGroup.HandleFunc("GET /data/peer-qr", e.handlePeerQrGet())
+	apiGroup.HandleFunc("GET /data/peer-mobileconfig", e.handlePeerMobileConfigGet())
+	apiGroup.HandleFunc("GET /data/config-bundle", e.handleConfigBundleGet())
 
 	apiGroup.HandleFunc("POST /new-peer", e.handleNewPeerPost())
 }
@@ -168,6 +174,98 @@ func (e ProvisioningEndpoint) handlePeerQrGet() http.HandlerFunc {
 	}
 }
 
+// handlePeerMobileConfigGet returns a gorm Handler function.
+//
+// @ID provisioning_handlePeerMobileConfigGet
+// @Tags Provisioning
+// @Summary Get the peer configuration as Apple configuration profile (.mobileconfig).
+// @Description Normal users can only access their own record. Admins can access all records.
+// @Description The profile contains the on-demand rules of the interface and is signed if a signing certificate is configured.
+// @Param PeerId query string true "The peer identifier (public key) that should be queried."
+// @Param Platform query string false "The target platform, either ios (default) or macos."
+// @Produce application/x-apple-aspen-config
+// @Produce json
+// @Success 200 {file} binary "The Apple configuration profile"
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /provisioning/data/peer-mobileconfig [get]
+// @Security BasicAuth
+func (e ProvisioningEndpoint) handlePeerMobileConfigGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(request.Query(r, "PeerId"))
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		filename, profile, err := e.provisioning.GetPeerMobileConfig(r.Context(), domain.PeerIdentifier(id),
+			request.Query(r, "Platform"))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.Attachment(w, http.StatusOK, filename, domain.MobileConfigContentType, profile)
+	}
+}
+
+// handleConfigBundleGet returns a gorm Handler function.
+//
+// @ID provisioning_handleConfigBundleGet
+// @Tags Provisioning
+// @Summary Get the configurations of multiple peers as zip archive.
+// @Description Exactly one of UserId, InterfaceId or PeerId must be set. The archive contains all configuration files and a QR code image per peer.
+// @Description Normal users can only access their own records. Admins can access all records.
+// @Param UserId query string false "Bundle all peers of this user."
+// @Param InterfaceId query string false "Bundle all peers of this interface."
+// @Param PeerId query []string false "Bundle the given peers (public keys)." collectionFormat(multi)
+// @Param Style query string false "The configuration style: wgquick (default), raw, networkd, networkmanager, openwrt or routeros."
+// @Produce application/zip
+// @Produce json
+// @Success 200 {file} binary "The zip archive"
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /provisioning/data/config-bundle [get]
+// @Security BasicAuth
+func (e ProvisioningEndpoint) handleConfigBundleGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerIds := make([]domain.PeerIdentifier, 0)
+		for _, id := range request.QuerySlice(r, "PeerId") {
+			peerIds = append(peerIds, domain.PeerIdentifier(strings.TrimSpace(id)))
+		}
+
+		req := domain.ConfigBundleRequest{
+			UserIdentifier:      domain.UserIdentifier(strings.TrimSpace(request.Query(r, "UserId"))),
+			InterfaceIdentifier: domain.InterfaceIdentifier(strings.TrimSpace(request.Query(r, "InterfaceId"))),
+			PeerIdentifiers:     peerIds,
+			Style:               request.Query(r, "Style"),
+		}
+		if err := req.Validate(); err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		aw := respond.NewAttachmentWriter(w, http.StatusOK, req.FileName(), domain.ConfigBundleContentType)
+		err := e.provisioning.WritePeerConfigBundle(r.Context(), aw, req)
+		switch {
+		case err != nil && !aw.Started():
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+		case err != nil:
+			slog.Error("failed to stream config bundle", "file", req.FileName(), "error", err)
+		}
+	}
+}
+
 // handleNewPeerPost returns a gorm Handler function.
 //
 // @ID provisioning_handleNewPeerPost
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-pkgz/routegroup"
+
+	"github.com/h44z/wg-portal/internal/app/api/core/request"
+	"github.com/h44z/wg-portal/internal/app/api/core/respond"
+	"github.com/h44z/wg-portal/internal/app/api/v1/models"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type RevisionService interface {
+	GetRevisions(
+		ctx context.Context,
+		objectType domain.RevisionObjectType,
+		id string,
+	) ([]domain.ConfigRevision, error)
+	DiffRevisions(
+		ctx context.Context,
+		objectType domain.RevisionObjectType,
+		id string,
+		from, to uint64,
+	) ([]domain.RevisionChange, error)
+	RollbackPeer(ctx context.Context, id domain.PeerIdentifier, version uint64) (*domain.Peer, error)
+	RollbackInterface(
+		ctx context.Context,
+		id domain.InterfaceIdentifier,
+		version uint64,
+	) (*domain.Interface, []domain.Peer, error)
+}
+
+type RevisionEndpoint struct {
+	revisions     RevisionService
+	authenticator Authenticator
+}
+
+func NewRevisionEndpoint(
+	authenticator Authenticator,
+	revisionService RevisionService,
+) *RevisionEndpoint {
+	return &RevisionEndpoint{
+		authenticator: authenticator,
+		revisions:     revisionService,
+	}
+}
+
+func (e RevisionEndpoint) GetName() string {
+	return "RevisionEndpoint"
+}
+
+func (e RevisionEndpoint) RegisterRoutes(g *routegroup.Bundle) {
+	apiGroup := g.Mount("/revision")
+	apiGroup.Use(e.authenticator.LoggedIn(ScopeAdmin))
+
+	apiGroup.HandleFunc("GET /by-peer/{id...}", e.handleAllGet(domain.RevisionObjectPeer))
+	apiGroup.HandleFunc("GET /by-interface/{id...}", e.handleAllGet(domain.RevisionObjectInterface))
+	apiGroup.HandleFunc("GET /diff/by-peer/{id...}", e.handleDiffGet(domain.RevisionObjectPeer))
+	apiGroup.HandleFunc("GET /diff/by-interface/{id...}", e.handleDiffGet(domain.RevisionObjectInterface))
+	apiGroup.HandleFunc("POST /rollback/by-peer/{id...}", e.handlePeerRollbackPost())
+	apiGroup.HandleFunc("POST /rollback/by-interface/{id...}", e.handleInterfaceRollbackPost())
+}
+
+// handleAllGet returns a gorm Handler function.
+//
+// @ID revision_handleAllGet
+// @Tags Revisions
+// @Summary Get all stored configuration revisions of a peer or interface.
+// @Description A revision is stored each time the configuration changes. The latest revision is returned first.
+// @Param id path string true "The peer or interface identifier."
+// @Produce json
+// @Success 200 {object} []models.ConfigRevision
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /revision/by-peer/{id} [get]
+// @Router /revision/by-interface/{id} [get]
+// @Security BasicAuth
+func (e RevisionEndpoint) handleAllGet(objectType domain.RevisionObjectType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing " + string(objectType) + " id"})
+			return
+		}
+
+		revisions, err := e.revisions.GetRevisions(r.Context(), objectType, id)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewConfigRevisions(revisions))
+	}
+}
+
+// handleDiffGet returns a gorm Handler function.
+//
+// @ID revision_handleDiffGet
+// @Tags Revisions
+// @Summary Get the changes between two configuration revisions of a peer or interface.
+// @Description Private and preshared keys are hidden, only the fact that they changed is shown.
+// @Param id path string true "The peer or interface identifier."
+// @Param from query int true "The older revision version."
+// @Param to query int false "The newer revision version. Defaults to the latest revision."
+// @Produce json
+// @Success 200 {object} []models.RevisionChange
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /revision/diff/by-peer/{id} [get]
+// @Router /revision/diff/by-interface/{id} [get]
+// @Security BasicAuth
+func (e RevisionEndpoint) handleDiffGet(objectType domain.RevisionObjectType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing " + string(objectType) + " id"})
+			return
+		}
+
+		from, err := strconv.ParseUint(request.Query(r, "from"), 10, 64)
+		if err != nil || from == 0 {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "invalid from parameter"})
+			return
+		}
+		to, err := strconv.ParseUint(request.QueryDefault(r, "to", "0"), 10, 64)
+		if err != nil {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "invalid to parameter"})
+			return
+		}
+
+		changes, err := e.revisions.DiffRevisions(r.Context(), objectType, id, from, to)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewRevisionChanges(changes))
+	}
+}
+
+// handlePeerRollbackPost returns a gorm Handler function.
+//
+// @ID revision_handlePeerRollbackPost
+// @Tags Revisions
+// @Summary Restore the configuration of a peer from a stored revision.
+// @Description The peer is updated like any other change, the rollback is stored as a new revision.
+// @Param id path string true "The peer identifier."
+// @Param version query int true "The revision version to restore."
+// @Produce json
+// @Success 200 {object} models.Peer
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /revision/rollback/by-peer/{id} [post]
+// @Security BasicAuth
+func (e RevisionEndpoint) handlePeerRollbackPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing peer id"})
+			return
+		}
+
+		version, err := strconv.ParseUint(request.Query(r, "version"), 10, 64)
+		if err != nil || version == 0 {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "invalid version parameter"})
+			return
+		}
+
+		peer, err := e.revisions.RollbackPeer(r.Context(), domain.PeerIdentifier(id), version)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewPeer(peer))
+	}
+}
+
+// handleInterfaceRollbackPost returns a gorm Handler function.
+//
+// @ID revision_handleInterfaceRollbackPost
+// @Tags Revisions
+// @Summary Restore the configuration of an interface from a stored revision.
+// @Description The interface is updated like any other change, the rollback is stored as a new revision.
+// @Param id path string true "The interface identifier."
+// @Param version query int true "The revision version to restore."
+// @Produce json
+// @Success 200 {object} models.Interface
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /revision/rollback/by-interface/{id} [post]
+// @Security BasicAuth
+func (e RevisionEndpoint) handleInterfaceRollbackPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing interface id"})
+			return
+		}
+
+		version, err := strconv.ParseUint(request.Query(r, "version"), 10, 64)
+		if err != nil || version == 0 {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "invalid version parameter"})
+			return
+		}
+
+		iface, peers, err := e.revisions.RollbackInterface(r.Context(), domain.InterfaceIdentifier(id), version)
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewInterface(iface, peers))
+	}
+}
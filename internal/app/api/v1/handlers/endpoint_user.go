@@ -18,6 +18,8 @@ type UserService interface {
 	Create(ctx context.Context, user *domain.User) (*domain.User, error)
 	Update(ctx context.Context, id domain.UserIdentifier, user *domain.User) (*domain.User, error)
 	Delete(ctx context.Context, id domain.UserIdentifier) error
+	GetDeleted(ctx context.Context) ([]domain.User, error)
+	Restore(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
 }
 
 type UserEndpoint struct {
@@ -51,6 +53,9 @@ func (e UserEndpoint) RegisterRoutes(g *routegroup.Bundle) {
 	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /new", e.handleCreatePost())
 	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("PUT /by-id/{id...}", e.handleUpdatePut())
 	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("DELETE /by-id/{id...}", e.handleDelete())
+
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("GET /deleted", e.handleDeletedGet())
+	apiGroup.With(e.authenticator.LoggedIn(ScopeAdmin)).HandleFunc("POST /restore/{id...}", e.handleRestorePost())
 }
 
 // handleAllGet returns a gorm Handler function.
@@ -232,3 +237,64 @@ func (e UserEndpoint) handleDelete() http.HandlerFunc {
 		respond.Status(w, http.StatusNoContent)
 	}
 }
+
+// handleDeletedGet returns a gorm Handler function.
+//
+// @ID users_handleDeletedGet
+// @Tags Users
+// @Summary Get all user records in the recycle bin.
+// @Description Deleted users are kept until the configured deletion retention is over.
+// @Produce json
+// @Success 200 {object} []models.DeletedUser
+// @Failure 401 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /user/deleted [get]
+// @Security BasicAuth
+func (e UserEndpoint) handleDeletedGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deletedUsers, err := e.users.GetDeleted(r.Context())
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewDeletedUsers(deletedUsers))
+	}
+}
+
+// handleRestorePost returns a gorm Handler function.
+//
+// @ID users_handleRestorePost
+// @Tags Users
+// @Summary Restore a user from the recycle bin.
+// @Description Peers of the user are not restored, deleted peers have to be restored separately.
+// @Param id path string true "The user identifier."
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.Error
+// @Failure 401 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /user/restore/{id} [post]
+// @Security BasicAuth
+func (e UserEndpoint) handleRestorePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := request.Path(r, "id")
+		if id == "" {
+			respond.JSON(w, http.StatusBadRequest,
+				models.Error{Code: http.StatusBadRequest, Message: "missing user id"})
+			return
+		}
+
+		restoredUser, err := e.users.Restore(r.Context(), domain.UserIdentifier(id))
+		if err != nil {
+			status, model := ParseServiceError(err)
+			respond.JSON(w, status, model)
+			return
+		}
+
+		respond.JSON(w, http.StatusOK, models.NewUser(restoredUser, false))
+	}
+}
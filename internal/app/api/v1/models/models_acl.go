@@ -0,0 +1,104 @@
+package models
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// AclRule matches traffic that is forwarded from a peer. All criteria of a rule must match.
+type AclRule struct {
+	// Action is either allow or deny.
+	Action string `json:"Action" binding:"oneof=allow deny" example:"allow"`
+	// Destination is the destination network in CIDR notation. An empty value matches all destinations.
+	Destination string `json:"Destination" binding:"omitempty,cidr" example:"10.0.0.0/24"`
+	// Protocol is tcp, udp or icmp. An empty value matches all protocols.
+	Protocol string `json:"Protocol" binding:"omitempty,oneof=tcp udp icmp" example:"tcp"`
+	// Ports is a destination port (443) or port range (8000-8100). It is only valid for tcp and udp.
+	Ports string `json:"Ports" example:"8000-8100"`
+}
+
+// AccessControlList restricts the destinations that a peer is allowed to reach. The first matching rule wins.
+type AccessControlList struct {
+	Rules []AclRule `json:"Rules" binding:"dive"`
+}
+
+// InterfaceAccessPolicy defines the forwarding policy for all peers of an interface.
+type InterfaceAccessPolicy struct {
+	// IsolatePeers prevents peers of the interface from reaching each other.
+	IsolatePeers bool `json:"IsolatePeers" example:"true"`
+	// DefaultAction is applied to traffic that is not matched by any rule. An empty value means allow.
+	DefaultAction string `json:"DefaultAction" binding:"omitempty,oneof=allow deny" example:"allow"`
+	// Rules apply to all peers of the interface. They are evaluated after the rules of the peer and its owner.
+	Rules []AclRule `json:"Rules" binding:"dive"`
+}
+
+func NewAclRules(src []domain.AclRule) []AclRule {
+	results := make([]AclRule, len(src))
+	for i, rule := range src {
+		results[i] = AclRule{
+			Action:      string(rule.Action),
+			Destination: rule.Destination,
+			Protocol:    rule.Protocol,
+			Ports:       rule.Ports,
+		}
+	}
+
+	return results
+}
+
+func NewDomainAclRules(src []AclRule) []domain.AclRule {
+	results := make([]domain.AclRule, len(src))
+	for i, rule := range src {
+		results[i] = domain.AclRule{
+			Action:      domain.AclAction(rule.Action),
+			Destination: rule.Destination,
+			Protocol:    rule.Protocol,
+			Ports:       rule.Ports,
+		}
+	}
+
+	return results
+}
+
+func NewAccessControlList(src *domain.AccessControlList) *AccessControlList {
+	if src == nil {
+		return nil
+	}
+
+	return &AccessControlList{
+		Rules: NewAclRules(src.Rules),
+	}
+}
+
+func NewDomainAccessControlList(src *AccessControlList) *domain.AccessControlList {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.AccessControlList{
+		Rules: NewDomainAclRules(src.Rules),
+	}
+}
+
+func NewInterfaceAccessPolicy(src *domain.InterfaceAccessPolicy) *InterfaceAccessPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &InterfaceAccessPolicy{
+		IsolatePeers:  src.IsolatePeers,
+		DefaultAction: string(src.DefaultAction),
+		Rules:         NewAclRules(src.Rules),
+	}
+}
+
+func NewDomainInterfaceAccessPolicy(src *InterfaceAccessPolicy) *domain.InterfaceAccessPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.InterfaceAccessPolicy{
+		IsolatePeers:  src.IsolatePeers,
+		DefaultAction: domain.AclAction(src.DefaultAction),
+		Rules:         NewDomainAclRules(src.Rules),
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// AmneziaParams contains the AmneziaWG obfuscation parameters of an interface.
+type AmneziaParams struct {
+	// Jc is the number of junk packets that are sent before the handshake.
+	Jc int `json:"Jc" binding:"min=0,max=128" example:"4"`
+	// Jmin is the minimum size of a junk packet.
+	Jmin int `json:"Jmin" binding:"min=0,max=1280" example:"40"`
+	// Jmax is the maximum size of a junk packet.
+	Jmax int `json:"Jmax" binding:"min=0,max=1280" example:"70"`
+	// S1 is the padding of the handshake initiation message.
+	S1 int `json:"S1" binding:"min=0,max=1132" example:"15"`
+	// S2 is the padding of the handshake response message.
+	S2 int `json:"S2" binding:"min=0,max=1188" example:"68"`
+	// H1 is the message type of the handshake initiation. Zero keeps the WireGuard default.
+	H1 uint32 `json:"H1" example:"1106457265"`
+	// H2 is the message type of the handshake response. Zero keeps the WireGuard default.
+	H2 uint32 `json:"H2" example:"249455488"`
+	// H3 is the message type of the cookie reply. Zero keeps the WireGuard default.
+	H3 uint32 `json:"H3" example:"1209847463"`
+	// H4 is the message type of the transport data. Zero keeps the WireGuard default.
+	H4 uint32 `json:"H4" example:"1646644382"`
+}
+
+func NewAmneziaParams(src *domain.AmneziaParams) *AmneziaParams {
+	if src == nil {
+		return nil
+	}
+
+	return &AmneziaParams{
+		Jc:   src.Jc,
+		Jmin: src.Jmin,
+		Jmax: src.Jmax,
+		S1:   src.S1,
+		S2:   src.S2,
+		H1:   src.H1,
+		H2:   src.H2,
+		H3:   src.H3,
+		H4:   src.H4,
+	}
+}
+
+func NewDomainAmneziaParams(src *AmneziaParams) *domain.AmneziaParams {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.AmneziaParams{
+		Jc:   src.Jc,
+		Jmin: src.Jmin,
+		Jmax: src.Jmax,
+		S1:   src.S1,
+		S2:   src.S2,
+		H1:   src.H1,
+		H2:   src.H2,
+		H3:   src.H3,
+		H4:   src.H4,
+	}
+}
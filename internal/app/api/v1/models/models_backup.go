@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// BackupSummary describes a restored backup.
+type BackupSummary struct {
+	// SchemaVersion is the database schema version of the backup.
+	SchemaVersion uint64 `json:"SchemaVersion" example:"4"`
+	// PortalVersion is the version of WireGuard Portal that created the backup.
+	PortalVersion string `json:"PortalVersion" example:"v2.1.0"`
+	// CreatedAt is the time when the backup was created.
+	CreatedAt time.Time `json:"CreatedAt"`
+	// Encrypted is true if the backup archive was encrypted with a passphrase.
+	Encrypted bool `json:"Encrypted" example:"true"`
+
+	// The number of restored objects.
+	Users             int `json:"Users" example:"10"`
+	Interfaces        int `json:"Interfaces" example:"2"`
+	Peers             int `json:"Peers" example:"25"`
+	InterfaceStatuses int `json:"InterfaceStatuses" example:"2"`
+	PeerStatuses      int `json:"PeerStatuses" example:"25"`
+	Meshes            int `json:"Meshes" example:"0"`
+	PeerRequests      int `json:"PeerRequests" example:"1"`
+	ConfigRevisions   int `json:"ConfigRevisions" example:"120"`
+	AuditEntries      int `json:"AuditEntries" example:"5000"`
+}
+
+func NewBackupSummary(src *domain.BackupSummary) *BackupSummary {
+	return &BackupSummary{
+		SchemaVersion:     src.SchemaVersion,
+		PortalVersion:     src.PortalVersion,
+		CreatedAt:         src.CreatedAt,
+		Encrypted:         src.Encrypted,
+		Users:             src.Users,
+		Interfaces:        src.Interfaces,
+		Peers:             src.Peers,
+		InterfaceStatuses: src.InterfaceStatuses,
+		PeerStatuses:      src.PeerStatuses,
+		Meshes:            src.Meshes,
+		PeerRequests:      src.PeerRequests,
+		ConfigRevisions:   src.ConfigRevisions,
+		AuditEntries:      src.AuditEntries,
+	}
+}
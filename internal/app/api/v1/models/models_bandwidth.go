@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// BandwidthLimit restricts the throughput of a peer. Rates are seen from the peer's perspective.
+type BandwidthLimit struct {
+	// UploadKbps is the maximum rate in kbit/s that the peer is allowed to send. Zero means unlimited.
+	UploadKbps int `json:"UploadKbps" binding:"gte=0" example:"10000"`
+	// DownloadKbps is the maximum rate in kbit/s that the peer is allowed to receive. Zero means unlimited.
+	DownloadKbps int `json:"DownloadKbps" binding:"gte=0" example:"50000"`
+}
+
+func NewBandwidthLimit(src *domain.BandwidthLimit) *BandwidthLimit {
+	if src == nil {
+		return nil
+	}
+
+	return &BandwidthLimit{
+		UploadKbps:   src.UploadKbps,
+		DownloadKbps: src.DownloadKbps,
+	}
+}
+
+func NewDomainBandwidthLimit(src *BandwidthLimit) *domain.BandwidthLimit {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.BandwidthLimit{
+		UploadKbps:   src.UploadKbps,
+		DownloadKbps: src.DownloadKbps,
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import "github.com/h44z/wg-portal/internal/domain"
+
+type BulkImportRowError struct {
+	Row        int    `json:"Row"`        // the 1-based record number, the csv header is not counted
+	Identifier string `json:"Identifier"` // the identifier of the failed record, if available
+	Message    string `json:"Message"`    // the error message
+}
+
+type BulkImportResult struct {
+	DryRun  bool                 `json:"DryRun"`  // if true, no records have been persisted
+	Total   int                  `json:"Total"`   // the total number of records
+	Created int                  `json:"Created"` // the number of created (or creatable) records
+	Updated int                  `json:"Updated"` // the number of updated (or updatable) records
+	Failed  int                  `json:"Failed"`  // the number of failed records
+	Errors  []BulkImportRowError `json:"Errors"`  // details for each failed record
+}
+
+func NewBulkImportResult(src *domain.BulkImportResult) *BulkImportResult {
+	res := &BulkImportResult{
+		DryRun:  src.DryRun,
+		Total:   src.Total,
+		Created: src.Created,
+		Updated: src.Updated,
+		Failed:  src.Failed,
+		Errors:  make([]BulkImportRowError, len(src.Errors)),
+	}
+
+	for i, rowErr := range src.Errors {
+		res.Errors[i] = BulkImportRowError{
+			Row:        rowErr.Row,
+			Identifier: rowErr.Identifier,
+			Message:    rowErr.Message,
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// DeclarativePlan contains the changes that are required to reach the declarative state.
+type DeclarativePlan struct {
+	// DryRun is true if the changes have only been planned, but not applied.
+	DryRun bool `json:"DryRun" example:"true"`
+	// Failed is the number of changes that could not be planned or applied.
+	Failed int `json:"Failed" example:"0"`
+	// Changes contains all changes, in the order in which they are applied.
+	Changes []DeclarativeChange `json:"Changes"`
+}
+
+// DeclarativeChange is a single change of the plan.
+type DeclarativeChange struct {
+	// Action is either "create", "update" or "delete".
+	Action string `json:"Action" example:"update"`
+	// ObjectType is either "user", "interface" or "peer".
+	ObjectType string `json:"ObjectType" example:"peer"`
+	// Identifier is the user or interface identifier, or the public key of the peer.
+	Identifier string `json:"Identifier" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// Fields contains the changed fields of updates. Private keys are hidden.
+	Fields []RevisionChange `json:"Fields,omitempty"`
+	// Error is set if the change could not be planned or applied.
+	Error string `json:"Error,omitempty" example:"unknown interface wg1"`
+}
+
+func NewDeclarativePlan(src *domain.DeclarativePlan) *DeclarativePlan {
+	changes := make([]DeclarativeChange, len(src.Changes))
+	for i, change := range src.Changes {
+		changes[i] = DeclarativeChange{
+			Action:     string(change.Action),
+			ObjectType: string(change.ObjectType),
+			Identifier: change.Identifier,
+			Fields:     NewRevisionChanges(change.Fields),
+			Error:      change.Error,
+		}
+	}
+
+	return &DeclarativePlan{
+		DryRun:  src.DryRun,
+		Failed:  src.Failed(),
+		Changes: changes,
+	}
+}
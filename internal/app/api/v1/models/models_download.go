@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// DownloadToken grants access to the configuration of a single peer without authentication.
+type DownloadToken struct {
+	// Identifier is the unique identifier of the token.
+	Identifier string `json:"Identifier" readonly:"true" example:"0f1d5f0c-2f8f-4d7a-8b61-2f0e1b6e8f2a"`
+	// PeerIdentifier is the peer whose configuration can be downloaded.
+	PeerIdentifier string `json:"PeerIdentifier" readonly:"true" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// Type is either config (configuration file) or qr (QR code image).
+	Type string `json:"Type" readonly:"true" example:"config"`
+	// Style is the configuration style, for example wgquick or networkd.
+	Style string `json:"Style" readonly:"true" example:"wgquick"`
+	// MaxUses is the number of allowed downloads.
+	MaxUses int `json:"MaxUses" readonly:"true" example:"1"`
+	// Uses is the number of downloads so far.
+	Uses int `json:"Uses" readonly:"true" example:"0"`
+	// ExpiresAt is the time after which the token can no longer be used.
+	ExpiresAt time.Time `json:"ExpiresAt" readonly:"true"`
+	// LastUsedAt is the time of the last download.
+	LastUsedAt *time.Time `json:"LastUsedAt,omitempty" readonly:"true"`
+	// RevokedAt is set if an admin revoked the token.
+	RevokedAt *time.Time `json:"RevokedAt,omitempty" readonly:"true"`
+	// RevokedBy is the admin that revoked the token.
+	RevokedBy string `json:"RevokedBy,omitempty" readonly:"true" example:""`
+	// CreatedBy is the user that created the token.
+	CreatedBy string `json:"CreatedBy" readonly:"true" example:"admin"`
+	// CreatedAt is the creation time of the token.
+	CreatedAt time.Time `json:"CreatedAt" readonly:"true"`
+}
+
+func NewDownloadToken(src *domain.DownloadToken) *DownloadToken {
+	return &DownloadToken{
+		Identifier:     string(src.Identifier),
+		PeerIdentifier: string(src.PeerIdentifier),
+		Type:           string(src.Type),
+		Style:          src.Style,
+		MaxUses:        src.MaxUses,
+		Uses:           src.Uses,
+		ExpiresAt:      src.ExpiresAt,
+		LastUsedAt:     src.LastUsedAt,
+		RevokedAt:      src.RevokedAt,
+		RevokedBy:      src.RevokedBy,
+		CreatedBy:      src.CreatedBy,
+		CreatedAt:      src.CreatedAt,
+	}
+}
+
+func NewDownloadTokens(src []domain.DownloadToken) []DownloadToken {
+	results := make([]DownloadToken, len(src))
+	for i := range src {
+		results[i] = *NewDownloadToken(&src[i])
+	}
+
+	return results
+}
+
+// DownloadTokenRequest contains the settings of a new download token.
+type DownloadTokenRequest struct {
+	// PeerIdentifier is the peer whose configuration should be downloadable.
+	PeerIdentifier string `json:"PeerIdentifier" binding:"required" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// Type is either config (configuration file, default) or qr (QR code image).
+	Type string `json:"Type" binding:"omitempty,oneof=config qr" example:"config"`
+	// Style is the configuration style: wgquick (default), raw, networkd, networkmanager, openwrt or routeros.
+	Style string `json:"Style" binding:"omitempty,oneof=wgquick raw networkd networkmanager openwrt routeros" example:"wgquick"`
+	// MaxUses is the number of allowed downloads, defaults to 1.
+	MaxUses int `json:"MaxUses" binding:"omitempty,min=1,max=100" example:"1"`
+	// ValidSeconds is the lifetime of the token in seconds, defaults to 24 hours.
+	ValidSeconds int `json:"ValidSeconds" binding:"omitempty,min=60,max=2592000" example:"86400"`
+}
+
+func NewDomainDownloadTokenOptions(src *DownloadTokenRequest) domain.DownloadTokenOptions {
+	return domain.DownloadTokenOptions{
+		Type:    domain.DownloadType(src.Type),
+		Style:   src.Style,
+		MaxUses: src.MaxUses,
+		TTL:     time.Duration(src.ValidSeconds) * time.Second,
+	}
+}
+
+// DownloadTokenCreated is returned once a new download token has been created.
+// The signed token is only returned once and can not be retrieved later.
+type DownloadTokenCreated struct {
+	// Token contains the details of the new token.
+	Token DownloadToken `json:"Token"`
+	// SignedToken is the secret part of the download link.
+	SignedToken string `json:"SignedToken" example:"0f1d5f0c-2f8f-4d7a-8b61-2f0e1b6e8f2a.hmtR4uYJ..."`
+	// DownloadUrl is the unauthenticated download link that can be shared.
+	DownloadUrl string `json:"DownloadUrl" example:"https://vpn.example.com/api/v1/download/0f1d5f0c-2f8f-4d7a-8b61-2f0e1b6e8f2a.hmtR4uYJ..."`
+}
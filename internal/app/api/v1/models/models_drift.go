@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// DriftReport contains the differences between the database and the backend for one interface.
+type DriftReport struct {
+	// InterfaceIdentifier is the identifier of the checked interface.
+	InterfaceIdentifier string `json:"InterfaceIdentifier" example:"wg0"`
+	// Backend is the identifier of the backend that hosts the interface.
+	Backend string `json:"Backend" example:"local"`
+	// CheckedAt is the time of the check.
+	CheckedAt time.Time `json:"CheckedAt"`
+	// Items contains the interfaces and peers that differ between the database and the backend.
+	Items []DriftItem `json:"Items"`
+	// Error is set if the state of the backend could not be loaded.
+	Error string `json:"Error,omitempty" example:"connection refused"`
+	// Healed is true if the database state has been re-applied to the backend after the check.
+	Healed bool `json:"Healed" example:"false"`
+}
+
+// DriftItem is an interface or peer that differs between the database and the backend.
+type DriftItem struct {
+	// Type is either "missing" (only stored in the database), "extra" (only present on the backend) or "changed".
+	Type string `json:"Type" example:"changed"`
+	// ObjectType is either "interface" or "peer".
+	ObjectType string `json:"ObjectType" example:"peer"`
+	// Identifier is the interface identifier or the public key of the peer.
+	Identifier string `json:"Identifier" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// Fields contains the differing settings of changed objects.
+	Fields []DriftField `json:"Fields,omitempty"`
+}
+
+// DriftField is a setting that differs between the database and the backend.
+type DriftField struct {
+	// Name is the name of the setting.
+	Name string `json:"Name" example:"AllowedIPs"`
+	// Expected is the value stored in the database.
+	Expected string `json:"Expected" example:"10.11.12.2/32"`
+	// Actual is the value found on the backend.
+	Actual string `json:"Actual" example:"10.11.12.2/32,192.168.1.0/24"`
+}
+
+func NewDriftReport(src *domain.DriftReport) *DriftReport {
+	items := make([]DriftItem, len(src.Items))
+	for i, item := range src.Items {
+		fields := make([]DriftField, len(item.Fields))
+		for j, field := range item.Fields {
+			fields[j] = DriftField{
+				Name:     field.Name,
+				Expected: field.Expected,
+				Actual:   field.Actual,
+			}
+		}
+		items[i] = DriftItem{
+			Type:       string(item.Type),
+			ObjectType: string(item.ObjectType),
+			Identifier: item.Identifier,
+			Fields:     fields,
+		}
+	}
+
+	return &DriftReport{
+		InterfaceIdentifier: string(src.InterfaceIdentifier),
+		Backend:             string(src.Backend),
+		CheckedAt:           src.CheckedAt,
+		Items:               items,
+		Error:               src.Error,
+		Healed:              src.Healed,
+	}
+}
+
+func NewDriftReports(src []domain.DriftReport) []DriftReport {
+	results := make([]DriftReport, len(src))
+	for i := range src {
+		results[i] = *NewDriftReport(&src[i])
+	}
+
+	return results
+}
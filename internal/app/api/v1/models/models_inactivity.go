@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// InactivityPolicy defines how peers of an interface are handled if they did not connect for a long time.
+type InactivityPolicy struct {
+	// WarnAfterDays is the number of inactive days after which the owner is warned by email. Zero disables the step.
+	WarnAfterDays int `json:"WarnAfterDays" binding:"gte=0" example:"60"`
+	// DisableAfterDays is the number of inactive days after which the peer is disabled. Zero disables the step.
+	DisableAfterDays int `json:"DisableAfterDays" binding:"gte=0" example:"90"`
+	// DeleteAfterDays is the number of inactive days after which the peer is deleted. Zero disables the step.
+	DeleteAfterDays int `json:"DeleteAfterDays" binding:"gte=0" example:"180"`
+}
+
+func NewInactivityPolicy(src *domain.InactivityPolicy) *InactivityPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &InactivityPolicy{
+		WarnAfterDays:    src.WarnAfterDays,
+		DisableAfterDays: src.DisableAfterDays,
+		DeleteAfterDays:  src.DeleteAfterDays,
+	}
+}
+
+func NewDomainInactivityPolicy(src *InactivityPolicy) *domain.InactivityPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.InactivityPolicy{
+		WarnAfterDays:    src.WarnAfterDays,
+		DisableAfterDays: src.DisableAfterDays,
+		DeleteAfterDays:  src.DeleteAfterDays,
+	}
+}
+
+// StalePeer is a report entry for a peer that did not connect for a long time.
+type StalePeer struct {
+	// Identifier is the unique identifier of the peer (its public key).
+	Identifier string `json:"Identifier" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// DisplayName is the display name of the peer.
+	DisplayName string `json:"DisplayName" example:"My Peer"`
+	// UserIdentifier is the identifier of the user that owns the peer.
+	UserIdentifier string `json:"UserIdentifier" example:"uid-1234567"`
+	// InterfaceIdentifier is the identifier of the interface the peer is linked to.
+	InterfaceIdentifier string `json:"InterfaceIdentifier" example:"wg0"`
+	// Disabled is true if the peer is disabled.
+	Disabled bool `json:"Disabled" example:"false"`
+	// DisabledReason is the reason why the peer has been disabled.
+	DisabledReason string `json:"DisabledReason" example:"inactive"`
+
+	// LastActivity is the time of the last handshake, or the creation date if the peer never connected.
+	LastActivity time.Time `json:"LastActivity"`
+	// NeverConnected is true if no handshake has been recorded for the peer.
+	NeverConnected bool `json:"NeverConnected" example:"false"`
+	// InactiveDays is the number of full days since the last activity.
+	InactiveDays int `json:"InactiveDays" example:"75"`
+	// Warned is the time at which the owner has been warned about the inactivity.
+	Warned *time.Time `json:"Warned,omitempty"`
+	// PendingAction is the action that will be executed by the next inactivity check (warn, disable or delete).
+	PendingAction string `json:"PendingAction,omitempty" example:"warn"`
+	// DisableAt is the earliest time at which the peer will be disabled.
+	DisableAt *time.Time `json:"DisableAt,omitempty"`
+	// DeleteAt is the earliest time at which the peer will be deleted.
+	DeleteAt *time.Time `json:"DeleteAt,omitempty"`
+}
+
+func NewStalePeers(src []domain.StalePeer) []StalePeer {
+	results := make([]StalePeer, len(src))
+	for i, p := range src {
+		results[i] = StalePeer{
+			Identifier:          string(p.Peer.Identifier),
+			DisplayName:         p.Peer.DisplayName,
+			UserIdentifier:      string(p.Peer.UserIdentifier),
+			InterfaceIdentifier: string(p.Peer.InterfaceIdentifier),
+			Disabled:            p.Peer.IsDisabled(),
+			DisabledReason:      p.Peer.DisabledReason,
+			LastActivity:        p.LastActivity,
+			NeverConnected:      p.NeverConnected,
+			InactiveDays:        p.InactiveDays,
+			Warned:              p.Peer.InactivityWarned,
+			PendingAction:       string(p.PendingAction),
+			DisableAt:           p.DisableAt,
+			DeleteAt:            p.DeleteAt,
+		}
+	}
+
+	return results
+}
@@ -23,6 +23,8 @@ type Interface struct {
 	Disabled bool `json:"Disabled" example:"false"`
 	// DisabledReason is the reason why the interface has been disabled.
 	DisabledReason string `json:"DisabledReason" binding:"required_if=Disabled true" example:"This is a reason why the interface has been disabled."`
+	// ManagedBy is set if the interface is defined by the declarative state file. This field is read-only.
+	ManagedBy string `json:"ManagedBy,omitempty" readonly:"true" example:"declarative"`
 	// SaveConfig is a flag that specifies if the configuration should be saved to the configuration file (wgX.conf in wg-quick format).
 	SaveConfig bool `json:"SaveConfig" example:"false"`
 
@@ -77,6 +79,34 @@ type Interface struct {
 	PeerDefPreDown string `json:"PeerDefPreDown"`
 	// PeerDefPostDown specifies the default action that is executed after the device is down for a new peer.
 	PeerDefPostDown string `json:"PeerDefPostDown"`
+	// PeerDefBandwidthLimit is the default rate limit for peers without an own limit.
+	// If it is omitted on updates, the existing limit is kept. Send an empty limit to remove it.
+	PeerDefBandwidthLimit *BandwidthLimit `json:"PeerDefBandwidthLimit,omitempty"`
+	// AccessPolicy defines peer isolation and forwarding rules for all peers. Only the local backend enforces it.
+	// If it is omitted on updates, the existing policy is kept. Send an empty policy to remove it.
+	AccessPolicy *InterfaceAccessPolicy `json:"AccessPolicy,omitempty"`
+	// OnDemandPolicy defines when Apple devices activate the tunnel of the generated .mobileconfig profiles.
+	// If it is omitted on updates, the existing policy is kept. Send an empty policy to remove it.
+	OnDemandPolicy *OnDemandPolicy `json:"OnDemandPolicy,omitempty"`
+
+	// InactivityPolicy defines how peers that did not connect for a long time are handled.
+	// If it is omitted on updates, the existing policy is kept. Send an empty policy to remove it.
+	InactivityPolicy *InactivityPolicy `json:"InactivityPolicy,omitempty"`
+	// IpamPolicy defines reserved address ranges and named ip pools of the peer networks.
+	// If it is omitted on updates, the existing policy is kept. Send an empty policy to remove it.
+	IpamPolicy *IpamPolicy `json:"IpamPolicy,omitempty"`
+	// PrefixDelegation defines the IPv6 pool from which every new peer gets its own routed prefix.
+	// If it is omitted on updates, the existing policy is kept. Send an empty policy to disable the delegation.
+	PrefixDelegation *PrefixDelegationPolicy `json:"PrefixDelegation,omitempty"`
+	// NatPolicy defines masquerading and forwarding rules for an outbound interface. Only the local backend applies it.
+	// If it is omitted on updates, the existing policy is kept. Send an empty policy to remove the rules.
+	NatPolicy *NatPolicy `json:"NatPolicy,omitempty"`
+	// AmneziaParams defines the AmneziaWG obfuscation parameters, they are copied to all peers of the interface.
+	// If it is omitted on updates, the existing parameters are kept. Send an empty object to remove them.
+	AmneziaParams *AmneziaParams `json:"AmneziaParams,omitempty"`
+	// MaintenanceSchedule defines scheduled disable and enable actions, for example recurring maintenance windows.
+	// If it is omitted on updates, the existing schedule is kept. Send an empty schedule to remove all windows.
+	MaintenanceSchedule *MaintenanceSchedule `json:"MaintenanceSchedule,omitempty"`
 
 	// Calculated values
 
@@ -98,6 +128,7 @@ func NewInterface(src *domain.Interface, peers []domain.Peer) *Interface {
 		PublicKey:                  src.PublicKey,
 		Disabled:                   src.IsDisabled(),
 		DisabledReason:             src.DisabledReason,
+		ManagedBy:                  src.ManagedBy,
 		SaveConfig:                 src.SaveConfig,
 		ListenPort:                 src.ListenPort,
 		Addresses:                  domain.CidrsToStringSlice(src.Addresses),
@@ -123,6 +154,15 @@ func NewInterface(src *domain.Interface, peers []domain.Peer) *Interface {
 		PeerDefPostUp:              src.PeerDefPostUp,
 		PeerDefPreDown:             src.PeerDefPreDown,
 		PeerDefPostDown:            src.PeerDefPostDown,
+		PeerDefBandwidthLimit:      NewBandwidthLimit(src.PeerDefBandwidthLimit),
+		AccessPolicy:               NewInterfaceAccessPolicy(src.AccessPolicy),
+		OnDemandPolicy:             NewOnDemandPolicy(src.OnDemandPolicy),
+		InactivityPolicy:           NewInactivityPolicy(src.InactivityPolicy),
+		IpamPolicy:                 NewIpamPolicy(src.IpamPolicy),
+		PrefixDelegation:           NewPrefixDelegationPolicy(src.PrefixDelegation),
+		NatPolicy:                  NewNatPolicy(src.NatPolicy),
+		AmneziaParams:              NewAmneziaParams(src.AmneziaParams),
+		MaintenanceSchedule:        NewMaintenanceSchedule(src.MaintenanceSchedule),
 
 		EnabledPeers: 0,
 		TotalPeers:   0,
@@ -195,6 +235,15 @@ func NewDomainInterface(src *Interface) *domain.Interface {
 		PeerDefPostUp:              src.PeerDefPostUp,
 		PeerDefPreDown:             src.PeerDefPreDown,
 		PeerDefPostDown:            src.PeerDefPostDown,
+		PeerDefBandwidthLimit:      NewDomainBandwidthLimit(src.PeerDefBandwidthLimit),
+		AccessPolicy:               NewDomainInterfaceAccessPolicy(src.AccessPolicy),
+		OnDemandPolicy:             NewDomainOnDemandPolicy(src.OnDemandPolicy),
+		InactivityPolicy:           NewDomainInactivityPolicy(src.InactivityPolicy),
+		IpamPolicy:                 NewDomainIpamPolicy(src.IpamPolicy),
+		PrefixDelegation:           NewDomainPrefixDelegationPolicy(src.PrefixDelegation),
+		NatPolicy:                  NewDomainNatPolicy(src.NatPolicy),
+		AmneziaParams:              NewDomainAmneziaParams(src.AmneziaParams),
+		MaintenanceSchedule:        NewDomainMaintenanceSchedule(src.MaintenanceSchedule),
 	}
 
 	if src.Disabled {
@@ -203,3 +252,24 @@ func NewDomainInterface(src *Interface) *domain.Interface {
 
 	return res
 }
+
+// InterfaceCloneRequest contains the settings of a new interface that is cloned from an existing interface.
+type InterfaceCloneRequest struct {
+	// Identifier is the identifier (device name) of the new interface. If empty, a free identifier is generated.
+	Identifier string `json:"Identifier" binding:"omitempty,max=15" example:"wg1"`
+	// DisplayName is the display name of the new interface. If empty, the identifier is used.
+	DisplayName string `json:"DisplayName" binding:"omitempty,max=64" example:"Region EU"`
+	// Backend is the backend of the new interface. If empty, the backend of the source interface is used.
+	Backend string `json:"Backend" example:"local"`
+	// ClonePeers specifies if all peers of the source interface are duplicated with fresh keys and addresses.
+	ClonePeers bool `json:"ClonePeers" example:"false"`
+}
+
+func NewDomainInterfaceCloneRequest(src *InterfaceCloneRequest) *domain.InterfaceCloneRequest {
+	return &domain.InterfaceCloneRequest{
+		Identifier:  domain.InterfaceIdentifier(src.Identifier),
+		DisplayName: src.DisplayName,
+		Backend:     domain.InterfaceBackend(src.Backend),
+		ClonePeers:  src.ClonePeers,
+	}
+}
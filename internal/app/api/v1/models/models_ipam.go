@@ -0,0 +1,165 @@
+package models
+
+import (
+	"slices"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// IpamPolicy defines which addresses of the peer networks are handed out automatically.
+type IpamPolicy struct {
+	// Reservations are never allocated automatically, for example gateways or statically configured devices.
+	Reservations []IpReservation `json:"Reservations" binding:"omitempty,dive"`
+	// Pools are named address ranges. Their addresses are only allocated if the pool is selected for a new peer.
+	Pools []IpPool `json:"Pools" binding:"omitempty,dive"`
+}
+
+// IpReservation excludes a single address, a network or an address range from the automatic allocation.
+type IpReservation struct {
+	// Range is a single address, a network in CIDR notation or a range in the form first-last.
+	Range string `json:"Range" binding:"required" example:"10.11.12.1-10.11.12.9"`
+	// Description is an optional note for the administrator.
+	Description string `json:"Description" example:"Gateways"`
+}
+
+// IpPool is a named set of address ranges within the peer networks of the interface.
+type IpPool struct {
+	// Name is the unique name of the pool within the interface.
+	Name string `json:"Name" binding:"required" example:"printers"`
+	// Description is an optional note for the administrator.
+	Description string `json:"Description" example:"Network printers"`
+	// Ranges are single addresses, networks or ranges in the form first-last. Pools must not overlap.
+	Ranges []string `json:"Ranges" binding:"required,min=1" example:"10.11.12.200-10.11.12.250"`
+}
+
+func NewIpamPolicy(src *domain.IpamPolicy) *IpamPolicy {
+	if src == nil {
+		return nil
+	}
+
+	policy := &IpamPolicy{
+		Reservations: make([]IpReservation, len(src.Reservations)),
+		Pools:        make([]IpPool, len(src.Pools)),
+	}
+	for i, reservation := range src.Reservations {
+		policy.Reservations[i] = IpReservation{Range: reservation.Range, Description: reservation.Description}
+	}
+	for i, pool := range src.Pools {
+		policy.Pools[i] = IpPool{Name: pool.Name, Description: pool.Description, Ranges: slices.Clone(pool.Ranges)}
+	}
+
+	return policy
+}
+
+func NewDomainIpamPolicy(src *IpamPolicy) *domain.IpamPolicy {
+	if src == nil {
+		return nil
+	}
+
+	policy := &domain.IpamPolicy{
+		Reservations: make([]domain.IpReservation, len(src.Reservations)),
+		Pools:        make([]domain.IpPool, len(src.Pools)),
+	}
+	for i, reservation := range src.Reservations {
+		policy.Reservations[i] = domain.IpReservation{Range: reservation.Range, Description: reservation.Description}
+	}
+	for i, pool := range src.Pools {
+		policy.Pools[i] = domain.IpPool{
+			Name:        pool.Name,
+			Description: pool.Description,
+			Ranges:      slices.Clone(pool.Ranges),
+		}
+	}
+
+	return policy
+}
+
+// IpamAddress is an address that is assigned to a peer or to an interface.
+type IpamAddress struct {
+	// Address is the assigned IP address.
+	Address string `json:"Address" example:"10.11.12.2"`
+	// PeerIdentifier is set if the address belongs to a peer.
+	PeerIdentifier string `json:"PeerIdentifier,omitempty" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// InterfaceIdentifier is set if the address belongs to an interface.
+	InterfaceIdentifier string `json:"InterfaceIdentifier,omitempty" example:"wg0"`
+	// DisplayName is the name of the peer. It is only set for peers of the requested interface.
+	DisplayName string `json:"DisplayName,omitempty" example:"My Peer"`
+}
+
+// IpamPoolStatus is the utilization of an ip pool within a single network.
+type IpamPoolStatus struct {
+	// Name is the name of the pool.
+	Name string `json:"Name" example:"printers"`
+	// Ranges are the ranges of the pool within the network.
+	Ranges []string `json:"Ranges" example:"10.11.12.200-10.11.12.250"`
+	// Size is the number of addresses in the pool, as decimal string.
+	Size string `json:"Size" example:"51"`
+	// Free is the number of addresses in the pool that are neither used nor reserved, as decimal string.
+	Free string `json:"Free" example:"49"`
+}
+
+// IpamNetworkStatus is the utilization of a single peer network of an interface.
+// Counters are decimal strings because IPv6 networks exceed the range of JSON numbers.
+type IpamNetworkStatus struct {
+	// Network is the peer network in CIDR notation.
+	Network string `json:"Network" example:"10.11.12.0/24"`
+	// Size is the number of addresses in the network, including the network and broadcast address.
+	Size string `json:"Size" example:"256"`
+	// Free is the number of addresses that can still be allocated, including addresses of pools.
+	Free string `json:"Free" example:"240"`
+	// Used are all addresses of the network that are assigned to peers or interfaces.
+	Used []IpamAddress `json:"Used"`
+	// Reserved are the reserved ranges within the network.
+	Reserved []string `json:"Reserved" example:"10.11.12.1-10.11.12.9"`
+	// FreeRanges are the ranges that are neither used nor reserved.
+	FreeRanges []string `json:"FreeRanges" example:"10.11.12.20-10.11.12.254"`
+	// Pools is the utilization of the ip pools within the network.
+	Pools []IpamPoolStatus `json:"Pools"`
+}
+
+func NewIpamNetworkStatuses(src []domain.IpamNetworkStatus) []IpamNetworkStatus {
+	results := make([]IpamNetworkStatus, len(src))
+	for i := range src {
+		results[i] = NewIpamNetworkStatus(&src[i])
+	}
+
+	return results
+}
+
+func NewIpamNetworkStatus(src *domain.IpamNetworkStatus) IpamNetworkStatus {
+	status := IpamNetworkStatus{
+		Network:    src.Network.String(),
+		Size:       src.Size.String(),
+		Free:       src.Free.String(),
+		Used:       make([]IpamAddress, len(src.Used)),
+		Reserved:   ipRangeStrings(src.Reserved),
+		FreeRanges: ipRangeStrings(src.FreeRanges),
+		Pools:      make([]IpamPoolStatus, len(src.Pools)),
+	}
+	for i, used := range src.Used {
+		status.Used[i] = IpamAddress{
+			Address:             used.Address.String(),
+			PeerIdentifier:      string(used.PeerIdentifier),
+			InterfaceIdentifier: string(used.InterfaceIdentifier),
+			DisplayName:         used.DisplayName,
+		}
+	}
+	for i, pool := range src.Pools {
+		status.Pools[i] = IpamPoolStatus{
+			Name:   pool.Name,
+			Ranges: ipRangeStrings(pool.Ranges),
+			Size:   pool.Size.String(),
+			Free:   pool.Free.String(),
+		}
+	}
+
+	return status
+}
+
+func ipRangeStrings(ranges []domain.IpRange) []string {
+	result := make([]string, len(ranges))
+	for i, r := range ranges {
+		result[i] = r.String()
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// MaintenanceSchedule contains the scheduled maintenance windows of an interface.
+type MaintenanceSchedule struct {
+	// Windows is the list of scheduled actions. An empty list removes all windows.
+	Windows []MaintenanceWindow `json:"Windows"`
+}
+
+// MaintenanceWindow is a scheduled action on an interface.
+type MaintenanceWindow struct {
+	// Action is either disable or enable.
+	Action string `json:"Action" binding:"required,oneof=disable enable" example:"disable"`
+	// Start is the time of the (first) action.
+	Start time.Time `json:"Start" binding:"required" example:"2024-01-07T02:00:00+01:00"`
+	// End is the time at which a disabled interface is enabled again. It is only allowed for disable actions.
+	End *time.Time `json:"End,omitempty" example:"2024-01-07T04:00:00+01:00"`
+	// Recurrence repeats the window daily, weekly or monthly. Keep it empty for a single action.
+	Recurrence string `json:"Recurrence,omitempty" binding:"omitempty,oneof=daily weekly monthly" example:"weekly"`
+	// Description is shown to the peer owners in the maintenance announcement.
+	Description string `json:"Description,omitempty" example:"Kernel updates"`
+
+	// Calculated values
+
+	// NextStart is the start of the next occurrence, empty if the window does not occur again.
+	NextStart *time.Time `json:"NextStart,omitempty" readonly:"true"`
+	// NextEnd is the end of the current or next occurrence.
+	NextEnd *time.Time `json:"NextEnd,omitempty" readonly:"true"`
+	// LastRun is the time at which the last action of the window has been checked or executed.
+	LastRun *time.Time `json:"LastRun,omitempty" readonly:"true"`
+}
+
+func NewMaintenanceSchedule(src *domain.MaintenanceSchedule) *MaintenanceSchedule {
+	if src == nil {
+		return nil
+	}
+
+	now := time.Now()
+	windows := make([]MaintenanceWindow, len(src.Windows))
+	for i, w := range src.Windows {
+		windows[i] = MaintenanceWindow{
+			Action:      string(w.Action),
+			Start:       w.Start,
+			End:         w.End,
+			Recurrence:  string(w.Recurrence),
+			Description: w.Description,
+			NextStart:   w.NextStart(now),
+			NextEnd:     w.NextEnd(now),
+			LastRun:     w.LastRun,
+		}
+	}
+
+	return &MaintenanceSchedule{Windows: windows}
+}
+
+func NewDomainMaintenanceSchedule(src *MaintenanceSchedule) *domain.MaintenanceSchedule {
+	if src == nil {
+		return nil
+	}
+
+	windows := make([]domain.MaintenanceWindow, len(src.Windows))
+	for i, w := range src.Windows {
+		windows[i] = domain.MaintenanceWindow{
+			Action:      domain.MaintenanceAction(w.Action),
+			Start:       w.Start,
+			End:         w.End,
+			Recurrence:  domain.MaintenanceRecurrence(w.Recurrence),
+			Description: w.Description,
+		}
+	}
+
+	return &domain.MaintenanceSchedule{Windows: windows}
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// Mesh connects multiple interfaces with each other. The peer entries of the links are generated by WireGuard Portal.
+type Mesh struct {
+	// Identifier is the unique identifier of the mesh.
+	Identifier string `json:"Identifier" binding:"required,max=64" example:"branches"`
+	// DisplayName is a nice display name / description for the mesh.
+	DisplayName string `json:"DisplayName" binding:"max=64" example:"Branch offices"`
+	// Topology is either full-mesh or hub-and-spoke.
+	Topology string `json:"Topology" binding:"oneof=full-mesh hub-and-spoke" example:"full-mesh"`
+	// PersistentKeepalive is the keep-alive interval of all generated peer entries, 0 disables it.
+	PersistentKeepalive int `json:"PersistentKeepalive" binding:"min=0" example:"25"`
+	// Nodes are the interfaces that are part of the mesh.
+	Nodes []MeshNode `json:"Nodes" binding:"min=2,dive"`
+
+	// CreatedBy is the user who created the mesh.
+	CreatedBy string `json:"CreatedBy" readonly:"true" example:"admin"`
+	// CreatedAt is the time the mesh was created.
+	CreatedAt time.Time `json:"CreatedAt" readonly:"true"`
+	// UpdatedAt is the time the mesh was last changed.
+	UpdatedAt time.Time `json:"UpdatedAt" readonly:"true"`
+}
+
+// MeshNode is an interface that is part of a mesh.
+type MeshNode struct {
+	// InterfaceIdentifier is the interface of the node, it can belong to any backend.
+	InterfaceIdentifier string `json:"InterfaceIdentifier" binding:"required" example:"wg0"`
+	// Hub marks the node as hub of a hub-and-spoke mesh. Spokes are only connected to the hubs.
+	Hub bool `json:"Hub" example:"false"`
+	// Endpoint is the address the other nodes connect to. If no port is given, the listen port of the interface is
+	// used. If empty, the default peer endpoint of the interface is used.
+	Endpoint string `json:"Endpoint" example:"branch1.example.com:51820"`
+	// Networks are the subnets that are routed to the node, for example the LAN of a branch router.
+	Networks []string `json:"Networks" example:"192.168.10.0/24"`
+}
+
+// MeshPeer is a peer entry that is generated for the link between two nodes of a mesh.
+type MeshPeer struct {
+	// InterfaceIdentifier is the node interface that the peer entry is created on.
+	InterfaceIdentifier string `json:"InterfaceIdentifier" example:"wg0"`
+	// RemoteInterface is the node interface that the peer entry points to.
+	RemoteInterface string `json:"RemoteInterface" example:"wg1"`
+	// PublicKey is the public key of the remote interface.
+	PublicKey string `json:"PublicKey" example:"TrMvSoP4jYQlY6RIzBgbssQqY3vxI2Pi+y71lOWWXX0="`
+	// Endpoint is the endpoint of the remote interface, it is empty if only the remote node initiates the connection.
+	Endpoint string `json:"Endpoint" example:"branch1.example.com:51820"`
+	// AllowedIPs are the tunnel address and the networks of the remote node.
+	AllowedIPs []string `json:"AllowedIPs" example:"10.100.0.2/32,192.168.10.0/24"`
+	// PersistentKeepalive is the keep-alive interval of the peer entry.
+	PersistentKeepalive int `json:"PersistentKeepalive" example:"25"`
+}
+
+func NewMesh(src *domain.Mesh) *Mesh {
+	nodes := make([]MeshNode, len(src.Nodes))
+	for i, node := range src.Nodes {
+		nodes[i] = MeshNode{
+			InterfaceIdentifier: string(node.InterfaceIdentifier),
+			Hub:                 node.Hub,
+			Endpoint:            node.Endpoint,
+			Networks:            node.Networks,
+		}
+	}
+
+	return &Mesh{
+		Identifier:          string(src.Identifier),
+		DisplayName:         src.DisplayName,
+		Topology:            string(src.Topology),
+		PersistentKeepalive: src.PersistentKeepalive,
+		Nodes:               nodes,
+		CreatedBy:           src.CreatedBy,
+		CreatedAt:           src.CreatedAt,
+		UpdatedAt:           src.UpdatedAt,
+	}
+}
+
+func NewMeshes(src []domain.Mesh) []Mesh {
+	results := make([]Mesh, len(src))
+	for i := range src {
+		results[i] = *NewMesh(&src[i])
+	}
+
+	return results
+}
+
+func NewDomainMesh(src *Mesh) *domain.Mesh {
+	nodes := make([]domain.MeshNode, len(src.Nodes))
+	for i, node := range src.Nodes {
+		nodes[i] = domain.MeshNode{
+			InterfaceIdentifier: domain.InterfaceIdentifier(node.InterfaceIdentifier),
+			Hub:                 node.Hub,
+			Endpoint:            node.Endpoint,
+			Networks:            node.Networks,
+		}
+	}
+
+	return &domain.Mesh{
+		Identifier:          domain.MeshIdentifier(src.Identifier),
+		DisplayName:         src.DisplayName,
+		Topology:            domain.MeshTopology(src.Topology),
+		PersistentKeepalive: src.PersistentKeepalive,
+		Nodes:               nodes,
+	}
+}
+
+func NewMeshPeers(src []domain.MeshPeer) []MeshPeer {
+	results := make([]MeshPeer, len(src))
+	for i, peer := range src {
+		results[i] = MeshPeer{
+			InterfaceIdentifier: string(peer.InterfaceIdentifier),
+			RemoteInterface:     string(peer.RemoteInterface),
+			PublicKey:           peer.PublicKey,
+			Endpoint:            peer.Endpoint,
+			AllowedIPs:          domain.CidrsToStringSlice(peer.AllowedIPs),
+			PersistentKeepalive: peer.PersistentKeepalive,
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"slices"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// OnDemandPolicy defines when Apple devices activate the tunnel of a .mobileconfig profile automatically.
+type OnDemandPolicy struct {
+	// TrustedSsids are Wi-Fi networks on which the tunnel is disconnected.
+	TrustedSsids []string `json:"TrustedSsids" binding:"dive,max=32" example:"Office-WiFi"`
+	// Wifi activates the tunnel on all other Wi-Fi networks.
+	Wifi bool `json:"Wifi" example:"true"`
+	// Cellular activates the tunnel on cellular networks. Only used by iOS profiles.
+	Cellular bool `json:"Cellular" example:"true"`
+	// Ethernet activates the tunnel on wired networks. Only used by macOS profiles.
+	Ethernet bool `json:"Ethernet" example:"false"`
+}
+
+func NewOnDemandPolicy(src *domain.OnDemandPolicy) *OnDemandPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &OnDemandPolicy{
+		TrustedSsids: slices.Clone(src.TrustedSsids),
+		Wifi:         src.Wifi,
+		Cellular:     src.Cellular,
+		Ethernet:     src.Ethernet,
+	}
+}
+
+func NewDomainOnDemandPolicy(src *OnDemandPolicy) *domain.OnDemandPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.OnDemandPolicy{
+		TrustedSsids: slices.Clone(src.TrustedSsids),
+		Wifi:         src.Wifi,
+		Cellular:     src.Cellular,
+		Ethernet:     src.Ethernet,
+	}
+}
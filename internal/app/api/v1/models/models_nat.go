@@ -0,0 +1,47 @@
+package models
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// NatPolicy defines the masquerading and forwarding rules between the interface and an outbound interface of the host.
+type NatPolicy struct {
+	// OutboundInterface is the uplink of the host, for example eth0.
+	OutboundInterface string `json:"OutboundInterface" binding:"omitempty,max=15" example:"eth0"`
+	// Masquerade rewrites the source address of peer traffic that leaves through the outbound interface.
+	Masquerade bool `json:"Masquerade" example:"true"`
+	// AllowForwarding accepts traffic from the peers to the outbound interface and the replies to it.
+	AllowForwarding bool `json:"AllowForwarding" example:"true"`
+	// IPv4 applies the rules to IPv4 traffic.
+	IPv4 bool `json:"IPv4" example:"true"`
+	// IPv6 applies the rules to IPv6 traffic.
+	IPv6 bool `json:"IPv6" example:"true"`
+}
+
+func NewNatPolicy(src *domain.NatPolicy) *NatPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &NatPolicy{
+		OutboundInterface: src.OutboundInterface,
+		Masquerade:        src.Masquerade,
+		AllowForwarding:   src.AllowForwarding,
+		IPv4:              src.IPv4,
+		IPv6:              src.IPv6,
+	}
+}
+
+func NewDomainNatPolicy(src *NatPolicy) *domain.NatPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.NatPolicy{
+		OutboundInterface: src.OutboundInterface,
+		Masquerade:        src.Masquerade,
+		AllowForwarding:   src.AllowForwarding,
+		IPv4:              src.IPv4,
+		IPv6:              src.IPv6,
+	}
+}
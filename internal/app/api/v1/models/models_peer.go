@@ -23,10 +23,26 @@ type Peer struct {
 	Disabled bool `json:"Disabled" example:"false"`
 	// DisabledReason is the reason why the peer has been disabled.
 	DisabledReason string `json:"DisabledReason" binding:"required_if=Disabled true" example:"This is a reason why the peer has been disabled."`
+	// ManagedBy is set if the peer is defined by the declarative state file. This field is read-only.
+	ManagedBy string `json:"ManagedBy,omitempty" readonly:"true" example:"declarative"`
 	// ExpiresAt is the expiry date of the peer  in YYYY-MM-DD format. An expired peer is not able to connect.
 	ExpiresAt string `json:"ExpiresAt,omitempty" binding:"omitempty,datetime=2006-01-02"`
 	// Notes is a note field for peers.
 	Notes string `json:"Notes" example:"This is a note for the peer."`
+	// AccessSchedule optionally restricts the time in which the peer is allowed to connect.
+	// If it is omitted on updates, the existing schedule is kept. Send an empty schedule to remove it.
+	// Peers without a schedule use the schedule of their owner.
+	AccessSchedule *AccessSchedule `json:"AccessSchedule,omitempty"`
+	// ScheduleBlocked is true while the peer is outside its access schedule. This field is read-only.
+	ScheduleBlocked bool `json:"ScheduleBlocked" readonly:"true" example:"false"`
+	// BandwidthLimit optionally restricts the throughput of the peer. Only the local backend enforces limits.
+	// If it is omitted on updates, the existing limit is kept. Send an empty limit to remove it.
+	// Peers without a limit use the default limit of their interface.
+	BandwidthLimit *BandwidthLimit `json:"BandwidthLimit,omitempty"`
+	// Acl optionally restricts the destinations that the peer is allowed to reach. Only the local backend
+	// enforces rules. The rules of the peer are evaluated before the rules of its owner and the interface.
+	// If it is omitted on updates, the existing list is kept. Send an empty list to remove it.
+	Acl *AccessControlList `json:"Acl,omitempty"`
 
 	// Endpoint is the endpoint address of the peer.
 	Endpoint ConfigOption[string] `json:"Endpoint"`
@@ -36,6 +52,8 @@ type Peer struct {
 	AllowedIPs ConfigOption[[]string] `json:"AllowedIPs"`
 	// ExtraAllowedIPs is a list of additional allowed IP subnets for the peer. These allowed IP subnets are added on the server side.
 	ExtraAllowedIPs []string `json:"ExtraAllowedIPs"`
+	// DelegatedPrefix is the IPv6 prefix that is routed to the network behind the peer. It is empty if no prefix is delegated.
+	DelegatedPrefix string `json:"DelegatedPrefix" example:"2001:db8:100:100::/56"`
 	// PresharedKey is the optional pre-shared Key of the peer.
 	PresharedKey string `json:"PresharedKey" example:"yAnz5TF+lXXJte14tji3zlMNq+hd2rYUIgJBgB3fBmk=" binding:"omitempty,len=44"`
 	// PersistentKeepalive is the optional persistent keep-alive interval in seconds.
@@ -72,6 +90,9 @@ type Peer struct {
 	PreDown ConfigOption[string] `json:"PreDown"`
 	// PostDown is an optional action that is executed after the device is down.
 	PostDown ConfigOption[string] `json:"PostDown"`
+	// AmneziaParams are the AmneziaWG obfuscation parameters of the interface.
+	// This value is read only, the parameters are always taken from the interface.
+	AmneziaParams *AmneziaParams `json:"AmneziaParams,omitempty" readonly:"true"`
 
 	// Filename is the name of the config file for this peer.
 	// This value is read only and is not settable by the user.
@@ -91,12 +112,18 @@ func NewPeer(src *domain.Peer) *Peer {
 		InterfaceIdentifier: string(src.InterfaceIdentifier),
 		Disabled:            src.IsDisabled(),
 		DisabledReason:      src.DisabledReason,
+		ManagedBy:           src.ManagedBy,
 		ExpiresAt:           expiresAt,
 		Notes:               src.Notes,
+		AccessSchedule:      NewAccessSchedule(src.AccessSchedule),
+		BandwidthLimit:      NewBandwidthLimit(src.BandwidthLimit),
+		Acl:                 NewAccessControlList(src.Acl),
+		ScheduleBlocked:     src.IsScheduleBlocked(),
 		Endpoint:            ConfigOptionFromDomain(src.Endpoint),
 		EndpointPublicKey:   ConfigOptionFromDomain(src.EndpointPublicKey),
 		AllowedIPs:          StringSliceConfigOptionFromDomain(src.AllowedIPsStr),
 		ExtraAllowedIPs:     internal.SliceString(src.ExtraAllowedIPsStr),
+		DelegatedPrefix:     src.DelegatedPrefix,
 		PresharedKey:        string(src.PresharedKey),
 		PersistentKeepalive: ConfigOptionFromDomain(src.PersistentKeepalive),
 		PrivateKey:          src.Interface.PrivateKey,
@@ -113,6 +140,7 @@ func NewPeer(src *domain.Peer) *Peer {
 		PostUp:              ConfigOptionFromDomain(src.Interface.PostUp),
 		PreDown:             ConfigOptionFromDomain(src.Interface.PreDown),
 		PostDown:            ConfigOptionFromDomain(src.Interface.PostDown),
+		AmneziaParams:       NewAmneziaParams(src.Interface.AmneziaParams),
 		Filename:            src.GetConfigFileName(),
 	}
 }
@@ -143,6 +171,7 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 		EndpointPublicKey:   ConfigOptionToDomain(src.EndpointPublicKey),
 		AllowedIPsStr:       StringSliceConfigOptionToDomain(src.AllowedIPs),
 		ExtraAllowedIPsStr:  internal.SliceToString(src.ExtraAllowedIPs),
+		DelegatedPrefix:     src.DelegatedPrefix,
 		PresharedKey:        domain.PreSharedKey(src.PresharedKey),
 		PersistentKeepalive: ConfigOptionToDomain(src.PersistentKeepalive),
 		DisplayName:         src.DisplayName,
@@ -153,6 +182,9 @@ func NewDomainPeer(src *Peer) *domain.Peer {
 		DisabledReason:      src.DisabledReason,
 		ExpiresAt:           expiresAt,
 		Notes:               src.Notes,
+		AccessSchedule:      NewDomainAccessSchedule(src.AccessSchedule),
+		BandwidthLimit:      NewDomainBandwidthLimit(src.BandwidthLimit),
+		Acl:                 NewDomainAccessControlList(src.Acl),
 		Interface: domain.PeerInterfaceConfig{
 			KeyPair: domain.KeyPair{
 				PrivateKey: src.PrivateKey,
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// PeerRequest is a request of a user for a new peer. The peer is created once an admin approves the request.
+type PeerRequest struct {
+	// Identifier is the unique identifier of the request.
+	Identifier string `json:"Identifier" readonly:"true" example:"5b4c3cb8-7a71-4c8a-a9a0-1c7f0a0f2f6e"`
+	// UserIdentifier is the requesting user. If it is not set, the authenticated user is used.
+	UserIdentifier string `json:"UserIdentifier" example:"uid-1234567"`
+	// InterfaceIdentifier is the server interface of the requested peer.
+	InterfaceIdentifier string `json:"InterfaceIdentifier" binding:"required" example:"wg0"`
+	// DisplayName is the device name, it is used as the display name of the new peer.
+	DisplayName string `json:"DisplayName" binding:"required,max=64" example:"My Laptop"`
+	// Justification explains why the peer is needed.
+	Justification string `json:"Justification" binding:"max=1024" example:"Remote work"`
+
+	// State is either pending, approved or rejected.
+	State string `json:"State" readonly:"true" example:"pending"`
+	// DecidedBy is the admin that approved or rejected the request.
+	DecidedBy string `json:"DecidedBy" readonly:"true" example:"admin"`
+	// DecidedAt is the time of the decision.
+	DecidedAt *time.Time `json:"DecidedAt,omitempty" readonly:"true"`
+	// DecisionReason is the optional comment of the admin.
+	DecisionReason string `json:"DecisionReason" readonly:"true" example:""`
+	// PeerIdentifier is the identifier of the peer that has been created on approval.
+	PeerIdentifier string `json:"PeerIdentifier" readonly:"true" example:""`
+	// CreatedAt is the time of the request.
+	CreatedAt time.Time `json:"CreatedAt" readonly:"true"`
+}
+
+// PeerRequestDecision contains the optional comment of an admin for an approval or rejection.
+type PeerRequestDecision struct {
+	// Reason is sent to the requesting user if the request is rejected.
+	Reason string `json:"Reason" example:"Please use your existing peer."`
+}
+
+func NewPeerRequest(src *domain.PeerRequest) *PeerRequest {
+	return &PeerRequest{
+		Identifier:          string(src.Identifier),
+		UserIdentifier:      string(src.UserIdentifier),
+		InterfaceIdentifier: string(src.InterfaceIdentifier),
+		DisplayName:         src.DisplayName,
+		Justification:       src.Justification,
+		State:               string(src.State),
+		DecidedBy:           src.DecidedBy,
+		DecidedAt:           src.DecidedAt,
+		DecisionReason:      src.DecisionReason,
+		PeerIdentifier:      string(src.PeerIdentifier),
+		CreatedAt:           src.CreatedAt,
+	}
+}
+
+func NewPeerRequests(src []domain.PeerRequest) []PeerRequest {
+	results := make([]PeerRequest, len(src))
+	for i := range src {
+		results[i] = *NewPeerRequest(&src[i])
+	}
+
+	return results
+}
+
+// NewDomainPeerRequest converts the fields that can be set by the requesting user.
+func NewDomainPeerRequest(src *PeerRequest) *domain.PeerRequest {
+	return &domain.PeerRequest{
+		UserIdentifier:      domain.UserIdentifier(src.UserIdentifier),
+		InterfaceIdentifier: domain.InterfaceIdentifier(src.InterfaceIdentifier),
+		DisplayName:         src.DisplayName,
+		Justification:       src.Justification,
+	}
+}
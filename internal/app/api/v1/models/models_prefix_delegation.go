@@ -0,0 +1,58 @@
+package models
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// PrefixDelegationPolicy defines the IPv6 pool that is split into one routed prefix per peer.
+type PrefixDelegationPolicy struct {
+	// Pool is the IPv6 network from which the prefixes are delegated. An empty pool disables the delegation.
+	Pool string `json:"Pool" example:"2001:db8:100::/48"`
+	// PrefixLength is the length of the prefix that each peer receives. It must be longer than the pool prefix.
+	PrefixLength int `json:"PrefixLength" binding:"omitempty,min=1,max=128" example:"56"`
+}
+
+func NewPrefixDelegationPolicy(src *domain.PrefixDelegationPolicy) *PrefixDelegationPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &PrefixDelegationPolicy{Pool: src.Pool, PrefixLength: src.PrefixLength}
+}
+
+func NewDomainPrefixDelegationPolicy(src *PrefixDelegationPolicy) *domain.PrefixDelegationPolicy {
+	if src == nil {
+		return nil
+	}
+
+	return &domain.PrefixDelegationPolicy{Pool: src.Pool, PrefixLength: src.PrefixLength}
+}
+
+// DelegatedPrefix is a prefix that is delegated to a peer.
+type DelegatedPrefix struct {
+	// PeerIdentifier is the public key of the peer.
+	PeerIdentifier string `json:"PeerIdentifier" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// DisplayName is the name of the peer.
+	DisplayName string `json:"DisplayName" example:"My Peer"`
+	// Prefix is the delegated prefix in CIDR notation.
+	Prefix string `json:"Prefix" example:"2001:db8:100:100::/56"`
+	// Index is the position of the prefix within the pool. It stays the same if the pool is renumbered.
+	Index uint64 `json:"Index" example:"1"`
+	// InPool is false if the prefix is not part of the current pool, for example because it was set manually.
+	InPool bool `json:"InPool" example:"true"`
+}
+
+func NewDelegatedPrefixes(src []domain.DelegatedPrefixAssignment) []DelegatedPrefix {
+	results := make([]DelegatedPrefix, len(src))
+	for i, assignment := range src {
+		results[i] = DelegatedPrefix{
+			PeerIdentifier: string(assignment.PeerIdentifier),
+			DisplayName:    assignment.DisplayName,
+			Prefix:         assignment.Prefix.String(),
+			Index:          assignment.Index,
+			InPool:         assignment.InPool,
+		}
+	}
+
+	return results
+}
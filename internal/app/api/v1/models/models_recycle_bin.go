@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// DeletedPeer is a peer in the recycle bin. Its addresses stay reserved until it is purged.
+type DeletedPeer struct {
+	Peer
+	// DeletedAt is the time the peer was moved to the recycle bin.
+	DeletedAt time.Time `json:"DeletedAt"`
+}
+
+func NewDeletedPeers(src []domain.Peer) []DeletedPeer {
+	results := make([]DeletedPeer, len(src))
+	for i := range src {
+		results[i] = DeletedPeer{
+			Peer:      *NewPeer(&src[i]),
+			DeletedAt: src[i].DeletedAt.Time,
+		}
+	}
+
+	return results
+}
+
+// DeletedUser is a user in the recycle bin.
+type DeletedUser struct {
+	User
+	// DeletedAt is the time the user was moved to the recycle bin.
+	DeletedAt time.Time `json:"DeletedAt"`
+}
+
+func NewDeletedUsers(src []domain.User) []DeletedUser {
+	results := make([]DeletedUser, len(src))
+	for i := range src {
+		results[i] = DeletedUser{
+			User:      *NewUser(&src[i], false),
+			DeletedAt: src[i].DeletedAt.Time,
+		}
+	}
+
+	return results
+}
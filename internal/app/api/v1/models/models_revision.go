@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// ConfigRevision is a stored version of a peer or interface configuration.
+type ConfigRevision struct {
+	// ObjectType is either "peer" or "interface".
+	ObjectType string `json:"ObjectType" example:"peer"`
+	// ObjectId is the identifier of the peer or interface.
+	ObjectId string `json:"ObjectId" example:"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="`
+	// Version increases with each revision of the object, starting at 1.
+	Version uint64 `json:"Version" example:"3"`
+	// CreatedAt is the time the revision was stored.
+	CreatedAt time.Time `json:"CreatedAt"`
+	// CreatedBy is the user that saved the configuration.
+	CreatedBy string `json:"CreatedBy" example:"admin@wgportal.local"`
+}
+
+func NewConfigRevisions(src []domain.ConfigRevision) []ConfigRevision {
+	results := make([]ConfigRevision, len(src))
+	for i := range src {
+		results[i] = ConfigRevision{
+			ObjectType: string(src[i].ObjectType),
+			ObjectId:   src[i].ObjectId,
+			Version:    src[i].Version,
+			CreatedAt:  src[i].CreatedAt,
+			CreatedBy:  src[i].CreatedBy,
+		}
+	}
+
+	return results
+}
+
+// RevisionChange is a single field that differs between two revisions.
+type RevisionChange struct {
+	// Field is the path of the field, nested fields are separated by dots.
+	Field string `json:"Field" example:"Interface.Mtu"`
+	// From is the JSON encoded old value. It is empty if the field did not exist.
+	From string `json:"From" example:"1420"`
+	// To is the JSON encoded new value. It is empty if the field was removed.
+	To string `json:"To" example:"1380"`
+}
+
+func NewRevisionChanges(src []domain.RevisionChange) []RevisionChange {
+	results := make([]RevisionChange, len(src))
+	for i := range src {
+		results[i] = RevisionChange{
+			Field: src[i].Field,
+			From:  src[i].From,
+			To:    src[i].To,
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// AccessWindow is a time range in which a peer is allowed to connect.
+type AccessWindow struct {
+	// Weekdays are the lowercase three-letter weekday names the window applies to. Empty means every day.
+	Weekdays []string `json:"Weekdays" example:"mon,tue,wed,thu,fri"`
+	// Start is the start time of the window in 24h format.
+	Start string `json:"Start" example:"08:00"`
+	// End is the end time of the window in 24h format. If End is before Start, the window spans midnight.
+	End string `json:"End" example:"17:30"`
+}
+
+// AccessSchedule restricts the time in which a peer is allowed to connect.
+type AccessSchedule struct {
+	// TimeZone is the IANA time zone name the windows are evaluated in. Empty means UTC.
+	TimeZone string `json:"TimeZone" example:"Europe/Vienna"`
+	// Windows are the time windows in which access is allowed.
+	Windows []AccessWindow `json:"Windows"`
+}
+
+func NewAccessSchedule(src *domain.AccessSchedule) *AccessSchedule {
+	if src == nil {
+		return nil
+	}
+
+	windows := make([]AccessWindow, len(src.Windows))
+	for i, w := range src.Windows {
+		windows[i] = AccessWindow{
+			Weekdays: w.Weekdays,
+			Start:    w.Start,
+			End:      w.End,
+		}
+	}
+
+	return &AccessSchedule{
+		TimeZone: src.TimeZone,
+		Windows:  windows,
+	}
+}
+
+func NewDomainAccessSchedule(src *AccessSchedule) *domain.AccessSchedule {
+	if src == nil {
+		return nil
+	}
+
+	windows := make([]domain.AccessWindow, len(src.Windows))
+	for i, w := range src.Windows {
+		windows[i] = domain.AccessWindow{
+			Weekdays: w.Weekdays,
+			Start:    w.Start,
+			End:      w.End,
+		}
+	}
+
+	return &domain.AccessSchedule{
+		TimeZone: src.TimeZone,
+		Windows:  windows,
+	}
+}
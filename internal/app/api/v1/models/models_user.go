@@ -39,6 +39,12 @@ type User struct {
 	Locked bool `json:"Locked" example:"false"`
 	// The reason why the user has been locked.
 	LockedReason string `json:"LockedReason" binding:"required_if=Locked true" example:""`
+	// The default access schedule for all peers of the user that do not define their own schedule.
+	// If it is omitted on updates, the existing schedule is kept. Send an empty schedule to remove it.
+	AccessSchedule *AccessSchedule `json:"AccessSchedule,omitempty"`
+	// The destination rules for all peers of the user. They are evaluated after the rules of the peer.
+	// If it is omitted on updates, the existing list is kept. Send an empty list to remove it.
+	Acl *AccessControlList `json:"Acl,omitempty"`
 
 	// The API token of the user. This field is never populated on bulk read operations.
 	ApiToken string `json:"ApiToken,omitempty" binding:"omitempty,min=32,max=64" example:""`
@@ -47,6 +53,8 @@ type User struct {
 
 	// The number of peers linked to the user. This field is read-only.
 	PeerCount int `json:"PeerCount" readonly:"true" example:"2"`
+	// If this field is set, the user is defined by the declarative state file. This field is read-only.
+	ManagedBy string `json:"ManagedBy,omitempty" readonly:"true" example:"declarative"`
 }
 
 func NewUser(src *domain.User, exposeCredentials bool) *User {
@@ -67,9 +75,12 @@ func NewUser(src *domain.User, exposeCredentials bool) *User {
 		DisabledReason: src.DisabledReason,
 		Locked:         src.IsLocked(),
 		LockedReason:   src.LockedReason,
+		AccessSchedule: NewAccessSchedule(src.AccessSchedule),
+		Acl:            NewAccessControlList(src.Acl),
 		ApiToken:       "", // by default, do not expose API token
 		ApiEnabled:     src.IsApiEnabled(),
 		PeerCount:      src.LinkedPeerCount,
+		ManagedBy:      src.ManagedBy,
 	}
 
 	if exposeCredentials {
@@ -104,6 +115,8 @@ func NewDomainUser(src *User) *domain.User {
 		DisabledReason: src.DisabledReason,
 		Locked:         nil, // set below
 		LockedReason:   src.LockedReason,
+		AccessSchedule: NewDomainAccessSchedule(src.AccessSchedule),
+		Acl:            NewDomainAccessControlList(src.Acl),
 	}
 
 	if src.ApiToken != "" {
@@ -8,11 +8,19 @@ type AuthEvent struct {
 }
 
 type InterfaceEvent struct {
-	Interface domain.Interface
-	Action    string
+	Interface   domain.Interface
+	Action      string
+	Maintenance *domain.MaintenanceWindow // the window that triggered a maintenance action
 }
 
 type PeerEvent struct {
 	Peer   domain.Peer
 	Action string
 }
+
+type DownloadEvent struct {
+	Token  domain.DownloadToken
+	Action string
+	Client string // the client address of a download, empty for management actions
+	Error  string
+}
@@ -90,6 +90,9 @@ func (r *Recorder) connectToMessageBus() error {
 	if err := r.bus.Subscribe(app.TopicAuditPeerChanged, r.handlePeerEvent); err != nil {
 		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditPeerChanged, err)
 	}
+	if err := r.bus.Subscribe(app.TopicAuditDownload, r.handleDownloadEvent); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", app.TopicAuditDownload, err)
+	}
 
 	return nil
 }
@@ -118,6 +121,14 @@ func (r *Recorder) handlePeerEvent(event domain.AuditEventWrapper[PeerEvent]) {
 	}
 }
 
+func (r *Recorder) handleDownloadEvent(event domain.AuditEventWrapper[DownloadEvent]) {
+	err := r.db.SaveAuditEntry(context.Background(), r.downloadEventToAuditEntry(event))
+	if err != nil {
+		slog.Error("failed to create audit entry for download event", "error", err)
+		return
+	}
+}
+
 func (r *Recorder) authEventToAuditEntry(event domain.AuditEventWrapper[AuthEvent]) *domain.AuditEntry {
 	contextUser := domain.GetUserInfo(event.Ctx)
 	e := domain.AuditEntry{
@@ -148,10 +159,24 @@ func (r *Recorder) interfaceEventToAuditEntry(event domain.AuditEventWrapper[Int
 	switch event.Event.Action {
 	case "save":
 		e.Message = fmt.Sprintf("%s updated", event.Event.Interface.Identifier)
+	case "maintenance-disable":
+		e.Message = fmt.Sprintf("%s disabled by maintenance window", event.Event.Interface.Identifier)
+	case "maintenance-enable":
+		e.Message = fmt.Sprintf("%s enabled by maintenance window", event.Event.Interface.Identifier)
+	case "maintenance-announce":
+		e.Message = fmt.Sprintf("%s maintenance announced to peer owners", event.Event.Interface.Identifier)
+		if w := event.Event.Maintenance; w != nil && w.Notified != nil {
+			e.Message = fmt.Sprintf("%s maintenance at %s announced to peer owners",
+				event.Event.Interface.Identifier, w.Notified.Format(time.RFC3339))
+		}
 	default:
 		e.Message = fmt.Sprintf("%s: unknown action", event.Event.Interface.Identifier)
 	}
 
+	if w := event.Event.Maintenance; w != nil && w.Description != "" {
+		e.Message = fmt.Sprintf("%s (%s)", e.Message, w.Description)
+	}
+
 	return &e
 }
 
@@ -167,9 +192,45 @@ func (r *Recorder) peerEventToAuditEntry(event domain.AuditEventWrapper[PeerEven
 	switch event.Event.Action {
 	case "save":
 		e.Message = fmt.Sprintf("%s updated", event.Event.Peer.Identifier)
+	case "schedule-block":
+		e.Message = fmt.Sprintf("%s blocked by access schedule", event.Event.Peer.Identifier)
+	case "schedule-unblock":
+		e.Message = fmt.Sprintf("%s unblocked by access schedule", event.Event.Peer.Identifier)
 	default:
 		e.Message = fmt.Sprintf("%s: unknown action", event.Event.Peer.Identifier)
 	}
 
 	return &e
 }
+
+func (r *Recorder) downloadEventToAuditEntry(event domain.AuditEventWrapper[DownloadEvent]) *domain.AuditEntry {
+	contextUser := domain.GetUserInfo(event.Ctx)
+	token := event.Event.Token
+	e := domain.AuditEntry{
+		CreatedAt:   time.Now(),
+		Severity:    domain.AuditSeverityLevelLow,
+		ContextUser: contextUser.UserId(),
+		Origin:      fmt.Sprintf("download: %s", event.Event.Action),
+	}
+
+	switch event.Event.Action {
+	case "create":
+		e.Message = fmt.Sprintf("download link %s created for %s (%s, %d uses, expires %s)", token.Identifier,
+			token.PeerIdentifier, token.Type, token.MaxUses, token.ExpiresAt.Format(time.RFC3339))
+	case "revoke":
+		e.Message = fmt.Sprintf("download link %s for %s revoked", token.Identifier, token.PeerIdentifier)
+	case "download":
+		e.Message = fmt.Sprintf("%s downloaded via link %s by %s (use %d of %d)", token.PeerIdentifier,
+			token.Identifier, event.Event.Client, token.Uses, token.MaxUses)
+	default:
+		e.Message = fmt.Sprintf("download link %s: unknown action", token.Identifier)
+	}
+
+	if event.Event.Error != "" {
+		e.Severity = domain.AuditSeverityLevelHigh
+		e.Message = fmt.Sprintf("download via link %s by %s failed: %s", token.Identifier, event.Event.Client,
+			event.Event.Error)
+	}
+
+	return &e
+}
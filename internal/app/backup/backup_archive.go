@@ -0,0 +1,137 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+const (
+	archiveFormat  = "wg-portal-backup"
+	archiveVersion = 1
+
+	// scrypt parameters, as recommended for interactive logins in 2017
+	scryptN      = 32768
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32 // AES-256
+	saltLen      = 16
+)
+
+// archive is the portable envelope of a backup. The payload is the gzip compressed JSON encoding of the backup,
+// it is encrypted with AES-256-GCM if a passphrase is used.
+type archive struct {
+	Format    string `json:"format"`
+	Version   int    `json:"version"`
+	Encrypted bool   `json:"encrypted"`
+	Salt      []byte `json:"salt,omitempty"`
+	Nonce     []byte `json:"nonce,omitempty"`
+	Payload   []byte `json:"payload"`
+}
+
+func encodeArchive(w io.Writer, backup *domain.Backup, passphrase string) error {
+	var payload bytes.Buffer
+	gz := gzip.NewWriter(&payload)
+	if err := json.NewEncoder(gz).Encode(backup); err != nil {
+		return fmt.Errorf("failed to encode backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress backup: %w", err)
+	}
+
+	a := archive{Format: archiveFormat, Version: archiveVersion, Payload: payload.Bytes()}
+	if passphrase != "" {
+		a.Encrypted = true
+		a.Salt = make([]byte, saltLen)
+		if _, err := rand.Read(a.Salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+
+		aead, err := newCipher(passphrase, a.Salt)
+		if err != nil {
+			return err
+		}
+		a.Nonce = make([]byte, aead.NonceSize())
+		if _, err := rand.Read(a.Nonce); err != nil {
+			return fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		a.Payload = aead.Seal(nil, a.Nonce, a.Payload, additionalData(&a))
+	}
+
+	if err := json.NewEncoder(w).Encode(a); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	return nil
+}
+
+func decodeArchive(r io.Reader, passphrase string) (*domain.Backup, bool, error) {
+	var a archive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, false, fmt.Errorf("failed to read archive: %w: %w", err, domain.ErrInvalidData)
+	}
+	if a.Format != archiveFormat {
+		return nil, false, fmt.Errorf("not a WireGuard Portal backup: %w", domain.ErrInvalidData)
+	}
+	if a.Version != archiveVersion {
+		return nil, false, fmt.Errorf("unsupported archive version %d: %w", a.Version, domain.ErrInvalidData)
+	}
+
+	payload := a.Payload
+	if a.Encrypted {
+		if passphrase == "" {
+			return nil, true, fmt.Errorf("backup is encrypted, a passphrase is required: %w", domain.ErrInvalidData)
+		}
+
+		aead, err := newCipher(passphrase, a.Salt)
+		if err != nil {
+			return nil, true, err
+		}
+		if len(a.Nonce) != aead.NonceSize() {
+			return nil, true, fmt.Errorf("invalid nonce: %w", domain.ErrInvalidData)
+		}
+		payload, err = aead.Open(nil, a.Nonce, a.Payload, additionalData(&a))
+		if err != nil {
+			return nil, true, fmt.Errorf("wrong passphrase or corrupted backup: %w", domain.ErrInvalidData)
+		}
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, a.Encrypted, fmt.Errorf("failed to decompress backup: %w: %w", err, domain.ErrInvalidData)
+	}
+	var backup domain.Backup
+	if err := json.NewDecoder(gz).Decode(&backup); err != nil && !errors.Is(err, io.EOF) {
+		return nil, a.Encrypted, fmt.Errorf("failed to decode backup: %w: %w", err, domain.ErrInvalidData)
+	}
+
+	return &backup, a.Encrypted, nil
+}
+
+func newCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the format and version of the envelope to the encrypted payload.
+func additionalData(a *archive) []byte {
+	return []byte(fmt.Sprintf("%s/%d", a.Format, a.Version))
+}
@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetSchemaVersion returns the latest applied database schema version.
+	GetSchemaVersion(ctx context.Context) (uint64, error)
+	// GetBackupData loads all data that is part of a backup.
+	GetBackupData(ctx context.Context) (*domain.Backup, error)
+	// RestoreBackupData replaces all data with the contents of the backup.
+	RestoreBackupData(ctx context.Context, backup *domain.Backup) error
+}
+
+// endregion dependencies
+
+// Manager creates and restores backups of all data. Backups are portable between all supported database types.
+type Manager struct {
+	db DatabaseRepo
+}
+
+// NewBackupManager creates a new backup manager.
+func NewBackupManager(db DatabaseRepo) (*Manager, error) {
+	return &Manager{
+		db: db,
+	}, nil
+}
+
+// CreateBackup writes a backup archive of all data to the given writer. If a passphrase is given,
+// the archive is encrypted. Unencrypted archives contain all private keys in plaintext.
+func (m Manager) CreateBackup(ctx context.Context, w io.Writer, passphrase string) (*domain.BackupSummary, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	backup, err := m.db.GetBackupData(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backup data: %w", err)
+	}
+	backup.PortalVersion = internal.Version
+	backup.CreatedAt = time.Now()
+
+	if err := encodeArchive(w, backup, passphrase); err != nil {
+		return nil, err
+	}
+
+	summary := backup.Summary(passphrase != "")
+	slog.Info("backup created", "schemaVersion", summary.SchemaVersion, "encrypted", summary.Encrypted,
+		"users", summary.Users, "interfaces", summary.Interfaces, "peers", summary.Peers)
+
+	return &summary, nil
+}
+
+// RestoreBackup reads a backup archive and replaces all data with its contents. The backup must have been created
+// with the same database schema version. Running managers and backends are not updated, WireGuard Portal must be
+// restarted afterward.
+func (m Manager) RestoreBackup(ctx context.Context, r io.Reader, passphrase string) (*domain.BackupSummary, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	backup, encrypted, err := decodeArchive(r, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaVersion, err := m.db.GetSchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := backup.Validate(schemaVersion); err != nil {
+		return nil, err
+	}
+
+	if err := m.db.RestoreBackupData(ctx, backup); err != nil {
+		return nil, fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	summary := backup.Summary(encrypted)
+	slog.Info("backup restored", "createdAt", summary.CreatedAt, "portalVersion", summary.PortalVersion,
+		"users", summary.Users, "interfaces", summary.Interfaces, "peers", summary.Peers)
+
+	return &summary, nil
+}
@@ -0,0 +1,118 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockDatabaseRepo struct {
+	schemaVersion uint64
+	backup        *domain.Backup
+	restored      *domain.Backup
+}
+
+func (m *mockDatabaseRepo) GetSchemaVersion(_ context.Context) (uint64, error) {
+	return m.schemaVersion, nil
+}
+
+func (m *mockDatabaseRepo) GetBackupData(_ context.Context) (*domain.Backup, error) {
+	return m.backup, nil
+}
+
+func (m *mockDatabaseRepo) RestoreBackupData(_ context.Context, backup *domain.Backup) error {
+	m.restored = backup
+	return nil
+}
+
+func newTestManager() (*Manager, *mockDatabaseRepo) {
+	db := &mockDatabaseRepo{
+		schemaVersion: 4,
+		backup: &domain.Backup{
+			SchemaVersion: 4,
+			Users:         []domain.User{{Identifier: "alice"}},
+			UserPasswords: map[domain.UserIdentifier]string{"alice": "hash"},
+			Interfaces: []domain.Interface{{
+				Identifier: "wg0",
+				KeyPair:    domain.KeyPair{PrivateKey: "iface-private-key"},
+			}},
+			Peers: []domain.Peer{{Identifier: "peer1", InterfaceIdentifier: "wg0"}},
+		},
+	}
+	m, _ := NewBackupManager(db)
+	return m, db
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+}
+
+func TestManager_CreateRestore(t *testing.T) {
+	m, db := newTestManager()
+
+	var archive bytes.Buffer
+	summary, err := m.CreateBackup(adminContext(), &archive, "")
+	require.NoError(t, err)
+	assert.False(t, summary.Encrypted)
+	assert.Equal(t, 1, summary.Peers)
+	assert.Contains(t, archive.String(), `"format":"wg-portal-backup"`)
+
+	summary, err = m.RestoreBackup(adminContext(), &archive, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Users)
+	require.NotNil(t, db.restored)
+	assert.Equal(t, "iface-private-key", db.restored.Interfaces[0].PrivateKey)
+	assert.Equal(t, "hash", db.restored.UserPasswords["alice"])
+	assert.False(t, db.restored.CreatedAt.IsZero())
+
+	_, err = m.CreateBackup(context.Background(), &archive, "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_CreateRestore_Encrypted(t *testing.T) {
+	m, db := newTestManager()
+
+	var archive bytes.Buffer
+	summary, err := m.CreateBackup(adminContext(), &archive, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, summary.Encrypted)
+	data := archive.String()
+
+	_, err = m.RestoreBackup(adminContext(), strings.NewReader(data), "")
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "a passphrase is required")
+	_, err = m.RestoreBackup(adminContext(), strings.NewReader(data), "wrong")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+	assert.Nil(t, db.restored)
+
+	summary, err = m.RestoreBackup(adminContext(), strings.NewReader(data), "correct horse")
+	require.NoError(t, err)
+	assert.True(t, summary.Encrypted)
+	assert.Equal(t, "iface-private-key", db.restored.Interfaces[0].PrivateKey)
+}
+
+func TestManager_RestoreBackup_SchemaVersion(t *testing.T) {
+	m, db := newTestManager()
+
+	var archive bytes.Buffer
+	_, err := m.CreateBackup(adminContext(), &archive, "")
+	require.NoError(t, err)
+	data := archive.String()
+
+	db.schemaVersion = 5
+	_, err = m.RestoreBackup(adminContext(), strings.NewReader(data), "")
+	assert.ErrorContains(t, err, "older than the database schema version")
+
+	db.schemaVersion = 3
+	_, err = m.RestoreBackup(adminContext(), strings.NewReader(data), "")
+	assert.ErrorContains(t, err, "newer than the database schema version")
+	assert.Nil(t, db.restored)
+
+	_, err = m.RestoreBackup(adminContext(), strings.NewReader(`{"format":"other"}`), "")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
@@ -0,0 +1,177 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// userCsvColumns and peerCsvColumns define the CSV header and column order. The names correspond to the JSON field
+// names of the bulk record types.
+var userCsvColumns = []string{
+	"Identifier", "Email", "Source", "ProviderName", "IsAdmin", "Firstname", "Lastname", "Phone", "Department",
+	"Notes", "Password", "Disabled", "Locked",
+}
+
+var peerCsvColumns = []string{
+	"Identifier", "InterfaceIdentifier", "UserIdentifier", "DisplayName", "PublicKey", "PrivateKey",
+	"PresharedKey", "Addresses", "ExtraAllowedIPs", "Endpoint", "PersistentKeepalive", "Disabled", "ExpiresAt",
+	"Notes",
+}
+
+func encodeRecords[T any](format domain.BulkFormat, w io.Writer, columns []string, records []T) error {
+	switch format {
+	case domain.BulkFormatJson:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case domain.BulkFormatCsv:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+		for _, record := range records {
+			row, err := recordToRow(record, columns)
+			if err != nil {
+				return err
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("unsupported format %s: %w", format, domain.ErrInvalidData)
+	}
+}
+
+// decodeRecords parses all records from the given reader. The returned row errors have the same length as the
+// records slice, a non-nil entry marks a record that could not be parsed.
+func decodeRecords[T any](format domain.BulkFormat, r io.Reader, columns []string) (
+	records []T,
+	rowErrors []error,
+	err error,
+) {
+	switch format {
+	case domain.BulkFormatJson:
+		if err := json.NewDecoder(r).Decode(&records); err != nil {
+			return nil, nil, fmt.Errorf("invalid json: %w", errors.Join(err, domain.ErrInvalidData))
+		}
+		return records, make([]error, len(records)), nil
+	case domain.BulkFormatCsv:
+		reader := csv.NewReader(r)
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1 // missing trailing columns are treated as empty
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid csv: %w", errors.Join(err, domain.ErrInvalidData))
+		}
+		if len(rows) == 0 {
+			return []T{}, []error{}, nil
+		}
+
+		header := rows[0]
+		for _, name := range header {
+			if !slices.Contains(columns, strings.TrimSpace(name)) {
+				return nil, nil, fmt.Errorf("unknown csv column %q: %w", name, domain.ErrInvalidData)
+			}
+		}
+
+		records = make([]T, len(rows)-1)
+		rowErrors = make([]error, len(rows)-1)
+		for i, row := range rows[1:] {
+			rowErrors[i] = rowToRecord(&records[i], header, row)
+		}
+		return records, rowErrors, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported format %s: %w", format, domain.ErrInvalidData)
+	}
+}
+
+func recordToRow(record any, columns []string) ([]string, error) {
+	value := reflect.ValueOf(record)
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		field := value.FieldByName(column)
+		if !field.IsValid() {
+			return nil, fmt.Errorf("unknown field %s", column)
+		}
+
+		switch v := field.Interface().(type) {
+		case string:
+			row[i] = v
+		case bool:
+			row[i] = strconv.FormatBool(v)
+		case int:
+			row[i] = strconv.Itoa(v)
+		case []string:
+			row[i] = internal.SliceToString(v)
+		case *time.Time:
+			if v != nil {
+				row[i] = v.Format(time.RFC3339)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported field type for %s", column)
+		}
+	}
+
+	return row, nil
+}
+
+func rowToRecord(record any, header, row []string) error {
+	value := reflect.ValueOf(record).Elem()
+	for i, column := range header {
+		if i >= len(row) {
+			break
+		}
+		raw := strings.TrimSpace(row[i])
+		field := value.FieldByName(strings.TrimSpace(column))
+
+		switch field.Interface().(type) {
+		case string:
+			field.SetString(raw)
+		case bool:
+			if raw == "" {
+				continue
+			}
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", column, domain.ErrInvalidData)
+			}
+			field.SetBool(b)
+		case int:
+			if raw == "" {
+				continue
+			}
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", column, domain.ErrInvalidData)
+			}
+			field.SetInt(int64(n))
+		case []string:
+			field.Set(reflect.ValueOf(internal.SliceString(raw)))
+		case *time.Time:
+			if raw == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", column, domain.ErrInvalidData)
+			}
+			field.Set(reflect.ValueOf(&t))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,349 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type UserManager interface {
+	// GetUser returns the user with the given identifier.
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// GetAllUsers returns all users.
+	GetAllUsers(ctx context.Context) ([]domain.User, error)
+	// CreateUser creates a new user.
+	CreateUser(ctx context.Context, user *domain.User) (*domain.User, error)
+	// UpdateUser updates the user with the given identifier.
+	UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error)
+}
+
+type PeerManager interface {
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetAllInterfacesAndPeers returns all interfaces and their peers.
+	GetAllInterfacesAndPeers(ctx context.Context) ([]domain.Interface, [][]domain.Peer, error)
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// PreparePeer prepares a new peer for the given interface with fresh keys and ip addresses.
+	PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
+	// PreviewPeer prepares a new peer like PreparePeer, but does not lease its ip addresses.
+	PreviewPeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
+	// CreatePeer creates a new peer.
+	CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+// endregion dependencies
+
+// Manager handles bulk import and export of users and peers.
+// All modifications are routed through the user and WireGuard managers, so validation, events and audit
+// logging behave exactly as for single record changes.
+type Manager struct {
+	users UserManager
+	peers PeerManager
+}
+
+// NewBulkManager creates a new bulk import/export manager.
+func NewBulkManager(users UserManager, peers PeerManager) (*Manager, error) {
+	return &Manager{
+		users: users,
+		peers: peers,
+	}, nil
+}
+
+// ExportUsers writes all users to the given writer.
+func (m Manager) ExportUsers(ctx context.Context, format domain.BulkFormat, w io.Writer) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	users, err := m.users.GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	records := make([]domain.BulkUserRecord, len(users))
+	for i := range users {
+		records[i] = domain.NewBulkUserRecord(&users[i])
+	}
+
+	return encodeRecords(format, w, userCsvColumns, records)
+}
+
+// ExportPeers writes all peers to the given writer. If interface identifiers are given, only peers of those
+// interfaces are exported. Key material is only written if the options request it.
+func (m Manager) ExportPeers(
+	ctx context.Context,
+	format domain.BulkFormat,
+	w io.Writer,
+	opts domain.BulkExportOptions,
+	interfaceIds ...domain.InterfaceIdentifier,
+) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	interfaces, interfacePeers, err := m.peers.GetAllInterfacesAndPeers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces and peers: %w", err)
+	}
+
+	records := make([]domain.BulkPeerRecord, 0)
+	for i, iface := range interfaces {
+		if len(interfaceIds) > 0 && !slices.Contains(interfaceIds, iface.Identifier) {
+			continue
+		}
+		for p := range interfacePeers[i] {
+			records = append(records, domain.NewBulkPeerRecord(&interfacePeers[i][p], opts.IncludeKeys))
+		}
+	}
+
+	return encodeRecords(format, w, peerCsvColumns, records)
+}
+
+// ImportUsers reads user records from the given reader and creates or updates the corresponding users.
+// Errors of single records do not abort the import, they are collected in the returned result instead.
+func (m Manager) ImportUsers(
+	ctx context.Context,
+	format domain.BulkFormat,
+	r io.Reader,
+	opts domain.BulkImportOptions,
+) (*domain.BulkImportResult, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	records, rowErrors, err := decodeRecords[domain.BulkUserRecord](format, r, userCsvColumns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse users: %w", err)
+	}
+
+	result := &domain.BulkImportResult{DryRun: opts.DryRun, Total: len(records), Errors: []domain.BulkImportRowError{}}
+	seen := make(map[string]struct{}, len(records))
+	for i, record := range records {
+		row := i + 1
+		if rowErrors[i] != nil {
+			result.AddError(row, record.Identifier, rowErrors[i])
+			continue
+		}
+		if _, ok := seen[record.Identifier]; ok && record.Identifier != "" {
+			result.AddError(row, record.Identifier,
+				fmt.Errorf("user %s is listed multiple times: %w", record.Identifier, domain.ErrDuplicateEntry))
+			continue
+		}
+		seen[record.Identifier] = struct{}{}
+
+		created, err := m.importUser(ctx, record, opts)
+		if err != nil {
+			result.AddError(row, record.Identifier, err)
+			continue
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	slog.Debug("user import finished",
+		"dryRun", opts.DryRun, "total", result.Total, "created", result.Created,
+		"updated", result.Updated, "failed", result.Failed)
+
+	return result, nil
+}
+
+// ImportPeers reads peer records from the given reader and creates or updates the corresponding peers.
+// The referenced interface and owner must already exist. Errors of single records do not abort the import,
+// they are collected in the returned result instead.
+func (m Manager) ImportPeers(
+	ctx context.Context,
+	format domain.BulkFormat,
+	r io.Reader,
+	opts domain.BulkImportOptions,
+) (*domain.BulkImportResult, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	records, rowErrors, err := decodeRecords[domain.BulkPeerRecord](format, r, peerCsvColumns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse peers: %w", err)
+	}
+
+	result := &domain.BulkImportResult{DryRun: opts.DryRun, Total: len(records), Errors: []domain.BulkImportRowError{}}
+	seen := make(map[string]struct{}, len(records))
+	for i, record := range records {
+		row := i + 1
+		key := record.PublicKey
+		if key == "" {
+			key = record.Identifier
+		}
+		if rowErrors[i] != nil {
+			result.AddError(row, key, rowErrors[i])
+			continue
+		}
+		if _, ok := seen[key]; ok && key != "" {
+			result.AddError(row, key,
+				fmt.Errorf("peer %s is listed multiple times: %w", key, domain.ErrDuplicateEntry))
+			continue
+		}
+		seen[key] = struct{}{}
+
+		created, err := m.importPeer(ctx, record, opts)
+		if err != nil {
+			result.AddError(row, key, err)
+			continue
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	slog.Debug("peer import finished",
+		"dryRun", opts.DryRun, "total", result.Total, "created", result.Created,
+		"updated", result.Updated, "failed", result.Failed)
+
+	return result, nil
+}
+
+func (m Manager) importUser(ctx context.Context, record domain.BulkUserRecord, opts domain.BulkImportOptions) (
+	created bool,
+	err error,
+) {
+	if record.Identifier == "" {
+		return false, fmt.Errorf("missing user identifier: %w", domain.ErrInvalidData)
+	}
+
+	existingUser, err := m.users.GetUser(ctx, domain.UserIdentifier(record.Identifier))
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return false, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	if existingUser != nil {
+		if !opts.Upsert {
+			return false, fmt.Errorf("user %s already exists: %w", record.Identifier, domain.ErrDuplicateEntry)
+		}
+
+		record.MergeToUser(existingUser)
+		if opts.DryRun {
+			return false, nil
+		}
+		if _, err := m.users.UpdateUser(ctx, existingUser); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	user := &domain.User{}
+	record.MergeToUser(user)
+	if record.Source != "" && domain.UserSource(record.Source) != domain.UserSourceDatabase {
+		user.Authentications = []domain.UserAuthentication{{
+			UserIdentifier: user.Identifier,
+			Source:         domain.UserSource(record.Source),
+			ProviderName:   record.ProviderName,
+		}}
+	} else if record.Password == "" {
+		return true, fmt.Errorf("database users require a password: %w", domain.ErrInvalidData)
+	}
+
+	if opts.DryRun {
+		return true, nil
+	}
+	if _, err := m.users.CreateUser(ctx, user); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+func (m Manager) importPeer(ctx context.Context, record domain.BulkPeerRecord, opts domain.BulkImportOptions) (
+	created bool,
+	err error,
+) {
+	if record.InterfaceIdentifier == "" {
+		return false, fmt.Errorf("missing interface identifier: %w", domain.ErrInvalidData)
+	}
+	if _, err := m.peers.GetInterface(ctx, domain.InterfaceIdentifier(record.InterfaceIdentifier)); err != nil {
+		return false, fmt.Errorf("unknown interface %s: %w", record.InterfaceIdentifier, domain.ErrInvalidData)
+	}
+	if record.UserIdentifier != "" {
+		if _, err := m.users.GetUser(ctx, domain.UserIdentifier(record.UserIdentifier)); err != nil {
+			return false, fmt.Errorf("unknown owner %s: %w", record.UserIdentifier, domain.ErrInvalidData)
+		}
+	}
+	if record.Identifier != "" && record.PublicKey != "" && record.Identifier != record.PublicKey {
+		return false, fmt.Errorf("identifier does not match public key: %w", domain.ErrInvalidData)
+	}
+	if record.PublicKey == "" {
+		record.PublicKey = record.Identifier
+	}
+
+	var existingPeer *domain.Peer
+	if record.PublicKey != "" {
+		existingPeer, err = m.peers.GetPeer(ctx, domain.PeerIdentifier(record.PublicKey))
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return false, fmt.Errorf("failed to load peer: %w", err)
+		}
+	}
+
+	if existingPeer != nil {
+		if !opts.Upsert {
+			return false, fmt.Errorf("peer %s already exists: %w", record.PublicKey, domain.ErrDuplicateEntry)
+		}
+		if existingPeer.InterfaceIdentifier != domain.InterfaceIdentifier(record.InterfaceIdentifier) {
+			return false, fmt.Errorf("peer %s belongs to interface %s: %w",
+				record.PublicKey, existingPeer.InterfaceIdentifier, domain.ErrInvalidData)
+		}
+
+		record.PrivateKey = keepPrivateKey(record, existingPeer)
+		if err := record.MergeToPeer(existingPeer); err != nil {
+			return false, errors.Join(err, domain.ErrInvalidData)
+		}
+		if opts.DryRun {
+			return false, nil
+		}
+		if _, err := m.peers.UpdatePeer(ctx, existingPeer); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	prepare := m.peers.PreparePeer
+	if opts.DryRun || len(record.Addresses) > 0 {
+		prepare = m.peers.PreviewPeer // the addresses are never used, leasing them would block them for a while
+	}
+	peer, err := prepare(ctx, domain.InterfaceIdentifier(record.InterfaceIdentifier))
+	if err != nil {
+		return true, fmt.Errorf("failed to prepare peer: %w", err)
+	}
+	if err := record.MergeToPeer(peer); err != nil {
+		return true, errors.Join(err, domain.ErrInvalidData)
+	}
+	peer.Identifier = domain.PeerIdentifier(peer.Interface.PublicKey)
+
+	if opts.DryRun {
+		return true, nil
+	}
+	if _, err := m.peers.CreatePeer(ctx, peer); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// keepPrivateKey returns the private key that should be stored for the peer. Exports without keys
+// (the default) must not wipe the key of an existing peer.
+func keepPrivateKey(record domain.BulkPeerRecord, existing *domain.Peer) string {
+	if record.PrivateKey != "" {
+		return record.PrivateKey
+	}
+	return existing.Interface.PrivateKey
+}
@@ -0,0 +1,259 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockUserManager struct {
+	users map[domain.UserIdentifier]*domain.User
+}
+
+func (m *mockUserManager) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	if u, ok := m.users[id]; ok {
+		return u, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockUserManager) GetAllUsers(_ context.Context) ([]domain.User, error) {
+	users := make([]domain.User, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, *u)
+	}
+	return users, nil
+}
+
+func (m *mockUserManager) CreateUser(_ context.Context, user *domain.User) (*domain.User, error) {
+	m.users[user.Identifier] = user
+	return user, nil
+}
+
+func (m *mockUserManager) UpdateUser(_ context.Context, user *domain.User) (*domain.User, error) {
+	m.users[user.Identifier] = user
+	return user, nil
+}
+
+type mockPeerManager struct {
+	iface    domain.Interface
+	peers    map[domain.PeerIdentifier]*domain.Peer
+	previews int
+}
+
+func (m *mockPeerManager) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	if id == m.iface.Identifier {
+		return &m.iface, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockPeerManager) GetAllInterfacesAndPeers(_ context.Context) ([]domain.Interface, [][]domain.Peer, error) {
+	peers := make([]domain.Peer, 0, len(m.peers))
+	for _, p := range m.peers {
+		peers = append(peers, *p)
+	}
+	return []domain.Interface{m.iface}, [][]domain.Peer{peers}, nil
+}
+
+func (m *mockPeerManager) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	if p, ok := m.peers[id]; ok {
+		return p, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockPeerManager) PreparePeer(_ context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
+	kp, err := domain.NewFreshKeypair()
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Peer{
+		Identifier:          domain.PeerIdentifier(kp.PublicKey),
+		InterfaceIdentifier: id,
+		Interface:           domain.PeerInterfaceConfig{KeyPair: kp},
+	}, nil
+}
+
+func (m *mockPeerManager) PreviewPeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
+	m.previews++
+	return m.PreparePeer(ctx, id)
+}
+
+func (m *mockPeerManager) CreatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	m.peers[peer.Identifier] = peer
+	return peer, nil
+}
+
+func (m *mockPeerManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	m.peers[peer.Identifier] = peer
+	return peer, nil
+}
+
+func newTestManager() (*Manager, *mockUserManager, *mockPeerManager) {
+	users := &mockUserManager{users: map[domain.UserIdentifier]*domain.User{}}
+	peers := &mockPeerManager{
+		iface: domain.Interface{Identifier: "wg0"},
+		peers: map[domain.PeerIdentifier]*domain.Peer{},
+	}
+	m, _ := NewBulkManager(users, peers)
+	return m, users, peers
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+}
+
+func TestManager_ImportUsers_Csv(t *testing.T) {
+	m, users, _ := newTestManager()
+
+	csvData := "Identifier,Email,Password,IsAdmin\n" +
+		"alice,alice@example.com,secret-password,true\n" +
+		"bob,bob@example.com,,false\n" +
+		"carol,carol@example.com,other-password,maybe\n"
+
+	result, err := m.ImportUsers(adminContext(), domain.BulkFormatCsv, strings.NewReader(csvData),
+		domain.BulkImportOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, result.Total)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 2, result.Failed)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 2, result.Errors[0].Row)
+	assert.Equal(t, "bob", result.Errors[0].Identifier)
+	assert.Equal(t, 3, result.Errors[1].Row)
+
+	require.Contains(t, users.users, domain.UserIdentifier("alice"))
+	assert.True(t, users.users["alice"].IsAdmin)
+}
+
+func TestManager_ImportUsers_DryRunAndUpsert(t *testing.T) {
+	m, users, _ := newTestManager()
+	users.users["alice"] = &domain.User{Identifier: "alice", Email: "old@example.com"}
+
+	jsonData := `[{"Identifier":"alice","Email":"new@example.com"}]`
+
+	result, err := m.ImportUsers(adminContext(), domain.BulkFormatJson, strings.NewReader(jsonData),
+		domain.BulkImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Failed, "existing users must not be overwritten without upsert")
+
+	result, err = m.ImportUsers(adminContext(), domain.BulkFormatJson, strings.NewReader(jsonData),
+		domain.BulkImportOptions{Upsert: true, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Updated)
+	assert.True(t, result.DryRun)
+
+	result, err = m.ImportUsers(adminContext(), domain.BulkFormatJson, strings.NewReader(jsonData),
+		domain.BulkImportOptions{Upsert: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, "new@example.com", users.users["alice"].Email)
+}
+
+func TestManager_ImportPeers_Leases(t *testing.T) {
+	m, users, peers := newTestManager()
+	users.users["alice"] = &domain.User{Identifier: "alice"}
+
+	csvData := "InterfaceIdentifier,UserIdentifier,DisplayName,Addresses\n" +
+		"wg0,alice,Laptop,\n" +
+		"wg0,alice,Phone,10.0.0.3/32\n"
+
+	_, err := m.ImportPeers(adminContext(), domain.BulkFormatCsv, strings.NewReader(csvData),
+		domain.BulkImportOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, peers.previews, "dry runs must not lease addresses")
+
+	peers.previews = 0
+	_, err = m.ImportPeers(adminContext(), domain.BulkFormatCsv, strings.NewReader(csvData),
+		domain.BulkImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, peers.previews, "explicit addresses must not lease addresses")
+	assert.Len(t, peers.peers, 2)
+}
+
+func TestManager_ImportPeers_Relations(t *testing.T) {
+	m, users, peers := newTestManager()
+	users.users["alice"] = &domain.User{Identifier: "alice"}
+
+	csvData := "InterfaceIdentifier,UserIdentifier,DisplayName,Addresses\n" +
+		"wg0,alice,Laptop,10.0.0.2/32\n" +
+		"wg1,alice,Phone,10.0.0.3/32\n" +
+		"wg0,mallory,Tablet,10.0.0.4/32\n"
+
+	result, err := m.ImportPeers(adminContext(), domain.BulkFormatCsv, strings.NewReader(csvData),
+		domain.BulkImportOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 2, result.Failed)
+	assert.Empty(t, peers.peers, "dry run must not persist peers")
+
+	result, err = m.ImportPeers(adminContext(), domain.BulkFormatCsv, strings.NewReader(csvData),
+		domain.BulkImportOptions{})
+	require.NoError(t, err)
+	require.Len(t, peers.peers, 1)
+	for _, p := range peers.peers {
+		assert.Equal(t, "Laptop", p.DisplayName)
+		assert.Equal(t, domain.UserIdentifier("alice"), p.UserIdentifier)
+		assert.Equal(t, "10.0.0.2/32", domain.CidrsToString(p.Interface.Addresses))
+	}
+}
+
+func TestManager_ExportImportPeers_RoundTrip(t *testing.T) {
+	m, _, peers := newTestManager()
+	peers.peers["pubkey"] = &domain.Peer{
+		Identifier:          "pubkey",
+		InterfaceIdentifier: "wg0",
+		DisplayName:         "Server",
+		Notes:               "note, with comma",
+		Interface: domain.PeerInterfaceConfig{
+			KeyPair: domain.KeyPair{PublicKey: "pubkey", PrivateKey: "privkey"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, m.ExportPeers(adminContext(), domain.BulkFormatCsv, &buf, domain.BulkExportOptions{}))
+	assert.NotContains(t, buf.String(), "privkey")
+
+	peers.peers["pubkey"].Notes = "changed"
+	result, err := m.ImportPeers(adminContext(), domain.BulkFormatCsv, &buf,
+		domain.BulkImportOptions{Upsert: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, "note, with comma", peers.peers["pubkey"].Notes)
+	assert.Equal(t, "privkey", peers.peers["pubkey"].Interface.PrivateKey)
+}
+
+func TestManager_ExportPeers_IncludeKeys(t *testing.T) {
+	m, _, peers := newTestManager()
+	peers.peers["pubkey"] = &domain.Peer{
+		Identifier:          "pubkey",
+		InterfaceIdentifier: "wg0",
+		PresharedKey:        "psk",
+		Interface: domain.PeerInterfaceConfig{
+			KeyPair: domain.KeyPair{PublicKey: "pubkey", PrivateKey: "privkey"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, m.ExportPeers(adminContext(), domain.BulkFormatJson, &buf,
+		domain.BulkExportOptions{IncludeKeys: true}))
+	assert.Contains(t, buf.String(), `"PrivateKey": "privkey"`)
+	assert.Contains(t, buf.String(), `"PresharedKey": "psk"`)
+}
+
+func TestManager_NoPermission(t *testing.T) {
+	m, _, _ := newTestManager()
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user"})
+
+	_, err := m.ImportUsers(ctx, domain.BulkFormatJson, strings.NewReader("[]"), domain.BulkImportOptions{})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	assert.ErrorIs(t, m.ExportUsers(ctx, domain.BulkFormatJson, &bytes.Buffer{}), domain.ErrNoPermission)
+}
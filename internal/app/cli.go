@@ -1,18 +1,98 @@
 package app
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"gorm.io/gorm"
 
+	"github.com/h44z/wg-portal/internal"
 	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
 )
 
+type BulkManager interface {
+	ExportUsers(ctx context.Context, format domain.BulkFormat, w io.Writer) error
+	ExportPeers(
+		ctx context.Context,
+		format domain.BulkFormat,
+		w io.Writer,
+		opts domain.BulkExportOptions,
+		ifaces ...domain.InterfaceIdentifier,
+	) error
+	ImportUsers(ctx context.Context, format domain.BulkFormat, r io.Reader, opts domain.BulkImportOptions) (
+		*domain.BulkImportResult,
+		error,
+	)
+	ImportPeers(ctx context.Context, format domain.BulkFormat, r io.Reader, opts domain.BulkImportOptions) (
+		*domain.BulkImportResult,
+		error,
+	)
+}
+
+type BackupManager interface {
+	CreateBackup(ctx context.Context, w io.Writer, passphrase string) (*domain.BackupSummary, error)
+	RestoreBackup(ctx context.Context, r io.Reader, passphrase string) (*domain.BackupSummary, error)
+}
+
+type DeclarativeManager interface {
+	Apply(ctx context.Context, data []byte, dryRun bool) (*domain.DeclarativePlan, error)
+}
+
+// bulkArgs holds the bulk import/export program arguments. They are parsed in HandleProgramArgs but can only be
+// processed once all managers are available, see HandleBulkProgramArgs.
+var bulkArgs struct {
+	importUsers string
+	importPeers string
+	exportUsers string
+	exportPeers string
+	exportKeys  bool
+	format      string
+	dryRun      bool
+	upsert      bool
+}
+
+// backupArgs holds the backup and restore program arguments, see HandleBackupProgramArgs.
+var backupArgs struct {
+	backup     string
+	restore    string
+	passphrase string
+}
+
+// stateArgs holds the declarative state program arguments, see HandleDeclarativeProgramArgs.
+var stateArgs struct {
+	applyState string
+	dryRun     bool
+}
+
 // HandleProgramArgs handles program arguments and returns true if the program should exit.
 func HandleProgramArgs(db *gorm.DB) (exit bool, err error) {
 	migrationSource := flag.String("migrateFrom", "", "path to v1 database file or DSN")
 	migrationDbType := flag.String("migrateFromType", string(config.DatabaseSQLite),
 		"old database type, either mysql, mssql, postgres or sqlite")
+	flag.StringVar(&bulkArgs.importUsers, "importUsers", "", "path to a csv or json file with users to import")
+	flag.StringVar(&bulkArgs.importPeers, "importPeers", "", "path to a csv or json file with peers to import")
+	flag.StringVar(&bulkArgs.exportUsers, "exportUsers", "", "path to the csv or json file users are exported to")
+	flag.StringVar(&bulkArgs.exportPeers, "exportPeers", "", "path to the csv or json file peers are exported to")
+	flag.BoolVar(&bulkArgs.exportKeys, "exportPeerKeys", false,
+		"include the private and pre-shared keys in the peer export (plaintext)")
+	flag.StringVar(&bulkArgs.format, "bulkFormat", "",
+		"bulk import/export format, either csv or json. Derived from the file extension if empty")
+	flag.BoolVar(&bulkArgs.dryRun, "bulkDryRun", false, "only validate the imported records, do not persist them")
+	flag.BoolVar(&bulkArgs.upsert, "bulkUpsert", false, "update existing records during import")
+	flag.StringVar(&backupArgs.backup, "backup", "", "path of the backup archive that is created")
+	flag.StringVar(&backupArgs.restore, "restoreBackup", "",
+		"path of a backup archive that replaces all data of the configured database")
+	flag.StringVar(&backupArgs.passphrase, "backupPassphrase", os.Getenv("WG_PORTAL_BACKUP_PASSPHRASE"),
+		"passphrase of the backup archive, the archive is not encrypted if empty")
+	flag.StringVar(&stateArgs.applyState, "applyState", "", "path to a declarative state file to apply")
+	flag.BoolVar(&stateArgs.dryRun, "stateDryRun", false, "only print the planned changes of the state file")
 	flag.Parse()
 
 	if *migrationSource != "" {
@@ -22,3 +102,196 @@ func HandleProgramArgs(db *gorm.DB) (exit bool, err error) {
 
 	return
 }
+
+// HandleBulkProgramArgs processes the bulk import/export program arguments and returns true if the program
+// should exit. Users are always processed before peers so that peer owners exist at import time.
+func HandleBulkProgramArgs(ctx context.Context, bulk BulkManager) (exit bool, err error) {
+	if bulkArgs.importUsers == "" && bulkArgs.importPeers == "" &&
+		bulkArgs.exportUsers == "" && bulkArgs.exportPeers == "" {
+		return false, nil
+	}
+
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	opts := domain.BulkImportOptions{DryRun: bulkArgs.dryRun, Upsert: bulkArgs.upsert}
+
+	if bulkArgs.importUsers != "" {
+		if err := runBulkImport(ctx, bulkArgs.importUsers, opts, bulk.ImportUsers); err != nil {
+			return true, fmt.Errorf("user import failed: %w", err)
+		}
+	}
+	if bulkArgs.importPeers != "" {
+		if err := runBulkImport(ctx, bulkArgs.importPeers, opts, bulk.ImportPeers); err != nil {
+			return true, fmt.Errorf("peer import failed: %w", err)
+		}
+	}
+	if bulkArgs.exportUsers != "" {
+		err := runBulkExport(bulkArgs.exportUsers, func(format domain.BulkFormat, w io.Writer) error {
+			return bulk.ExportUsers(ctx, format, w)
+		})
+		if err != nil {
+			return true, fmt.Errorf("user export failed: %w", err)
+		}
+	}
+	if bulkArgs.exportPeers != "" {
+		err := runBulkExport(bulkArgs.exportPeers, func(format domain.BulkFormat, w io.Writer) error {
+			return bulk.ExportPeers(ctx, format, w, domain.BulkExportOptions{IncludeKeys: bulkArgs.exportKeys})
+		})
+		if err != nil {
+			return true, fmt.Errorf("peer export failed: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// HandleBackupProgramArgs creates or restores a backup and returns true if the program should exit.
+// It must be called before any background jobs are started, as a restore replaces all data.
+func HandleBackupProgramArgs(ctx context.Context, backups BackupManager) (exit bool, err error) {
+	if backupArgs.backup == "" && backupArgs.restore == "" {
+		return false, nil
+	}
+	if backupArgs.backup != "" && backupArgs.restore != "" {
+		return true, fmt.Errorf("backup and restore can not be combined")
+	}
+
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	if backupArgs.backup != "" {
+		file, err := os.OpenFile(backupArgs.backup, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return true, err
+		}
+		defer internal.LogClose(file)
+
+		summary, err := backups.CreateBackup(ctx, file, backupArgs.passphrase)
+		if err != nil {
+			return true, fmt.Errorf("backup failed: %w", err)
+		}
+		slog.Info("backup written", "file", backupArgs.backup, "encrypted", summary.Encrypted,
+			"users", summary.Users, "interfaces", summary.Interfaces, "peers", summary.Peers,
+			"auditEntries", summary.AuditEntries)
+		return true, nil
+	}
+
+	file, err := os.Open(backupArgs.restore)
+	if err != nil {
+		return true, err
+	}
+	defer internal.LogClose(file)
+
+	summary, err := backups.RestoreBackup(ctx, file, backupArgs.passphrase)
+	if err != nil {
+		return true, fmt.Errorf("restore failed: %w", err)
+	}
+	slog.Info("backup restored, please restart WireGuard Portal", "file", backupArgs.restore,
+		"createdAt", summary.CreatedAt, "portalVersion", summary.PortalVersion,
+		"users", summary.Users, "interfaces", summary.Interfaces, "peers", summary.Peers,
+		"auditEntries", summary.AuditEntries)
+	return true, nil
+}
+
+// HandleDeclarativeProgramArgs applies the given declarative state file and returns true if the program should
+// exit. All planned changes are logged, failed changes are reported as error.
+func HandleDeclarativeProgramArgs(ctx context.Context, declarative DeclarativeManager) (exit bool, err error) {
+	if stateArgs.applyState == "" {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(stateArgs.applyState)
+	if err != nil {
+		return true, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if len(data) == 0 {
+		return true, fmt.Errorf("state file %s is empty", stateArgs.applyState)
+	}
+
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+	plan, err := declarative.Apply(ctx, data, stateArgs.dryRun)
+	if err != nil {
+		return true, err
+	}
+
+	for _, change := range plan.Changes {
+		if change.Error != "" {
+			slog.Error("declarative change failed", "action", change.Action, "type", change.ObjectType,
+				"id", change.Identifier, "error", change.Error)
+			continue
+		}
+		slog.Info("declarative change", "action", change.Action, "type", change.ObjectType,
+			"id", change.Identifier, "fields", len(change.Fields), "dryRun", plan.DryRun)
+		for _, field := range change.Fields {
+			slog.Info("declarative change field", "id", change.Identifier, "field", field.Field,
+				"from", field.From, "to", field.To)
+		}
+	}
+
+	if failed := plan.Failed(); failed > 0 {
+		return true, fmt.Errorf("%d of %d changes failed", failed, len(plan.Changes))
+	}
+	return true, nil
+}
+
+func runBulkImport(
+	ctx context.Context,
+	path string,
+	opts domain.BulkImportOptions,
+	importFn func(context.Context, domain.BulkFormat, io.Reader, domain.BulkImportOptions) (
+		*domain.BulkImportResult,
+		error,
+	),
+) error {
+	format, err := bulkFormatForFile(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer internal.LogClose(file)
+
+	result, err := importFn(ctx, format, file, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, rowErr := range result.Errors {
+		slog.Error("failed to import record", "file", path, "row", rowErr.Row, "identifier", rowErr.Identifier,
+			"error", rowErr.Message)
+	}
+	slog.Info("bulk import finished", "file", path, "dryRun", result.DryRun, "total", result.Total,
+		"created", result.Created, "updated", result.Updated, "failed", result.Failed)
+
+	if result.Failed > 0 {
+		return fmt.Errorf("%d of %d records failed", result.Failed, result.Total)
+	}
+	return nil
+}
+
+func runBulkExport(path string, exportFn func(domain.BulkFormat, io.Writer) error) error {
+	format, err := bulkFormatForFile(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600) // the export may contain private keys
+	if err != nil {
+		return err
+	}
+	defer internal.LogClose(file)
+
+	if err := exportFn(format, file); err != nil {
+		return err
+	}
+
+	slog.Info("bulk export finished", "file", path)
+	return nil
+}
+
+func bulkFormatForFile(path string) (domain.BulkFormat, error) {
+	if bulkArgs.format != "" {
+		return domain.ParseBulkFormat(bulkArgs.format)
+	}
+	return domain.ParseBulkFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+}
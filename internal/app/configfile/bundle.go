@@ -0,0 +1,136 @@
+package configfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// WritePeerConfigBundle writes a zip archive to w that contains the configuration files and a QR code image
+// for every selected peer. Access rights are checked for all peers before anything is written, so a permission
+// error never results in a partial archive.
+func (m Manager) WritePeerConfigBundle(ctx context.Context, w io.Writer, req domain.ConfigBundleRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	peers, err := m.getBundlePeers(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	qrStyle := req.Style
+	if !domain.IsWireGuardConfigStyle(qrStyle) {
+		qrStyle = domain.ConfigStyleWgQuick
+	}
+
+	now := time.Now()
+	zw := zip.NewWriter(w)
+	usedNames := make(map[string]struct{}, len(peers))
+	for i := range peers {
+		peer := &peers[i]
+
+		files, err := m.tplHandler.GetPeerConfigFiles(peer, req.Style)
+		if err != nil {
+			return fmt.Errorf("failed to get peer config for %s: %w", peer.Identifier, err)
+		}
+		qrCode, err := m.renderQrCode(peer, qrStyle)
+		if err != nil {
+			return err
+		}
+
+		// multiple peers may share the same display name, so the file names get a numeric suffix
+		baseName := strings.TrimSuffix(peer.GetConfigFileName(), ".conf")
+		uniqueName := baseName
+		for n := 2; ; n++ {
+			if _, exists := usedNames[uniqueName]; !exists {
+				break
+			}
+			uniqueName = fmt.Sprintf("%s_%d", baseName, n)
+		}
+		usedNames[uniqueName] = struct{}{}
+
+		for _, file := range files {
+			name := uniqueName + strings.TrimPrefix(file.Name, baseName)
+			if err := writeZipEntry(zw, name, now, bytes.NewReader(file.Data)); err != nil {
+				return err
+			}
+		}
+		if err := writeZipEntry(zw, uniqueName+".png", now, qrCode); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize config bundle: %w", err)
+	}
+
+	return nil
+}
+
+// getBundlePeers loads the peers of the bundle selection and validates that the current user may access them.
+func (m Manager) getBundlePeers(ctx context.Context, req domain.ConfigBundleRequest) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	switch {
+	case req.UserIdentifier != "":
+		if err := domain.ValidateUserAccessRights(ctx, req.UserIdentifier); err != nil {
+			return nil, err
+		}
+
+		userPeers, err := m.wg.GetUserPeers(ctx, req.UserIdentifier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch peers of user %s: %w", req.UserIdentifier, err)
+		}
+		peers = userPeers
+	case req.InterfaceIdentifier != "":
+		_, ifacePeers, err := m.wg.GetInterfaceAndPeers(ctx, req.InterfaceIdentifier)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch interface %s: %w", req.InterfaceIdentifier, err)
+		}
+		peers = ifacePeers
+	default:
+		peers = make([]domain.Peer, 0, len(req.PeerIdentifiers))
+		for _, id := range req.PeerIdentifiers {
+			peer, err := m.wg.GetPeer(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch peer %s: %w", id, err)
+			}
+			peers = append(peers, *peer)
+		}
+	}
+
+	for _, peer := range peers {
+		if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("no peers found for config bundle: %w", domain.ErrNotFound)
+	}
+
+	return peers, nil
+}
+
+func writeZipEntry(zw *zip.Writer, name string, modified time.Time, data io.Reader) error {
+	entry, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to config bundle: %w", name, err)
+	}
+
+	if _, err := io.Copy(entry, data); err != nil {
+		return fmt.Errorf("failed to write %s to config bundle: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package configfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockWireguardRepo struct {
+	peers []domain.Peer
+}
+
+func (m *mockWireguardRepo) GetInterfaceAndPeers(_ context.Context, id domain.InterfaceIdentifier) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	var peers []domain.Peer
+	for _, peer := range m.peers {
+		if peer.InterfaceIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return &domain.Interface{Identifier: id}, peers, nil
+}
+
+func (m *mockWireguardRepo) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	for i := range m.peers {
+		if m.peers[i].Identifier == id {
+			peer := m.peers[i]
+			return &peer, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockWireguardRepo) GetUserPeers(_ context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range m.peers {
+		if peer.UserIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+func (m *mockWireguardRepo) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (
+	*domain.Interface,
+	error,
+) {
+	return &domain.Interface{Identifier: id}, nil
+}
+
+func newBundleTestManager(t *testing.T) Manager {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	alice := *newTestPeer(t)
+	alice.UserIdentifier = "alice"
+
+	// same display name as the first peer, the files must not overwrite each other
+	aliceTablet := *newTestPeer(t)
+	aliceTablet.Identifier = "peer-pub-key-2"
+	aliceTablet.UserIdentifier = "alice"
+
+	bob := *newTestPeer(t)
+	bob.Identifier = "peer-pub-key-3"
+	bob.DisplayName = "Bob Phone"
+	bob.UserIdentifier = "bob"
+
+	return Manager{
+		tplHandler: handler,
+		wg:         &mockWireguardRepo{peers: []domain.Peer{alice, aliceTablet, bob}},
+	}
+}
+
+func userContext(id domain.UserIdentifier, admin bool) context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: id, IsAdmin: admin})
+}
+
+func readBundle(t *testing.T, data []byte) []string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	names := make([]string, len(zr.File))
+	for i, f := range zr.File {
+		names[i] = f.Name
+	}
+	return names
+}
+
+func TestManager_WritePeerConfigBundle_User(t *testing.T) {
+	m := newBundleTestManager(t)
+
+	buf := bytes.Buffer{}
+	err := m.WritePeerConfigBundle(userContext("alice", false), &buf, domain.ConfigBundleRequest{
+		UserIdentifier: "alice",
+		Style:          domain.ConfigStyleNetworkd,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"Alice_Laptop.netdev", "Alice_Laptop.network", "Alice_Laptop.png",
+		"Alice_Laptop_2.netdev", "Alice_Laptop_2.network", "Alice_Laptop_2.png",
+	}, readBundle(t, buf.Bytes()))
+}
+
+func TestManager_WritePeerConfigBundle_Interface(t *testing.T) {
+	m := newBundleTestManager(t)
+
+	buf := bytes.Buffer{}
+	err := m.WritePeerConfigBundle(userContext("alice", false), &buf, domain.ConfigBundleRequest{
+		InterfaceIdentifier: "wg-office",
+	})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	assert.Zero(t, buf.Len())
+
+	err = m.WritePeerConfigBundle(userContext("admin", true), &buf, domain.ConfigBundleRequest{
+		InterfaceIdentifier: "wg-office",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"Alice_Laptop.conf", "Alice_Laptop.png",
+		"Alice_Laptop_2.conf", "Alice_Laptop_2.png",
+		"Bob_Phone.conf", "Bob_Phone.png",
+	}, readBundle(t, buf.Bytes()))
+}
+
+func TestManager_WritePeerConfigBundle_Peers(t *testing.T) {
+	m := newBundleTestManager(t)
+
+	buf := bytes.Buffer{}
+	err := m.WritePeerConfigBundle(userContext("bob", false), &buf, domain.ConfigBundleRequest{
+		PeerIdentifiers: []domain.PeerIdentifier{"peer-pub-key-3", "peer-pub-key"},
+	})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	assert.Zero(t, buf.Len())
+
+	err = m.WritePeerConfigBundle(userContext("bob", false), &buf, domain.ConfigBundleRequest{
+		PeerIdentifiers: []domain.PeerIdentifier{"peer-pub-key-3"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bob_Phone.conf", "Bob_Phone.png"}, readBundle(t, buf.Bytes()))
+
+	err = m.WritePeerConfigBundle(userContext("bob", false), &buf, domain.ConfigBundleRequest{
+		UserIdentifier: "carol",
+	})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
@@ -30,6 +30,8 @@ type WireguardDatabaseRepo interface {
 	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
 	// GetPeer returns the peer with the given identifier.
 	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	// GetUserPeers returns all peers associated with the given user.
+	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
 	// GetInterface returns the interface with the given identifier.
 	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
 }
@@ -47,6 +49,14 @@ type TemplateRenderer interface {
 	GetInterfaceConfig(iface *domain.Interface, peers []domain.Peer) (io.Reader, error)
 	// GetPeerConfig returns the configuration file for the given peer.
 	GetPeerConfig(peer *domain.Peer, style string) (io.Reader, error)
+	// GetPeerConfigFiles returns all configuration files for the given peer.
+	GetPeerConfigFiles(peer *domain.Peer, style string) ([]domain.ConfigFile, error)
+	// GetPeerMobileConfig returns the unsigned Apple configuration profile for the given peer.
+	GetPeerMobileConfig(
+		peer *domain.Peer,
+		onDemand *domain.OnDemandPolicy,
+		platform domain.MobileConfigPlatform,
+	) (io.Reader, error)
 }
 
 type EventBus interface {
@@ -65,6 +75,8 @@ type Manager struct {
 	fsRepo     FileSystemRepo
 	users      UserDatabaseRepo
 	wg         WireguardDatabaseRepo
+
+	profileSigner *profileSigner // nil if Apple profiles should not be signed
 }
 
 // NewConfigFileManager creates a new Manager instance.
@@ -90,6 +102,14 @@ func NewConfigFileManager(
 		wg:     wg,
 	}
 
+	if cfg.Advanced.MobileConfigSigningCert != "" {
+		m.profileSigner, err = newProfileSigner(cfg.Advanced.MobileConfigSigningCert,
+			cfg.Advanced.MobileConfigSigningKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load mobileconfig signing certificate: %w", err)
+		}
+	}
+
 	if m.cfg.Advanced.ConfigStoragePath != "" {
 		if err := m.createStorageDirectory(); err != nil {
 			return nil, err
@@ -185,7 +205,7 @@ func (m Manager) GetInterfaceConfig(ctx context.Context, id domain.InterfaceIden
 }
 
 // GetPeerConfig returns the configuration file for the given peer.
-// The file is structured in wg-quick format.
+// The file is structured according to the given style. Styles that consist of multiple files are concatenated.
 func (m Manager) GetPeerConfig(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error) {
 	peer, err := m.wg.GetPeer(ctx, id)
 	if err != nil {
@@ -199,8 +219,30 @@ func (m Manager) GetPeerConfig(ctx context.Context, id domain.PeerIdentifier, st
 	return m.tplHandler.GetPeerConfig(peer, style)
 }
 
+// GetPeerConfigFiles returns all configuration files for the given peer in the given style.
+func (m Manager) GetPeerConfigFiles(ctx context.Context, id domain.PeerIdentifier, style string) (
+	[]domain.ConfigFile,
+	error,
+) {
+	peer, err := m.wg.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch peer %s: %w", id, err)
+	}
+
+	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+		return nil, err
+	}
+
+	return m.tplHandler.GetPeerConfigFiles(peer, style)
+}
+
 // GetPeerConfigQrCode returns a QR code image containing the configuration for the given peer.
+// QR codes are scanned by the WireGuard apps, so styles of other network managers fall back to wg-quick.
 func (m Manager) GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error) {
+	if !domain.IsWireGuardConfigStyle(style) {
+		style = domain.ConfigStyleWgQuick
+	}
+
 	peer, err := m.wg.GetPeer(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch peer %s: %w", id, err)
@@ -210,9 +252,14 @@ func (m Manager) GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifi
 		return nil, err
 	}
 
+	return m.renderQrCode(peer, style)
+}
+
+// renderQrCode renders the WireGuard configuration of the given peer as PNG image.
+func (m Manager) renderQrCode(peer *domain.Peer, style string) (io.Reader, error) {
 	cfgData, err := m.tplHandler.GetPeerConfig(peer, style)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get peer config for %s: %w", id, err)
+		return nil, fmt.Errorf("failed to get peer config for %s: %w", peer.Identifier, err)
 	}
 
 	// remove comments from qr-code config as it is not needed
@@ -226,13 +273,13 @@ func (m Manager) GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifi
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read peer config for %s: %w", id, err)
+		return nil, fmt.Errorf("failed to read peer config for %s: %w", peer.Identifier, err)
 	}
 
 	code, err := qrcode.NewWith(sb.String(),
 		qrcode.WithErrorCorrectionLevel(qrcode.ErrorCorrectionLow), qrcode.WithEncodingMode(qrcode.EncModeByte))
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize qr code for %s: %w", id, err)
+		return nil, fmt.Errorf("failed to initialize qr code for %s: %w", peer.Identifier, err)
 	}
 
 	buf := bytes.NewBuffer(nil)
@@ -244,12 +291,56 @@ func (m Manager) GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifi
 	qrWriter := compressed.NewWithWriter(wr, &option)
 	err = code.Save(qrWriter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to write code for %s: %w", id, err)
+		return nil, fmt.Errorf("failed to write code for %s: %w", peer.Identifier, err)
 	}
 
 	return buf, nil
 }
 
+// GetPeerMobileConfig returns an Apple configuration profile (.mobileconfig) for the given peer.
+// The on-demand rules are taken from the interface of the peer. If a signing certificate is configured,
+// the profile is returned as signed PKCS#7 structure.
+func (m Manager) GetPeerMobileConfig(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	platform domain.MobileConfigPlatform,
+) (io.Reader, error) {
+	peer, err := m.wg.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch peer %s: %w", id, err)
+	}
+
+	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+		return nil, err
+	}
+
+	iface, err := m.wg.GetInterface(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+
+	profile, err := m.tplHandler.GetPeerMobileConfig(peer, iface.OnDemandPolicy, platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mobileconfig for %s: %w", id, err)
+	}
+
+	if m.profileSigner == nil {
+		return profile, nil
+	}
+
+	profileData, err := io.ReadAll(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mobileconfig for %s: %w", id, err)
+	}
+
+	signedProfile, err := m.profileSigner.Sign(profileData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign mobileconfig for %s: %w", id, err)
+	}
+
+	return bytes.NewReader(signedProfile), nil
+}
+
 // PersistInterfaceConfig writes the configuration file for the given interface to the file system.
 func (m Manager) PersistInterfaceConfig(ctx context.Context, id domain.InterfaceIdentifier) error {
 	iface, peers, err := m.wg.GetInterfaceAndPeers(ctx, id)
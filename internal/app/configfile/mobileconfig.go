@@ -0,0 +1,96 @@
+package configfile
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// profileSigner signs Apple configuration profiles, so that devices show them as verified.
+type profileSigner struct {
+	cert  *x509.Certificate
+	chain []*x509.Certificate
+	key   crypto.PrivateKey
+}
+
+// newProfileSigner loads the PEM encoded signing certificate and private key. The first certificate in the
+// certificate file is used for signing, all further certificates are embedded as intermediate chain.
+func newProfileSigner(certFile, keyFile string) (*profileSigner, error) {
+	certData, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing certificate: %w", err)
+	}
+	keyData, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(certData); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found in signing certificate file")
+	}
+
+	key, err := parsePrivateKey(keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &profileSigner{
+		cert:  certs[0],
+		chain: certs[1:],
+		key:   key,
+	}, nil
+}
+
+func parsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found in signing key file")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unsupported signing key format, expected PKCS#1, PKCS#8 or EC private key")
+}
+
+// Sign wraps the profile in a DER encoded PKCS#7 signed data structure.
+func (s *profileSigner) Sign(profile []byte) ([]byte, error) {
+	signedData, err := pkcs7.NewSignedData(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize signed data: %w", err)
+	}
+
+	if err := signedData.AddSignerChain(s.cert, s.key, s.chain, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("failed to add signer: %w", err)
+	}
+
+	signed, err := signedData.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign profile: %w", err)
+	}
+
+	return signed, nil
+}
@@ -0,0 +1,96 @@
+package configfile
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestTemplateHandler_GetPeerMobileConfig(t *testing.T) {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	peer := newTestPeer(t)
+	peer.DisplayName = "Alice & Bob"
+	policy := &domain.OnDemandPolicy{TrustedSsids: []string{"Office <5G>"}, Wifi: true, Cellular: true}
+
+	reader, err := handler.GetPeerMobileConfig(peer, policy, domain.MobileConfigPlatformIos)
+	require.NoError(t, err)
+	profile, _ := io.ReadAll(reader)
+
+	assert.Contains(t, string(profile), "<string>com.wireguard.ios</string>")
+	assert.Contains(t, string(profile), "<string>Alice &amp; Bob</string>")
+	assert.Contains(t, string(profile), "<key>WgQuickConfig</key>")
+	assert.Contains(t, string(profile), "PrivateKey = peer-priv-key")
+	assert.Contains(t, string(profile), "<string>vpn.example.com</string>")
+	assert.Contains(t, string(profile), "<key>OnDemandEnabled</key>")
+	assert.Contains(t, string(profile), "<string>Office &lt;5G&gt;</string>")
+	assert.Contains(t, string(profile), "<string>Cellular</string>")
+
+	// the profile identifiers are stable per peer and platform
+	again, err := handler.GetPeerMobileConfig(peer, policy, domain.MobileConfigPlatformIos)
+	require.NoError(t, err)
+	againData, _ := io.ReadAll(again)
+	assert.Equal(t, profile, againData)
+
+	reader, err = handler.GetPeerMobileConfig(peer, nil, domain.MobileConfigPlatformMacOs)
+	require.NoError(t, err)
+	profile, _ = io.ReadAll(reader)
+	assert.Contains(t, string(profile), "<string>com.wireguard.macos</string>")
+	assert.NotContains(t, string(profile), "OnDemandEnabled")
+	assert.NotEqual(t, againData, profile)
+}
+
+func TestProfileSigner_Sign(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "wg-portal profile signing"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer}),
+		0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}),
+		0600))
+
+	signer, err := newProfileSigner(certFile, keyFile)
+	require.NoError(t, err)
+
+	signed, err := signer.Sign([]byte("<plist/>"))
+	require.NoError(t, err)
+
+	p7, err := pkcs7.Parse(signed)
+	require.NoError(t, err)
+	require.NoError(t, p7.Verify())
+	assert.Equal(t, []byte("<plist/>"), p7.Content)
+
+	_, err = newProfileSigner(keyFile, keyFile)
+	assert.Error(t, err)
+}
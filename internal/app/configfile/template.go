@@ -3,16 +3,46 @@ package configfile
 import (
 	"bytes"
 	"embed"
+	"encoding/xml"
 	"fmt"
 	"io"
+	"net"
+	"net/netip"
+	"regexp"
+	"strings"
 	"text/template"
+	"unicode"
 
+	"github.com/google/uuid"
+
+	"github.com/h44z/wg-portal/internal"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
 //go:embed tpl_files/*
 var TemplateFiles embed.FS
 
+type peerConfigTemplate struct {
+	name      string // the template file
+	extension string // the file extension of the rendered file
+}
+
+// peerConfigTemplates contains the templates that are rendered for each configuration style.
+// Styles like systemd-networkd need more than one file to set up a WireGuard device.
+var peerConfigTemplates = map[string][]peerConfigTemplate{
+	domain.ConfigStyleWgQuick: {{"wg_peer.tpl", ".conf"}},
+	domain.ConfigStyleRaw:     {{"wg_peer.tpl", ".conf"}},
+	domain.ConfigStyleNetworkd: {
+		{"wg_peer_networkd_netdev.tpl", ".netdev"},
+		{"wg_peer_networkd_network.tpl", ".network"},
+	},
+	domain.ConfigStyleNetworkManager: {{"wg_peer_networkmanager.tpl", ".nmconnection"}},
+	domain.ConfigStyleOpenWrt:        {{"wg_peer_openwrt.tpl", ".uci"}},
+	domain.ConfigStyleRouterOs:       {{"wg_peer_routeros.tpl", ".rsc"}},
+}
+
+var invalidDeviceNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
 // TemplateHandler is responsible for rendering the WireGuard configuration files
 // based on the provided templates.
 type TemplateHandler struct {
@@ -21,7 +51,21 @@ type TemplateHandler struct {
 
 func newTemplateHandler() (*TemplateHandler, error) {
 	tplFuncs := template.FuncMap{
-		"CidrsToString": domain.CidrsToString,
+		"CidrsToString":      domain.CidrsToString,
+		"CidrsToStringSlice": domain.CidrsToStringSlice,
+		"ListItems":          listItems,
+		"IPv4Only":           filterFamily(true),
+		"IPv6Only":           filterFamily(false),
+		"IsDefaultRoute":     isDefaultRoute,
+		"EndpointHost":       endpointHost,
+		"EndpointPort":       endpointPort,
+		"Join":               strings.Join,
+		"Quote":              quote,
+		"RosQuote":           routerOsQuote,
+		"SingleLine":         singleLine,
+		"NetworkdRouteTable": networkdRouteTable,
+		"Inc":                func(i int) int { return i + 1 },
+		"Xml":                xmlEscape,
 	}
 
 	templateCache, err := template.New("WireGuard").Funcs(tplFuncs).ParseFS(TemplateFiles, "tpl_files/*.tpl")
@@ -54,20 +98,229 @@ func (c TemplateHandler) GetInterfaceConfig(cfg *domain.Interface, peers []domai
 	return &tplBuff, nil
 }
 
-// GetPeerConfig returns the rendered configuration file for a WireGuard peer.
+// GetPeerConfig returns the rendered configuration for a WireGuard peer.
+// If the style consists of multiple files, the files are concatenated.
 func (c TemplateHandler) GetPeerConfig(peer *domain.Peer, style string) (io.Reader, error) {
-	var tplBuff bytes.Buffer
+	files, err := c.GetPeerConfigFiles(peer, style)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for i, file := range files {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.Write(file.Data)
+	}
+
+	return &buf, nil
+}
+
+// GetPeerConfigFiles returns all rendered configuration files for a WireGuard peer in the given style.
+func (c TemplateHandler) GetPeerConfigFiles(peer *domain.Peer, style string) ([]domain.ConfigFile, error) {
+	templates, ok := peerConfigTemplates[style]
+	if !ok {
+		return nil, fmt.Errorf("unsupported configuration style %s: %w", style, domain.ErrInvalidData)
+	}
 
-	err := c.templates.ExecuteTemplate(&tplBuff, "wg_peer.tpl", map[string]any{
-		"Style": style,
-		"Peer":  peer,
+	baseName := strings.TrimSuffix(peer.GetConfigFileName(), ".conf")
+	files := make([]domain.ConfigFile, 0, len(templates))
+	for _, tpl := range templates {
+		var tplBuff bytes.Buffer
+
+		fileName := baseName + tpl.extension
+		err := c.templates.ExecuteTemplate(&tplBuff, tpl.name, map[string]any{
+			"Style":    style,
+			"Peer":     peer,
+			"Device":   peerDeviceName(peer),
+			"FileName": fileName,
+			"Portal": map[string]any{
+				"Version": "unknown",
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute peer template %s for %s: %w", tpl.name, peer.Identifier, err)
+		}
+
+		files = append(files, domain.ConfigFile{Name: fileName, Data: tplBuff.Bytes()})
+	}
+
+	return files, nil
+}
+
+// GetPeerMobileConfig returns an unsigned Apple configuration profile for a WireGuard peer. The profile embeds
+// the wg-quick configuration in a VPN payload for the WireGuard app of the given platform.
+func (c TemplateHandler) GetPeerMobileConfig(
+	peer *domain.Peer,
+	onDemand *domain.OnDemandPolicy,
+	platform domain.MobileConfigPlatform,
+) (io.Reader, error) {
+	wgQuickConfig, err := c.GetPeerConfig(peer, domain.ConfigStyleWgQuick)
+	if err != nil {
+		return nil, err
+	}
+	wgQuickData, err := io.ReadAll(wgQuickConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read peer config for %s: %w", peer.Identifier, err)
+	}
+
+	name := peer.DisplayName
+	if name == "" {
+		name = string(peer.InterfaceIdentifier)
+	}
+
+	// the identifiers are stable, so that installing an updated profile replaces the previous one
+	profileUuid := uuid.NewSHA1(uuid.NameSpaceOID, []byte("wg-portal/peer/"+string(peer.Identifier)+"/"+string(platform)))
+	payloadUuid := uuid.NewSHA1(profileUuid, []byte("vpn"))
+
+	var tplBuff bytes.Buffer
+	err = c.templates.ExecuteTemplate(&tplBuff, "wg_peer_mobileconfig.tpl", map[string]any{
+		"Peer":        peer,
+		"Name":        name,
+		"Platform":    platform,
+		"Identifier":  "com.wireguard-portal." + profileUuid.String(),
+		"ProfileUuid": strings.ToUpper(profileUuid.String()),
+		"PayloadUuid": strings.ToUpper(payloadUuid.String()),
+		"Config":      string(wgQuickData),
+		"Rules":       onDemand.Rules(platform),
 		"Portal": map[string]any{
 			"Version": "unknown",
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute peer template for %s: %w", peer.Identifier, err)
+		return nil, fmt.Errorf("failed to execute mobileconfig template for %s: %w", peer.Identifier, err)
 	}
 
 	return &tplBuff, nil
 }
+
+// peerDeviceName returns the name of the WireGuard device on the peer side. The name is derived from the
+// interface identifier and restricted to characters that are valid for Linux devices and OpenWrt sections.
+func peerDeviceName(peer *domain.Peer) string {
+	name := invalidDeviceNameChars.ReplaceAllString(string(peer.InterfaceIdentifier), "_")
+	name = internal.TruncateString(name, 15)
+	if name == "" {
+		return "wg0"
+	}
+	return name
+}
+
+// listItems splits a comma separated list and removes empty entries.
+func listItems(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// filterFamily returns a template function that only keeps IP addresses or prefixes of the given family.
+func filterFamily(ipv4 bool) func(items []string) []string {
+	return func(items []string) []string {
+		filtered := make([]string, 0, len(items))
+		for _, item := range items {
+			var addr netip.Addr
+			if prefix, err := netip.ParsePrefix(item); err == nil {
+				addr = prefix.Addr()
+			} else if addr, err = netip.ParseAddr(item); err != nil {
+				continue
+			}
+			if addr.Is4() == ipv4 {
+				filtered = append(filtered, item)
+			}
+		}
+		return filtered
+	}
+}
+
+func isDefaultRoute(prefix string) bool {
+	p, err := netip.ParsePrefix(prefix)
+	return err == nil && p.Bits() == 0
+}
+
+// endpointHost returns the host part of an endpoint in host:port notation.
+func endpointHost(endpoint string) string {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	return host
+}
+
+// endpointPort returns the port part of an endpoint in host:port notation.
+func endpointPort(endpoint string) string {
+	_, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+// quote returns the value as double-quoted string, quotes and backslashes are escaped.
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// routerOsQuote returns the value as double-quoted RouterOS string. Besides quotes and backslashes, RouterOS
+// expands variables ($) and interprets some characters (?) within strings, and a line break ends the command.
+// All of them are escaped, so that the value cannot inject commands into the script.
+func routerOsQuote(value string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\', '"', '$', '?':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if unicode.IsControl(r) {
+				if r < 0x100 {
+					_, _ = fmt.Fprintf(&sb, `\%02X`, r)
+				} // other control characters cannot be represented and are dropped
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// singleLine replaces line breaks and other control characters with spaces, so that the value cannot add
+// lines to line based configuration formats.
+func singleLine(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, value)
+}
+
+// xmlEscape escapes the value for the use in XML character data.
+func xmlEscape(value string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(value))
+	return sb.String()
+}
+
+// networkdRouteTable converts the wg-quick routing table setting to the RouteTable value of systemd-networkd.
+// In contrast to wg-quick, systemd-networkd does not add routes for the allowed IPs by default.
+func networkdRouteTable(table string) string {
+	switch strings.ToLower(strings.TrimSpace(table)) {
+	case "", "auto":
+		return "main"
+	default:
+		return table
+	}
+}
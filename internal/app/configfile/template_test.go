@@ -0,0 +1,216 @@
+package configfile
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func newTestPeer(t *testing.T) *domain.Peer {
+	addrV4, err := domain.CidrFromString("10.11.12.2/32")
+	require.NoError(t, err)
+	addrV6, err := domain.CidrFromString("fd00::2/128")
+	require.NoError(t, err)
+
+	return &domain.Peer{
+		Identifier:          "peer-pub-key",
+		DisplayName:         "Alice Laptop",
+		InterfaceIdentifier: "wg-office",
+		Endpoint:            domain.NewConfigOption("vpn.example.com:51820", true),
+		EndpointPublicKey:   domain.NewConfigOption("server-pub-key", true),
+		AllowedIPsStr:       domain.NewConfigOption("10.11.12.0/24, 0.0.0.0/0, fd00::/64", true),
+		PresharedKey:        "psk",
+		PersistentKeepalive: domain.NewConfigOption(25, true),
+		Interface: domain.PeerInterfaceConfig{
+			KeyPair:      domain.KeyPair{PrivateKey: "peer-priv-key", PublicKey: "peer-pub-key"},
+			Type:         domain.InterfaceTypeClient,
+			Addresses:    []domain.Cidr{addrV4, addrV6},
+			DnsStr:       domain.NewConfigOption("10.11.12.1,fd00::1", true),
+			DnsSearchStr: domain.NewConfigOption("example.com", true),
+			Mtu:          domain.NewConfigOption(1420, true),
+		},
+	}
+}
+
+func renderPeerFiles(t *testing.T, style string) []domain.ConfigFile {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	files, err := handler.GetPeerConfigFiles(newTestPeer(t), style)
+	require.NoError(t, err)
+
+	return files
+}
+
+func TestTemplateHandler_GetPeerConfigFiles_Networkd(t *testing.T) {
+	files := renderPeerFiles(t, domain.ConfigStyleNetworkd)
+	require.Len(t, files, 2)
+
+	assert.Equal(t, "Alice_Laptop.netdev", files[0].Name)
+	netdev := string(files[0].Data)
+	assert.Contains(t, netdev, "Name=wg_office\nKind=wireguard")
+	assert.Contains(t, netdev, "RouteTable=main")
+	assert.Contains(t, netdev, "AllowedIPs=10.11.12.0/24\nAllowedIPs=0.0.0.0/0\nAllowedIPs=fd00::/64")
+	assert.Contains(t, netdev, "PersistentKeepalive=25")
+
+	assert.Equal(t, "Alice_Laptop.network", files[1].Name)
+	network := string(files[1].Data)
+	assert.Contains(t, network, "Address=10.11.12.2/32\nAddress=fd00::2/128")
+	assert.Contains(t, network, "DNS=10.11.12.1\nDNS=fd00::1")
+	assert.Contains(t, network, "Domains=example.com")
+}
+
+func TestTemplateHandler_GetPeerConfigFiles_NetworkManager(t *testing.T) {
+	files := renderPeerFiles(t, domain.ConfigStyleNetworkManager)
+	require.Len(t, files, 1)
+
+	assert.Equal(t, "Alice_Laptop.nmconnection", files[0].Name)
+	cfg := string(files[0].Data)
+	assert.Contains(t, cfg, "id=Alice Laptop\ntype=wireguard\ninterface-name=wg_office")
+	assert.Contains(t, cfg, "[wireguard-peer.server-pub-key]")
+	assert.Contains(t, cfg, "allowed-ips=10.11.12.0/24;0.0.0.0/0;fd00::/64;")
+	assert.Contains(t, cfg, "[ipv4]\nmethod=manual\naddress1=10.11.12.2/32\ndns=10.11.12.1;\ndns-search=example.com;")
+	assert.Contains(t, cfg, "method=manual\naddress1=fd00::2/128\ndns=fd00::1;")
+}
+
+func TestTemplateHandler_GetPeerConfigFiles_OpenWrt(t *testing.T) {
+	files := renderPeerFiles(t, domain.ConfigStyleOpenWrt)
+	require.Len(t, files, 1)
+
+	assert.Equal(t, "Alice_Laptop.uci", files[0].Name)
+	cfg := string(files[0].Data)
+	assert.Contains(t, cfg, "config interface 'wg_office'")
+	assert.Contains(t, cfg, "config wireguard_wg_office")
+	assert.Contains(t, cfg, "option description \"Alice Laptop\"")
+	assert.Contains(t, cfg, "option endpoint_host 'vpn.example.com'\n\toption endpoint_port '51820'")
+	assert.Contains(t, cfg, "list allowed_ips '0.0.0.0/0'")
+}
+
+func TestTemplateHandler_GetPeerConfigFiles_RouterOs(t *testing.T) {
+	files := renderPeerFiles(t, domain.ConfigStyleRouterOs)
+	require.Len(t, files, 1)
+
+	assert.Equal(t, "Alice_Laptop.rsc", files[0].Name)
+	cfg := string(files[0].Data)
+	assert.Contains(t, cfg, "add name=wg_office private-key=\"peer-priv-key\" mtu=1420 comment=\"Alice Laptop\"")
+	assert.Contains(t, cfg,
+		"endpoint-address=\"vpn.example.com\" endpoint-port=51820 allowed-address=10.11.12.0/24,0.0.0.0/0,fd00::/64")
+	assert.Contains(t, cfg, "add address=10.11.12.2/32 interface=wg_office")
+	assert.Contains(t, cfg, "add dst-address=10.11.12.0/24 gateway=wg_office")
+	assert.Contains(t, cfg, "# add dst-address=0.0.0.0/0 gateway=wg_office")
+	assert.Contains(t, cfg, "add dst-address=fd00::/64 gateway=wg_office")
+}
+
+func TestTemplateHandler_GetPeerConfig(t *testing.T) {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	_, err = handler.GetPeerConfig(newTestPeer(t), "unknown")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	reader, err := handler.GetPeerConfig(newTestPeer(t), domain.ConfigStyleNetworkd)
+	require.NoError(t, err)
+	cfg, _ := io.ReadAll(reader)
+	assert.Equal(t, 1, strings.Count(string(cfg), "[NetDev]"))
+	assert.Equal(t, 1, strings.Count(string(cfg), "[Match]"))
+
+	reader, err = handler.GetPeerConfig(newTestPeer(t), domain.ConfigStyleWgQuick)
+	require.NoError(t, err)
+	cfg, _ = io.ReadAll(reader)
+	assert.Contains(t, string(cfg), "[Interface]")
+}
+
+func TestTemplateHandler_DelegatedPrefix(t *testing.T) {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	peer := newTestPeer(t)
+	peer.DelegatedPrefix = "2001:db8:100:100::/56"
+
+	reader, err := handler.GetPeerConfig(peer, domain.ConfigStyleWgQuick)
+	require.NoError(t, err)
+	cfg, _ := io.ReadAll(reader)
+	assert.Contains(t, string(cfg), "# -WGP- Delegated prefix: 2001:db8:100:100::/56")
+
+	iface := &domain.Interface{Identifier: "wg-office", Type: domain.InterfaceTypeServer}
+	reader, err = handler.GetInterfaceConfig(iface, []domain.Peer{*peer})
+	require.NoError(t, err)
+	cfg, _ = io.ReadAll(reader)
+	assert.Contains(t, string(cfg), "AllowedIPs = 10.11.12.2/32,fd00::2/128, 2001:db8:100:100::/56")
+}
+
+func TestTemplateHandler_UntrustedDisplayName(t *testing.T) {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	peer := newTestPeer(t)
+	peer.DisplayName = "Alice\n[Peer]\r\nPublicKey=evil $[/system reboot]"
+
+	files, err := handler.GetPeerConfigFiles(peer, domain.ConfigStyleRouterOs)
+	require.NoError(t, err)
+	cfg := string(files[0].Data)
+	assert.Contains(t, cfg, `comment="Alice\n[Peer]\r\nPublicKey=evil \$[/system reboot]"`)
+	assert.NotContains(t, cfg, "\n[Peer]")
+
+	files, err = handler.GetPeerConfigFiles(peer, domain.ConfigStyleNetworkManager)
+	require.NoError(t, err)
+	cfg = string(files[0].Data)
+	assert.Contains(t, cfg, "id=Alice [Peer]  PublicKey=evil $[/system reboot]\n")
+	assert.NotContains(t, cfg, "\n[Peer]")
+
+	files, err = handler.GetPeerConfigFiles(peer, domain.ConfigStyleNetworkd)
+	require.NoError(t, err)
+	cfg = string(files[0].Data)
+	assert.Contains(t, cfg, "Description=Alice [Peer]  PublicKey=evil $[/system reboot]\n")
+	assert.Contains(t, cfg, "# -WGP- Display name: Alice [Peer]  PublicKey=evil $[/system reboot]\n")
+	assert.NotContains(t, cfg, "\n[Peer]")
+}
+
+func TestTemplateHelpers(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, listItems(" a, ,b,"))
+	assert.Equal(t, []string{"10.0.0.0/8", "1.1.1.1"}, filterFamily(true)([]string{"10.0.0.0/8", "fd00::/8", "1.1.1.1", "x"}))
+	assert.Equal(t, []string{"fd00::/8"}, filterFamily(false)([]string{"10.0.0.0/8", "fd00::/8", "x"}))
+	assert.True(t, isDefaultRoute("::/0"))
+	assert.False(t, isDefaultRoute("10.0.0.0/8"))
+	assert.Equal(t, "fd00::1", endpointHost("[fd00::1]:51820"))
+	assert.Equal(t, "example.com", endpointHost("example.com"))
+	assert.Equal(t, "51820", endpointPort("example.com:51820"))
+	assert.Equal(t, "", endpointPort("example.com"))
+	assert.Equal(t, `"a \"b\" \\c"`, quote(`a "b" \c`))
+	assert.Equal(t, `"a \"b\" \\c \$x \$[:put 1] \?"`, routerOsQuote(`a "b" \c $x $[:put 1] ?`))
+	assert.Equal(t, `"a\nb\r\tc\00\1B"`, routerOsQuote("a\nb\r\tc\x00\x1b"))
+	assert.Equal(t, "a b  c d", singleLine("a\nb\r\nc\x00d"))
+	assert.Equal(t, "main", networkdRouteTable("auto"))
+	assert.Equal(t, "1234", networkdRouteTable("1234"))
+	assert.Equal(t, "wg0", peerDeviceName(&domain.Peer{}))
+	assert.Equal(t, "wg_very_long_na", peerDeviceName(&domain.Peer{InterfaceIdentifier: "wg-very-long-name"}))
+}
+
+func TestTemplateHandler_AmneziaParams(t *testing.T) {
+	handler, err := newTemplateHandler()
+	require.NoError(t, err)
+
+	peer := newTestPeer(t)
+	reader, err := handler.GetPeerConfig(peer, domain.ConfigStyleWgQuick)
+	require.NoError(t, err)
+	cfg, _ := io.ReadAll(reader)
+	assert.NotContains(t, string(cfg), "Jc =")
+
+	peer.Interface.AmneziaParams = &domain.AmneziaParams{Jc: 4, Jmin: 8, Jmax: 80, S1: 15, S2: 20, H1: 5}
+	reader, err = handler.GetPeerConfig(peer, domain.ConfigStyleWgQuick)
+	require.NoError(t, err)
+	cfg, _ = io.ReadAll(reader)
+	assert.Contains(t, string(cfg), "Jc = 4\nJmin = 8\nJmax = 80\nS1 = 15\nS2 = 20\nH1 = 5\nH2 = 2\nH3 = 3\nH4 = 4\n")
+
+	iface := &domain.Interface{Identifier: "wg-office", Type: domain.InterfaceTypeServer,
+		AmneziaParams: peer.Interface.AmneziaParams}
+	reader, err = handler.GetInterfaceConfig(iface, []domain.Peer{*peer})
+	require.NoError(t, err)
+	cfg, _ = io.ReadAll(reader)
+	assert.Contains(t, string(cfg), "# AmneziaWG obfuscation (optional)\nJc = 4\n")
+}
@@ -38,6 +38,20 @@ Table = {{.Interface.RoutingTable}}
 {{- if .Interface.SaveConfig}}
 SaveConfig = true
 {{- end}}
+{{- if not .Interface.AmneziaParams.IsEmpty}}
+
+# AmneziaWG obfuscation (optional)
+{{- with .Interface.AmneziaParams}}
+Jc = {{ .Jc }}
+Jmin = {{ .Jmin }}
+Jmax = {{ .Jmax }}
+S1 = {{ .S1 }}
+S2 = {{ .S2 }}
+{{- range $i, $h := .Headers}}
+H{{ Inc $i }} = {{ $h }}
+{{- end}}
+{{- end}}
+{{- end}}
 
 # Interface hooks (optional)
 {{- if .Interface.PreUp}}
@@ -74,7 +88,7 @@ PublicKey = {{ .Interface.KeyPair.PublicKey }}
 PresharedKey = {{ .PresharedKey }}
 {{- end}}
 {{- if eq $.Interface.Type "server"}}
-AllowedIPs = {{ CidrsToString .Interface.Addresses }}{{if ne .ExtraAllowedIPsStr ""}}, {{ .ExtraAllowedIPsStr }}{{end}}
+AllowedIPs = {{ CidrsToString .Interface.Addresses }}{{if ne .ExtraAllowedIPsStr ""}}, {{ .ExtraAllowedIPsStr }}{{end}}{{if ne .DelegatedPrefix ""}}, {{ .DelegatedPrefix }}{{end}}
 {{- end}}
 {{- if eq $.Interface.Type "client"}}
 {{- if .AllowedIPsStr.GetValue}}
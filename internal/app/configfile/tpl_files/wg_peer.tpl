@@ -26,6 +26,10 @@ PrivateKey = {{ .Peer.Interface.KeyPair.PrivateKey }}
 {{- if eq .Style "wgquick"}}
 Address = {{ CidrsToString .Peer.Interface.Addresses }}
 {{- end}}
+{{- if .Peer.DelegatedPrefix}}
+# -WGP- Delegated prefix: {{ .Peer.DelegatedPrefix }}
+# The delegated prefix is routed to this peer, assign it to the network behind the peer.
+{{- end}}
 
 # Misc. settings (optional)
 {{- if eq .Style "wgquick"}}
@@ -42,6 +46,20 @@ Table = {{ .Peer.Interface.RoutingTable.GetValue }}
 {{- if ne .Peer.Interface.FirewallMark.GetValue 0}}
 FwMark = {{ .Peer.Interface.FirewallMark.GetValue }}
 {{- end}}
+{{- if not .Peer.Interface.AmneziaParams.IsEmpty}}
+
+# AmneziaWG obfuscation (optional)
+{{- with .Peer.Interface.AmneziaParams}}
+Jc = {{ .Jc }}
+Jmin = {{ .Jmin }}
+Jmax = {{ .Jmax }}
+S1 = {{ .S1 }}
+S2 = {{ .S2 }}
+{{- range $i, $h := .Headers}}
+H{{ Inc $i }} = {{ $h }}
+{{- end}}
+{{- end}}
+{{- end}}
 
 {{- if eq .Style "wgquick"}}
 # Interface hooks (optional)
@@ -0,0 +1,76 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>PayloadDisplayName</key>
+	<string>{{ Xml .Name }}</string>
+	<key>PayloadDescription</key>
+	<string>WireGuard VPN configuration</string>
+	<key>PayloadType</key>
+	<string>Configuration</string>
+	<key>PayloadVersion</key>
+	<integer>1</integer>
+	<key>PayloadIdentifier</key>
+	<string>{{ .Identifier }}</string>
+	<key>PayloadUUID</key>
+	<string>{{ .ProfileUuid }}</string>
+	<key>PayloadRemovalDisallowed</key>
+	<false/>
+	<key>PayloadContent</key>
+	<array>
+		<dict>
+			<key>PayloadDisplayName</key>
+			<string>VPN</string>
+			<key>PayloadType</key>
+			<string>com.apple.vpn.managed</string>
+			<key>PayloadVersion</key>
+			<integer>1</integer>
+			<key>PayloadIdentifier</key>
+			<string>{{ .Identifier }}.vpn</string>
+			<key>PayloadUUID</key>
+			<string>{{ .PayloadUuid }}</string>
+			<key>UserDefinedName</key>
+			<string>{{ Xml .Name }}</string>
+			<key>VPNType</key>
+			<string>VPN</string>
+			<key>VPNSubType</key>
+			<string>{{ .Platform.VpnSubType }}</string>
+			<key>VendorConfig</key>
+			<dict>
+				<key>WgQuickConfig</key>
+				<string>{{ Xml .Config }}</string>
+			</dict>
+			<key>VPN</key>
+			<dict>
+				<key>RemoteAddress</key>
+				<string>{{ Xml (EndpointHost .Peer.Endpoint.GetValue) }}</string>
+				<key>AuthenticationMethod</key>
+				<string>Password</string>
+{{- with .Rules}}
+				<key>OnDemandEnabled</key>
+				<integer>1</integer>
+				<key>OnDemandRules</key>
+				<array>
+{{- range .}}
+					<dict>
+						<key>Action</key>
+						<string>{{ .Action }}</string>
+						<key>InterfaceTypeMatch</key>
+						<string>{{ .InterfaceType }}</string>
+{{- with .Ssids}}
+						<key>SSIDMatch</key>
+						<array>
+{{- range .}}
+							<string>{{ Xml . }}</string>
+{{- end}}
+						</array>
+{{- end}}
+					</dict>
+{{- end}}
+				</array>
+{{- end}}
+			</dict>
+		</dict>
+	</array>
+</dict>
+</plist>
@@ -0,0 +1,525 @@
+package declarative
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/a8m/envsubst"
+	"gopkg.in/yaml.v3"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type UserManager interface {
+	// GetAllUsers returns all users.
+	GetAllUsers(ctx context.Context) ([]domain.User, error)
+	// CreateUser creates a new user.
+	CreateUser(ctx context.Context, user *domain.User) (*domain.User, error)
+	// UpdateUser updates the user with the given identifier.
+	UpdateUser(ctx context.Context, user *domain.User) (*domain.User, error)
+	// DeleteUser deletes the user with the given identifier.
+	DeleteUser(ctx context.Context, id domain.UserIdentifier) error
+}
+
+type WireGuardManager interface {
+	// GetAllInterfacesAndPeers returns all interfaces and their peers.
+	GetAllInterfacesAndPeers(ctx context.Context) ([]domain.Interface, [][]domain.Peer, error)
+	// PrepareInterface prepares a new interface with fresh keys, addresses and listen port.
+	PrepareInterface(ctx context.Context) (*domain.Interface, error)
+	// CreateInterface creates a new interface.
+	CreateInterface(ctx context.Context, in *domain.Interface) (*domain.Interface, error)
+	// UpdateInterface updates the given interface.
+	UpdateInterface(ctx context.Context, in *domain.Interface) (*domain.Interface, []domain.Peer, error)
+	// DeleteInterface deletes the interface with the given identifier.
+	DeleteInterface(ctx context.Context, id domain.InterfaceIdentifier) error
+	// PreparePeer prepares a new peer for the given interface with fresh keys and ip addresses.
+	PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
+	// CreatePeer creates a new peer.
+	CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+	// DeletePeer deletes the peer with the given identifier.
+	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
+}
+
+// endregion dependencies
+
+// Manager applies the declarative state file. The required changes are computed against the database and applied
+// through the user and WireGuard managers, so validation, events, audit logging and the backends behave exactly
+// as for changes in the UI.
+type Manager struct {
+	cfg   *config.Config
+	users UserManager
+	wg    WireGuardManager
+}
+
+// plannedChange is a change of the plan together with the function that applies it.
+type plannedChange struct {
+	domain.DeclarativeChange
+	apply func(ctx context.Context) error
+}
+
+// NewDeclarativeManager creates a new declarative state manager.
+func NewDeclarativeManager(cfg *config.Config, users UserManager, wg WireGuardManager) (*Manager, error) {
+	return &Manager{
+		cfg:   cfg,
+		users: users,
+		wg:    wg,
+	}, nil
+}
+
+// StartBackgroundJobs applies the configured state file once, if apply_on_startup is enabled.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if !m.cfg.Declarative.ApplyOnStartup || m.cfg.Declarative.StateFile == "" {
+		return
+	}
+
+	go func() {
+		ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+		if _, err := m.Apply(ctx, nil, false); err != nil {
+			slog.Error("failed to apply declarative state", "file", m.cfg.Declarative.StateFile, "error", err)
+		}
+	}()
+}
+
+// Plan computes the changes that are required to reach the given state, without applying them.
+// If no state is given, the configured state file is loaded.
+func (m Manager) Plan(ctx context.Context, data []byte) (*domain.DeclarativePlan, error) {
+	return m.Apply(ctx, data, true)
+}
+
+// Apply computes and applies the changes that are required to reach the given state. If no state is given,
+// the configured state file is loaded. Errors of single changes do not abort the run, they are recorded
+// in the returned plan instead.
+func (m Manager) Apply(ctx context.Context, data []byte, dryRun bool) (*domain.DeclarativePlan, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	state, err := m.loadState(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = domain.SetUserInfo(ctx, domain.DeclarativeContextUserInfo())
+
+	changes, err := m.plan(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &domain.DeclarativePlan{DryRun: dryRun, Changes: make([]domain.DeclarativeChange, len(changes))}
+	for i := range changes {
+		if !dryRun && changes[i].Error == "" {
+			if err := changes[i].apply(ctx); err != nil {
+				changes[i].Error = err.Error()
+				slog.Error("failed to apply declarative change",
+					"action", changes[i].Action, "type", changes[i].ObjectType, "id", changes[i].Identifier,
+					"error", err)
+			}
+		}
+		plan.Changes[i] = changes[i].DeclarativeChange
+	}
+
+	slog.Info("declarative state processed",
+		"dryRun", dryRun, "changes", len(plan.Changes), "failed", plan.Failed())
+
+	return plan, nil
+}
+
+// ParseState decodes and validates a state file. Environment variables in the state are substituted
+// like in the configuration file, unknown fields are rejected.
+func ParseState(data []byte) (*domain.DeclarativeState, error) {
+	data, err := envsubst.Bytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("envsubst error: %w", err)
+	}
+
+	var state domain.DeclarativeState
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&state); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode state: %w: %w", err, domain.ErrInvalidData)
+	}
+
+	if err := state.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid state: %w", err)
+	}
+
+	return &state, nil
+}
+
+func (m Manager) loadState(data []byte) (*domain.DeclarativeState, error) {
+	if len(data) == 0 {
+		if m.cfg.Declarative.StateFile == "" {
+			return nil, fmt.Errorf("no state given and no state file configured: %w", domain.ErrInvalidData)
+		}
+
+		var err error
+		data, err = os.ReadFile(m.cfg.Declarative.StateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read state file: %w", err)
+		}
+	}
+
+	return ParseState(data)
+}
+
+// plan computes all changes. Users are planned first, as peers reference them, then interfaces and peers.
+// Deletions are planned last and in reverse order.
+func (m Manager) plan(ctx context.Context, state *domain.DeclarativeState) ([]plannedChange, error) {
+	users, err := m.users.GetAllUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+	interfaces, interfacePeers, err := m.wg.GetAllInterfacesAndPeers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interfaces and peers: %w", err)
+	}
+
+	existingUsers := make(map[domain.UserIdentifier]*domain.User, len(users))
+	for i := range users {
+		existingUsers[users[i].Identifier] = &users[i]
+	}
+	existingInterfaces := make(map[domain.InterfaceIdentifier]*domain.Interface, len(interfaces))
+	existingPeers := make(map[domain.PeerIdentifier]*domain.Peer)
+	for i := range interfaces {
+		existingInterfaces[interfaces[i].Identifier] = &interfaces[i]
+		for p := range interfacePeers[i] {
+			existingPeers[interfacePeers[i][p].Identifier] = &interfacePeers[i][p]
+		}
+	}
+
+	stateUsers := make(map[domain.UserIdentifier]struct{}, len(state.Users))
+	stateInterfaces := make(map[domain.InterfaceIdentifier]struct{}, len(state.Interfaces))
+	statePeers := make(map[domain.PeerIdentifier]struct{}, len(state.Peers))
+
+	var changes []plannedChange
+	for i := range state.Users {
+		id := domain.UserIdentifier(state.Users[i].Identifier)
+		stateUsers[id] = struct{}{}
+		change := m.planUser(&state.Users[i], state.IsAdmin(string(id)), existingUsers[id])
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	for i := range state.Interfaces {
+		id := domain.InterfaceIdentifier(state.Interfaces[i].Identifier)
+		stateInterfaces[id] = struct{}{}
+		change := m.planInterface(ctx, &state.Interfaces[i], existingInterfaces[id])
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	for i := range state.Peers {
+		peer := &state.Peers[i]
+		id := domain.PeerIdentifier(peer.Key())
+		statePeers[id] = struct{}{}
+
+		var change *plannedChange
+		switch {
+		case !m.interfaceAvailable(domain.InterfaceIdentifier(peer.Interface), stateInterfaces, existingInterfaces):
+			change = failedChange(domain.DeclarativeActionCreate, domain.DeclarativeObjectPeer, string(id),
+				fmt.Errorf("unknown interface %s: %w", peer.Interface, domain.ErrInvalidData))
+		case peer.User != "" && !m.userAvailable(domain.UserIdentifier(peer.User), stateUsers, existingUsers):
+			change = failedChange(domain.DeclarativeActionCreate, domain.DeclarativeObjectPeer, string(id),
+				fmt.Errorf("unknown user %s: %w", peer.User, domain.ErrInvalidData))
+		default:
+			change = m.planPeer(peer, existingPeers[id])
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	if !m.cfg.Declarative.Prune {
+		return changes, nil
+	}
+
+	for i := range interfacePeers {
+		for _, peer := range interfacePeers[i] {
+			if _, ok := statePeers[peer.Identifier]; ok || peer.ManagedBy != domain.ManagedByDeclarative {
+				continue
+			}
+			changes = append(changes, deleteChange(domain.DeclarativeObjectPeer, string(peer.Identifier),
+				func(ctx context.Context) error {
+					return m.wg.DeletePeer(ctx, peer.Identifier)
+				}))
+		}
+	}
+	for _, iface := range interfaces {
+		if _, ok := stateInterfaces[iface.Identifier]; ok || iface.ManagedBy != domain.ManagedByDeclarative {
+			continue
+		}
+		changes = append(changes, deleteChange(domain.DeclarativeObjectInterface, string(iface.Identifier),
+			func(ctx context.Context) error {
+				return m.wg.DeleteInterface(ctx, iface.Identifier)
+			}))
+	}
+	for _, user := range users {
+		if _, ok := stateUsers[user.Identifier]; ok || user.ManagedBy != domain.ManagedByDeclarative {
+			continue
+		}
+		changes = append(changes, deleteChange(domain.DeclarativeObjectUser, string(user.Identifier),
+			func(ctx context.Context) error {
+				return m.users.DeleteUser(ctx, user.Identifier)
+			}))
+	}
+
+	return changes, nil
+}
+
+// interfaceAvailable returns true if the interface is part of the state or exists and will not be pruned.
+func (m Manager) interfaceAvailable(
+	id domain.InterfaceIdentifier,
+	state map[domain.InterfaceIdentifier]struct{},
+	existing map[domain.InterfaceIdentifier]*domain.Interface,
+) bool {
+	if _, ok := state[id]; ok {
+		return true
+	}
+	iface, ok := existing[id]
+	return ok && (!m.cfg.Declarative.Prune || iface.ManagedBy != domain.ManagedByDeclarative)
+}
+
+// userAvailable returns true if the user is part of the state or exists and will not be pruned.
+func (m Manager) userAvailable(
+	id domain.UserIdentifier,
+	state map[domain.UserIdentifier]struct{},
+	existing map[domain.UserIdentifier]*domain.User,
+) bool {
+	if _, ok := state[id]; ok {
+		return true
+	}
+	user, ok := existing[id]
+	return ok && (!m.cfg.Declarative.Prune || user.ManagedBy != domain.ManagedByDeclarative)
+}
+
+// planUser returns the change for the given user, or nil if the user is up to date.
+func (m Manager) planUser(state *domain.DeclarativeUser, isAdmin bool, existing *domain.User) *plannedChange {
+	if existing != nil {
+		user := *existing
+		state.MergeToUser(&user, isAdmin, false)
+		return updateChange(domain.DeclarativeObjectUser, state.Identifier, userSnapshot, existing, &user,
+			func(ctx context.Context) error {
+				_, err := m.users.UpdateUser(ctx, &user)
+				return err
+			})
+	}
+
+	user := &domain.User{}
+	state.MergeToUser(user, isAdmin, true)
+	switch source := domain.UserSource(state.Source); source {
+	case "", domain.UserSourceDatabase:
+		if state.Password == "" {
+			return failedChange(domain.DeclarativeActionCreate, domain.DeclarativeObjectUser, state.Identifier,
+				fmt.Errorf("database users require a password: %w", domain.ErrInvalidData))
+		}
+	case domain.UserSourceLdap, domain.UserSourceOauth:
+		user.Authentications = []domain.UserAuthentication{{
+			UserIdentifier: user.Identifier,
+			Source:         source,
+			ProviderName:   state.ProviderName,
+		}}
+	default:
+		return failedChange(domain.DeclarativeActionCreate, domain.DeclarativeObjectUser, state.Identifier,
+			fmt.Errorf("invalid user source %q: %w", state.Source, domain.ErrInvalidData))
+	}
+
+	return &plannedChange{
+		DeclarativeChange: domain.DeclarativeChange{
+			Action:     domain.DeclarativeActionCreate,
+			ObjectType: domain.DeclarativeObjectUser,
+			Identifier: state.Identifier,
+		},
+		apply: func(ctx context.Context) error {
+			_, err := m.users.CreateUser(ctx, user)
+			return err
+		},
+	}
+}
+
+// planInterface returns the change for the given interface, or nil if the interface is up to date.
+func (m Manager) planInterface(
+	ctx context.Context,
+	state *domain.DeclarativeInterface,
+	existing *domain.Interface,
+) *plannedChange {
+	if existing != nil {
+		iface := *existing
+		if err := state.MergeToInterface(&iface); err != nil {
+			return failedChange(domain.DeclarativeActionUpdate, domain.DeclarativeObjectInterface, state.Identifier,
+				err)
+		}
+		return updateChange(domain.DeclarativeObjectInterface, state.Identifier, domain.InterfaceSnapshot,
+			existing, &iface,
+			func(ctx context.Context) error {
+				_, _, err := m.wg.UpdateInterface(ctx, &iface)
+				return err
+			})
+	}
+
+	iface, err := m.wg.PrepareInterface(ctx)
+	if err == nil {
+		err = state.MergeToInterface(iface)
+	}
+	if err != nil {
+		return failedChange(domain.DeclarativeActionCreate, domain.DeclarativeObjectInterface, state.Identifier, err)
+	}
+
+	return &plannedChange{
+		DeclarativeChange: domain.DeclarativeChange{
+			Action:     domain.DeclarativeActionCreate,
+			ObjectType: domain.DeclarativeObjectInterface,
+			Identifier: state.Identifier,
+		},
+		apply: func(ctx context.Context) error {
+			_, err := m.wg.CreateInterface(ctx, iface)
+			return err
+		},
+	}
+}
+
+// planPeer returns the change for the given peer, or nil if the peer is up to date. New peers are prepared when
+// the change is applied, as their interface might not exist yet and preparing leases ip addresses.
+func (m Manager) planPeer(state *domain.DeclarativePeer, existing *domain.Peer) *plannedChange {
+	id := state.Key()
+
+	if existing != nil {
+		if existing.InterfaceIdentifier != domain.InterfaceIdentifier(state.Interface) {
+			return failedChange(domain.DeclarativeActionUpdate, domain.DeclarativeObjectPeer, id,
+				fmt.Errorf("peer belongs to interface %s, moving peers is not supported: %w",
+					existing.InterfaceIdentifier, domain.ErrInvalidData))
+		}
+
+		peer := *existing
+		if err := state.MergeToPeer(&peer); err != nil {
+			return failedChange(domain.DeclarativeActionUpdate, domain.DeclarativeObjectPeer, id, err)
+		}
+		return updateChange(domain.DeclarativeObjectPeer, id, domain.PeerSnapshot, existing, &peer,
+			func(ctx context.Context) error {
+				_, err := m.wg.UpdatePeer(ctx, &peer)
+				return err
+			})
+	}
+
+	if err := state.MergeToPeer(&domain.Peer{}); err != nil {
+		return failedChange(domain.DeclarativeActionCreate, domain.DeclarativeObjectPeer, id, err)
+	}
+
+	return &plannedChange{
+		DeclarativeChange: domain.DeclarativeChange{
+			Action:     domain.DeclarativeActionCreate,
+			ObjectType: domain.DeclarativeObjectPeer,
+			Identifier: id,
+		},
+		apply: func(ctx context.Context) error {
+			peer, err := m.wg.PreparePeer(ctx, domain.InterfaceIdentifier(state.Interface))
+			if err != nil {
+				return fmt.Errorf("failed to prepare peer: %w", err)
+			}
+			if err := state.MergeToPeer(peer); err != nil {
+				return err
+			}
+			_, err = m.wg.CreatePeer(ctx, peer)
+			return err
+		},
+	}
+}
+
+// updateChange returns an update change with the differing fields, or nil if both objects are equal.
+func updateChange[T any](
+	objectType domain.DeclarativeObjectType,
+	id string,
+	snapshot func(*T) (string, error),
+	existing, updated *T,
+	apply func(ctx context.Context) error,
+) *plannedChange {
+	fields, err := diffObjects(snapshot, existing, updated)
+	if err != nil {
+		return failedChange(domain.DeclarativeActionUpdate, objectType, id, err)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return &plannedChange{
+		DeclarativeChange: domain.DeclarativeChange{
+			Action:     domain.DeclarativeActionUpdate,
+			ObjectType: objectType,
+			Identifier: id,
+			Fields:     fields,
+		},
+		apply: apply,
+	}
+}
+
+func deleteChange(
+	objectType domain.DeclarativeObjectType,
+	id string,
+	apply func(ctx context.Context) error,
+) plannedChange {
+	return plannedChange{
+		DeclarativeChange: domain.DeclarativeChange{
+			Action:     domain.DeclarativeActionDelete,
+			ObjectType: objectType,
+			Identifier: id,
+		},
+		apply: apply,
+	}
+}
+
+// failedChange returns a change that could not be planned, it is reported but never applied.
+func failedChange(
+	action domain.DeclarativeAction,
+	objectType domain.DeclarativeObjectType,
+	id string,
+	err error,
+) *plannedChange {
+	return &plannedChange{
+		DeclarativeChange: domain.DeclarativeChange{
+			Action:     action,
+			ObjectType: objectType,
+			Identifier: id,
+			Error:      err.Error(),
+		},
+	}
+}
+
+func diffObjects[T any](snapshot func(*T) (string, error), from, to *T) ([]domain.RevisionChange, error) {
+	fromSnapshot, err := snapshot(from)
+	if err != nil {
+		return nil, err
+	}
+	toSnapshot, err := snapshot(to)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.DiffSnapshots(fromSnapshot, toSnapshot)
+}
+
+// userSnapshot encodes the user for the diff, calculated attributes are left out.
+func userSnapshot(user *domain.User) (string, error) {
+	u := *user
+	u.UpdatedAt = time.Time{}
+	u.UpdatedBy = ""
+	u.LinkedPeerCount = 0
+
+	data, err := json.Marshal(&u)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode user %s: %w", user.Identifier, err)
+	}
+
+	return string(data), nil
+}
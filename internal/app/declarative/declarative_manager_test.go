@@ -0,0 +1,272 @@
+package declarative
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockUserManager struct {
+	users map[domain.UserIdentifier]*domain.User
+}
+
+func (m *mockUserManager) GetAllUsers(_ context.Context) ([]domain.User, error) {
+	users := make([]domain.User, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, *u)
+	}
+	slices.SortFunc(users, func(a, b domain.User) int {
+		return strings.Compare(string(a.Identifier), string(b.Identifier))
+	})
+	return users, nil
+}
+
+func (m *mockUserManager) CreateUser(_ context.Context, user *domain.User) (*domain.User, error) {
+	m.users[user.Identifier] = user
+	return user, nil
+}
+
+func (m *mockUserManager) UpdateUser(_ context.Context, user *domain.User) (*domain.User, error) {
+	m.users[user.Identifier] = user
+	return user, nil
+}
+
+func (m *mockUserManager) DeleteUser(_ context.Context, id domain.UserIdentifier) error {
+	delete(m.users, id)
+	return nil
+}
+
+type mockWireGuardManager struct {
+	interfaces map[domain.InterfaceIdentifier]*domain.Interface
+	peers      map[domain.PeerIdentifier]*domain.Peer
+}
+
+func (m *mockWireGuardManager) GetAllInterfacesAndPeers(_ context.Context) (
+	[]domain.Interface,
+	[][]domain.Peer,
+	error,
+) {
+	interfaces := make([]domain.Interface, 0, len(m.interfaces))
+	for _, iface := range m.interfaces {
+		interfaces = append(interfaces, *iface)
+	}
+	slices.SortFunc(interfaces, func(a, b domain.Interface) int {
+		return strings.Compare(string(a.Identifier), string(b.Identifier))
+	})
+
+	peers := make([][]domain.Peer, len(interfaces))
+	for i, iface := range interfaces {
+		for _, peer := range m.peers {
+			if peer.InterfaceIdentifier == iface.Identifier {
+				peers[i] = append(peers[i], *peer)
+			}
+		}
+	}
+	return interfaces, peers, nil
+}
+
+func (m *mockWireGuardManager) PrepareInterface(_ context.Context) (*domain.Interface, error) {
+	kp, err := domain.NewFreshKeypair()
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Interface{Identifier: "wg9", KeyPair: kp, Backend: config.LocalBackendName}, nil
+}
+
+func (m *mockWireGuardManager) CreateInterface(_ context.Context, in *domain.Interface) (*domain.Interface, error) {
+	m.interfaces[in.Identifier] = in
+	return in, nil
+}
+
+func (m *mockWireGuardManager) UpdateInterface(_ context.Context, in *domain.Interface) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	m.interfaces[in.Identifier] = in
+	return in, nil, nil
+}
+
+func (m *mockWireGuardManager) DeleteInterface(_ context.Context, id domain.InterfaceIdentifier) error {
+	delete(m.interfaces, id)
+	return nil
+}
+
+func (m *mockWireGuardManager) PreparePeer(_ context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
+	if _, ok := m.interfaces[id]; !ok {
+		return nil, domain.ErrNotFound
+	}
+	kp, err := domain.NewFreshKeypair()
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := domain.CidrsFromString("10.0.0.2/32")
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Peer{
+		Identifier:          domain.PeerIdentifier(kp.PublicKey),
+		InterfaceIdentifier: id,
+		Interface:           domain.PeerInterfaceConfig{KeyPair: kp, Addresses: addresses},
+	}, nil
+}
+
+func (m *mockWireGuardManager) CreatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	m.peers[peer.Identifier] = peer
+	return peer, nil
+}
+
+func (m *mockWireGuardManager) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	m.peers[peer.Identifier] = peer
+	return peer, nil
+}
+
+func (m *mockWireGuardManager) DeletePeer(_ context.Context, id domain.PeerIdentifier) error {
+	delete(m.peers, id)
+	return nil
+}
+
+func newTestManager(t *testing.T) (*Manager, *mockUserManager, *mockWireGuardManager) {
+	addresses, err := domain.CidrsFromString("10.0.0.1/24")
+	require.NoError(t, err)
+
+	users := &mockUserManager{users: map[domain.UserIdentifier]*domain.User{
+		"admin": {Identifier: "admin", IsAdmin: true},
+		"old":   {Identifier: "old", ManagedBy: domain.ManagedByDeclarative},
+	}}
+	wg := &mockWireGuardManager{
+		interfaces: map[domain.InterfaceIdentifier]*domain.Interface{
+			"wg0": {Identifier: "wg0", Type: domain.InterfaceTypeServer, Addresses: addresses, ListenPort: 51820},
+		},
+		peers: map[domain.PeerIdentifier]*domain.Peer{
+			"manual": {Identifier: "manual", InterfaceIdentifier: "wg0"},
+			"stale":  {Identifier: "stale", InterfaceIdentifier: "wg0", ManagedBy: domain.ManagedByDeclarative},
+		},
+	}
+
+	cfg := &config.Config{}
+	cfg.Declarative.Prune = true
+	m, _ := NewDeclarativeManager(cfg, users, wg)
+	return m, users, wg
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+}
+
+const testState = `
+users:
+  - identifier: alice
+    email: alice@example.com
+    password: ${TEST_ALICE_PASSWORD}
+groups:
+  - name: admins
+    admin: true
+    members: [alice]
+interfaces:
+  - identifier: wg0
+    listen_port: 51821
+    addresses: [10.0.0.1/24]
+    peer_defaults:
+      networks: [10.0.0.0/24]
+peers:
+  - interface: wg0
+    private_key: aJ4Kq7uWNyGx7DOGxNdWGy5kW7Ck3LW5wvCh6DbZWXo=
+    display_name: laptop
+    user: alice
+`
+
+func TestManager_Apply(t *testing.T) {
+	t.Setenv("TEST_ALICE_PASSWORD", "very-secret-password")
+	m, users, wg := newTestManager(t)
+	peerKey := domain.PublicKeyFromPrivateKey("aJ4Kq7uWNyGx7DOGxNdWGy5kW7Ck3LW5wvCh6DbZWXo=")
+
+	plan, err := m.Apply(adminContext(), []byte(testState), true)
+	require.NoError(t, err)
+	assert.True(t, plan.DryRun)
+	assert.Zero(t, plan.Failed())
+
+	actions := make([]string, len(plan.Changes))
+	for i, change := range plan.Changes {
+		actions[i] = string(change.Action) + " " + string(change.ObjectType) + " " + change.Identifier
+	}
+	assert.Equal(t, []string{
+		"create user alice",
+		"update interface wg0",
+		"create peer " + peerKey,
+		"delete peer stale",
+		"delete user old",
+	}, actions)
+	assert.Contains(t, plan.Changes[1].Fields,
+		domain.RevisionChange{Field: "ManagedBy", From: `""`, To: `"declarative"`})
+	assert.Contains(t, plan.Changes[1].Fields, domain.RevisionChange{Field: "ListenPort", From: "51820", To: "51821"})
+
+	assert.NotContains(t, users.users, domain.UserIdentifier("alice"), "dry runs must not change anything")
+	assert.Equal(t, 51820, wg.interfaces["wg0"].ListenPort)
+
+	plan, err = m.Apply(adminContext(), []byte(testState), false)
+	require.NoError(t, err)
+	assert.Zero(t, plan.Failed())
+
+	alice := users.users["alice"]
+	require.NotNil(t, alice)
+	assert.True(t, alice.IsAdmin)
+	assert.Equal(t, domain.PrivateString("very-secret-password"), alice.Password)
+	assert.Equal(t, domain.ManagedByDeclarative, alice.ManagedBy)
+	assert.NotContains(t, users.users, domain.UserIdentifier("old"))
+	assert.Contains(t, users.users, domain.UserIdentifier("admin"), "unmanaged users are never pruned")
+
+	assert.Equal(t, 51821, wg.interfaces["wg0"].ListenPort)
+	assert.Equal(t, domain.ManagedByDeclarative, wg.interfaces["wg0"].ManagedBy)
+
+	peer := wg.peers[domain.PeerIdentifier(peerKey)]
+	require.NotNil(t, peer)
+	assert.Equal(t, "laptop", peer.DisplayName)
+	assert.Equal(t, domain.UserIdentifier("alice"), peer.UserIdentifier)
+	assert.Equal(t, "10.0.0.2/32", domain.CidrsToString(peer.Interface.Addresses), "allocated addresses are used")
+	assert.NotContains(t, wg.peers, domain.PeerIdentifier("stale"))
+	assert.Contains(t, wg.peers, domain.PeerIdentifier("manual"), "unmanaged peers are never pruned")
+
+	plan, err = m.Plan(adminContext(), []byte(testState))
+	require.NoError(t, err)
+	assert.Empty(t, plan.Changes, "applying the same state twice must not change anything")
+}
+
+func TestManager_Apply_Errors(t *testing.T) {
+	m, users, wg := newTestManager(t)
+
+	state := `
+users:
+  - identifier: bob
+peers:
+  - interface: wg1
+    public_key: xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=
+  - interface: wg0
+    public_key: HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8ykw=
+`
+	plan, err := m.Apply(adminContext(), []byte(state), false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, plan.Failed())
+	assert.Contains(t, plan.Changes[0].Error, "password")
+	assert.Contains(t, plan.Changes[1].Error, "unknown interface wg1")
+
+	assert.NotContains(t, users.users, domain.UserIdentifier("bob"))
+	assert.Contains(t, wg.peers, domain.PeerIdentifier("HIgo9xNzJMWLKASShiTqIybxZ0U3wGLiUeJ1PKf8ykw="),
+		"failed changes do not abort the run")
+
+	_, err = m.Apply(adminContext(), []byte("unknown_section: []"), true)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.Apply(adminContext(), nil, true)
+	assert.ErrorIs(t, err, domain.ErrInvalidData, "no state file configured")
+
+	_, err = m.Apply(context.Background(), []byte(state), true)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
@@ -0,0 +1,154 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type InterfaceAndPeerDatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetInterfacePeers returns all peers of the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// Manager serves the names of all peers and interfaces through the built-in DNS server.
+// The zone is kept in memory and updated whenever a peer or interface changes.
+type Manager struct {
+	cfg *config.Config
+
+	bus EventBus
+	db  InterfaceAndPeerDatabaseRepo
+
+	zone     *zone
+	upstream []string // upstream servers including the port
+}
+
+// NewDnsManager creates a new DNS manager instance. The DNS server is started by StartBackgroundJobs.
+func NewDnsManager(cfg *config.Config, bus EventBus, db InterfaceAndPeerDatabaseRepo) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+		db:  db,
+
+		zone: newZone(cfg.Dns.Zone),
+	}
+
+	for _, upstream := range cfg.Dns.Upstream {
+		if _, _, err := net.SplitHostPort(upstream); err != nil {
+			upstream = net.JoinHostPort(upstream, "53")
+		}
+		m.upstream = append(m.upstream, upstream)
+	}
+
+	if cfg.Dns.ListeningAddress != "" {
+		m.connectToMessageBus()
+	}
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceSavedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceSavedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceDeletedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerEvent)
+}
+
+// StartBackgroundJobs loads the zone and starts the DNS server if a listening address is configured.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if m.cfg.Dns.ListeningAddress == "" {
+		return
+	}
+
+	if err := m.loadZone(ctx); err != nil {
+		slog.Error("failed to load DNS zone", "error", err)
+	}
+
+	m.startServer(ctx)
+}
+
+// loadZone adds all interfaces and peers to the zone.
+func (m Manager) loadZone(ctx context.Context) error {
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	for i := range interfaces {
+		m.zone.setInterface(&interfaces[i])
+
+		peers, err := m.db.GetInterfacePeers(ctx, interfaces[i].Identifier)
+		if err != nil {
+			return fmt.Errorf("failed to load peers of interface %s: %w", interfaces[i].Identifier, err)
+		}
+		for j := range peers {
+			m.zone.setPeer(&peers[j])
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) handleInterfaceSavedEvent(iface domain.Interface) {
+	slog.Debug("handling interface save event", "interface", iface.Identifier)
+
+	m.refreshInterface(iface.Identifier)
+}
+
+func (m Manager) handleInterfaceDeletedEvent(iface domain.Interface) {
+	slog.Debug("handling interface delete event", "interface", iface.Identifier)
+
+	m.refreshInterface(iface.Identifier)
+}
+
+// handlePeerEvent updates the peer in the zone. Events of different topics are not delivered in order,
+// so the current state of the peer is always loaded from the database instead of using the event payload.
+func (m Manager) handlePeerEvent(peer domain.Peer) {
+	slog.Debug("handling peer event", "peer", peer.Identifier)
+
+	current, err := m.db.GetPeer(context.Background(), peer.Identifier)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		m.zone.removePeer(peer.Identifier)
+	case err != nil:
+		slog.Error("failed to load peer for DNS update", "peer", peer.Identifier, "error", err)
+	default:
+		m.zone.setPeer(current)
+	}
+}
+
+func (m Manager) refreshInterface(id domain.InterfaceIdentifier) {
+	iface, err := m.db.GetInterface(context.Background(), id)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		m.zone.removeInterface(id)
+	case err != nil:
+		slog.Error("failed to load interface for DNS update", "interface", id, "error", err)
+	default:
+		m.zone.setInterface(iface)
+	}
+}
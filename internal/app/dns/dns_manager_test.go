@@ -0,0 +1,335 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockDatabase struct {
+	iface *domain.Interface
+	peers map[domain.PeerIdentifier]*domain.Peer
+}
+
+func (m *mockDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return []domain.Interface{*m.iface}, nil
+}
+
+func (m *mockDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	if m.iface == nil || m.iface.Identifier != id {
+		return nil, domain.ErrNotFound
+	}
+	return m.iface, nil
+}
+
+func (m *mockDatabase) GetInterfacePeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	peers := make([]domain.Peer, 0, len(m.peers))
+	for _, p := range m.peers {
+		peers = append(peers, *p)
+	}
+	return peers, nil
+}
+
+func (m *mockDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	if peer, ok := m.peers[id]; ok {
+		return peer, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+type mockBus struct{}
+
+func (f *mockBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+func cidrs(t *testing.T, values ...string) []domain.Cidr {
+	result, err := domain.CidrsFromArray(values)
+	require.NoError(t, err)
+	return result
+}
+
+// peerAddr is the address of a peer of the test interface, queries from this address are forwarded.
+var peerAddr = netip.MustParseAddr("10.0.0.2")
+
+func newTestManager(t *testing.T, upstream ...string) (*Manager, *mockDatabase) {
+	db := &mockDatabase{
+		iface: &domain.Interface{Identifier: "wg0", Addresses: cidrs(t, "10.0.0.1/24")},
+		peers: map[domain.PeerIdentifier]*domain.Peer{
+			"peer1": {
+				Identifier:          "peer1",
+				DisplayName:         "Alice's Laptop",
+				UserIdentifier:      "alice@example.com",
+				InterfaceIdentifier: "wg0",
+				Interface:           domain.PeerInterfaceConfig{Addresses: cidrs(t, "10.0.0.2/32", "fd00::2/128")},
+			},
+			"peer2": {
+				Identifier:          "peer2",
+				DisplayName:         "printer",
+				InterfaceIdentifier: "wg0",
+				Interface:           domain.PeerInterfaceConfig{Addresses: cidrs(t, "10.0.0.3/32")},
+			},
+		},
+	}
+
+	cfg := &config.Config{}
+	cfg.Dns.Zone = "VPN.internal."
+	cfg.Dns.Upstream = upstream
+	cfg.Dns.Ttl = time.Minute
+
+	m, err := NewDnsManager(cfg, &mockBus{}, db)
+	require.NoError(t, err)
+	require.NoError(t, m.loadZone(context.Background()))
+
+	return m, db
+}
+
+func query(t *testing.T, m *Manager, name string, qType dnsmessage.Type) (dnsmessage.Header, []dnsmessage.Resource) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 42, RecursionDesired: true})
+	require.NoError(t, b.StartQuestions())
+	require.NoError(t, b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  qType,
+		Class: dnsmessage.ClassINET,
+	}))
+	raw, err := b.Finish()
+	require.NoError(t, err)
+
+	response := m.handleQuery(context.Background(), "udp", peerAddr, raw)
+	require.NotNil(t, response)
+
+	var msg dnsmessage.Message
+	require.NoError(t, msg.Unpack(response))
+	assert.Equal(t, uint16(42), msg.Header.ID)
+	assert.True(t, msg.Header.Response)
+
+	return msg.Header, msg.Answers
+}
+
+func answerAddrs(answers []dnsmessage.Resource) []string {
+	var result []string
+	for _, answer := range answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			result = append(result, netip.AddrFrom4(body.A).String())
+		case *dnsmessage.AAAAResource:
+			result = append(result, netip.AddrFrom16(body.AAAA).String())
+		case *dnsmessage.PTRResource:
+			result = append(result, body.PTR.String())
+		}
+	}
+	return result
+}
+
+func TestManager_HandleQuery_Zone(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	header, answers := query(t, m, "alice-s-laptop.alice-example-com.wg0.vpn.internal.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeSuccess, header.RCode)
+	assert.True(t, header.Authoritative)
+	assert.Equal(t, []string{"10.0.0.2"}, answerAddrs(answers))
+	assert.Equal(t, uint32(60), answers[0].Header.TTL)
+
+	_, answers = query(t, m, "Alice-S-Laptop.alice-example-com.wg0.VPN.internal.", dnsmessage.TypeAAAA)
+	assert.Equal(t, []string{"fd00::2"}, answerAddrs(answers))
+
+	_, answers = query(t, m, "printer.wg0.vpn.internal.", dnsmessage.TypeA)
+	assert.Equal(t, []string{"10.0.0.3"}, answerAddrs(answers), "peers without a user")
+
+	_, answers = query(t, m, "wg0.vpn.internal.", dnsmessage.TypeA)
+	assert.Equal(t, []string{"10.0.0.1"}, answerAddrs(answers), "interface address")
+
+	header, answers = query(t, m, "printer.wg0.vpn.internal.", dnsmessage.TypeAAAA)
+	assert.Equal(t, dnsmessage.RCodeSuccess, header.RCode, "name exists without AAAA record")
+	assert.Empty(t, answers)
+
+	header, _ = query(t, m, "alice-example-com.wg0.vpn.internal.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeSuccess, header.RCode, "intermediate names exist")
+
+	header, _ = query(t, m, "unknown.wg0.vpn.internal.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeNameError, header.RCode)
+	assert.True(t, header.Authoritative)
+}
+
+func TestManager_HandleQuery_Reverse(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	_, answers := query(t, m, "2.0.0.10.in-addr.arpa.", dnsmessage.TypePTR)
+	assert.Equal(t, []string{"alice-s-laptop.alice-example-com.wg0.vpn.internal."}, answerAddrs(answers))
+
+	_, answers = query(t, m,
+		"2.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa.", dnsmessage.TypePTR)
+	assert.Equal(t, []string{"alice-s-laptop.alice-example-com.wg0.vpn.internal."}, answerAddrs(answers))
+
+	header, _ := query(t, m, "9.0.0.10.in-addr.arpa.", dnsmessage.TypePTR)
+	assert.Equal(t, dnsmessage.RCodeRefused, header.RCode, "unknown addresses are forwarded")
+}
+
+func TestManager_HandleQuery_Forward(t *testing.T) {
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer upstream.Close()
+
+	go func() {
+		buf := make([]byte, 512)
+		n, remote, err := upstream.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var msg dnsmessage.Message
+		if msg.Unpack(buf[:n]) != nil {
+			return
+		}
+		msg.Header.Response = true
+		msg.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: msg.Questions[0].Name, Class: dnsmessage.ClassINET, TTL: 30},
+			Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+		}}
+		response, _ := msg.Pack()
+		_, _ = upstream.WriteTo(response, remote)
+	}()
+
+	m, _ := newTestManager(t, upstream.LocalAddr().String())
+	header, answers := query(t, m, "example.com.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeSuccess, header.RCode)
+	assert.Equal(t, []string{"192.0.2.1"}, answerAddrs(answers))
+
+	m, _ = newTestManager(t)
+	header, _ = query(t, m, "example.com.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeRefused, header.RCode, "without upstream servers")
+}
+
+// ednsQuery returns an A query for example.com with an EDNS OPT record, a payload size of 0 omits the record.
+func ednsQuery(t *testing.T, payloadSize int) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 42, RecursionDesired: true})
+	require.NoError(t, b.StartQuestions())
+	require.NoError(t, b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName("example.com."),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	}))
+	if payloadSize > 0 {
+		var opt dnsmessage.ResourceHeader
+		require.NoError(t, opt.SetEDNS0(payloadSize, dnsmessage.RCodeSuccess, false))
+		require.NoError(t, b.StartAdditionals())
+		require.NoError(t, b.OPTResource(opt, dnsmessage.OPTResource{}))
+	}
+	raw, err := b.Finish()
+	require.NoError(t, err)
+
+	return raw
+}
+
+func TestUdpPayloadSize(t *testing.T) {
+	assert.Equal(t, 512, udpPayloadSize(ednsQuery(t, 0)), "without EDNS")
+	assert.Equal(t, 1232, udpPayloadSize(ednsQuery(t, 1232)))
+	assert.Equal(t, 4096, udpPayloadSize(ednsQuery(t, 4096)))
+	assert.Equal(t, 512, udpPayloadSize(ednsQuery(t, 256)), "smaller sizes are treated as 512 bytes")
+	assert.Equal(t, 512, udpPayloadSize([]byte{0, 42}), "invalid query")
+}
+
+func TestManager_HandleQuery_ForwardEdns(t *testing.T) {
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer upstream.Close()
+
+	// the upstream answer with 40 addresses does not fit into 512 bytes
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, remote, err := upstream.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var msg dnsmessage.Message
+			if msg.Unpack(buf[:n]) != nil {
+				return
+			}
+			msg.Header.Response = true
+			for i := 0; i < 40; i++ {
+				msg.Answers = append(msg.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: msg.Questions[0].Name, Class: dnsmessage.ClassINET, TTL: 30},
+					Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, byte(i)}},
+				})
+			}
+			response, _ := msg.Pack()
+			_, _ = upstream.WriteTo(response, remote)
+		}
+	}()
+
+	m, _ := newTestManager(t, upstream.LocalAddr().String())
+	for _, payloadSize := range []int{0, 4096} {
+		var msg dnsmessage.Message
+		require.NoError(t, msg.Unpack(m.handleQuery(context.Background(), "udp", peerAddr, ednsQuery(t, payloadSize))))
+		if payloadSize == 0 {
+			assert.True(t, msg.Header.Truncated, "truncated without EDNS")
+			assert.Empty(t, msg.Answers)
+		} else {
+			assert.False(t, msg.Header.Truncated, "not truncated with EDNS")
+			assert.Len(t, msg.Answers, 40)
+		}
+	}
+}
+
+func TestManager_HandleQuery_ForwardClients(t *testing.T) {
+	m, _ := newTestManager(t, "127.0.0.1:1") // the upstream is never reached
+	raw := ednsQuery(t, 0)
+
+	var msg dnsmessage.Message
+	require.NoError(t, msg.Unpack(m.handleQuery(context.Background(), "udp", netip.MustParseAddr("192.0.2.10"), raw)))
+	assert.Equal(t, dnsmessage.RCodeRefused, msg.Header.RCode, "clients outside of the interface networks")
+
+	zoneQuery := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 42})
+	require.NoError(t, zoneQuery.StartQuestions())
+	require.NoError(t, zoneQuery.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName("printer.wg0.vpn.internal."),
+		Type:  dnsmessage.TypeA,
+		Class: dnsmessage.ClassINET,
+	}))
+	raw, err := zoneQuery.Finish()
+	require.NoError(t, err)
+	require.NoError(t, msg.Unpack(m.handleQuery(context.Background(), "udp", netip.MustParseAddr("192.0.2.10"), raw)))
+	assert.Equal(t, dnsmessage.RCodeSuccess, msg.Header.RCode, "names of the zone are answered for all clients")
+
+	assert.True(t, m.zone.isClient(netip.MustParseAddr("10.0.0.200")))
+	assert.True(t, m.zone.isClient(netip.MustParseAddr("::1")))
+	assert.True(t, m.zone.isClient(netip.MustParseAddr("::ffff:10.0.0.5")))
+	assert.False(t, m.zone.isClient(netip.MustParseAddr("10.0.1.1")))
+	assert.False(t, m.zone.isClient(netip.Addr{}))
+}
+
+func TestManager_HandlePeerEvent(t *testing.T) {
+	m, db := newTestManager(t)
+
+	db.peers["peer2"].DisplayName = "scanner"
+	m.handlePeerEvent(*db.peers["peer2"])
+	_, answers := query(t, m, "scanner.wg0.vpn.internal.", dnsmessage.TypeA)
+	assert.Equal(t, []string{"10.0.0.3"}, answerAddrs(answers))
+	header, _ := query(t, m, "printer.wg0.vpn.internal.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeNameError, header.RCode, "old name is removed")
+
+	now := time.Now()
+	db.peers["peer2"].Disabled = &now
+	m.handlePeerEvent(*db.peers["peer2"])
+	header, _ = query(t, m, "scanner.wg0.vpn.internal.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeNameError, header.RCode, "disabled peers are not resolved")
+
+	peer1 := *db.peers["peer1"]
+	delete(db.peers, "peer1")
+	m.handlePeerEvent(peer1)
+	header, _ = query(t, m, "2.0.0.10.in-addr.arpa.", dnsmessage.TypePTR)
+	assert.Equal(t, dnsmessage.RCodeRefused, header.RCode, "deleted peers are removed")
+
+	iface := *db.iface
+	db.iface = nil
+	m.handleInterfaceDeletedEvent(iface)
+	header, _ = query(t, m, "wg0.vpn.internal.", dnsmessage.TypeA)
+	assert.Equal(t, dnsmessage.RCodeNameError, header.RCode)
+}
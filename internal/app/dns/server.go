@@ -0,0 +1,476 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// maxUdpResponseSize is the response size limit for UDP clients without EDNS, larger responses are truncated.
+	// Clients with EDNS advertise their own limit, which must not be smaller.
+	maxUdpResponseSize = 512
+	// forwardTimeout limits the time an upstream server may take to answer.
+	forwardTimeout = 3 * time.Second
+	// tcpIdleTimeout closes TCP connections of clients that do not send further queries.
+	tcpIdleTimeout = 10 * time.Second
+	// maxConcurrentQueries limits the number of UDP queries that are handled at the same time. Further queries
+	// wait until a running query is answered.
+	maxConcurrentQueries = 256
+	// maxTcpConnections limits the number of open TCP connections, further connections wait to be accepted.
+	maxTcpConnections = 64
+)
+
+// startServer starts the UDP and TCP listeners. Both are closed once the context is done.
+func (m Manager) startServer(ctx context.Context) {
+	addr := m.cfg.Dns.ListeningAddress
+
+	udpConn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		slog.Error("failed to start DNS server", "address", addr, "network", "udp", "error", err)
+		return
+	}
+	tcpListener, err := net.Listen("tcp", addr)
+	if err != nil {
+		_ = udpConn.Close()
+		slog.Error("failed to start DNS server", "address", addr, "network", "tcp", "error", err)
+		return
+	}
+
+	go m.serveUdp(ctx, udpConn)
+	go m.serveTcp(ctx, tcpListener)
+
+	go func() {
+		<-ctx.Done()
+		_ = udpConn.Close()
+		_ = tcpListener.Close()
+	}()
+
+	slog.Info("started DNS server", "address", addr, "zone", m.zone.origin)
+}
+
+func (m Manager) serveUdp(ctx context.Context, conn net.PacketConn) {
+	sem := make(chan struct{}, maxConcurrentQueries)
+	buf := make([]byte, 65535)
+	for {
+		n, remote, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Error("failed to read DNS query", "network", "udp", "error", err)
+			}
+			return
+		}
+
+		query := make([]byte, n)
+		copy(query, buf[:n])
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }()
+			if response := m.handleQuery(ctx, "udp", remoteAddr(remote), query); response != nil {
+				_, _ = conn.WriteTo(response, remote)
+			}
+		}()
+	}
+}
+
+func (m Manager) serveTcp(ctx context.Context, listener net.Listener) {
+	sem := make(chan struct{}, maxTcpConnections)
+	for {
+		sem <- struct{}{}
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Error("failed to accept DNS connection", "network", "tcp", "error", err)
+			}
+			return
+		}
+
+		go func() {
+			defer func() { <-sem }()
+			defer conn.Close()
+			source := remoteAddr(conn.RemoteAddr())
+			for {
+				_ = conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+				query, err := readTcpMessage(conn)
+				if err != nil {
+					return
+				}
+				response := m.handleQuery(ctx, "tcp", source, query)
+				if response == nil || writeTcpMessage(conn, response) != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// handleQuery answers the raw DNS query. Names of the zone and reverse lookups of zone addresses are answered
+// directly, everything else is forwarded to the upstream servers. Only queries of clients in the networks of the
+// interfaces are forwarded, so that the server can not be abused as open resolver. If nil is returned, no response
+// is sent.
+func (m Manager) handleQuery(ctx context.Context, network string, source netip.Addr, query []byte) []byte {
+	var p dnsmessage.Parser
+	header, err := p.Start(query)
+	if err != nil || header.Response {
+		return nil // not a query, there is nothing to respond to
+	}
+
+	question, err := p.Question()
+	if err != nil || header.OpCode != 0 {
+		return m.errorResponse(header, nil, dnsmessage.RCodeFormatError)
+	}
+
+	name := question.Name.String()
+	var response []byte
+	switch {
+	case m.zone.contains(name):
+		response, err = m.zoneResponse(header, question)
+	case question.Type == dnsmessage.TypePTR && m.zone.lookupAddr(reverseAddr(name)) != "":
+		response, err = m.ptrResponse(header, question)
+	case !m.zone.isClient(source):
+		response = m.errorResponse(header, &question, dnsmessage.RCodeRefused)
+	default:
+		response = m.forward(ctx, network, header, question, query)
+	}
+	if err != nil {
+		slog.Error("failed to build DNS response", "name", name, "error", err)
+		return m.errorResponse(header, &question, dnsmessage.RCodeServerFailure)
+	}
+
+	if network == "udp" && len(response) > udpPayloadSize(query) {
+		return m.truncatedResponse(header, question)
+	}
+
+	return response
+}
+
+// udpPayloadSize returns the UDP response size limit of the client. It is the payload size of the EDNS OPT record
+// of the query, or maxUdpResponseSize if the query has no OPT record.
+func udpPayloadSize(query []byte) int {
+	var p dnsmessage.Parser
+	if _, err := p.Start(query); err != nil {
+		return maxUdpResponseSize
+	}
+	if p.SkipAllQuestions() != nil || p.SkipAllAnswers() != nil || p.SkipAllAuthorities() != nil {
+		return maxUdpResponseSize
+	}
+
+	for {
+		h, err := p.AdditionalHeader()
+		if err != nil {
+			return maxUdpResponseSize // no OPT record
+		}
+		if h.Type == dnsmessage.TypeOPT {
+			return max(int(h.Class), maxUdpResponseSize) // the class of the OPT record holds the payload size
+		}
+		if err := p.SkipAdditional(); err != nil {
+			return maxUdpResponseSize
+		}
+	}
+}
+
+// zoneResponse answers a question for a name of the zone. Unknown names are answered with NXDOMAIN and existing
+// names without a record of the requested type with an empty answer, both with the SOA record of the zone.
+func (m Manager) zoneResponse(header dnsmessage.Header, question dnsmessage.Question) ([]byte, error) {
+	addrs, exists := m.zone.lookup(question.Name.String())
+	rcode := dnsmessage.RCodeSuccess
+	if !exists {
+		rcode = dnsmessage.RCodeNameError
+	}
+
+	b := m.newResponseBuilder(header, rcode, true)
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(question); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	answers := 0
+	resourceHeader := m.resourceHeader(question.Name)
+	for _, addr := range addrs {
+		switch {
+		case addr.Is4() && (question.Type == dnsmessage.TypeA || question.Type == dnsmessage.TypeALL):
+			err := b.AResource(resourceHeader, dnsmessage.AResource{A: addr.As4()})
+			if err != nil {
+				return nil, err
+			}
+			answers++
+		case addr.Is6() && (question.Type == dnsmessage.TypeAAAA || question.Type == dnsmessage.TypeALL):
+			err := b.AAAAResource(resourceHeader, dnsmessage.AAAAResource{AAAA: addr.As16()})
+			if err != nil {
+				return nil, err
+			}
+			answers++
+		}
+	}
+
+	isApex := strings.EqualFold(question.Name.String(), m.zone.origin)
+	if isApex && question.Type == dnsmessage.TypeSOA {
+		if err := m.soaRecord(&b); err != nil {
+			return nil, err
+		}
+		answers++
+	}
+
+	if answers == 0 {
+		if err := b.StartAuthorities(); err != nil {
+			return nil, err
+		}
+		if err := m.soaRecord(&b); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.Finish()
+}
+
+// ptrResponse answers a reverse lookup of an address of the zone.
+func (m Manager) ptrResponse(header dnsmessage.Header, question dnsmessage.Question) ([]byte, error) {
+	target, err := dnsmessage.NewName(m.zone.lookupAddr(reverseAddr(question.Name.String())))
+	if err != nil {
+		return nil, err
+	}
+
+	b := m.newResponseBuilder(header, dnsmessage.RCodeSuccess, true)
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(question); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if err := b.PTRResource(m.resourceHeader(question.Name), dnsmessage.PTRResource{PTR: target}); err != nil {
+		return nil, err
+	}
+
+	return b.Finish()
+}
+
+// forward sends the query to the upstream servers in order and returns the first response.
+func (m Manager) forward(
+	ctx context.Context,
+	network string,
+	header dnsmessage.Header,
+	question dnsmessage.Question,
+	query []byte,
+) []byte {
+	if len(m.upstream) == 0 {
+		return m.errorResponse(header, &question, dnsmessage.RCodeRefused)
+	}
+
+	for _, upstream := range m.upstream {
+		response, err := exchange(ctx, network, upstream, query)
+		if err != nil {
+			slog.Debug("failed to forward DNS query", "upstream", upstream, "name", question.Name, "error", err)
+			continue
+		}
+		return response
+	}
+
+	return m.errorResponse(header, &question, dnsmessage.RCodeServerFailure)
+}
+
+// exchange sends the query to the server and waits for the response.
+func exchange(ctx context.Context, network, server string, query []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, forwardTimeout)
+	defer cancel()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	var response []byte
+	if network == "tcp" {
+		if err := writeTcpMessage(conn, query); err != nil {
+			return nil, err
+		}
+		response, err = readTcpMessage(conn)
+	} else {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		var n int
+		n, err = conn.Read(buf)
+		response = buf[:n]
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) < 2 || binary.BigEndian.Uint16(response) != binary.BigEndian.Uint16(query) {
+		return nil, fmt.Errorf("response id does not match the query")
+	}
+
+	return response, nil
+}
+
+func (m Manager) newResponseBuilder(
+	header dnsmessage.Header,
+	rcode dnsmessage.RCode,
+	authoritative bool,
+) dnsmessage.Builder {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		OpCode:             header.OpCode,
+		Authoritative:      authoritative,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: len(m.upstream) > 0,
+		RCode:              rcode,
+	})
+	b.EnableCompression()
+
+	return b
+}
+
+// errorResponse returns a response without records. The question is optional.
+func (m Manager) errorResponse(header dnsmessage.Header, question *dnsmessage.Question, rcode dnsmessage.RCode) []byte {
+	b := m.newResponseBuilder(header, rcode, false)
+	if question != nil {
+		_ = b.StartQuestions()
+		_ = b.Question(*question)
+	}
+
+	response, _ := b.Finish()
+	return response
+}
+
+// truncatedResponse tells UDP clients to repeat the query over TCP.
+func (m Manager) truncatedResponse(header dnsmessage.Header, question dnsmessage.Question) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		OpCode:             header.OpCode,
+		Truncated:          true,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: len(m.upstream) > 0,
+	})
+	_ = b.StartQuestions()
+	_ = b.Question(question)
+
+	response, _ := b.Finish()
+	return response
+}
+
+func (m Manager) resourceHeader(name dnsmessage.Name) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{
+		Name:  name,
+		Class: dnsmessage.ClassINET,
+		TTL:   uint32(m.cfg.Dns.Ttl.Seconds()),
+	}
+}
+
+// soaRecord adds the SOA record of the zone. The zone is only served by this server, so there are no secondaries
+// that would use the refresh and retry values.
+func (m Manager) soaRecord(b *dnsmessage.Builder) error {
+	origin, err := dnsmessage.NewName(m.zone.origin)
+	if err != nil {
+		return err
+	}
+	mbox, err := dnsmessage.NewName("hostmaster." + m.zone.origin)
+	if err != nil {
+		return err
+	}
+
+	ttl := uint32(m.cfg.Dns.Ttl.Seconds())
+	return b.SOAResource(m.resourceHeader(origin), dnsmessage.SOAResource{
+		NS:      origin,
+		MBox:    mbox,
+		Serial:  m.zone.soaSerial(),
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		MinTTL:  ttl,
+	})
+}
+
+// reverseAddr parses a reverse lookup name of the in-addr.arpa or ip6.arpa domain.
+// An invalid address is returned if the name is not a complete reverse lookup name.
+func reverseAddr(name string) netip.Addr {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	switch {
+	case strings.HasSuffix(name, ".in-addr.arpa"):
+		labels := strings.Split(strings.TrimSuffix(name, ".in-addr.arpa"), ".")
+		if len(labels) != 4 {
+			return netip.Addr{}
+		}
+		addr, _ := netip.ParseAddr(labels[3] + "." + labels[2] + "." + labels[1] + "." + labels[0])
+		return addr
+	case strings.HasSuffix(name, ".ip6.arpa"):
+		nibbles := strings.Split(strings.TrimSuffix(name, ".ip6.arpa"), ".")
+		if len(nibbles) != 32 {
+			return netip.Addr{}
+		}
+		var b strings.Builder
+		for i := len(nibbles) - 1; i >= 0; i-- {
+			if len(nibbles[i]) != 1 {
+				return netip.Addr{}
+			}
+			b.WriteString(nibbles[i])
+			if i%4 == 0 && i > 0 {
+				b.WriteByte(':')
+			}
+		}
+		addr, _ := netip.ParseAddr(b.String())
+		return addr
+	default:
+		return netip.Addr{}
+	}
+}
+
+// remoteAddr returns the IP address of a UDP or TCP remote address, or an invalid address for other addresses.
+func remoteAddr(addr net.Addr) netip.Addr {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.AddrPort().Addr()
+	case *net.TCPAddr:
+		return a.AddrPort().Addr()
+	default:
+		return netip.Addr{}
+	}
+}
+
+func readTcpMessage(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func writeTcpMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+
+	_, err := w.Write(buf)
+	return err
+}
@@ -0,0 +1,226 @@
+package dns
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// zone holds the names of all interfaces and peers. Peers are named <peer>.<user>.<interface>.<zone>,
+// peers without a user <peer>.<interface>.<zone>, and interfaces <interface>.<zone>.
+type zone struct {
+	origin string // fully qualified, lowercase, with a trailing dot
+
+	mux        sync.RWMutex
+	interfaces map[domain.InterfaceIdentifier][]netip.Addr
+	networks   map[domain.InterfaceIdentifier][]netip.Prefix // networks of the interfaces, used to identify clients
+	peers      map[domain.PeerIdentifier]zonePeer
+
+	// lookup indexes, rebuilt after each change
+	names   map[string][]netip.Addr
+	reverse map[netip.Addr]string
+	serial  uint32 // SOA serial, increases with each change
+}
+
+type zonePeer struct {
+	label     string
+	userLabel string
+	iface     domain.InterfaceIdentifier
+	addresses []netip.Addr
+}
+
+func newZone(origin string) *zone {
+	z := &zone{
+		origin:     domain.FqdnDnsName(origin),
+		interfaces: make(map[domain.InterfaceIdentifier][]netip.Addr),
+		networks:   make(map[domain.InterfaceIdentifier][]netip.Prefix),
+		peers:      make(map[domain.PeerIdentifier]zonePeer),
+	}
+	z.rebuild()
+
+	return z
+}
+
+// setInterface adds or updates the interface. Its peers keep their names.
+func (z *zone) setInterface(iface *domain.Interface) {
+	z.mux.Lock()
+	defer z.mux.Unlock()
+
+	z.interfaces[iface.Identifier] = cidrAddrs(iface.Addresses)
+	z.networks[iface.Identifier] = cidrNetworks(iface.Addresses)
+	z.rebuild()
+}
+
+// removeInterface removes the interface and all of its peers.
+func (z *zone) removeInterface(id domain.InterfaceIdentifier) {
+	z.mux.Lock()
+	defer z.mux.Unlock()
+
+	delete(z.interfaces, id)
+	delete(z.networks, id)
+	for peerId, peer := range z.peers {
+		if peer.iface == id {
+			delete(z.peers, peerId)
+		}
+	}
+	z.rebuild()
+}
+
+// setPeer adds or updates the peer. Disabled peers and peers without a usable name are removed.
+func (z *zone) setPeer(peer *domain.Peer) {
+	z.mux.Lock()
+	defer z.mux.Unlock()
+
+	label := domain.DnsLabel(peer.DisplayName)
+	if peer.IsDisabled() || label == "" {
+		delete(z.peers, peer.Identifier)
+	} else {
+		z.peers[peer.Identifier] = zonePeer{
+			label:     label,
+			userLabel: domain.DnsLabel(string(peer.UserIdentifier)),
+			iface:     peer.InterfaceIdentifier,
+			addresses: cidrAddrs(peer.Interface.Addresses),
+		}
+	}
+	z.rebuild()
+}
+
+func (z *zone) removePeer(id domain.PeerIdentifier) {
+	z.mux.Lock()
+	defer z.mux.Unlock()
+
+	delete(z.peers, id)
+	z.rebuild()
+}
+
+// rebuild recreates the lookup indexes, the caller must hold the write lock.
+func (z *zone) rebuild() {
+	names := make(map[string][]netip.Addr)
+	reverse := make(map[netip.Addr]string)
+
+	add := func(name string, addrs []netip.Addr) {
+		names[name] = append(names[name], addrs...)
+		for _, addr := range addrs {
+			// peers sharing an address (which should not happen) resolve to the lexically first name
+			if existing, ok := reverse[addr]; !ok || name < existing {
+				reverse[addr] = name
+			}
+		}
+	}
+
+	for id, addrs := range z.interfaces {
+		add(domain.DnsLabel(string(id))+"."+z.origin, addrs)
+	}
+	for _, peer := range z.peers {
+		name := peer.label + "."
+		if peer.userLabel != "" {
+			name += peer.userLabel + "."
+		}
+		add(name+domain.DnsLabel(string(peer.iface))+"."+z.origin, peer.addresses)
+	}
+
+	for name := range names {
+		slices.SortFunc(names[name], func(a, b netip.Addr) int { return a.Compare(b) })
+		names[name] = slices.Compact(names[name])
+	}
+
+	z.names = names
+	z.reverse = reverse
+	z.serial = max(z.serial+1, uint32(time.Now().Unix()))
+}
+
+// contains returns true if the fully qualified name belongs to the zone.
+func (z *zone) contains(name string) bool {
+	name = strings.ToLower(name)
+	return name == z.origin || strings.HasSuffix(name, "."+z.origin)
+}
+
+// lookup returns the addresses of the fully qualified name. The second return value is false if the name does not
+// exist in the zone.
+func (z *zone) lookup(name string) ([]netip.Addr, bool) {
+	z.mux.RLock()
+	defer z.mux.RUnlock()
+
+	name = strings.ToLower(name)
+	if name == z.origin {
+		return nil, true
+	}
+
+	addrs, ok := z.names[name]
+	if ok {
+		return addrs, true
+	}
+
+	// names like <user>.<interface>.<zone> exist as long as they have sub names
+	for existing := range z.names {
+		if strings.HasSuffix(existing, "."+name) {
+			return nil, true
+		}
+	}
+
+	return nil, false
+}
+
+// soaSerial returns the current serial of the zone.
+func (z *zone) soaSerial() uint32 {
+	z.mux.RLock()
+	defer z.mux.RUnlock()
+
+	return z.serial
+}
+
+// isClient returns true if the address belongs to the network of an interface. Loopback addresses of the host
+// are clients too.
+func (z *zone) isClient(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() {
+		return true
+	}
+
+	z.mux.RLock()
+	defer z.mux.RUnlock()
+
+	for _, networks := range z.networks {
+		for _, network := range networks {
+			if network.Contains(addr) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// lookupAddr returns the name of the address, or an empty string if the address is not part of the zone.
+func (z *zone) lookupAddr(addr netip.Addr) string {
+	z.mux.RLock()
+	defer z.mux.RUnlock()
+
+	return z.reverse[addr.Unmap()]
+}
+
+func cidrAddrs(cidrs []domain.Cidr) []netip.Addr {
+	addrs := make([]netip.Addr, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if addr, err := netip.ParseAddr(cidr.Addr); err == nil {
+			addrs = append(addrs, addr.Unmap())
+		}
+	}
+
+	return addrs
+}
+
+func cidrNetworks(cidrs []domain.Cidr) []netip.Prefix {
+	networks := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if addr, err := netip.ParseAddr(cidr.Addr); err == nil {
+			networks = append(networks, netip.PrefixFrom(addr, cidr.NetLength).Masked())
+		}
+	}
+
+	return networks
+}
@@ -0,0 +1,431 @@
+package dnsupdate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type InterfaceAndPeerDatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfacePeers returns all peers of the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+}
+
+type DnsUpdater interface {
+	// Update applies the changes to a zone of the primary DNS server.
+	Update(ctx context.Context, update domain.DnsUpdate) error
+	// Transfer returns the records of a zone of the primary DNS server.
+	Transfer(ctx context.Context, zone string) ([]domain.DnsRecord, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+// endregion dependencies
+
+// ownerMarker prefixes the TXT records that mark the names managed by WireGuard Portal.
+const ownerMarker = "heritage=wg-portal,owner="
+
+// Manager keeps the A, AAAA and PTR records of all peers up to date on an external primary DNS server.
+// Each peer name also gets a TXT record that marks it as managed, so that the reconciliation never touches
+// records of other sources.
+type Manager struct {
+	cfg *config.Config
+
+	bus EventBus
+	db  InterfaceAndPeerDatabaseRepo
+	dns DnsUpdater
+
+	nameTemplate *template.Template
+	zone         string   // fully qualified forward zone
+	reverseZones []string // fully qualified reverse zones
+
+	mux   *sync.Mutex
+	peers map[domain.PeerIdentifier]peerRecords // the records of each peer as last sent to the server
+}
+
+type peerRecords struct {
+	iface   domain.InterfaceIdentifier
+	records []domain.DnsRecord
+}
+
+// nameTemplateData is passed to the name template. All values are valid DNS labels, User is empty if the peer is
+// not linked to a user.
+type nameTemplateData struct {
+	Peer      string
+	User      string
+	Interface string
+}
+
+// NewDnsUpdateManager creates a new DNS update manager instance.
+func NewDnsUpdateManager(
+	cfg *config.Config,
+	bus EventBus,
+	db InterfaceAndPeerDatabaseRepo,
+	dns DnsUpdater,
+) (*Manager, error) {
+	nameTemplate, err := template.New("name").Option("missingkey=error").Parse(cfg.DnsUpdate.NameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DNS name template: %w", err)
+	}
+
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+		db:  db,
+		dns: dns,
+
+		nameTemplate: nameTemplate,
+		zone:         domain.FqdnDnsName(cfg.DnsUpdate.Zone),
+
+		mux:   &sync.Mutex{},
+		peers: make(map[domain.PeerIdentifier]peerRecords),
+	}
+	for _, zone := range cfg.DnsUpdate.ReverseZones {
+		m.reverseZones = append(m.reverseZones, domain.FqdnDnsName(zone))
+	}
+
+	if cfg.DnsUpdate.Server != "" {
+		if cfg.DnsUpdate.Zone == "" {
+			return nil, errors.New("a zone is required for DNS updates")
+		}
+		m.connectToMessageBus()
+	}
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceDeletedEvent)
+}
+
+// StartBackgroundJobs starts the periodic reconciliation if DNS updates are enabled.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	if m.cfg.DnsUpdate.Server == "" {
+		return
+	}
+
+	go m.runReconciliation(ctx)
+}
+
+func (m Manager) runReconciliation(ctx context.Context) {
+	m.reconcileAndLog(ctx)
+
+	if m.cfg.DnsUpdate.ReconcileInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.cfg.DnsUpdate.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.reconcileAndLog(ctx)
+		}
+	}
+}
+
+func (m Manager) reconcileAndLog(ctx context.Context) {
+	if err := m.reconcile(ctx); err != nil {
+		slog.Error("failed to reconcile DNS records", "error", err)
+	}
+}
+
+// handlePeerEvent updates the records of the peer. Events of different topics are not delivered in order,
+// so the current state of the peer is always loaded from the database instead of using the event payload.
+func (m Manager) handlePeerEvent(peer domain.Peer) {
+	slog.Debug("handling peer event", "peer", peer.Identifier)
+
+	if err := m.syncPeer(context.Background(), peer.Identifier); err != nil {
+		slog.Error("failed to update DNS records", "peer", peer.Identifier, "error", err)
+	}
+}
+
+func (m Manager) handleInterfaceDeletedEvent(iface domain.Interface) {
+	slog.Debug("handling interface delete event", "interface", iface.Identifier)
+
+	m.mux.Lock()
+	var ids []domain.PeerIdentifier
+	for id, state := range m.peers {
+		if state.iface == iface.Identifier {
+			ids = append(ids, id)
+		}
+	}
+	m.mux.Unlock()
+
+	for _, id := range ids {
+		if err := m.syncPeer(context.Background(), id); err != nil {
+			slog.Error("failed to remove DNS records", "peer", id, "error", err)
+		}
+	}
+}
+
+// syncPeer sends the changes of the records of the peer since the last update.
+func (m Manager) syncPeer(ctx context.Context, id domain.PeerIdentifier) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	var desired peerRecords
+	peer, err := m.db.GetPeer(ctx, id)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		// the peer was deleted, all of its records are removed
+	case err != nil:
+		return fmt.Errorf("failed to load peer: %w", err)
+	default:
+		desired = peerRecords{iface: peer.InterfaceIdentifier, records: m.peerRecords(peer)}
+	}
+
+	if err := m.apply(ctx, m.peers[id].records, desired.records); err != nil {
+		return err
+	}
+
+	if len(desired.records) == 0 {
+		delete(m.peers, id)
+	} else {
+		m.peers[id] = desired
+	}
+
+	return nil
+}
+
+// reconcile compares the managed records in the zones with the records of all peers and fixes all differences,
+// for example changes that were missed while WireGuard Portal was not running.
+func (m Manager) reconcile(ctx context.Context) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	desired := make(map[domain.PeerIdentifier]peerRecords)
+	interfaces, err := m.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interfaces: %w", err)
+	}
+	for _, iface := range interfaces {
+		peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+		if err != nil {
+			return fmt.Errorf("failed to load peers of interface %s: %w", iface.Identifier, err)
+		}
+		for i := range peers {
+			if records := m.peerRecords(&peers[i]); len(records) > 0 {
+				desired[peers[i].Identifier] = peerRecords{iface: iface.Identifier, records: records}
+			}
+		}
+	}
+
+	var desiredRecords []domain.DnsRecord
+	desiredNames := make(map[string]struct{})
+	for _, state := range desired {
+		desiredRecords = append(desiredRecords, state.records...)
+		for _, record := range state.records {
+			if record.Type != domain.DnsRecordTypePTR {
+				desiredNames[record.Name] = struct{}{}
+			}
+		}
+	}
+
+	current, err := m.managedRecords(ctx, desiredNames)
+	if err != nil {
+		return err
+	}
+
+	if err := m.apply(ctx, current, desiredRecords); err != nil {
+		return err
+	}
+	m.peers = desired
+
+	return nil
+}
+
+// managedRecords loads the records of all names that are marked as managed by this instance, and the PTR records
+// that point to these names or to the given names.
+func (m Manager) managedRecords(ctx context.Context, names map[string]struct{}) ([]domain.DnsRecord, error) {
+	zoneRecords, err := m.dns.Transfer(ctx, m.zone)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make(map[string]struct{})
+	for _, record := range zoneRecords {
+		if record.Type == domain.DnsRecordTypeTXT && strings.HasPrefix(record.Value, m.ownerPrefix()) {
+			owned[record.Name] = struct{}{}
+			names[record.Name] = struct{}{}
+		}
+	}
+
+	var managed []domain.DnsRecord
+	for _, record := range zoneRecords {
+		if _, ok := owned[record.Name]; !ok {
+			continue
+		}
+		switch record.Type {
+		case domain.DnsRecordTypeA, domain.DnsRecordTypeAAAA:
+			managed = append(managed, record)
+		case domain.DnsRecordTypeTXT:
+			if strings.HasPrefix(record.Value, m.ownerPrefix()) {
+				managed = append(managed, record)
+			}
+		}
+	}
+
+	for _, zone := range m.reverseZones {
+		zoneRecords, err := m.dns.Transfer(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range zoneRecords {
+			if _, ok := names[record.Value]; ok && record.Type == domain.DnsRecordTypePTR {
+				managed = append(managed, record)
+			}
+		}
+	}
+
+	return managed, nil
+}
+
+// apply sends the difference between the current and the desired records, one update per zone.
+func (m Manager) apply(ctx context.Context, current, desired []domain.DnsRecord) error {
+	updates := make(map[string]*domain.DnsUpdate)
+	update := func(record domain.DnsRecord) *domain.DnsUpdate {
+		zone := m.zoneOf(record)
+		if updates[zone] == nil {
+			updates[zone] = &domain.DnsUpdate{Zone: zone}
+		}
+		return updates[zone]
+	}
+
+	currentKeys := make(map[string]struct{}, len(current))
+	for _, record := range current {
+		currentKeys[record.Key()] = struct{}{}
+	}
+	desiredKeys := make(map[string]struct{}, len(desired))
+	for _, record := range desired {
+		desiredKeys[record.Key()] = struct{}{}
+		if _, ok := currentKeys[record.Key()]; !ok {
+			u := update(record)
+			u.Adds = append(u.Adds, record)
+		}
+	}
+	for _, record := range current {
+		if _, ok := desiredKeys[record.Key()]; !ok {
+			u := update(record)
+			u.Deletes = append(u.Deletes, record)
+		}
+	}
+
+	zones := make([]string, 0, len(updates))
+	for zone := range updates {
+		zones = append(zones, zone)
+	}
+	slices.Sort(zones)
+
+	for _, zone := range zones {
+		if err := m.dns.Update(ctx, *updates[zone]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// peerRecords returns the records of the peer. Disabled peers and peers without a usable name have no records.
+func (m Manager) peerRecords(peer *domain.Peer) []domain.DnsRecord {
+	if peer.IsDisabled() {
+		return nil
+	}
+
+	data := nameTemplateData{
+		Peer:      domain.DnsLabel(peer.DisplayName),
+		User:      domain.DnsLabel(string(peer.UserIdentifier)),
+		Interface: domain.DnsLabel(string(peer.InterfaceIdentifier)),
+	}
+	if data.Peer == "" {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := m.nameTemplate.Execute(&buf, data); err != nil {
+		slog.Error("failed to render DNS name", "peer", peer.Identifier, "error", err)
+		return nil
+	}
+	relative := strings.ToLower(strings.Trim(strings.TrimSpace(buf.String()), "."))
+	if relative == "" || strings.Contains(relative, "..") {
+		slog.Warn("skipping peer with invalid DNS name", "peer", peer.Identifier, "name", buf.String())
+		return nil
+	}
+	name := relative + "." + m.zone
+
+	ttl := uint32(m.cfg.DnsUpdate.Ttl.Seconds())
+	records := []domain.DnsRecord{{
+		Name:  name,
+		Type:  domain.DnsRecordTypeTXT,
+		Value: m.ownerPrefix() + "peer=" + string(peer.Identifier),
+		Ttl:   ttl,
+	}}
+	for _, cidr := range peer.Interface.Addresses {
+		addr, err := netip.ParseAddr(cidr.Addr)
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap()
+
+		record := domain.DnsRecord{Name: name, Type: domain.DnsRecordTypeAAAA, Value: addr.String(), Ttl: ttl}
+		if addr.Is4() {
+			record.Type = domain.DnsRecordTypeA
+		}
+		records = append(records, record)
+
+		ptr := domain.DnsRecord{Name: domain.ReverseDnsName(addr), Type: domain.DnsRecordTypePTR, Value: name, Ttl: ttl}
+		if m.zoneOf(ptr) != "" {
+			records = append(records, ptr)
+		}
+	}
+
+	return records
+}
+
+// zoneOf returns the zone of the record. PTR records belong to the most specific reverse zone, or to no zone.
+func (m Manager) zoneOf(record domain.DnsRecord) string {
+	if record.Type != domain.DnsRecordTypePTR {
+		return m.zone
+	}
+
+	zone := ""
+	for _, reverseZone := range m.reverseZones {
+		if strings.HasSuffix(record.Name, "."+reverseZone) && len(reverseZone) > len(zone) {
+			zone = reverseZone
+		}
+	}
+
+	return zone
+}
+
+func (m Manager) ownerPrefix() string {
+	return ownerMarker + m.cfg.DnsUpdate.Owner + ","
+}
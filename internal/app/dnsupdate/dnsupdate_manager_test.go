@@ -0,0 +1,205 @@
+package dnsupdate
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockDatabase struct {
+	peers map[domain.PeerIdentifier]*domain.Peer
+}
+
+func (m *mockDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return []domain.Interface{{Identifier: "wg0"}}, nil
+}
+
+func (m *mockDatabase) GetInterfacePeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	peers := make([]domain.Peer, 0, len(m.peers))
+	for _, p := range m.peers {
+		peers = append(peers, *p)
+	}
+	return peers, nil
+}
+
+func (m *mockDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	if peer, ok := m.peers[id]; ok {
+		return peer, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+type mockBus struct{}
+
+func (f *mockBus) Subscribe(_ string, _ interface{}) error { return nil }
+
+// mockDnsServer stores the records of all zones and applies updates like a primary DNS server.
+type mockDnsServer struct {
+	zones   map[string][]domain.DnsRecord
+	updates []domain.DnsUpdate
+}
+
+func (m *mockDnsServer) Update(_ context.Context, update domain.DnsUpdate) error {
+	m.updates = append(m.updates, update)
+	for _, deleted := range update.Deletes {
+		m.zones[update.Zone] = slices.DeleteFunc(m.zones[update.Zone], func(r domain.DnsRecord) bool {
+			return r.Key() == deleted.Key()
+		})
+	}
+	for _, added := range update.Adds {
+		exists := slices.ContainsFunc(m.zones[update.Zone], func(r domain.DnsRecord) bool {
+			return r.Key() == added.Key()
+		})
+		if !exists { // adding an existing record is ignored, as specified by RFC 2136
+			m.zones[update.Zone] = append(m.zones[update.Zone], added)
+		}
+	}
+	return nil
+}
+
+func (m *mockDnsServer) Transfer(_ context.Context, zone string) ([]domain.DnsRecord, error) {
+	return slices.Clone(m.zones[zone]), nil
+}
+
+func (m *mockDnsServer) keys(zone string) []string {
+	var keys []string
+	for _, record := range m.zones[zone] {
+		keys = append(keys, record.Key())
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func newTestManager(t *testing.T) (*Manager, *mockDatabase, *mockDnsServer) {
+	addresses, err := domain.CidrsFromArray([]string{"10.0.0.2/32", "fd00::2/128"})
+	require.NoError(t, err)
+
+	db := &mockDatabase{peers: map[domain.PeerIdentifier]*domain.Peer{
+		"peer1": {
+			Identifier:          "peer1",
+			DisplayName:         "Laptop",
+			UserIdentifier:      "alice",
+			InterfaceIdentifier: "wg0",
+			Interface:           domain.PeerInterfaceConfig{Addresses: addresses},
+		},
+	}}
+	server := &mockDnsServer{zones: map[string][]domain.DnsRecord{}}
+
+	cfg := &config.Config{}
+	cfg.DnsUpdate.Server = "ns1.example.com"
+	cfg.DnsUpdate.Zone = "VPN.example.com"
+	cfg.DnsUpdate.ReverseZones = []string{"in-addr.arpa", "0.0.10.in-addr.arpa"}
+	cfg.DnsUpdate.NameTemplate = "{{.Peer}}{{if .User}}.{{.User}}{{end}}"
+	cfg.DnsUpdate.Owner = "test"
+	cfg.DnsUpdate.Ttl = time.Minute
+
+	m, err := NewDnsUpdateManager(cfg, &mockBus{}, db, server)
+	require.NoError(t, err)
+
+	return m, db, server
+}
+
+func TestNewDnsUpdateManager_InvalidConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DnsUpdate.NameTemplate = "{{.Peer"
+	_, err := NewDnsUpdateManager(cfg, &mockBus{}, &mockDatabase{}, &mockDnsServer{})
+	assert.Error(t, err)
+
+	cfg.DnsUpdate.NameTemplate = "{{.Peer}}"
+	cfg.DnsUpdate.Server = "ns1.example.com"
+	_, err = NewDnsUpdateManager(cfg, &mockBus{}, &mockDatabase{}, &mockDnsServer{})
+	assert.Error(t, err, "zone is required")
+}
+
+func TestManager_HandlePeerEvent(t *testing.T) {
+	m, db, server := newTestManager(t)
+
+	m.handlePeerEvent(*db.peers["peer1"])
+	assert.Equal(t, []string{
+		"laptop.alice.vpn.example.com. A 10.0.0.2",
+		"laptop.alice.vpn.example.com. AAAA fd00::2",
+		"laptop.alice.vpn.example.com. TXT heritage=wg-portal,owner=test,peer=peer1",
+	}, server.keys("vpn.example.com."))
+	assert.Equal(t, []string{"2.0.0.10.in-addr.arpa. PTR laptop.alice.vpn.example.com."},
+		server.keys("0.0.10.in-addr.arpa."), "the most specific reverse zone is used")
+	assert.Empty(t, server.keys("in-addr.arpa."))
+	assert.Equal(t, uint32(60), server.zones["vpn.example.com."][0].Ttl)
+
+	server.updates = nil
+	m.handlePeerEvent(*db.peers["peer1"])
+	assert.Empty(t, server.updates, "unchanged peers do not cause updates")
+
+	db.peers["peer1"].DisplayName = "Desktop"
+	m.handlePeerEvent(*db.peers["peer1"])
+	assert.Equal(t, []string{
+		"desktop.alice.vpn.example.com. A 10.0.0.2",
+		"desktop.alice.vpn.example.com. AAAA fd00::2",
+		"desktop.alice.vpn.example.com. TXT heritage=wg-portal,owner=test,peer=peer1",
+	}, server.keys("vpn.example.com."), "renamed peers replace their records")
+	assert.Equal(t, []string{"2.0.0.10.in-addr.arpa. PTR desktop.alice.vpn.example.com."},
+		server.keys("0.0.10.in-addr.arpa."))
+
+	now := time.Now()
+	db.peers["peer1"].Disabled = &now
+	m.handlePeerEvent(*db.peers["peer1"])
+	assert.Empty(t, server.keys("vpn.example.com."), "disabled peers have no records")
+
+	db.peers["peer1"].Disabled = nil
+	m.handlePeerEvent(*db.peers["peer1"])
+	require.NotEmpty(t, server.keys("vpn.example.com."))
+	delete(db.peers, "peer1")
+	m.handleInterfaceDeletedEvent(domain.Interface{Identifier: "wg0"})
+	assert.Empty(t, server.keys("vpn.example.com."), "peers of deleted interfaces are removed")
+	assert.Empty(t, server.keys("0.0.10.in-addr.arpa."))
+}
+
+func TestManager_Reconcile(t *testing.T) {
+	m, _, server := newTestManager(t)
+
+	server.zones["vpn.example.com."] = []domain.DnsRecord{
+		// foreign records are never touched
+		{Name: "www.vpn.example.com.", Type: domain.DnsRecordTypeA, Value: "192.0.2.1"},
+		{Name: "www.vpn.example.com.", Type: domain.DnsRecordTypeTXT, Value: "v=spf1 -all"},
+		// stale records of a peer that was renamed while WireGuard Portal was not running
+		{Name: "old.alice.vpn.example.com.", Type: domain.DnsRecordTypeA, Value: "10.0.0.2"},
+		{Name: "old.alice.vpn.example.com.", Type: domain.DnsRecordTypeTXT,
+			Value: "heritage=wg-portal,owner=test,peer=peer1"},
+		// records of another instance
+		{Name: "other.vpn.example.com.", Type: domain.DnsRecordTypeA, Value: "10.0.0.9"},
+		{Name: "other.vpn.example.com.", Type: domain.DnsRecordTypeTXT,
+			Value: "heritage=wg-portal,owner=other,peer=peer9"},
+		// existing records of the peer are kept
+		{Name: "laptop.alice.vpn.example.com.", Type: domain.DnsRecordTypeA, Value: "10.0.0.2"},
+	}
+	server.zones["0.0.10.in-addr.arpa."] = []domain.DnsRecord{
+		{Name: "2.0.0.10.in-addr.arpa.", Type: domain.DnsRecordTypePTR, Value: "old.alice.vpn.example.com."},
+		{Name: "9.0.0.10.in-addr.arpa.", Type: domain.DnsRecordTypePTR, Value: "other.vpn.example.com."},
+	}
+
+	require.NoError(t, m.reconcile(context.Background()))
+
+	assert.Equal(t, []string{
+		"laptop.alice.vpn.example.com. A 10.0.0.2",
+		"laptop.alice.vpn.example.com. AAAA fd00::2",
+		"laptop.alice.vpn.example.com. TXT heritage=wg-portal,owner=test,peer=peer1",
+		"other.vpn.example.com. A 10.0.0.9",
+		"other.vpn.example.com. TXT heritage=wg-portal,owner=other,peer=peer9",
+		"www.vpn.example.com. A 192.0.2.1",
+		"www.vpn.example.com. TXT v=spf1 -all",
+	}, server.keys("vpn.example.com."))
+	assert.Equal(t, []string{
+		"2.0.0.10.in-addr.arpa. PTR laptop.alice.vpn.example.com.",
+		"9.0.0.10.in-addr.arpa. PTR other.vpn.example.com.",
+	}, server.keys("0.0.10.in-addr.arpa."))
+
+	server.updates = nil
+	require.NoError(t, m.reconcile(context.Background()))
+	assert.Empty(t, server.updates, "a second reconciliation does not change anything")
+}
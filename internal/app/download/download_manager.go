@@ -0,0 +1,345 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetDownloadToken returns the download token with the given identifier.
+	GetDownloadToken(ctx context.Context, id domain.DownloadTokenIdentifier) (*domain.DownloadToken, error)
+	// GetAllDownloadTokens returns all download tokens.
+	GetAllDownloadTokens(ctx context.Context) ([]domain.DownloadToken, error)
+	// GetPeerDownloadTokens returns all download tokens of the given peer.
+	GetPeerDownloadTokens(ctx context.Context, id domain.PeerIdentifier) ([]domain.DownloadToken, error)
+	// SaveDownloadToken updates the download token with the given identifier in a single transaction.
+	SaveDownloadToken(
+		ctx context.Context,
+		id domain.DownloadTokenIdentifier,
+		updateFunc func(in *domain.DownloadToken) (*domain.DownloadToken, error),
+	) error
+	// GetPeer returns the peer with the given identifier.
+	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+}
+
+type ConfigFileManager interface {
+	// GetPeerConfigFiles returns all configuration files for the given peer.
+	GetPeerConfigFiles(ctx context.Context, id domain.PeerIdentifier, style string) ([]domain.ConfigFile, error)
+	// GetPeerConfigQrCode returns the QR code for the given peer.
+	GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error)
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+}
+
+// endregion dependencies
+
+// Manager issues and redeems download tokens. A token is handed out as a signed string, so that only tokens
+// that have been issued by this instance are looked up in the database.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db          DatabaseRepo
+	configFiles ConfigFileManager
+
+	signingKey []byte
+	mux        *sync.Mutex // serializes downloads, so that the use counter can not be exceeded
+}
+
+// NewDownloadManager creates a new download token manager.
+func NewDownloadManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	configFiles ConfigFileManager,
+) (*Manager, error) {
+	if cfg.Web.SessionSecret == "" {
+		return nil, errors.New("a session secret is required to sign download tokens")
+	}
+
+	keyHash := sha256.Sum256([]byte("download-token:" + cfg.Web.SessionSecret))
+
+	return &Manager{
+		cfg:         cfg,
+		bus:         bus,
+		db:          db,
+		configFiles: configFiles,
+		signingKey:  keyHash[:],
+		mux:         &sync.Mutex{},
+	}, nil
+}
+
+// CreateDownloadToken issues a new download token for the given peer. The returned string is the signed token
+// that has to be passed to Download. It is not stored and can not be recovered later.
+func (m Manager) CreateDownloadToken(
+	ctx context.Context,
+	id domain.PeerIdentifier,
+	opts domain.DownloadTokenOptions,
+) (*domain.DownloadToken, string, error) {
+	peer, err := m.db.GetPeer(ctx, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to find peer %s: %w", id, err)
+	}
+
+	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+		return nil, "", err
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	tokenId := domain.DownloadTokenIdentifier(uuid.New().String())
+	var token *domain.DownloadToken
+	err = m.db.SaveDownloadToken(ctx, tokenId, func(t *domain.DownloadToken) (*domain.DownloadToken, error) {
+		t.PeerIdentifier = peer.Identifier
+		t.Type = opts.Type
+		t.Style = opts.Style
+		t.MaxUses = opts.MaxUses
+		t.ExpiresAt = time.Now().Add(opts.TTL)
+		token = t
+		return t, nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to save download token: %w", err)
+	}
+
+	m.publishAudit(ctx, *token, "create", "", nil)
+
+	return token, m.sign(tokenId), nil
+}
+
+// GetActiveDownloadTokens returns all download tokens that can still be used.
+func (m Manager) GetActiveDownloadTokens(ctx context.Context) ([]domain.DownloadToken, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	tokens, err := m.db.GetAllDownloadTokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load download tokens: %w", err)
+	}
+
+	now := time.Now()
+	active := make([]domain.DownloadToken, 0, len(tokens))
+	for _, token := range tokens {
+		if token.IsActive(now) {
+			active = append(active, token)
+		}
+	}
+
+	return active, nil
+}
+
+// GetPeerDownloadTokens returns all download tokens of the given peer, including used and expired ones.
+func (m Manager) GetPeerDownloadTokens(ctx context.Context, id domain.PeerIdentifier) (
+	[]domain.DownloadToken,
+	error,
+) {
+	peer, err := m.db.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find peer %s: %w", id, err)
+	}
+
+	if err := domain.ValidateUserAccessRights(ctx, peer.UserIdentifier); err != nil {
+		return nil, err
+	}
+
+	tokens, err := m.db.GetPeerDownloadTokens(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load download tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeDownloadToken invalidates the given download token.
+func (m Manager) RevokeDownloadToken(ctx context.Context, id domain.DownloadTokenIdentifier) (
+	*domain.DownloadToken,
+	error,
+) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	var token *domain.DownloadToken
+	err := m.db.SaveDownloadToken(ctx, id, func(t *domain.DownloadToken) (*domain.DownloadToken, error) {
+		if t.PeerIdentifier == "" {
+			return nil, domain.ErrNotFound
+		}
+		if !t.IsRevoked() {
+			t.Revoke(domain.GetUserInfo(ctx).Id)
+		}
+		token = t
+		return t, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke download token %s: %w", id, err)
+	}
+
+	m.publishAudit(ctx, *token, "revoke", "", nil)
+
+	return token, nil
+}
+
+// Download redeems the given signed token and returns the file name and content of the peer configuration.
+// Every attempt with a valid signature is recorded in the audit log, including the client address.
+func (m Manager) Download(ctx context.Context, signedToken, client string) (string, io.Reader, error) {
+	id, err := m.verify(signedToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	token, err := m.db.GetDownloadToken(ctx, id)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to load download token: %w", err)
+	}
+
+	filename, data, err := m.redeem(ctx, token)
+	if err != nil {
+		m.publishAudit(ctx, *token, "download", client, err)
+		return "", nil, err
+	}
+
+	m.publishAudit(ctx, *token, "download", client, nil)
+
+	return filename, data, nil
+}
+
+func (m Manager) redeem(ctx context.Context, token *domain.DownloadToken) (string, io.Reader, error) {
+	if !token.IsActive(time.Now()) {
+		// use the domain validation to produce a meaningful error
+		return "", nil, token.Use(time.Now())
+	}
+
+	// the token grants access to the peer, independent of the (anonymous) caller
+	sysCtx := domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	peer, err := m.db.GetPeer(sysCtx, token.PeerIdentifier)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to find peer %s: %w", token.PeerIdentifier, err)
+	}
+
+	var data io.Reader
+	filename := peer.GetConfigFileName()
+	switch token.Type {
+	case domain.DownloadTypeQrCode:
+		data, err = m.configFiles.GetPeerConfigQrCode(sysCtx, peer.Identifier, token.Style)
+		filename = strings.TrimSuffix(filename, ".conf") + ".png"
+	default:
+		var files []domain.ConfigFile
+		files, err = m.configFiles.GetPeerConfigFiles(sysCtx, peer.Identifier, token.Style)
+		if err == nil {
+			filename, data = joinConfigFiles(files, filename)
+		}
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to render configuration of %s: %w", peer.Identifier, err)
+	}
+
+	err = m.db.SaveDownloadToken(sysCtx, token.Identifier,
+		func(t *domain.DownloadToken) (*domain.DownloadToken, error) {
+			if err := t.Use(time.Now()); err != nil {
+				return nil, err
+			}
+			*token = *t
+			return t, nil
+		})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return filename, data, nil
+}
+
+// joinConfigFiles returns the single configuration file of a style, or all files in one text document
+// for styles that consist of multiple files.
+func joinConfigFiles(files []domain.ConfigFile, defaultName string) (string, io.Reader) {
+	if len(files) == 1 {
+		return files[0].Name, bytes.NewReader(files[0].Data)
+	}
+
+	buf := bytes.Buffer{}
+	for i, file := range files {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.Write(file.Data)
+	}
+
+	return strings.TrimSuffix(defaultName, ".conf") + ".txt", &buf
+}
+
+func (m Manager) publishAudit(ctx context.Context, token domain.DownloadToken, action, client string, err error) {
+	event := audit.DownloadEvent{
+		Token:  token,
+		Action: action,
+		Client: client,
+	}
+	if err != nil {
+		event.Error = err.Error()
+		slog.Debug("download token rejected", "token", token.Identifier, "client", client, "error", err)
+	}
+
+	m.bus.Publish(app.TopicAuditDownload, domain.AuditEventWrapper[audit.DownloadEvent]{
+		Ctx:    ctx,
+		Source: client,
+		Event:  event,
+	})
+}
+
+// sign returns the token identifier together with its signature, in the form <id>.<signature>.
+func (m Manager) sign(id domain.DownloadTokenIdentifier) string {
+	return string(id) + "." + base64.RawURLEncoding.EncodeToString(m.signature(id))
+}
+
+// verify checks the signature of the given token and returns the token identifier.
+func (m Manager) verify(signedToken string) (domain.DownloadTokenIdentifier, error) {
+	rawId, rawSignature, found := strings.Cut(signedToken, ".")
+	if !found || rawId == "" {
+		return "", fmt.Errorf("malformed download token: %w", domain.ErrInvalidData)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(rawSignature)
+	if err != nil {
+		return "", fmt.Errorf("malformed download token signature: %w", domain.ErrInvalidData)
+	}
+
+	id := domain.DownloadTokenIdentifier(rawId)
+	if !hmac.Equal(signature, m.signature(id)) {
+		return "", fmt.Errorf("invalid download token signature: %w", domain.ErrNoPermission)
+	}
+
+	return id, nil
+}
+
+func (m Manager) signature(id domain.DownloadTokenIdentifier) []byte {
+	mac := hmac.New(sha256.New, m.signingKey)
+	mac.Write([]byte(id))
+	return mac.Sum(nil)
+}
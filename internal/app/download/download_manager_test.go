@@ -0,0 +1,253 @@
+package download
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockDatabase struct {
+	tokens map[domain.DownloadTokenIdentifier]*domain.DownloadToken
+	peers  map[domain.PeerIdentifier]*domain.Peer
+}
+
+func (m *mockDatabase) GetDownloadToken(_ context.Context, id domain.DownloadTokenIdentifier) (
+	*domain.DownloadToken,
+	error,
+) {
+	t, ok := m.tokens[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	cpy := *t
+	return &cpy, nil
+}
+
+func (m *mockDatabase) GetAllDownloadTokens(_ context.Context) ([]domain.DownloadToken, error) {
+	tokens := make([]domain.DownloadToken, 0, len(m.tokens))
+	for _, t := range m.tokens {
+		tokens = append(tokens, *t)
+	}
+	return tokens, nil
+}
+
+func (m *mockDatabase) GetPeerDownloadTokens(_ context.Context, id domain.PeerIdentifier) (
+	[]domain.DownloadToken,
+	error,
+) {
+	tokens := make([]domain.DownloadToken, 0, len(m.tokens))
+	for _, t := range m.tokens {
+		if t.PeerIdentifier == id {
+			tokens = append(tokens, *t)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *mockDatabase) SaveDownloadToken(
+	_ context.Context,
+	id domain.DownloadTokenIdentifier,
+	updateFunc func(in *domain.DownloadToken) (*domain.DownloadToken, error),
+) error {
+	token := domain.DownloadToken{Identifier: id}
+	if existing, ok := m.tokens[id]; ok {
+		token = *existing
+	}
+
+	updated, err := updateFunc(&token)
+	if err != nil {
+		return err
+	}
+	cpy := *updated
+	m.tokens[id] = &cpy
+	return nil
+}
+
+func (m *mockDatabase) GetPeer(_ context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	p, ok := m.peers[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return p, nil
+}
+
+type mockConfigFiles struct{}
+
+func (m mockConfigFiles) GetPeerConfigFiles(ctx context.Context, id domain.PeerIdentifier, style string) (
+	[]domain.ConfigFile,
+	error,
+) {
+	if !domain.GetUserInfo(ctx).IsAdmin {
+		return nil, domain.ErrNoPermission
+	}
+	if style == domain.ConfigStyleNetworkd {
+		return []domain.ConfigFile{
+			{Name: "Laptop.netdev", Data: []byte("netdev")},
+			{Name: "Laptop.network", Data: []byte("network")},
+		}, nil
+	}
+	return []domain.ConfigFile{{Name: "Laptop.conf", Data: []byte("config of " + string(id) + " in " + style)}}, nil
+}
+
+func (m mockConfigFiles) GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier, _ string) (
+	io.Reader,
+	error,
+) {
+	if !domain.GetUserInfo(ctx).IsAdmin {
+		return nil, domain.ErrNoPermission
+	}
+	return strings.NewReader("qr of " + string(id)), nil
+}
+
+type mockBus struct {
+	events []audit.DownloadEvent
+}
+
+func (m *mockBus) Publish(topic string, args ...any) {
+	if topic != app.TopicAuditDownload {
+		return
+	}
+	m.events = append(m.events, args[0].(domain.AuditEventWrapper[audit.DownloadEvent]).Event)
+}
+
+func newTestManager(t *testing.T) (*Manager, *mockDatabase, *mockBus) {
+	db := &mockDatabase{
+		tokens: map[domain.DownloadTokenIdentifier]*domain.DownloadToken{},
+		peers: map[domain.PeerIdentifier]*domain.Peer{
+			"peer-a": {Identifier: "peer-a", UserIdentifier: "alice", DisplayName: "Laptop"},
+		},
+	}
+	bus := &mockBus{}
+
+	cfg := &config.Config{}
+	cfg.Web.SessionSecret = "secret"
+	m, err := NewDownloadManager(cfg, bus, db, mockConfigFiles{})
+	require.NoError(t, err)
+
+	return m, db, bus
+}
+
+func userCtx(id domain.UserIdentifier, admin bool) context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: id, IsAdmin: admin})
+}
+
+func TestManager_CreateDownloadToken(t *testing.T) {
+	m, db, bus := newTestManager(t)
+
+	_, _, err := m.CreateDownloadToken(userCtx("bob", false), "peer-a", domain.DownloadTokenOptions{})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	_, _, err = m.CreateDownloadToken(userCtx("alice", false), "peer-a", domain.DownloadTokenOptions{MaxUses: -1})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	token, signed, err := m.CreateDownloadToken(userCtx("alice", false), "peer-a", domain.DownloadTokenOptions{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(signed, string(token.Identifier)+"."))
+	assert.Equal(t, 1, token.MaxUses)
+	assert.Contains(t, db.tokens, token.Identifier)
+	require.Len(t, bus.events, 1)
+	assert.Equal(t, "create", bus.events[0].Action)
+}
+
+func TestManager_Download(t *testing.T) {
+	m, _, bus := newTestManager(t)
+
+	_, signed, err := m.CreateDownloadToken(userCtx("alice", false), "peer-a", domain.DownloadTokenOptions{})
+	require.NoError(t, err)
+
+	filename, data, err := m.Download(context.Background(), signed, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, "Laptop.conf", filename)
+	content, _ := io.ReadAll(data)
+	assert.Equal(t, "config of peer-a in wgquick", string(content))
+
+	_, _, err = m.Download(context.Background(), signed, "192.0.2.1")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	require.Len(t, bus.events, 3)
+	assert.Equal(t, "192.0.2.1", bus.events[1].Client)
+	assert.Empty(t, bus.events[1].Error)
+	assert.NotEmpty(t, bus.events[2].Error)
+}
+
+func TestManager_Download_QrCode(t *testing.T) {
+	m, _, _ := newTestManager(t)
+
+	_, signed, err := m.CreateDownloadToken(userCtx("admin", true), "peer-a",
+		domain.DownloadTokenOptions{Type: domain.DownloadTypeQrCode})
+	require.NoError(t, err)
+
+	filename, _, err := m.Download(context.Background(), signed, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Laptop.png", filename)
+}
+
+func TestManager_Download_MultipleFiles(t *testing.T) {
+	m, _, _ := newTestManager(t)
+
+	_, signed, err := m.CreateDownloadToken(userCtx("admin", true), "peer-a",
+		domain.DownloadTokenOptions{Style: domain.ConfigStyleNetworkd})
+	require.NoError(t, err)
+
+	filename, data, err := m.Download(context.Background(), signed, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Laptop.txt", filename)
+	content, _ := io.ReadAll(data)
+	assert.Equal(t, "netdev\nnetwork", string(content))
+}
+
+func TestManager_Download_InvalidSignature(t *testing.T) {
+	m, _, bus := newTestManager(t)
+
+	token, signed, err := m.CreateDownloadToken(userCtx("alice", false), "peer-a", domain.DownloadTokenOptions{})
+	require.NoError(t, err)
+
+	_, _, err = m.Download(context.Background(), string(token.Identifier)+".AAAA", "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	_, _, err = m.Download(context.Background(), string(token.Identifier), "")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, _, err = m.Download(context.Background(), signed+"x", "")
+	assert.Error(t, err)
+
+	assert.Len(t, bus.events, 1) // forged tokens are not looked up
+}
+
+func TestManager_RevokeDownloadToken(t *testing.T) {
+	m, _, _ := newTestManager(t)
+
+	token, signed, err := m.CreateDownloadToken(userCtx("alice", false), "peer-a",
+		domain.DownloadTokenOptions{MaxUses: 5})
+	require.NoError(t, err)
+
+	_, err = m.RevokeDownloadToken(userCtx("alice", false), token.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	active, err := m.GetActiveDownloadTokens(userCtx("admin", true))
+	require.NoError(t, err)
+	assert.Len(t, active, 1)
+
+	revoked, err := m.RevokeDownloadToken(userCtx("admin", true), token.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", revoked.RevokedBy)
+
+	_, _, err = m.Download(context.Background(), signed, "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	active, err = m.GetActiveDownloadTokens(userCtx("admin", true))
+	require.NoError(t, err)
+	assert.Empty(t, active)
+
+	_, err = m.RevokeDownloadToken(userCtx("admin", true), "unknown")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
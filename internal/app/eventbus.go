@@ -27,6 +27,8 @@ const TopicInterfaceCreated = "interface:created"
 const TopicInterfaceUpdated = "interface:updated"
 const TopicInterfaceDeleted = "interface:deleted"
 const TopicInterfaceStatsUpdated = "interface:stats:updated"
+const TopicInterfaceStateRestored = "interface:state:restored"
+const TopicInterfaceMaintenanceUpcoming = "interface:maintenance:upcoming"
 
 // endregion interface-events
 
@@ -34,6 +36,7 @@ const TopicInterfaceStatsUpdated = "interface:stats:updated"
 
 const TopicPeerCreated = "peer:created"
 const TopicPeerDeleted = "peer:deleted"
+const TopicPeerPurged = "peer:purged"
 const TopicPeerUpdated = "peer:updated"
 const TopicPeerInterfaceUpdated = "peer:interface:updated"
 const TopicPeerIdentifierUpdated = "peer:identifier:updated"
@@ -42,6 +45,13 @@ const TopicPeerStatsUpdated = "peer:stats:updated"
 
 // endregion peer-events
 
+// region peer-request-events
+
+const TopicPeerRequestCreated = "peer:request:created"
+const TopicPeerRequestUpdated = "peer:request:updated"
+
+// endregion peer-request-events
+
 // region audit-events
 
 const TopicAuditLoginSuccess = "audit:login:success"
@@ -49,5 +59,6 @@ const TopicAuditLoginFailed = "audit:login:failed"
 
 const TopicAuditInterfaceChanged = "audit:interface:changed"
 const TopicAuditPeerChanged = "audit:peer:changed"
+const TopicAuditDownload = "audit:download"
 
 // endregion audit-events
@@ -0,0 +1,243 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type ControllerManager interface {
+	// GetController returns the controller for the given interface.
+	GetController(iface domain.Interface) domain.InterfaceController
+}
+
+type InterfaceAndPeerDatabaseRepo interface {
+	// GetInterfaceAndPeers returns the interface and all peers associated with it.
+	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
+	// GetUserPeers returns all peers of the given user.
+	GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error)
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
+type AccessRulesController interface {
+	// SetAccessRules replaces the forwarding rules of the given interface atomically.
+	SetAccessRules(ctx context.Context, rules domain.InterfaceAccessRules) error
+	// RemoveAccessRules removes all forwarding rules of the given interface. If no rules exist, the function is a no-op.
+	RemoveAccessRules(ctx context.Context, id domain.InterfaceIdentifier) error
+}
+
+type NatRulesController interface {
+	// SetNatRules replaces the masquerading and forwarding rules of the given interface atomically.
+	SetNatRules(ctx context.Context, rules domain.InterfaceNatRules) error
+	// RemoveNatRules removes all masquerading and forwarding rules of the given interface.
+	// If no rules exist, the function is a no-op.
+	RemoveNatRules(ctx context.Context, id domain.InterfaceIdentifier) error
+}
+
+// endregion dependencies
+
+// Manager keeps the peer isolation and access control rules of all interfaces in sync with the
+// backend controllers. Rules are only enforced by controllers that implement AccessRulesController.
+// The NAT policies of the interfaces are applied the same way by controllers that implement NatRulesController.
+type Manager struct {
+	cfg *config.Config
+
+	bus          EventBus
+	db           InterfaceAndPeerDatabaseRepo
+	wgController ControllerManager
+
+	mux *sync.Mutex
+}
+
+// NewFirewallManager creates a new firewall manager instance.
+func NewFirewallManager(
+	cfg *config.Config,
+	bus EventBus,
+	db InterfaceAndPeerDatabaseRepo,
+	wgController ControllerManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:           db,
+		wgController: wgController,
+		mux:          &sync.Mutex{},
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceSavedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceSavedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceDeletedEvent)
+	_ = m.bus.Subscribe(app.TopicPeerInterfaceUpdated, m.handlePeerInterfaceUpdatedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceStateRestored, m.handleInterfaceStateRestoredEvent)
+	_ = m.bus.Subscribe(app.TopicUserUpdated, m.handleUserUpdatedEvent)
+}
+
+// StartBackgroundJobs starts background jobs for the firewall manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(_ context.Context) {
+	// this is a no-op for now
+}
+
+func (m Manager) handleInterfaceSavedEvent(iface domain.Interface) {
+	slog.Debug("handling interface save event", "interface", iface.Identifier)
+
+	m.syncAccessRulesAndLog(iface.Identifier)
+	m.syncNatRulesAndLog(iface.Identifier)
+}
+
+func (m Manager) handlePeerInterfaceUpdatedEvent(id domain.InterfaceIdentifier) {
+	slog.Debug("handling peer interface updated event", "interface", id)
+
+	m.syncAccessRulesAndLog(id)
+}
+
+func (m Manager) handleInterfaceStateRestoredEvent(id domain.InterfaceIdentifier) {
+	slog.Debug("handling interface state restored event", "interface", id)
+
+	m.syncAccessRulesAndLog(id)
+	m.syncNatRulesAndLog(id)
+}
+
+func (m Manager) handleUserUpdatedEvent(user domain.User) {
+	peers, err := m.db.GetUserPeers(context.Background(), user.Identifier)
+	if err != nil {
+		slog.Error("failed to load user peers for access rule update", "user", user.Identifier, "error", err)
+		return
+	}
+
+	// user rules apply to all peers of the user, so every interface with a peer of the user needs an update
+	updated := make(map[domain.InterfaceIdentifier]struct{})
+	for _, peer := range peers {
+		if _, ok := updated[peer.InterfaceIdentifier]; ok {
+			continue
+		}
+		updated[peer.InterfaceIdentifier] = struct{}{}
+
+		m.syncAccessRulesAndLog(peer.InterfaceIdentifier)
+	}
+}
+
+func (m Manager) handleInterfaceDeletedEvent(iface domain.Interface) {
+	m.mux.Lock() // ensure that only one rule update is processed at a time
+	defer m.mux.Unlock()
+
+	slog.Debug("handling interface delete event", "interface", iface.Identifier)
+
+	controller := m.wgController.GetController(iface)
+
+	if ac, ok := controller.(AccessRulesController); ok {
+		if err := ac.RemoveAccessRules(context.Background(), iface.Identifier); err != nil {
+			slog.Error("failed to remove access rules", "interface", iface.Identifier, "error", err)
+		}
+	}
+
+	if nc, ok := controller.(NatRulesController); ok {
+		if err := nc.RemoveNatRules(context.Background(), iface.Identifier); err != nil {
+			slog.Error("failed to remove nat rules", "interface", iface.Identifier, "error", err)
+		}
+	}
+}
+
+func (m Manager) syncAccessRulesAndLog(id domain.InterfaceIdentifier) {
+	m.mux.Lock() // ensure that only one rule update is processed at a time
+	defer m.mux.Unlock()
+
+	if err := m.syncAccessRules(context.Background(), id); err != nil {
+		slog.Error("failed to synchronize access rules", "interface", id, "error", err)
+		return
+	}
+
+	slog.Debug("access rules synchronized", "interface", id)
+}
+
+func (m Manager) syncAccessRules(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load interface %s: %w", id, err)
+	}
+
+	rules := domain.NewInterfaceAccessRules(iface, peers)
+
+	ac, ok := m.wgController.GetController(*iface).(AccessRulesController)
+	if !ok {
+		if !rules.IsEmpty() {
+			slog.Warn("no capable access-rules-controller found for interface, rules are not enforced",
+				"interface", id)
+		}
+		return nil
+	}
+
+	if iface.IsDisabled() || rules.IsEmpty() {
+		if err := ac.RemoveAccessRules(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove access rules: %w", err)
+		}
+		return nil
+	}
+
+	if err := ac.SetAccessRules(ctx, rules); err != nil {
+		return fmt.Errorf("failed to set access rules: %w", err)
+	}
+
+	return nil
+}
+
+func (m Manager) syncNatRulesAndLog(id domain.InterfaceIdentifier) {
+	m.mux.Lock() // ensure that only one rule update is processed at a time
+	defer m.mux.Unlock()
+
+	if err := m.syncNatRules(context.Background(), id); err != nil {
+		slog.Error("failed to synchronize nat rules", "interface", id, "error", err)
+		return
+	}
+
+	slog.Debug("nat rules synchronized", "interface", id)
+}
+
+func (m Manager) syncNatRules(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, _, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load interface %s: %w", id, err)
+	}
+
+	rules := domain.NewInterfaceNatRules(iface)
+
+	nc, ok := m.wgController.GetController(*iface).(NatRulesController)
+	if !ok {
+		if !rules.IsEmpty() {
+			slog.Warn("no capable nat-rules-controller found for interface, nat policy is not applied",
+				"interface", id)
+		}
+		return nil
+	}
+
+	if iface.IsDisabled() || rules.IsEmpty() {
+		if err := nc.RemoveNatRules(ctx, id); err != nil {
+			return fmt.Errorf("failed to remove nat rules: %w", err)
+		}
+		return nil
+	}
+
+	if err := nc.SetNatRules(ctx, rules); err != nil {
+		return fmt.Errorf("failed to set nat rules: %w", err)
+	}
+
+	return nil
+}
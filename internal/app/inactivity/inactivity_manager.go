@@ -0,0 +1,284 @@
+package inactivity
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// defaultStaleAfterDays is used for the stale peer report if neither the request nor the interface
+// specify a threshold.
+const defaultStaleAfterDays = 30
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetAllInterfaces returns all interfaces.
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	// GetInterfaceAndPeers returns the interface and all peers for the given interface identifier.
+	GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, []domain.Peer, error)
+	// GetPeersStats returns the status information of the given peers.
+	GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error)
+	// SavePeer updates the peer with the given identifier without touching the WireGuard backend.
+	SavePeer(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		updateFunc func(in *domain.Peer) (*domain.Peer, error),
+	) error
+}
+
+type PeerManager interface {
+	// UpdatePeer updates the given peer.
+	UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+	// DeletePeer deletes the peer with the given identifier.
+	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
+}
+
+type Mailer interface {
+	// SendPeerInactivityEmail notifies the owner of the stale peer about the given action.
+	SendPeerInactivityEmail(ctx context.Context, stalePeer *domain.StalePeer, action domain.InactivityAction) error
+}
+
+// endregion dependencies
+
+// Manager applies the inactivity policies of all interfaces. Owners of inactive peers are warned by mail,
+// and peers are disabled or deleted once the thresholds of the policy are reached.
+type Manager struct {
+	cfg *config.Config
+
+	db    DatabaseRepo
+	peers PeerManager
+	mail  Mailer
+}
+
+// NewInactivityManager creates a new inactivity manager.
+func NewInactivityManager(cfg *config.Config, db DatabaseRepo, peers PeerManager, mail Mailer) (*Manager, error) {
+	return &Manager{
+		cfg:   cfg,
+		db:    db,
+		peers: peers,
+		mail:  mail,
+	}, nil
+}
+
+// StartBackgroundJobs starts the periodic inactivity check. This method is non-blocking.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	go m.runInactivityCheck(ctx)
+}
+
+// GetStalePeers returns all peers of the given interface that did not connect for at least minDays days.
+// If minDays is zero, the lowest threshold of the interface policy is used.
+// The result is sorted by inactivity, the longest inactive peer first.
+func (m Manager) GetStalePeers(ctx context.Context, id domain.InterfaceIdentifier, minDays int) (
+	[]domain.StalePeer,
+	error,
+) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if minDays < 0 {
+		return nil, fmt.Errorf("minimum days must not be negative: %w", domain.ErrInvalidData)
+	}
+
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load interface %s: %w", id, err)
+	}
+
+	if minDays == 0 {
+		minDays = iface.InactivityPolicy.StaleAfterDays()
+	}
+	if minDays == 0 {
+		minDays = defaultStaleAfterDays
+	}
+
+	stalePeers, err := m.evaluatePeers(ctx, iface, peers, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	stalePeers = slices.DeleteFunc(stalePeers, func(p domain.StalePeer) bool {
+		return p.InactiveDays < minDays
+	})
+	slices.SortFunc(stalePeers, func(a, b domain.StalePeer) int {
+		return a.LastActivity.Compare(b.LastActivity)
+	})
+
+	return stalePeers, nil
+}
+
+func (m Manager) runInactivityCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(m.cfg.Advanced.InactivityCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		interfaces, err := m.db.GetAllInterfaces(ctx)
+		if err != nil {
+			slog.Error("failed to fetch all interfaces for inactivity check", "error", err)
+			continue
+		}
+
+		for _, iface := range interfaces {
+			if !iface.InactivityPolicy.IsEnabled() {
+				continue
+			}
+
+			if err := m.checkInterface(ctx, iface.Identifier); err != nil {
+				slog.Error("failed to apply inactivity policy",
+					"interface", iface.Identifier,
+					"error", err)
+			}
+		}
+	}
+}
+
+func (m Manager) checkInterface(ctx context.Context, id domain.InterfaceIdentifier) error {
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to load interface %s: %w", id, err)
+	}
+
+	stalePeers, err := m.evaluatePeers(ctx, iface, peers, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range stalePeers {
+		if err := m.applyAction(ctx, &stalePeers[i]); err != nil {
+			slog.Error("failed to apply inactivity action",
+				"peer", stalePeers[i].Peer.Identifier,
+				"action", stalePeers[i].PendingAction,
+				"error", err)
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) evaluatePeers(
+	ctx context.Context,
+	iface *domain.Interface,
+	peers []domain.Peer,
+	now time.Time,
+) ([]domain.StalePeer, error) {
+	peerIds := make([]domain.PeerIdentifier, len(peers))
+	for i, peer := range peers {
+		peerIds[i] = peer.Identifier
+	}
+
+	stats, err := m.db.GetPeersStats(ctx, peerIds...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load peer status for interface %s: %w", iface.Identifier, err)
+	}
+	statsById := make(map[domain.PeerIdentifier]*domain.PeerStatus, len(stats))
+	for i := range stats {
+		statsById[stats[i].PeerId] = &stats[i]
+	}
+
+	policy := iface.InactivityPolicy
+	stalePeers := make([]domain.StalePeer, len(peers))
+	for i, peer := range peers {
+		lastActivity, neverConnected := peer.LastActivity(statsById[peer.Identifier])
+
+		warned := peer.InactivityWarned
+		if warned != nil && lastActivity.After(*warned) {
+			warned = nil // the peer connected after the warning has been sent
+		}
+
+		pendingAction := policy.NextAction(lastActivity, warned, now)
+		if warned == nil && pendingAction == domain.InactivityActionWarn {
+			warned = &now // the grace period starts with the pending warning
+		}
+
+		stalePeers[i] = domain.StalePeer{
+			Peer:           peer,
+			LastActivity:   lastActivity,
+			NeverConnected: neverConnected,
+			InactiveDays:   int(now.Sub(lastActivity).Hours() / 24),
+			PendingAction:  pendingAction,
+			DisableAt:      policy.DueDate(domain.InactivityActionDisable, lastActivity, warned),
+			DeleteAt:       policy.DueDate(domain.InactivityActionDelete, lastActivity, warned),
+		}
+	}
+
+	return stalePeers, nil
+}
+
+func (m Manager) applyAction(ctx context.Context, stalePeer *domain.StalePeer) error {
+	peer := &stalePeer.Peer
+
+	// reset the warning state of peers that connected again
+	if peer.InactivityWarned != nil && stalePeer.LastActivity.After(*peer.InactivityWarned) {
+		if err := m.setWarned(ctx, peer.Identifier, nil); err != nil {
+			return fmt.Errorf("failed to reset inactivity warning: %w", err)
+		}
+		peer.InactivityWarned = nil
+	}
+
+	switch stalePeer.PendingAction {
+	case domain.InactivityActionWarn:
+		if peer.InactivityWarned != nil || peer.IsDisabled() {
+			return nil // already warned, or nothing to warn about
+		}
+
+		slog.Info("peer is inactive, warning owner", "peer", peer.Identifier, "days", stalePeer.InactiveDays)
+
+		// the warning state is only stored if the mail was sent, so peers are never disabled without notice
+		if err := m.mail.SendPeerInactivityEmail(ctx, stalePeer, domain.InactivityActionWarn); err != nil {
+			return fmt.Errorf("failed to send inactivity warning: %w", err)
+		}
+
+		now := time.Now()
+		return m.setWarned(ctx, peer.Identifier, &now)
+	case domain.InactivityActionDisable:
+		if peer.IsDisabled() {
+			return nil
+		}
+
+		slog.Info("peer is inactive, disabling", "peer", peer.Identifier, "days", stalePeer.InactiveDays)
+
+		if err := m.mail.SendPeerInactivityEmail(ctx, stalePeer, domain.InactivityActionDisable); err != nil {
+			slog.Warn("failed to send inactivity notification", "peer", peer.Identifier, "error", err)
+		}
+
+		now := time.Now()
+		peer.Disabled = &now
+		peer.DisabledReason = domain.DisabledReasonInactive
+		if _, err := m.peers.UpdatePeer(ctx, peer); err != nil {
+			return fmt.Errorf("failed to disable inactive peer: %w", err)
+		}
+	case domain.InactivityActionDelete:
+		slog.Info("peer is inactive, deleting", "peer", peer.Identifier, "days", stalePeer.InactiveDays)
+
+		if err := m.mail.SendPeerInactivityEmail(ctx, stalePeer, domain.InactivityActionDelete); err != nil {
+			slog.Warn("failed to send inactivity notification", "peer", peer.Identifier, "error", err)
+		}
+
+		if err := m.peers.DeletePeer(ctx, peer.Identifier); err != nil {
+			return fmt.Errorf("failed to delete inactive peer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (m Manager) setWarned(ctx context.Context, id domain.PeerIdentifier, warned *time.Time) error {
+	return m.db.SavePeer(ctx, id, func(p *domain.Peer) (*domain.Peer, error) {
+		p.InactivityWarned = warned
+		return p, nil
+	})
+}
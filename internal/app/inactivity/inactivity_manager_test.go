@@ -0,0 +1,161 @@
+package inactivity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockDatabase struct {
+	iface domain.Interface
+	peers map[domain.PeerIdentifier]*domain.Peer
+	stats []domain.PeerStatus
+}
+
+func (m *mockDatabase) GetAllInterfaces(_ context.Context) ([]domain.Interface, error) {
+	return []domain.Interface{m.iface}, nil
+}
+
+func (m *mockDatabase) GetInterfaceAndPeers(_ context.Context, _ domain.InterfaceIdentifier) (
+	*domain.Interface,
+	[]domain.Peer,
+	error,
+) {
+	peers := make([]domain.Peer, 0, len(m.peers))
+	for _, p := range m.peers {
+		peers = append(peers, *p)
+	}
+	return &m.iface, peers, nil
+}
+
+func (m *mockDatabase) GetPeersStats(_ context.Context, _ ...domain.PeerIdentifier) ([]domain.PeerStatus, error) {
+	return m.stats, nil
+}
+
+func (m *mockDatabase) SavePeer(
+	_ context.Context,
+	id domain.PeerIdentifier,
+	updateFunc func(in *domain.Peer) (*domain.Peer, error),
+) error {
+	peer, err := updateFunc(m.peers[id])
+	if err != nil {
+		return err
+	}
+	m.peers[id] = peer
+	return nil
+}
+
+func (m *mockDatabase) UpdatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	m.peers[peer.Identifier] = peer
+	return peer, nil
+}
+
+func (m *mockDatabase) DeletePeer(_ context.Context, id domain.PeerIdentifier) error {
+	delete(m.peers, id)
+	return nil
+}
+
+type mockMailer struct {
+	sent map[domain.PeerIdentifier][]domain.InactivityAction
+}
+
+func (m *mockMailer) SendPeerInactivityEmail(
+	_ context.Context,
+	stalePeer *domain.StalePeer,
+	action domain.InactivityAction,
+) error {
+	m.sent[stalePeer.Peer.Identifier] = append(m.sent[stalePeer.Peer.Identifier], action)
+	return nil
+}
+
+func newTestManager(db *mockDatabase) (*Manager, *mockMailer) {
+	mailer := &mockMailer{sent: map[domain.PeerIdentifier][]domain.InactivityAction{}}
+	m, _ := NewInactivityManager(&config.Config{}, db, db, mailer)
+	return m, mailer
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+}
+
+func daysAgo(days int) time.Time {
+	return time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+}
+
+func TestManager_checkInterface(t *testing.T) {
+	recentHandshake := daysAgo(1)
+	oldHandshake := daysAgo(200)
+	warned := daysAgo(40)
+
+	db := &mockDatabase{
+		iface: domain.Interface{
+			Identifier:       "wg0",
+			InactivityPolicy: &domain.InactivityPolicy{WarnAfterDays: 30, DisableAfterDays: 60, DeleteAfterDays: 90},
+		},
+		peers: map[domain.PeerIdentifier]*domain.Peer{
+			"active":    {Identifier: "active", BaseModel: domain.BaseModel{CreatedAt: daysAgo(300)}},
+			"never":     {Identifier: "never", BaseModel: domain.BaseModel{CreatedAt: daysAgo(45)}},
+			"warned":    {Identifier: "warned", BaseModel: domain.BaseModel{CreatedAt: daysAgo(300)}, InactivityWarned: &warned},
+			"reconnect": {Identifier: "reconnect", BaseModel: domain.BaseModel{CreatedAt: daysAgo(300)}, InactivityWarned: &warned},
+		},
+		stats: []domain.PeerStatus{
+			{PeerId: "active", LastHandshake: &recentHandshake},
+			{PeerId: "warned", LastHandshake: &oldHandshake},
+			{PeerId: "reconnect", LastHandshake: &recentHandshake},
+		},
+	}
+	m, mailer := newTestManager(db)
+
+	require.NoError(t, m.checkInterface(adminContext(), "wg0"))
+
+	assert.Empty(t, mailer.sent["active"])
+	assert.Equal(t, []domain.InactivityAction{domain.InactivityActionWarn}, mailer.sent["never"])
+	assert.NotNil(t, db.peers["never"].InactivityWarned)
+	assert.False(t, db.peers["never"].IsDisabled(), "peers must be warned before they are disabled")
+
+	assert.Equal(t, []domain.InactivityAction{domain.InactivityActionDisable}, mailer.sent["warned"])
+	assert.True(t, db.peers["warned"].IsDisabled())
+	assert.Equal(t, domain.DisabledReasonInactive, db.peers["warned"].DisabledReason)
+
+	assert.Empty(t, mailer.sent["reconnect"])
+	assert.Nil(t, db.peers["reconnect"].InactivityWarned, "warning must be reset after a new handshake")
+
+	// a second run must not send the warning again
+	require.NoError(t, m.checkInterface(adminContext(), "wg0"))
+	assert.Len(t, mailer.sent["never"], 1)
+}
+
+func TestManager_GetStalePeers(t *testing.T) {
+	handshake := daysAgo(20)
+	db := &mockDatabase{
+		iface: domain.Interface{Identifier: "wg0"},
+		peers: map[domain.PeerIdentifier]*domain.Peer{
+			"recent": {Identifier: "recent", BaseModel: domain.BaseModel{CreatedAt: daysAgo(100)}},
+			"old":    {Identifier: "old", BaseModel: domain.BaseModel{CreatedAt: daysAgo(100)}},
+		},
+		stats: []domain.PeerStatus{{PeerId: "recent", LastHandshake: &handshake}},
+	}
+	m, _ := newTestManager(db)
+
+	stalePeers, err := m.GetStalePeers(adminContext(), "wg0", 0)
+	require.NoError(t, err)
+	require.Len(t, stalePeers, 1, "default threshold is 30 days")
+	assert.Equal(t, domain.PeerIdentifier("old"), stalePeers[0].Peer.Identifier)
+	assert.True(t, stalePeers[0].NeverConnected)
+	assert.Equal(t, domain.InactivityActionNone, stalePeers[0].PendingAction)
+
+	stalePeers, err = m.GetStalePeers(adminContext(), "wg0", 10)
+	require.NoError(t, err)
+	require.Len(t, stalePeers, 2)
+	assert.Equal(t, domain.PeerIdentifier("old"), stalePeers[0].Peer.Identifier, "longest inactive peer first")
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user"})
+	_, err = m.GetStalePeers(userCtx, "wg0", 0)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
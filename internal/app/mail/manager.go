@@ -1,18 +1,26 @@
 package mail
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/mail"
+	"strings"
 
+	"github.com/h44z/wg-portal/internal/app"
 	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 )
 
 // region dependencies
 
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+}
+
 type Mailer interface {
 	// Send sends an email with the given subject and body to the given recipients.
 	Send(ctx context.Context, subject, body string, to []string, options *domain.MailOptions) error
@@ -23,8 +31,18 @@ type ConfigFileManager interface {
 	GetInterfaceConfig(ctx context.Context, id domain.InterfaceIdentifier) (io.Reader, error)
 	// GetPeerConfig returns the configuration for the given peer.
 	GetPeerConfig(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error)
+	// GetPeerConfigFiles returns all configuration files for the given peer.
+	GetPeerConfigFiles(ctx context.Context, id domain.PeerIdentifier, style string) ([]domain.ConfigFile, error)
 	// GetPeerConfigQrCode returns the QR code for the given peer.
 	GetPeerConfigQrCode(ctx context.Context, id domain.PeerIdentifier, style string) (io.Reader, error)
+	// GetPeerMobileConfig returns the Apple configuration profile for the given peer.
+	GetPeerMobileConfig(
+		ctx context.Context,
+		id domain.PeerIdentifier,
+		platform domain.MobileConfigPlatform,
+	) (io.Reader, error)
+	// WritePeerConfigBundle writes a zip archive with the configurations of the selected peers.
+	WritePeerConfigBundle(ctx context.Context, w io.Writer, req domain.ConfigBundleRequest) error
 }
 
 type UserDatabaseRepo interface {
@@ -50,12 +68,29 @@ type TemplateRenderer interface {
 		io.Reader,
 		error,
 	)
+	// GetPeerInactivityMail returns the text and html template for the inactivity notification mail.
+	GetPeerInactivityMail(user *domain.User, stalePeer *domain.StalePeer, action domain.InactivityAction) (
+		io.Reader,
+		io.Reader,
+		error,
+	)
+	// GetPeerRequestMail returns the text and html template for the peer request notification mail.
+	GetPeerRequestMail(user *domain.User, request *domain.PeerRequest) (io.Reader, io.Reader, error)
+	// GetConfigBundleMail returns the text and html template for the mail with an attached configuration bundle.
+	GetConfigBundleMail(user *domain.User, bundleName string) (io.Reader, io.Reader, error)
+	// GetInterfaceMaintenanceMail returns the text and html template for the maintenance announcement mail.
+	GetInterfaceMaintenanceMail(user *domain.User, notice *domain.MaintenanceNotice, peers []domain.Peer) (
+		io.Reader,
+		io.Reader,
+		error,
+	)
 }
 
 // endregion dependencies
 
 type Manager struct {
 	cfg *config.Config
+	bus EventBus
 
 	tplHandler  TemplateRenderer
 	mailer      Mailer
@@ -67,6 +102,7 @@ type Manager struct {
 // NewMailManager creates a new mail manager.
 func NewMailManager(
 	cfg *config.Config,
+	bus EventBus,
 	mailer Mailer,
 	configFiles ConfigFileManager,
 	users UserDatabaseRepo,
@@ -79,6 +115,7 @@ func NewMailManager(
 
 	m := &Manager{
 		cfg:         cfg,
+		bus:         bus,
 		tplHandler:  tplHandler,
 		mailer:      mailer,
 		configFiles: configFiles,
@@ -86,11 +123,39 @@ func NewMailManager(
 		wg:          wg,
 	}
 
+	m.connectToMessageBus()
+
 	return m, nil
 }
 
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicInterfaceMaintenanceUpcoming, m.handleInterfaceMaintenanceEvent)
+}
+
+func (m Manager) handleInterfaceMaintenanceEvent(notice domain.MaintenanceNotice) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	if err := m.SendInterfaceMaintenanceEmail(ctx, &notice); err != nil {
+		slog.Error("failed to send maintenance announcement",
+			"interface", notice.Interface.Identifier, "error", err)
+	}
+}
+
 // SendPeerEmail sends an email to the user linked to the given peers.
-func (m Manager) SendPeerEmail(ctx context.Context, linkOnly bool, style string, peers ...domain.PeerIdentifier) error {
+// If a mobileConfig platform is given, an Apple configuration profile is attached in addition to the config files.
+func (m Manager) SendPeerEmail(
+	ctx context.Context,
+	linkOnly bool,
+	style string,
+	mobileConfig domain.MobileConfigPlatform,
+	peers ...domain.PeerIdentifier,
+) error {
+	if mobileConfig != "" {
+		if _, err := domain.ParseMobileConfigPlatform(string(mobileConfig)); err != nil {
+			return err
+		}
+	}
+
 	for _, peerId := range peers {
 		peer, err := m.wg.GetPeer(ctx, peerId)
 		if err != nil {
@@ -110,7 +175,7 @@ func (m Manager) SendPeerEmail(ctx context.Context, linkOnly bool, style string,
 			return fmt.Errorf("peer %s has no valid email address, no email is sent", peerId)
 		}
 
-		err = m.sendPeerEmail(ctx, linkOnly, style, &user, peer)
+		err = m.sendPeerEmail(ctx, linkOnly, style, mobileConfig, &user, peer)
 		if err != nil {
 			return fmt.Errorf("failed to send peer email for %s: %w", peerId, err)
 		}
@@ -119,15 +184,189 @@ func (m Manager) SendPeerEmail(ctx context.Context, linkOnly bool, style string,
 	return nil
 }
 
+// SendPeerInactivityEmail notifies the owner of the given stale peer about the upcoming or executed
+// inactivity action. Peers without a reachable owner are skipped silently.
+func (m Manager) SendPeerInactivityEmail(
+	ctx context.Context,
+	stalePeer *domain.StalePeer,
+	action domain.InactivityAction,
+) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	email, user := m.resolveEmail(ctx, &stalePeer.Peer)
+	if email == "" {
+		return nil
+	}
+
+	txtMail, htmlMail, err := m.tplHandler.GetPeerInactivityMail(&user, stalePeer, action)
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	subject := "WireGuard VPN peer inactive"
+	switch action {
+	case domain.InactivityActionDisable:
+		subject = "WireGuard VPN peer disabled due to inactivity"
+	case domain.InactivityActionDelete:
+		subject = "WireGuard VPN peer deleted due to inactivity"
+	}
+
+	err = m.mailer.Send(ctx, subject, string(txtMailStr), []string{email},
+		&domain.MailOptions{HtmlBody: string(htmlMailStr)})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendPeerRequestEmail notifies the given recipients about the peer request. Pending requests are sent to admins
+// for review, rejected requests are sent to the requesting user. Recipients without an email address are skipped.
+func (m Manager) SendPeerRequestEmail(
+	ctx context.Context,
+	request *domain.PeerRequest,
+	recipients ...domain.User,
+) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	subject := "WireGuard VPN peer request"
+	if request.State == domain.PeerRequestStateRejected {
+		subject = "WireGuard VPN peer request rejected"
+	}
+
+	for _, recipient := range recipients {
+		if recipient.Email == "" {
+			continue
+		}
+
+		txtMail, htmlMail, err := m.tplHandler.GetPeerRequestMail(&recipient, request)
+		if err != nil {
+			return fmt.Errorf("failed to get mail body: %w", err)
+		}
+
+		txtMailStr, _ := io.ReadAll(txtMail)
+		htmlMailStr, _ := io.ReadAll(htmlMail)
+
+		err = m.mailer.Send(ctx, subject, string(txtMailStr), []string{recipient.Email},
+			&domain.MailOptions{HtmlBody: string(htmlMailStr)})
+		if err != nil {
+			return fmt.Errorf("failed to send mail to %s: %w", recipient.Identifier, err)
+		}
+	}
+
+	return nil
+}
+
+// SendPeerConfigBundleEmail sends the configurations of the selected peers as a single zip attachment.
+// Bundles of a user are sent to that user, all other bundles are sent to the requesting user.
+func (m Manager) SendPeerConfigBundleEmail(ctx context.Context, req domain.ConfigBundleRequest) error {
+	bundle := bytes.Buffer{}
+	if err := m.configFiles.WritePeerConfigBundle(ctx, &bundle, req); err != nil {
+		return fmt.Errorf("failed to create config bundle: %w", err)
+	}
+
+	recipientId := req.UserIdentifier
+	if recipientId == "" {
+		recipientId = domain.GetUserInfo(ctx).Id
+	}
+	recipient, err := m.users.GetUser(ctx, recipientId)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user %s: %w", recipientId, err)
+	}
+	if recipient.Email == "" {
+		return fmt.Errorf("user %s has no email address: %w", recipientId, domain.ErrInvalidData)
+	}
+
+	txtMail, htmlMail, err := m.tplHandler.GetConfigBundleMail(recipient, req.FileName())
+	if err != nil {
+		return fmt.Errorf("failed to get mail body: %w", err)
+	}
+
+	txtMailStr, _ := io.ReadAll(txtMail)
+	htmlMailStr, _ := io.ReadAll(htmlMail)
+
+	err = m.mailer.Send(ctx, "WireGuard VPN Configurations", string(txtMailStr), []string{recipient.Email},
+		&domain.MailOptions{
+			HtmlBody: string(htmlMailStr),
+			Attachments: []domain.MailAttachment{{
+				Name:        req.FileName(),
+				ContentType: domain.ConfigBundleContentType,
+				Data:        &bundle,
+				Embedded:    false,
+			}},
+		})
+	if err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+// SendInterfaceMaintenanceEmail announces an upcoming maintenance window to the owners of all enabled peers of the
+// interface. Each owner receives a single mail that lists the affected peers, owners without an email address are
+// skipped silently.
+func (m Manager) SendInterfaceMaintenanceEmail(ctx context.Context, notice *domain.MaintenanceNotice) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	_, peers, err := m.wg.GetInterfaceAndPeers(ctx, notice.Interface.Identifier)
+	if err != nil {
+		return fmt.Errorf("failed to fetch peers of interface %s: %w", notice.Interface.Identifier, err)
+	}
+
+	var owners []domain.UserIdentifier
+	ownerPeers := make(map[domain.UserIdentifier][]domain.Peer)
+	for _, peer := range peers {
+		if peer.UserIdentifier == "" || peer.IsDisabled() {
+			continue
+		}
+		if _, ok := ownerPeers[peer.UserIdentifier]; !ok {
+			owners = append(owners, peer.UserIdentifier)
+		}
+		ownerPeers[peer.UserIdentifier] = append(ownerPeers[peer.UserIdentifier], peer)
+	}
+
+	for _, owner := range owners {
+		email, user := m.resolveEmail(ctx, &ownerPeers[owner][0])
+		if email == "" {
+			continue
+		}
+
+		txtMail, htmlMail, err := m.tplHandler.GetInterfaceMaintenanceMail(&user, notice, ownerPeers[owner])
+		if err != nil {
+			return fmt.Errorf("failed to get mail body: %w", err)
+		}
+
+		txtMailStr, _ := io.ReadAll(txtMail)
+		htmlMailStr, _ := io.ReadAll(htmlMail)
+
+		err = m.mailer.Send(ctx, "WireGuard VPN maintenance", string(txtMailStr), []string{email},
+			&domain.MailOptions{HtmlBody: string(htmlMailStr)})
+		if err != nil {
+			return fmt.Errorf("failed to send mail to %s: %w", owner, err)
+		}
+	}
+
+	return nil
+}
+
 func (m Manager) sendPeerEmail(
 	ctx context.Context,
 	linkOnly bool,
 	style string,
+	mobileConfig domain.MobileConfigPlatform,
 	user *domain.User,
 	peer *domain.Peer,
 ) error {
 	qrName := "WireGuardQRCode.png"
-	configName := peer.GetConfigFileName()
 
 	var (
 		txtMail, htmlMail io.Reader
@@ -141,7 +380,7 @@ func (m Manager) sendPeerEmail(
 		}
 
 	} else {
-		peerConfig, err := m.configFiles.GetPeerConfig(ctx, peer.Identifier, style)
+		peerConfigFiles, err := m.configFiles.GetPeerConfigFiles(ctx, peer.Identifier, style)
 		if err != nil {
 			return fmt.Errorf("failed to fetch peer config for %s: %w", peer.Identifier, err)
 		}
@@ -151,17 +390,38 @@ func (m Manager) sendPeerEmail(
 			return fmt.Errorf("failed to fetch peer config QR code for %s: %w", peer.Identifier, err)
 		}
 
-		txtMail, htmlMail, err = m.tplHandler.GetConfigMailWithAttachment(user, configName, qrName)
+		configNames := make([]string, len(peerConfigFiles))
+		for i, file := range peerConfigFiles {
+			configNames[i] = file.Name
+			mailOptions.Attachments = append(mailOptions.Attachments, domain.MailAttachment{
+				Name:        file.Name,
+				ContentType: "text/plain",
+				Data:        bytes.NewReader(file.Data),
+				Embedded:    false,
+			})
+		}
+
+		if mobileConfig != "" {
+			profile, err := m.configFiles.GetPeerMobileConfig(ctx, peer.Identifier, mobileConfig)
+			if err != nil {
+				return fmt.Errorf("failed to fetch mobileconfig for %s: %w", peer.Identifier, err)
+			}
+
+			configNames = append(configNames, peer.GetMobileConfigFileName())
+			mailOptions.Attachments = append(mailOptions.Attachments, domain.MailAttachment{
+				Name:        peer.GetMobileConfigFileName(),
+				ContentType: domain.MobileConfigContentType,
+				Data:        profile,
+				Embedded:    false,
+			})
+		}
+
+		txtMail, htmlMail, err = m.tplHandler.GetConfigMailWithAttachment(user, strings.Join(configNames, ", "),
+			qrName)
 		if err != nil {
 			return fmt.Errorf("failed to get full mail body: %w", err)
 		}
 
-		mailOptions.Attachments = append(mailOptions.Attachments, domain.MailAttachment{
-			Name:        configName,
-			ContentType: "text/plain",
-			Data:        peerConfig,
-			Embedded:    false,
-		})
 		mailOptions.Attachments = append(mailOptions.Attachments, domain.MailAttachment{
 			Name:        qrName,
 			ContentType: "image/png",
@@ -188,3 +188,126 @@ func (c TemplateHandler) GetConfigMailWithAttachment(user *domain.User, cfgName,
 
 	return &tplBuff, &htmlTplBuff, nil
 }
+
+// GetPeerInactivityMail returns the text and html template for the mail that notifies the owner of an inactive peer.
+func (c TemplateHandler) GetPeerInactivityMail(
+	user *domain.User,
+	stalePeer *domain.StalePeer,
+	action domain.InactivityAction,
+) (io.Reader, io.Reader, error) {
+	var tplBuff bytes.Buffer
+	var htmlTplBuff bytes.Buffer
+
+	data := map[string]any{
+		"User":         user,
+		"Peer":         stalePeer.Peer,
+		"Action":       string(action),
+		"LastActivity": stalePeer.LastActivity,
+		"DisableAt":    stalePeer.DisableAt,
+		"DeleteAt":     stalePeer.DeleteAt,
+		"PortalUrl":    c.portalUrl,
+		"PortalName":   c.portalName,
+	}
+
+	err := c.textTemplates.ExecuteTemplate(&tplBuff, "peer_inactivity.gotpl", data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute template peer_inactivity.gotpl: %w", err)
+	}
+
+	err = c.htmlTemplates.ExecuteTemplate(&htmlTplBuff, "peer_inactivity.gohtml", data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute template peer_inactivity.gohtml: %w", err)
+	}
+
+	return &tplBuff, &htmlTplBuff, nil
+}
+
+// GetPeerRequestMail returns the text and html template for the mail that notifies admins about a new peer request,
+// or the requesting user about a rejected request.
+func (c TemplateHandler) GetPeerRequestMail(user *domain.User, request *domain.PeerRequest) (
+	io.Reader,
+	io.Reader,
+	error,
+) {
+	var tplBuff bytes.Buffer
+	var htmlTplBuff bytes.Buffer
+
+	data := map[string]any{
+		"User":       user,
+		"Request":    request,
+		"State":      string(request.State),
+		"PortalUrl":  c.portalUrl,
+		"PortalName": c.portalName,
+	}
+
+	err := c.textTemplates.ExecuteTemplate(&tplBuff, "peer_request.gotpl", data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute template peer_request.gotpl: %w", err)
+	}
+
+	err = c.htmlTemplates.ExecuteTemplate(&htmlTplBuff, "peer_request.gohtml", data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute template peer_request.gohtml: %w", err)
+	}
+
+	return &tplBuff, &htmlTplBuff, nil
+}
+
+// GetConfigBundleMail returns the text and html template for the mail with an attached configuration bundle.
+func (c TemplateHandler) GetConfigBundleMail(user *domain.User, bundleName string) (io.Reader, io.Reader, error) {
+	var tplBuff bytes.Buffer
+	var htmlTplBuff bytes.Buffer
+
+	data := map[string]any{
+		"User":       user,
+		"BundleName": bundleName,
+		"PortalUrl":  c.portalUrl,
+		"PortalName": c.portalName,
+	}
+
+	err := c.textTemplates.ExecuteTemplate(&tplBuff, "config_bundle.gotpl", data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute template config_bundle.gotpl: %w", err)
+	}
+
+	err = c.htmlTemplates.ExecuteTemplate(&htmlTplBuff, "config_bundle.gohtml", data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute template config_bundle.gohtml: %w", err)
+	}
+
+	return &tplBuff, &htmlTplBuff, nil
+}
+
+// GetInterfaceMaintenanceMail returns the text and html template for the mail that announces an upcoming
+// maintenance window to the owner of the given peers.
+func (c TemplateHandler) GetInterfaceMaintenanceMail(
+	user *domain.User,
+	notice *domain.MaintenanceNotice,
+	peers []domain.Peer,
+) (io.Reader, io.Reader, error) {
+	var tplBuff bytes.Buffer
+	var htmlTplBuff bytes.Buffer
+
+	data := map[string]any{
+		"User":        user,
+		"Interface":   notice.Interface,
+		"Peers":       peers,
+		"Start":       notice.Start,
+		"End":         notice.End,
+		"Description": notice.Window.Description,
+		"PortalUrl":   c.portalUrl,
+		"PortalName":  c.portalName,
+	}
+
+	err := c.textTemplates.ExecuteTemplate(&tplBuff, "interface_maintenance.gotpl", data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute template interface_maintenance.gotpl: %w", err)
+	}
+
+	err = c.htmlTemplates.ExecuteTemplate(&htmlTplBuff, "interface_maintenance.gohtml", data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute template interface_maintenance.gohtml: %w", err)
+	}
+
+	return &tplBuff, &htmlTplBuff, nil
+}
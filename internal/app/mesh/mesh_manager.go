@@ -0,0 +1,513 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetMesh returns the mesh with the given identifier.
+	GetMesh(ctx context.Context, id domain.MeshIdentifier) (*domain.Mesh, error)
+	// GetAllMeshes returns all meshes.
+	GetAllMeshes(ctx context.Context) ([]domain.Mesh, error)
+	// SaveMesh creates or updates the mesh with the given identifier.
+	SaveMesh(
+		ctx context.Context,
+		id domain.MeshIdentifier,
+		updateFunc func(in *domain.Mesh) (*domain.Mesh, error),
+	) error
+	// DeleteMesh deletes the mesh with the given identifier.
+	DeleteMesh(ctx context.Context, id domain.MeshIdentifier) error
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetInterfacePeers returns all peers of the given interface.
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+}
+
+type ControllerManager interface {
+	// GetController returns the controller for the given interface.
+	GetController(iface domain.Interface) domain.InterfaceController
+}
+
+type EventBus interface {
+	// Subscribe subscribes to a topic
+	Subscribe(topic string, fn interface{}) error
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+}
+
+// endregion dependencies
+
+// Manager maintains the peer entries of site-to-site meshes. The entries are generated from the node interfaces
+// and written to the backend controllers of the nodes; they are regenerated whenever a node interface changes.
+type Manager struct {
+	cfg *config.Config
+
+	bus          EventBus
+	db           DatabaseRepo
+	wgController ControllerManager
+
+	mux *sync.Mutex // serializes the synchronization of the node interfaces
+}
+
+// NewMeshManager creates a new mesh manager instance.
+func NewMeshManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	wgController ControllerManager,
+) (*Manager, error) {
+	m := &Manager{
+		cfg: cfg,
+		bus: bus,
+
+		db:           db,
+		wgController: wgController,
+		mux:          &sync.Mutex{},
+	}
+
+	m.connectToMessageBus()
+
+	return m, nil
+}
+
+func (m Manager) connectToMessageBus() {
+	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceSavedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceSavedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceSavedEvent)
+	_ = m.bus.Subscribe(app.TopicInterfaceStateRestored, m.handleInterfaceStateRestoredEvent)
+}
+
+// StartBackgroundJobs starts background jobs for the mesh manager.
+// This method is non-blocking and returns immediately.
+func (m Manager) StartBackgroundJobs(ctx context.Context) {
+	go func() {
+		ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+		meshes, err := m.db.GetAllMeshes(ctx)
+		if err != nil {
+			slog.Error("failed to load meshes", "error", err)
+			return
+		}
+
+		var nodes []domain.InterfaceIdentifier
+		for _, mesh := range meshes {
+			nodes = appendNodes(nodes, &mesh)
+		}
+		if err := m.syncInterfaces(ctx, nil, nodes...); err != nil {
+			slog.Error("failed to synchronize mesh peers", "error", err)
+		}
+	}()
+}
+
+// handleInterfaceSavedEvent regenerates the peer entries of all nodes that are linked to the changed interface,
+// as the keys, addresses and endpoint of the interface are part of their peer entries. If the interface was deleted,
+// the peer entries that point to it are removed from the other nodes.
+func (m Manager) handleInterfaceSavedEvent(iface domain.Interface) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	meshes, err := m.db.GetAllMeshes(ctx)
+	if err != nil {
+		slog.Error("failed to load meshes", "interface", iface.Identifier, "error", err)
+		return
+	}
+
+	var nodes []domain.InterfaceIdentifier
+	for _, mesh := range meshes {
+		if mesh.HasNode(iface.Identifier) {
+			nodes = appendNodes(nodes, &mesh)
+		}
+	}
+	if len(nodes) == 0 {
+		return
+	}
+
+	slog.Debug("handling mesh node change", "interface", iface.Identifier)
+
+	formerKeys := map[string]struct{}{iface.PublicKey: {}}
+	if err := m.syncInterfaces(ctx, formerKeys, nodes...); err != nil {
+		slog.Error("failed to synchronize mesh peers", "interface", iface.Identifier, "error", err)
+	}
+}
+
+// handleInterfaceStateRestoredEvent re-creates the peer entries of the interface, as restoring the interface
+// state removes all peers that are not stored in the database.
+func (m Manager) handleInterfaceStateRestoredEvent(id domain.InterfaceIdentifier) {
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+
+	meshes, err := m.db.GetAllMeshes(ctx)
+	if err != nil {
+		slog.Error("failed to load meshes", "interface", id, "error", err)
+		return
+	}
+	if !slices.ContainsFunc(meshes, func(mesh domain.Mesh) bool { return mesh.HasNode(id) }) {
+		return
+	}
+
+	if err := m.syncInterfaces(ctx, nil, id); err != nil {
+		slog.Error("failed to synchronize mesh peers", "interface", id, "error", err)
+	}
+}
+
+// GetAllMeshes returns all meshes.
+func (m Manager) GetAllMeshes(ctx context.Context) ([]domain.Mesh, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetAllMeshes(ctx)
+}
+
+// GetMesh returns the mesh with the given identifier.
+func (m Manager) GetMesh(ctx context.Context, id domain.MeshIdentifier) (*domain.Mesh, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetMesh(ctx, id)
+}
+
+// GetMeshPeers returns the peer entries that are generated for the links of the mesh.
+func (m Manager) GetMeshPeers(ctx context.Context, id domain.MeshIdentifier) ([]domain.MeshPeer, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	mesh, err := m.db.GetMesh(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load mesh %s: %w", id, err)
+	}
+
+	interfaces, err := m.loadNodeInterfaces(ctx, mesh)
+	if err != nil {
+		return nil, err
+	}
+
+	return mesh.Peers(interfaces)
+}
+
+// CreateMesh stores a new mesh and creates the peer entries on all nodes.
+func (m Manager) CreateMesh(ctx context.Context, mesh *domain.Mesh) (*domain.Mesh, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := m.validateMesh(ctx, mesh); err != nil {
+		return nil, err
+	}
+
+	_, err := m.db.GetMesh(ctx, mesh.Identifier)
+	if err == nil {
+		return nil, fmt.Errorf("mesh %s already exists: %w", mesh.Identifier, domain.ErrDuplicateEntry)
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("unable to check for existing mesh %s: %w", mesh.Identifier, err)
+	}
+
+	var created *domain.Mesh
+	err = m.db.SaveMesh(ctx, mesh.Identifier, func(in *domain.Mesh) (*domain.Mesh, error) {
+		mesh.BaseModel = in.BaseModel
+		created = mesh
+		return mesh, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save mesh %s: %w", mesh.Identifier, err)
+	}
+
+	if err := m.syncInterfaces(ctx, nil, appendNodes(nil, created)...); err != nil {
+		return created, fmt.Errorf("failed to create peers of mesh %s: %w", mesh.Identifier, err)
+	}
+
+	return created, nil
+}
+
+// UpdateMesh updates the mesh and regenerates the peer entries of all current and former nodes.
+func (m Manager) UpdateMesh(ctx context.Context, mesh *domain.Mesh) (*domain.Mesh, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	existing, err := m.db.GetMesh(ctx, mesh.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load mesh %s: %w", mesh.Identifier, err)
+	}
+
+	if err := m.validateMesh(ctx, mesh); err != nil {
+		return nil, err
+	}
+
+	formerKeys, err := m.nodeKeys(ctx, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *domain.Mesh
+	err = m.db.SaveMesh(ctx, mesh.Identifier, func(in *domain.Mesh) (*domain.Mesh, error) {
+		mesh.BaseModel = in.BaseModel
+		updated = mesh
+		return mesh, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save mesh %s: %w", mesh.Identifier, err)
+	}
+
+	nodes := appendNodes(appendNodes(nil, existing), updated)
+	if err := m.syncInterfaces(ctx, formerKeys, nodes...); err != nil {
+		return updated, fmt.Errorf("failed to update peers of mesh %s: %w", mesh.Identifier, err)
+	}
+
+	return updated, nil
+}
+
+// DeleteMesh deletes the mesh and removes its peer entries from all nodes.
+func (m Manager) DeleteMesh(ctx context.Context, id domain.MeshIdentifier) error {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return err
+	}
+
+	existing, err := m.db.GetMesh(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to load mesh %s: %w", id, err)
+	}
+
+	formerKeys, err := m.nodeKeys(ctx, existing)
+	if err != nil {
+		return err
+	}
+
+	if err := m.db.DeleteMesh(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete mesh %s: %w", id, err)
+	}
+
+	if err := m.syncInterfaces(ctx, formerKeys, appendNodes(nil, existing)...); err != nil {
+		return fmt.Errorf("failed to remove peers of mesh %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// validateMesh checks that all node interfaces exist and that the peer entries of the mesh can be generated.
+func (m Manager) validateMesh(ctx context.Context, mesh *domain.Mesh) error {
+	if err := mesh.Validate(); err != nil {
+		return err
+	}
+
+	interfaces, err := m.loadNodeInterfaces(ctx, mesh)
+	if err != nil {
+		return err
+	}
+	for _, node := range mesh.Nodes {
+		if interfaces[node.InterfaceIdentifier] == nil {
+			return fmt.Errorf("interface %s of node does not exist: %w", node.InterfaceIdentifier,
+				domain.ErrInvalidData)
+		}
+	}
+
+	peers, err := mesh.Peers(interfaces)
+	if err != nil {
+		return err
+	}
+
+	// a link needs at least one side with a known endpoint, otherwise no node can initiate the handshake
+	for _, peer := range peers {
+		reverse := slices.IndexFunc(peers, func(p domain.MeshPeer) bool {
+			return p.InterfaceIdentifier == peer.RemoteInterface && p.RemoteInterface == peer.InterfaceIdentifier
+		})
+		if peer.Endpoint == "" && (reverse < 0 || peers[reverse].Endpoint == "") {
+			return fmt.Errorf("neither %s nor %s has an endpoint: %w", peer.InterfaceIdentifier,
+				peer.RemoteInterface, domain.ErrInvalidData)
+		}
+	}
+
+	return nil
+}
+
+// loadNodeInterfaces returns the interfaces of all nodes of the mesh. Nodes of deleted interfaces are omitted.
+func (m Manager) loadNodeInterfaces(
+	ctx context.Context,
+	mesh *domain.Mesh,
+) (map[domain.InterfaceIdentifier]*domain.Interface, error) {
+	interfaces := make(map[domain.InterfaceIdentifier]*domain.Interface, len(mesh.Nodes))
+	for _, node := range mesh.Nodes {
+		iface, err := m.db.GetInterface(ctx, node.InterfaceIdentifier)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to load interface %s: %w", node.InterfaceIdentifier, err)
+		}
+		interfaces[node.InterfaceIdentifier] = iface
+	}
+
+	return interfaces, nil
+}
+
+// nodeKeys returns the public keys of all node interfaces of the mesh. Peers with these keys are mesh peer entries.
+func (m Manager) nodeKeys(ctx context.Context, mesh *domain.Mesh) (map[string]struct{}, error) {
+	interfaces, err := m.loadNodeInterfaces(ctx, mesh)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]struct{}, len(interfaces))
+	for _, iface := range interfaces {
+		keys[iface.PublicKey] = struct{}{}
+	}
+
+	return keys, nil
+}
+
+// syncInterfaces brings the mesh peer entries of the given interfaces in line with all stored meshes.
+// The formerKeys contain the public keys of node interfaces that might no longer be part of a mesh, peer entries
+// with these keys are removed if they are no longer generated.
+func (m Manager) syncInterfaces(
+	ctx context.Context,
+	formerKeys map[string]struct{},
+	ids ...domain.InterfaceIdentifier,
+) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	meshes, err := m.db.GetAllMeshes(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load meshes: %w", err)
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if err := m.syncInterface(ctx, id, meshes, formerKeys); err != nil {
+			errs = append(errs, fmt.Errorf("interface %s: %w", id, err))
+			continue
+		}
+		slog.Debug("mesh peers synchronized", "interface", id)
+	}
+
+	return errors.Join(errs...)
+}
+
+// syncInterface writes the mesh peer entries of the interface to its controller. Peer entries of removed links are
+// removed: these are the peers with the key of a former or current node interface that are no longer generated.
+// Other peers that are not stored in the database are only removed if mesh_remove_unknown_peers is enabled.
+func (m Manager) syncInterface(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	meshes []domain.Mesh,
+	formerKeys map[string]struct{},
+) error {
+	iface, err := m.db.GetInterface(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil // nothing to do for deleted interfaces
+	}
+	if err != nil {
+		return fmt.Errorf("unable to load interface: %w", err)
+	}
+	if iface.IsDisabled() {
+		return nil
+	}
+
+	peers, err := m.db.GetInterfacePeers(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to load peers: %w", err)
+	}
+	known := make(map[string]struct{}, len(peers))
+	for _, peer := range peers {
+		known[string(peer.Identifier)] = struct{}{}
+	}
+
+	stale := maps.Clone(formerKeys)
+	if stale == nil {
+		stale = make(map[string]struct{})
+	}
+
+	var desired []domain.MeshPeer
+	for _, mesh := range meshes {
+		if !mesh.HasNode(id) {
+			continue
+		}
+
+		interfaces, err := m.loadNodeInterfaces(ctx, &mesh)
+		if err != nil {
+			return err
+		}
+		meshPeers, err := mesh.Peers(interfaces)
+		if err != nil {
+			return fmt.Errorf("failed to generate peers of mesh %s: %w", mesh.Identifier, err)
+		}
+		for _, node := range interfaces {
+			stale[node.PublicKey] = struct{}{}
+		}
+
+		for _, peer := range meshPeers {
+			if peer.InterfaceIdentifier != id {
+				continue
+			}
+			if _, exists := known[peer.PublicKey]; exists {
+				slog.Warn("mesh peer conflicts with an existing peer, skipping",
+					"mesh", mesh.Identifier, "interface", id, "remote", peer.RemoteInterface)
+				continue
+			}
+			known[peer.PublicKey] = struct{}{}
+			desired = append(desired, peer)
+		}
+	}
+
+	controller := m.wgController.GetController(*iface)
+	for _, peer := range desired {
+		err := controller.SavePeer(ctx, id, domain.PeerIdentifier(peer.PublicKey),
+			func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error) {
+				peer.MergeToPhysicalPeer(pp)
+				return pp, nil
+			})
+		if err != nil {
+			return fmt.Errorf("failed to save peer for node %s: %w", peer.RemoteInterface, err)
+		}
+	}
+
+	physicalPeers, err := controller.GetPeers(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to load physical peers: %w", err)
+	}
+	for _, pp := range physicalPeers {
+		if _, ok := known[pp.PublicKey]; ok {
+			continue
+		}
+		if _, ok := stale[pp.PublicKey]; !ok && !m.cfg.Advanced.MeshRemoveUnknownPeers {
+			continue
+		}
+		if err := controller.DeletePeer(ctx, id, domain.PeerIdentifier(pp.PublicKey)); err != nil {
+			return fmt.Errorf("failed to remove stale peer %s: %w", pp.PublicKey, err)
+		}
+	}
+
+	// the route manager adds the allowed IPs of the mesh peer entries to the routes of the stored peers
+	m.bus.Publish(app.TopicRouteUpdate, domain.RoutingTableInfo{
+		Interface:  *iface,
+		AllowedIps: iface.GetAllowedIPs(peers),
+		FwMark:     iface.FirewallMark,
+		Table:      iface.GetRoutingTable(),
+		TableStr:   iface.RoutingTable,
+	})
+
+	return nil
+}
+
+// appendNodes appends the node interfaces of the mesh that are not yet part of the list.
+func appendNodes(ids []domain.InterfaceIdentifier, mesh *domain.Mesh) []domain.InterfaceIdentifier {
+	for _, node := range mesh.Nodes {
+		if !slices.Contains(ids, node.InterfaceIdentifier) {
+			ids = append(ids, node.InterfaceIdentifier)
+		}
+	}
+	return ids
+}
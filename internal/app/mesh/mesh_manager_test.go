@@ -0,0 +1,319 @@
+package mesh
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockDatabase struct {
+	meshes     map[domain.MeshIdentifier]*domain.Mesh
+	interfaces map[domain.InterfaceIdentifier]*domain.Interface
+	peers      map[domain.InterfaceIdentifier][]domain.Peer
+}
+
+func (m *mockDatabase) GetMesh(_ context.Context, id domain.MeshIdentifier) (*domain.Mesh, error) {
+	mesh, ok := m.meshes[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	cpy := *mesh
+	return &cpy, nil
+}
+
+func (m *mockDatabase) GetAllMeshes(_ context.Context) ([]domain.Mesh, error) {
+	meshes := make([]domain.Mesh, 0, len(m.meshes))
+	for _, mesh := range m.meshes {
+		meshes = append(meshes, *mesh)
+	}
+	return meshes, nil
+}
+
+func (m *mockDatabase) SaveMesh(
+	_ context.Context,
+	id domain.MeshIdentifier,
+	updateFunc func(in *domain.Mesh) (*domain.Mesh, error),
+) error {
+	mesh, ok := m.meshes[id]
+	if !ok {
+		mesh = &domain.Mesh{Identifier: id}
+	}
+	updated, err := updateFunc(mesh)
+	if err != nil {
+		return err
+	}
+	m.meshes[id] = updated
+	return nil
+}
+
+func (m *mockDatabase) DeleteMesh(_ context.Context, id domain.MeshIdentifier) error {
+	delete(m.meshes, id)
+	return nil
+}
+
+func (m *mockDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	iface, ok := m.interfaces[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return iface, nil
+}
+
+func (m *mockDatabase) GetInterfacePeers(_ context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	return m.peers[id], nil
+}
+
+// mockController stores the physical peers of all interfaces, the remaining controller functions are not used.
+type mockController struct {
+	domain.InterfaceController
+
+	peers map[domain.InterfaceIdentifier]map[string]domain.PhysicalPeer
+}
+
+func (c *mockController) GetPeers(_ context.Context, deviceId domain.InterfaceIdentifier) (
+	[]domain.PhysicalPeer,
+	error,
+) {
+	peers := make([]domain.PhysicalPeer, 0, len(c.peers[deviceId]))
+	for _, pp := range c.peers[deviceId] {
+		peers = append(peers, pp)
+	}
+	return peers, nil
+}
+
+func (c *mockController) SavePeer(
+	_ context.Context,
+	deviceId domain.InterfaceIdentifier,
+	id domain.PeerIdentifier,
+	updateFunc func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error),
+) error {
+	pp, ok := c.peers[deviceId][string(id)]
+	if !ok {
+		pp = domain.PhysicalPeer{Identifier: id, ImportSource: domain.ControllerTypeLocal}
+	}
+	updated, err := updateFunc(&pp)
+	if err != nil {
+		return err
+	}
+	if c.peers[deviceId] == nil {
+		c.peers[deviceId] = make(map[string]domain.PhysicalPeer)
+	}
+	c.peers[deviceId][string(id)] = *updated
+	return nil
+}
+
+func (c *mockController) DeletePeer(
+	_ context.Context,
+	deviceId domain.InterfaceIdentifier,
+	id domain.PeerIdentifier,
+) error {
+	delete(c.peers[deviceId], string(id))
+	return nil
+}
+
+func (c *mockController) GetController(_ domain.Interface) domain.InterfaceController {
+	return c
+}
+
+type mockBus struct {
+	routes []domain.RoutingTableInfo
+}
+
+func (b *mockBus) Subscribe(_ string, _ interface{}) error {
+	return nil
+}
+
+func (b *mockBus) Publish(topic string, args ...any) {
+	if topic == app.TopicRouteUpdate {
+		b.routes = append(b.routes, args[0].(domain.RoutingTableInfo))
+	}
+}
+
+func newTestManager(t *testing.T) (*Manager, *mockDatabase, *mockController) {
+	m, db, controller, _ := newTestManagerWithBus(t, &config.Config{})
+	return m, db, controller
+}
+
+func newTestManagerWithBus(t *testing.T, cfg *config.Config) (*Manager, *mockDatabase, *mockController, *mockBus) {
+	newIface := func(id domain.InterfaceIdentifier, addr, endpoint string) *domain.Interface {
+		cidr, err := domain.CidrFromString(addr)
+		require.NoError(t, err)
+		kp, err := domain.NewFreshKeypair()
+		require.NoError(t, err)
+		return &domain.Interface{
+			Identifier:      id,
+			KeyPair:         kp,
+			ListenPort:      51820,
+			Addresses:       []domain.Cidr{cidr},
+			PeerDefEndpoint: endpoint,
+		}
+	}
+
+	db := &mockDatabase{
+		meshes: make(map[domain.MeshIdentifier]*domain.Mesh),
+		interfaces: map[domain.InterfaceIdentifier]*domain.Interface{
+			"hq":       newIface("hq", "10.100.0.1/24", "hq.example.com"),
+			"branch-a": newIface("branch-a", "10.100.0.2/24", "a.example.com"),
+			"branch-b": newIface("branch-b", "10.100.0.3/24", ""),
+		},
+		peers: make(map[domain.InterfaceIdentifier][]domain.Peer),
+	}
+	controller := &mockController{peers: make(map[domain.InterfaceIdentifier]map[string]domain.PhysicalPeer)}
+
+	bus := &mockBus{}
+
+	m, err := NewMeshManager(cfg, bus, db, controller)
+	require.NoError(t, err)
+
+	return m, db, controller, bus
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+}
+
+func newTestMesh() *domain.Mesh {
+	return &domain.Mesh{
+		Identifier:          "branches",
+		Topology:            domain.MeshTopologyFullMesh,
+		PersistentKeepalive: 25,
+		Nodes: []domain.MeshNode{
+			{InterfaceIdentifier: "hq", Networks: []string{"192.168.0.0/24"}},
+			{InterfaceIdentifier: "branch-a", Networks: []string{"192.168.10.0/24"}},
+			{InterfaceIdentifier: "branch-b", Networks: []string{"192.168.20.0/24"}},
+		},
+	}
+}
+
+func TestManager_CreateMesh(t *testing.T) {
+	m, db, controller := newTestManager(t)
+
+	// a regular peer and an unknown peer of a former link
+	db.peers["hq"] = []domain.Peer{{Identifier: "regular-peer", InterfaceIdentifier: "hq"}}
+	controller.peers["hq"] = map[string]domain.PhysicalPeer{
+		"regular-peer": {Identifier: "regular-peer", KeyPair: domain.KeyPair{PublicKey: "regular-peer"}},
+		"stale-peer":   {Identifier: "stale-peer", KeyPair: domain.KeyPair{PublicKey: "stale-peer"}},
+	}
+
+	_, err := m.CreateMesh(adminContext(), newTestMesh())
+	require.NoError(t, err)
+
+	assert.Len(t, controller.peers["hq"], 4, "two mesh peers, the regular peer and the unknown peer")
+	assert.Contains(t, controller.peers["hq"], "regular-peer")
+	assert.Contains(t, controller.peers["hq"], "stale-peer", "unknown peers are kept by default")
+	assert.Len(t, controller.peers["branch-a"], 2)
+	assert.Len(t, controller.peers["branch-b"], 2)
+
+	pp := controller.peers["branch-b"][db.interfaces["hq"].PublicKey]
+	assert.Equal(t, "hq.example.com:51820", pp.Endpoint)
+	assert.Equal(t, []string{"10.100.0.1/32", "192.168.0.0/24"}, domain.CidrsToStringSlice(pp.AllowedIPs))
+	assert.Equal(t, 25, pp.PersistentKeepalive)
+
+	_, err = m.CreateMesh(adminContext(), newTestMesh())
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+}
+
+func TestManager_CreateMesh_RemoveUnknownPeers(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Advanced.MeshRemoveUnknownPeers = true
+	m, db, controller, _ := newTestManagerWithBus(t, cfg)
+
+	db.peers["hq"] = []domain.Peer{{Identifier: "regular-peer", InterfaceIdentifier: "hq"}}
+	controller.peers["hq"] = map[string]domain.PhysicalPeer{
+		"regular-peer": {Identifier: "regular-peer", KeyPair: domain.KeyPair{PublicKey: "regular-peer"}},
+		"stale-peer":   {Identifier: "stale-peer", KeyPair: domain.KeyPair{PublicKey: "stale-peer"}},
+	}
+
+	_, err := m.CreateMesh(adminContext(), newTestMesh())
+	require.NoError(t, err)
+
+	assert.Len(t, controller.peers["hq"], 3, "two mesh peers and the regular peer")
+	assert.NotContains(t, controller.peers["hq"], "stale-peer")
+}
+
+func TestManager_CreateMesh_Routes(t *testing.T) {
+	m, _, _, bus := newTestManagerWithBus(t, &config.Config{})
+
+	_, err := m.CreateMesh(adminContext(), newTestMesh())
+	require.NoError(t, err)
+
+	var nodes []domain.InterfaceIdentifier
+	for _, info := range bus.routes {
+		nodes = append(nodes, info.Interface.Identifier)
+	}
+	assert.ElementsMatch(t, []domain.InterfaceIdentifier{"hq", "branch-a", "branch-b"}, nodes,
+		"the routes of all nodes are updated")
+}
+
+func TestManager_CreateMesh_Invalid(t *testing.T) {
+	m, db, _ := newTestManager(t)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user", IsAdmin: false})
+	_, err := m.CreateMesh(userCtx, newTestMesh())
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	mesh := newTestMesh()
+	mesh.Nodes[2].InterfaceIdentifier = "unknown"
+	_, err = m.CreateMesh(adminContext(), mesh)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	// without an endpoint on either side, branch-a and branch-b can not connect to each other
+	db.interfaces["branch-a"].PeerDefEndpoint = ""
+	_, err = m.CreateMesh(adminContext(), newTestMesh())
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	assert.Empty(t, db.meshes)
+}
+
+func TestManager_NodeChanges(t *testing.T) {
+	m, db, controller := newTestManager(t)
+
+	_, err := m.CreateMesh(adminContext(), newTestMesh())
+	require.NoError(t, err)
+
+	// a changed endpoint and subnet of a node is propagated to the other nodes
+	db.interfaces["hq"].PeerDefEndpoint = "vpn.example.com:51000"
+	db.interfaces["hq"].Addresses, _ = domain.CidrsFromString("10.100.0.10/24")
+	m.handleInterfaceSavedEvent(*db.interfaces["hq"])
+
+	pp := controller.peers["branch-a"][db.interfaces["hq"].PublicKey]
+	assert.Equal(t, "vpn.example.com:51000", pp.Endpoint)
+	assert.Equal(t, []string{"10.100.0.10/32", "192.168.0.0/24"}, domain.CidrsToStringSlice(pp.AllowedIPs))
+
+	// switching to hub-and-spoke removes the links between the spokes
+	mesh := newTestMesh()
+	mesh.Topology = domain.MeshTopologyHubAndSpoke
+	mesh.Nodes[0].Hub = true
+	_, err = m.UpdateMesh(adminContext(), mesh)
+	require.NoError(t, err)
+	assert.Len(t, controller.peers["hq"], 2)
+	assert.Len(t, controller.peers["branch-a"], 1)
+	assert.Len(t, controller.peers["branch-b"], 1)
+
+	// deleting the mesh removes all generated peers
+	require.NoError(t, m.DeleteMesh(adminContext(), "branches"))
+	assert.Empty(t, controller.peers["hq"])
+	assert.Empty(t, controller.peers["branch-a"])
+	assert.Empty(t, controller.peers["branch-b"])
+}
+
+func TestManager_NodeDeleted(t *testing.T) {
+	m, db, controller := newTestManager(t)
+
+	_, err := m.CreateMesh(adminContext(), newTestMesh())
+	require.NoError(t, err)
+
+	deleted := db.interfaces["branch-b"]
+	delete(db.interfaces, "branch-b")
+	m.handleInterfaceSavedEvent(*deleted)
+
+	assert.NotContains(t, controller.peers["hq"], deleted.PublicKey)
+	assert.NotContains(t, controller.peers["branch-a"], deleted.PublicKey)
+	assert.Len(t, controller.peers["hq"], 1)
+}
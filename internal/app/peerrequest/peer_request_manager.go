@@ -0,0 +1,338 @@
+package peerrequest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// region dependencies
+
+type DatabaseRepo interface {
+	// GetPeerRequest returns the peer request with the given identifier.
+	GetPeerRequest(ctx context.Context, id domain.PeerRequestIdentifier) (*domain.PeerRequest, error)
+	// GetAllPeerRequests returns all peer requests.
+	GetAllPeerRequests(ctx context.Context) ([]domain.PeerRequest, error)
+	// GetUserPeerRequests returns all peer requests of the given user.
+	GetUserPeerRequests(ctx context.Context, id domain.UserIdentifier) ([]domain.PeerRequest, error)
+	// SavePeerRequest creates or updates the given peer request.
+	SavePeerRequest(ctx context.Context, request *domain.PeerRequest) error
+	// GetInterface returns the interface with the given identifier.
+	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetUser returns the user with the given identifier.
+	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
+	// GetAllUsers returns all users.
+	GetAllUsers(ctx context.Context) ([]domain.User, error)
+}
+
+type PeerManager interface {
+	// PreparePeer returns a new peer with fresh keys and addresses for the given interface.
+	PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error)
+	// CreatePeer creates the given peer.
+	CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Peer, error)
+}
+
+type Mailer interface {
+	// SendPeerEmail sends the configuration of the given peers to their owners.
+	SendPeerEmail(
+		ctx context.Context,
+		linkOnly bool,
+		style string,
+		mobileConfig domain.MobileConfigPlatform,
+		peers ...domain.PeerIdentifier,
+	) error
+	// SendPeerRequestEmail notifies the given recipients about the peer request.
+	SendPeerRequestEmail(ctx context.Context, request *domain.PeerRequest, recipients ...domain.User) error
+}
+
+type EventBus interface {
+	// Publish sends a message to the message bus.
+	Publish(topic string, args ...any)
+}
+
+// endregion dependencies
+
+// Manager handles peer requests of users. Requests are stored in a pending state until an admin approves or
+// rejects them. Approved requests are turned into peers, and the configuration is mailed to the requesting user.
+type Manager struct {
+	cfg *config.Config
+	bus EventBus
+
+	db    DatabaseRepo
+	peers PeerManager
+	mail  Mailer
+
+	decisions *sync.Mutex // serializes approvals and rejections, so that a request is only decided once
+}
+
+// NewPeerRequestManager creates a new peer request manager.
+func NewPeerRequestManager(
+	cfg *config.Config,
+	bus EventBus,
+	db DatabaseRepo,
+	peers PeerManager,
+	mail Mailer,
+) (*Manager, error) {
+	return &Manager{
+		cfg:   cfg,
+		bus:   bus,
+		db:    db,
+		peers: peers,
+		mail:  mail,
+
+		decisions: &sync.Mutex{},
+	}, nil
+}
+
+// SubmitPeerRequest stores a new pending peer request and notifies all admins.
+// If no user is set, the request is submitted for the authenticated user.
+func (m Manager) SubmitPeerRequest(ctx context.Context, request *domain.PeerRequest) (*domain.PeerRequest, error) {
+	currentUser := domain.GetUserInfo(ctx)
+	if !m.cfg.Core.PeerRequestsAllowed && !currentUser.IsAdmin {
+		return nil, fmt.Errorf("peer requests are disabled: %w", domain.ErrNoPermission)
+	}
+
+	if request.UserIdentifier == "" {
+		request.UserIdentifier = currentUser.Id
+	}
+	if err := domain.ValidateUserAccessRights(ctx, request.UserIdentifier); err != nil {
+		return nil, err
+	}
+
+	request.DisplayName = strings.TrimSpace(request.DisplayName)
+	request.Justification = strings.TrimSpace(request.Justification)
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	iface, err := m.db.GetInterface(ctx, request.InterfaceIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", request.InterfaceIdentifier, err)
+	}
+	if iface.Type != domain.InterfaceTypeServer || iface.IsDisabled() {
+		return nil, fmt.Errorf("peers can only be requested for enabled server interfaces: %w",
+			domain.ErrInvalidData)
+	}
+	if !iface.IsUserAllowed(request.UserIdentifier, m.cfg) {
+		return nil, fmt.Errorf("user %s is not allowed to use interface %s: %w", request.UserIdentifier,
+			request.InterfaceIdentifier, domain.ErrNoPermission)
+	}
+
+	existing, err := m.db.GetUserPeerRequests(ctx, request.UserIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load existing requests: %w", err)
+	}
+	for _, r := range existing {
+		if r.IsPending() && r.InterfaceIdentifier == request.InterfaceIdentifier {
+			return nil, fmt.Errorf("a request for interface %s is already pending: %w",
+				request.InterfaceIdentifier, domain.ErrDuplicateEntry)
+		}
+	}
+
+	request.Identifier = domain.PeerRequestIdentifier(uuid.New().String())
+	request.State = domain.PeerRequestStatePending
+	request.DecidedBy = ""
+	request.DecidedAt = nil
+	request.DecisionReason = ""
+	request.PeerIdentifier = ""
+
+	if err := m.db.SavePeerRequest(ctx, request); err != nil {
+		return nil, fmt.Errorf("failed to save peer request: %w", err)
+	}
+
+	m.bus.Publish(app.TopicPeerRequestCreated, *request)
+
+	// notifications are sent with system privileges, as the requesting user is not allowed to read other users
+	m.notifyAdmins(domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo()), request)
+
+	return request, nil
+}
+
+// GetAllPeerRequests returns all peer requests. If pendingOnly is set, decided requests are omitted.
+func (m Manager) GetAllPeerRequests(ctx context.Context, pendingOnly bool) ([]domain.PeerRequest, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	requests, err := m.db.GetAllPeerRequests(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load peer requests: %w", err)
+	}
+
+	return filterPending(requests, pendingOnly), nil
+}
+
+// GetUserPeerRequests returns all peer requests of the given user.
+func (m Manager) GetUserPeerRequests(ctx context.Context, id domain.UserIdentifier) ([]domain.PeerRequest, error) {
+	if err := domain.ValidateUserAccessRights(ctx, id); err != nil {
+		return nil, err
+	}
+
+	requests, err := m.db.GetUserPeerRequests(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load peer requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// GetPeerRequest returns the peer request with the given identifier.
+func (m Manager) GetPeerRequest(ctx context.Context, id domain.PeerRequestIdentifier) (*domain.PeerRequest, error) {
+	request, err := m.db.GetPeerRequest(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load peer request %s: %w", id, err)
+	}
+
+	if err := domain.ValidateUserAccessRights(ctx, request.UserIdentifier); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// ApprovePeerRequest creates the requested peer and mails its configuration to the requesting user.
+func (m Manager) ApprovePeerRequest(
+	ctx context.Context,
+	id domain.PeerRequestIdentifier,
+	reason string,
+) (*domain.PeerRequest, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	// the pending state must be checked again while holding the lock, another admin might have decided meanwhile
+	m.decisions.Lock()
+	defer m.decisions.Unlock()
+
+	request, err := m.getPendingRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	peer, err := m.peers.PreparePeer(ctx, request.InterfaceIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare peer: %w", err)
+	}
+	peer.UserIdentifier = request.UserIdentifier
+	peer.DisplayName = request.DisplayName
+	if request.Justification != "" {
+		peer.Notes = request.Justification
+	}
+
+	peer, err = m.peers.CreatePeer(ctx, peer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create peer: %w", err)
+	}
+
+	request.Decide(domain.PeerRequestStateApproved, domain.GetUserInfo(ctx).Id, strings.TrimSpace(reason))
+	request.PeerIdentifier = peer.Identifier
+	if err := m.db.SavePeerRequest(ctx, request); err != nil {
+		return nil, fmt.Errorf("failed to save peer request: %w", err)
+	}
+
+	m.bus.Publish(app.TopicPeerRequestUpdated, *request)
+
+	if err := m.mail.SendPeerEmail(ctx, false, domain.ConfigStyleWgQuick, "", peer.Identifier); err != nil {
+		slog.Warn("failed to send configuration of approved peer request",
+			"request", request.Identifier, "peer", peer.Identifier, "error", err)
+	}
+
+	return request, nil
+}
+
+// RejectPeerRequest rejects the peer request and notifies the requesting user.
+func (m Manager) RejectPeerRequest(
+	ctx context.Context,
+	id domain.PeerRequestIdentifier,
+	reason string,
+) (*domain.PeerRequest, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	m.decisions.Lock()
+	defer m.decisions.Unlock()
+
+	request, err := m.getPendingRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Decide(domain.PeerRequestStateRejected, domain.GetUserInfo(ctx).Id, strings.TrimSpace(reason))
+	if err := m.db.SavePeerRequest(ctx, request); err != nil {
+		return nil, fmt.Errorf("failed to save peer request: %w", err)
+	}
+
+	m.bus.Publish(app.TopicPeerRequestUpdated, *request)
+
+	user, err := m.db.GetUser(ctx, request.UserIdentifier)
+	if err != nil {
+		slog.Warn("failed to load user of rejected peer request",
+			"request", request.Identifier, "user", request.UserIdentifier, "error", err)
+		return request, nil
+	}
+	if err := m.mail.SendPeerRequestEmail(ctx, request, *user); err != nil {
+		slog.Warn("failed to send peer request rejection", "request", request.Identifier, "error", err)
+	}
+
+	return request, nil
+}
+
+func (m Manager) getPendingRequest(ctx context.Context, id domain.PeerRequestIdentifier) (
+	*domain.PeerRequest,
+	error,
+) {
+	request, err := m.db.GetPeerRequest(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load peer request %s: %w", id, err)
+	}
+
+	if !request.IsPending() {
+		return nil, fmt.Errorf("peer request %s has already been %s: %w", id, request.State, domain.ErrInvalidData)
+	}
+
+	return request, nil
+}
+
+func (m Manager) notifyAdmins(ctx context.Context, request *domain.PeerRequest) {
+	users, err := m.db.GetAllUsers(ctx)
+	if err != nil {
+		slog.Warn("failed to load admins for peer request notification", "request", request.Identifier,
+			"error", err)
+		return
+	}
+
+	admins := make([]domain.User, 0, len(users))
+	for _, user := range users {
+		if user.IsAdmin && !user.IsDisabled() && !user.IsLocked() {
+			admins = append(admins, user)
+		}
+	}
+
+	if err := m.mail.SendPeerRequestEmail(ctx, request, admins...); err != nil {
+		slog.Warn("failed to notify admins about peer request", "request", request.Identifier, "error", err)
+	}
+}
+
+func filterPending(requests []domain.PeerRequest, pendingOnly bool) []domain.PeerRequest {
+	if !pendingOnly {
+		return requests
+	}
+
+	pending := make([]domain.PeerRequest, 0, len(requests))
+	for _, request := range requests {
+		if request.IsPending() {
+			pending = append(pending, request)
+		}
+	}
+
+	return pending
+}
@@ -0,0 +1,296 @@
+package peerrequest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockDatabase struct {
+	requests   map[domain.PeerRequestIdentifier]*domain.PeerRequest
+	interfaces map[domain.InterfaceIdentifier]*domain.Interface
+	users      []domain.User
+}
+
+func (m *mockDatabase) GetPeerRequest(_ context.Context, id domain.PeerRequestIdentifier) (
+	*domain.PeerRequest,
+	error,
+) {
+	r, ok := m.requests[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	cpy := *r
+	return &cpy, nil
+}
+
+func (m *mockDatabase) GetAllPeerRequests(_ context.Context) ([]domain.PeerRequest, error) {
+	requests := make([]domain.PeerRequest, 0, len(m.requests))
+	for _, r := range m.requests {
+		requests = append(requests, *r)
+	}
+	return requests, nil
+}
+
+func (m *mockDatabase) GetUserPeerRequests(_ context.Context, id domain.UserIdentifier) (
+	[]domain.PeerRequest,
+	error,
+) {
+	requests := make([]domain.PeerRequest, 0, len(m.requests))
+	for _, r := range m.requests {
+		if r.UserIdentifier == id {
+			requests = append(requests, *r)
+		}
+	}
+	return requests, nil
+}
+
+func (m *mockDatabase) SavePeerRequest(_ context.Context, request *domain.PeerRequest) error {
+	cpy := *request
+	m.requests[request.Identifier] = &cpy
+	return nil
+}
+
+func (m *mockDatabase) GetInterface(_ context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
+	iface, ok := m.interfaces[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return iface, nil
+}
+
+func (m *mockDatabase) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	for i := range m.users {
+		if m.users[i].Identifier == id {
+			return &m.users[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockDatabase) GetAllUsers(_ context.Context) ([]domain.User, error) {
+	return m.users, nil
+}
+
+type mockPeerManager struct {
+	created []domain.Peer
+}
+
+func (m *mockPeerManager) PreparePeer(_ context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
+	return &domain.Peer{Identifier: "peer-key", InterfaceIdentifier: id}, nil
+}
+
+func (m *mockPeerManager) CreatePeer(_ context.Context, peer *domain.Peer) (*domain.Peer, error) {
+	m.created = append(m.created, *peer)
+	return peer, nil
+}
+
+type mockMailer struct {
+	configs    []domain.PeerIdentifier
+	recipients map[domain.PeerRequestState][]domain.UserIdentifier
+}
+
+func (m *mockMailer) SendPeerEmail(
+	_ context.Context,
+	_ bool,
+	_ string,
+	_ domain.MobileConfigPlatform,
+	peers ...domain.PeerIdentifier,
+) error {
+	m.configs = append(m.configs, peers...)
+	return nil
+}
+
+func (m *mockMailer) SendPeerRequestEmail(
+	_ context.Context,
+	request *domain.PeerRequest,
+	recipients ...domain.User,
+) error {
+	for _, r := range recipients {
+		m.recipients[request.State] = append(m.recipients[request.State], r.Identifier)
+	}
+	return nil
+}
+
+type mockBus struct {
+	topics []string
+}
+
+func (m *mockBus) Publish(topic string, _ ...any) {
+	m.topics = append(m.topics, topic)
+}
+
+func newTestManager(allowed bool) (*Manager, *mockDatabase, *mockPeerManager, *mockMailer) {
+	now := time.Now()
+	db := &mockDatabase{
+		requests: map[domain.PeerRequestIdentifier]*domain.PeerRequest{},
+		interfaces: map[domain.InterfaceIdentifier]*domain.Interface{
+			"wg0":    {Identifier: "wg0", Type: domain.InterfaceTypeServer},
+			"client": {Identifier: "client", Type: domain.InterfaceTypeClient},
+		},
+		users: []domain.User{
+			{Identifier: "alice"},
+			{Identifier: "admin", IsAdmin: true},
+			{Identifier: "locked-admin", IsAdmin: true, Locked: &now},
+		},
+	}
+	peers := &mockPeerManager{}
+	mailer := &mockMailer{recipients: map[domain.PeerRequestState][]domain.UserIdentifier{}}
+
+	cfg := &config.Config{}
+	cfg.Core.PeerRequestsAllowed = allowed
+	m, _ := NewPeerRequestManager(cfg, &mockBus{}, db, peers, mailer)
+
+	return m, db, peers, mailer
+}
+
+func userCtx(id domain.UserIdentifier, admin bool) context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: id, IsAdmin: admin})
+}
+
+func TestManager_SubmitPeerRequest(t *testing.T) {
+	m, db, _, mailer := newTestManager(true)
+	ctx := userCtx("alice", false)
+
+	req, err := m.SubmitPeerRequest(ctx, &domain.PeerRequest{
+		InterfaceIdentifier: "wg0",
+		DisplayName:         "  Laptop ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.UserIdentifier("alice"), req.UserIdentifier)
+	assert.Equal(t, "Laptop", req.DisplayName)
+	assert.Equal(t, domain.PeerRequestStatePending, req.State)
+	assert.NotEmpty(t, req.Identifier)
+	assert.Len(t, db.requests, 1)
+	assert.Equal(t, []domain.UserIdentifier{"admin"}, mailer.recipients[domain.PeerRequestStatePending])
+
+	_, err = m.SubmitPeerRequest(ctx, &domain.PeerRequest{InterfaceIdentifier: "wg0", DisplayName: "Phone"})
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+}
+
+func TestManager_SubmitPeerRequest_Invalid(t *testing.T) {
+	m, _, _, _ := newTestManager(true)
+	ctx := userCtx("alice", false)
+
+	_, err := m.SubmitPeerRequest(ctx, &domain.PeerRequest{InterfaceIdentifier: "client", DisplayName: "Laptop"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.SubmitPeerRequest(ctx, &domain.PeerRequest{InterfaceIdentifier: "wg0"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, err = m.SubmitPeerRequest(ctx, &domain.PeerRequest{
+		UserIdentifier:      "bob",
+		InterfaceIdentifier: "wg0",
+		DisplayName:         "Laptop",
+	})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_SubmitPeerRequest_Disabled(t *testing.T) {
+	m, _, _, _ := newTestManager(false)
+
+	_, err := m.SubmitPeerRequest(userCtx("alice", false),
+		&domain.PeerRequest{InterfaceIdentifier: "wg0", DisplayName: "Laptop"})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_ApprovePeerRequest(t *testing.T) {
+	m, db, peers, mailer := newTestManager(true)
+
+	req, err := m.SubmitPeerRequest(userCtx("alice", false), &domain.PeerRequest{
+		InterfaceIdentifier: "wg0",
+		DisplayName:         "Laptop",
+		Justification:       "home office",
+	})
+	require.NoError(t, err)
+
+	_, err = m.ApprovePeerRequest(userCtx("alice", false), req.Identifier, "")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	approved, err := m.ApprovePeerRequest(userCtx("admin", true), req.Identifier, "ok")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerRequestStateApproved, approved.State)
+	assert.Equal(t, "admin", approved.DecidedBy)
+	assert.Equal(t, domain.PeerIdentifier("peer-key"), approved.PeerIdentifier)
+	assert.Equal(t, domain.PeerRequestStateApproved, db.requests[req.Identifier].State)
+
+	require.Len(t, peers.created, 1)
+	assert.Equal(t, domain.UserIdentifier("alice"), peers.created[0].UserIdentifier)
+	assert.Equal(t, "Laptop", peers.created[0].DisplayName)
+	assert.Equal(t, "home office", peers.created[0].Notes)
+	assert.Equal(t, []domain.PeerIdentifier{"peer-key"}, mailer.configs)
+
+	_, err = m.RejectPeerRequest(userCtx("admin", true), req.Identifier, "")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
+
+func TestManager_ApprovePeerRequest_Concurrent(t *testing.T) {
+	m, db, peers, _ := newTestManager(true)
+
+	req, err := m.SubmitPeerRequest(userCtx("alice", false),
+		&domain.PeerRequest{InterfaceIdentifier: "wg0", DisplayName: "Laptop"})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	failures := 0
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.ApprovePeerRequest(userCtx("admin", true), req.Identifier, "")
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrInvalidData)
+				failures++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, peers.created, 1)
+	assert.Equal(t, 4, failures)
+	assert.Equal(t, domain.PeerRequestStateApproved, db.requests[req.Identifier].State)
+}
+
+func TestManager_SubmitPeerRequest_RestrictedInterface(t *testing.T) {
+	m, db, _, _ := newTestManager(true)
+	m.cfg.Auth.Ldap = []config.LdapProvider{{
+		ProviderName:    "ldap",
+		InterfaceFilter: map[string]string{"wg0": "(memberOf=CN=VPNUsers)"},
+	}}
+	db.interfaces["wg0"].LdapAllowedUsers = map[string][]domain.UserIdentifier{"ldap": {"bob"}}
+
+	_, err := m.SubmitPeerRequest(userCtx("alice", false),
+		&domain.PeerRequest{InterfaceIdentifier: "wg0", DisplayName: "Laptop"})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+	assert.Empty(t, db.requests)
+}
+
+func TestManager_RejectPeerRequest(t *testing.T) {
+	m, _, peers, mailer := newTestManager(true)
+
+	req, err := m.SubmitPeerRequest(userCtx("alice", false),
+		&domain.PeerRequest{InterfaceIdentifier: "wg0", DisplayName: "Laptop"})
+	require.NoError(t, err)
+
+	rejected, err := m.RejectPeerRequest(userCtx("admin", true), req.Identifier, " not needed ")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PeerRequestStateRejected, rejected.State)
+	assert.Equal(t, "not needed", rejected.DecisionReason)
+	assert.Empty(t, peers.created)
+	assert.Equal(t, []domain.UserIdentifier{"alice"}, mailer.recipients[domain.PeerRequestStateRejected])
+
+	pending, err := m.GetAllPeerRequests(userCtx("admin", true), true)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
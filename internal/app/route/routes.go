@@ -2,8 +2,10 @@ package route
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 
 	"github.com/h44z/wg-portal/internal/app"
@@ -21,6 +23,8 @@ type ControllerManager interface {
 type InterfaceAndPeerDatabaseRepo interface {
 	// GetInterface returns the interface with the given identifier.
 	GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error)
+	// GetAllMeshes returns all meshes.
+	GetAllMeshes(ctx context.Context) ([]domain.Mesh, error)
 }
 
 type EventBus interface {
@@ -99,7 +103,14 @@ func (m Manager) handleRouteUpdateEvent(info domain.RoutingTableInfo) {
 		return // route management disabled
 	}
 
-	err := m.syncRoutes(context.Background(), info)
+	ctx := context.Background()
+	info, err := m.withMeshRoutes(ctx, info)
+	if err != nil {
+		slog.Error("failed to load mesh routes", "info", info.String(), "error", err)
+		return
+	}
+
+	err = m.syncRoutes(ctx, info)
 	if err != nil {
 		slog.Error("failed to synchronize routes",
 			"info", info.String(), "error", err)
@@ -119,7 +130,14 @@ func (m Manager) handleRouteRemoveEvent(info domain.RoutingTableInfo) {
 		return // route management disabled
 	}
 
-	err := m.removeRoutes(context.Background(), info)
+	ctx := context.Background()
+	info, err := m.withMeshRoutes(ctx, info)
+	if err != nil {
+		slog.Error("failed to load mesh routes", "info", info.String(), "error", err)
+		return
+	}
+
+	err = m.removeRoutes(ctx, info)
 	if err != nil {
 		slog.Error("failed to synchronize routes",
 			"info", info.String(), "error", err)
@@ -129,6 +147,52 @@ func (m Manager) handleRouteRemoveEvent(info domain.RoutingTableInfo) {
 	slog.Debug("routes removed", "info", info.String())
 }
 
+// withMeshRoutes adds the allowed IPs of the mesh peer entries of the interface to the routing info. The mesh peer
+// entries are not stored as peers, so they are not part of the allowed IPs of the published routing info.
+func (m Manager) withMeshRoutes(ctx context.Context, info domain.RoutingTableInfo) (domain.RoutingTableInfo, error) {
+	meshes, err := m.db.GetAllMeshes(ctx)
+	if err != nil {
+		return info, fmt.Errorf("unable to load meshes: %w", err)
+	}
+
+	id := info.Interface.Identifier
+	info.AllowedIps = slices.Clone(info.AllowedIps) // the slice is shared with other subscribers of the event
+	for _, mesh := range meshes {
+		if !mesh.HasNode(id) {
+			continue
+		}
+
+		interfaces := make(map[domain.InterfaceIdentifier]*domain.Interface, len(mesh.Nodes))
+		for _, node := range mesh.Nodes {
+			iface, err := m.db.GetInterface(ctx, node.InterfaceIdentifier)
+			if errors.Is(err, domain.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return info, fmt.Errorf("unable to load interface %s: %w", node.InterfaceIdentifier, err)
+			}
+			interfaces[node.InterfaceIdentifier] = iface
+		}
+
+		peers, err := mesh.Peers(interfaces)
+		if err != nil {
+			return info, fmt.Errorf("failed to generate peers of mesh %s: %w", mesh.Identifier, err)
+		}
+		for _, peer := range peers {
+			if peer.InterfaceIdentifier != id {
+				continue
+			}
+			for _, cidr := range peer.AllowedIPs {
+				if !slices.Contains(info.AllowedIps, cidr) {
+					info.AllowedIps = append(info.AllowedIps, cidr)
+				}
+			}
+		}
+	}
+
+	return info, nil
+}
+
 func (m Manager) syncRoutes(ctx context.Context, info domain.RoutingTableInfo) error {
 	rc, ok := m.wgController.GetController(info.Interface).(RoutesController)
 	if !ok {
@@ -106,6 +106,16 @@ func (m Manager) updateLdapUsers(
 	fields *config.LdapFields,
 	adminGroupDN *ldap.DN,
 ) error {
+	// users in the recycle bin are not re-created, they must be restored or purged first
+	deletedUsers, err := m.users.GetDeletedUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load deleted users: %w", err)
+	}
+	deleted := make(map[domain.UserIdentifier]struct{}, len(deletedUsers))
+	for _, user := range deletedUsers {
+		deleted[user.Identifier] = struct{}{}
+	}
+
 	for _, rawUser := range rawUsers {
 		user, err := convertRawLdapUser(provider.ProviderName, rawUser, fields, adminGroupDN)
 		if err != nil && !errors.Is(err, domain.ErrNotFound) {
@@ -123,6 +133,11 @@ func (m Manager) updateLdapUsers(
 				"is-admin", user.IsAdmin, "provider", provider.ProviderName)
 		}
 
+		if _, ok := deleted[user.Identifier]; ok {
+			slog.Debug("skipping LDAP user in recycle bin", "user", user.Identifier, "provider", provider.ProviderName)
+			continue
+		}
+
 		existingUser, err := m.users.GetUser(ctx, user.Identifier)
 		if err != nil && !errors.Is(err, domain.ErrNotFound) {
 			return fmt.Errorf("find error for user id %s: %w", user.Identifier, err)
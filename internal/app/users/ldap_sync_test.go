@@ -0,0 +1,82 @@
+package users
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// mockUserDatabase stores the active and deleted users, the remaining repository functions are not used.
+type mockUserDatabase struct {
+	UserDatabaseRepo
+
+	users   map[domain.UserIdentifier]*domain.User
+	deleted []domain.User
+}
+
+func (m *mockUserDatabase) GetUser(_ context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	user, ok := m.users[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	cpy := *user
+	return &cpy, nil
+}
+
+func (m *mockUserDatabase) SaveUser(
+	_ context.Context,
+	id domain.UserIdentifier,
+	updateFunc func(u *domain.User) (*domain.User, error),
+) error {
+	for _, user := range m.deleted {
+		if user.Identifier == id {
+			return domain.ErrDuplicateEntry // like the database, deleted users can not be re-created
+		}
+	}
+
+	user, ok := m.users[id]
+	if !ok {
+		user = &domain.User{Identifier: id}
+	}
+	updated, err := updateFunc(user)
+	if err != nil {
+		return err
+	}
+	m.users[id] = updated
+	return nil
+}
+
+func (m *mockUserDatabase) GetDeletedUsers(_ context.Context) ([]domain.User, error) {
+	return m.deleted, nil
+}
+
+type mockBus struct{}
+
+func (b mockBus) Publish(_ string, _ ...any) {}
+
+func TestManager_UpdateLdapUsers_SkipsDeletedUsers(t *testing.T) {
+	db := &mockUserDatabase{
+		users:   make(map[domain.UserIdentifier]*domain.User),
+		deleted: []domain.User{{Identifier: "bob"}},
+	}
+	m, err := NewUserManager(&config.Config{}, mockBus{}, db, nil, nil)
+	require.NoError(t, err)
+
+	rawUsers := []internal.RawLdapUser{
+		makeRawLdapUser("alice", "alice@example.com", "Alice", "Smith", "", ""),
+		makeRawLdapUser("bob", "bob@example.com", "Bob", "Jones", "", ""),
+	}
+	provider := &config.LdapProvider{ProviderName: "test-ldap"}
+
+	err = m.updateLdapUsers(context.Background(), provider, rawUsers, makeTestLdapFields(), makeTestAdminGroupDN(t))
+	require.NoError(t, err, "users in the recycle bin do not fail the synchronization")
+
+	assert.Contains(t, db.users, domain.UserIdentifier("alice"))
+	assert.NotContains(t, db.users, domain.UserIdentifier("bob"))
+}
@@ -0,0 +1,83 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// GetDeletedUsers returns all users in the recycle bin.
+func (m Manager) GetDeletedUsers(ctx context.Context) ([]domain.User, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.users.GetDeletedUsers(ctx)
+}
+
+// RestoreUser moves the user with the given identifier out of the recycle bin. Peers of the user are not restored,
+// they were either moved to the recycle bin themselves or disabled and unlinked when the user was deleted.
+func (m Manager) RestoreUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := m.users.RestoreUser(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to restore user %s: %w", id, err)
+	}
+
+	user, err := m.users.GetUser(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load restored user %s: %w", id, err)
+	}
+
+	m.bus.Publish(app.TopicUserUpdated, *user)
+
+	return user, nil
+}
+
+func (m Manager) runRecycleBinPurge(ctx context.Context) {
+	if m.cfg.Advanced.DeletionRetention <= 0 {
+		return // users are deleted permanently right away
+	}
+
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(m.cfg.Advanced.ExpiryCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		m.purgeDeletedUsers(ctx, time.Now().Add(-m.cfg.Advanced.DeletionRetention))
+	}
+}
+
+// purgeDeletedUsers permanently deletes all users that were moved to the recycle bin before the given time.
+func (m Manager) purgeDeletedUsers(ctx context.Context, deletedBefore time.Time) {
+	users, err := m.users.GetDeletedUsers(ctx)
+	if err != nil {
+		slog.Error("failed to fetch deleted users for purge", "error", err)
+		return
+	}
+
+	for _, user := range users {
+		if !user.DeletedAt.Time.Before(deletedBefore) {
+			continue
+		}
+
+		slog.Info("purging deleted user", "user", user.Identifier, "deleted", user.DeletedAt.Time)
+
+		if err := m.users.DeleteUser(ctx, user.Identifier); err != nil {
+			slog.Error("failed to purge deleted user", "user", user.Identifier, "error", err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"reflect"
 	"sync"
 	"time"
 
@@ -30,8 +31,14 @@ type UserDatabaseRepo interface {
 	FindUsers(ctx context.Context, search string) ([]domain.User, error)
 	// SaveUser saves the user with the given identifier.
 	SaveUser(ctx context.Context, id domain.UserIdentifier, updateFunc func(u *domain.User) (*domain.User, error)) error
-	// DeleteUser deletes the user with the given identifier.
+	// DeleteUser permanently deletes the user with the given identifier.
 	DeleteUser(ctx context.Context, id domain.UserIdentifier) error
+	// SoftDeleteUser moves the user with the given identifier to the recycle bin.
+	SoftDeleteUser(ctx context.Context, id domain.UserIdentifier) error
+	// RestoreUser moves the user with the given identifier out of the recycle bin.
+	RestoreUser(ctx context.Context, id domain.UserIdentifier) error
+	// GetDeletedUsers returns all users in the recycle bin.
+	GetDeletedUsers(ctx context.Context) ([]domain.User, error)
 }
 
 type PeerDatabaseRepo interface {
@@ -100,6 +107,7 @@ func (m Manager) RegisterUser(ctx context.Context, user *domain.User) error {
 // This method is non-blocking and returns immediately.
 func (m Manager) StartBackgroundJobs(ctx context.Context) {
 	go m.runLdapSynchronizationService(ctx)
+	go m.runRecycleBinPurge(ctx)
 }
 
 // GetUser returns the user with the given identifier.
@@ -183,6 +191,20 @@ func (m Manager) UpdateUser(ctx context.Context, user *domain.User) (*domain.Use
 	}
 
 	user.CopyCalculatedAttributes(existingUser, true) // ensure that crucial attributes stay the same
+	if domain.GetUserInfo(ctx).Id != domain.CtxSystemDeclarative {
+		user.ManagedBy = existingUser.ManagedBy // only the state file marks or releases users
+	}
+	if m.cfg.Declarative.ProtectManaged {
+		if err := domain.ValidateManagedChange(ctx, existingUser.ManagedBy); err != nil {
+			return nil, err
+		}
+	}
+	if user.AccessSchedule == nil {
+		user.AccessSchedule = existingUser.AccessSchedule // an empty schedule must be sent to remove it
+	}
+	if user.Acl == nil {
+		user.Acl = existingUser.Acl // an empty list must be sent to remove it
+	}
 
 	return m.update(ctx, existingUser, user, true)
 }
@@ -207,7 +229,8 @@ func (m Manager) CreateUser(ctx context.Context, user *domain.User) (*domain.Use
 	return m.create(ctx, user)
 }
 
-// DeleteUser deletes the user with the given identifier.
+// DeleteUser deletes the user with the given identifier. If a deletion retention is configured,
+// the user is kept in the recycle bin until the retention period is over.
 func (m Manager) DeleteUser(ctx context.Context, id domain.UserIdentifier) error {
 	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
 		return err
@@ -222,7 +245,11 @@ func (m Manager) DeleteUser(ctx context.Context, id domain.UserIdentifier) error
 		return fmt.Errorf("deletion not allowed: %w", err)
 	}
 
-	err = m.users.DeleteUser(ctx, id)
+	if m.cfg.Advanced.DeletionRetention > 0 {
+		err = m.users.SoftDeleteUser(ctx, id)
+	} else {
+		err = m.users.DeleteUser(ctx, id)
+	}
 	if err != nil {
 		return fmt.Errorf("deletion failure: %w", err)
 	}
@@ -311,6 +338,14 @@ func (m Manager) validateModifications(ctx context.Context, old, new *domain.Use
 		return fmt.Errorf("cannot lock own user: %w", domain.ErrInvalidData)
 	}
 
+	if err := new.AccessSchedule.Validate(); err != nil {
+		return fmt.Errorf("invalid access schedule: %w", err)
+	}
+
+	if err := new.Acl.Validate(); err != nil {
+		return fmt.Errorf("invalid access control list: %w", err)
+	}
+
 	return nil
 }
 
@@ -351,6 +386,14 @@ func (m Manager) validateAdminModifications(ctx context.Context, old, new *domai
 		return fmt.Errorf("cannot change disabled state: %w", domain.ErrInvalidData)
 	}
 
+	if !reflect.DeepEqual(old.AccessSchedule, new.AccessSchedule) {
+		return fmt.Errorf("cannot change access schedule: %w", domain.ErrInvalidData)
+	}
+
+	if !reflect.DeepEqual(old.Acl, new.Acl) {
+		return fmt.Errorf("cannot change access control list: %w", domain.ErrInvalidData)
+	}
+
 	return nil
 }
 
@@ -389,6 +432,14 @@ func (m Manager) validateCreation(ctx context.Context, new *domain.User) error {
 		return errors.Join(fmt.Errorf("password too weak: %w", err), domain.ErrInvalidData)
 	}
 
+	if err := new.AccessSchedule.Validate(); err != nil {
+		return fmt.Errorf("invalid access schedule: %w", err)
+	}
+
+	if err := new.Acl.Validate(); err != nil {
+		return fmt.Errorf("invalid access control list: %w", err)
+	}
+
 	return nil
 }
 
@@ -403,6 +454,12 @@ func (m Manager) validateDeletion(ctx context.Context, del *domain.User) error {
 		return errors.Join(fmt.Errorf("no access: %w", err), domain.ErrInvalidData)
 	}
 
+	if m.cfg.Declarative.ProtectManaged {
+		if err := domain.ValidateManagedChange(ctx, del.ManagedBy); err != nil {
+			return err
+		}
+	}
+
 	if currentUser.Id == del.Identifier {
 		return fmt.Errorf("cannot delete own user: %w", domain.ErrInvalidData)
 	}
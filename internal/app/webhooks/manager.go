@@ -67,6 +67,9 @@ func (m Manager) connectToMessageBus() {
 	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.handlePeerDeleteEvent)
 	_ = m.bus.Subscribe(app.TopicPeerStateChanged, m.handlePeerStateChangeEvent)
 
+	_ = m.bus.Subscribe(app.TopicPeerRequestCreated, m.handlePeerRequestCreateEvent)
+	_ = m.bus.Subscribe(app.TopicPeerRequestUpdated, m.handlePeerRequestUpdateEvent)
+
 	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceCreateEvent)
 	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.handleInterfaceUpdateEvent)
 	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.handleInterfaceDeleteEvent)
@@ -125,6 +128,14 @@ func (m Manager) handlePeerDeleteEvent(peer domain.Peer) {
 	m.handleGenericEvent(WebhookEventDelete, models.NewPeer(peer))
 }
 
+func (m Manager) handlePeerRequestCreateEvent(request domain.PeerRequest) {
+	m.handleGenericEvent(WebhookEventCreate, models.NewPeerRequest(request))
+}
+
+func (m Manager) handlePeerRequestUpdateEvent(request domain.PeerRequest) {
+	m.handleGenericEvent(WebhookEventUpdate, models.NewPeerRequest(request))
+}
+
 func (m Manager) handleInterfaceCreateEvent(iface domain.Interface) {
 	m.handleGenericEvent(WebhookEventCreate, models.NewInterface(iface))
 }
@@ -187,6 +198,9 @@ func (m Manager) createWebhookData(action WebhookEvent, payload any) (*WebhookDa
 	case models.Interface:
 		d.Entity = WebhookEntityInterface
 		d.Identifier = v.Identifier
+	case models.PeerRequest:
+		d.Entity = WebhookEntityPeerRequest
+		d.Identifier = v.Identifier
 	case models.PeerMetrics:
 		d.Entity = WebhookEntityPeerMetric
 		d.Identifier = v.Peer.Identifier
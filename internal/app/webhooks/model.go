@@ -34,10 +34,11 @@ func (d *WebhookData) Serialize() (io.Reader, error) {
 type WebhookEntity = string
 
 const (
-	WebhookEntityUser       WebhookEntity = "user"
-	WebhookEntityPeer       WebhookEntity = "peer"
-	WebhookEntityPeerMetric WebhookEntity = "peer_metric"
-	WebhookEntityInterface  WebhookEntity = "interface"
+	WebhookEntityUser        WebhookEntity = "user"
+	WebhookEntityPeer        WebhookEntity = "peer"
+	WebhookEntityPeerMetric  WebhookEntity = "peer_metric"
+	WebhookEntityPeerRequest WebhookEntity = "peer_request"
+	WebhookEntityInterface   WebhookEntity = "interface"
 )
 
 type WebhookEvent = string
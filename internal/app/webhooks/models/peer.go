@@ -17,6 +17,7 @@ type Peer struct {
 	EndpointPublicKey   string `json:"EndpointPublicKey"`
 	AllowedIPsStr       string `json:"AllowedIPsStr"`
 	ExtraAllowedIPsStr  string `json:"ExtraAllowedIPsStr"`
+	DelegatedPrefix     string `json:"DelegatedPrefix"`
 	PresharedKey        string `json:"PresharedKey"`
 	PersistentKeepalive int    `json:"PersistentKeepalive"`
 
@@ -60,6 +61,7 @@ func NewPeer(src domain.Peer) Peer {
 		EndpointPublicKey:    src.EndpointPublicKey.GetValue(),
 		AllowedIPsStr:        src.AllowedIPsStr.GetValue(),
 		ExtraAllowedIPsStr:   src.ExtraAllowedIPsStr,
+		DelegatedPrefix:      src.DelegatedPrefix,
 		PresharedKey:         string(src.PresharedKey),
 		PersistentKeepalive:  src.PersistentKeepalive.GetValue(),
 		DisplayName:          src.DisplayName,
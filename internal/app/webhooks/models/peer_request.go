@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// PeerRequest represents a peer request model for webhooks. For details about the fields,
+// see the domain.PeerRequest struct.
+type PeerRequest struct {
+	CreatedBy string    `json:"CreatedBy"`
+	UpdatedBy string    `json:"UpdatedBy"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+
+	Identifier          string `json:"Identifier"`
+	UserIdentifier      string `json:"UserIdentifier"`
+	InterfaceIdentifier string `json:"InterfaceIdentifier"`
+	DisplayName         string `json:"DisplayName"`
+	Justification       string `json:"Justification,omitempty"`
+
+	State          string     `json:"State"`
+	DecidedBy      string     `json:"DecidedBy,omitempty"`
+	DecidedAt      *time.Time `json:"DecidedAt,omitempty"`
+	DecisionReason string     `json:"DecisionReason,omitempty"`
+	PeerIdentifier string     `json:"PeerIdentifier,omitempty"`
+}
+
+// NewPeerRequest creates a new PeerRequest model from a domain.PeerRequest.
+func NewPeerRequest(src domain.PeerRequest) PeerRequest {
+	return PeerRequest{
+		CreatedBy:           src.CreatedBy,
+		UpdatedBy:           src.UpdatedBy,
+		CreatedAt:           src.CreatedAt,
+		UpdatedAt:           src.UpdatedAt,
+		Identifier:          string(src.Identifier),
+		UserIdentifier:      string(src.UserIdentifier),
+		InterfaceIdentifier: string(src.InterfaceIdentifier),
+		DisplayName:         src.DisplayName,
+		Justification:       src.Justification,
+		State:               string(src.State),
+		DecidedBy:           src.DecidedBy,
+		DecidedAt:           src.DecidedAt,
+		DecisionReason:      src.DecisionReason,
+		PeerIdentifier:      string(src.PeerIdentifier),
+	}
+}
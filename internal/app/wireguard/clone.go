@@ -0,0 +1,243 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// CloneInterface creates a new interface with the settings of an existing interface. The new interface gets fresh
+// keys, a free listen port and a free address range; peer defaults that refer to the old network or listen port are
+// adjusted. If requested, all peers are duplicated with fresh keys and addresses.
+func (m Manager) CloneInterface(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	req domain.InterfaceCloneRequest,
+) (*domain.Interface, []domain.Peer, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	source, sourcePeers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to find interface %s: %w", id, err)
+	}
+
+	clone, err := m.prepareInterfaceClone(ctx, source, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// default peers of the users would conflict with the cloned peers, so they are only enabled after cloning
+	createDefaultPeer := clone.CreateDefaultPeer
+	if req.ClonePeers {
+		clone.CreateDefaultPeer = false
+	}
+
+	clone, err = m.CreateInterface(ctx, clone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !req.ClonePeers {
+		return clone, nil, nil
+	}
+
+	peers := make([]domain.Peer, 0, len(sourcePeers))
+	for i := range sourcePeers {
+		peer, err := m.clonePeer(ctx, clone, &sourcePeers[i])
+		if err != nil {
+			return clone, peers, fmt.Errorf("failed to clone peer %s: %w", sourcePeers[i].Identifier, err)
+		}
+		peers = append(peers, *peer)
+	}
+
+	if createDefaultPeer {
+		err := m.db.SaveInterface(ctx, clone.Identifier, func(i *domain.Interface) (*domain.Interface, error) {
+			i.CreateDefaultPeer = true
+			return i, nil
+		})
+		if err != nil {
+			return clone, peers, fmt.Errorf("failed to enable default peers of interface %s: %w", clone.Identifier, err)
+		}
+		clone.CreateDefaultPeer = true
+	}
+
+	return clone, peers, nil
+}
+
+// prepareInterfaceClone copies the settings of the source interface to a new interface. Address reservations,
+// ip pools and prefix delegations are not copied, as they belong to the address range of the source interface.
+func (m Manager) prepareInterfaceClone(
+	ctx context.Context,
+	source *domain.Interface,
+	req domain.InterfaceCloneRequest,
+) (*domain.Interface, error) {
+	currentUser := domain.GetUserInfo(ctx)
+
+	backend := source.Backend
+	if req.Backend != "" {
+		backend = req.Backend
+		exists := slices.ContainsFunc(m.wg.GetControllerNames(), func(b config.BackendBase) bool {
+			return b.Id == string(backend)
+		})
+		if !exists {
+			return nil, fmt.Errorf("unknown backend %s: %w", backend, domain.ErrInvalidData)
+		}
+	}
+
+	id := req.Identifier
+	if id == "" {
+		var err error
+		if id, err = m.getNewInterfaceName(ctx); err != nil {
+			return nil, fmt.Errorf("failed to generate new identifier: %w", err)
+		}
+	}
+
+	kp, err := domain.NewFreshKeypair()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate keys: %w", err)
+	}
+
+	ipv4, ipv6, err := m.getFreshInterfaceIpConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate new ip config: %w", err)
+	}
+
+	port, err := m.getFreshListenPort(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate new listen port: %w", err)
+	}
+
+	ips := []domain.Cidr{ipv4}
+	networks := []domain.Cidr{ipv4.NetworkAddr()}
+	if m.cfg.Advanced.UseIpV6 {
+		ips = append(ips, ipv6)
+		networks = append(networks, ipv6.NetworkAddr())
+	}
+
+	clone := *source
+	clone.BaseModel = domain.BaseModel{
+		CreatedBy: string(currentUser.Id),
+		UpdatedBy: string(currentUser.Id),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	clone.Identifier = id
+	clone.KeyPair = kp
+	clone.ListenPort = port
+	clone.Addresses = ips
+	clone.DisplayName = req.DisplayName
+	if clone.DisplayName == "" {
+		clone.DisplayName = string(id)
+	}
+	clone.Backend = backend
+	if backend != source.Backend {
+		clone.DriverType = ""
+	}
+	clone.Disabled = nil
+	clone.DisabledReason = ""
+	clone.LdapAllowedUsers = nil
+	clone.ManagedBy = "" // the copy is not part of the declarative state, it can be edited freely
+	clone.IpamPolicy = nil
+	clone.PrefixDelegation = nil
+
+	clone.PeerDefNetworkStr = domain.CidrsToString(networks)
+	clone.PeerDefAllowedIPsStr = replaceNetworks(source.PeerDefAllowedIPsStr, source.PeerDefNetworkStr, networks)
+	clone.PeerDefEndpoint = replaceEndpointPort(source.PeerDefEndpoint, source.ListenPort, port)
+
+	return &clone, nil
+}
+
+// clonePeer creates a copy of the source peer on the given interface. The copy gets fresh keys and addresses, all
+// other settings of the source peer are kept.
+func (m Manager) clonePeer(ctx context.Context, iface *domain.Interface, source *domain.Peer) (*domain.Peer, error) {
+	peer, err := m.PreparePeer(ctx, iface.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare peer: %w", err)
+	}
+
+	peer.DisplayName = source.DisplayName
+	peer.UserIdentifier = source.UserIdentifier
+	peer.Notes = source.Notes
+	peer.Disabled = source.Disabled
+	peer.DisabledReason = source.DisabledReason
+	peer.ExpiresAt = source.ExpiresAt
+	peer.AutomaticallyCreated = source.AutomaticallyCreated
+	peer.AccessSchedule = source.AccessSchedule
+	peer.BandwidthLimit = source.BandwidthLimit
+	peer.Acl = source.Acl
+
+	keepCustomValue(&peer.Endpoint, source.Endpoint)
+	keepCustomValue(&peer.AllowedIPsStr, source.AllowedIPsStr)
+	keepCustomValue(&peer.PersistentKeepalive, source.PersistentKeepalive)
+	keepCustomValue(&peer.Interface.DnsStr, source.Interface.DnsStr)
+	keepCustomValue(&peer.Interface.DnsSearchStr, source.Interface.DnsSearchStr)
+	keepCustomValue(&peer.Interface.Mtu, source.Interface.Mtu)
+	keepCustomValue(&peer.Interface.FirewallMark, source.Interface.FirewallMark)
+	keepCustomValue(&peer.Interface.RoutingTable, source.Interface.RoutingTable)
+	keepCustomValue(&peer.Interface.PreUp, source.Interface.PreUp)
+	keepCustomValue(&peer.Interface.PostUp, source.Interface.PostUp)
+	keepCustomValue(&peer.Interface.PreDown, source.Interface.PreDown)
+	keepCustomValue(&peer.Interface.PostDown, source.Interface.PostDown)
+
+	if err := m.validatePeerCreation(ctx, nil, peer); err != nil {
+		return nil, fmt.Errorf("creation not allowed: %w", err)
+	}
+
+	if err := m.savePeers(ctx, peer); err != nil {
+		return nil, fmt.Errorf("creation failure: %w", err)
+	}
+
+	m.bus.Publish(app.TopicPeerCreated, *peer)
+
+	return peer, nil
+}
+
+// keepCustomValue copies a setting that was changed on the source peer instead of being inherited from the
+// interface defaults.
+func keepCustomValue[T any](dst *domain.ConfigOption[T], src domain.ConfigOption[T]) {
+	if !src.Overridable {
+		*dst = src
+	}
+}
+
+// replaceNetworks replaces the entries of the comma separated list that are one of the old networks with the new
+// network of the same address family. All other entries are kept.
+func replaceNetworks(list, oldNetworks string, newNetworks []domain.Cidr) string {
+	old, _ := domain.CidrsFromString(oldNetworks)
+
+	entries := internal.SliceString(list)
+	for i, entry := range entries {
+		cidr, err := domain.CidrFromString(entry)
+		if err != nil || !slices.ContainsFunc(old, cidr.EqualPrefix) {
+			continue
+		}
+		for _, network := range newNetworks {
+			if network.IsV4() == cidr.IsV4() {
+				entries[i] = network.String()
+				break
+			}
+		}
+	}
+
+	return internal.SliceToString(entries)
+}
+
+// replaceEndpointPort replaces the port of the endpoint if it is the old listen port.
+func replaceEndpointPort(endpoint string, oldPort, newPort int) string {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil || port != strconv.Itoa(oldPort) {
+		return endpoint
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(newPort))
+}
@@ -0,0 +1,134 @@
+package wireguard
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func newCloneTestManager(t *testing.T) (Manager, *mockDB, *domain.Peer) {
+	m, db := newIpamTestManager(t)
+	m.cfg.Advanced.StartCidrV4 = "10.11.0.0/24"
+	m.cfg.Advanced.StartCidrV6 = "fd11::/64"
+	m.cfg.Advanced.StartListenPort = 51820
+
+	db.iface.ListenPort = 51820
+	db.iface.Addresses = cidrs(t, "10.0.0.1/24")
+	db.iface.Backend = config.LocalBackendName
+	db.iface.Mtu = 1380
+	db.iface.RoutingTable = "100"
+	db.iface.PostUp = "iptables -A FORWARD -i %i -j ACCEPT"
+	db.iface.CreateDefaultPeer = true
+	db.iface.PeerDefDnsStr = "10.0.0.1"
+	db.iface.PeerDefAllowedIPsStr = "10.0.0.0/24,192.168.1.0/24"
+	db.iface.PeerDefEndpoint = "vpn.example.com:51820"
+	db.iface.PeerDefMtu = 1420
+	db.iface.PrefixDelegation = &domain.PrefixDelegationPolicy{Pool: "fd00:1::/48", PrefixLength: 64}
+
+	peer, err := m.PreparePeer(adminContext(), "wg0")
+	require.NoError(t, err)
+	peer.DisplayName = "laptop"
+	peer.UserIdentifier = "alice"
+	peer.Interface.Mtu = domain.NewConfigOption(1280, false)
+	peer, err = m.CreatePeer(adminContext(), peer)
+	require.NoError(t, err)
+
+	db.interfaces = []domain.Interface{*db.iface}
+
+	return m, db, peer
+}
+
+func TestManager_CloneInterface(t *testing.T) {
+	m, db, _ := newCloneTestManager(t)
+	source := *db.iface
+
+	clone, peers, err := m.CloneInterface(adminContext(), "wg0", domain.InterfaceCloneRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, peers)
+
+	assert.Equal(t, domain.InterfaceIdentifier("wg1"), clone.Identifier)
+	assert.Equal(t, "wg1", clone.DisplayName)
+	assert.NotEqual(t, source.PublicKey, clone.PublicKey)
+	assert.Equal(t, 51821, clone.ListenPort)
+	assert.Equal(t, []string{"10.11.0.1/24"}, domain.CidrsToStringSlice(clone.Addresses))
+
+	assert.Equal(t, source.Mtu, clone.Mtu)
+	assert.Equal(t, source.RoutingTable, clone.RoutingTable)
+	assert.Equal(t, source.PostUp, clone.PostUp)
+	assert.Equal(t, source.PeerDefDnsStr, clone.PeerDefDnsStr)
+	assert.Equal(t, source.PeerDefMtu, clone.PeerDefMtu)
+	assert.True(t, clone.CreateDefaultPeer)
+
+	assert.Equal(t, "10.11.0.0/24", clone.PeerDefNetworkStr)
+	assert.Equal(t, "10.11.0.0/24,192.168.1.0/24", clone.PeerDefAllowedIPsStr, "only the old network is replaced")
+	assert.Equal(t, "vpn.example.com:51821", clone.PeerDefEndpoint)
+	assert.Nil(t, clone.IpamPolicy, "reservations of the old network are not copied")
+	assert.Nil(t, clone.PrefixDelegation)
+
+	stored, err := db.GetInterface(context.Background(), "wg1")
+	require.NoError(t, err)
+	assert.Equal(t, clone.PublicKey, stored.PublicKey)
+}
+
+func TestManager_CloneInterface_Peers(t *testing.T) {
+	m, db, sourcePeer := newCloneTestManager(t)
+
+	clone, peers, err := m.CloneInterface(adminContext(), "wg0", domain.InterfaceCloneRequest{
+		Identifier:  "wg-eu",
+		DisplayName: "Region EU",
+		ClonePeers:  true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.InterfaceIdentifier("wg-eu"), clone.Identifier)
+	assert.Equal(t, "Region EU", clone.DisplayName)
+	assert.True(t, clone.CreateDefaultPeer)
+	assert.True(t, db.iface.CreateDefaultPeer, "default peers are enabled again after cloning the peers")
+
+	require.Len(t, peers, 1)
+	peer := peers[0]
+	assert.NotEqual(t, sourcePeer.Identifier, peer.Identifier)
+	assert.Equal(t, domain.InterfaceIdentifier("wg-eu"), peer.InterfaceIdentifier)
+	assert.Equal(t, "laptop", peer.DisplayName)
+	assert.Equal(t, domain.UserIdentifier("alice"), peer.UserIdentifier)
+	assert.Equal(t, clone.PublicKey, peer.EndpointPublicKey.GetValue())
+	assert.Equal(t, "vpn.example.com:51821", peer.Endpoint.GetValue())
+	assert.Equal(t, 1280, peer.Interface.Mtu.GetValue(), "custom settings of the peer are kept")
+	require.Len(t, peer.Interface.Addresses, 1)
+	assert.True(t, clone.Addresses[0].NetworkAddr().Contains(peer.Interface.Addresses[0]))
+
+	assert.Contains(t, db.savedPeers, peer.Identifier)
+	assert.Contains(t, db.savedPeers, sourcePeer.Identifier, "the source peer is not changed")
+}
+
+func TestManager_CloneInterface_Declarative(t *testing.T) {
+	m, db, _ := newCloneTestManager(t)
+	db.iface.ManagedBy = domain.ManagedByDeclarative
+	db.interfaces = []domain.Interface{*db.iface}
+
+	clone, _, err := m.CloneInterface(adminContext(), "wg0", domain.InterfaceCloneRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, clone.ManagedBy, "clones of declarative interfaces are managed in the UI")
+
+	stored, err := db.GetInterface(context.Background(), clone.Identifier)
+	require.NoError(t, err)
+	assert.Empty(t, stored.ManagedBy)
+}
+
+func TestManager_CloneInterface_Errors(t *testing.T) {
+	m, _, _ := newCloneTestManager(t)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user", IsAdmin: false})
+	_, _, err := m.CloneInterface(userCtx, "wg0", domain.InterfaceCloneRequest{})
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	_, _, err = m.CloneInterface(adminContext(), "wg0", domain.InterfaceCloneRequest{Backend: "unknown"})
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	_, _, err = m.CloneInterface(adminContext(), "wg0", domain.InterfaceCloneRequest{Identifier: "wg0"})
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+}
@@ -148,6 +148,13 @@ func (c *ControllerManager) getController(
 	return controller
 }
 
+// SupportsBandwidthLimits returns true if the controller of the given backend is able to enforce peer bandwidth
+// limits. Limits configured for interfaces of other backends are not applied.
+func (c *ControllerManager) SupportsBandwidthLimits(backend domain.InterfaceBackend) bool {
+	limiter, ok := c.getController(backend, "").Implementation.(BandwidthLimitController)
+	return ok && limiter.SupportsBandwidthLimits()
+}
+
 func (c *ControllerManager) GetAllControllers() []backendInstance {
 	var backendInstances = make([]backendInstance, 0, len(c.controllers))
 	for instance := range maps.Values(c.controllers) {
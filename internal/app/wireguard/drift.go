@@ -0,0 +1,451 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type DriftDatabaseRepo interface {
+	GetAllInterfaces(ctx context.Context) ([]domain.Interface, error)
+	GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error)
+	GetAllMeshes(ctx context.Context) ([]domain.Mesh, error)
+}
+
+type DriftMetricsServer interface {
+	UpdateDriftMetrics(reports []domain.DriftReport)
+}
+
+type DriftHealer interface {
+	// RestoreInterfaceState re-applies the stored state of the given interfaces and their peers.
+	RestoreInterfaceState(ctx context.Context, updateDbOnError bool, filter ...domain.InterfaceIdentifier) error
+	// ImportPeers stores the given physical peers of the interface in the database.
+	ImportPeers(ctx context.Context, id domain.InterfaceIdentifier, peers ...domain.PhysicalPeer) (int, error)
+}
+
+// DriftDetector compares the interfaces and peers stored in the database with the state of the backends.
+// Changes that were made directly on a backend, e.g. on a MikroTik router or using `wg set`, are reported and
+// optionally reverted.
+type DriftDetector struct {
+	cfg *config.Config
+
+	db     DriftDatabaseRepo
+	wg     *ControllerManager
+	ms     DriftMetricsServer
+	healer DriftHealer
+
+	mux     sync.Mutex // serializes the checks and protects the last reports
+	reports []domain.DriftReport
+}
+
+// NewDriftDetector creates a new drift detector.
+func NewDriftDetector(
+	cfg *config.Config,
+	db DriftDatabaseRepo,
+	wg *ControllerManager,
+	ms DriftMetricsServer,
+	healer DriftHealer,
+) (*DriftDetector, error) {
+	return &DriftDetector{
+		cfg: cfg,
+
+		db:     db,
+		wg:     wg,
+		ms:     ms,
+		healer: healer,
+	}, nil
+}
+
+// StartBackgroundJobs starts the periodic drift check.
+// This method is non-blocking and returns immediately.
+func (d *DriftDetector) StartBackgroundJobs(ctx context.Context) {
+	if d.cfg.Advanced.DriftCheckInterval <= 0 {
+		return
+	}
+
+	go d.runDriftCheck(ctx)
+
+	slog.Debug("started drift detection", "interval", d.cfg.Advanced.DriftCheckInterval)
+}
+
+func (d *DriftDetector) runDriftCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	ticker := time.NewTicker(d.cfg.Advanced.DriftCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.CheckDrift(ctx); err != nil {
+				slog.Error("failed to check for configuration drift", "error", err)
+			}
+		}
+	}
+}
+
+// GetDriftReports returns the reports of the last drift check.
+func (d *DriftDetector) GetDriftReports(ctx context.Context) ([]domain.DriftReport, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	return slices.Clone(d.reports), nil
+}
+
+// CheckDrift compares all interfaces and peers with the backends and returns one report per interface.
+// If auto-healing is enabled, the database state is re-applied to all interfaces with drift.
+func (d *DriftDetector) CheckDrift(ctx context.Context) ([]domain.DriftReport, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	reports, err := d.collectReports(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.cfg.Advanced.DriftAutoHeal {
+		for i := range reports {
+			if !reports[i].HasDrift() || reports[i].Error != "" || isExtraInterface(&reports[i]) {
+				continue
+			}
+			err := d.heal(ctx, &reports[i], d.cfg.Advanced.DriftImportUnknownPeers)
+			if err != nil {
+				slog.Error("failed to heal configuration drift",
+					"interface", reports[i].InterfaceIdentifier, "error", err)
+				continue
+			}
+			reports[i].Healed = true
+		}
+	}
+
+	for _, report := range reports {
+		if report.HasDrift() {
+			slog.Warn("detected configuration drift", "interface", report.InterfaceIdentifier,
+				"backend", report.Backend, "missing", report.Count(domain.DriftMissing),
+				"extra", report.Count(domain.DriftExtra), "changed", report.Count(domain.DriftChanged),
+				"healed", report.Healed)
+		}
+	}
+
+	d.reports = reports
+	d.ms.UpdateDriftMetrics(reports)
+
+	return slices.Clone(reports), nil
+}
+
+// HealDrift re-applies the database state to the given interface. If importUnknown is set, peers that only exist
+// on the backend are imported first, otherwise they are removed from the backend.
+func (d *DriftDetector) HealDrift(ctx context.Context, id domain.InterfaceIdentifier, importUnknown bool) (
+	*domain.DriftReport,
+	error,
+) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	reports, err := d.collectReports(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := slices.IndexFunc(reports, func(r domain.DriftReport) bool {
+		return r.InterfaceIdentifier == id && !isExtraInterface(&r)
+	})
+	if idx < 0 {
+		return nil, fmt.Errorf("interface %s is not managed: %w", id, domain.ErrNotFound)
+	}
+	report := reports[idx]
+	if report.Error != "" {
+		return nil, fmt.Errorf("unable to check interface %s: %s", id, report.Error)
+	}
+
+	if err := d.heal(ctx, &report, importUnknown); err != nil {
+		return nil, err
+	}
+	report.Healed = true
+
+	return &report, nil
+}
+
+func (d *DriftDetector) heal(ctx context.Context, report *domain.DriftReport, importUnknown bool) error {
+	if importUnknown {
+		var unknown []domain.PhysicalPeer
+		for _, item := range report.Items {
+			if item.Type == domain.DriftExtra && item.ObjectType == domain.DriftObjectPeer {
+				unknown = append(unknown, domain.PhysicalPeer{Identifier: domain.PeerIdentifier(item.Identifier)})
+			}
+		}
+		if len(unknown) > 0 {
+			if err := d.importUnknownPeers(ctx, report.InterfaceIdentifier, unknown); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := d.healer.RestoreInterfaceState(ctx, false, report.InterfaceIdentifier); err != nil {
+		return fmt.Errorf("failed to restore state of interface %s: %w", report.InterfaceIdentifier, err)
+	}
+
+	return nil
+}
+
+// importUnknownPeers loads the full state of the unknown peers from the backend and imports them.
+func (d *DriftDetector) importUnknownPeers(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	unknown []domain.PhysicalPeer,
+) error {
+	interfaces, err := d.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load interfaces: %w", err)
+	}
+	idx := slices.IndexFunc(interfaces, func(i domain.Interface) bool { return i.Identifier == id })
+	if idx < 0 {
+		return fmt.Errorf("interface %s not found: %w", id, domain.ErrNotFound)
+	}
+
+	physicalPeers, err := d.wg.GetController(interfaces[idx]).GetPeers(ctx, id)
+	if err != nil {
+		return fmt.Errorf("unable to load peers of interface %s: %w", id, err)
+	}
+
+	var peers []domain.PhysicalPeer
+	for _, pp := range physicalPeers {
+		if slices.ContainsFunc(unknown, func(u domain.PhysicalPeer) bool { return string(u.Identifier) == pp.PublicKey }) {
+			peers = append(peers, pp)
+		}
+	}
+
+	if _, err := d.healer.ImportPeers(ctx, id, peers...); err != nil {
+		return fmt.Errorf("failed to import unknown peers of interface %s: %w", id, err)
+	}
+
+	return nil
+}
+
+func (d *DriftDetector) collectReports(ctx context.Context) ([]domain.DriftReport, error) {
+	interfaces, err := d.db.GetAllInterfaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load interfaces: %w", err)
+	}
+
+	meshes, err := d.db.GetAllMeshes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load meshes: %w", err)
+	}
+	meshPeers := expectedMeshPeers(interfaces, meshes)
+
+	now := time.Now()
+	var reports []domain.DriftReport
+	for _, backend := range d.wg.GetAllControllers() {
+		physicalInterfaces, listErr := backend.Implementation.GetInterfaces(ctx)
+
+		for _, iface := range interfaces {
+			if d.wg.getController(iface.Backend, iface.Identifier).Config.Id != backend.Config.Id {
+				continue
+			}
+			if iface.IsDisabled() {
+				continue // disabled interfaces are removed or deactivated by the backend
+			}
+
+			report := domain.DriftReport{
+				InterfaceIdentifier: iface.Identifier,
+				Backend:             domain.InterfaceBackend(backend.Config.Id),
+				CheckedAt:           now,
+			}
+			if listErr != nil {
+				report.Error = listErr.Error()
+				reports = append(reports, report)
+				continue
+			}
+
+			idx := slices.IndexFunc(physicalInterfaces, func(pi domain.PhysicalInterface) bool {
+				return pi.Identifier == iface.Identifier
+			})
+			if idx < 0 {
+				report.Items = append(report.Items, domain.DriftItem{
+					Type:       domain.DriftMissing,
+					ObjectType: domain.DriftObjectInterface,
+					Identifier: string(iface.Identifier),
+				})
+				reports = append(reports, report)
+				continue
+			}
+
+			if fields := domain.CompareInterfaceState(&iface, &physicalInterfaces[idx]); len(fields) > 0 {
+				report.Items = append(report.Items, domain.DriftItem{
+					Type:       domain.DriftChanged,
+					ObjectType: domain.DriftObjectInterface,
+					Identifier: string(iface.Identifier),
+					Fields:     fields,
+				})
+			}
+
+			if err := d.comparePeers(ctx, backend.Implementation, &iface, meshPeers[iface.Identifier],
+				&report); err != nil {
+				report.Error = err.Error()
+			}
+
+			reports = append(reports, report)
+		}
+
+		// interfaces that only exist on the backend
+		for _, pi := range physicalInterfaces {
+			if slices.Contains(backend.Config.IgnoredInterfaces, string(pi.Identifier)) {
+				continue
+			}
+			if slices.ContainsFunc(interfaces, func(i domain.Interface) bool { return i.Identifier == pi.Identifier }) {
+				continue
+			}
+			reports = append(reports, domain.DriftReport{
+				InterfaceIdentifier: pi.Identifier,
+				Backend:             domain.InterfaceBackend(backend.Config.Id),
+				CheckedAt:           now,
+				Items: []domain.DriftItem{{
+					Type:       domain.DriftExtra,
+					ObjectType: domain.DriftObjectInterface,
+					Identifier: string(pi.Identifier),
+				}},
+			})
+		}
+	}
+
+	slices.SortFunc(reports, func(a, b domain.DriftReport) int {
+		if a.Backend != b.Backend {
+			return strings.Compare(string(a.Backend), string(b.Backend))
+		}
+		return strings.Compare(string(a.InterfaceIdentifier), string(b.InterfaceIdentifier))
+	})
+
+	return reports, nil
+}
+
+func (d *DriftDetector) comparePeers(
+	ctx context.Context,
+	controller domain.InterfaceController,
+	iface *domain.Interface,
+	meshPeers []domain.MeshPeer,
+	report *domain.DriftReport,
+) error {
+	peers, err := d.db.GetInterfacePeers(ctx, iface.Identifier)
+	if err != nil {
+		return fmt.Errorf("unable to load peers: %w", err)
+	}
+
+	physicalPeers, err := controller.GetPeers(ctx, iface.Identifier)
+	if err != nil {
+		return fmt.Errorf("unable to load physical peers: %w", err)
+	}
+	actual := make(map[domain.PeerIdentifier]*domain.PhysicalPeer, len(physicalPeers))
+	for i := range physicalPeers {
+		actual[domain.PeerIdentifier(physicalPeers[i].PublicKey)] = &physicalPeers[i]
+	}
+
+	expected := make(map[domain.PeerIdentifier]*domain.PhysicalPeer, len(peers)+len(meshPeers))
+	ignored := make(map[domain.PeerIdentifier]struct{})
+	for _, peer := range peers {
+		if peer.IsDisabled() || peer.IsScheduleBlocked() {
+			ignored[peer.Identifier] = struct{}{} // depending on the backend, inactive peers are removed or disabled
+			continue
+		}
+		pp := &domain.PhysicalPeer{}
+		domain.MergeToPhysicalPeer(pp, &peer)
+		expected[peer.Identifier] = pp
+	}
+	for _, meshPeer := range meshPeers {
+		id := domain.PeerIdentifier(meshPeer.PublicKey)
+		if _, ok := expected[id]; ok {
+			continue // regular peers take precedence, the mesh manager skips such peers as well
+		}
+		pp := &domain.PhysicalPeer{}
+		meshPeer.MergeToPhysicalPeer(pp)
+		expected[id] = pp
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(expected)) {
+		pp, ok := actual[id]
+		if !ok {
+			report.Items = append(report.Items, domain.DriftItem{
+				Type:       domain.DriftMissing,
+				ObjectType: domain.DriftObjectPeer,
+				Identifier: string(id),
+			})
+			continue
+		}
+		if fields := domain.ComparePeerState(expected[id], pp); len(fields) > 0 {
+			report.Items = append(report.Items, domain.DriftItem{
+				Type:       domain.DriftChanged,
+				ObjectType: domain.DriftObjectPeer,
+				Identifier: string(id),
+				Fields:     fields,
+			})
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(actual)) {
+		_, isExpected := expected[id]
+		_, isIgnored := ignored[id]
+		if isExpected || isIgnored {
+			continue
+		}
+		report.Items = append(report.Items, domain.DriftItem{
+			Type:       domain.DriftExtra,
+			ObjectType: domain.DriftObjectPeer,
+			Identifier: string(id),
+		})
+	}
+
+	return nil
+}
+
+// expectedMeshPeers returns the generated mesh peers per interface. Meshes that can not be generated are skipped,
+// the mesh manager reports them when they are applied.
+func expectedMeshPeers(
+	interfaces []domain.Interface,
+	meshes []domain.Mesh,
+) map[domain.InterfaceIdentifier][]domain.MeshPeer {
+	nodes := make(map[domain.InterfaceIdentifier]*domain.Interface, len(interfaces))
+	for i := range interfaces {
+		nodes[interfaces[i].Identifier] = &interfaces[i]
+	}
+
+	peers := make(map[domain.InterfaceIdentifier][]domain.MeshPeer)
+	for _, mesh := range meshes {
+		meshPeers, err := mesh.Peers(nodes)
+		if err != nil {
+			continue
+		}
+		for _, peer := range meshPeers {
+			peers[peer.InterfaceIdentifier] = append(peers[peer.InterfaceIdentifier], peer)
+		}
+	}
+
+	return peers
+}
+
+// isExtraInterface returns true if the report belongs to an interface that is not stored in the database.
+func isExtraInterface(report *domain.DriftReport) bool {
+	return len(report.Items) == 1 && report.Items[0].Type == domain.DriftExtra &&
+		report.Items[0].ObjectType == domain.DriftObjectInterface
+}
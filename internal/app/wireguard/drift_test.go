@@ -0,0 +1,242 @@
+package wireguard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type driftController struct {
+	mockController
+	interfaces []domain.PhysicalInterface
+	peers      []domain.PhysicalPeer
+}
+
+func (f *driftController) GetInterfaces(_ context.Context) ([]domain.PhysicalInterface, error) {
+	return f.interfaces, nil
+}
+func (f *driftController) GetPeers(_ context.Context, _ domain.InterfaceIdentifier) ([]domain.PhysicalPeer, error) {
+	return f.peers, nil
+}
+
+type driftDB struct {
+	*mockDB
+	meshes []domain.Mesh
+}
+
+func (f *driftDB) GetAllMeshes(_ context.Context) ([]domain.Mesh, error) {
+	return f.meshes, nil
+}
+
+type driftHealer struct {
+	restored []domain.InterfaceIdentifier
+	imported []domain.PhysicalPeer
+}
+
+func (f *driftHealer) RestoreInterfaceState(
+	_ context.Context,
+	_ bool,
+	filter ...domain.InterfaceIdentifier,
+) error {
+	f.restored = append(f.restored, filter...)
+	return nil
+}
+func (f *driftHealer) ImportPeers(
+	_ context.Context,
+	_ domain.InterfaceIdentifier,
+	peers ...domain.PhysicalPeer,
+) (int, error) {
+	f.imported = append(f.imported, peers...)
+	return len(peers), nil
+}
+
+type driftMetrics struct {
+	reports []domain.DriftReport
+}
+
+func (f *driftMetrics) UpdateDriftMetrics(reports []domain.DriftReport) { f.reports = reports }
+
+func newDriftTestSetup(t *testing.T) (*driftController, *driftDB) {
+	addrs, err := domain.CidrsFromString("10.0.0.1/24")
+	require.NoError(t, err)
+	iface := domain.Interface{
+		Identifier: "wg0",
+		KeyPair:    domain.KeyPair{PublicKey: "iface-pub"},
+		ListenPort: 51820,
+		Addresses:  addrs,
+		Type:       domain.InterfaceTypeServer,
+	}
+
+	newPeer := func(id, addr string) *domain.Peer {
+		peerAddrs, err := domain.CidrsFromString(addr)
+		require.NoError(t, err)
+		return &domain.Peer{
+			Identifier:          domain.PeerIdentifier(id),
+			InterfaceIdentifier: "wg0",
+			Interface: domain.PeerInterfaceConfig{
+				KeyPair:   domain.KeyPair{PublicKey: id},
+				Type:      domain.InterfaceTypeClient,
+				Addresses: peerAddrs,
+			},
+		}
+	}
+	now := time.Now()
+	disabled := newPeer("disabled", "10.0.0.4/32")
+	disabled.Disabled = &now
+
+	db := &driftDB{mockDB: &mockDB{
+		interfaces: []domain.Interface{iface},
+		savedPeers: map[domain.PeerIdentifier]*domain.Peer{
+			"changed":  newPeer("changed", "10.0.0.2/32"),
+			"missing":  newPeer("missing", "10.0.0.3/32"),
+			"disabled": disabled,
+		},
+	}}
+
+	changedIPs, err := domain.CidrsFromString("10.0.0.2/32,192.168.0.0/24")
+	require.NoError(t, err)
+	unknownIPs, err := domain.CidrsFromString("10.0.0.9/32")
+	require.NoError(t, err)
+	controller := &driftController{
+		interfaces: []domain.PhysicalInterface{
+			{Identifier: "wg0", KeyPair: domain.KeyPair{PublicKey: "iface-pub"}, ListenPort: 51820, Addresses: addrs},
+			{Identifier: "wg9"},
+			{Identifier: "ignored"},
+		},
+		peers: []domain.PhysicalPeer{
+			{Identifier: "changed", KeyPair: domain.KeyPair{PublicKey: "changed"}, AllowedIPs: changedIPs},
+			{Identifier: "unknown", KeyPair: domain.KeyPair{PublicKey: "unknown"}, AllowedIPs: unknownIPs},
+		},
+	}
+
+	return controller, db
+}
+
+func newDriftTestDetector(
+	cfg *config.Config,
+	controller *driftController,
+	db *driftDB,
+	healer *driftHealer,
+	ms *driftMetrics,
+) *DriftDetector {
+	wg := &ControllerManager{controllers: map[domain.InterfaceBackend]backendInstance{
+		config.LocalBackendName: {
+			Config:         config.BackendBase{Id: config.LocalBackendName, IgnoredInterfaces: []string{"ignored"}},
+			Implementation: controller,
+		},
+	}}
+	detector, _ := NewDriftDetector(cfg, db, wg, ms, healer)
+	return detector
+}
+
+func TestDriftDetector_CheckDrift(t *testing.T) {
+	controller, db := newDriftTestSetup(t)
+	healer := &driftHealer{}
+	ms := &driftMetrics{}
+	detector := newDriftTestDetector(&config.Config{}, controller, db, healer, ms)
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	reports, err := detector.CheckDrift(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+
+	wg0 := reports[0]
+	assert.Equal(t, domain.InterfaceIdentifier("wg0"), wg0.InterfaceIdentifier)
+	assert.Equal(t, domain.InterfaceBackend(config.LocalBackendName), wg0.Backend)
+	require.Len(t, wg0.Items, 3)
+	assert.Equal(t, domain.DriftItem{
+		Type:       domain.DriftChanged,
+		ObjectType: domain.DriftObjectPeer,
+		Identifier: "changed",
+		Fields: []domain.DriftField{{
+			Name:     "AllowedIPs",
+			Expected: "10.0.0.2/32",
+			Actual:   "10.0.0.2/32,192.168.0.0/24",
+		}},
+	}, wg0.Items[0])
+	assert.Equal(t, domain.DriftMissing, wg0.Items[1].Type)
+	assert.Equal(t, "missing", wg0.Items[1].Identifier)
+	assert.Equal(t, domain.DriftExtra, wg0.Items[2].Type)
+	assert.Equal(t, "unknown", wg0.Items[2].Identifier)
+	assert.False(t, wg0.Healed)
+
+	assert.Equal(t, domain.InterfaceIdentifier("wg9"), reports[1].InterfaceIdentifier)
+	assert.Equal(t, domain.DriftObjectInterface, reports[1].Items[0].ObjectType)
+
+	assert.Empty(t, healer.restored, "auto-heal is disabled")
+	assert.Equal(t, reports, ms.reports)
+
+	lastReports, err := detector.GetDriftReports(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, reports, lastReports)
+
+	_, err = detector.CheckDrift(context.Background())
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestDriftDetector_CheckDrift_AutoHeal(t *testing.T) {
+	controller, db := newDriftTestSetup(t)
+	healer := &driftHealer{}
+	cfg := &config.Config{}
+	cfg.Advanced.DriftAutoHeal = true
+	cfg.Advanced.DriftImportUnknownPeers = true
+	detector := newDriftTestDetector(cfg, controller, db, healer, &driftMetrics{})
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	reports, err := detector.CheckDrift(ctx)
+	require.NoError(t, err)
+
+	assert.True(t, reports[0].Healed)
+	assert.False(t, reports[1].Healed, "interfaces that only exist on the backend are not healed")
+	assert.Equal(t, []domain.InterfaceIdentifier{"wg0"}, healer.restored)
+	require.Len(t, healer.imported, 1)
+	assert.Equal(t, "unknown", healer.imported[0].PublicKey)
+	assert.Equal(t, "10.0.0.9/32", healer.imported[0].AllowedIPs[0].String())
+}
+
+func TestDriftDetector_HealDrift(t *testing.T) {
+	controller, db := newDriftTestSetup(t)
+	healer := &driftHealer{}
+	detector := newDriftTestDetector(&config.Config{}, controller, db, healer, &driftMetrics{})
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	report, err := detector.HealDrift(ctx, "wg0", false)
+	require.NoError(t, err)
+	assert.True(t, report.Healed)
+	assert.Equal(t, []domain.InterfaceIdentifier{"wg0"}, healer.restored)
+	assert.Empty(t, healer.imported)
+
+	_, err = detector.HealDrift(ctx, "wg9", false)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestDriftDetector_CheckDrift_MeshPeers(t *testing.T) {
+	controller, db := newDriftTestSetup(t)
+	db.interfaces = append(db.interfaces, domain.Interface{
+		Identifier: "wg9",
+		KeyPair:    domain.KeyPair{PublicKey: "remote-pub"},
+		ListenPort: 51821,
+	})
+	db.meshes = []domain.Mesh{{
+		Identifier: "sites",
+		Topology:   domain.MeshTopologyFullMesh,
+		Nodes:      []domain.MeshNode{{InterfaceIdentifier: "wg0"}, {InterfaceIdentifier: "wg9"}},
+	}}
+	detector := newDriftTestDetector(&config.Config{}, controller, db, &driftHealer{}, &driftMetrics{})
+
+	ctx := domain.SetUserInfo(context.Background(), domain.SystemAdminContextUserInfo())
+	reports, err := detector.CheckDrift(ctx)
+	require.NoError(t, err)
+
+	assert.Contains(t, reports[0].Items, domain.DriftItem{
+		Type:       domain.DriftMissing,
+		ObjectType: domain.DriftObjectPeer,
+		Identifier: "remote-pub",
+	})
+}
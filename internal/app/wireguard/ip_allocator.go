@@ -0,0 +1,419 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// ipClaimTimeout is the time after which addresses of prepared peers, that were never saved, are released again.
+const ipClaimTimeout = 10 * time.Minute
+
+// ipAllocatorResyncInterval defines how often the allocator reloads all addresses from the database.
+// The periodic reload heals changes that were made without an event, for example by a second instance.
+const ipAllocatorResyncInterval = 15 * time.Minute
+
+type ipAllocatorDatabaseRepo interface {
+	GetPeerIps(ctx context.Context) (map[domain.PeerIdentifier][]domain.Cidr, error)
+	GetInterfaceIps(ctx context.Context) (map[domain.InterfaceIdentifier][]domain.Cidr, error)
+}
+
+// ipOwner is the peer or interface that uses an address. Leases belong to the prepared peer and its interface.
+type ipOwner struct {
+	Peer      domain.PeerIdentifier
+	Interface domain.InterfaceIdentifier
+}
+
+type ipClaim struct {
+	owner   ipOwner
+	lease   bool      // true for addresses of prepared peers, which do not block claims of other peers
+	expires time.Time // zero for addresses that are stored in the database
+}
+
+type pendingIpClaim struct {
+	addr  netip.Addr
+	claim ipClaim
+}
+
+// ipAllocator keeps all used addresses in memory, so that free addresses can be found without loading and scanning
+// all peers. It is loaded lazily from the database and kept in sync through peer and interface events.
+//
+// Allocated addresses are leased until the prepared peer is saved, so concurrent allocations never return the same
+// address. Addresses of peers that are validated but not yet stored are claimed for the peer, which makes the
+// uniqueness check safe against concurrent requests.
+type ipAllocator struct {
+	db ipAllocatorDatabaseRepo
+
+	mu      sync.Mutex
+	synced  time.Time // zero if the allocator must be reloaded before the next use
+	used    addrSet
+	claims  map[netip.Addr][]ipClaim
+	owned   map[ipOwner][]netip.Addr // stored addresses per owner
+	pending []pendingIpClaim         // leases and claims that are not stored yet, ordered by expiry
+}
+
+func newIpAllocator(db ipAllocatorDatabaseRepo) *ipAllocator {
+	return &ipAllocator{db: db}
+}
+
+// allocate returns the first address of the candidate ranges that is neither used nor part of a blocked range and
+// leases it for the prepared peer. If lease is the zero value, the address is only looked up and not leased, which
+// is used for previews that are never saved. The second return value is false if no address is available.
+func (a *ipAllocator) allocate(
+	ctx context.Context,
+	lease ipOwner,
+	candidates, blocked []domain.IpRange,
+) (netip.Addr, bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.sync(ctx); err != nil {
+		return netip.Addr{}, false, err
+	}
+
+	addr, ok := a.firstFree(candidates, blocked)
+	if ok && lease != (ipOwner{}) {
+		a.addPending(addr, ipClaim{owner: lease, lease: true, expires: time.Now().Add(ipClaimTimeout)})
+	}
+
+	return addr, ok, nil
+}
+
+// release returns addresses that were leased for the prepared peer but are no longer needed, for example if the
+// allocation of a second address family failed.
+func (a *ipAllocator) release(lease ipOwner, addrs ...netip.Addr) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, addr := range addrs {
+		a.removeClaims(addr, func(c ipClaim) bool { return c.lease && c.owner == lease })
+	}
+}
+
+// isLeased returns true if all addresses are leased for the prepared peer.
+func (a *ipAllocator) isLeased(lease ipOwner, addrs []netip.Addr) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, addr := range addrs {
+		if !slices.ContainsFunc(a.claims[addr], func(c ipClaim) bool { return c.lease && c.owner == lease }) {
+			return false
+		}
+	}
+
+	return len(addrs) > 0
+}
+
+// claim reserves the addresses for the given peer until the peer is stored. It fails with domain.ErrDuplicateEntry
+// if one of the addresses belongs to another peer or to an interface. Leases of prepared peers are ignored.
+// Claims of previousId are accepted as well, the identifier of a peer changes if its public key is replaced.
+func (a *ipAllocator) claim(
+	ctx context.Context,
+	id, previousId domain.PeerIdentifier,
+	addrs []netip.Addr,
+) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.sync(ctx); err != nil {
+		return err
+	}
+
+	owner := ipOwner{Peer: id}
+	for _, addr := range addrs {
+		for _, c := range a.claims[addr] {
+			switch {
+			case c.lease || c.owner == owner || (previousId != "" && c.owner.Peer == previousId):
+				continue
+			case c.owner.Interface != "":
+				return fmt.Errorf("address %s is already assigned to interface %s: %w", addr,
+					c.owner.Interface, domain.ErrDuplicateEntry)
+			default:
+				return fmt.Errorf("address %s is already assigned to peer %s: %w", addr,
+					c.owner.Peer, domain.ErrDuplicateEntry)
+			}
+		}
+	}
+
+	expires := time.Now().Add(ipClaimTimeout)
+	for _, addr := range addrs {
+		if !slices.ContainsFunc(a.claims[addr], func(c ipClaim) bool { return c.owner == owner && !c.lease }) {
+			a.addPending(addr, ipClaim{owner: owner, expires: expires})
+		}
+	}
+
+	return nil
+}
+
+// invalidate forces a reload from the database before the next allocation.
+func (a *ipAllocator) invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.synced = time.Time{}
+}
+
+// region event-handlers
+
+func (a *ipAllocator) handlePeerSaved(peer domain.Peer) {
+	a.setOwned(ipOwner{Peer: peer.Identifier}, peer.Interface.Addresses)
+}
+
+// handlePeerDeleted frees the addresses of the peer. Addresses of peers in the recycle bin stay in use until the
+// peer is purged.
+func (a *ipAllocator) handlePeerDeleted(peer domain.Peer) {
+	if peer.IsDeleted() {
+		return
+	}
+	a.setOwned(ipOwner{Peer: peer.Identifier}, nil)
+}
+
+func (a *ipAllocator) handlePeerPurged(peer domain.Peer) {
+	a.setOwned(ipOwner{Peer: peer.Identifier}, nil)
+}
+
+func (a *ipAllocator) handleInterfaceSaved(iface domain.Interface) {
+	a.setOwned(ipOwner{Interface: iface.Identifier}, iface.Addresses)
+}
+
+// handleInterfaceDeleted reloads all addresses, the peers of the interface are deleted without separate events.
+func (a *ipAllocator) handleInterfaceDeleted(_ domain.Interface) {
+	a.invalidate()
+}
+
+// endregion event-handlers
+
+// setOwned replaces the stored addresses of the owner. Pending claims of the owner for these addresses are
+// confirmed. All leases of the owner are released, addresses that did not end up in the stored peer are free again.
+// Leases of the stored addresses are released as well, as the prepared peer might have been saved with another
+// identifier, for example if its public key was replaced.
+func (a *ipAllocator) setOwned(owner ipOwner, cidrs []domain.Cidr) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	addrs := make([]netip.Addr, len(cidrs))
+	for i, cidr := range cidrs {
+		addrs[i] = cidr.Prefix().Addr().Unmap()
+	}
+
+	// leases are kept across reloads, so they must be released even if the allocator is outdated
+	for _, p := range a.pending {
+		if p.claim.lease && p.claim.owner.Peer == owner.Peer && owner.Peer != "" {
+			a.removeClaims(p.addr, func(c ipClaim) bool { return c == p.claim })
+		}
+	}
+	for _, addr := range addrs {
+		a.removeClaims(addr, func(c ipClaim) bool { return c.lease })
+	}
+
+	if a.synced.IsZero() {
+		return // the next reload reads the current state from the database
+	}
+
+	for _, addr := range a.owned[owner] {
+		if !slices.Contains(addrs, addr) {
+			a.removeClaims(addr, func(c ipClaim) bool { return c.owner == owner })
+		}
+	}
+	for _, addr := range addrs {
+		a.removeClaims(addr, func(c ipClaim) bool { return c.owner == owner && !c.expires.IsZero() })
+		if !slices.Contains(a.owned[owner], addr) {
+			a.addClaim(addr, ipClaim{owner: owner})
+		}
+	}
+
+	if len(addrs) == 0 {
+		delete(a.owned, owner)
+	} else {
+		a.owned[owner] = addrs
+	}
+}
+
+// sync loads all addresses from the database if the allocator was never loaded, was invalidated or is outdated.
+// Unexpired leases and claims are kept. It also releases expired leases and claims. The caller must hold the lock.
+func (a *ipAllocator) sync(ctx context.Context) error {
+	now := time.Now()
+	a.expire(now)
+
+	if !a.synced.IsZero() && now.Sub(a.synced) < ipAllocatorResyncInterval {
+		return nil
+	}
+
+	peerIps, err := a.db.GetPeerIps(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load peer addresses: %w", err)
+	}
+	interfaceIps, err := a.db.GetInterfaceIps(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load interface addresses: %w", err)
+	}
+
+	previous := a.claims
+	a.claims = make(map[netip.Addr][]ipClaim, len(peerIps))
+	a.owned = make(map[ipOwner][]netip.Addr, len(peerIps)+len(interfaceIps))
+	own := func(owner ipOwner, cidrs []domain.Cidr) {
+		for _, cidr := range cidrs {
+			addr := cidr.Prefix().Addr().Unmap()
+			a.claims[addr] = append(a.claims[addr], ipClaim{owner: owner})
+			a.owned[owner] = append(a.owned[owner], addr)
+		}
+	}
+	for id, cidrs := range peerIps {
+		own(ipOwner{Peer: id}, cidrs)
+	}
+	for id, cidrs := range interfaceIps {
+		own(ipOwner{Interface: id}, cidrs)
+	}
+	for _, p := range a.pending {
+		if slices.Contains(previous[p.addr], p.claim) { // skip released and confirmed claims
+			a.claims[p.addr] = append(a.claims[p.addr], p.claim)
+		}
+	}
+
+	addrs := make([]netip.Addr, 0, len(a.claims))
+	for addr := range a.claims {
+		addrs = append(addrs, addr)
+	}
+	slices.SortFunc(addrs, netip.Addr.Compare)
+	a.used = addrSet{}
+	for _, addr := range addrs {
+		a.used.add(addr) // appends or extends the last range, as the addresses are sorted
+	}
+
+	a.synced = now
+
+	return nil
+}
+
+// expire removes all leases and claims that expired before now. The caller must hold the lock.
+func (a *ipAllocator) expire(now time.Time) {
+	n := 0
+	for n < len(a.pending) && !a.pending[n].claim.expires.After(now) {
+		p := a.pending[n]
+		a.removeClaims(p.addr, func(c ipClaim) bool { return c == p.claim })
+		n++
+	}
+	a.pending = a.pending[n:]
+}
+
+func (a *ipAllocator) addPending(addr netip.Addr, claim ipClaim) {
+	a.addClaim(addr, claim)
+	a.pending = append(a.pending, pendingIpClaim{addr: addr, claim: claim})
+}
+
+func (a *ipAllocator) addClaim(addr netip.Addr, claim ipClaim) {
+	if len(a.claims[addr]) == 0 {
+		a.used.add(addr)
+	}
+	a.claims[addr] = append(a.claims[addr], claim)
+}
+
+func (a *ipAllocator) removeClaims(addr netip.Addr, match func(c ipClaim) bool) {
+	claims, ok := a.claims[addr]
+	if !ok {
+		return
+	}
+
+	claims = slices.DeleteFunc(claims, match)
+	if len(claims) == 0 {
+		delete(a.claims, addr)
+		a.used.remove(addr)
+		return
+	}
+	a.claims[addr] = claims
+}
+
+// firstFree returns the lowest address of the candidate ranges that is not used and not blocked.
+// Each step skips a whole range of used or blocked addresses, so the search does not depend on the number of peers.
+func (a *ipAllocator) firstFree(candidates, blocked []domain.IpRange) (netip.Addr, bool) {
+	for _, candidate := range candidates {
+		addr := candidate.From
+		for addr.IsValid() && candidate.Contains(addr) {
+			if r, ok := a.used.rangeOf(addr); ok {
+				addr = r.To.Next()
+				continue
+			}
+			if i := slices.IndexFunc(blocked, func(r domain.IpRange) bool { return r.Contains(addr) }); i >= 0 {
+				addr = blocked[i].To.Next()
+				continue
+			}
+			return addr, true
+		}
+	}
+
+	return netip.Addr{}, false
+}
+
+// addrSet is a set of IP addresses, stored as sorted ranges that neither overlap nor touch each other.
+// Lookups use a binary search, consecutive addresses (the common case for allocated peers) share a single range.
+type addrSet struct {
+	ranges []domain.IpRange
+}
+
+// search returns the index of the first range that ends at or after addr.
+func (s *addrSet) search(addr netip.Addr) int {
+	return sort.Search(len(s.ranges), func(i int) bool {
+		return !s.ranges[i].To.Less(addr)
+	})
+}
+
+// rangeOf returns the range that contains the address.
+func (s *addrSet) rangeOf(addr netip.Addr) (domain.IpRange, bool) {
+	i := s.search(addr)
+	if i < len(s.ranges) && !addr.Less(s.ranges[i].From) {
+		return s.ranges[i], true
+	}
+	return domain.IpRange{}, false
+}
+
+func (s *addrSet) contains(addr netip.Addr) bool {
+	_, ok := s.rangeOf(addr)
+	return ok
+}
+
+func (s *addrSet) add(addr netip.Addr) {
+	i := s.search(addr)
+	if i < len(s.ranges) && !addr.Less(s.ranges[i].From) {
+		return // already part of the set
+	}
+
+	joinPrev := i > 0 && s.ranges[i-1].To.Next() == addr
+	joinNext := i < len(s.ranges) && s.ranges[i].From.Prev() == addr
+	switch {
+	case joinPrev && joinNext:
+		s.ranges[i-1].To = s.ranges[i].To
+		s.ranges = slices.Delete(s.ranges, i, i+1)
+	case joinPrev:
+		s.ranges[i-1].To = addr
+	case joinNext:
+		s.ranges[i].From = addr
+	default:
+		s.ranges = slices.Insert(s.ranges, i, domain.IpRange{From: addr, To: addr})
+	}
+}
+
+func (s *addrSet) remove(addr netip.Addr) {
+	i := s.search(addr)
+	if i == len(s.ranges) || addr.Less(s.ranges[i].From) {
+		return // not part of the set
+	}
+
+	r := s.ranges[i]
+	switch {
+	case r.From == r.To:
+		s.ranges = slices.Delete(s.ranges, i, i+1)
+	case addr == r.From:
+		s.ranges[i].From = addr.Next()
+	case addr == r.To:
+		s.ranges[i].To = addr.Prev()
+	default:
+		s.ranges[i].To = addr.Prev()
+		s.ranges = slices.Insert(s.ranges, i+1, domain.IpRange{From: addr.Next(), To: r.To})
+	}
+}
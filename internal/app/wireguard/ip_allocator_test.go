@@ -0,0 +1,329 @@
+package wireguard
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type mockIpRepo struct {
+	peerIps      map[domain.PeerIdentifier][]domain.Cidr
+	interfaceIps map[domain.InterfaceIdentifier][]domain.Cidr
+	loads        int
+}
+
+func (r *mockIpRepo) GetPeerIps(_ context.Context) (map[domain.PeerIdentifier][]domain.Cidr, error) {
+	r.loads++
+	return r.peerIps, nil
+}
+
+func (r *mockIpRepo) GetInterfaceIps(_ context.Context) (map[domain.InterfaceIdentifier][]domain.Cidr, error) {
+	return r.interfaceIps, nil
+}
+
+// testLease is the owner of addresses that are leased for a prepared peer in tests.
+var testLease = ipOwner{Peer: "prepared", Interface: "wg0"}
+
+func cidrs(t testing.TB, addrs ...string) []domain.Cidr {
+	result := make([]domain.Cidr, len(addrs))
+	for i, addr := range addrs {
+		cidr, err := domain.CidrFromString(addr)
+		require.NoError(t, err)
+		result[i] = cidr
+	}
+	return result
+}
+
+func ipRange(t testing.TB, str string) domain.IpRange {
+	r, err := domain.ParseIpRange(str)
+	require.NoError(t, err)
+	return r
+}
+
+func TestAddrSet(t *testing.T) {
+	s := addrSet{}
+	for _, addr := range []string{"10.0.0.5", "10.0.0.3", "10.0.0.4", "10.0.0.7", "fd00::1", "10.0.0.6"} {
+		s.add(netip.MustParseAddr(addr))
+	}
+	require.Len(t, s.ranges, 2)
+	assert.Equal(t, "10.0.0.3-10.0.0.7", s.ranges[0].String())
+	assert.Equal(t, "fd00::1", s.ranges[1].String())
+
+	s.remove(netip.MustParseAddr("10.0.0.5"))
+	s.remove(netip.MustParseAddr("10.0.0.3"))
+	s.remove(netip.MustParseAddr("10.0.0.9")) // not part of the set
+	require.Len(t, s.ranges, 3)
+	assert.Equal(t, "10.0.0.4", s.ranges[0].String())
+	assert.Equal(t, "10.0.0.6-10.0.0.7", s.ranges[1].String())
+
+	assert.True(t, s.contains(netip.MustParseAddr("10.0.0.7")))
+	assert.False(t, s.contains(netip.MustParseAddr("10.0.0.5")))
+	assert.False(t, s.contains(netip.MustParseAddr("fd00::2")))
+
+	s.remove(netip.MustParseAddr("fd00::1"))
+	s.add(netip.MustParseAddr("10.0.0.5"))
+	require.Len(t, s.ranges, 1)
+	assert.Equal(t, "10.0.0.4-10.0.0.7", s.ranges[0].String())
+}
+
+func TestIpAllocator_Allocate(t *testing.T) {
+	repo := &mockIpRepo{
+		peerIps:      map[domain.PeerIdentifier][]domain.Cidr{"peer1": cidrs(t, "10.0.0.2/32")},
+		interfaceIps: map[domain.InterfaceIdentifier][]domain.Cidr{"wg0": cidrs(t, "10.0.0.1/24")},
+	}
+	a := newIpAllocator(repo)
+	network := []domain.IpRange{ipRange(t, "10.0.0.0/29")}
+	blocked := []domain.IpRange{ipRange(t, "10.0.0.0"), ipRange(t, "10.0.0.4-10.0.0.5")}
+
+	var leased []string
+	for {
+		addr, ok, err := a.allocate(context.Background(), testLease, network, blocked)
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+		leased = append(leased, addr.String())
+	}
+	assert.Equal(t, []string{"10.0.0.3", "10.0.0.6", "10.0.0.7"}, leased)
+	assert.Equal(t, 1, repo.loads)
+
+	a.release(testLease, netip.MustParseAddr("10.0.0.6"))
+	addr, ok, err := a.allocate(context.Background(), testLease, network, blocked)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "10.0.0.6", addr.String())
+
+	// leases expire if the prepared peer is never saved
+	a.mu.Lock()
+	a.expire(time.Now().Add(ipClaimTimeout + time.Second))
+	a.mu.Unlock()
+	addr, _, _ = a.allocate(context.Background(), testLease, network, blocked)
+	assert.Equal(t, "10.0.0.3", addr.String())
+}
+
+func TestIpAllocator_Claim(t *testing.T) {
+	repo := &mockIpRepo{
+		peerIps:      map[domain.PeerIdentifier][]domain.Cidr{"peer1": cidrs(t, "10.0.0.2/32")},
+		interfaceIps: map[domain.InterfaceIdentifier][]domain.Cidr{"wg0": cidrs(t, "10.0.0.1/24")},
+	}
+	a := newIpAllocator(repo)
+	ctx := context.Background()
+	addrs := func(strs ...string) []netip.Addr {
+		result := make([]netip.Addr, len(strs))
+		for i, str := range strs {
+			result[i] = netip.MustParseAddr(str)
+		}
+		return result
+	}
+
+	err := a.claim(ctx, "peer2", "", addrs("10.0.0.1"))
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+	assert.ErrorContains(t, err, "interface wg0")
+	assert.ErrorIs(t, a.claim(ctx, "peer2", "", addrs("10.0.0.2")), domain.ErrDuplicateEntry)
+	assert.NoError(t, a.claim(ctx, "peer1-new-key", "peer1", addrs("10.0.0.2")))
+
+	// leases of prepared peers do not block explicit assignments, but claims block the allocation
+	leased, _, err := a.allocate(ctx, testLease, []domain.IpRange{ipRange(t, "10.0.0.3-10.0.0.10")}, nil)
+	require.NoError(t, err)
+	require.NoError(t, a.claim(ctx, "peer2", "", []netip.Addr{leased, netip.MustParseAddr("10.0.0.4")}))
+	assert.ErrorIs(t, a.claim(ctx, "peer3", "", addrs("10.0.0.4")), domain.ErrDuplicateEntry)
+	next, _, _ := a.allocate(ctx, testLease, []domain.IpRange{ipRange(t, "10.0.0.3-10.0.0.10")}, nil)
+	assert.Equal(t, "10.0.0.5", next.String())
+}
+
+func TestIpAllocator_Events(t *testing.T) {
+	repo := &mockIpRepo{
+		peerIps: map[domain.PeerIdentifier][]domain.Cidr{"peer1": cidrs(t, "10.0.0.2/32")},
+	}
+	a := newIpAllocator(repo)
+	ctx := context.Background()
+	network := []domain.IpRange{ipRange(t, "10.0.0.1-10.0.0.254")}
+
+	// events before the first use are ignored, the state is loaded from the database
+	a.handlePeerSaved(domain.Peer{Identifier: "peer0", Interface: domain.PeerInterfaceConfig{
+		Addresses: cidrs(t, "10.0.0.1/32"),
+	}})
+	addr, _, _ := a.allocate(ctx, testLease, network, nil)
+	assert.Equal(t, "10.0.0.1", addr.String())
+	a.release(testLease, addr)
+
+	peer := domain.Peer{Identifier: "peer2", Interface: domain.PeerInterfaceConfig{Addresses: cidrs(t, "10.0.0.3/32")}}
+	require.NoError(t, a.claim(ctx, peer.Identifier, "", []netip.Addr{netip.MustParseAddr("10.0.0.3")}))
+	a.handlePeerSaved(peer)
+
+	// the stored address must survive the expiry of the claim
+	a.mu.Lock()
+	a.expire(time.Now().Add(ipClaimTimeout + time.Second))
+	a.mu.Unlock()
+	assert.ErrorIs(t, a.claim(ctx, "peer3", "", []netip.Addr{netip.MustParseAddr("10.0.0.3")}),
+		domain.ErrDuplicateEntry)
+
+	peer.Interface.Addresses = cidrs(t, "10.0.0.10/32")
+	a.handlePeerSaved(peer)
+	assert.NoError(t, a.claim(ctx, "peer3", "", []netip.Addr{netip.MustParseAddr("10.0.0.3")}))
+
+	a.handlePeerDeleted(domain.Peer{Identifier: "peer1"})
+	addr, _, _ = a.allocate(ctx, testLease, network, nil)
+	assert.Equal(t, "10.0.0.1", addr.String())
+	addr, _, _ = a.allocate(ctx, testLease, network, nil)
+	assert.Equal(t, "10.0.0.2", addr.String())
+
+	a.handleInterfaceSaved(domain.Interface{Identifier: "wg0", Addresses: cidrs(t, "10.0.0.4/24")})
+	addr, _, _ = a.allocate(ctx, testLease, network, nil)
+	assert.Equal(t, "10.0.0.5", addr.String())
+
+	assert.Equal(t, 1, repo.loads)
+	a.handleInterfaceDeleted(domain.Interface{Identifier: "wg0"})
+	_, _, _ = a.allocate(ctx, testLease, network, nil)
+	assert.Equal(t, 2, repo.loads)
+}
+
+func TestIpAllocator_ReleaseLeasesOnSave(t *testing.T) {
+	a := newIpAllocator(&mockIpRepo{})
+	ctx := context.Background()
+	network := []domain.IpRange{ipRange(t, "10.0.0.1-10.0.0.254")}
+	otherLease := ipOwner{Peer: "prepared-2", Interface: "wg0"}
+
+	addr1, _, _ := a.allocate(ctx, testLease, network, nil)
+	addr2, _, _ := a.allocate(ctx, otherLease, network, nil)
+	assert.True(t, a.isLeased(testLease, []netip.Addr{addr1}))
+	assert.False(t, a.isLeased(testLease, []netip.Addr{addr2}))
+	assert.False(t, a.isLeased(ipOwner{Peer: testLease.Peer, Interface: "wg1"}, []netip.Addr{addr1}))
+
+	// previews do not lease the address
+	preview, _, _ := a.allocate(ctx, ipOwner{}, network, nil)
+	assert.Equal(t, "10.0.0.3", preview.String())
+	next, _, _ := a.allocate(ctx, ipOwner{}, network, nil)
+	assert.Equal(t, "10.0.0.3", next.String())
+
+	// the prepared peer is saved with other addresses, its lease is released
+	a.handlePeerSaved(domain.Peer{Identifier: testLease.Peer, Interface: domain.PeerInterfaceConfig{
+		Addresses: cidrs(t, "10.0.0.50/32"),
+	}})
+	assert.False(t, a.isLeased(testLease, []netip.Addr{addr1}))
+	next, _, _ = a.allocate(ctx, ipOwner{}, network, nil)
+	assert.Equal(t, addr1, next)
+
+	// the other prepared peer is saved with a new key, the lease of its address is released as well,
+	// so the address is free immediately after the peer is deleted
+	a.handlePeerSaved(domain.Peer{Identifier: "new-key", Interface: domain.PeerInterfaceConfig{
+		Addresses: []domain.Cidr{domain.CidrFromPrefix(netip.PrefixFrom(addr2, 32))},
+	}})
+	assert.False(t, a.isLeased(otherLease, []netip.Addr{addr2}))
+	a.handlePeerDeleted(domain.Peer{Identifier: "new-key"})
+	a.mu.Lock()
+	assert.False(t, a.used.contains(addr2))
+	a.mu.Unlock()
+}
+
+func TestIpAllocator_RecycleBin(t *testing.T) {
+	repo := &mockIpRepo{
+		peerIps: map[domain.PeerIdentifier][]domain.Cidr{"peer1": cidrs(t, "10.0.0.1/32")},
+	}
+	a := newIpAllocator(repo)
+	ctx := context.Background()
+	network := []domain.IpRange{ipRange(t, "10.0.0.1-10.0.0.254")}
+
+	addr, _, _ := a.allocate(ctx, ipOwner{}, network, nil)
+	assert.Equal(t, "10.0.0.2", addr.String())
+
+	// peers in the recycle bin keep their addresses until they are purged
+	deleted := domain.Peer{Identifier: "peer1", DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}
+	a.handlePeerDeleted(deleted)
+	addr, _, _ = a.allocate(ctx, ipOwner{}, network, nil)
+	assert.Equal(t, "10.0.0.2", addr.String())
+
+	a.handlePeerPurged(deleted)
+	addr, _, _ = a.allocate(ctx, ipOwner{}, network, nil)
+	assert.Equal(t, "10.0.0.1", addr.String())
+}
+
+func TestIpAllocator_Concurrent(t *testing.T) {
+	a := newIpAllocator(&mockIpRepo{})
+	network := []domain.IpRange{ipRange(t, "10.0.0.0/24")}
+
+	var mu sync.Mutex
+	seen := map[netip.Addr]struct{}{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addr, ok, err := a.allocate(context.Background(), testLease, network, nil)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			mu.Lock()
+			defer mu.Unlock()
+			seen[addr] = struct{}{}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, 50)
+}
+
+// newBenchmarkIpRepo returns a repository with one peer for every address of the network, except every tenth address
+// which belongs to a deleted peer. So allocations must skip many small ranges of used addresses.
+func newBenchmarkIpRepo(network string, peers int) *mockIpRepo {
+	repo := &mockIpRepo{peerIps: make(map[domain.PeerIdentifier][]domain.Cidr, peers)}
+
+	addr := netip.MustParsePrefix(network).Addr()
+	for i := 1; len(repo.peerIps) < peers; i++ {
+		addr = addr.Next()
+		if i%10 == 0 {
+			continue
+		}
+		repo.peerIps[domain.PeerIdentifier(addr.String())] = []domain.Cidr{
+			domain.CidrFromPrefix(netip.PrefixFrom(addr, addr.BitLen())),
+		}
+	}
+
+	return repo
+}
+
+func benchmarkFreshPeerIpConfig(b *testing.B, network string, peers int) {
+	ctx := context.Background()
+	repo := newBenchmarkIpRepo(network, peers)
+	iface := &domain.Interface{Identifier: "wg0", PeerDefNetworkStr: network}
+
+	newManager := func() Manager {
+		m := Manager{ips: newIpAllocator(repo)}
+		m.ips.mu.Lock()
+		require.NoError(b, m.ips.sync(ctx))
+		m.ips.mu.Unlock()
+		return m
+	}
+	m := newManager()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ips, err := m.getFreshPeerIpConfig(ctx, iface, "", testLease)
+		if err != nil { // the network is exhausted, start again with the original peers
+			b.StopTimer()
+			m = newManager()
+			b.StartTimer()
+			continue
+		}
+		if len(ips) != 1 {
+			b.Fatalf("expected one address, got %v", ips)
+		}
+	}
+}
+
+func BenchmarkFreshPeerIpConfig_IPv4Slash16(b *testing.B) {
+	benchmarkFreshPeerIpConfig(b, "10.0.0.0/16", 50_000)
+}
+
+func BenchmarkFreshPeerIpConfig_IPv6Slash64(b *testing.B) {
+	benchmarkFreshPeerIpConfig(b, "fd00::/64", 100_000)
+}
@@ -0,0 +1,155 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// GetIpamStatus returns the used, reserved and free addresses of all peer networks of the given interface.
+// Addresses that are assigned on other interfaces are reported as used as well.
+func (m Manager) GetIpamStatus(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.IpamNetworkStatus,
+	error,
+) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", id, err)
+	}
+
+	if iface.PeerDefNetworkStr == "" {
+		return []domain.IpamNetworkStatus{}, nil
+	}
+	networks, err := domain.CidrsFromString(iface.PeerDefNetworkStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse default network address: %w", err)
+	}
+
+	usage, err := m.getAddressUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	peerNames := make(map[domain.PeerIdentifier]string, len(peers))
+	for _, peer := range peers {
+		peerNames[peer.Identifier] = peer.DisplayName
+	}
+
+	reserved := iface.IpamPolicy.ReservedRanges()
+	result := make([]domain.IpamNetworkStatus, 0, len(networks))
+	for _, network := range networks {
+		netRange := domain.IpRangeFromPrefix(network.Prefix())
+
+		status := domain.IpamNetworkStatus{
+			Network: domain.CidrFromPrefix(network.Prefix().Masked()),
+			Size:    netRange.Size(),
+			Used:    []domain.IpamAddress{},
+		}
+
+		blocked := domain.UnassignableIpRanges(network)
+		for addr, owners := range usage {
+			if !netRange.Contains(addr) {
+				continue
+			}
+			for _, owner := range owners {
+				owner.DisplayName = peerNames[owner.PeerIdentifier]
+				status.Used = append(status.Used, owner)
+			}
+			blocked = append(blocked, domain.IpRange{From: addr, To: addr})
+		}
+		slices.SortFunc(status.Used, func(a, b domain.IpamAddress) int {
+			return a.Address.Compare(b.Address)
+		})
+
+		for _, r := range reserved {
+			if inNetwork, ok := netRange.Intersect(r); ok {
+				status.Reserved = append(status.Reserved, inNetwork)
+				blocked = append(blocked, inNetwork)
+			}
+		}
+
+		status.FreeRanges = domain.SubtractIpRanges(netRange, blocked)
+		status.Free = domain.SumIpRanges(status.FreeRanges)
+
+		if iface.IpamPolicy != nil {
+			for _, pool := range iface.IpamPolicy.Pools {
+				poolStatus := domain.IpamPoolStatus{Name: pool.Name}
+				var freeRanges []domain.IpRange
+				for _, r := range pool.IpRanges() {
+					if inNetwork, ok := netRange.Intersect(r); ok {
+						poolStatus.Ranges = append(poolStatus.Ranges, inNetwork)
+						freeRanges = append(freeRanges, domain.SubtractIpRanges(inNetwork, blocked)...)
+					}
+				}
+				if len(poolStatus.Ranges) == 0 {
+					continue // the pool belongs to another network
+				}
+				poolStatus.Size = domain.SumIpRanges(poolStatus.Ranges)
+				poolStatus.Free = domain.SumIpRanges(freeRanges)
+				status.Pools = append(status.Pools, poolStatus)
+			}
+		}
+
+		result = append(result, status)
+	}
+
+	return result, nil
+}
+
+// getAddressUsage returns the owners of all addresses that are assigned to peers or interfaces.
+// An address can have multiple owners if it was assigned twice before the uniqueness check existed.
+func (m Manager) getAddressUsage(ctx context.Context) (map[netip.Addr][]domain.IpamAddress, error) {
+	peerIps, err := m.db.GetPeerIps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peer addresses: %w", err)
+	}
+	interfaceIps, err := m.db.GetInterfaceIps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface addresses: %w", err)
+	}
+
+	usage := make(map[netip.Addr][]domain.IpamAddress)
+	for peerId, cidrs := range peerIps {
+		for _, cidr := range cidrs {
+			addr := cidr.Prefix().Addr().Unmap()
+			usage[addr] = append(usage[addr], domain.IpamAddress{Address: addr, PeerIdentifier: peerId})
+		}
+	}
+	for ifaceId, cidrs := range interfaceIps {
+		for _, cidr := range cidrs {
+			addr := cidr.Prefix().Addr().Unmap()
+			usage[addr] = append(usage[addr], domain.IpamAddress{Address: addr, InterfaceIdentifier: ifaceId})
+		}
+	}
+
+	return usage, nil
+}
+
+// validatePeerAddresses ensures that none of the peer addresses is assigned to another peer or to an interface.
+// On updates, only addresses that were added are checked, so that existing duplicates do not block other changes.
+// The checked addresses are claimed for the peer, so that concurrent requests can not use them as well.
+func (m Manager) validatePeerAddresses(ctx context.Context, existing, peer *domain.Peer) error {
+	var previousId domain.PeerIdentifier
+	var addresses []netip.Addr
+	for _, cidr := range peer.Interface.Addresses {
+		if existing != nil && slices.ContainsFunc(existing.Interface.Addresses, cidr.EqualPrefix) {
+			continue
+		}
+		addresses = append(addresses, cidr.Prefix().Addr().Unmap())
+	}
+	if existing != nil {
+		previousId = existing.Identifier // the identifier changes if the public key of the peer is replaced
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	return m.ipAllocator().claim(ctx, peer.Identifier, previousId, addresses)
+}
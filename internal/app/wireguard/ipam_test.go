@@ -0,0 +1,156 @@
+package wireguard
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/config"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func newIpamTestManager(t *testing.T) (Manager, *mockDB) {
+	db := &mockDB{
+		iface: &domain.Interface{
+			Identifier:        "wg0",
+			Type:              domain.InterfaceTypeServer,
+			PeerDefNetworkStr: "10.0.0.0/24",
+			IpamPolicy: &domain.IpamPolicy{
+				Reservations: []domain.IpReservation{{Range: "10.0.0.1-10.0.0.3", Description: "gateways"}},
+				Pools:        []domain.IpPool{{Name: "printers", Ranges: []string{"10.0.0.4-10.0.0.5"}}},
+			},
+		},
+	}
+
+	m := Manager{
+		cfg: &config.Config{},
+		bus: &mockBus{},
+		db:  db,
+		wg: &ControllerManager{
+			controllers: map[domain.InterfaceBackend]backendInstance{
+				config.LocalBackendName: {Implementation: &mockController{}},
+			},
+		},
+	}
+
+	return m, db
+}
+
+func adminContext() context.Context {
+	return domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "admin", IsAdmin: true})
+}
+
+func addressesOf(peer *domain.Peer) []string {
+	return domain.CidrsToStringSlice(peer.Interface.Addresses)
+}
+
+func TestManager_PreparePeer_SkipsReservationsAndPools(t *testing.T) {
+	m, _ := newIpamTestManager(t)
+
+	peer, err := m.PreparePeer(adminContext(), "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.6/32"}, addressesOf(peer))
+}
+
+func TestManager_PreparePeerFromPool(t *testing.T) {
+	m, db := newIpamTestManager(t)
+	ctx := adminContext()
+
+	for _, expected := range []string{"10.0.0.4/32", "10.0.0.5/32"} {
+		peer, err := m.PreparePeerFromPool(ctx, "wg0", "printers")
+		require.NoError(t, err)
+		assert.Equal(t, []string{expected}, addressesOf(peer))
+		_, err = m.CreatePeer(ctx, peer)
+		require.NoError(t, err)
+	}
+	require.Len(t, db.savedPeers, 2)
+
+	_, err := m.PreparePeerFromPool(ctx, "wg0", "printers")
+	assert.ErrorContains(t, err, "ip pool printers is exhausted")
+
+	_, err = m.PreparePeerFromPool(ctx, "wg0", "unknown")
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user"})
+	_, err = m.PreparePeerFromPool(userCtx, "wg0", "printers")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_CreatePeer_DuplicateAddress(t *testing.T) {
+	m, _ := newIpamTestManager(t)
+	ctx := adminContext()
+
+	first, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	_, err = m.CreatePeer(ctx, first)
+	require.NoError(t, err)
+
+	second, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.7/32"}, addressesOf(second))
+
+	second.Interface.Addresses = first.Interface.Addresses
+	_, err = m.CreatePeer(ctx, second)
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+
+	// the peer keeps its own address on updates
+	assert.NoError(t, m.validatePeerAddresses(ctx, first, first))
+}
+
+func TestManager_GetIpamStatus(t *testing.T) {
+	m, _ := newIpamTestManager(t)
+	ctx := adminContext()
+
+	peer, err := m.PreparePeerFromPool(ctx, "wg0", "printers")
+	require.NoError(t, err)
+	_, err = m.CreatePeer(ctx, peer)
+	require.NoError(t, err)
+
+	status, err := m.GetIpamStatus(ctx, "wg0")
+	require.NoError(t, err)
+	require.Len(t, status, 1)
+
+	network := status[0]
+	assert.Equal(t, "10.0.0.0/24", network.Network.String())
+	assert.Equal(t, int64(256), network.Size.Int64())
+	require.Len(t, network.Used, 1)
+	assert.Equal(t, peer.Identifier, network.Used[0].PeerIdentifier)
+	assert.Equal(t, "10.0.0.1-10.0.0.3", network.Reserved[0].String())
+	// 256 - network - broadcast - 3 reserved - 1 used
+	assert.Equal(t, int64(250), network.Free.Int64())
+	assert.Equal(t, "10.0.0.5-10.0.0.254", network.FreeRanges[0].String())
+	require.Len(t, network.Pools, 1)
+	assert.Equal(t, int64(2), network.Pools[0].Size.Int64())
+	assert.Equal(t, int64(1), network.Pools[0].Free.Int64())
+
+	userCtx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user"})
+	_, err = m.GetIpamStatus(userCtx, "wg0")
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
+
+func TestManager_CreatePeer_KeepsLeasedAddresses(t *testing.T) {
+	m, db := newIpamTestManager(t)
+	m.cfg.Core.SelfProvisioningAllowed = true
+	m.ips = newIpAllocator(db)
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user", IsAdmin: false})
+
+	first, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	second, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.6/32"}, addressesOf(first))
+	assert.Equal(t, []string{"10.0.0.7/32"}, addressesOf(second))
+
+	// users get the addresses that were shown when the peer was prepared
+	created, err := m.CreatePeer(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.6/32"}, addressesOf(created))
+
+	// addresses that were not leased for the peer are replaced
+	second.Interface.Addresses = cidrs(t, "10.0.0.100/32")
+	created, err = m.CreatePeer(ctx, second)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.8/32"}, addressesOf(created))
+}
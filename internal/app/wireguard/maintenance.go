@@ -0,0 +1,125 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func (m Manager) runMaintenanceCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(m.cfg.Advanced.MaintenanceCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		interfaces, err := m.db.GetAllInterfaces(ctx)
+		if err != nil {
+			slog.Error("failed to fetch all interfaces for maintenance check", "error", err)
+			continue
+		}
+
+		now := time.Now()
+		for _, iface := range interfaces {
+			if iface.MaintenanceSchedule.IsEmpty() {
+				continue
+			}
+			if err := m.checkMaintenanceSchedule(ctx, &iface, now); err != nil {
+				slog.Error("failed to apply maintenance schedule", "interface", iface.Identifier, "error", err)
+			}
+		}
+	}
+}
+
+// checkMaintenanceSchedule executes the due actions of all maintenance windows of the given interface
+// and announces upcoming windows to the peer owners.
+func (m Manager) checkMaintenanceSchedule(ctx context.Context, iface *domain.Interface, now time.Time) error {
+	var events []audit.InterfaceEvent
+	var notices []domain.MaintenanceNotice
+	stateChanged, enabledChanged := false, false
+
+	for i := range iface.MaintenanceSchedule.Windows {
+		w := &iface.MaintenanceSchedule.Windows[i]
+
+		if start, due := w.NotificationDue(now, m.cfg.Advanced.MaintenanceNotifyBefore); due {
+			w.Notified = &start
+			stateChanged = true
+			notices = append(notices, domain.MaintenanceNotice{Window: *w, Start: start, End: w.NextEnd(start)})
+		}
+
+		action, _, due := w.DueAction(now)
+		if !due {
+			continue
+		}
+		w.LastRun = &now
+		stateChanged = true
+
+		switch {
+		case action == domain.MaintenanceActionDisable && !iface.IsDisabled():
+			iface.Disabled = &now
+			iface.DisabledReason = domain.DisabledReasonMaintenance
+			events = append(events, audit.InterfaceEvent{Action: "maintenance-disable", Maintenance: w})
+		case action == domain.MaintenanceActionEnable && iface.IsDisabled():
+			// the end of a window only re-enables interfaces that have been disabled by a maintenance window
+			if w.Action == domain.MaintenanceActionDisable && iface.DisabledReason != domain.DisabledReasonMaintenance {
+				continue
+			}
+			iface.Disabled = nil
+			iface.DisabledReason = ""
+			events = append(events, audit.InterfaceEvent{Action: "maintenance-enable", Maintenance: w})
+		default:
+			continue
+		}
+		enabledChanged = true
+	}
+
+	switch {
+	case enabledChanged:
+		slog.Info("executing interface maintenance", "interface", iface.Identifier, "disabled", iface.IsDisabled())
+
+		saved, err := m.saveInterface(ctx, iface)
+		if err != nil {
+			return fmt.Errorf("failed to save interface: %w", err)
+		}
+		iface = saved
+
+		m.bus.Publish(app.TopicInterfaceUpdated, *iface)
+	case stateChanged:
+		err := m.db.SaveInterface(ctx, iface.Identifier, func(in *domain.Interface) (*domain.Interface, error) {
+			in.MaintenanceSchedule = iface.MaintenanceSchedule
+			return in, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save maintenance state: %w", err)
+		}
+	}
+
+	for _, notice := range notices {
+		notice.Interface = *iface
+		slog.Info("announcing interface maintenance", "interface", iface.Identifier, "start", notice.Start)
+
+		m.bus.Publish(app.TopicInterfaceMaintenanceUpcoming, notice)
+		events = append(events, audit.InterfaceEvent{Action: "maintenance-announce", Maintenance: &notice.Window})
+	}
+
+	for _, event := range events {
+		event.Interface = *iface
+		m.bus.Publish(app.TopicAuditInterfaceChanged, domain.AuditEventWrapper[audit.InterfaceEvent]{
+			Ctx:   ctx,
+			Event: event,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package wireguard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestManager_CheckMaintenanceSchedule(t *testing.T) {
+	m, db := newIpamTestManager(t)
+	m.cfg.Advanced.MaintenanceNotifyBefore = 24 * time.Hour
+	bus := m.bus.(*mockBus)
+	ctx := adminContext()
+
+	start := time.Date(2024, 1, 7, 2, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	db.iface.MaintenanceSchedule = &domain.MaintenanceSchedule{Windows: []domain.MaintenanceWindow{
+		{Action: domain.MaintenanceActionDisable, Start: start, End: &end, Recurrence: "weekly"},
+	}}
+	db.iface.MaintenanceSchedule.CarryState(nil, start.Add(-48*time.Hour))
+
+	check := func(now time.Time) *domain.Interface {
+		iface := *db.iface
+		schedule := *db.iface.MaintenanceSchedule
+		schedule.Windows = append([]domain.MaintenanceWindow(nil), schedule.Windows...)
+		iface.MaintenanceSchedule = &schedule
+		require.NoError(t, m.checkMaintenanceSchedule(ctx, &iface, now))
+		return db.iface
+	}
+
+	iface := check(start.Add(-time.Hour))
+	assert.False(t, iface.IsDisabled())
+	assert.Equal(t, &start, iface.MaintenanceSchedule.Windows[0].Notified)
+	assert.Contains(t, bus.topics, app.TopicInterfaceMaintenanceUpcoming)
+
+	iface = check(start.Add(time.Minute))
+	require.True(t, iface.IsDisabled())
+	assert.Equal(t, domain.DisabledReasonMaintenance, iface.DisabledReason)
+	assert.Contains(t, bus.topics, app.TopicAuditInterfaceChanged)
+
+	iface = check(end.Add(time.Minute))
+	assert.False(t, iface.IsDisabled())
+
+	// interfaces disabled by an admin are not enabled at the end of a window
+	nextStart := start.AddDate(0, 0, 7)
+	check(nextStart.Add(time.Minute))
+	db.iface.DisabledReason = domain.DisabledReasonAdmin
+	iface = check(end.AddDate(0, 0, 7).Add(time.Minute))
+	assert.True(t, iface.IsDisabled())
+}
+
+func TestManager_UpdateInterface_KeepsMaintenanceState(t *testing.T) {
+	m, db := newIpamTestManager(t)
+	ctx := adminContext()
+
+	start := time.Now().Add(time.Hour)
+	lastRun := time.Now().Add(-time.Hour)
+	db.iface.MaintenanceSchedule = &domain.MaintenanceSchedule{Windows: []domain.MaintenanceWindow{
+		{Action: domain.MaintenanceActionDisable, Start: start, Recurrence: "daily", LastRun: &lastRun},
+	}}
+
+	update := *db.iface
+	update.MaintenanceSchedule = nil
+	saved, _, err := m.UpdateInterface(ctx, &update)
+	require.NoError(t, err)
+	require.NotNil(t, saved.MaintenanceSchedule, "omitted schedules are kept")
+
+	changed := *saved
+	changed.MaintenanceSchedule = &domain.MaintenanceSchedule{Windows: []domain.MaintenanceWindow{
+		{Action: domain.MaintenanceActionDisable, Start: start, Recurrence: "daily", Description: "updates"},
+		{Action: domain.MaintenanceActionEnable, Start: start.Add(time.Hour)},
+	}}
+	saved, _, err = m.UpdateInterface(ctx, &changed)
+	require.NoError(t, err)
+	assert.True(t, saved.MaintenanceSchedule.Windows[0].LastRun.Equal(lastRun))
+	assert.True(t, saved.MaintenanceSchedule.Windows[1].LastRun.After(lastRun))
+
+	invalid := *saved
+	invalid.MaintenanceSchedule = &domain.MaintenanceSchedule{Windows: []domain.MaintenanceWindow{
+		{Action: domain.MaintenanceActionEnable, Start: start, Recurrence: "hourly"},
+	}}
+	_, _, err = m.UpdateInterface(ctx, &invalid)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
@@ -0,0 +1,179 @@
+package wireguard
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+type prefixClaim struct {
+	owner   ipOwner // the peer and the interface it belongs to
+	prefix  netip.Prefix
+	lease   bool // true for prefixes of prepared peers, which do not block claims of other peers
+	expires time.Time
+}
+
+// prefixAllocator reserves delegated prefixes that are not stored yet. Stored prefixes are always read from the
+// database, the allocator only knows about prefixes of prepared peers (leases) and of validated peers that are
+// about to be saved (claims). Like the ipAllocator, it prevents concurrent requests from using the same prefix.
+type prefixAllocator struct {
+	mu     sync.Mutex
+	claims []prefixClaim
+}
+
+func newPrefixAllocator() *prefixAllocator {
+	return &prefixAllocator{}
+}
+
+// allocate returns the first prefix of the pool that overlaps neither with a stored prefix nor with a pending lease
+// or claim of the interface, and leases it for the prepared peer. If lease is the zero value, the prefix is only
+// looked up. The second return value is false if the pool is exhausted.
+func (a *prefixAllocator) allocate(
+	iface domain.InterfaceIdentifier,
+	policy *domain.PrefixDelegationPolicy,
+	stored []netip.Prefix,
+	lease ipOwner,
+) (domain.Cidr, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	a.expire(now)
+
+	used := make([]domain.IpRange, 0, len(stored)+len(a.claims))
+	for _, prefix := range stored {
+		used = append(used, domain.IpRangeFromPrefix(prefix))
+	}
+	for _, c := range a.claims {
+		if c.owner.Interface == iface {
+			used = append(used, domain.IpRangeFromPrefix(c.prefix))
+		}
+	}
+
+	prefix, ok := firstFreePrefix(policy, mergeIpRanges(used))
+	if ok && lease != (ipOwner{}) {
+		a.claims = append(a.claims, prefixClaim{
+			owner:   lease,
+			prefix:  prefix.Prefix(),
+			lease:   true,
+			expires: now.Add(ipClaimTimeout),
+		})
+	}
+
+	return prefix, ok
+}
+
+// claim reserves the prefix for the given peer until it is stored. It fails with domain.ErrDuplicateEntry if the
+// prefix overlaps with a claim of another peer of the same interface. Leases of prepared peers are ignored.
+// Claims of previousId are accepted as well, the identifier of a peer changes if its public key is replaced.
+func (a *prefixAllocator) claim(owner ipOwner, previousId domain.PeerIdentifier, prefix netip.Prefix) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	a.expire(now)
+
+	for _, c := range a.claims {
+		if c.lease || c.owner.Interface != owner.Interface || c.owner.Peer == owner.Peer ||
+			(previousId != "" && c.owner.Peer == previousId) || !c.prefix.Overlaps(prefix) {
+			continue
+		}
+		return fmt.Errorf("delegated prefix %s overlaps with prefix %s of peer %s: %w", prefix, c.prefix,
+			c.owner.Peer, domain.ErrDuplicateEntry)
+	}
+
+	a.claims = append(a.claims, prefixClaim{owner: owner, prefix: prefix, expires: now.Add(ipClaimTimeout)})
+
+	return nil
+}
+
+// region event-handlers
+
+// handlePeerSaved releases all leases and outdated claims of the peer. Claims of the stored prefix are kept until
+// they expire, they are covered by the database anyway. Leases of the stored prefix are released as well, as the
+// prepared peer might have been saved with another identifier.
+func (a *prefixAllocator) handlePeerSaved(peer domain.Peer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	saved, hasPrefix := peer.DelegatedPrefixCidr()
+	a.claims = slices.DeleteFunc(a.claims, func(c prefixClaim) bool {
+		switch {
+		case c.owner.Peer == peer.Identifier:
+			return c.lease || !hasPrefix || c.prefix != saved.Prefix().Masked()
+		case c.lease && hasPrefix && c.owner.Interface == peer.InterfaceIdentifier:
+			return c.prefix.Overlaps(saved.Prefix())
+		default:
+			return false
+		}
+	})
+}
+
+func (a *prefixAllocator) handlePeerDeleted(peer domain.Peer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.claims = slices.DeleteFunc(a.claims, func(c prefixClaim) bool {
+		return c.owner.Peer == peer.Identifier
+	})
+}
+
+// endregion event-handlers
+
+// expire removes all leases and claims that expired before now. The caller must hold the lock.
+func (a *prefixAllocator) expire(now time.Time) {
+	a.claims = slices.DeleteFunc(a.claims, func(c prefixClaim) bool {
+		return !c.expires.After(now)
+	})
+}
+
+// firstFreePrefix walks the prefixes of the pool in order and returns the first one that does not overlap with a
+// used range. The used ranges must be sorted and must not overlap. All prefixes that overlap with the same used range
+// are skipped at once, so the walk takes at most one step per used range.
+func firstFreePrefix(policy *domain.PrefixDelegationPolicy, used []domain.IpRange) (domain.Cidr, bool) {
+	pool := policy.PoolPrefix()
+
+	i := 0
+	for prefix := policy.FirstPrefix(); ; prefix = prefix.NextSubnet() {
+		candidate := domain.IpRangeFromPrefix(prefix.Prefix())
+		for i < len(used) && used[i].To.Less(candidate.From) {
+			i++
+		}
+		if i == len(used) || candidate.To.Less(used[i].From) {
+			return prefix, true
+		}
+
+		// continue after the last prefix that overlaps with the used range
+		if !pool.Contains(used[i].To) {
+			return domain.Cidr{}, false
+		}
+		prefix = domain.CidrFromPrefix(netip.PrefixFrom(used[i].To, policy.PrefixLength).Masked())
+		if policy.IsLastPrefix(prefix) {
+			return domain.Cidr{}, false
+		}
+	}
+}
+
+// mergeIpRanges sorts the ranges and joins overlapping ones.
+func mergeIpRanges(ranges []domain.IpRange) []domain.IpRange {
+	slices.SortFunc(ranges, func(a, b domain.IpRange) int {
+		return a.From.Compare(b.From)
+	})
+
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && !merged[n-1].To.Less(r.From) {
+			if merged[n-1].To.Less(r.To) {
+				merged[n-1].To = r.To
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
@@ -0,0 +1,196 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// GetDelegatedPrefixes returns the delegated prefixes of all peers of the given interface, ordered by prefix.
+func (m Manager) GetDelegatedPrefixes(ctx context.Context, id domain.InterfaceIdentifier) (
+	[]domain.DelegatedPrefixAssignment,
+	error,
+) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	iface, peers, err := m.db.GetInterfaceAndPeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", id, err)
+	}
+
+	result := make([]domain.DelegatedPrefixAssignment, 0, len(peers))
+	for _, peer := range peers {
+		prefix, ok := peer.DelegatedPrefixCidr()
+		if !ok {
+			continue
+		}
+
+		assignment := domain.DelegatedPrefixAssignment{
+			PeerIdentifier: peer.Identifier,
+			DisplayName:    peer.DisplayName,
+			Prefix:         prefix,
+		}
+		if !iface.PrefixDelegation.IsEmpty() {
+			assignment.Index, assignment.InPool = iface.PrefixDelegation.IndexOf(prefix)
+		}
+		result = append(result, assignment)
+	}
+
+	slices.SortFunc(result, func(a, b domain.DelegatedPrefixAssignment) int {
+		return a.Prefix.Prefix().Addr().Compare(b.Prefix.Prefix().Addr())
+	})
+
+	return result, nil
+}
+
+// getFreshDelegatedPrefix returns the first prefix of the delegation pool that does not overlap
+// with a prefix of another peer of the interface. The prefix is leased for the prepared peer until it is saved,
+// a zero lease owner skips the lease.
+func (m Manager) getFreshDelegatedPrefix(ctx context.Context, iface *domain.Interface, lease ipOwner) (
+	domain.Cidr,
+	error,
+) {
+	peers, err := m.getPrefixHolders(ctx, iface.Identifier)
+	if err != nil {
+		return domain.Cidr{}, err
+	}
+
+	used := make([]netip.Prefix, 0, len(peers))
+	for _, peer := range peers {
+		if prefix, ok := peer.DelegatedPrefixCidr(); ok {
+			used = append(used, prefix.Prefix().Masked())
+		}
+	}
+
+	prefix, ok := m.prefixAllocator().allocate(iface.Identifier, iface.PrefixDelegation, used, lease)
+	if !ok {
+		return domain.Cidr{}, fmt.Errorf("delegation pool %s is exhausted: %w", iface.PrefixDelegation.Pool,
+			domain.ErrInvalidData)
+	}
+
+	return prefix, nil
+}
+
+// validateDelegatedPrefix normalizes the delegated prefix of the peer and ensures that it does not overlap
+// with the prefix of another peer of the same interface. Unchanged prefixes are not checked again.
+// The checked prefix is claimed for the peer, so that concurrent requests can not use it as well.
+func (m Manager) validateDelegatedPrefix(ctx context.Context, existing, peer *domain.Peer) error {
+	peer.DelegatedPrefix = strings.TrimSpace(peer.DelegatedPrefix)
+	if peer.DelegatedPrefix == "" {
+		return nil
+	}
+
+	prefix, err := netip.ParsePrefix(peer.DelegatedPrefix)
+	if err != nil || !prefix.Addr().Is6() || prefix.Addr().Is4In6() || prefix.Masked() != prefix {
+		return fmt.Errorf("delegated prefix %s is not an IPv6 network: %w", peer.DelegatedPrefix,
+			domain.ErrInvalidData)
+	}
+	peer.DelegatedPrefix = prefix.String()
+
+	if existing != nil && existing.DelegatedPrefix == peer.DelegatedPrefix &&
+		existing.InterfaceIdentifier == peer.InterfaceIdentifier {
+		return nil
+	}
+
+	// users can only keep the prefix that was allocated from the pool when the peer was prepared
+	if !domain.GetUserInfo(ctx).IsAdmin {
+		if existing != nil {
+			return fmt.Errorf("delegated prefix can only be changed by admins: %w", domain.ErrNoPermission)
+		}
+		iface, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier)
+		if err != nil {
+			return fmt.Errorf("invalid interface: %w", domain.ErrInvalidData)
+		}
+		if iface.PrefixDelegation.IsEmpty() {
+			return fmt.Errorf("delegated prefix can only be set by admins: %w", domain.ErrNoPermission)
+		}
+		if _, ok := iface.PrefixDelegation.IndexOf(domain.CidrFromPrefix(prefix)); !ok {
+			return fmt.Errorf("delegated prefix must be part of the delegation pool: %w", domain.ErrNoPermission)
+		}
+	}
+
+	peers, err := m.getPrefixHolders(ctx, peer.InterfaceIdentifier)
+	if err != nil {
+		return err
+	}
+	for _, other := range peers {
+		if other.Identifier == peer.Identifier || (existing != nil && other.Identifier == existing.Identifier) {
+			continue
+		}
+		otherPrefix, ok := other.DelegatedPrefixCidr()
+		if ok && otherPrefix.Prefix().Overlaps(prefix) {
+			return fmt.Errorf("delegated prefix %s overlaps with prefix %s of peer %s: %w", peer.DelegatedPrefix,
+				other.DelegatedPrefix, other.Identifier, domain.ErrDuplicateEntry)
+		}
+	}
+
+	var previousId domain.PeerIdentifier
+	if existing != nil {
+		previousId = existing.Identifier
+	}
+	owner := ipOwner{Peer: peer.Identifier, Interface: peer.InterfaceIdentifier}
+
+	return m.prefixAllocator().claim(owner, previousId, prefix)
+}
+
+// renumberDelegatedPrefixes moves the delegated prefixes of the peers to the new delegation pool.
+// Each prefix keeps its index within the pool, so the n-th prefix of the old pool becomes the n-th prefix of the
+// new pool. Prefixes that are not part of the old pool, e.g. manually assigned ones, are left unchanged.
+// The returned peers have been modified and must be saved.
+func renumberDelegatedPrefixes(old, new *domain.PrefixDelegationPolicy, peers []domain.Peer) ([]*domain.Peer, error) {
+	if err := new.Validate(); err != nil { // the interface is validated when it is saved, but the pool is needed now
+		return nil, fmt.Errorf("invalid prefix delegation: %w", err)
+	}
+	if old.IsEmpty() || new.IsEmpty() || *old == *new {
+		return nil, nil
+	}
+
+	changed := make([]*domain.Peer, 0, len(peers))
+	for i := range peers {
+		prefix, ok := peers[i].DelegatedPrefixCidr()
+		if !ok {
+			continue
+		}
+		index, ok := old.IndexOf(prefix)
+		if !ok {
+			continue
+		}
+		newPrefix, ok := new.PrefixAt(index)
+		if !ok {
+			return nil, fmt.Errorf("prefix %s of peer %s does not fit into delegation pool %s: %w",
+				prefix.String(), peers[i].Identifier, new.Pool, domain.ErrInvalidData)
+		}
+
+		peers[i].DelegatedPrefix = newPrefix.String()
+		changed = append(changed, &peers[i])
+	}
+
+	return changed, nil
+}
+
+// getPrefixHolders returns all peers of the interface that may hold a delegated prefix. Peers in the recycle bin keep
+// their prefix until they are purged, so they are included as well.
+func (m Manager) getPrefixHolders(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
+	peers, err := m.db.GetInterfacePeers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing peers: %w", err)
+	}
+
+	deletedPeers, err := m.db.GetDeletedPeers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deleted peers: %w", err)
+	}
+	for _, peer := range deletedPeers {
+		if peer.InterfaceIdentifier == id {
+			peers = append(peers, peer)
+		}
+	}
+
+	return peers, nil
+}
@@ -0,0 +1,232 @@
+package wireguard
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func newPrefixDelegationTestManager(t *testing.T) (Manager, *mockDB) {
+	m, db := newIpamTestManager(t)
+	db.iface.IpamPolicy = nil
+	db.iface.PrefixDelegation = &domain.PrefixDelegationPolicy{Pool: "2001:db8:100::/54", PrefixLength: 56}
+
+	return m, db
+}
+
+func TestManager_PreparePeer_DelegatedPrefix(t *testing.T) {
+	m, db := newPrefixDelegationTestManager(t)
+	ctx := adminContext()
+
+	var prefixes []string
+	for i := 0; i < 4; i++ {
+		peer, err := m.PreparePeer(ctx, "wg0")
+		require.NoError(t, err)
+		prefixes = append(prefixes, peer.DelegatedPrefix)
+		_, err = m.CreatePeer(ctx, peer)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{
+		"2001:db8:100::/56", "2001:db8:100:100::/56", "2001:db8:100:200::/56", "2001:db8:100:300::/56",
+	}, prefixes)
+
+	_, err := m.PreparePeer(ctx, "wg0")
+	assert.ErrorContains(t, err, "delegation pool 2001:db8:100::/54 is exhausted")
+
+	// prefixes of deleted peers are reused
+	for id, peer := range db.savedPeers {
+		if peer.DelegatedPrefix == "2001:db8:100:100::/56" {
+			delete(db.savedPeers, id)
+		}
+	}
+	peer, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8:100:100::/56", peer.DelegatedPrefix)
+}
+
+func TestManager_ValidateDelegatedPrefix(t *testing.T) {
+	m, db := newPrefixDelegationTestManager(t)
+	ctx := adminContext()
+
+	peer, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	_, err = m.CreatePeer(ctx, peer)
+	require.NoError(t, err)
+
+	other := &domain.Peer{Identifier: "other", InterfaceIdentifier: "wg0", DelegatedPrefix: "2001:db8:100::/64"}
+	assert.ErrorIs(t, m.validateDelegatedPrefix(ctx, nil, other), domain.ErrDuplicateEntry)
+
+	other.DelegatedPrefix = "2001:db8:100::1/64"
+	assert.ErrorIs(t, m.validateDelegatedPrefix(ctx, nil, other), domain.ErrInvalidData)
+	other.DelegatedPrefix = "10.0.0.0/24"
+	assert.ErrorIs(t, m.validateDelegatedPrefix(ctx, nil, other), domain.ErrInvalidData)
+
+	other.DelegatedPrefix = " 2001:db8:200:0::/56"
+	assert.NoError(t, m.validateDelegatedPrefix(ctx, nil, other))
+	assert.Equal(t, "2001:db8:200::/56", other.DelegatedPrefix)
+
+	// the peer itself does not conflict with its own prefix
+	existing := db.savedPeers[peer.Identifier]
+	updated := *existing
+	updated.DelegatedPrefix = "2001:db8:100::/60"
+	assert.NoError(t, m.validateDelegatedPrefix(ctx, existing, &updated))
+}
+
+func TestManager_UpdateInterface_RenumbersDelegatedPrefixes(t *testing.T) {
+	m, db := newPrefixDelegationTestManager(t)
+	ctx := adminContext()
+
+	for i := 0; i < 3; i++ {
+		peer, err := m.PreparePeer(ctx, "wg0")
+		require.NoError(t, err)
+		_, err = m.CreatePeer(ctx, peer)
+		require.NoError(t, err)
+	}
+	manual := &domain.Peer{Identifier: "manual", InterfaceIdentifier: "wg0", DelegatedPrefix: "2001:db8:ff::/56"}
+	db.savedPeers[manual.Identifier] = manual
+
+	smaller := *db.iface
+	smaller.PrefixDelegation = &domain.PrefixDelegationPolicy{Pool: "2001:db8:200::/55", PrefixLength: 56}
+	_, _, err := m.UpdateInterface(ctx, &smaller)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+
+	renumbered := *db.iface
+	renumbered.PrefixDelegation = &domain.PrefixDelegationPolicy{Pool: "2001:db8:200::/48", PrefixLength: 60}
+	_, _, err = m.UpdateInterface(ctx, &renumbered)
+	require.NoError(t, err)
+
+	assignments, err := m.GetDelegatedPrefixes(ctx, "wg0")
+	require.NoError(t, err)
+	require.Len(t, assignments, 4)
+	for i, expected := range []string{"2001:db8:ff::/56", "2001:db8:200::/60", "2001:db8:200:10::/60",
+		"2001:db8:200:20::/60"} {
+		assert.Equal(t, expected, assignments[i].Prefix.String())
+	}
+	assert.False(t, assignments[0].InPool)
+	assert.Equal(t, domain.PeerIdentifier("manual"), assignments[0].PeerIdentifier)
+	assert.True(t, assignments[3].InPool)
+	assert.Equal(t, uint64(2), assignments[3].Index)
+
+	// omitting the policy keeps it
+	unchanged := *db.iface
+	unchanged.PrefixDelegation = nil
+	updated, _, err := m.UpdateInterface(ctx, &unchanged)
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8:200::/48", updated.PrefixDelegation.Pool)
+}
+
+func TestManager_ValidateDelegatedPrefix_User(t *testing.T) {
+	m, _ := newPrefixDelegationTestManager(t)
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user"})
+
+	peer := &domain.Peer{Identifier: "peer", InterfaceIdentifier: "wg0", DelegatedPrefix: "2001:db8:100:200::/56"}
+	assert.NoError(t, m.validateDelegatedPrefix(ctx, nil, peer))
+
+	outside := &domain.Peer{Identifier: "outside", InterfaceIdentifier: "wg0", DelegatedPrefix: "2001:db8:200::/56"}
+	assert.ErrorIs(t, m.validateDelegatedPrefix(ctx, nil, outside), domain.ErrNoPermission)
+
+	changed := *peer
+	changed.DelegatedPrefix = "2001:db8:100:300::/56"
+	assert.ErrorIs(t, m.validateDelegatedPrefix(ctx, peer, &changed), domain.ErrNoPermission)
+	assert.NoError(t, m.validateDelegatedPrefix(ctx, peer, peer))
+}
+
+func TestFirstFreePrefix(t *testing.T) {
+	policy := &domain.PrefixDelegationPolicy{Pool: "2001:db8:100::/54", PrefixLength: 56}
+	used := func(prefixes ...string) []domain.IpRange {
+		ranges := make([]domain.IpRange, len(prefixes))
+		for i, prefix := range prefixes {
+			ranges[i] = domain.IpRangeFromPrefix(netip.MustParsePrefix(prefix))
+		}
+		return mergeIpRanges(ranges)
+	}
+
+	prefix, ok := firstFreePrefix(policy, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "2001:db8:100::/56", prefix.String())
+
+	// a manually assigned larger prefix blocks all prefixes it contains
+	prefix, ok = firstFreePrefix(policy, used("2001:db8:100::/55"))
+	assert.True(t, ok)
+	assert.Equal(t, "2001:db8:100:200::/56", prefix.String())
+
+	// a smaller prefix blocks the prefix that contains it
+	prefix, ok = firstFreePrefix(policy, used("2001:db8:100:200::/64", "2001:db8:100::/55", "2001:db8:100::/56"))
+	assert.True(t, ok)
+	assert.Equal(t, "2001:db8:100:300::/56", prefix.String())
+
+	_, ok = firstFreePrefix(policy, used("2001:db8:100::/55", "2001:db8:100:200::/56", "2001:db8:100:3ff::/64"))
+	assert.False(t, ok)
+	_, ok = firstFreePrefix(policy, used("2001:db8:100:100::/56", "2001:db8:100::/48"))
+	assert.False(t, ok)
+}
+
+func TestPrefixAllocator_LeasesAndClaims(t *testing.T) {
+	policy := &domain.PrefixDelegationPolicy{Pool: "2001:db8:100::/54", PrefixLength: 56}
+	a := newPrefixAllocator()
+	leaseA := ipOwner{Peer: "prepared-a", Interface: "wg0"}
+	leaseB := ipOwner{Peer: "prepared-b", Interface: "wg0"}
+	allocate := func(lease ipOwner) string {
+		prefix, ok := a.allocate("wg0", policy, nil, lease)
+		require.True(t, ok)
+		return prefix.String()
+	}
+
+	assert.Equal(t, "2001:db8:100::/56", allocate(leaseA))
+	assert.Equal(t, "2001:db8:100:100::/56", allocate(leaseB))
+	assert.Equal(t, "2001:db8:100:200::/56", allocate(ipOwner{}), "previews do not lease the prefix")
+	assert.Equal(t, "2001:db8:100:200::/56", allocate(ipOwner{}))
+
+	// claims block other peers and the allocation, leases only block the allocation
+	x := ipOwner{Peer: "x", Interface: "wg0"}
+	require.NoError(t, a.claim(x, "", netip.MustParsePrefix("2001:db8:100:200::/56")))
+	assert.ErrorIs(t, a.claim(ipOwner{Peer: "y", Interface: "wg0"}, "", netip.MustParsePrefix("2001:db8:100:200::/60")),
+		domain.ErrDuplicateEntry)
+	assert.NoError(t, a.claim(ipOwner{Peer: "y", Interface: "wg1"}, "", netip.MustParsePrefix("2001:db8:100:200::/56")))
+	assert.NoError(t, a.claim(ipOwner{Peer: "x-new-key", Interface: "wg0"}, "x",
+		netip.MustParsePrefix("2001:db8:100:200::/56")))
+	assert.Equal(t, "2001:db8:100:300::/56", allocate(ipOwner{}))
+
+	// saving the prepared peer with another prefix releases its lease
+	a.handlePeerSaved(domain.Peer{Identifier: "prepared-a", InterfaceIdentifier: "wg0", DelegatedPrefix: "2001:db8:ff::/56"})
+	assert.Equal(t, "2001:db8:100::/56", allocate(ipOwner{}))
+
+	// saving a peer with a new identifier releases the lease of its prefix
+	a.handlePeerSaved(domain.Peer{Identifier: "new", InterfaceIdentifier: "wg0", DelegatedPrefix: "2001:db8:100:100::/56"})
+	a.handlePeerDeleted(domain.Peer{Identifier: "x"})
+	a.handlePeerDeleted(domain.Peer{Identifier: "x-new-key"})
+	prefix, ok := a.allocate("wg0", policy, []netip.Prefix{netip.MustParsePrefix("2001:db8:100::/56")}, ipOwner{})
+	require.True(t, ok)
+	assert.Equal(t, "2001:db8:100:100::/56", prefix.String())
+}
+
+func TestManager_CreatePeer_KeepsSubmittedDelegatedPrefix(t *testing.T) {
+	m, _ := newPrefixDelegationTestManager(t)
+	m.cfg.Core.SelfProvisioningAllowed = true
+	m.prefixes = newPrefixAllocator()
+	ctx := domain.SetUserInfo(context.Background(), &domain.ContextUserInfo{Id: "user", IsAdmin: false})
+
+	first, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	second, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8:100::/56", first.DelegatedPrefix)
+	assert.Equal(t, "2001:db8:100:100::/56", second.DelegatedPrefix)
+
+	created, err := m.CreatePeer(ctx, second)
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8:100:100::/56", created.DelegatedPrefix)
+
+	first.DelegatedPrefix = "2001:db8:100:100::/56"
+	_, err = m.CreatePeer(ctx, first)
+	assert.ErrorIs(t, err, domain.ErrDuplicateEntry)
+
+	first.DelegatedPrefix = "2001:db8:200::/56"
+	_, err = m.CreatePeer(ctx, first)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+}
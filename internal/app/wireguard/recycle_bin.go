@@ -0,0 +1,101 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// GetDeletedPeers returns all peers in the recycle bin.
+func (m Manager) GetDeletedPeers(ctx context.Context) ([]domain.Peer, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetDeletedPeers(ctx)
+}
+
+// RestorePeer moves the peer with the given identifier out of the recycle bin and re-creates it
+// on the WireGuard backend. Addresses and delegated prefixes of deleted peers stay reserved,
+// so the restored peer can not conflict with peers that were created in the meantime.
+func (m Manager) RestorePeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	peer, err := m.db.GetDeletedPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find deleted peer %s: %w", id, err)
+	}
+
+	if _, err := m.db.GetInterface(ctx, peer.InterfaceIdentifier); err != nil {
+		return nil, fmt.Errorf("unable to find interface %s: %w", peer.InterfaceIdentifier, err)
+	}
+
+	if err := m.db.RestorePeer(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to restore peer %s: %w", id, err)
+	}
+
+	peer, err = m.db.GetPeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load restored peer %s: %w", id, err)
+	}
+
+	err = m.savePeers(ctx, peer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-create restored peer %s: %w", id, err)
+	}
+
+	m.bus.Publish(app.TopicPeerCreated, *peer)
+
+	return peer, nil
+}
+
+func (m Manager) runRecycleBinPurge(ctx context.Context) {
+	if m.cfg.Advanced.DeletionRetention <= 0 {
+		return // peers are deleted permanently right away
+	}
+
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(m.cfg.Advanced.ExpiryCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		m.purgeDeletedPeers(ctx, time.Now().Add(-m.cfg.Advanced.DeletionRetention))
+	}
+}
+
+// purgeDeletedPeers permanently deletes all peers that were moved to the recycle bin before the given time.
+func (m Manager) purgeDeletedPeers(ctx context.Context, deletedBefore time.Time) {
+	peers, err := m.db.GetDeletedPeers(ctx)
+	if err != nil {
+		slog.Error("failed to fetch deleted peers for purge", "error", err)
+		return
+	}
+
+	for _, peer := range peers {
+		if !peer.DeletedAt.Time.Before(deletedBefore) {
+			continue
+		}
+
+		slog.Info("purging deleted peer", "peer", peer.Identifier, "deleted", peer.DeletedAt.Time)
+
+		if err := m.db.DeletePeer(ctx, peer.Identifier); err != nil {
+			slog.Error("failed to purge deleted peer", "peer", peer.Identifier, "error", err)
+			continue
+		}
+
+		m.bus.Publish(app.TopicPeerPurged, peer)
+	}
+}
@@ -0,0 +1,81 @@
+package wireguard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestManager_DeletePeer_RecycleBin(t *testing.T) {
+	m, db := newIpamTestManager(t)
+	m.cfg.Advanced.DeletionRetention = time.Hour
+	ctx := adminContext()
+
+	peer, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	_, err = m.CreatePeer(ctx, peer)
+	require.NoError(t, err)
+
+	require.NoError(t, m.DeletePeer(ctx, peer.Identifier))
+	require.Contains(t, db.deletedPeers, peer.Identifier, "peer is moved to the recycle bin")
+	assert.NotContains(t, db.savedPeers, peer.Identifier)
+
+	// the address of the deleted peer stays reserved
+	next, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	assert.NotEqual(t, addressesOf(peer), addressesOf(next))
+
+	deleted, err := m.GetDeletedPeers(ctx)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+
+	userCtx := domain.SetUserInfo(ctx, &domain.ContextUserInfo{Id: "user", IsAdmin: false})
+	_, err = m.RestorePeer(userCtx, peer.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	restored, err := m.RestorePeer(ctx, peer.Identifier)
+	require.NoError(t, err)
+	assert.False(t, restored.IsDeleted())
+	assert.Equal(t, addressesOf(peer), addressesOf(restored))
+	assert.Contains(t, db.savedPeers, peer.Identifier)
+	assert.Contains(t, m.bus.(*mockBus).topics, app.TopicPeerCreated)
+
+	_, err = m.RestorePeer(ctx, peer.Identifier)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestManager_DeletePeer_WithoutRetention(t *testing.T) {
+	m, db := newIpamTestManager(t)
+	ctx := adminContext()
+
+	peer, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	_, err = m.CreatePeer(ctx, peer)
+	require.NoError(t, err)
+
+	require.NoError(t, m.DeletePeer(ctx, peer.Identifier))
+	assert.Empty(t, db.deletedPeers)
+}
+
+func TestManager_PurgeDeletedPeers(t *testing.T) {
+	m, db := newIpamTestManager(t)
+	bus := m.bus.(*mockBus)
+	now := time.Now()
+
+	db.deletedPeers = map[domain.PeerIdentifier]*domain.Peer{
+		"old":    {Identifier: "old", DeletedAt: gorm.DeletedAt{Time: now.Add(-2 * time.Hour), Valid: true}},
+		"recent": {Identifier: "recent", DeletedAt: gorm.DeletedAt{Time: now, Valid: true}},
+	}
+
+	m.purgeDeletedPeers(adminContext(), now.Add(-time.Hour))
+
+	assert.NotContains(t, db.deletedPeers, domain.PeerIdentifier("old"))
+	assert.Contains(t, db.deletedPeers, domain.PeerIdentifier("recent"))
+	assert.Equal(t, []string{app.TopicPeerPurged}, bus.topics)
+}
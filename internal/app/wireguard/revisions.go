@@ -0,0 +1,146 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+// GetRevisions returns all stored revisions of the peer or interface, the latest revision first.
+func (m Manager) GetRevisions(
+	ctx context.Context,
+	objectType domain.RevisionObjectType,
+	id string,
+) ([]domain.ConfigRevision, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.db.GetConfigRevisions(ctx, objectType, id)
+}
+
+// DiffRevisions returns the changes between two revisions of the peer or interface.
+// If to is zero, the latest revision is used.
+func (m Manager) DiffRevisions(
+	ctx context.Context,
+	objectType domain.RevisionObjectType,
+	id string,
+	from, to uint64,
+) ([]domain.RevisionChange, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	if to == 0 {
+		revisions, err := m.db.GetConfigRevisions(ctx, objectType, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load revisions of %s %s: %w", objectType, id, err)
+		}
+		if len(revisions) == 0 {
+			return nil, fmt.Errorf("no revisions of %s %s: %w", objectType, id, domain.ErrNotFound)
+		}
+		to = revisions[0].Version
+	}
+
+	fromRevision, err := m.db.GetConfigRevision(ctx, objectType, id, from)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find revision %d of %s %s: %w", from, objectType, id, err)
+	}
+	toRevision, err := m.db.GetConfigRevision(ctx, objectType, id, to)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find revision %d of %s %s: %w", to, objectType, id, err)
+	}
+
+	return domain.DiffRevisions(fromRevision, toRevision)
+}
+
+// RollbackPeer restores the configuration of the given peer revision. The peer is updated like any other change,
+// so the WireGuard backend and the routes are updated as well, and the rollback is stored as a new revision.
+func (m Manager) RollbackPeer(ctx context.Context, id domain.PeerIdentifier, version uint64) (*domain.Peer, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, err
+	}
+
+	revision, err := m.db.GetConfigRevision(ctx, domain.RevisionObjectPeer, string(id), version)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find revision %d of peer %s: %w", version, id, err)
+	}
+
+	peer, err := revision.Peer()
+	if err != nil {
+		return nil, err
+	}
+
+	// UpdatePeer keeps the current settings for missing optional settings, empty settings remove them
+	if peer.AccessSchedule == nil {
+		peer.AccessSchedule = &domain.AccessSchedule{}
+	}
+	if peer.BandwidthLimit == nil {
+		peer.BandwidthLimit = &domain.BandwidthLimit{}
+	}
+	if peer.Acl == nil {
+		peer.Acl = &domain.AccessControlList{}
+	}
+
+	return m.UpdatePeer(ctx, peer)
+}
+
+// RollbackInterface restores the configuration of the given interface revision through UpdateInterface.
+// The allowed LDAP users are not part of revisions, the current list is kept.
+func (m Manager) RollbackInterface(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	version uint64,
+) (*domain.Interface, []domain.Peer, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	revision, err := m.db.GetConfigRevision(ctx, domain.RevisionObjectInterface, string(id), version)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to find revision %d of interface %s: %w", version, id, err)
+	}
+
+	iface, err := revision.Interface()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to load interface %s: %w", id, err)
+	}
+	iface.LdapAllowedUsers = current.LdapAllowedUsers
+
+	// UpdateInterface keeps the current policies for missing policies, empty policies remove them
+	if iface.InactivityPolicy == nil {
+		iface.InactivityPolicy = &domain.InactivityPolicy{}
+	}
+	if iface.PeerDefBandwidthLimit == nil {
+		iface.PeerDefBandwidthLimit = &domain.BandwidthLimit{}
+	}
+	if iface.AccessPolicy == nil {
+		iface.AccessPolicy = &domain.InterfaceAccessPolicy{}
+	}
+	if iface.OnDemandPolicy == nil {
+		iface.OnDemandPolicy = &domain.OnDemandPolicy{}
+	}
+	if iface.IpamPolicy == nil {
+		iface.IpamPolicy = &domain.IpamPolicy{}
+	}
+	if iface.PrefixDelegation == nil {
+		iface.PrefixDelegation = &domain.PrefixDelegationPolicy{}
+	}
+	if iface.NatPolicy == nil {
+		iface.NatPolicy = &domain.NatPolicy{}
+	}
+	if iface.AmneziaParams == nil {
+		iface.AmneziaParams = &domain.AmneziaParams{}
+	}
+	if iface.MaintenanceSchedule == nil {
+		iface.MaintenanceSchedule = &domain.MaintenanceSchedule{}
+	}
+
+	return m.UpdateInterface(ctx, iface)
+}
@@ -0,0 +1,71 @@
+package wireguard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/h44z/wg-portal/internal/domain"
+)
+
+func TestManager_RollbackPeer(t *testing.T) {
+	m, db := newIpamTestManager(t)
+	ctx := adminContext()
+
+	peer, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	peer.DisplayName = "original"
+	peer, err = m.CreatePeer(ctx, peer)
+	require.NoError(t, err)
+
+	snapshot, err := domain.PeerSnapshot(peer)
+	require.NoError(t, err)
+	db.revisions = []domain.ConfigRevision{
+		{ObjectType: domain.RevisionObjectPeer, ObjectId: string(peer.Identifier), Version: 1, Snapshot: snapshot},
+	}
+
+	changed := *peer
+	changed.DisplayName = "changed"
+	changed.AccessSchedule = &domain.AccessSchedule{Windows: []domain.AccessWindow{{Start: "08:00", End: "17:00"}}}
+	_, err = m.UpdatePeer(ctx, &changed)
+	require.NoError(t, err)
+	require.NotNil(t, db.savedPeers[peer.Identifier].AccessSchedule)
+
+	userCtx := domain.SetUserInfo(ctx, &domain.ContextUserInfo{Id: "user", IsAdmin: false})
+	_, err = m.RollbackPeer(userCtx, peer.Identifier, 1)
+	assert.ErrorIs(t, err, domain.ErrNoPermission)
+
+	_, err = m.RollbackPeer(ctx, peer.Identifier, 2)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	restored, err := m.RollbackPeer(ctx, peer.Identifier, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "original", restored.DisplayName)
+	assert.True(t, db.savedPeers[peer.Identifier].AccessSchedule.IsEmpty(), "settings added later are removed")
+	assert.Equal(t, addressesOf(peer), addressesOf(restored))
+}
+
+func TestManager_RollbackInterface_RemovesMaintenanceSchedule(t *testing.T) {
+	m, db := newIpamTestManager(t)
+	ctx := adminContext()
+
+	snapshot, err := domain.InterfaceSnapshot(db.iface)
+	require.NoError(t, err)
+	db.revisions = []domain.ConfigRevision{
+		{ObjectType: domain.RevisionObjectInterface, ObjectId: "wg0", Version: 1, Snapshot: snapshot},
+	}
+
+	changed := *db.iface
+	changed.MaintenanceSchedule = &domain.MaintenanceSchedule{Windows: []domain.MaintenanceWindow{
+		{Action: domain.MaintenanceActionDisable, Start: time.Now().Add(time.Hour), Recurrence: "daily"},
+	}}
+	_, _, err = m.UpdateInterface(ctx, &changed)
+	require.NoError(t, err)
+	require.False(t, db.iface.MaintenanceSchedule.IsEmpty())
+
+	restored, _, err := m.RollbackInterface(ctx, "wg0", 1)
+	require.NoError(t, err)
+	assert.True(t, restored.MaintenanceSchedule.IsEmpty(), "schedules added later are removed")
+}
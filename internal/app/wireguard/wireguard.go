@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/h44z/wg-portal/internal/app"
+	"github.com/h44z/wg-portal/internal/app/audit"
 	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 )
@@ -33,10 +34,25 @@ type InterfaceAndPeerDatabaseRepo interface {
 		updateFunc func(in *domain.Peer) (*domain.Peer, error),
 	) error
 	DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
+	SoftDeletePeer(ctx context.Context, id domain.PeerIdentifier) error
+	RestorePeer(ctx context.Context, id domain.PeerIdentifier) error
 	GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
-	GetUsedIpsPerSubnet(ctx context.Context, subnets []domain.Cidr) (map[domain.Cidr][]domain.Cidr, error)
+	GetDeletedPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error)
+	GetDeletedPeers(ctx context.Context) ([]domain.Peer, error)
+	GetPeerIps(ctx context.Context) (map[domain.PeerIdentifier][]domain.Cidr, error)
 	GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error)
 	GetAllUsers(ctx context.Context) ([]domain.User, error)
+	GetConfigRevisions(
+		ctx context.Context,
+		objectType domain.RevisionObjectType,
+		id string,
+	) ([]domain.ConfigRevision, error)
+	GetConfigRevision(
+		ctx context.Context,
+		objectType domain.RevisionObjectType,
+		id string,
+		version uint64,
+	) (*domain.ConfigRevision, error)
 }
 
 type WgQuickController interface {
@@ -45,6 +61,11 @@ type WgQuickController interface {
 	UnsetDNS(ctx context.Context, id domain.InterfaceIdentifier, dnsStr, dnsSearchStr string) error
 }
 
+// BandwidthLimitController is implemented by controllers that are able to enforce peer bandwidth limits.
+type BandwidthLimitController interface {
+	SupportsBandwidthLimits() bool
+}
+
 type EventBus interface {
 	// Publish sends a message to the message bus.
 	Publish(topic string, args ...any)
@@ -59,6 +80,9 @@ type Manager struct {
 	bus EventBus
 	db  InterfaceAndPeerDatabaseRepo
 	wg  *ControllerManager
+	ips *ipAllocator
+
+	prefixes *prefixAllocator
 
 	userLockMap      *sync.Map
 	interfaceLockMap *sync.Map
@@ -75,6 +99,8 @@ func NewWireGuardManager(
 		bus:              bus,
 		wg:               wg,
 		db:               db,
+		ips:              newIpAllocator(db),
+		prefixes:         newPrefixAllocator(),
 		userLockMap:      &sync.Map{},
 		interfaceLockMap: &sync.Map{},
 	}
@@ -88,6 +114,9 @@ func NewWireGuardManager(
 // This method is non-blocking.
 func (m Manager) StartBackgroundJobs(ctx context.Context) {
 	go m.runExpiredPeersCheck(ctx)
+	go m.runAccessScheduleCheck(ctx)
+	go m.runMaintenanceCheck(ctx)
+	go m.runRecycleBinPurge(ctx)
 }
 
 func (m Manager) connectToMessageBus() {
@@ -97,6 +126,36 @@ func (m Manager) connectToMessageBus() {
 	_ = m.bus.Subscribe(app.TopicUserEnabled, m.handleUserEnabledEvent)
 	_ = m.bus.Subscribe(app.TopicUserDeleted, m.handleUserDeletedEvent)
 	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.handleInterfaceCreatedEvent)
+
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.ips.handlePeerSaved)
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.ips.handlePeerSaved)
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.ips.handlePeerDeleted)
+	_ = m.bus.Subscribe(app.TopicPeerPurged, m.ips.handlePeerPurged)
+	_ = m.bus.Subscribe(app.TopicInterfaceCreated, m.ips.handleInterfaceSaved)
+	_ = m.bus.Subscribe(app.TopicInterfaceUpdated, m.ips.handleInterfaceSaved)
+	_ = m.bus.Subscribe(app.TopicInterfaceDeleted, m.ips.handleInterfaceDeleted)
+
+	_ = m.bus.Subscribe(app.TopicPeerCreated, m.prefixes.handlePeerSaved)
+	_ = m.bus.Subscribe(app.TopicPeerUpdated, m.prefixes.handlePeerSaved)
+	_ = m.bus.Subscribe(app.TopicPeerDeleted, m.prefixes.handlePeerDeleted)
+}
+
+// ipAllocator returns the shared address allocator. Managers that were not created by NewWireGuardManager
+// use a fresh allocator, which loads the used addresses from the database on each call.
+func (m Manager) ipAllocator() *ipAllocator {
+	if m.ips != nil {
+		return m.ips
+	}
+	return newIpAllocator(m.db)
+}
+
+// prefixAllocator returns the shared allocator for delegated prefixes. Like ipAllocator, managers that were not
+// created by NewWireGuardManager use a fresh allocator, which only knows the stored prefixes.
+func (m Manager) prefixAllocator() *prefixAllocator {
+	if m.prefixes != nil {
+		return m.prefixes
+	}
+	return newPrefixAllocator()
 }
 
 func (m Manager) handleUserCreationEvent(user domain.User) {
@@ -348,3 +407,79 @@ func (m Manager) checkExpiredPeers(ctx context.Context, peers []domain.Peer) {
 		}
 	}
 }
+
+func (m Manager) runAccessScheduleCheck(ctx context.Context) {
+	ctx = domain.SetUserInfo(ctx, domain.SystemAdminContextUserInfo())
+
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+			continue
+		case <-time.After(m.cfg.Advanced.ScheduleCheckInterval):
+			// select blocks until one of the cases evaluate to true
+		}
+
+		interfaces, err := m.db.GetAllInterfaces(ctx)
+		if err != nil {
+			slog.Error("failed to fetch all interfaces for access schedule check", "error", err)
+			continue
+		}
+
+		for _, iface := range interfaces {
+			peers, err := m.db.GetInterfacePeers(ctx, iface.Identifier)
+			if err != nil {
+				slog.Error("failed to fetch all peers from interface for access schedule check",
+					"interface", iface.Identifier,
+					"error", err)
+				continue
+			}
+
+			m.checkAccessSchedules(ctx, peers)
+		}
+	}
+}
+
+func (m Manager) checkAccessSchedules(ctx context.Context, peers []domain.Peer) {
+	now := time.Now()
+	owners := make(map[domain.UserIdentifier]*domain.User)
+
+	for _, peer := range peers {
+		owner, ok := owners[peer.UserIdentifier]
+		if !ok && peer.UserIdentifier != "" {
+			user, err := m.getPeerOwner(ctx, &peer)
+			if err != nil {
+				slog.Error("failed to load peer owner for access schedule check",
+					"peer", peer.Identifier, "user", peer.UserIdentifier, "error", err)
+				continue
+			}
+			owner = user
+			owners[peer.UserIdentifier] = owner
+		}
+
+		if !peer.UpdateScheduleState(peer.EffectiveAccessSchedule(owner), now) {
+			continue // nothing changed
+		}
+
+		action := "schedule-unblock"
+		if peer.IsScheduleBlocked() {
+			action = "schedule-block"
+		}
+		slog.Info("access schedule state of peer changed", "peer", peer.Identifier, "action", action)
+
+		// savePeers re-evaluates the schedule state and updates the physical peer accordingly
+		if err := m.savePeers(ctx, &peer); err != nil {
+			slog.Error("failed to apply access schedule to peer", "peer", peer.Identifier, "error", err)
+			continue
+		}
+
+		m.bus.Publish(app.TopicAuditPeerChanged, domain.AuditEventWrapper[audit.PeerEvent]{
+			Ctx: ctx,
+			Event: audit.PeerEvent{
+				Action: action,
+				Peer:   peer,
+			},
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"reflect"
 	"slices"
 	"strings"
 	"time"
@@ -164,6 +165,51 @@ func (m Manager) ImportNewInterfaces(ctx context.Context, filter ...domain.Inter
 		}
 	}
 
+	if imported > 0 {
+		m.ipAllocator().invalidate() // imported peers are stored without events
+	}
+
+	return imported, nil
+}
+
+// ImportPeers imports the given physical peers of an existing interface. Peers that already exist are skipped.
+func (m Manager) ImportPeers(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	peers ...domain.PhysicalPeer,
+) (int, error) {
+	if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+		return 0, err
+	}
+
+	iface, err := m.db.GetInterface(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("unable to load interface %s: %w", id, err)
+	}
+
+	imported := 0
+	for _, peer := range peers {
+		existingPeer, err := m.db.GetPeer(ctx, domain.PeerIdentifier(peer.PublicKey))
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return imported, err
+		}
+		if existingPeer != nil {
+			continue // skip peers that already exist
+		}
+
+		err = m.importPeer(ctx, iface, &peer)
+		if err != nil {
+			return imported, fmt.Errorf("import of peer %s failed: %w", peer.Identifier, err)
+		}
+
+		slog.Info("imported new peer", "interface", id, "peer", peer.Identifier)
+		imported++
+	}
+
+	if imported > 0 {
+		m.ipAllocator().invalidate() // imported peers are stored without events
+	}
+
 	return imported, nil
 }
 
@@ -294,6 +340,7 @@ func (m Manager) RestoreInterfaceState(
 				err := controller.SavePeer(ctx, iface.Identifier, peer.Identifier,
 					func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error) {
 						domain.MergeToPhysicalPeer(pp, &peer)
+						mergeBandwidthLimit(pp, &peer, &iface)
 						return pp, nil
 					})
 				if err != nil {
@@ -322,6 +369,9 @@ func (m Manager) RestoreInterfaceState(
 				}
 			}
 		}
+
+		// notify listeners (e.g. the firewall manager) so that their state is restored as well
+		m.bus.Publish(app.TopicInterfaceStateRestored, iface.Identifier)
 	}
 
 	return nil
@@ -427,6 +477,8 @@ func (m Manager) CreateInterface(ctx context.Context, in *domain.Interface) (*do
 		return nil, fmt.Errorf("creation not allowed: %w", err)
 	}
 
+	in.MaintenanceSchedule.CarryState(nil, time.Now())
+
 	in, err = m.saveInterface(ctx, in)
 	if err != nil {
 		return nil, fmt.Errorf("creation failure: %w", err)
@@ -448,15 +500,84 @@ func (m Manager) UpdateInterface(ctx context.Context, in *domain.Interface) (*do
 		return nil, nil, fmt.Errorf("unable to load existing interface %s: %w", in.Identifier, err)
 	}
 
+	// requests without an inactivity policy keep the existing one, an empty policy removes it
+	if in.InactivityPolicy == nil {
+		in.InactivityPolicy = existingInterface.InactivityPolicy
+	}
+	if in.PeerDefBandwidthLimit == nil {
+		in.PeerDefBandwidthLimit = existingInterface.PeerDefBandwidthLimit
+	}
+	if in.AccessPolicy == nil {
+		in.AccessPolicy = existingInterface.AccessPolicy
+	}
+	if in.OnDemandPolicy == nil {
+		in.OnDemandPolicy = existingInterface.OnDemandPolicy
+	}
+	if in.IpamPolicy == nil {
+		in.IpamPolicy = existingInterface.IpamPolicy
+	}
+	if in.PrefixDelegation == nil {
+		in.PrefixDelegation = existingInterface.PrefixDelegation
+	}
+	if in.NatPolicy == nil {
+		in.NatPolicy = existingInterface.NatPolicy
+	}
+	if in.AmneziaParams == nil {
+		in.AmneziaParams = existingInterface.AmneziaParams
+	}
+	if in.MaintenanceSchedule == nil {
+		in.MaintenanceSchedule = existingInterface.MaintenanceSchedule
+	} else {
+		in.MaintenanceSchedule.CarryState(existingInterface.MaintenanceSchedule, time.Now())
+	}
+	if domain.GetUserInfo(ctx).Id != domain.CtxSystemDeclarative {
+		in.ManagedBy = existingInterface.ManagedBy // only the state file marks or releases interfaces
+	}
+
 	if err := m.validateInterfaceModifications(ctx, existingInterface, in); err != nil {
 		return nil, nil, fmt.Errorf("update not allowed: %w", err)
 	}
 
+	renumberedPeers, err := renumberDelegatedPrefixes(existingInterface.PrefixDelegation, in.PrefixDelegation,
+		existingPeers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("update not allowed: %w", err)
+	}
+
 	in, err = m.saveInterface(ctx, in)
 	if err != nil {
 		return nil, nil, fmt.Errorf("update failure: %w", err)
 	}
 
+	if err := m.savePeers(ctx, renumberedPeers...); err != nil {
+		return nil, nil, fmt.Errorf("failed to renumber delegated prefixes: %w", err)
+	}
+
+	// peers without an own limit inherit the interface default, so they must be re-applied if the default changes
+	if !reflect.DeepEqual(existingInterface.PeerDefBandwidthLimit, in.PeerDefBandwidthLimit) && !in.IsDisabled() {
+		inheritingPeers := make([]*domain.Peer, 0, len(existingPeers))
+		for i := range existingPeers {
+			if existingPeers[i].BandwidthLimit.IsEmpty() {
+				inheritingPeers = append(inheritingPeers, &existingPeers[i])
+			}
+		}
+		if err := m.savePeers(ctx, inheritingPeers...); err != nil {
+			return nil, nil, fmt.Errorf("failed to apply default bandwidth limit: %w", err)
+		}
+	}
+
+	// the obfuscation parameters of the peers must match the interface, otherwise the handshakes fail
+	if !reflect.DeepEqual(existingInterface.AmneziaParams, in.AmneziaParams) &&
+		!(existingInterface.AmneziaParams.IsEmpty() && in.AmneziaParams.IsEmpty()) {
+		peers := make([]*domain.Peer, len(existingPeers))
+		for i := range existingPeers {
+			peers[i] = &existingPeers[i]
+		}
+		if err := m.savePeers(ctx, peers...); err != nil {
+			return nil, nil, fmt.Errorf("failed to apply amneziawg parameters: %w", err)
+		}
+	}
+
 	m.bus.Publish(app.TopicInterfaceUpdated, *in)
 
 	return in, existingPeers, nil
@@ -634,6 +755,7 @@ func (m Manager) saveInterface(ctx context.Context, iface *domain.Interface) (
 			saveErr := m.wg.GetController(*iface).SavePeer(ctx, iface.Identifier, peer.Identifier,
 				func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error) {
 					domain.MergeToPhysicalPeer(pp, &peer)
+					mergeBandwidthLimit(pp, &peer, iface)
 					return pp, nil
 				})
 			if saveErr != nil {
@@ -1032,6 +1154,7 @@ func (m Manager) importPeer(ctx context.Context, in *domain.Interface, p *domain
 	peer.Interface.PostUp = domain.NewConfigOption(in.PeerDefPostUp, true)
 	peer.Interface.PreDown = domain.NewConfigOption(in.PeerDefPreDown, true)
 	peer.Interface.PostDown = domain.NewConfigOption(in.PeerDefPostDown, true)
+	peer.Interface.AmneziaParams = in.AmneziaParams.Clone()
 
 	var displayName string
 	switch in.Type {
@@ -1075,13 +1198,23 @@ func (m Manager) deleteInterfacePeers(ctx context.Context, iface *domain.Interfa
 	return nil
 }
 
-func (m Manager) validateInterfaceModifications(ctx context.Context, _, _ *domain.Interface) error {
+func (m Manager) validateInterfaceModifications(ctx context.Context, old, new *domain.Interface) error {
 	currentUser := domain.GetUserInfo(ctx)
 
 	if !currentUser.IsAdmin {
 		return fmt.Errorf("insufficient permissions")
 	}
 
+	if m.cfg.Declarative.ProtectManaged {
+		if err := domain.ValidateManagedChange(ctx, old.ManagedBy); err != nil {
+			return err
+		}
+	}
+
+	if err := m.validateBandwidthLimit(new, new.PeerDefBandwidthLimit); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1103,16 +1236,26 @@ func (m Manager) validateInterfaceCreation(ctx context.Context, _, new *domain.I
 		}
 	}
 
+	if err := m.validateBandwidthLimit(new, new.PeerDefBandwidthLimit); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (m Manager) validateInterfaceDeletion(ctx context.Context, _ *domain.Interface) error {
+func (m Manager) validateInterfaceDeletion(ctx context.Context, del *domain.Interface) error {
 	currentUser := domain.GetUserInfo(ctx)
 
 	if !currentUser.IsAdmin {
 		return fmt.Errorf("insufficient permissions")
 	}
 
+	if m.cfg.Declarative.ProtectManaged {
+		if err := domain.ValidateManagedChange(ctx, del.ManagedBy); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
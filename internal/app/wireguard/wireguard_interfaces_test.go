@@ -5,7 +5,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/h44z/wg-portal/internal/app"
 	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
 )
@@ -252,3 +254,39 @@ func TestManager_GetUserInterfaces_Filtering(t *testing.T) {
 		}
 	})
 }
+
+func TestRestoreInterfaceState_PublishesRestoredEvent(t *testing.T) {
+	m, _ := newIpamTestManager(t)
+	bus := m.bus.(*mockBus)
+
+	assert.NoError(t, m.RestoreInterfaceState(adminContext(), false))
+
+	// config files are not rewritten on restore, so the peer interface event must not be published
+	assert.Contains(t, bus.topics, app.TopicInterfaceStateRestored)
+	assert.NotContains(t, bus.topics, app.TopicPeerInterfaceUpdated)
+}
+
+func TestManager_UpdateInterface_PropagatesAmneziaParams(t *testing.T) {
+	m, db := newIpamTestManager(t)
+	ctx := adminContext()
+
+	peer, err := m.PreparePeer(ctx, "wg0")
+	require.NoError(t, err)
+	_, err = m.CreatePeer(ctx, peer)
+	require.NoError(t, err)
+
+	update := *db.iface
+	update.AmneziaParams = &domain.AmneziaParams{Jc: 4, Jmin: 8, Jmax: 80, S1: 15, S2: 20, H1: 5, H2: 6, H3: 7, H4: 8}
+	_, _, err = m.UpdateInterface(ctx, &update)
+	require.NoError(t, err)
+
+	saved := db.savedPeers[peer.Identifier]
+	require.NotNil(t, saved.Interface.AmneziaParams)
+	assert.Equal(t, *update.AmneziaParams, *saved.Interface.AmneziaParams)
+	assert.NotSame(t, db.iface.AmneziaParams, saved.Interface.AmneziaParams)
+
+	invalid := *db.iface
+	invalid.AmneziaParams = &domain.AmneziaParams{H1: 5, H2: 5}
+	_, _, err = m.UpdateInterface(ctx, &invalid)
+	assert.ErrorIs(t, err, domain.ErrInvalidData)
+}
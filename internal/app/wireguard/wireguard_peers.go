@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/netip"
 	"slices"
 	"time"
 
@@ -123,6 +124,40 @@ func (m Manager) GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]
 
 // PreparePeer prepares a new peer for the given interface with fresh keys and ip addresses.
 func (m Manager) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
+	return m.PreparePeerFromPool(ctx, id, "")
+}
+
+// PreparePeerFromPool prepares a new peer like PreparePeer, but allocates the ip addresses from the named ip pool
+// of the interface. Only admins can select a pool. An empty pool name uses the regular allocation.
+func (m Manager) PreparePeerFromPool(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	pool string,
+) (*domain.Peer, error) {
+	return m.preparePeer(ctx, id, pool, true)
+}
+
+// PreviewPeer prepares a new peer like PreparePeer, but does not lease its ip addresses. It is used if the peer is
+// only shown or validated, or if its addresses are replaced anyway. The addresses are claimed once the peer is
+// validated for creation.
+func (m Manager) PreviewPeer(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Peer, error) {
+	return m.preparePeer(ctx, id, "", false)
+}
+
+// preparePeer prepares a new peer with fresh keys and ip addresses. If lease is true, the addresses are leased
+// until the peer is saved.
+func (m Manager) preparePeer(
+	ctx context.Context,
+	id domain.InterfaceIdentifier,
+	pool string,
+	lease bool,
+) (*domain.Peer, error) {
+	if pool != "" {
+		if err := domain.ValidateAdminAccessRights(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	if !m.cfg.Core.SelfProvisioningAllowed {
 		if err := domain.ValidateAdminAccessRights(ctx); err != nil {
 			return nil, err
@@ -144,11 +179,6 @@ func (m Manager) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier)
 		return nil, fmt.Errorf("self provisioning is only allowed for server interfaces: %w", domain.ErrNoPermission)
 	}
 
-	ips, err := m.getFreshPeerIpConfig(ctx, iface)
-	if err != nil {
-		return nil, fmt.Errorf("unable to get fresh ip addresses: %w", err)
-	}
-
 	kp, err := domain.NewFreshKeypair()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate keys: %w", err)
@@ -159,12 +189,32 @@ func (m Manager) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier)
 		return nil, fmt.Errorf("failed to generate preshared key: %w", err)
 	}
 
+	peerId := domain.PeerIdentifier(kp.PublicKey)
+	var leaseOwner ipOwner
+	if lease {
+		leaseOwner = ipOwner{Peer: peerId, Interface: iface.Identifier}
+	}
+
+	ips, err := m.getFreshPeerIpConfig(ctx, iface, pool, leaseOwner)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get fresh ip addresses: %w", err)
+	}
+
+	var delegatedPrefix string
+	if !iface.PrefixDelegation.IsEmpty() {
+		prefix, err := m.getFreshDelegatedPrefix(ctx, iface, leaseOwner)
+		if err != nil {
+			m.releaseAddresses(leaseOwner, ips)
+			return nil, fmt.Errorf("unable to get delegated prefix: %w", err)
+		}
+		delegatedPrefix = prefix.String()
+	}
+
 	peerMode := domain.InterfaceTypeClient
 	if iface.Type == domain.InterfaceTypeClient {
 		peerMode = domain.InterfaceTypeServer
 	}
 
-	peerId := domain.PeerIdentifier(kp.PublicKey)
 	freshPeer := &domain.Peer{
 		BaseModel: domain.BaseModel{
 			CreatedBy: string(currentUser.Id),
@@ -176,6 +226,7 @@ func (m Manager) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier)
 		EndpointPublicKey:   domain.NewConfigOption(iface.PublicKey, true),
 		AllowedIPsStr:       domain.NewConfigOption(iface.PeerDefAllowedIPsStr, true),
 		ExtraAllowedIPsStr:  "",
+		DelegatedPrefix:     delegatedPrefix,
 		PresharedKey:        pk,
 		PersistentKeepalive: domain.NewConfigOption(iface.PeerDefPersistentKeepalive, true),
 		Identifier:          peerId,
@@ -199,6 +250,7 @@ func (m Manager) PreparePeer(ctx context.Context, id domain.InterfaceIdentifier)
 			PostUp:            domain.NewConfigOption(iface.PeerDefPostUp, true),
 			PreDown:           domain.NewConfigOption(iface.PeerDefPreDown, true),
 			PostDown:          domain.NewConfigOption(iface.PeerDefPostDown, true),
+			AmneziaParams:     iface.AmneziaParams.Clone(),
 		},
 	}
 	freshPeer.GenerateDisplayName("")
@@ -273,10 +325,20 @@ func (m Manager) CreatePeer(ctx context.Context, peer *domain.Peer) (*domain.Pee
 
 	// if a peer is self provisioned, ensure that only allowed fields are set from the request
 	if !sessionUser.IsAdmin {
-		preparedPeer, err := m.PreparePeer(ctx, peer.InterfaceIdentifier)
+		preparedPeer, err := m.PreviewPeer(ctx, peer.InterfaceIdentifier)
 		if err != nil {
 			return nil, fmt.Errorf("failed to prepare peer for interface %s: %w", peer.InterfaceIdentifier, err)
 		}
+		// keep the addresses that were leased when the peer was prepared, so the user gets the addresses that were
+		// shown before
+		if m.isLeasedPeer(peer) {
+			preparedPeer.Interface.Addresses = peer.Interface.Addresses
+		}
+		// the submitted prefix is validated like the prefix of any other new peer, users can only use free prefixes
+		// of the delegation pool
+		if peer.DelegatedPrefix != "" {
+			preparedPeer.DelegatedPrefix = peer.DelegatedPrefix
+		}
 
 		preparedPeer.OverwriteUserEditableFields(peer, m.cfg)
 
@@ -311,7 +373,7 @@ func (m Manager) CreateMultiplePeers(
 	createdPeers := make([]domain.Peer, 0, len(r.UserIdentifiers))
 
 	for _, id := range r.UserIdentifiers {
-		freshPeer, err := m.PreparePeer(ctx, interfaceId)
+		freshPeer, err := m.PreparePeerFromPool(ctx, interfaceId, r.IpPool)
 		if err != nil {
 			return nil, fmt.Errorf("failed to prepare peer for interface %s: %w", interfaceId, err)
 		}
@@ -353,6 +415,20 @@ func (m Manager) UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Pee
 		return nil, err
 	}
 
+	// requests without an access schedule keep the existing one, an empty schedule removes it
+	if peer.AccessSchedule == nil {
+		peer.AccessSchedule = existingPeer.AccessSchedule
+	}
+	if peer.BandwidthLimit == nil {
+		peer.BandwidthLimit = existingPeer.BandwidthLimit
+	}
+	if peer.Acl == nil {
+		peer.Acl = existingPeer.Acl
+	}
+	if domain.GetUserInfo(ctx).Id != domain.CtxSystemDeclarative {
+		peer.ManagedBy = existingPeer.ManagedBy // only the state file marks or releases peers
+	}
+
 	if err := m.validatePeerModifications(ctx, existingPeer, peer); err != nil {
 		return nil, fmt.Errorf("update not allowed: %w", err)
 	}
@@ -370,6 +446,13 @@ func (m Manager) UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Pee
 		peer = originalPeer
 	}
 
+	// re-enabled peers get a fresh grace period before the inactivity policy applies again
+	if existingPeer.IsDisabled() && !peer.IsDisabled() {
+		peer.InactivityWarned = nil
+	} else {
+		peer.InactivityWarned = existingPeer.InactivityWarned
+	}
+
 	// handle peer identifier change (new public key)
 	if existingPeer.Identifier != domain.PeerIdentifier(peer.Interface.PublicKey) {
 		peer.Identifier = domain.PeerIdentifier(peer.Interface.PublicKey) // set new identifier
@@ -383,8 +466,8 @@ func (m Manager) UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Pee
 			return nil, fmt.Errorf("peer %s already exists: %w", peer.Identifier, domain.ErrDuplicateEntry)
 		}
 
-		// delete old peer
-		err = m.DeletePeer(ctx, existingPeer.Identifier)
+		// delete old peer, it is replaced by the new one and must not end up in the recycle bin
+		err = m.deletePeer(ctx, existingPeer.Identifier, true)
 		if err != nil {
 			return nil, fmt.Errorf("failed to delete old peer %s for %s: %w",
 				existingPeer.Identifier, peer.Identifier, err)
@@ -411,8 +494,13 @@ func (m Manager) UpdatePeer(ctx context.Context, peer *domain.Peer) (*domain.Pee
 	return peer, nil
 }
 
-// DeletePeer deletes the peer with the given identifier.
+// DeletePeer deletes the peer with the given identifier. If a deletion retention is configured, the peer is only
+// removed from the WireGuard backend and kept in the recycle bin until the retention period is over.
 func (m Manager) DeletePeer(ctx context.Context, id domain.PeerIdentifier) error {
+	return m.deletePeer(ctx, id, m.cfg.Advanced.DeletionRetention <= 0)
+}
+
+func (m Manager) deletePeer(ctx context.Context, id domain.PeerIdentifier, permanently bool) error {
 	peer, err := m.db.GetPeer(ctx, id)
 	if err != nil {
 		return fmt.Errorf("unable to find peer %s: %w", id, err)
@@ -440,7 +528,14 @@ func (m Manager) DeletePeer(ctx context.Context, id domain.PeerIdentifier) error
 		return fmt.Errorf("wireguard failed to delete peer %s: %w", id, err)
 	}
 
-	err = m.db.DeletePeer(ctx, id)
+	if permanently {
+		err = m.db.DeletePeer(ctx, id)
+	} else {
+		err = m.db.SoftDeletePeer(ctx, id)
+		if err == nil {
+			peer, err = m.db.GetDeletedPeer(ctx, id) // reload to publish the deletion time
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete peer %s: %w", id, err)
 	}
@@ -520,14 +615,25 @@ func (m Manager) savePeers(ctx context.Context, peers ...*domain.Peer) error {
 
 		iface := interfaces[peer.InterfaceIdentifier]
 
+		owner, err := m.getPeerOwner(ctx, peer)
+		if err != nil {
+			return fmt.Errorf("unable to load owner %s of peer %s: %w", peer.UserIdentifier, peer.Identifier, err)
+		}
+
 		// Always save the peer to the backend, regardless of disabled/expired state
 		// The backend will handle the disabled state appropriately
-		err := m.db.SavePeer(ctx, peer.Identifier, func(p *domain.Peer) (*domain.Peer, error) {
+		// both sides of a connection need the same obfuscation parameters, so they always follow the interface
+		peer.Interface.AmneziaParams = iface.AmneziaParams.Clone()
+
+		err = m.db.SavePeer(ctx, peer.Identifier, func(p *domain.Peer) (*domain.Peer, error) {
 			peer.CopyCalculatedAttributes(p)
+			// re-evaluate the schedule so that changed access schedules take effect immediately
+			peer.UpdateScheduleState(peer.EffectiveAccessSchedule(owner), time.Now())
 
 			err := m.wg.GetController(iface).SavePeer(ctx, peer.InterfaceIdentifier, peer.Identifier,
 				func(pp *domain.PhysicalPeer) (*domain.PhysicalPeer, error) {
 					domain.MergeToPhysicalPeer(pp, peer)
+					mergeBandwidthLimit(pp, peer, &iface)
 					return pp, nil
 				})
 			if err != nil {
@@ -574,59 +680,119 @@ func (m Manager) savePeers(ctx context.Context, peers ...*domain.Peer) error {
 	return nil
 }
 
-func (m Manager) getFreshPeerIpConfig(ctx context.Context, iface *domain.Interface) (ips []domain.Cidr, err error) {
+// getFreshPeerIpConfig returns the lowest free address of every peer network of the interface. Reserved addresses
+// are skipped. Addresses of ip pools are only used if the pool is selected, networks that are not covered by the
+// selected pool fall back to the regular allocation. The returned addresses are leased for the given prepared peer
+// until it is saved, so that concurrent calls never return the same address. A zero lease owner skips the lease.
+func (m Manager) getFreshPeerIpConfig(
+	ctx context.Context,
+	iface *domain.Interface,
+	pool string,
+	lease ipOwner,
+) (
+	ips []domain.Cidr,
+	err error,
+) {
 	if iface.PeerDefNetworkStr == "" {
+		if pool != "" {
+			return nil, fmt.Errorf("ip pool %s can not be used without peer network: %w", pool, domain.ErrInvalidData)
+		}
 		return []domain.Cidr{}, nil // cannot suggest new ip addresses if there is no subnet
 	}
 
-	networks, err := domain.CidrsFromString(iface.PeerDefNetworkStr)
-	if err != nil {
-		err = fmt.Errorf("failed to parse default network address: %w", err)
-		return
+	var poolRanges []domain.IpRange
+	if pool != "" {
+		ipPool, err := iface.IpamPolicy.FindPool(pool)
+		if err != nil {
+			return nil, err
+		}
+		poolRanges = ipPool.IpRanges()
 	}
 
-	existingIps, err := m.db.GetUsedIpsPerSubnet(ctx, networks)
+	networks, err := domain.CidrsFromString(iface.PeerDefNetworkStr)
 	if err != nil {
-		err = fmt.Errorf("failed to get existing IP addresses: %w", err)
+		err = fmt.Errorf("failed to parse default network address: %w", err)
 		return
 	}
 
+	allocator := m.ipAllocator()
+	reserved := iface.IpamPolicy.ReservedRanges()
+	allPoolRanges := iface.IpamPolicy.PoolRanges()
 	for _, network := range networks {
-		ip := network.NextAddr()
-
-		for {
-			ipConflict := false
-			for _, usedIp := range existingIps[network] {
-				if usedIp.Addr == ip.Addr {
-					ipConflict = true
-					break
-				}
-			}
+		netRange := domain.IpRangeFromPrefix(network.Prefix())
+		blocked := append(domain.UnassignableIpRanges(network), reserved...)
 
-			if !ipConflict {
-				break
+		var candidates []domain.IpRange
+		for _, r := range poolRanges {
+			if inNetwork, ok := netRange.Intersect(r); ok {
+				candidates = append(candidates, inNetwork)
 			}
+		}
+		fromPool := len(candidates) > 0
+		if !fromPool {
+			candidates = []domain.IpRange{netRange}
+			blocked = append(blocked, allPoolRanges...) // pool addresses are kept for explicit selection
+		}
 
-			ip = ip.NextAddr()
-
-			if !ip.IsValid() {
-				return nil, fmt.Errorf("ip space on subnet %s is exhausted", network.String())
-			}
+		addr, ok, allocErr := allocator.allocate(ctx, lease, candidates, blocked)
+		switch {
+		case allocErr != nil:
+			err = fmt.Errorf("failed to get existing IP addresses: %w", allocErr)
+		case !ok && fromPool:
+			err = fmt.Errorf("ip pool %s is exhausted on subnet %s", pool, network.String())
+		case !ok:
+			err = fmt.Errorf("ip space on subnet %s is exhausted", network.String())
+		}
+		if err != nil {
+			m.releaseAddresses(lease, ips) // do not block the addresses of the other subnets
+			return nil, err
 		}
 
-		ips = append(ips, ip.HostAddr())
+		ips = append(ips, domain.CidrFromPrefix(netip.PrefixFrom(addr, addr.BitLen())))
 	}
 
 	return
 }
 
-func (m Manager) validatePeerModifications(ctx context.Context, _, _ *domain.Peer) error {
+func (m Manager) validatePeerModifications(ctx context.Context, old, new *domain.Peer) error {
 	currentUser := domain.GetUserInfo(ctx)
 
 	if !currentUser.IsAdmin && !m.cfg.Core.SelfProvisioningAllowed {
 		return domain.ErrNoPermission
 	}
 
+	if m.cfg.Declarative.ProtectManaged {
+		if err := domain.ValidateManagedChange(ctx, old.ManagedBy); err != nil {
+			return err
+		}
+	}
+
+	if err := new.AccessSchedule.Validate(); err != nil {
+		return fmt.Errorf("invalid access schedule: %w", err)
+	}
+
+	if err := new.Acl.Validate(); err != nil {
+		return fmt.Errorf("invalid access control list: %w", err)
+	}
+
+	if !new.BandwidthLimit.IsEmpty() {
+		iface, err := m.db.GetInterface(ctx, new.InterfaceIdentifier)
+		if err != nil {
+			return fmt.Errorf("invalid interface: %w", domain.ErrInvalidData)
+		}
+		if err := m.validateBandwidthLimit(iface, new.BandwidthLimit); err != nil {
+			return err
+		}
+	}
+
+	if err := m.validatePeerAddresses(ctx, old, new); err != nil {
+		return err
+	}
+
+	if err := m.validateDelegatedPrefix(ctx, old, new); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -641,21 +807,103 @@ func (m Manager) validatePeerCreation(ctx context.Context, _, new *domain.Peer)
 		return domain.ErrNoPermission
 	}
 
-	_, err := m.db.GetInterface(ctx, new.InterfaceIdentifier)
+	iface, err := m.db.GetInterface(ctx, new.InterfaceIdentifier)
 	if err != nil {
 		return fmt.Errorf("invalid interface: %w", domain.ErrInvalidData)
 	}
 
+	if !currentUser.IsAdmin && !new.AccessSchedule.IsEmpty() {
+		return fmt.Errorf("access schedule can only be set by admins: %w", domain.ErrNoPermission)
+	}
+
+	if err := new.AccessSchedule.Validate(); err != nil {
+		return fmt.Errorf("invalid access schedule: %w", err)
+	}
+
+	if !currentUser.IsAdmin && !new.Acl.IsEmpty() {
+		return fmt.Errorf("access control list can only be set by admins: %w", domain.ErrNoPermission)
+	}
+
+	if err := new.Acl.Validate(); err != nil {
+		return fmt.Errorf("invalid access control list: %w", err)
+	}
+
+	if !currentUser.IsAdmin && !new.BandwidthLimit.IsEmpty() {
+		return fmt.Errorf("bandwidth limit can only be set by admins: %w", domain.ErrNoPermission)
+	}
+
+	if err := m.validateBandwidthLimit(iface, new.BandwidthLimit); err != nil {
+		return err
+	}
+
+	if err := m.validatePeerAddresses(ctx, nil, new); err != nil {
+		return err
+	}
+
+	if err := m.validateDelegatedPrefix(ctx, nil, new); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (m Manager) validatePeerDeletion(ctx context.Context, _ *domain.Peer) error {
+// getPeerOwner returns the owner of the peer. Peers loaded from the database already carry their owner,
+// so the database is only queried for peers that were not loaded with it. Missing owners are returned as nil.
+func (m Manager) getPeerOwner(ctx context.Context, peer *domain.Peer) (*domain.User, error) {
+	if peer.UserIdentifier == "" {
+		return nil, nil
+	}
+	if peer.User != nil && peer.User.Identifier == peer.UserIdentifier {
+		return peer.User, nil
+	}
+
+	user, err := m.db.GetUser(ctx, peer.UserIdentifier)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, nil
+	}
+
+	return user, nil
+}
+
+// validateBandwidthLimit checks the given limit and ensures that the backend of the interface is able to enforce it.
+func (m Manager) validateBandwidthLimit(iface *domain.Interface, limit *domain.BandwidthLimit) error {
+	if err := limit.Validate(); err != nil {
+		return fmt.Errorf("invalid bandwidth limit: %w", err)
+	}
+
+	if !limit.IsEmpty() && !m.wg.SupportsBandwidthLimits(iface.Backend) {
+		return fmt.Errorf("backend %s of interface %s cannot enforce bandwidth limits: %w",
+			iface.Backend, iface.Identifier, domain.ErrInvalidData)
+	}
+
+	return nil
+}
+
+// mergeBandwidthLimit applies the effective bandwidth limit of the peer to the physical peer.
+// Limits that cannot be enforced by the backend are logged, as the peer itself is still usable.
+func mergeBandwidthLimit(pp *domain.PhysicalPeer, peer *domain.Peer, iface *domain.Interface) {
+	if !pp.SetBandwidthLimit(peer.EffectiveBandwidthLimit(iface)) {
+		slog.Warn("backend cannot enforce bandwidth limits, limit is not applied",
+			"peer", peer.Identifier, "interface", iface.Identifier, "backend", iface.Backend)
+	}
+}
+
+func (m Manager) validatePeerDeletion(ctx context.Context, del *domain.Peer) error {
 	currentUser := domain.GetUserInfo(ctx)
 
 	if !currentUser.IsAdmin && !m.cfg.Core.SelfProvisioningAllowed {
 		return domain.ErrNoPermission
 	}
 
+	if m.cfg.Declarative.ProtectManaged {
+		if err := domain.ValidateManagedChange(ctx, del.ManagedBy); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -712,4 +960,26 @@ func (m Manager) prepareDefaultPeer(ctx context.Context, iface *domain.Interface
 	return peer, nil
 }
 
+// isLeasedPeer returns true if all addresses of the peer were leased for it when it was prepared for its interface.
+func (m Manager) isLeasedPeer(peer *domain.Peer) bool {
+	addrs := make([]netip.Addr, len(peer.Interface.Addresses))
+	for i, cidr := range peer.Interface.Addresses {
+		addrs[i] = cidr.Prefix().Addr().Unmap()
+	}
+	return m.ipAllocator().isLeased(ipOwner{Peer: peer.Identifier, Interface: peer.InterfaceIdentifier}, addrs)
+}
+
+// releaseAddresses releases the addresses that were leased for a prepared peer that is not used.
+func (m Manager) releaseAddresses(lease ipOwner, cidrs []domain.Cidr) {
+	if lease == (ipOwner{}) {
+		return
+	}
+
+	addrs := make([]netip.Addr, len(cidrs))
+	for i, cidr := range cidrs {
+		addrs[i] = cidr.Prefix().Addr().Unmap()
+	}
+	m.ipAllocator().release(lease, addrs...)
+}
+
 // endregion helper-functions
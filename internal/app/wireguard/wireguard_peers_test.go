@@ -2,7 +2,11 @@ package wireguard
 
 import (
 	"context"
+	"slices"
 	"testing"
+	"time"
+
+	"gorm.io/gorm"
 
 	"github.com/h44z/wg-portal/internal/config"
 	"github.com/h44z/wg-portal/internal/domain"
@@ -10,9 +14,11 @@ import (
 
 // --- Test mocks ---
 
-type mockBus struct{}
+type mockBus struct {
+	topics []string
+}
 
-func (f *mockBus) Publish(topic string, args ...any)            {}
+func (f *mockBus) Publish(topic string, args ...any)            { f.topics = append(f.topics, topic) }
 func (f *mockBus) Subscribe(topic string, fn interface{}) error { return nil }
 
 type mockController struct{}
@@ -58,16 +64,27 @@ func (f *mockController) PingAddresses(_ context.Context, _ string) (*domain.Pin
 }
 
 type mockDB struct {
-	savedPeers map[domain.PeerIdentifier]*domain.Peer
-	iface      *domain.Interface
-	interfaces []domain.Interface
-	users      []domain.User
+	savedPeers   map[domain.PeerIdentifier]*domain.Peer
+	deletedPeers map[domain.PeerIdentifier]*domain.Peer
+	iface        *domain.Interface
+	interfaces   []domain.Interface
+	users        []domain.User
+	userLoads    int
+	revisions    []domain.ConfigRevision
 }
 
 func (f *mockDB) GetInterface(ctx context.Context, id domain.InterfaceIdentifier) (*domain.Interface, error) {
 	if f.iface != nil && f.iface.Identifier == id {
 		return f.iface, nil
 	}
+	for i := range f.interfaces {
+		if f.interfaces[i].Identifier == id {
+			return &f.interfaces[i], nil
+		}
+	}
+	if f.interfaces != nil {
+		return nil, domain.ErrNotFound
+	}
 	return &domain.Interface{Identifier: id}, nil
 }
 func (f *mockDB) GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceIdentifier) (
@@ -75,7 +92,7 @@ func (f *mockDB) GetInterfaceAndPeers(ctx context.Context, id domain.InterfaceId
 	[]domain.Peer,
 	error,
 ) {
-	return f.iface, nil, nil
+	return f.iface, f.interfacePeers(id), nil
 }
 func (f *mockDB) GetPeersStats(ctx context.Context, ids ...domain.PeerIdentifier) ([]domain.PeerStatus, error) {
 	return nil, nil
@@ -97,6 +114,20 @@ func (f *mockDB) SaveInterface(
 	id domain.InterfaceIdentifier,
 	updateFunc func(in *domain.Interface) (*domain.Interface, error),
 ) error {
+	if f.interfaces != nil { // multiple interfaces, the saved interface becomes the current one
+		existing, err := f.GetInterface(ctx, id)
+		if err != nil {
+			existing = &domain.Interface{Identifier: id}
+		}
+		updated, err := updateFunc(existing)
+		if err != nil {
+			return err
+		}
+		f.interfaces = slices.DeleteFunc(f.interfaces, func(i domain.Interface) bool { return i.Identifier == id })
+		f.interfaces = append(f.interfaces, *updated)
+		f.iface = updated
+		return nil
+	}
 	if f.iface == nil {
 		f.iface = &domain.Interface{Identifier: id}
 	}
@@ -108,7 +139,16 @@ func (f *mockDB) DeleteInterface(ctx context.Context, id domain.InterfaceIdentif
 	return nil
 }
 func (f *mockDB) GetInterfacePeers(ctx context.Context, id domain.InterfaceIdentifier) ([]domain.Peer, error) {
-	return nil, nil
+	return f.interfacePeers(id), nil
+}
+func (f *mockDB) interfacePeers(id domain.InterfaceIdentifier) []domain.Peer {
+	var peers []domain.Peer
+	for _, peer := range f.savedPeers {
+		if peer.InterfaceIdentifier == id {
+			peers = append(peers, *peer)
+		}
+	}
+	return peers
 }
 func (f *mockDB) GetUserPeers(ctx context.Context, id domain.UserIdentifier) ([]domain.Peer, error) {
 	return nil, nil
@@ -132,17 +172,64 @@ func (f *mockDB) SavePeer(
 	f.savedPeers[updated.Identifier] = updated
 	return nil
 }
-func (f *mockDB) DeletePeer(ctx context.Context, id domain.PeerIdentifier) error { return nil }
+func (f *mockDB) DeletePeer(ctx context.Context, id domain.PeerIdentifier) error {
+	delete(f.deletedPeers, id)
+	return nil
+}
+func (f *mockDB) SoftDeletePeer(ctx context.Context, id domain.PeerIdentifier) error {
+	peer, ok := f.savedPeers[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if f.deletedPeers == nil {
+		f.deletedPeers = make(map[domain.PeerIdentifier]*domain.Peer)
+	}
+	peer.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	f.deletedPeers[id] = peer
+	delete(f.savedPeers, id)
+	return nil
+}
+func (f *mockDB) RestorePeer(ctx context.Context, id domain.PeerIdentifier) error {
+	peer, ok := f.deletedPeers[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	peer.DeletedAt = gorm.DeletedAt{}
+	f.savedPeers[id] = peer
+	delete(f.deletedPeers, id)
+	return nil
+}
 func (f *mockDB) GetPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	if peer, ok := f.savedPeers[id]; ok {
+		return peer, nil
+	}
 	return nil, domain.ErrNotFound
 }
-func (f *mockDB) GetUsedIpsPerSubnet(ctx context.Context, subnets []domain.Cidr) (
-	map[domain.Cidr][]domain.Cidr,
-	error,
-) {
-	return map[domain.Cidr][]domain.Cidr{}, nil
+func (f *mockDB) GetDeletedPeer(ctx context.Context, id domain.PeerIdentifier) (*domain.Peer, error) {
+	if peer, ok := f.deletedPeers[id]; ok {
+		return peer, nil
+	}
+	return nil, domain.ErrNotFound
+}
+func (f *mockDB) GetDeletedPeers(ctx context.Context) ([]domain.Peer, error) {
+	var peers []domain.Peer
+	for _, peer := range f.deletedPeers {
+		peers = append(peers, *peer)
+	}
+	return peers, nil
+}
+func (f *mockDB) GetPeerIps(ctx context.Context) (map[domain.PeerIdentifier][]domain.Cidr, error) {
+	result := map[domain.PeerIdentifier][]domain.Cidr{}
+	for id, peer := range f.savedPeers {
+		result[id] = peer.Interface.Addresses
+	}
+	for id, peer := range f.deletedPeers {
+		result[id] = peer.Interface.Addresses
+	}
+	return result, nil
 }
 func (f *mockDB) GetUser(ctx context.Context, id domain.UserIdentifier) (*domain.User, error) {
+	f.userLoads++
 	return &domain.User{
 		Identifier: id,
 		IsAdmin:    false,
@@ -151,6 +238,33 @@ func (f *mockDB) GetUser(ctx context.Context, id domain.UserIdentifier) (*domain
 func (f *mockDB) GetAllUsers(ctx context.Context) ([]domain.User, error) {
 	return f.users, nil
 }
+func (f *mockDB) GetConfigRevisions(
+	ctx context.Context,
+	objectType domain.RevisionObjectType,
+	id string,
+) ([]domain.ConfigRevision, error) {
+	var revisions []domain.ConfigRevision
+	for i := len(f.revisions) - 1; i >= 0; i-- {
+		if f.revisions[i].ObjectType == objectType && f.revisions[i].ObjectId == id {
+			revisions = append(revisions, f.revisions[i])
+		}
+	}
+	return revisions, nil
+}
+func (f *mockDB) GetConfigRevision(
+	ctx context.Context,
+	objectType domain.RevisionObjectType,
+	id string,
+	version uint64,
+) (*domain.ConfigRevision, error) {
+	for i := range f.revisions {
+		r := f.revisions[i]
+		if r.ObjectType == objectType && r.ObjectId == id && r.Version == version {
+			return &r, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
 
 // --- Test ---
 
@@ -266,3 +380,26 @@ func TestCreateDefaultPeer_RespectsInterfaceFlag(t *testing.T) {
 		t.Fatalf("expected 1 peer to be created because interface flag is true, but got %d", len(db.savedPeers))
 	}
 }
+
+func TestGetPeerOwner_UsesLoadedUser(t *testing.T) {
+	db := &mockDB{}
+	m := Manager{db: db}
+	ctx := context.Background()
+
+	owner := &domain.User{Identifier: "alice"}
+	user, err := m.getPeerOwner(ctx, &domain.Peer{UserIdentifier: "alice", User: owner})
+	if err != nil || user != owner || db.userLoads != 0 {
+		t.Fatalf("expected the loaded owner without database access, got %v, %v, %d loads", user, err, db.userLoads)
+	}
+
+	// peers that were not loaded from the database fall back to a lookup
+	user, err = m.getPeerOwner(ctx, &domain.Peer{UserIdentifier: "bob"})
+	if err != nil || user == nil || user.Identifier != "bob" || db.userLoads != 1 {
+		t.Fatalf("expected owner bob from the database, got %v, %v, %d loads", user, err, db.userLoads)
+	}
+
+	user, _ = m.getPeerOwner(ctx, &domain.Peer{})
+	if user != nil || db.userLoads != 1 {
+		t.Fatalf("expected no owner for peers without user")
+	}
+}
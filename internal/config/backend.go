@@ -16,6 +16,7 @@ type Backend struct {
 
 	IgnoredLocalInterfaces []string `yaml:"ignored_local_interfaces"` // A list of interface names that should be ignored by this backend (e.g., "wg0")
 	LocalResolvconfPrefix  string   `yaml:"local_resolvconf_prefix"`  // The prefix to use for interface names when passing them to resolvconf.
+	LocalAmneziaWG         bool     `yaml:"local_amneziawg"`          // Manage local interfaces with the AmneziaWG kernel module instead of WireGuard.
 
 	// External Backend-specific configuration
 
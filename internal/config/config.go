@@ -30,6 +30,7 @@ type Config struct {
 		ReEnablePeerAfterUserEnable          bool `yaml:"re_enable_peer_after_user_enable"`
 		DeletePeerAfterUserDeleted           bool `yaml:"delete_peer_after_user_deleted"`
 		SelfProvisioningAllowed              bool `yaml:"self_provisioning_allowed"`
+		PeerRequestsAllowed                  bool `yaml:"peer_requests_allowed"` // users can request peers that are created after admin approval
 		ImportExisting                       bool `yaml:"import_existing"`
 		RestoreState                         bool `yaml:"restore_state"`
 	} `yaml:"core"`
@@ -44,10 +45,22 @@ type Config struct {
 		UseIpV6                  bool          `yaml:"use_ip_v6"`
 		ConfigStoragePath        string        `yaml:"config_storage_path"` // keep empty to disable config export to file
 		ExpiryCheckInterval      time.Duration `yaml:"expiry_check_interval"`
+		ScheduleCheckInterval    time.Duration `yaml:"schedule_check_interval"`
+		InactivityCheckInterval  time.Duration `yaml:"inactivity_check_interval"`
+		MaintenanceCheckInterval time.Duration `yaml:"maintenance_check_interval"`
+		MaintenanceNotifyBefore  time.Duration `yaml:"maintenance_notify_before"`  // 0 disables the announcement of maintenance windows
+		DriftCheckInterval       time.Duration `yaml:"drift_check_interval"`       // 0 disables the periodic drift check
+		DriftAutoHeal            bool          `yaml:"drift_auto_heal"`            // re-apply the database state to interfaces with drift
+		DriftImportUnknownPeers  bool          `yaml:"drift_import_unknown_peers"` // import unknown peers instead of removing them on auto-heal
+		MeshRemoveUnknownPeers   bool          `yaml:"mesh_remove_unknown_peers"`  // remove all peers of mesh nodes that are neither stored nor generated
+		DeletionRetention        time.Duration `yaml:"deletion_retention"`         // keep deleted peers and users in the recycle bin, 0 deletes them right away
+		ConfigRevisionLimit      int           `yaml:"config_revision_limit"`      // number of revisions kept per peer and interface, 0 keeps all
 		RulePrioOffset           int           `yaml:"rule_prio_offset"`
 		RouteTableOffset         int           `yaml:"route_table_offset"`
 		ApiAdminOnly             bool          `yaml:"api_admin_only"` // if true, only admin users can access the API
 		LimitAdditionalUserPeers int           `yaml:"limit_additional_user_peers"`
+		MobileConfigSigningCert  string        `yaml:"mobileconfig_signing_cert"` // PEM certificate (chain) used to sign Apple profiles, keep empty to disable signing
+		MobileConfigSigningKey   string        `yaml:"mobileconfig_signing_key"`  // PEM private key of the signing certificate
 	} `yaml:"advanced"`
 
 	Backend Backend `yaml:"backend"`
@@ -73,6 +86,12 @@ type Config struct {
 	Web WebConfig `yaml:"web"`
 
 	Webhook WebhookConfig `yaml:"webhook"`
+
+	Dns DnsConfig `yaml:"dns"`
+
+	DnsUpdate DnsUpdateConfig `yaml:"dns_update"`
+
+	Declarative DeclarativeConfig `yaml:"declarative"`
 }
 
 // LogStartupValues logs the startup values of the configuration in debug level
@@ -85,6 +104,7 @@ func (c *Config) LogStartupValues() {
 		"reEnablePeerAfterUserEnable", c.Core.ReEnablePeerAfterUserEnable,
 		"deletePeerAfterUserDeleted", c.Core.DeletePeerAfterUserDeleted,
 		"selfProvisioningAllowed", c.Core.SelfProvisioningAllowed,
+		"peerRequestsAllowed", c.Core.PeerRequestsAllowed,
 		"limitAdditionalUserPeers", c.Advanced.LimitAdditionalUserPeers,
 		"importExisting", c.Core.ImportExisting,
 		"restoreState", c.Core.RestoreState,
@@ -141,6 +161,7 @@ func defaultConfig() *Config {
 		false)
 	cfg.Core.EditableKeys = getEnvBool("WG_PORTAL_CORE_EDITABLE_KEYS", true)
 	cfg.Core.SelfProvisioningAllowed = getEnvBool("WG_PORTAL_CORE_SELF_PROVISIONING_ALLOWED", false)
+	cfg.Core.PeerRequestsAllowed = getEnvBool("WG_PORTAL_CORE_PEER_REQUESTS_ALLOWED", false)
 	cfg.Core.ReEnablePeerAfterUserEnable = getEnvBool("WG_PORTAL_CORE_RE_ENABLE_PEER_AFTER_USER_ENABLE", true)
 	cfg.Core.DeletePeerAfterUserDeleted = getEnvBool("WG_PORTAL_CORE_DELETE_PEER_AFTER_USER_DELETED", false)
 
@@ -159,6 +180,7 @@ func defaultConfig() *Config {
 		// Most resolconf implementations use "tun." as a prefix for interface names.
 		// But systemd's implementation uses no prefix, for example.
 		LocalResolvconfPrefix: getEnvStr("WG_PORTAL_BACKEND_LOCAL_RESOLVCONF_PREFIX", "tun."),
+		LocalAmneziaWG:        getEnvBool("WG_PORTAL_BACKEND_LOCAL_AMNEZIAWG", false),
 	}
 
 	cfg.Web = WebConfig{
@@ -186,10 +208,25 @@ func defaultConfig() *Config {
 	cfg.Advanced.UseIpV6 = getEnvBool("WG_PORTAL_ADVANCED_USE_IP_V6", true)
 	cfg.Advanced.ConfigStoragePath = getEnvStr("WG_PORTAL_ADVANCED_CONFIG_STORAGE_PATH", "")
 	cfg.Advanced.ExpiryCheckInterval = getEnvDuration("WG_PORTAL_ADVANCED_EXPIRY_CHECK_INTERVAL", 15*time.Minute)
+	cfg.Advanced.ScheduleCheckInterval = getEnvDuration("WG_PORTAL_ADVANCED_SCHEDULE_CHECK_INTERVAL", 1*time.Minute)
+	cfg.Advanced.InactivityCheckInterval = getEnvDuration("WG_PORTAL_ADVANCED_INACTIVITY_CHECK_INTERVAL",
+		1*time.Hour)
+	cfg.Advanced.MaintenanceCheckInterval = getEnvDuration("WG_PORTAL_ADVANCED_MAINTENANCE_CHECK_INTERVAL",
+		1*time.Minute)
+	cfg.Advanced.MaintenanceNotifyBefore = getEnvDuration("WG_PORTAL_ADVANCED_MAINTENANCE_NOTIFY_BEFORE",
+		24*time.Hour)
+	cfg.Advanced.DriftCheckInterval = getEnvDuration("WG_PORTAL_ADVANCED_DRIFT_CHECK_INTERVAL", 15*time.Minute)
+	cfg.Advanced.DriftAutoHeal = getEnvBool("WG_PORTAL_ADVANCED_DRIFT_AUTO_HEAL", false)
+	cfg.Advanced.DriftImportUnknownPeers = getEnvBool("WG_PORTAL_ADVANCED_DRIFT_IMPORT_UNKNOWN_PEERS", false)
+	cfg.Advanced.MeshRemoveUnknownPeers = getEnvBool("WG_PORTAL_ADVANCED_MESH_REMOVE_UNKNOWN_PEERS", false)
+	cfg.Advanced.DeletionRetention = getEnvDuration("WG_PORTAL_ADVANCED_DELETION_RETENTION", 0)
+	cfg.Advanced.ConfigRevisionLimit = getEnvInt("WG_PORTAL_ADVANCED_CONFIG_REVISION_LIMIT", 100)
 	cfg.Advanced.RulePrioOffset = getEnvInt("WG_PORTAL_ADVANCED_RULE_PRIO_OFFSET", 20000)
 	cfg.Advanced.RouteTableOffset = getEnvInt("WG_PORTAL_ADVANCED_ROUTE_TABLE_OFFSET", 20000)
 	cfg.Advanced.ApiAdminOnly = getEnvBool("WG_PORTAL_ADVANCED_API_ADMIN_ONLY", true)
 	cfg.Advanced.LimitAdditionalUserPeers = getEnvInt("WG_PORTAL_ADVANCED_LIMIT_ADDITIONAL_USER_PEERS", 0)
+	cfg.Advanced.MobileConfigSigningCert = getEnvStr("WG_PORTAL_ADVANCED_MOBILECONFIG_SIGNING_CERT", "")
+	cfg.Advanced.MobileConfigSigningKey = getEnvStr("WG_PORTAL_ADVANCED_MOBILECONFIG_SIGNING_KEY", "")
 
 	cfg.Statistics.UsePingChecks = getEnvBool("WG_PORTAL_STATISTICS_USE_PING_CHECKS", true)
 	cfg.Statistics.PingCheckWorkers = getEnvInt("WG_PORTAL_STATISTICS_PING_CHECK_WORKERS", 10)
@@ -220,6 +257,29 @@ func defaultConfig() *Config {
 	cfg.Webhook.Authentication = getEnvStr("WG_PORTAL_WEBHOOK_AUTHENTICATION", "")
 	cfg.Webhook.Timeout = getEnvDuration("WG_PORTAL_WEBHOOK_TIMEOUT", 10*time.Second)
 
+	cfg.Dns.ListeningAddress = getEnvStr("WG_PORTAL_DNS_LISTENING_ADDRESS", "") // no DNS server by default
+	cfg.Dns.Zone = getEnvStr("WG_PORTAL_DNS_ZONE", "vpn.internal")
+	cfg.Dns.Upstream = getEnvStrSlice("WG_PORTAL_DNS_UPSTREAM", nil)
+	cfg.Dns.Ttl = getEnvDuration("WG_PORTAL_DNS_TTL", 60*time.Second)
+
+	cfg.DnsUpdate.Server = getEnvStr("WG_PORTAL_DNS_UPDATE_SERVER", "") // no dynamic updates by default
+	cfg.DnsUpdate.Zone = getEnvStr("WG_PORTAL_DNS_UPDATE_ZONE", "")
+	cfg.DnsUpdate.ReverseZones = getEnvStrSlice("WG_PORTAL_DNS_UPDATE_REVERSE_ZONES", nil)
+	cfg.DnsUpdate.NameTemplate = getEnvStr("WG_PORTAL_DNS_UPDATE_NAME_TEMPLATE",
+		"{{.Peer}}{{if .User}}.{{.User}}{{end}}.{{.Interface}}")
+	cfg.DnsUpdate.Ttl = getEnvDuration("WG_PORTAL_DNS_UPDATE_TTL", 5*time.Minute)
+	cfg.DnsUpdate.Owner = getEnvStr("WG_PORTAL_DNS_UPDATE_OWNER", "wg-portal")
+	cfg.DnsUpdate.TsigKeyName = getEnvStr("WG_PORTAL_DNS_UPDATE_TSIG_KEY_NAME", "")
+	cfg.DnsUpdate.TsigSecret = getEnvStr("WG_PORTAL_DNS_UPDATE_TSIG_SECRET", "")
+	cfg.DnsUpdate.TsigAlgorithm = getEnvStr("WG_PORTAL_DNS_UPDATE_TSIG_ALGORITHM", "hmac-sha256")
+	cfg.DnsUpdate.ReconcileInterval = getEnvDuration("WG_PORTAL_DNS_UPDATE_RECONCILE_INTERVAL", 1*time.Hour)
+	cfg.DnsUpdate.Timeout = getEnvDuration("WG_PORTAL_DNS_UPDATE_TIMEOUT", 10*time.Second)
+
+	cfg.Declarative.StateFile = getEnvStr("WG_PORTAL_DECLARATIVE_STATE_FILE", "") // no state file by default
+	cfg.Declarative.ApplyOnStartup = getEnvBool("WG_PORTAL_DECLARATIVE_APPLY_ON_STARTUP", true)
+	cfg.Declarative.Prune = getEnvBool("WG_PORTAL_DECLARATIVE_PRUNE", true)
+	cfg.Declarative.ProtectManaged = getEnvBool("WG_PORTAL_DECLARATIVE_PROTECT_MANAGED", true)
+
 	cfg.Auth.WebAuthn.Enabled = getEnvBool("WG_PORTAL_AUTH_WEBAUTHN_ENABLED", true)
 	cfg.Auth.MinPasswordLength = getEnvInt("WG_PORTAL_AUTH_MIN_PASSWORD_LENGTH", 16)
 	cfg.Auth.HideLoginForm = getEnvBool("WG_PORTAL_AUTH_HIDE_LOGIN_FORM", false)
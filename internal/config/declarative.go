@@ -0,0 +1,14 @@
+package config
+
+// DeclarativeConfig contains the configuration of the declarative state file. The file defines interfaces, peers
+// and users in YAML, so that they can be kept in version control.
+type DeclarativeConfig struct {
+	// StateFile is the path of the state file. If empty, no state is applied on startup.
+	StateFile string `yaml:"state_file"`
+	// ApplyOnStartup applies the state file each time WireGuard Portal starts.
+	ApplyOnStartup bool `yaml:"apply_on_startup"`
+	// Prune deletes managed objects that are no longer part of the state file.
+	Prune bool `yaml:"prune"`
+	// ProtectManaged rejects changes of managed objects that are made through the web UI or the REST API.
+	ProtectManaged bool `yaml:"protect_managed"`
+}
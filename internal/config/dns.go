@@ -0,0 +1,42 @@
+package config
+
+import "time"
+
+// DnsConfig contains the configuration of the built-in DNS server.
+type DnsConfig struct {
+	// ListeningAddress is the UDP and TCP address of the DNS server. If empty, the DNS server is disabled.
+	ListeningAddress string `yaml:"listening_address"`
+	// Zone is the domain under which peer and interface names are served, for example vpn.internal.
+	Zone string `yaml:"zone"`
+	// Upstream servers receive all queries for names outside the zone. If empty, such queries are refused.
+	Upstream []string `yaml:"upstream"`
+	// Ttl is the time-to-live of the records in the zone.
+	Ttl time.Duration `yaml:"ttl"`
+}
+
+// DnsUpdateConfig contains the configuration of dynamic DNS updates (RFC 2136) of peer records
+// on an external primary DNS server.
+type DnsUpdateConfig struct {
+	// Server is the address of the primary DNS server, for example ns1.example.com:53. If empty, updates are disabled.
+	Server string `yaml:"server"`
+	// Zone is the forward zone that receives the A, AAAA and TXT records of the peers.
+	Zone string `yaml:"zone"`
+	// ReverseZones receive the PTR records of the peers. Addresses outside these zones get no PTR record.
+	ReverseZones []string `yaml:"reverse_zones"`
+	// NameTemplate is a Go template for the name of a peer, relative to the zone.
+	NameTemplate string `yaml:"name_template"`
+	// Ttl is the time-to-live of the records.
+	Ttl time.Duration `yaml:"ttl"`
+	// Owner marks the records of this instance, so that several instances can share a zone.
+	Owner string `yaml:"owner"`
+	// TsigKeyName is the name of the TSIG key. If empty, updates are not signed.
+	TsigKeyName string `yaml:"tsig_key_name"`
+	// TsigSecret is the base64 encoded TSIG secret.
+	TsigSecret string `yaml:"tsig_secret"`
+	// TsigAlgorithm is one of hmac-sha1, hmac-sha256, hmac-sha384 and hmac-sha512.
+	TsigAlgorithm string `yaml:"tsig_algorithm"`
+	// ReconcileInterval is the interval of the full reconciliation against the zone contents. Zero disables it.
+	ReconcileInterval time.Duration `yaml:"reconcile_interval"`
+	// Timeout limits the time of a single update or zone transfer.
+	Timeout time.Duration `yaml:"timeout"`
+}
@@ -0,0 +1,223 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	AclActionAllow AclAction = "allow"
+	AclActionDeny  AclAction = "deny"
+)
+
+const (
+	AclProtocolAny  = ""
+	AclProtocolTcp  = "tcp"
+	AclProtocolUdp  = "udp"
+	AclProtocolIcmp = "icmp"
+)
+
+type AclAction string
+
+// AclRule matches traffic that is forwarded from a peer to a destination.
+// All criteria of a rule must match, empty criteria match everything.
+type AclRule struct {
+	Action      AclAction `json:"Action"`      // allow or deny
+	Destination string    `json:"Destination"` // destination network in CIDR notation, empty matches all destinations
+	Protocol    string    `json:"Protocol"`    // tcp, udp or icmp, empty matches all protocols
+	Ports       string    `json:"Ports"`       // destination port (443) or port range (8000-8100), only valid for tcp and udp
+}
+
+// Validate checks all criteria of the rule.
+func (r AclRule) Validate() error {
+	if r.Action != AclActionAllow && r.Action != AclActionDeny {
+		return fmt.Errorf("invalid action %q: %w", r.Action, ErrInvalidData)
+	}
+
+	if r.Destination != "" {
+		if _, err := CidrFromString(r.Destination); err != nil {
+			return fmt.Errorf("invalid destination %q: %w", r.Destination, ErrInvalidData)
+		}
+	}
+
+	switch r.Protocol {
+	case AclProtocolAny, AclProtocolTcp, AclProtocolUdp, AclProtocolIcmp:
+	default:
+		return fmt.Errorf("invalid protocol %q: %w", r.Protocol, ErrInvalidData)
+	}
+
+	if r.Ports != "" {
+		if r.Protocol != AclProtocolTcp && r.Protocol != AclProtocolUdp {
+			return fmt.Errorf("ports require the tcp or udp protocol: %w", ErrInvalidData)
+		}
+		if _, _, err := r.PortRange(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DestinationCidr returns the destination network of the rule, or false if the rule matches all destinations.
+func (r AclRule) DestinationCidr() (Cidr, bool) {
+	if r.Destination == "" {
+		return Cidr{}, false
+	}
+	cidr, err := CidrFromString(r.Destination)
+	if err != nil {
+		return Cidr{}, false
+	}
+	return cidr.NetworkAddr(), true
+}
+
+// PortRange returns the first and last destination port of the rule. Both are zero if the rule matches all ports.
+func (r AclRule) PortRange() (from, to uint16, err error) {
+	if r.Ports == "" {
+		return 0, 0, nil
+	}
+
+	fromStr, toStr, isRange := strings.Cut(r.Ports, "-")
+	if !isRange {
+		toStr = fromStr
+	}
+
+	fromPort, errFrom := strconv.ParseUint(strings.TrimSpace(fromStr), 10, 16)
+	toPort, errTo := strconv.ParseUint(strings.TrimSpace(toStr), 10, 16)
+	if errFrom != nil || errTo != nil || fromPort == 0 || toPort < fromPort {
+		return 0, 0, fmt.Errorf("invalid port range %q: %w", r.Ports, ErrInvalidData)
+	}
+
+	return uint16(fromPort), uint16(toPort), nil
+}
+
+// AccessControlList contains destination rules for peers. The first matching rule wins.
+type AccessControlList struct {
+	Rules []AclRule `json:"Rules"`
+}
+
+// IsEmpty returns true if the list does not contain any rules.
+func (l *AccessControlList) IsEmpty() bool {
+	return l == nil || len(l.Rules) == 0
+}
+
+// Validate checks all rules of the list.
+func (l *AccessControlList) Validate() error {
+	if l == nil {
+		return nil
+	}
+
+	return validateAclRules(l.Rules)
+}
+
+// InterfaceAccessPolicy defines the forwarding policy for all peers of an interface.
+type InterfaceAccessPolicy struct {
+	IsolatePeers  bool      `json:"IsolatePeers"`  // if true, peers are not able to reach each other
+	DefaultAction AclAction `json:"DefaultAction"` // action for traffic that is not matched by any rule, empty means allow
+	Rules         []AclRule `json:"Rules"`         // rules for all peers, evaluated after the rules of the peer and its owner
+}
+
+// IsEmpty returns true if the policy does not restrict anything.
+func (p *InterfaceAccessPolicy) IsEmpty() bool {
+	return p == nil || (!p.IsolatePeers && p.GetDefaultAction() == AclActionAllow && len(p.Rules) == 0)
+}
+
+// GetDefaultAction returns the action for traffic that is not matched by any rule.
+func (p *InterfaceAccessPolicy) GetDefaultAction() AclAction {
+	if p == nil || p.DefaultAction == "" {
+		return AclActionAllow
+	}
+	return p.DefaultAction
+}
+
+// Validate checks the default action and all rules of the policy.
+func (p *InterfaceAccessPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.DefaultAction != "" && p.DefaultAction != AclActionAllow && p.DefaultAction != AclActionDeny {
+		return fmt.Errorf("invalid default action %q: %w", p.DefaultAction, ErrInvalidData)
+	}
+
+	return validateAclRules(p.Rules)
+}
+
+func validateAclRules(rules []AclRule) error {
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// PeerAccessRules contains the rules that apply to traffic originating from a single peer.
+type PeerAccessRules struct {
+	Identifier PeerIdentifier
+	Sources    []Cidr    // the addresses of the peer and the networks routed through it
+	Rules      []AclRule // the rules of the peer, followed by the rules of its owner
+}
+
+// InterfaceAccessRules is the complete forwarding policy of an interface. It is applied by capable controllers.
+// Rules are evaluated in order: peer rules, interface rules, peer isolation and finally the default action.
+type InterfaceAccessRules struct {
+	Interface     InterfaceIdentifier
+	IsolatePeers  bool
+	DefaultAction AclAction
+	Rules         []AclRule // rules for all peers of the interface
+	Peers         []PeerAccessRules
+}
+
+// NewInterfaceAccessRules collects the access rules of the interface and all enabled peers.
+// The owner of each peer must be loaded (Peer.User) for user rules to apply.
+func NewInterfaceAccessRules(iface *Interface, peers []Peer) InterfaceAccessRules {
+	rules := InterfaceAccessRules{
+		Interface:     iface.Identifier,
+		DefaultAction: iface.AccessPolicy.GetDefaultAction(),
+	}
+	if iface.AccessPolicy != nil {
+		rules.IsolatePeers = iface.AccessPolicy.IsolatePeers
+		rules.Rules = iface.AccessPolicy.Rules
+	}
+
+	for _, peer := range peers {
+		if peer.IsDisabled() {
+			continue
+		}
+
+		var peerRules []AclRule
+		if !peer.Acl.IsEmpty() {
+			peerRules = append(peerRules, peer.Acl.Rules...)
+		}
+		if peer.User != nil && !peer.User.Acl.IsEmpty() {
+			peerRules = append(peerRules, peer.User.Acl.Rules...)
+		}
+		if len(peerRules) == 0 {
+			continue
+		}
+
+		sources := make([]Cidr, 0, len(peer.Interface.Addresses))
+		for _, addr := range peer.Interface.Addresses {
+			sources = append(sources, addr.HostAddr())
+		}
+		extraSources, _ := CidrsFromString(peer.ExtraAllowedIPsStr)
+		sources = append(sources, extraSources...)
+		if len(sources) == 0 {
+			continue
+		}
+
+		rules.Peers = append(rules.Peers, PeerAccessRules{
+			Identifier: peer.Identifier,
+			Sources:    sources,
+			Rules:      peerRules,
+		})
+	}
+
+	return rules
+}
+
+// IsEmpty returns true if the rules do not restrict any traffic.
+func (r InterfaceAccessRules) IsEmpty() bool {
+	return !r.IsolatePeers && r.DefaultAction == AclActionAllow && len(r.Rules) == 0 && len(r.Peers) == 0
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAclRule_Validate(t *testing.T) {
+	assert.NoError(t, AclRule{Action: AclActionAllow}.Validate())
+	assert.NoError(t, AclRule{Action: AclActionDeny, Destination: "10.0.0.0/8", Protocol: AclProtocolTcp, Ports: "443"}.Validate())
+
+	assert.ErrorIs(t, AclRule{Action: "reject"}.Validate(), ErrInvalidData)
+	assert.ErrorIs(t, AclRule{Action: AclActionAllow, Destination: "10.0.0"}.Validate(), ErrInvalidData)
+	assert.ErrorIs(t, AclRule{Action: AclActionAllow, Protocol: "sctp"}.Validate(), ErrInvalidData)
+	assert.ErrorIs(t, AclRule{Action: AclActionAllow, Protocol: AclProtocolIcmp, Ports: "80"}.Validate(), ErrInvalidData)
+	assert.ErrorIs(t, AclRule{Action: AclActionAllow, Protocol: AclProtocolUdp, Ports: "100-10"}.Validate(), ErrInvalidData)
+}
+
+func TestAclRule_PortRange(t *testing.T) {
+	from, to, err := AclRule{Ports: "8000-8100"}.PortRange()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(8000), from)
+	assert.Equal(t, uint16(8100), to)
+
+	from, to, err = AclRule{Ports: "53"}.PortRange()
+	require.NoError(t, err)
+	assert.Equal(t, from, to)
+
+	_, _, err = AclRule{Ports: "70000"}.PortRange()
+	assert.ErrorIs(t, err, ErrInvalidData)
+}
+
+func TestNewInterfaceAccessRules(t *testing.T) {
+	addr, _ := CidrFromString("10.0.0.2/24")
+	iface := &Interface{
+		Identifier:   "wg0",
+		AccessPolicy: &InterfaceAccessPolicy{IsolatePeers: true},
+	}
+	peers := []Peer{
+		{
+			Identifier:         "peer",
+			Interface:          PeerInterfaceConfig{Addresses: []Cidr{addr}},
+			ExtraAllowedIPsStr: "192.168.10.0/24",
+			Acl:                &AccessControlList{Rules: []AclRule{{Action: AclActionAllow, Destination: "10.1.0.0/16"}}},
+			User:               &User{Acl: &AccessControlList{Rules: []AclRule{{Action: AclActionDeny}}}},
+		},
+		{
+			Identifier: "no-rules",
+			Interface:  PeerInterfaceConfig{Addresses: []Cidr{addr}},
+		},
+	}
+
+	rules := NewInterfaceAccessRules(iface, peers)
+	assert.False(t, rules.IsEmpty())
+	assert.True(t, rules.IsolatePeers)
+	assert.Equal(t, AclActionAllow, rules.DefaultAction)
+	require.Len(t, rules.Peers, 1)
+	assert.Equal(t, PeerIdentifier("peer"), rules.Peers[0].Identifier)
+	assert.Equal(t, []string{"10.0.0.2/32", "192.168.10.0/24"},
+		[]string{rules.Peers[0].Sources[0].String(), rules.Peers[0].Sources[1].String()})
+	require.Len(t, rules.Peers[0].Rules, 2)
+	assert.Equal(t, AclActionDeny, rules.Peers[0].Rules[1].Action, "user rules are evaluated after peer rules")
+
+	assert.True(t, NewInterfaceAccessRules(&Interface{Identifier: "wg1"}, peers[1:]).IsEmpty())
+}
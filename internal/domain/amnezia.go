@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"fmt"
+)
+
+const (
+	amneziaMaxJunkCount  = 128
+	amneziaMaxPacketSize = 1280 // junk and padded handshake packets must fit into the minimal IPv6 MTU
+	amneziaInitSize      = 148  // size of a WireGuard handshake initiation message
+	amneziaResponseSize  = 92   // size of a WireGuard handshake response message
+)
+
+// AmneziaParams contains the AmneziaWG obfuscation parameters of an interface. The parameters are copied to all
+// peers of the interface, as both sides of a connection must use the same header and padding values.
+type AmneziaParams struct {
+	Jc   int `json:"Jc"`   // number of junk packets that are sent before the handshake
+	Jmin int `json:"Jmin"` // minimum size of a junk packet
+	Jmax int `json:"Jmax"` // maximum size of a junk packet
+	S1   int `json:"S1"`   // padding of the handshake initiation message
+	S2   int `json:"S2"`   // padding of the handshake response message
+
+	H1 uint32 `json:"H1"` // message type of the handshake initiation, zero keeps the WireGuard default
+	H2 uint32 `json:"H2"` // message type of the handshake response, zero keeps the WireGuard default
+	H3 uint32 `json:"H3"` // message type of the cookie reply, zero keeps the WireGuard default
+	H4 uint32 `json:"H4"` // message type of the transport data, zero keeps the WireGuard default
+}
+
+// IsEmpty returns true if no obfuscation is configured, the interface then behaves like a plain WireGuard interface.
+func (p *AmneziaParams) IsEmpty() bool {
+	return p == nil || *p == AmneziaParams{}
+}
+
+// Headers returns the message types H1 to H4. Unset values are replaced by the WireGuard defaults 1 to 4.
+func (p *AmneziaParams) Headers() [4]uint32 {
+	headers := [4]uint32{1, 2, 3, 4}
+	if p == nil {
+		return headers
+	}
+
+	for i, h := range []uint32{p.H1, p.H2, p.H3, p.H4} {
+		if h != 0 {
+			headers[i] = h
+		}
+	}
+
+	return headers
+}
+
+// Validate checks the parameters against the limits of AmneziaWG.
+func (p *AmneziaParams) Validate() error {
+	if p.IsEmpty() {
+		return nil
+	}
+
+	if p.Jc < 0 || p.Jc > amneziaMaxJunkCount {
+		return fmt.Errorf("junk packet count must be between 0 and %d: %w", amneziaMaxJunkCount, ErrInvalidData)
+	}
+	if p.Jmin < 0 || p.Jmax < 0 || p.Jmax > amneziaMaxPacketSize {
+		return fmt.Errorf("junk packet sizes must be between 0 and %d: %w", amneziaMaxPacketSize, ErrInvalidData)
+	}
+	if p.Jc > 0 && p.Jmin >= p.Jmax {
+		return fmt.Errorf("minimum junk packet size must be smaller than the maximum size: %w", ErrInvalidData)
+	}
+	if p.S1 < 0 || p.S1 > amneziaMaxPacketSize-amneziaInitSize {
+		return fmt.Errorf("S1 must be between 0 and %d: %w", amneziaMaxPacketSize-amneziaInitSize, ErrInvalidData)
+	}
+	if p.S2 < 0 || p.S2 > amneziaMaxPacketSize-amneziaResponseSize {
+		return fmt.Errorf("S2 must be between 0 and %d: %w", amneziaMaxPacketSize-amneziaResponseSize, ErrInvalidData)
+	}
+	// with equal sizes, initiation and response messages could not be distinguished
+	if p.S1+amneziaInitSize == p.S2+amneziaResponseSize {
+		return fmt.Errorf("S1 + 56 must not be equal to S2: %w", ErrInvalidData)
+	}
+
+	headers := p.Headers()
+	for i := range headers {
+		for j := i + 1; j < len(headers); j++ {
+			if headers[i] == headers[j] {
+				return fmt.Errorf("H1 to H4 must be unique: %w", ErrInvalidData)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Clone returns a copy of the parameters, so that peers do not share the parameters of their interface.
+func (p *AmneziaParams) Clone() *AmneziaParams {
+	if p == nil {
+		return nil
+	}
+
+	c := *p
+	return &c
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmneziaParams_Validate(t *testing.T) {
+	var params *AmneziaParams
+	assert.NoError(t, params.Validate())
+	assert.True(t, params.IsEmpty())
+	assert.True(t, (&AmneziaParams{}).IsEmpty())
+
+	valid := &AmneziaParams{Jc: 4, Jmin: 40, Jmax: 70, S1: 15, S2: 68, H1: 1106457265, H2: 249455488, H3: 1209847463,
+		H4: 1646644382}
+	assert.NoError(t, valid.Validate())
+	assert.NoError(t, (&AmneziaParams{S1: 20}).Validate())
+
+	tests := []AmneziaParams{
+		{Jc: 129, Jmin: 8, Jmax: 80},
+		{Jc: 4, Jmin: 80, Jmax: 80},
+		{Jc: 4, Jmin: 8, Jmax: 1281},
+		{S1: 1133},
+		{S2: 1189},
+		{S1: 10, S2: 66},
+		{H1: 2},
+		{H1: 7, H4: 7},
+	}
+	for _, tt := range tests {
+		assert.ErrorIs(t, tt.Validate(), ErrInvalidData, "params %+v", tt)
+	}
+}
+
+func TestAmneziaParams_Headers(t *testing.T) {
+	var params *AmneziaParams
+	assert.Equal(t, [4]uint32{1, 2, 3, 4}, params.Headers())
+	assert.Equal(t, [4]uint32{1, 10, 3, 4}, (&AmneziaParams{H2: 10}).Headers())
+}
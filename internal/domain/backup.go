@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Backup contains all data of WireGuard Portal. Encrypted database fields are stored in plaintext,
+// so that the backup can be restored into a database with a different encryption passphrase.
+type Backup struct {
+	SchemaVersion uint64    // the database schema version of the portal that created the backup
+	PortalVersion string    // the version of the portal that created the backup
+	CreatedAt     time.Time // the time when the backup was created
+
+	Users             []User                    // including their authentications and WebAuthn credentials
+	UserPasswords     map[UserIdentifier]string // the password hashes, they are never part of an encoded user
+	Interfaces        []Interface
+	Peers             []Peer // including peers in the recycle bin
+	InterfaceStatuses []InterfaceStatus
+	PeerStatuses      []PeerStatus
+	Meshes            []Mesh
+	PeerRequests      []PeerRequest
+	ConfigRevisions   []ConfigRevision
+	AuditEntries      []AuditEntry
+}
+
+// Validate checks if the backup can be restored into a database with the given schema version.
+func (b *Backup) Validate(schemaVersion uint64) error {
+	if b.SchemaVersion == 0 {
+		return fmt.Errorf("backup has no schema version: %w", ErrInvalidData)
+	}
+	if b.SchemaVersion > schemaVersion {
+		return fmt.Errorf("backup schema version %d is newer than the database schema version %d, "+
+			"update WireGuard Portal first: %w", b.SchemaVersion, schemaVersion, ErrInvalidData)
+	}
+	if b.SchemaVersion < schemaVersion {
+		return fmt.Errorf("backup schema version %d is older than the database schema version %d, "+
+			"restore it with the version %s that created it and update afterward: %w",
+			b.SchemaVersion, schemaVersion, b.PortalVersion, ErrInvalidData)
+	}
+
+	return nil
+}
+
+// Summary returns the number of stored objects.
+func (b *Backup) Summary(encrypted bool) BackupSummary {
+	return BackupSummary{
+		SchemaVersion:     b.SchemaVersion,
+		PortalVersion:     b.PortalVersion,
+		CreatedAt:         b.CreatedAt,
+		Encrypted:         encrypted,
+		Users:             len(b.Users),
+		Interfaces:        len(b.Interfaces),
+		Peers:             len(b.Peers),
+		InterfaceStatuses: len(b.InterfaceStatuses),
+		PeerStatuses:      len(b.PeerStatuses),
+		Meshes:            len(b.Meshes),
+		PeerRequests:      len(b.PeerRequests),
+		ConfigRevisions:   len(b.ConfigRevisions),
+		AuditEntries:      len(b.AuditEntries),
+	}
+}
+
+// BackupSummary describes a created or restored backup.
+type BackupSummary struct {
+	SchemaVersion uint64
+	PortalVersion string
+	CreatedAt     time.Time
+	Encrypted     bool
+
+	Users             int
+	Interfaces        int
+	Peers             int
+	InterfaceStatuses int
+	PeerStatuses      int
+	Meshes            int
+	PeerRequests      int
+	ConfigRevisions   int
+	AuditEntries      int
+}
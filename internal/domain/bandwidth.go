@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"fmt"
+)
+
+// BandwidthLimit restricts the throughput of a peer. The directions are seen from the peer's perspective:
+// upload is the traffic sent by the peer, download is the traffic received by the peer.
+// A rate of zero means that the direction is not limited.
+type BandwidthLimit struct {
+	UploadKbps   int `json:"UploadKbps"`   // maximum upload rate in kbit/s
+	DownloadKbps int `json:"DownloadKbps"` // maximum download rate in kbit/s
+}
+
+// IsEmpty returns true if no direction is limited.
+func (b *BandwidthLimit) IsEmpty() bool {
+	return b == nil || (b.UploadKbps == 0 && b.DownloadKbps == 0)
+}
+
+// Validate checks that the configured rates are not negative.
+func (b *BandwidthLimit) Validate() error {
+	if b == nil {
+		return nil
+	}
+
+	if b.UploadKbps < 0 || b.DownloadKbps < 0 {
+		return fmt.Errorf("bandwidth limits must not be negative: %w", ErrInvalidData)
+	}
+
+	return nil
+}
+
+// EffectiveBandwidthLimit returns the bandwidth limit of the peer. If the peer has no own limit,
+// the peer default of the given interface is used. Nil is returned if the peer is not limited at all.
+func (p *Peer) EffectiveBandwidthLimit(iface *Interface) *BandwidthLimit {
+	if !p.BandwidthLimit.IsEmpty() {
+		return p.BandwidthLimit
+	}
+	if iface != nil && !iface.PeerDefBandwidthLimit.IsEmpty() {
+		return iface.PeerDefBandwidthLimit
+	}
+	return nil
+}
+
+// SetBandwidthLimit stores the given limit in the backend extras of the physical peer.
+// Only the local controller is able to enforce bandwidth limits, false is returned for all other backends.
+func (p *PhysicalPeer) SetBandwidthLimit(limit *BandwidthLimit) bool {
+	extras, ok := p.GetExtras().(LocalPeerExtras)
+	if !ok {
+		return limit.IsEmpty()
+	}
+
+	extras.UploadKbps, extras.DownloadKbps = 0, 0
+	if !limit.IsEmpty() {
+		extras.UploadKbps = limit.UploadKbps
+		extras.DownloadKbps = limit.DownloadKbps
+	}
+	p.SetExtras(extras)
+
+	return true
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandwidthLimit_Validate(t *testing.T) {
+	var limit *BandwidthLimit
+	assert.NoError(t, limit.Validate())
+	assert.NoError(t, (&BandwidthLimit{UploadKbps: 1000}).Validate())
+	assert.ErrorIs(t, (&BandwidthLimit{DownloadKbps: -1}).Validate(), ErrInvalidData)
+}
+
+func TestPeer_EffectiveBandwidthLimit(t *testing.T) {
+	iface := &Interface{PeerDefBandwidthLimit: &BandwidthLimit{UploadKbps: 1000, DownloadKbps: 5000}}
+
+	peer := &Peer{}
+	assert.Nil(t, peer.EffectiveBandwidthLimit(&Interface{}))
+	assert.Equal(t, iface.PeerDefBandwidthLimit, peer.EffectiveBandwidthLimit(iface))
+
+	peer.BandwidthLimit = &BandwidthLimit{}
+	assert.Equal(t, iface.PeerDefBandwidthLimit, peer.EffectiveBandwidthLimit(iface), "empty limits are inherited")
+
+	peer.BandwidthLimit = &BandwidthLimit{DownloadKbps: 100}
+	assert.Equal(t, peer.BandwidthLimit, peer.EffectiveBandwidthLimit(iface))
+}
+
+func TestPhysicalPeer_SetBandwidthLimit(t *testing.T) {
+	local := &PhysicalPeer{}
+	local.SetExtras(LocalPeerExtras{Disabled: true})
+	assert.True(t, local.SetBandwidthLimit(&BandwidthLimit{UploadKbps: 1000, DownloadKbps: 5000}))
+	assert.Equal(t, LocalPeerExtras{Disabled: true, UploadKbps: 1000, DownloadKbps: 5000}, local.GetExtras())
+	assert.True(t, local.SetBandwidthLimit(nil))
+	assert.Equal(t, LocalPeerExtras{Disabled: true}, local.GetExtras())
+
+	remote := &PhysicalPeer{}
+	remote.SetExtras(MikrotikPeerExtras{})
+	assert.True(t, remote.SetBandwidthLimit(nil))
+	assert.False(t, remote.SetBandwidthLimit(&BandwidthLimit{UploadKbps: 1000}))
+}
@@ -59,10 +59,16 @@ const (
 	DisabledReasonLdapMissing      = "missing in ldap"
 	DisabledReasonMigrationDummy   = "migration dummy user"
 	DisabledReasonInterfaceMissing = "missing WireGuard interface"
+	DisabledReasonInactive         = "inactive"
+	DisabledReasonMaintenance      = "scheduled maintenance"
 
 	LockedReasonAdmin = "locked by admin"
 	LockedReasonApi   = "locked by admin"
 
-	ConfigStyleRaw     = "raw"
-	ConfigStyleWgQuick = "wgquick"
+	ConfigStyleRaw            = "raw"
+	ConfigStyleWgQuick        = "wgquick"
+	ConfigStyleNetworkd       = "networkd"
+	ConfigStyleNetworkManager = "networkmanager"
+	ConfigStyleOpenWrt        = "openwrt"
+	ConfigStyleRouterOs       = "routeros"
 )
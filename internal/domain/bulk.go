@@ -0,0 +1,231 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+type BulkFormat string
+
+const (
+	BulkFormatCsv  BulkFormat = "csv"
+	BulkFormatJson BulkFormat = "json"
+)
+
+// ParseBulkFormat parses the given string into a BulkFormat. An empty string defaults to JSON.
+func ParseBulkFormat(str string) (BulkFormat, error) {
+	switch BulkFormat(strings.ToLower(strings.TrimSpace(str))) {
+	case BulkFormatCsv:
+		return BulkFormatCsv, nil
+	case BulkFormatJson, "":
+		return BulkFormatJson, nil
+	default:
+		return "", fmt.Errorf("unsupported bulk format %q: %w", str, ErrInvalidData)
+	}
+}
+
+// BulkImportOptions controls the behavior of bulk imports.
+type BulkImportOptions struct {
+	DryRun bool // if set, records are only validated, nothing is persisted
+	Upsert bool // if set, existing records are updated instead of being reported as duplicates
+}
+
+// BulkExportOptions controls the behavior of bulk exports.
+type BulkExportOptions struct {
+	IncludeKeys bool // if set, private and pre-shared keys of peers are exported in plaintext
+}
+
+// BulkUserRecord is the flat, serializable representation of a user used for bulk import and export.
+type BulkUserRecord struct {
+	Identifier   string `json:"Identifier"`
+	Email        string `json:"Email"`
+	Source       string `json:"Source"`       // authentication source (db, ldap, oauth), defaults to db
+	ProviderName string `json:"ProviderName"` // authentication provider name, empty for db users
+	IsAdmin      bool   `json:"IsAdmin"`
+	Firstname    string `json:"Firstname"`
+	Lastname     string `json:"Lastname"`
+	Phone        string `json:"Phone"`
+	Department   string `json:"Department"`
+	Notes        string `json:"Notes"`
+	Password     string `json:"Password,omitempty"` // only used for imports, never exported
+	Disabled     bool   `json:"Disabled"`
+	Locked       bool   `json:"Locked"`
+}
+
+// NewBulkUserRecord converts the given user to a bulk user record.
+func NewBulkUserRecord(user *User) BulkUserRecord {
+	rec := BulkUserRecord{
+		Identifier: string(user.Identifier),
+		Email:      user.Email,
+		IsAdmin:    user.IsAdmin,
+		Firstname:  user.Firstname,
+		Lastname:   user.Lastname,
+		Phone:      user.Phone,
+		Department: user.Department,
+		Notes:      user.Notes,
+		Disabled:   user.IsDisabled(),
+		Locked:     user.IsLocked(),
+	}
+	if len(user.Authentications) > 0 {
+		rec.Source = string(user.Authentications[0].Source)
+		rec.ProviderName = user.Authentications[0].ProviderName
+	}
+
+	return rec
+}
+
+// MergeToUser applies the record values to the given user.
+func (r BulkUserRecord) MergeToUser(user *User) {
+	now := time.Now()
+
+	user.Identifier = UserIdentifier(r.Identifier)
+	user.Email = r.Email
+	user.IsAdmin = r.IsAdmin
+	user.Firstname = r.Firstname
+	user.Lastname = r.Lastname
+	user.Phone = r.Phone
+	user.Department = r.Department
+	user.Notes = r.Notes
+	if r.Password != "" {
+		user.Password = PrivateString(r.Password)
+	}
+
+	switch {
+	case r.Disabled && !user.IsDisabled():
+		user.Disabled = &now
+		user.DisabledReason = DisabledReasonAdmin
+	case !r.Disabled:
+		user.Disabled = nil
+		user.DisabledReason = ""
+	}
+
+	switch {
+	case r.Locked && !user.IsLocked():
+		user.Locked = &now
+		user.LockedReason = LockedReasonAdmin
+	case !r.Locked:
+		user.Locked = nil
+		user.LockedReason = ""
+	}
+}
+
+// BulkPeerRecord is the flat, serializable representation of a peer used for bulk import and export.
+// The relation to the interface and the owning user is kept through their identifiers.
+type BulkPeerRecord struct {
+	Identifier          string     `json:"Identifier"` // equals the public key, empty for new peers
+	InterfaceIdentifier string     `json:"InterfaceIdentifier"`
+	UserIdentifier      string     `json:"UserIdentifier"`
+	DisplayName         string     `json:"DisplayName"`
+	PublicKey           string     `json:"PublicKey"`  // if empty, a fresh key pair is generated on import
+	PrivateKey          string     `json:"PrivateKey"` // optional, peers without private key cannot download a config
+	PresharedKey        string     `json:"PresharedKey"`
+	Addresses           []string   `json:"Addresses"` // if empty, fresh addresses are allocated on import
+	ExtraAllowedIPs     []string   `json:"ExtraAllowedIPs"`
+	Endpoint            string     `json:"Endpoint"`
+	PersistentKeepalive int        `json:"PersistentKeepalive"`
+	Disabled            bool       `json:"Disabled"`
+	ExpiresAt           *time.Time `json:"ExpiresAt,omitempty"`
+	Notes               string     `json:"Notes"`
+}
+
+// NewBulkPeerRecord converts the given peer to a bulk peer record. Private and pre-shared keys are only
+// included if includeKeys is set, otherwise the columns stay empty.
+func NewBulkPeerRecord(peer *Peer, includeKeys bool) BulkPeerRecord {
+	record := BulkPeerRecord{
+		Identifier:          string(peer.Identifier),
+		InterfaceIdentifier: string(peer.InterfaceIdentifier),
+		UserIdentifier:      string(peer.UserIdentifier),
+		DisplayName:         peer.DisplayName,
+		PublicKey:           peer.Interface.PublicKey,
+		Addresses:           CidrsToStringSlice(peer.Interface.Addresses),
+		ExtraAllowedIPs:     internal.SliceString(peer.ExtraAllowedIPsStr),
+		Endpoint:            peer.Endpoint.GetValue(),
+		PersistentKeepalive: peer.PersistentKeepalive.GetValue(),
+		Disabled:            peer.IsDisabled(),
+		ExpiresAt:           peer.ExpiresAt,
+		Notes:               peer.Notes,
+	}
+	if includeKeys {
+		record.PrivateKey = peer.Interface.PrivateKey
+		record.PresharedKey = string(peer.PresharedKey)
+	}
+
+	return record
+}
+
+// MergeToPeer applies the record values to the given peer. Empty key material and addresses are ignored so that
+// prepared defaults stay in place.
+func (r BulkPeerRecord) MergeToPeer(peer *Peer) error {
+	now := time.Now()
+
+	if r.PublicKey != "" {
+		peer.Interface.PublicKey = r.PublicKey
+		peer.Interface.PrivateKey = r.PrivateKey
+	}
+	if r.PresharedKey != "" {
+		peer.PresharedKey = PreSharedKey(r.PresharedKey)
+	}
+	if len(r.Addresses) > 0 {
+		addresses, err := CidrsFromArray(r.Addresses)
+		if err != nil {
+			return fmt.Errorf("invalid addresses: %w", err)
+		}
+		peer.Interface.Addresses = addresses
+	}
+	if r.Endpoint != "" {
+		peer.Endpoint.SetValue(r.Endpoint)
+	}
+	if r.PersistentKeepalive != 0 {
+		peer.PersistentKeepalive.SetValue(r.PersistentKeepalive)
+	}
+
+	peer.InterfaceIdentifier = InterfaceIdentifier(r.InterfaceIdentifier)
+	peer.UserIdentifier = UserIdentifier(r.UserIdentifier)
+	if r.DisplayName != "" {
+		peer.DisplayName = r.DisplayName
+	}
+	peer.ExtraAllowedIPsStr = internal.SliceToString(r.ExtraAllowedIPs)
+	peer.ExpiresAt = r.ExpiresAt
+	peer.Notes = r.Notes
+
+	switch {
+	case r.Disabled && !peer.IsDisabled():
+		peer.Disabled = &now
+		peer.DisabledReason = DisabledReasonAdmin
+	case !r.Disabled:
+		peer.Disabled = nil
+		peer.DisabledReason = ""
+	}
+
+	return nil
+}
+
+// BulkImportRowError describes a single record that could not be imported.
+type BulkImportRowError struct {
+	Row        int    `json:"Row"` // 1-based record index, for CSV files the header row is not counted
+	Identifier string `json:"Identifier"`
+	Message    string `json:"Message"`
+}
+
+// BulkImportResult summarizes the outcome of a bulk import.
+type BulkImportResult struct {
+	DryRun  bool                 `json:"DryRun"`
+	Total   int                  `json:"Total"`
+	Created int                  `json:"Created"`
+	Updated int                  `json:"Updated"`
+	Failed  int                  `json:"Failed"`
+	Errors  []BulkImportRowError `json:"Errors"`
+}
+
+// AddError records a failed row.
+func (r *BulkImportResult) AddError(row int, identifier string, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, BulkImportRowError{
+		Row:        row,
+		Identifier: identifier,
+		Message:    err.Error(),
+	})
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"fmt"
+)
+
+// ConfigBundleContentType is the MIME type of configuration bundles.
+const ConfigBundleContentType = "application/zip"
+
+// ConfigBundleRequest selects the peers whose configurations are packed into a single zip archive.
+// Exactly one of the selections (user, interface or explicit peer list) must be set.
+type ConfigBundleRequest struct {
+	UserIdentifier      UserIdentifier
+	InterfaceIdentifier InterfaceIdentifier
+	PeerIdentifiers     []PeerIdentifier
+	Style               string // the configuration style, defaults to wgquick
+}
+
+// Validate checks that exactly one selection is set and applies the default style.
+func (r *ConfigBundleRequest) Validate() error {
+	selections := 0
+	if r.UserIdentifier != "" {
+		selections++
+	}
+	if r.InterfaceIdentifier != "" {
+		selections++
+	}
+	if len(r.PeerIdentifiers) > 0 {
+		selections++
+	}
+	if selections != 1 {
+		return fmt.Errorf("exactly one of user, interface or peers must be selected: %w", ErrInvalidData)
+	}
+
+	for _, id := range r.PeerIdentifiers {
+		if id == "" {
+			return fmt.Errorf("empty peer identifier: %w", ErrInvalidData)
+		}
+	}
+
+	if r.Style == "" {
+		r.Style = ConfigStyleWgQuick
+	}
+	if !IsValidConfigStyle(r.Style) {
+		return fmt.Errorf("unknown configuration style %s: %w", r.Style, ErrInvalidData)
+	}
+
+	return nil
+}
+
+// FileName returns the name of the zip archive, derived from the selected user or interface.
+func (r *ConfigBundleRequest) FileName() string {
+	var name string
+	switch {
+	case r.InterfaceIdentifier != "":
+		name = allowedFileNameRegex.ReplaceAllString(string(r.InterfaceIdentifier), "")
+	case r.UserIdentifier != "":
+		name = allowedFileNameRegex.ReplaceAllString(string(r.UserIdentifier), "")
+	}
+	if name == "" {
+		name = "wireguard"
+	}
+
+	return name + "_configs.zip"
+}
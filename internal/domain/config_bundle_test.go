@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigBundleRequest_Validate(t *testing.T) {
+	req := ConfigBundleRequest{InterfaceIdentifier: "wg0"}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, ConfigStyleWgQuick, req.Style)
+
+	assert.ErrorIs(t, (&ConfigBundleRequest{}).Validate(), ErrInvalidData)
+	assert.ErrorIs(t, (&ConfigBundleRequest{UserIdentifier: "bob", InterfaceIdentifier: "wg0"}).Validate(),
+		ErrInvalidData)
+	assert.ErrorIs(t, (&ConfigBundleRequest{PeerIdentifiers: []PeerIdentifier{""}}).Validate(), ErrInvalidData)
+	assert.ErrorIs(t, (&ConfigBundleRequest{UserIdentifier: "bob", Style: "zip"}).Validate(), ErrInvalidData)
+}
+
+func TestConfigBundleRequest_FileName(t *testing.T) {
+	assert.Equal(t, "wg0_configs.zip", (&ConfigBundleRequest{InterfaceIdentifier: "wg0"}).FileName())
+	assert.Equal(t, "bobexamplecom_configs.zip", (&ConfigBundleRequest{UserIdentifier: "bob@example.com"}).FileName())
+	assert.Equal(t, "wireguard_configs.zip",
+		(&ConfigBundleRequest{PeerIdentifiers: []PeerIdentifier{"a", "b"}}).FileName())
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "slices"
+
+// ConfigStyles contains all supported peer configuration styles.
+var ConfigStyles = []string{
+	ConfigStyleWgQuick,
+	ConfigStyleRaw,
+	ConfigStyleNetworkd,
+	ConfigStyleNetworkManager,
+	ConfigStyleOpenWrt,
+	ConfigStyleRouterOs,
+}
+
+// ConfigFile is a single rendered configuration file.
+type ConfigFile struct {
+	Name string
+	Data []byte
+}
+
+// IsValidConfigStyle returns true if the given style is a supported peer configuration style.
+func IsValidConfigStyle(style string) bool {
+	return slices.Contains(ConfigStyles, style)
+}
+
+// IsWireGuardConfigStyle returns true if the style produces a native WireGuard configuration file
+// that can be imported by the WireGuard apps, for example via QR code.
+func IsWireGuardConfigStyle(style string) bool {
+	return style == ConfigStyleWgQuick || style == ConfigStyleRaw
+}
@@ -9,12 +9,13 @@ import (
 const CtxUserInfo = "userInfo"
 
 const (
-	CtxSystemAdminId    = "_WG_SYS_ADMIN_"
-	CtxUnknownUserId    = "_WG_SYS_UNKNOWN_"
-	CtxSystemLdapSyncer = "_WG_SYS_LDAP_SYNCER_"
-	CtxSystemWgImporter = "_WG_SYS_WG_IMPORTER_"
-	CtxSystemV1Migrator = "_WG_SYS_V1_MIGRATOR_"
-	CtxSystemDBMigrator = "_WG_SYS_DB_MIGRATOR_"
+	CtxSystemAdminId     = "_WG_SYS_ADMIN_"
+	CtxUnknownUserId     = "_WG_SYS_UNKNOWN_"
+	CtxSystemLdapSyncer  = "_WG_SYS_LDAP_SYNCER_"
+	CtxSystemWgImporter  = "_WG_SYS_WG_IMPORTER_"
+	CtxSystemV1Migrator  = "_WG_SYS_V1_MIGRATOR_"
+	CtxSystemDBMigrator  = "_WG_SYS_DB_MIGRATOR_"
+	CtxSystemDeclarative = "_WG_SYS_DECLARATIVE_"
 )
 
 type ContextUserInfo struct {
@@ -54,6 +55,25 @@ func LdapSyncContextUserInfo() *ContextUserInfo {
 	}
 }
 
+// DeclarativeContextUserInfo returns a context user info for the declarative state manager.
+func DeclarativeContextUserInfo() *ContextUserInfo {
+	return &ContextUserInfo{
+		Id:      CtxSystemDeclarative,
+		IsAdmin: true,
+	}
+}
+
+// IsSystem returns true if the context belongs to an internal process instead of a real user.
+func (u *ContextUserInfo) IsSystem() bool {
+	switch u.Id {
+	case CtxSystemAdminId, CtxSystemLdapSyncer, CtxSystemWgImporter, CtxSystemV1Migrator, CtxSystemDBMigrator,
+		CtxSystemDeclarative:
+		return true
+	default:
+		return false
+	}
+}
+
 // SetUserInfo sets the user info in the context.
 func SetUserInfo(ctx context.Context, info *ContextUserInfo) context.Context {
 	ctx = context.WithValue(ctx, CtxUserInfo, info)
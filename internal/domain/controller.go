@@ -29,7 +29,9 @@ type MikrotikPeerExtras struct {
 }
 
 type LocalPeerExtras struct {
-	Disabled bool
+	Disabled     bool
+	UploadKbps   int // upload limit that is enforced using traffic control, zero if unlimited
+	DownloadKbps int // download limit that is enforced using traffic control, zero if unlimited
 }
 
 type PfsenseInterfaceExtras struct {
@@ -0,0 +1,375 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/h44z/wg-portal/internal"
+)
+
+// ManagedByDeclarative marks interfaces, peers and users that are defined by the declarative state file.
+const ManagedByDeclarative = "declarative"
+
+const (
+	DeclarativeActionCreate DeclarativeAction = "create"
+	DeclarativeActionUpdate DeclarativeAction = "update"
+	DeclarativeActionDelete DeclarativeAction = "delete"
+)
+
+const (
+	DeclarativeObjectUser      DeclarativeObjectType = "user"
+	DeclarativeObjectInterface DeclarativeObjectType = "interface"
+	DeclarativeObjectPeer      DeclarativeObjectType = "peer"
+)
+
+type DeclarativeAction string
+
+type DeclarativeObjectType string
+
+// DeclarativeState is the desired state of users, interfaces and peers, as defined in the state file.
+type DeclarativeState struct {
+	Users      []DeclarativeUser      `yaml:"users"`
+	Groups     []DeclarativeGroup     `yaml:"groups"`
+	Interfaces []DeclarativeInterface `yaml:"interfaces"`
+	Peers      []DeclarativePeer      `yaml:"peers"`
+}
+
+// DeclarativeUser is a user of the state file.
+type DeclarativeUser struct {
+	Identifier   string `yaml:"identifier"`
+	Email        string `yaml:"email"`
+	Source       string `yaml:"source"`        // authentication source (db, ldap, oauth), defaults to db
+	ProviderName string `yaml:"provider_name"` // authentication provider name, empty for db users
+	Password     string `yaml:"password"`      // only used when the user is created, required for db users
+	Firstname    string `yaml:"firstname"`
+	Lastname     string `yaml:"lastname"`
+	Phone        string `yaml:"phone"`
+	Department   string `yaml:"department"`
+	Notes        string `yaml:"notes"`
+	Disabled     bool   `yaml:"disabled"`
+	Locked       bool   `yaml:"locked"`
+}
+
+// DeclarativeGroup assigns the admin role to its members. Users that are not a member of an admin group are
+// regular users.
+type DeclarativeGroup struct {
+	Name    string   `yaml:"name"`
+	Admin   bool     `yaml:"admin"`
+	Members []string `yaml:"members"` // identifiers of users of the state file
+}
+
+// DeclarativeInterface is an interface of the state file.
+type DeclarativeInterface struct {
+	Identifier   string                  `yaml:"identifier"`
+	DisplayName  string                  `yaml:"display_name"` // defaults to the identifier
+	Type         string                  `yaml:"type"`         // server, client or any, defaults to server
+	Backend      string                  `yaml:"backend"`      // defaults to the local backend
+	PrivateKey   string                  `yaml:"private_key"`  // if empty, a key is generated once and kept afterward
+	ListenPort   int                     `yaml:"listen_port"`
+	Addresses    []string                `yaml:"addresses"`
+	Dns          []string                `yaml:"dns"`
+	DnsSearch    []string                `yaml:"dns_search"`
+	Mtu          int                     `yaml:"mtu"`
+	FirewallMark uint32                  `yaml:"firewall_mark"`
+	RoutingTable string                  `yaml:"routing_table"`
+	PreUp        string                  `yaml:"pre_up"`
+	PostUp       string                  `yaml:"post_up"`
+	PreDown      string                  `yaml:"pre_down"`
+	PostDown     string                  `yaml:"post_down"`
+	SaveConfig   bool                    `yaml:"save_config"`
+	Disabled     bool                    `yaml:"disabled"`
+	PeerDefaults DeclarativePeerDefaults `yaml:"peer_defaults"`
+}
+
+// DeclarativePeerDefaults are the default settings of new peers of an interface.
+type DeclarativePeerDefaults struct {
+	Networks            []string `yaml:"networks"` // the subnets from which peers get their addresses
+	Endpoint            string   `yaml:"endpoint"`
+	AllowedIPs          []string `yaml:"allowed_ips"`
+	Dns                 []string `yaml:"dns"`
+	DnsSearch           []string `yaml:"dns_search"`
+	Mtu                 int      `yaml:"mtu"`
+	PersistentKeepalive int      `yaml:"persistent_keepalive"`
+	FirewallMark        uint32   `yaml:"firewall_mark"`
+	RoutingTable        string   `yaml:"routing_table"`
+	PreUp               string   `yaml:"pre_up"`
+	PostUp              string   `yaml:"post_up"`
+	PreDown             string   `yaml:"pre_down"`
+	PostDown            string   `yaml:"post_down"`
+}
+
+// DeclarativePeer is a peer of the state file. Peers are identified by their public key, it is derived from the
+// private key if only the private key is given.
+type DeclarativePeer struct {
+	Interface           string     `yaml:"interface"`
+	PublicKey           string     `yaml:"public_key"`
+	PrivateKey          string     `yaml:"private_key"`   // optional, peers without private key cannot download a config
+	PresharedKey        string     `yaml:"preshared_key"` // optional, an existing key is kept if empty
+	DisplayName         string     `yaml:"display_name"`
+	User                string     `yaml:"user"`
+	Addresses           []string   `yaml:"addresses"` // if empty, addresses are allocated once and kept afterward
+	ExtraAllowedIPs     []string   `yaml:"extra_allowed_ips"`
+	Endpoint            string     `yaml:"endpoint"`
+	PersistentKeepalive int        `yaml:"persistent_keepalive"`
+	Disabled            bool       `yaml:"disabled"`
+	ExpiresAt           *time.Time `yaml:"expires_at"`
+	Notes               string     `yaml:"notes"`
+}
+
+// Validate checks the state for missing identifiers, duplicates and unknown group members.
+// References to interfaces and users that only exist in the database are checked when the state is planned.
+func (s *DeclarativeState) Validate() error {
+	users := make(map[string]struct{}, len(s.Users))
+	for i, user := range s.Users {
+		if user.Identifier == "" {
+			return fmt.Errorf("user %d has no identifier: %w", i, ErrInvalidData)
+		}
+		if _, ok := users[user.Identifier]; ok {
+			return fmt.Errorf("user %s is defined multiple times: %w", user.Identifier, ErrInvalidData)
+		}
+		users[user.Identifier] = struct{}{}
+	}
+
+	groups := make(map[string]struct{}, len(s.Groups))
+	for i, group := range s.Groups {
+		if group.Name == "" {
+			return fmt.Errorf("group %d has no name: %w", i, ErrInvalidData)
+		}
+		if _, ok := groups[group.Name]; ok {
+			return fmt.Errorf("group %s is defined multiple times: %w", group.Name, ErrInvalidData)
+		}
+		groups[group.Name] = struct{}{}
+		for _, member := range group.Members {
+			if _, ok := users[member]; !ok {
+				return fmt.Errorf("member %s of group %s is not a user of the state: %w", member, group.Name,
+					ErrInvalidData)
+			}
+		}
+	}
+
+	interfaces := make(map[string]struct{}, len(s.Interfaces))
+	for i, iface := range s.Interfaces {
+		if iface.Identifier == "" {
+			return fmt.Errorf("interface %d has no identifier: %w", i, ErrInvalidData)
+		}
+		if _, ok := interfaces[iface.Identifier]; ok {
+			return fmt.Errorf("interface %s is defined multiple times: %w", iface.Identifier, ErrInvalidData)
+		}
+		interfaces[iface.Identifier] = struct{}{}
+	}
+
+	peers := make(map[string]struct{}, len(s.Peers))
+	for i, peer := range s.Peers {
+		if peer.Interface == "" {
+			return fmt.Errorf("peer %d has no interface: %w", i, ErrInvalidData)
+		}
+		key := peer.Key()
+		if key == "" {
+			return fmt.Errorf("peer %d needs a valid public or private key: %w", i, ErrInvalidData)
+		}
+		if peer.PublicKey != "" && peer.PrivateKey != "" && PublicKeyFromPrivateKey(peer.PrivateKey) != key {
+			return fmt.Errorf("public key of peer %s does not match the private key: %w", key, ErrInvalidData)
+		}
+		if _, ok := peers[key]; ok {
+			return fmt.Errorf("peer %s is defined multiple times: %w", key, ErrInvalidData)
+		}
+		peers[key] = struct{}{}
+	}
+
+	return nil
+}
+
+// IsAdmin returns true if the user is a member of an admin group.
+func (s *DeclarativeState) IsAdmin(id string) bool {
+	for _, group := range s.Groups {
+		if !group.Admin {
+			continue
+		}
+		for _, member := range group.Members {
+			if member == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MergeToUser applies the state of the user. The password is only applied to new users, as the stored password
+// hash can not be compared with the state.
+func (u *DeclarativeUser) MergeToUser(user *User, isAdmin, isNew bool) {
+	record := BulkUserRecord{
+		Identifier: u.Identifier,
+		Email:      u.Email,
+		IsAdmin:    isAdmin,
+		Firstname:  u.Firstname,
+		Lastname:   u.Lastname,
+		Phone:      u.Phone,
+		Department: u.Department,
+		Notes:      u.Notes,
+		Disabled:   u.Disabled,
+		Locked:     u.Locked,
+	}
+	if isNew {
+		record.Password = u.Password
+	}
+	record.MergeToUser(user)
+	user.ManagedBy = ManagedByDeclarative
+}
+
+// MergeToInterface applies the state of the interface. An empty private key keeps the existing key.
+func (i *DeclarativeInterface) MergeToInterface(iface *Interface) error {
+	addresses, err := CidrsFromArray(i.Addresses)
+	if err != nil {
+		return fmt.Errorf("invalid addresses: %w", ErrInvalidData)
+	}
+	networks, err := CidrsFromArray(i.PeerDefaults.Networks)
+	if err != nil {
+		return fmt.Errorf("invalid peer networks: %w", ErrInvalidData)
+	}
+	if _, err := CidrsFromArray(i.PeerDefaults.AllowedIPs); err != nil {
+		return fmt.Errorf("invalid peer allowed IPs: %w", ErrInvalidData)
+	}
+
+	switch InterfaceType(i.Type) {
+	case "":
+		iface.Type = InterfaceTypeServer
+	case InterfaceTypeServer, InterfaceTypeClient, InterfaceTypeAny:
+		iface.Type = InterfaceType(i.Type)
+	default:
+		return fmt.Errorf("invalid interface type %q: %w", i.Type, ErrInvalidData)
+	}
+
+	if i.PrivateKey != "" {
+		publicKey := PublicKeyFromPrivateKey(i.PrivateKey)
+		if publicKey == "" {
+			return fmt.Errorf("invalid private key: %w", ErrInvalidData)
+		}
+		iface.KeyPair = KeyPair{PrivateKey: i.PrivateKey, PublicKey: publicKey}
+	}
+	if i.Backend != "" {
+		iface.Backend = InterfaceBackend(i.Backend)
+	}
+
+	iface.Identifier = InterfaceIdentifier(i.Identifier)
+	iface.DisplayName = i.DisplayName
+	if iface.DisplayName == "" {
+		iface.DisplayName = i.Identifier
+	}
+	iface.ListenPort = i.ListenPort
+	iface.Addresses = addresses
+	iface.DnsStr = internal.SliceToString(i.Dns)
+	iface.DnsSearchStr = internal.SliceToString(i.DnsSearch)
+	iface.Mtu = i.Mtu
+	iface.FirewallMark = i.FirewallMark
+	iface.RoutingTable = i.RoutingTable
+	iface.PreUp = i.PreUp
+	iface.PostUp = i.PostUp
+	iface.PreDown = i.PreDown
+	iface.PostDown = i.PostDown
+	iface.SaveConfig = i.SaveConfig
+
+	iface.PeerDefNetworkStr = CidrsToString(networks)
+	iface.PeerDefEndpoint = i.PeerDefaults.Endpoint
+	iface.PeerDefAllowedIPsStr = internal.SliceToString(i.PeerDefaults.AllowedIPs)
+	iface.PeerDefDnsStr = internal.SliceToString(i.PeerDefaults.Dns)
+	iface.PeerDefDnsSearchStr = internal.SliceToString(i.PeerDefaults.DnsSearch)
+	iface.PeerDefMtu = i.PeerDefaults.Mtu
+	iface.PeerDefPersistentKeepalive = i.PeerDefaults.PersistentKeepalive
+	iface.PeerDefFirewallMark = i.PeerDefaults.FirewallMark
+	iface.PeerDefRoutingTable = i.PeerDefaults.RoutingTable
+	iface.PeerDefPreUp = i.PeerDefaults.PreUp
+	iface.PeerDefPostUp = i.PeerDefaults.PostUp
+	iface.PeerDefPreDown = i.PeerDefaults.PreDown
+	iface.PeerDefPostDown = i.PeerDefaults.PostDown
+
+	switch {
+	case i.Disabled && !iface.IsDisabled():
+		now := time.Now()
+		iface.Disabled = &now
+		iface.DisabledReason = DisabledReasonAdmin
+	case !i.Disabled:
+		iface.Disabled = nil
+		iface.DisabledReason = ""
+	}
+
+	iface.ManagedBy = ManagedByDeclarative
+
+	return nil
+}
+
+// Key returns the public key of the peer, it is derived from the private key if no public key is given.
+func (p *DeclarativePeer) Key() string {
+	if p.PublicKey != "" {
+		return p.PublicKey
+	}
+	return PublicKeyFromPrivateKey(p.PrivateKey)
+}
+
+// MergeToPeer applies the state of the peer. Empty private keys, preshared keys and addresses keep the existing
+// values, so that generated keys and allocated addresses do not have to be part of the state file.
+func (p *DeclarativePeer) MergeToPeer(peer *Peer) error {
+	record := BulkPeerRecord{
+		InterfaceIdentifier: p.Interface,
+		UserIdentifier:      p.User,
+		DisplayName:         p.DisplayName,
+		PublicKey:           p.Key(),
+		PrivateKey:          p.PrivateKey,
+		PresharedKey:        p.PresharedKey,
+		Addresses:           p.Addresses,
+		ExtraAllowedIPs:     p.ExtraAllowedIPs,
+		Endpoint:            p.Endpoint,
+		PersistentKeepalive: p.PersistentKeepalive,
+		Disabled:            p.Disabled,
+		ExpiresAt:           p.ExpiresAt,
+		Notes:               p.Notes,
+	}
+	if record.PrivateKey == "" && peer.Interface.PublicKey == record.PublicKey {
+		record.PrivateKey = peer.Interface.PrivateKey
+	}
+	if err := record.MergeToPeer(peer); err != nil {
+		return fmt.Errorf("%w: %w", err, ErrInvalidData)
+	}
+
+	peer.Identifier = PeerIdentifier(record.PublicKey)
+	peer.ManagedBy = ManagedByDeclarative
+
+	return nil
+}
+
+// DeclarativeChange is a single change that is required to reach the declarative state.
+type DeclarativeChange struct {
+	Action     DeclarativeAction
+	ObjectType DeclarativeObjectType
+	Identifier string
+	Fields     []RevisionChange // the changed fields of updates, private keys are hidden
+	Error      string           // set if the change could not be planned or applied
+}
+
+// DeclarativePlan contains all changes that are required to reach the declarative state, in the order in which
+// they are applied.
+type DeclarativePlan struct {
+	DryRun  bool
+	Changes []DeclarativeChange
+}
+
+// Failed returns the number of changes that could not be planned or applied.
+func (p *DeclarativePlan) Failed() int {
+	failed := 0
+	for _, change := range p.Changes {
+		if change.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// ValidateManagedChange returns an error if a user tries to change an object that is managed by the declarative
+// state file. Internal processes, like the expiry check or the state manager itself, may still change it.
+func ValidateManagedChange(ctx context.Context, managedBy string) error {
+	if managedBy == "" || GetUserInfo(ctx).IsSystem() {
+		return nil
+	}
+
+	return fmt.Errorf("object is managed by the %s state file, change it there: %w", managedBy, ErrNoPermission)
+}
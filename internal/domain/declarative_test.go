@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDeclarativePrivateKey = "aJ4Kq7uWNyGx7DOGxNdWGy5kW7Ck3LW5wvCh6DbZWXo="
+
+func TestDeclarativeState_Validate(t *testing.T) {
+	valid := DeclarativeState{
+		Users:      []DeclarativeUser{{Identifier: "alice"}},
+		Groups:     []DeclarativeGroup{{Name: "admins", Admin: true, Members: []string{"alice"}}},
+		Interfaces: []DeclarativeInterface{{Identifier: "wg0"}},
+		Peers:      []DeclarativePeer{{Interface: "wg0", PrivateKey: testDeclarativePrivateKey}},
+	}
+	require.NoError(t, valid.Validate())
+	assert.True(t, valid.IsAdmin("alice"))
+	assert.False(t, valid.IsAdmin("bob"))
+
+	tests := []struct {
+		name   string
+		modify func(s *DeclarativeState)
+	}{
+		{"duplicate user", func(s *DeclarativeState) { s.Users = append(s.Users, DeclarativeUser{Identifier: "alice"}) }},
+		{"unknown member", func(s *DeclarativeState) { s.Groups[0].Members = []string{"bob"} }},
+		{"missing interface id", func(s *DeclarativeState) { s.Interfaces[0].Identifier = "" }},
+		{"peer without key", func(s *DeclarativeState) { s.Peers[0].PrivateKey = "" }},
+		{"key mismatch", func(s *DeclarativeState) { s.Peers[0].PublicKey = "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := valid
+			state.Users = append([]DeclarativeUser(nil), valid.Users...)
+			state.Groups = []DeclarativeGroup{{Name: "admins", Admin: true, Members: []string{"alice"}}}
+			state.Interfaces = append([]DeclarativeInterface(nil), valid.Interfaces...)
+			state.Peers = append([]DeclarativePeer(nil), valid.Peers...)
+			tt.modify(&state)
+			assert.ErrorIs(t, state.Validate(), ErrInvalidData)
+		})
+	}
+}
+
+func TestDeclarativeInterface_MergeToInterface(t *testing.T) {
+	iface := &Interface{
+		Identifier: "wg0",
+		KeyPair:    KeyPair{PrivateKey: "existing", PublicKey: "existing-pub"},
+		Backend:    "mikrotik",
+	}
+	state := DeclarativeInterface{
+		Identifier: "wg0",
+		ListenPort: 51820,
+		Addresses:  []string{"10.0.0.1/24"},
+		Disabled:   true,
+	}
+
+	require.NoError(t, state.MergeToInterface(iface))
+	assert.Equal(t, "existing", iface.PrivateKey, "an empty private key keeps the existing key")
+	assert.Equal(t, InterfaceBackend("mikrotik"), iface.Backend)
+	assert.Equal(t, "wg0", iface.DisplayName)
+	assert.Equal(t, InterfaceTypeServer, iface.Type)
+	assert.True(t, iface.IsDisabled())
+	assert.Equal(t, ManagedByDeclarative, iface.ManagedBy)
+
+	state.Type = "bridge"
+	assert.ErrorIs(t, state.MergeToInterface(iface), ErrInvalidData)
+	state.Type = ""
+	state.Addresses = []string{"invalid"}
+	assert.ErrorIs(t, state.MergeToInterface(iface), ErrInvalidData)
+}
+
+func TestDeclarativePeer_MergeToPeer(t *testing.T) {
+	publicKey := PublicKeyFromPrivateKey(testDeclarativePrivateKey)
+	addresses, err := CidrsFromString("10.0.0.2/32")
+	require.NoError(t, err)
+	peer := &Peer{
+		Identifier:   PeerIdentifier(publicKey),
+		PresharedKey: "psk",
+		Interface: PeerInterfaceConfig{
+			KeyPair:   KeyPair{PrivateKey: testDeclarativePrivateKey, PublicKey: publicKey},
+			Addresses: addresses,
+		},
+	}
+
+	state := DeclarativePeer{Interface: "wg0", PublicKey: publicKey, DisplayName: "laptop"}
+	require.NoError(t, state.MergeToPeer(peer))
+	assert.Equal(t, testDeclarativePrivateKey, peer.Interface.PrivateKey, "the existing private key is kept")
+	assert.Equal(t, PreSharedKey("psk"), peer.PresharedKey)
+	assert.Equal(t, addresses, peer.Interface.Addresses)
+	assert.Equal(t, "laptop", peer.DisplayName)
+	assert.Equal(t, ManagedByDeclarative, peer.ManagedBy)
+
+	state.Addresses = []string{"invalid"}
+	assert.ErrorIs(t, state.MergeToPeer(peer), ErrInvalidData)
+}
+
+func TestValidateManagedChange(t *testing.T) {
+	adminCtx := SetUserInfo(context.Background(), &ContextUserInfo{Id: "admin", IsAdmin: true})
+	systemCtx := SetUserInfo(context.Background(), SystemAdminContextUserInfo())
+	declarativeCtx := SetUserInfo(context.Background(), DeclarativeContextUserInfo())
+
+	assert.NoError(t, ValidateManagedChange(adminCtx, ""))
+	assert.ErrorIs(t, ValidateManagedChange(adminCtx, ManagedByDeclarative), ErrNoPermission)
+	assert.NoError(t, ValidateManagedChange(systemCtx, ManagedByDeclarative))
+	assert.NoError(t, ValidateManagedChange(declarativeCtx, ManagedByDeclarative))
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+const (
+	DnsRecordTypeA    DnsRecordType = "A"
+	DnsRecordTypeAAAA DnsRecordType = "AAAA"
+	DnsRecordTypePTR  DnsRecordType = "PTR"
+	DnsRecordTypeTXT  DnsRecordType = "TXT"
+)
+
+// maxDnsLabelLength is the maximum length of a single DNS label.
+const maxDnsLabelLength = 63
+
+type DnsRecordType string
+
+// DnsRecord is a single resource record of a DNS zone.
+type DnsRecord struct {
+	Name  string // fully qualified, lowercase, with a trailing dot
+	Type  DnsRecordType
+	Value string // the address for A and AAAA records, a fully qualified name for PTR records, the text for TXT records
+	Ttl   uint32
+}
+
+// Key identifies the record independent of its TTL.
+func (r DnsRecord) Key() string {
+	return r.Name + " " + string(r.Type) + " " + r.Value
+}
+
+// DnsUpdate is a set of changes to a single zone that is applied atomically.
+type DnsUpdate struct {
+	Zone    string
+	Deletes []DnsRecord // if the value of a record is empty, all records of its name and type are deleted
+	Adds    []DnsRecord
+}
+
+// IsEmpty returns true if the update does not change anything.
+func (u DnsUpdate) IsEmpty() bool {
+	return len(u.Deletes) == 0 && len(u.Adds) == 0
+}
+
+// DnsLabel converts the value to a valid DNS label. Letters are lowercased, all other characters except digits
+// are replaced by dashes. An empty string is returned if nothing usable remains.
+func DnsLabel(value string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(value) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	label := strings.TrimRight(b.String(), "-")
+	if len(label) > maxDnsLabelLength {
+		label = strings.TrimRight(label[:maxDnsLabelLength], "-")
+	}
+
+	return label
+}
+
+// FqdnDnsName returns the lowercase, fully qualified form of the name.
+func FqdnDnsName(name string) string {
+	return strings.ToLower(strings.Trim(name, ".")) + "."
+}
+
+// ReverseDnsName returns the name of the PTR record of the address in the in-addr.arpa or ip6.arpa domain.
+func ReverseDnsName(addr netip.Addr) string {
+	addr = addr.Unmap()
+	if addr.Is4() {
+		b := addr.As4()
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", b[3], b[2], b[1], b[0])
+	}
+
+	const hexDigits = "0123456789abcdef"
+	b := addr.As16()
+	var name strings.Builder
+	for i := len(b) - 1; i >= 0; i-- {
+		name.WriteByte(hexDigits[b[i]&0x0f])
+		name.WriteByte('.')
+		name.WriteByte(hexDigits[b[i]>>4])
+		name.WriteByte('.')
+	}
+	name.WriteString("ip6.arpa.")
+
+	return name.String()
+}
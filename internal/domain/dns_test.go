@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDnsLabel(t *testing.T) {
+	assert.Equal(t, "alice-s-laptop", DnsLabel("Alice's  Laptop!"))
+	assert.Equal(t, "alice-example-com", DnsLabel("alice@example.com"))
+	assert.Equal(t, "", DnsLabel("---"))
+	assert.Equal(t, strings.Repeat("a", 63), DnsLabel(strings.Repeat("a", 100)))
+}
+
+func TestReverseDnsName(t *testing.T) {
+	assert.Equal(t, "2.0.0.10.in-addr.arpa.", ReverseDnsName(netip.MustParseAddr("10.0.0.2")))
+	assert.Equal(t, "2.0.0.10.in-addr.arpa.", ReverseDnsName(netip.MustParseAddr("::ffff:10.0.0.2")))
+	assert.Equal(t,
+		"2.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa.",
+		ReverseDnsName(netip.MustParseAddr("fd00::2")))
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DownloadTypeConfig DownloadType = "config"
+	DownloadTypeQrCode DownloadType = "qr"
+)
+
+const (
+	DownloadTokenMinTTL = 1 * time.Minute
+	DownloadTokenMaxTTL = 30 * 24 * time.Hour
+
+	DownloadTokenMaxUses = 100
+)
+
+type DownloadTokenIdentifier string
+
+type DownloadType string
+
+// DownloadToken grants access to the configuration of a single peer without authentication.
+// The token can be used a limited number of times until it expires or gets revoked.
+type DownloadToken struct {
+	BaseModel
+
+	Identifier     DownloadTokenIdentifier `gorm:"primaryKey;column:identifier"`
+	PeerIdentifier PeerIdentifier          `gorm:"index;column:peer_identifier"`
+	Type           DownloadType            `gorm:"column:type"`  // config file or QR code
+	Style          string                  `gorm:"column:style"` // the configuration style, e.g. wgquick or raw
+
+	MaxUses    int        `gorm:"column:max_uses"`     // number of allowed downloads
+	Uses       int        `gorm:"column:uses"`         // number of downloads so far
+	ExpiresAt  time.Time  `gorm:"column:expires_at"`   // the token can not be used after this time
+	LastUsedAt *time.Time `gorm:"column:last_used_at"` // time of the last download
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`   // set if an admin revoked the token
+	RevokedBy  string     `gorm:"column:revoked_by"`
+}
+
+// DownloadTokenOptions are the user supplied settings of a new download token.
+type DownloadTokenOptions struct {
+	Type    DownloadType
+	Style   string
+	MaxUses int
+	TTL     time.Duration
+}
+
+// Validate checks the options and applies defaults for empty values.
+func (o *DownloadTokenOptions) Validate() error {
+	switch o.Type {
+	case "":
+		o.Type = DownloadTypeConfig
+	case DownloadTypeConfig, DownloadTypeQrCode:
+	default:
+		return fmt.Errorf("unknown download type %s: %w", o.Type, ErrInvalidData)
+	}
+
+	if o.Style == "" {
+		o.Style = ConfigStyleWgQuick
+	}
+	if !IsValidConfigStyle(o.Style) {
+		return fmt.Errorf("unknown configuration style %s: %w", o.Style, ErrInvalidData)
+	}
+
+	if o.MaxUses == 0 {
+		o.MaxUses = 1
+	}
+	if o.MaxUses < 0 || o.MaxUses > DownloadTokenMaxUses {
+		return fmt.Errorf("max uses must be between 1 and %d: %w", DownloadTokenMaxUses, ErrInvalidData)
+	}
+
+	if o.TTL == 0 {
+		o.TTL = 24 * time.Hour
+	}
+	if o.TTL < DownloadTokenMinTTL || o.TTL > DownloadTokenMaxTTL {
+		return fmt.Errorf("ttl must be between %s and %s: %w", DownloadTokenMinTTL, DownloadTokenMaxTTL,
+			ErrInvalidData)
+	}
+
+	return nil
+}
+
+// IsRevoked returns true if the token has been revoked by an admin.
+func (t *DownloadToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+// IsExhausted returns true if all downloads of the token have been used up.
+func (t *DownloadToken) IsExhausted() bool {
+	return t.Uses >= t.MaxUses
+}
+
+// IsActive returns true if the token can still be used at the given time.
+func (t *DownloadToken) IsActive(now time.Time) bool {
+	return !t.IsRevoked() && !t.IsExhausted() && now.Before(t.ExpiresAt)
+}
+
+// Use records a download. An error is returned if the token is no longer active.
+func (t *DownloadToken) Use(now time.Time) error {
+	switch {
+	case t.IsRevoked():
+		return fmt.Errorf("download link has been revoked: %w", ErrNoPermission)
+	case t.IsExhausted():
+		return fmt.Errorf("download link has already been used: %w", ErrNoPermission)
+	case !now.Before(t.ExpiresAt):
+		return fmt.Errorf("download link has expired: %w", ErrNoPermission)
+	}
+
+	t.Uses++
+	t.LastUsedAt = &now
+
+	return nil
+}
+
+// Revoke invalidates the token.
+func (t *DownloadToken) Revoke(admin UserIdentifier) {
+	now := time.Now()
+	t.RevokedAt = &now
+	t.RevokedBy = string(admin)
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadTokenOptions_Validate(t *testing.T) {
+	opts := DownloadTokenOptions{}
+	require.NoError(t, opts.Validate())
+	assert.Equal(t, DownloadTypeConfig, opts.Type)
+	assert.Equal(t, ConfigStyleWgQuick, opts.Style)
+	assert.Equal(t, 1, opts.MaxUses)
+	assert.Equal(t, 24*time.Hour, opts.TTL)
+
+	assert.ErrorIs(t, (&DownloadTokenOptions{Type: "zip"}).Validate(), ErrInvalidData)
+	assert.ErrorIs(t, (&DownloadTokenOptions{MaxUses: -1}).Validate(), ErrInvalidData)
+	assert.ErrorIs(t, (&DownloadTokenOptions{MaxUses: DownloadTokenMaxUses + 1}).Validate(), ErrInvalidData)
+	assert.ErrorIs(t, (&DownloadTokenOptions{TTL: time.Second}).Validate(), ErrInvalidData)
+	assert.ErrorIs(t, (&DownloadTokenOptions{TTL: DownloadTokenMaxTTL + time.Hour}).Validate(), ErrInvalidData)
+}
+
+func TestDownloadToken_Use(t *testing.T) {
+	now := time.Now()
+	token := DownloadToken{MaxUses: 2, ExpiresAt: now.Add(time.Hour)}
+
+	require.NoError(t, token.Use(now))
+	assert.True(t, token.IsActive(now))
+	require.NoError(t, token.Use(now))
+	assert.Equal(t, 2, token.Uses)
+	assert.Equal(t, &now, token.LastUsedAt)
+
+	assert.False(t, token.IsActive(now))
+	assert.ErrorIs(t, token.Use(now), ErrNoPermission)
+	assert.Equal(t, 2, token.Uses)
+}
+
+func TestDownloadToken_Use_ExpiredOrRevoked(t *testing.T) {
+	now := time.Now()
+
+	expired := DownloadToken{MaxUses: 1, ExpiresAt: now.Add(-time.Minute)}
+	assert.ErrorIs(t, expired.Use(now), ErrNoPermission)
+
+	revoked := DownloadToken{MaxUses: 1, ExpiresAt: now.Add(time.Hour)}
+	revoked.Revoke("admin")
+	assert.True(t, revoked.IsRevoked())
+	assert.Equal(t, "admin", revoked.RevokedBy)
+	assert.ErrorIs(t, revoked.Use(now), ErrNoPermission)
+	assert.Equal(t, 0, revoked.Uses)
+}
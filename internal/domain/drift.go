@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DriftMissing DriftType = "missing" // the object is stored in the database, but not present on the backend
+	DriftExtra   DriftType = "extra"   // the object is present on the backend, but not stored in the database
+	DriftChanged DriftType = "changed" // the object differs between the database and the backend
+)
+
+const (
+	DriftObjectInterface DriftObjectType = "interface"
+	DriftObjectPeer      DriftObjectType = "peer"
+)
+
+type DriftType string
+
+type DriftObjectType string
+
+// DriftField is a setting that differs between the database and the backend.
+type DriftField struct {
+	Name     string
+	Expected string // the value stored in the database
+	Actual   string // the value found on the backend
+}
+
+// DriftItem is an interface or peer that differs between the database and the backend.
+type DriftItem struct {
+	Type       DriftType
+	ObjectType DriftObjectType
+	Identifier string
+	Fields     []DriftField // only set for changed objects
+}
+
+// DriftReport contains the differences between the database and the backend for one interface.
+type DriftReport struct {
+	InterfaceIdentifier InterfaceIdentifier
+	Backend             InterfaceBackend
+	CheckedAt           time.Time
+	Items               []DriftItem
+	Error               string // set if the backend state could not be loaded
+	Healed              bool   // true if the database state has been re-applied after the check
+}
+
+// HasDrift returns true if the backend state differs from the database.
+func (r *DriftReport) HasDrift() bool {
+	return len(r.Items) > 0
+}
+
+// Count returns the number of items of the given type.
+func (r *DriftReport) Count(t DriftType) int {
+	count := 0
+	for _, item := range r.Items {
+		if item.Type == t {
+			count++
+		}
+	}
+	return count
+}
+
+// CompareInterfaceState returns the settings of the physical interface that differ from the stored interface.
+func CompareInterfaceState(expected *Interface, actual *PhysicalInterface) []DriftField {
+	var fields []DriftField
+	fields = appendDriftField(fields, "PublicKey", expected.PublicKey, actual.PublicKey)
+	fields = appendDriftField(fields, "ListenPort", strconv.Itoa(expected.ListenPort),
+		strconv.Itoa(actual.ListenPort))
+	fields = appendDriftField(fields, "Addresses", sortedCidrs(expected.Addresses), sortedCidrs(actual.Addresses))
+	if expected.Mtu != 0 { // zero means that the MTU is chosen by the backend
+		fields = appendDriftField(fields, "Mtu", strconv.Itoa(expected.Mtu), strconv.Itoa(actual.Mtu))
+	}
+	fields = appendDriftField(fields, "FirewallMark", strconv.FormatUint(uint64(expected.FirewallMark), 10),
+		strconv.FormatUint(uint64(actual.FirewallMark), 10))
+
+	return fields
+}
+
+// ComparePeerState returns the settings of the physical peer that differ from the expected peer. Endpoints are
+// only compared if the expected endpoint is an IP address, as backends report the resolved address of host names.
+// Preshared keys are only compared if the backend reports them, and their values are not part of the result.
+func ComparePeerState(expected, actual *PhysicalPeer) []DriftField {
+	var fields []DriftField
+	if host, _, err := net.SplitHostPort(expected.Endpoint); err == nil {
+		if _, err := netip.ParseAddr(host); err == nil {
+			fields = appendDriftField(fields, "Endpoint", expected.Endpoint, actual.Endpoint)
+		}
+	}
+	fields = appendDriftField(fields, "AllowedIPs", sortedCidrs(expected.AllowedIPs), sortedCidrs(actual.AllowedIPs))
+	fields = appendDriftField(fields, "PersistentKeepalive", strconv.Itoa(expected.PersistentKeepalive),
+		strconv.Itoa(actual.PersistentKeepalive))
+	if actual.PresharedKey != "" && expected.PresharedKey != actual.PresharedKey {
+		fields = append(fields, DriftField{Name: "PresharedKey", Expected: "(hidden)", Actual: "(hidden)"})
+	}
+
+	return fields
+}
+
+func appendDriftField(fields []DriftField, name, expected, actual string) []DriftField {
+	if expected == actual {
+		return fields
+	}
+	return append(fields, DriftField{Name: name, Expected: expected, Actual: actual})
+}
+
+func sortedCidrs(cidrs []Cidr) string {
+	values := CidrsToStringSlice(cidrs)
+	slices.Sort(values)
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareInterfaceState(t *testing.T) {
+	addrs, err := CidrsFromString("10.0.0.1/24,fd00::1/64")
+	require.NoError(t, err)
+
+	expected := &Interface{
+		Identifier: "wg0",
+		KeyPair:    KeyPair{PublicKey: "pub"},
+		ListenPort: 51820,
+		Addresses:  addrs,
+	}
+	actual := &PhysicalInterface{
+		Identifier: "wg0",
+		KeyPair:    KeyPair{PublicKey: "pub"},
+		ListenPort: 51820,
+		Addresses:  []Cidr{addrs[1], addrs[0]}, // order does not matter
+		Mtu:        1420,                       // the MTU is chosen by the backend
+	}
+	assert.Empty(t, CompareInterfaceState(expected, actual))
+
+	actual.ListenPort = 51821
+	fields := CompareInterfaceState(expected, actual)
+	require.Len(t, fields, 1)
+	assert.Equal(t, DriftField{Name: "ListenPort", Expected: "51820", Actual: "51821"}, fields[0])
+}
+
+func TestComparePeerState(t *testing.T) {
+	allowedIPs, err := CidrsFromString("10.0.0.2/32")
+	require.NoError(t, err)
+
+	expected := &PhysicalPeer{
+		Identifier:   "peer",
+		Endpoint:     "vpn.example.com:51820",
+		AllowedIPs:   allowedIPs,
+		PresharedKey: "psk",
+	}
+	actual := &PhysicalPeer{
+		Identifier: "peer",
+		Endpoint:   "192.0.2.1:51820", // resolved host name
+		AllowedIPs: allowedIPs,
+	}
+	assert.Empty(t, ComparePeerState(expected, actual), "host names and unknown preshared keys are not compared")
+
+	expected.Endpoint = "192.0.2.2:51820"
+	actual.PresharedKey = "other"
+	actual.PersistentKeepalive = 25
+	fields := ComparePeerState(expected, actual)
+	require.Len(t, fields, 3)
+	assert.Equal(t, "Endpoint", fields[0].Name)
+	assert.Equal(t, DriftField{Name: "PersistentKeepalive", Expected: "0", Actual: "25"}, fields[1])
+	assert.Equal(t, DriftField{Name: "PresharedKey", Expected: "(hidden)", Actual: "(hidden)"}, fields[2])
+}
+
+func TestDriftReport_Count(t *testing.T) {
+	report := DriftReport{Items: []DriftItem{
+		{Type: DriftMissing},
+		{Type: DriftChanged},
+		{Type: DriftMissing},
+	}}
+
+	assert.True(t, report.HasDrift())
+	assert.Equal(t, 2, report.Count(DriftMissing))
+	assert.Equal(t, 0, report.Count(DriftExtra))
+	assert.False(t, (&DriftReport{}).HasDrift())
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	InactivityActionNone    InactivityAction = ""
+	InactivityActionWarn    InactivityAction = "warn"
+	InactivityActionDisable InactivityAction = "disable"
+	InactivityActionDelete  InactivityAction = "delete"
+)
+
+const oneDay = 24 * time.Hour
+
+type InactivityAction string
+
+// InactivityPolicy defines how peers of an interface are handled if they did not connect for a long time.
+// A threshold of zero disables the corresponding step.
+type InactivityPolicy struct {
+	WarnAfterDays    int `json:"WarnAfterDays"`    // the owner is warned after this many days of inactivity
+	DisableAfterDays int `json:"DisableAfterDays"` // the peer is disabled after this many days of inactivity
+	DeleteAfterDays  int `json:"DeleteAfterDays"`  // the peer is deleted after this many days of inactivity
+}
+
+// IsEnabled returns true if at least one step of the policy is enabled.
+func (p *InactivityPolicy) IsEnabled() bool {
+	return p != nil && (p.WarnAfterDays > 0 || p.DisableAfterDays > 0 || p.DeleteAfterDays > 0)
+}
+
+// Validate checks that all thresholds are positive and in ascending order.
+func (p *InactivityPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.WarnAfterDays < 0 || p.DisableAfterDays < 0 || p.DeleteAfterDays < 0 {
+		return fmt.Errorf("inactivity thresholds must not be negative: %w", ErrInvalidData)
+	}
+
+	last := 0
+	for _, threshold := range []int{p.WarnAfterDays, p.DisableAfterDays, p.DeleteAfterDays} {
+		if threshold == 0 {
+			continue
+		}
+		if threshold <= last {
+			return fmt.Errorf("inactivity thresholds must be ascending (warn < disable < delete): %w",
+				ErrInvalidData)
+		}
+		last = threshold
+	}
+
+	return nil
+}
+
+// StaleAfterDays returns the lowest enabled threshold, or zero if the policy is disabled.
+func (p *InactivityPolicy) StaleAfterDays() int {
+	if p == nil {
+		return 0
+	}
+	for _, threshold := range []int{p.WarnAfterDays, p.DisableAfterDays, p.DeleteAfterDays} {
+		if threshold > 0 {
+			return threshold
+		}
+	}
+	return 0
+}
+
+// NextAction returns the most severe action that is due at the given point in time.
+// If warnings are enabled, a peer is never disabled or deleted before its owner has been warned. The full grace
+// period between the warning and the action is granted, even if the peer has been inactive for much longer.
+func (p *InactivityPolicy) NextAction(lastActivity time.Time, warned *time.Time, now time.Time) InactivityAction {
+	if !p.IsEnabled() {
+		return InactivityActionNone
+	}
+
+	inactive := now.Sub(lastActivity)
+	if p.WarnAfterDays > 0 {
+		warnAfter := time.Duration(p.WarnAfterDays) * oneDay
+		if warned == nil {
+			inactive = min(inactive, warnAfter)
+		} else {
+			inactive = min(inactive, now.Sub(*warned)+warnAfter)
+		}
+	}
+
+	switch {
+	case p.DeleteAfterDays > 0 && inactive >= time.Duration(p.DeleteAfterDays)*oneDay:
+		return InactivityActionDelete
+	case p.DisableAfterDays > 0 && inactive >= time.Duration(p.DisableAfterDays)*oneDay:
+		return InactivityActionDisable
+	case p.WarnAfterDays > 0 && inactive >= time.Duration(p.WarnAfterDays)*oneDay:
+		return InactivityActionWarn
+	default:
+		return InactivityActionNone
+	}
+}
+
+// DueDate returns the earliest point in time at which the given action will be executed, or nil if the action
+// is disabled by the policy.
+func (p *InactivityPolicy) DueDate(action InactivityAction, lastActivity time.Time, warned *time.Time) *time.Time {
+	if p == nil {
+		return nil
+	}
+
+	var threshold int
+	switch action {
+	case InactivityActionWarn:
+		threshold = p.WarnAfterDays
+	case InactivityActionDisable:
+		threshold = p.DisableAfterDays
+	case InactivityActionDelete:
+		threshold = p.DeleteAfterDays
+	}
+	if threshold == 0 {
+		return nil
+	}
+
+	due := lastActivity.Add(time.Duration(threshold) * oneDay)
+	if action != InactivityActionWarn && p.WarnAfterDays > 0 && warned != nil {
+		graceEnd := warned.Add(time.Duration(threshold-p.WarnAfterDays) * oneDay)
+		if graceEnd.After(due) {
+			due = graceEnd
+		}
+	}
+
+	return &due
+}
+
+// StalePeer is a report entry for a peer that did not connect for a long time.
+type StalePeer struct {
+	Peer           Peer
+	LastActivity   time.Time        // the last handshake, or the creation date if the peer never connected
+	NeverConnected bool             // true if no handshake has been recorded for the peer
+	InactiveDays   int              // number of full days since LastActivity
+	PendingAction  InactivityAction // the action that will be executed by the next inactivity check
+	DisableAt      *time.Time       // the point in time at which the peer will be disabled, if enabled by the policy
+	DeleteAt       *time.Time       // the point in time at which the peer will be deleted, if enabled by the policy
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInactivityPolicy_Validate(t *testing.T) {
+	var policy *InactivityPolicy
+	assert.NoError(t, policy.Validate())
+	assert.NoError(t, (&InactivityPolicy{WarnAfterDays: 30, DeleteAfterDays: 60}).Validate())
+	assert.NoError(t, (&InactivityPolicy{DisableAfterDays: 10}).Validate())
+
+	assert.ErrorIs(t, (&InactivityPolicy{WarnAfterDays: -1}).Validate(), ErrInvalidData)
+	assert.ErrorIs(t, (&InactivityPolicy{WarnAfterDays: 30, DisableAfterDays: 30}).Validate(), ErrInvalidData)
+	assert.ErrorIs(t, (&InactivityPolicy{DisableAfterDays: 60, DeleteAfterDays: 30}).Validate(), ErrInvalidData)
+}
+
+func TestInactivityPolicy_NextAction(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.Add(-time.Duration(days) * oneDay) }
+	policy := &InactivityPolicy{WarnAfterDays: 30, DisableAfterDays: 60, DeleteAfterDays: 90}
+
+	var disabled *InactivityPolicy
+	assert.Equal(t, InactivityActionNone, disabled.NextAction(daysAgo(365), nil, now))
+
+	assert.Equal(t, InactivityActionNone, policy.NextAction(daysAgo(29), nil, now))
+	assert.Equal(t, InactivityActionWarn, policy.NextAction(daysAgo(30), nil, now))
+
+	// without a warning, peers are never disabled or deleted
+	assert.Equal(t, InactivityActionWarn, policy.NextAction(daysAgo(365), nil, now))
+
+	// the full grace period applies after the warning
+	warned := daysAgo(10)
+	assert.Equal(t, InactivityActionWarn, policy.NextAction(daysAgo(365), &warned, now))
+	warned = daysAgo(30)
+	assert.Equal(t, InactivityActionDisable, policy.NextAction(daysAgo(365), &warned, now))
+	warned = daysAgo(60)
+	assert.Equal(t, InactivityActionDelete, policy.NextAction(daysAgo(365), &warned, now))
+
+	noWarning := &InactivityPolicy{DisableAfterDays: 60}
+	assert.Equal(t, InactivityActionDisable, noWarning.NextAction(daysAgo(60), nil, now))
+}
+
+func TestInactivityPolicy_DueDate(t *testing.T) {
+	lastActivity := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := &InactivityPolicy{WarnAfterDays: 30, DisableAfterDays: 60}
+
+	assert.Nil(t, policy.DueDate(InactivityActionDelete, lastActivity, nil))
+	assert.Equal(t, lastActivity.Add(60*oneDay), *policy.DueDate(InactivityActionDisable, lastActivity, nil))
+
+	warned := lastActivity.Add(100 * oneDay)
+	assert.Equal(t, warned.Add(30*oneDay), *policy.DueDate(InactivityActionDisable, lastActivity, &warned))
+}
+
+func TestPeer_LastActivity(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	peer := &Peer{BaseModel: BaseModel{CreatedAt: created}}
+
+	lastActivity, neverConnected := peer.LastActivity(nil)
+	assert.Equal(t, created, lastActivity)
+	assert.True(t, neverConnected)
+
+	handshake := created.Add(48 * time.Hour)
+	lastActivity, neverConnected = peer.LastActivity(&PeerStatus{LastHandshake: &handshake})
+	assert.Equal(t, handshake, lastActivity)
+	assert.False(t, neverConnected)
+}
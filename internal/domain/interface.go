@@ -60,6 +60,7 @@ type Interface struct {
 	DriverType        string           // the interface driver type (linux, software, ...)
 	Disabled          *time.Time       `gorm:"index"` // flag that specifies if the interface is enabled (up) or not (down)
 	DisabledReason    string           // the reason why the interface has been disabled
+	ManagedBy         string           `gorm:"column:managed_by"` // set if the interface is defined by the declarative state file
 
 	// Default settings for the peer, used for new peers, those settings will be published to ConfigOption options of
 	// the peer config
@@ -79,8 +80,20 @@ type Interface struct {
 	PeerDefPreDown  string // default action that is executed before the device is down
 	PeerDefPostDown string // default action that is executed after the device is down
 
+	PeerDefBandwidthLimit *BandwidthLimit `gorm:"serializer:json"` // default rate limit for peers without an own limit
+
 	// Self-provisioning access control
 	LdapAllowedUsers map[string][]UserIdentifier `gorm:"serializer:json"` // Materialised during LDAP sync, keyed by ProviderName
+
+	InactivityPolicy *InactivityPolicy       `gorm:"serializer:json"` // optional policy for peers that did not connect for a long time
+	AccessPolicy     *InterfaceAccessPolicy  `gorm:"serializer:json"` // optional forwarding policy (peer isolation and ACLs) for all peers
+	OnDemandPolicy   *OnDemandPolicy         `gorm:"serializer:json"` // optional on-demand rules for Apple .mobileconfig profiles
+	IpamPolicy       *IpamPolicy             `gorm:"serializer:json"` // optional address reservations and pools for the peer networks
+	PrefixDelegation *PrefixDelegationPolicy `gorm:"serializer:json"` // optional pool of routed IPv6 prefixes for new peers
+	NatPolicy        *NatPolicy              `gorm:"serializer:json"` // optional masquerading and forwarding rules for the outbound interface
+	AmneziaParams    *AmneziaParams          `gorm:"serializer:json"` // optional AmneziaWG obfuscation parameters, copied to all peers
+
+	MaintenanceSchedule *MaintenanceSchedule `gorm:"serializer:json"` // optional scheduled disable and enable actions
 }
 
 // IsUserAllowed returns true if the interface has no filter, or if the user is in the allowed list.
@@ -135,6 +148,50 @@ func (i *Interface) Validate() error {
 		i.PeerDefEndpoint = net.JoinHostPort(host, port)
 	}
 
+	if err := i.InactivityPolicy.Validate(); err != nil {
+		return fmt.Errorf("invalid inactivity policy: %w", err)
+	}
+
+	if err := i.PeerDefBandwidthLimit.Validate(); err != nil {
+		return fmt.Errorf("invalid default bandwidth limit: %w", err)
+	}
+
+	if err := i.AccessPolicy.Validate(); err != nil {
+		return fmt.Errorf("invalid access policy: %w", err)
+	}
+
+	if err := i.OnDemandPolicy.Validate(); err != nil {
+		return fmt.Errorf("invalid on-demand policy: %w", err)
+	}
+
+	var peerNetworks []Cidr
+	if i.IpamPolicy != nil && i.PeerDefNetworkStr != "" {
+		networks, err := CidrsFromString(i.PeerDefNetworkStr)
+		if err != nil {
+			return fmt.Errorf("invalid peer network: %w", ErrInvalidData)
+		}
+		peerNetworks = networks
+	}
+	if err := i.IpamPolicy.Validate(peerNetworks); err != nil {
+		return fmt.Errorf("invalid ipam policy: %w", err)
+	}
+
+	if err := i.PrefixDelegation.Validate(); err != nil {
+		return fmt.Errorf("invalid prefix delegation: %w", err)
+	}
+
+	if err := i.NatPolicy.Validate(); err != nil {
+		return fmt.Errorf("invalid nat policy: %w", err)
+	}
+
+	if err := i.AmneziaParams.Validate(); err != nil {
+		return fmt.Errorf("invalid amneziawg parameters: %w", err)
+	}
+
+	if err := i.MaintenanceSchedule.Validate(); err != nil {
+		return fmt.Errorf("invalid maintenance schedule: %w", err)
+	}
+
 	return nil
 }
 
@@ -179,6 +236,9 @@ func (i *Interface) GetAllowedIPs(peers []Peer) []Cidr {
 					allowedCidrs = append(allowedCidrs, extraIPs...)
 				}
 			}
+			if prefix, ok := peer.DelegatedPrefixCidr(); ok {
+				allowedCidrs = append(allowedCidrs, prefix)
+			}
 		}
 	case InterfaceTypeClient:
 		for _, peer := range peers {
@@ -252,6 +312,14 @@ func (i *Interface) CreateDefaultPeers() bool {
 	return true
 }
 
+// InterfaceCloneRequest describes the new interface that is created from the settings of an existing interface.
+type InterfaceCloneRequest struct {
+	Identifier  InterfaceIdentifier // optional, a free identifier is generated if empty
+	DisplayName string              // optional, the identifier is used if empty
+	Backend     InterfaceBackend    // optional, the backend of the source interface is used if empty
+	ClonePeers  bool                // if true, all peers are duplicated with fresh keys and addresses
+}
+
 type PhysicalInterface struct {
 	Identifier InterfaceIdentifier // device name, for example: wg0
 	KeyPair                        // private/public Key of the server interface
@@ -262,6 +330,8 @@ type PhysicalInterface struct {
 	Mtu          int    // the device MTU
 	FirewallMark uint32 // a firewall mark
 
+	AmneziaParams *AmneziaParams // AmneziaWG obfuscation parameters, only used by capable backends
+
 	DeviceUp bool // device status
 
 	ImportSource string // import source (wgctrl, file, ...)
@@ -327,6 +397,7 @@ func ConvertPhysicalInterface(pi *PhysicalInterface) *Interface {
 		PeerDefPostUp:              "",
 		PeerDefPreDown:             "",
 		PeerDefPostDown:            "",
+		AmneziaParams:              pi.AmneziaParams.Clone(),
 	}
 
 	if pi.GetExtras() == nil {
@@ -364,6 +435,7 @@ func MergeToPhysicalInterface(pi *PhysicalInterface, i *Interface) {
 	pi.ListenPort = i.ListenPort
 	pi.Mtu = i.Mtu
 	pi.FirewallMark = i.FirewallMark
+	pi.AmneziaParams = i.AmneziaParams.Clone()
 	pi.DeviceUp = !i.IsDisabled()
 	pi.Addresses = i.Addresses
 
@@ -0,0 +1,340 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// IpRange is an inclusive range of IP addresses of a single address family.
+type IpRange struct {
+	From netip.Addr
+	To   netip.Addr
+}
+
+// ParseIpRange parses a single address (10.0.0.1), a network (10.0.0.0/28) or an address range
+// in the form first-last (10.0.0.10-10.0.0.20).
+func ParseIpRange(str string) (IpRange, error) {
+	str = strings.TrimSpace(str)
+
+	switch {
+	case strings.Contains(str, "/"):
+		prefix, err := netip.ParsePrefix(str)
+		if err != nil {
+			return IpRange{}, fmt.Errorf("invalid network %s: %w", str, ErrInvalidData)
+		}
+		return IpRangeFromPrefix(prefix), nil
+	case strings.Contains(str, "-"):
+		fromStr, toStr, _ := strings.Cut(str, "-")
+		from, err := netip.ParseAddr(strings.TrimSpace(fromStr))
+		if err != nil {
+			return IpRange{}, fmt.Errorf("invalid range start %s: %w", fromStr, ErrInvalidData)
+		}
+		to, err := netip.ParseAddr(strings.TrimSpace(toStr))
+		if err != nil {
+			return IpRange{}, fmt.Errorf("invalid range end %s: %w", toStr, ErrInvalidData)
+		}
+		r := IpRange{From: from.Unmap(), To: to.Unmap()}
+		if !r.IsValid() {
+			return IpRange{}, fmt.Errorf("invalid range %s: %w", str, ErrInvalidData)
+		}
+		return r, nil
+	default:
+		addr, err := netip.ParseAddr(str)
+		if err != nil {
+			return IpRange{}, fmt.Errorf("invalid address %s: %w", str, ErrInvalidData)
+		}
+		return IpRange{From: addr.Unmap(), To: addr.Unmap()}, nil
+	}
+}
+
+// IpRangeFromPrefix returns the range of all addresses in the given network, including the network
+// and broadcast address.
+func IpRangeFromPrefix(prefix netip.Prefix) IpRange {
+	masked := prefix.Masked()
+	last := CidrFromPrefix(masked).BroadcastAddr()
+
+	return IpRange{From: masked.Addr(), To: netip.MustParseAddr(last.Addr)}
+}
+
+// IsValid returns true if both ends belong to the same address family and the range is not reversed.
+func (r IpRange) IsValid() bool {
+	return r.From.IsValid() && r.To.IsValid() && r.From.BitLen() == r.To.BitLen() && !r.To.Less(r.From)
+}
+
+// Contains returns true if the address is part of the range.
+func (r IpRange) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.BitLen() == r.From.BitLen() && r.From.Compare(addr) <= 0 && addr.Compare(r.To) <= 0
+}
+
+// Intersect returns the addresses that are part of both ranges. The second return value is false
+// if the ranges do not overlap.
+func (r IpRange) Intersect(other IpRange) (IpRange, bool) {
+	if r.From.BitLen() != other.From.BitLen() {
+		return IpRange{}, false
+	}
+
+	from := r.From
+	if from.Less(other.From) {
+		from = other.From
+	}
+	to := r.To
+	if other.To.Less(to) {
+		to = other.To
+	}
+	if to.Less(from) {
+		return IpRange{}, false
+	}
+
+	return IpRange{From: from, To: to}, true
+}
+
+// Size returns the number of addresses in the range. IPv6 ranges can exceed 64 bits.
+func (r IpRange) Size() *big.Int {
+	from, to := r.From.As16(), r.To.As16()
+	size := new(big.Int).Sub(new(big.Int).SetBytes(to[:]), new(big.Int).SetBytes(from[:]))
+
+	return size.Add(size, big.NewInt(1))
+}
+
+func (r IpRange) String() string {
+	if r.From == r.To {
+		return r.From.String()
+	}
+	return r.From.String() + "-" + r.To.String()
+}
+
+// SubtractIpRanges returns the parts of base that are not covered by any of the blocked ranges, in ascending order.
+func SubtractIpRanges(base IpRange, blocked []IpRange) []IpRange {
+	overlapping := make([]IpRange, 0, len(blocked))
+	for _, b := range blocked {
+		if r, ok := base.Intersect(b); ok {
+			overlapping = append(overlapping, r)
+		}
+	}
+	slices.SortFunc(overlapping, func(a, b IpRange) int {
+		return a.From.Compare(b.From)
+	})
+
+	var free []IpRange
+	next := base.From
+	for _, b := range overlapping {
+		if b.To.Less(next) {
+			continue // already covered by a previous range
+		}
+		if next.Less(b.From) {
+			free = append(free, IpRange{From: next, To: b.From.Prev()})
+		}
+		if b.To == base.To {
+			return free
+		}
+		next = b.To.Next()
+	}
+
+	return append(free, IpRange{From: next, To: base.To})
+}
+
+// SumIpRanges returns the total number of addresses in the given ranges.
+func SumIpRanges(ranges []IpRange) *big.Int {
+	total := new(big.Int)
+	for _, r := range ranges {
+		total.Add(total, r.Size())
+	}
+	return total
+}
+
+// UnassignableIpRanges returns the addresses of the network that are never handed out to peers:
+// the network address and, for IPv4 networks larger than /31, the broadcast address.
+func UnassignableIpRanges(network Cidr) []IpRange {
+	prefix := network.Prefix().Masked()
+	netRange := IpRangeFromPrefix(prefix)
+	if (prefix.Addr().Is4() && prefix.Bits() >= 31) || prefix.Bits() == 128 {
+		return nil
+	}
+
+	unassignable := []IpRange{{From: netRange.From, To: netRange.From}}
+	if prefix.Addr().Is4() {
+		unassignable = append(unassignable, IpRange{From: netRange.To, To: netRange.To})
+	}
+
+	return unassignable
+}
+
+// FirstFreeAddr returns the lowest address of the candidate ranges that is not blocked.
+func FirstFreeAddr(candidates, blocked []IpRange) (netip.Addr, bool) {
+	for _, candidate := range candidates {
+		if free := SubtractIpRanges(candidate, blocked); len(free) > 0 {
+			return free[0].From, true
+		}
+	}
+
+	return netip.Addr{}, false
+}
+
+// IpamPolicy contains the IP address management settings of an interface.
+type IpamPolicy struct {
+	Reservations []IpReservation `json:"Reservations"` // ranges that are never allocated automatically
+	Pools        []IpPool        `json:"Pools"`        // named ranges that can be selected when a peer is created
+}
+
+// IpReservation excludes addresses from the automatic allocation, for example gateways or printers.
+type IpReservation struct {
+	Range       string `json:"Range"` // single address, network or first-last range
+	Description string `json:"Description"`
+}
+
+// IpPool is a named set of address ranges. Addresses of pools are only allocated if the pool is
+// explicitly selected.
+type IpPool struct {
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	Ranges      []string `json:"Ranges"` // single addresses, networks or first-last ranges
+}
+
+// Validate checks all ranges of the policy. Pools must be part of the given peer networks and must not overlap.
+func (p *IpamPolicy) Validate(networks []Cidr) error {
+	if p == nil {
+		return nil
+	}
+
+	for i := range p.Reservations {
+		p.Reservations[i].Range = strings.TrimSpace(p.Reservations[i].Range)
+		if _, err := ParseIpRange(p.Reservations[i].Range); err != nil {
+			return fmt.Errorf("invalid reservation: %w", err)
+		}
+	}
+
+	if len(p.Pools) > 0 && len(networks) == 0 {
+		return fmt.Errorf("ip pools require a peer network: %w", ErrInvalidData)
+	}
+
+	names := make(map[string]struct{}, len(p.Pools))
+	var poolRanges []IpRange
+	for i := range p.Pools {
+		pool := &p.Pools[i]
+		pool.Name = strings.TrimSpace(pool.Name)
+		if pool.Name == "" {
+			return fmt.Errorf("ip pool without name: %w", ErrInvalidData)
+		}
+		if _, exists := names[pool.Name]; exists {
+			return fmt.Errorf("duplicate ip pool %s: %w", pool.Name, ErrInvalidData)
+		}
+		names[pool.Name] = struct{}{}
+
+		if len(pool.Ranges) == 0 {
+			return fmt.Errorf("ip pool %s has no ranges: %w", pool.Name, ErrInvalidData)
+		}
+		for j := range pool.Ranges {
+			pool.Ranges[j] = strings.TrimSpace(pool.Ranges[j])
+			r, err := ParseIpRange(pool.Ranges[j])
+			if err != nil {
+				return fmt.Errorf("invalid range in ip pool %s: %w", pool.Name, err)
+			}
+			if !rangeInNetworks(r, networks) {
+				return fmt.Errorf("range %s of ip pool %s is outside of the peer networks: %w", r, pool.Name,
+					ErrInvalidData)
+			}
+			for _, other := range poolRanges {
+				if _, overlaps := r.Intersect(other); overlaps {
+					return fmt.Errorf("range %s of ip pool %s overlaps with another pool: %w", r, pool.Name,
+						ErrInvalidData)
+				}
+			}
+			poolRanges = append(poolRanges, r)
+		}
+	}
+
+	return nil
+}
+
+func rangeInNetworks(r IpRange, networks []Cidr) bool {
+	for _, network := range networks {
+		netRange := IpRangeFromPrefix(network.Prefix())
+		if netRange.Contains(r.From) && netRange.Contains(r.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReservedRanges returns the parsed reservations. Invalid entries are skipped.
+func (p *IpamPolicy) ReservedRanges() []IpRange {
+	if p == nil {
+		return nil
+	}
+
+	ranges := make([]IpRange, 0, len(p.Reservations))
+	for _, reservation := range p.Reservations {
+		if r, err := ParseIpRange(reservation.Range); err == nil {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// PoolRanges returns the parsed ranges of all pools.
+func (p *IpamPolicy) PoolRanges() []IpRange {
+	if p == nil {
+		return nil
+	}
+
+	var ranges []IpRange
+	for _, pool := range p.Pools {
+		ranges = append(ranges, pool.IpRanges()...)
+	}
+	return ranges
+}
+
+// FindPool returns the pool with the given name.
+func (p *IpamPolicy) FindPool(name string) (*IpPool, error) {
+	if p != nil {
+		for i := range p.Pools {
+			if p.Pools[i].Name == name {
+				return &p.Pools[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unknown ip pool %s: %w", name, ErrInvalidData)
+}
+
+// IpRanges returns the parsed ranges of the pool. Invalid entries are skipped.
+func (p IpPool) IpRanges() []IpRange {
+	ranges := make([]IpRange, 0, len(p.Ranges))
+	for _, str := range p.Ranges {
+		if r, err := ParseIpRange(str); err == nil {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// IpamAddress is an address that is assigned to a peer or an interface.
+type IpamAddress struct {
+	Address             netip.Addr
+	PeerIdentifier      PeerIdentifier      // empty for interface addresses
+	InterfaceIdentifier InterfaceIdentifier // empty for peer addresses
+	DisplayName         string              // only set for peers of the inspected interface
+}
+
+// IpamPoolStatus is the utilization of a pool within a single network.
+type IpamPoolStatus struct {
+	Name   string
+	Ranges []IpRange
+	Size   *big.Int
+	Free   *big.Int
+}
+
+// IpamNetworkStatus is the utilization of a single peer network of an interface.
+type IpamNetworkStatus struct {
+	Network    Cidr
+	Size       *big.Int // all addresses of the network, including network and broadcast address
+	Free       *big.Int // addresses that can still be allocated automatically or from a pool
+	Used       []IpamAddress
+	Reserved   []IpRange
+	FreeRanges []IpRange
+	Pools      []IpamPoolStatus
+}
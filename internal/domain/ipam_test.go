@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustRange(t *testing.T, str string) IpRange {
+	r, err := ParseIpRange(str)
+	require.NoError(t, err)
+	return r
+}
+
+func TestParseIpRange(t *testing.T) {
+	r := mustRange(t, "10.0.0.5")
+	assert.Equal(t, "10.0.0.5", r.String())
+	assert.Equal(t, int64(1), r.Size().Int64())
+
+	r = mustRange(t, "10.0.0.17/28")
+	assert.Equal(t, "10.0.0.16-10.0.0.31", r.String())
+	assert.Equal(t, int64(16), r.Size().Int64())
+
+	r = mustRange(t, " 10.0.0.10 - 10.0.0.20 ")
+	assert.True(t, r.Contains(netip.MustParseAddr("10.0.0.15")))
+	assert.False(t, r.Contains(netip.MustParseAddr("10.0.0.21")))
+	assert.False(t, r.Contains(netip.MustParseAddr("fd00::15")))
+
+	r = mustRange(t, "fd00::/64")
+	assert.Equal(t, "18446744073709551616", r.Size().String())
+
+	for _, invalid := range []string{"", "10.0.0", "10.0.0.20-10.0.0.10", "10.0.0.1-fd00::1", "10.0.0.0/33"} {
+		_, err := ParseIpRange(invalid)
+		assert.ErrorIs(t, err, ErrInvalidData, invalid)
+	}
+}
+
+func TestSubtractIpRanges(t *testing.T) {
+	base := mustRange(t, "10.0.0.0/28")
+
+	free := SubtractIpRanges(base, []IpRange{
+		mustRange(t, "10.0.0.0"),
+		mustRange(t, "10.0.0.5-10.0.0.7"),
+		mustRange(t, "10.0.0.6"),
+		mustRange(t, "10.0.0.15"),
+		mustRange(t, "10.0.1.0/24"),
+		mustRange(t, "fd00::/64"),
+	})
+	require.Len(t, free, 2)
+	assert.Equal(t, "10.0.0.1-10.0.0.4", free[0].String())
+	assert.Equal(t, "10.0.0.8-10.0.0.14", free[1].String())
+	assert.Equal(t, int64(11), SumIpRanges(free).Int64())
+
+	assert.Empty(t, SubtractIpRanges(base, []IpRange{mustRange(t, "10.0.0.0/24")}))
+	assert.Equal(t, []IpRange{base}, SubtractIpRanges(base, nil))
+}
+
+func TestFirstFreeAddr(t *testing.T) {
+	network, _ := CidrFromString("10.0.0.0/30")
+	candidates := []IpRange{IpRangeFromPrefix(network.Prefix())}
+	blocked := UnassignableIpRanges(network)
+
+	addr, ok := FirstFreeAddr(candidates, blocked)
+	require.True(t, ok)
+	assert.Equal(t, "10.0.0.1", addr.String())
+
+	_, ok = FirstFreeAddr(candidates, append(blocked, mustRange(t, "10.0.0.1-10.0.0.2")))
+	assert.False(t, ok)
+
+	v6, _ := CidrFromString("fd00::/64")
+	assert.Len(t, UnassignableIpRanges(v6), 1)
+	single, _ := CidrFromString("10.0.0.1/32")
+	assert.Empty(t, UnassignableIpRanges(single))
+}
+
+func TestIpamPolicy_Validate(t *testing.T) {
+	networks, err := CidrsFromString("10.0.0.0/24,fd00::/64")
+	require.NoError(t, err)
+
+	policy := &IpamPolicy{
+		Reservations: []IpReservation{{Range: " 10.0.0.1 ", Description: "gateway"}},
+		Pools: []IpPool{
+			{Name: " printers ", Ranges: []string{"10.0.0.200-10.0.0.250"}},
+			{Name: "servers", Ranges: []string{"10.0.0.16/28", "fd00::1000-fd00::1fff"}},
+		},
+	}
+	require.NoError(t, policy.Validate(networks))
+	assert.Equal(t, "10.0.0.1", policy.Reservations[0].Range)
+	assert.Equal(t, "printers", policy.Pools[0].Name)
+	assert.Len(t, policy.PoolRanges(), 3)
+
+	pool, err := policy.FindPool("servers")
+	require.NoError(t, err)
+	assert.Len(t, pool.IpRanges(), 2)
+	_, err = policy.FindPool("unknown")
+	assert.ErrorIs(t, err, ErrInvalidData)
+
+	invalid := []IpamPolicy{
+		{Reservations: []IpReservation{{Range: "x"}}},
+		{Pools: []IpPool{{Name: "", Ranges: []string{"10.0.0.5"}}}},
+		{Pools: []IpPool{{Name: "a", Ranges: nil}}},
+		{Pools: []IpPool{{Name: "a", Ranges: []string{"10.0.1.5"}}}},
+		{Pools: []IpPool{{Name: "a", Ranges: []string{"10.0.0.5"}}, {Name: "a", Ranges: []string{"10.0.0.6"}}}},
+		{Pools: []IpPool{{Name: "a", Ranges: []string{"10.0.0.0/28"}}, {Name: "b", Ranges: []string{"10.0.0.10"}}}},
+	}
+	for i := range invalid {
+		assert.ErrorIs(t, invalid[i].Validate(networks), ErrInvalidData, "policy %d", i)
+	}
+
+	assert.ErrorIs(t, (&IpamPolicy{Pools: []IpPool{{Name: "a", Ranges: []string{"10.0.0.5"}}}}).Validate(nil),
+		ErrInvalidData)
+	assert.NoError(t, (*IpamPolicy)(nil).Validate(nil))
+}
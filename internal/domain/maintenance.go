@@ -0,0 +1,273 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	MaintenanceActionDisable MaintenanceAction = "disable"
+	MaintenanceActionEnable  MaintenanceAction = "enable"
+)
+
+const (
+	MaintenanceRecurrenceNone    MaintenanceRecurrence = ""
+	MaintenanceRecurrenceDaily   MaintenanceRecurrence = "daily"
+	MaintenanceRecurrenceWeekly  MaintenanceRecurrence = "weekly"
+	MaintenanceRecurrenceMonthly MaintenanceRecurrence = "monthly"
+)
+
+type MaintenanceAction string
+
+type MaintenanceRecurrence string
+
+// MaintenanceWindow is a scheduled action on an interface. A disable action with an end time describes a window,
+// the interface is disabled at Start and enabled again at End. Recurring windows repeat Start and End with the
+// given interval, monthly windows keep the day of the month.
+type MaintenanceWindow struct {
+	Action      MaintenanceAction     `json:"Action"`
+	Start       time.Time             `json:"Start"`
+	End         *time.Time            `json:"End,omitempty"` // optional, only allowed for disable actions
+	Recurrence  MaintenanceRecurrence `json:"Recurrence,omitempty"`
+	Description string                `json:"Description,omitempty"`
+
+	// state of the maintenance check, carried over as long as the window is not modified
+	LastRun  *time.Time `json:"LastRun,omitempty"`  // time of the last executed action, older actions are skipped
+	Notified *time.Time `json:"Notified,omitempty"` // start of the occurrence the peer owners have been notified about
+}
+
+// MaintenanceSchedule contains the scheduled maintenance windows of an interface.
+type MaintenanceSchedule struct {
+	Windows []MaintenanceWindow `json:"Windows"`
+}
+
+// IsEmpty returns true if the schedule does not contain any windows.
+func (s *MaintenanceSchedule) IsEmpty() bool {
+	return s == nil || len(s.Windows) == 0
+}
+
+// Validate checks all windows of the schedule.
+func (s *MaintenanceSchedule) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	for i, w := range s.Windows {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("window %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// CarryState copies the state of unchanged windows from the old schedule. New or modified windows only execute
+// actions after the given point in time, so that past occurrences are not replayed.
+func (s *MaintenanceSchedule) CarryState(old *MaintenanceSchedule, now time.Time) {
+	if s == nil {
+		return
+	}
+
+	for i := range s.Windows {
+		s.Windows[i].LastRun = &now
+		s.Windows[i].Notified = nil
+		if old == nil {
+			continue
+		}
+		for _, o := range old.Windows {
+			if s.Windows[i].sameTiming(&o) {
+				s.Windows[i].LastRun = o.LastRun
+				s.Windows[i].Notified = o.Notified
+				break
+			}
+		}
+	}
+}
+
+// Validate checks the action, the recurrence and the time range of the window.
+func (w *MaintenanceWindow) Validate() error {
+	switch w.Action {
+	case MaintenanceActionDisable, MaintenanceActionEnable:
+	default:
+		return fmt.Errorf("invalid action %q: %w", w.Action, ErrInvalidData)
+	}
+
+	if w.Start.IsZero() {
+		return fmt.Errorf("start time is required: %w", ErrInvalidData)
+	}
+
+	var period time.Duration
+	switch w.Recurrence {
+	case MaintenanceRecurrenceNone:
+	case MaintenanceRecurrenceDaily:
+		period = oneDay
+	case MaintenanceRecurrenceWeekly:
+		period = 7 * oneDay
+	case MaintenanceRecurrenceMonthly:
+		period = 28 * oneDay
+		if w.Start.Day() > 28 {
+			return fmt.Errorf("monthly windows must start on one of the first 28 days: %w", ErrInvalidData)
+		}
+	default:
+		return fmt.Errorf("invalid recurrence %q: %w", w.Recurrence, ErrInvalidData)
+	}
+
+	if w.End != nil {
+		if w.Action != MaintenanceActionDisable {
+			return fmt.Errorf("end time is only allowed for disable actions: %w", ErrInvalidData)
+		}
+		if !w.End.After(w.Start) {
+			return fmt.Errorf("end time must be after the start time: %w", ErrInvalidData)
+		}
+		if period > 0 && w.duration() >= period {
+			return fmt.Errorf("window must be shorter than the %s recurrence: %w", w.Recurrence, ErrInvalidData)
+		}
+	}
+
+	return nil
+}
+
+// DueAction returns the most recent action of the window that has not been executed yet.
+// If multiple actions have been missed, only the latest one is returned.
+func (w *MaintenanceWindow) DueAction(now time.Time) (MaintenanceAction, time.Time, bool) {
+	var action MaintenanceAction
+	var at time.Time
+
+	if n, ok := w.lastOccurrence(now); ok {
+		action, at = w.Action, w.occurrence(n)
+	}
+	if w.End != nil {
+		// the end of the latest window that is already over
+		if n, ok := w.lastOccurrence(now.Add(-w.duration())); ok {
+			if end := w.occurrence(n).Add(w.duration()); action == "" || end.After(at) {
+				action, at = MaintenanceActionEnable, end
+			}
+		}
+	}
+
+	if action == "" || (w.LastRun != nil && !at.After(*w.LastRun)) {
+		return "", time.Time{}, false
+	}
+
+	return action, at, true
+}
+
+// NextStart returns the start of the next occurrence after the given point in time,
+// or nil if the window does not occur again.
+func (w *MaintenanceWindow) NextStart(now time.Time) *time.Time {
+	n, ok := w.lastOccurrence(now)
+	switch {
+	case !ok:
+		next := w.Start
+		return &next
+	case w.Recurrence == MaintenanceRecurrenceNone:
+		return nil
+	}
+
+	next := w.occurrence(n + 1)
+	return &next
+}
+
+// NextEnd returns the end of the current or next occurrence, or nil if the window has no end.
+func (w *MaintenanceWindow) NextEnd(now time.Time) *time.Time {
+	if w.End == nil {
+		return nil
+	}
+
+	if n, ok := w.lastOccurrence(now); ok {
+		if end := w.occurrence(n).Add(w.duration()); end.After(now) {
+			return &end
+		}
+	}
+	next := w.NextStart(now)
+	if next == nil {
+		return nil
+	}
+	end := next.Add(w.duration())
+	return &end
+}
+
+// NotificationDue returns the start of the next disable action if it lies within the given lead time and the
+// peer owners have not been notified about it yet.
+func (w *MaintenanceWindow) NotificationDue(now time.Time, lead time.Duration) (time.Time, bool) {
+	if w.Action != MaintenanceActionDisable || lead <= 0 {
+		return time.Time{}, false
+	}
+
+	next := w.NextStart(now)
+	if next == nil || next.Sub(now) > lead {
+		return time.Time{}, false
+	}
+	if w.Notified != nil && w.Notified.Equal(*next) {
+		return time.Time{}, false
+	}
+
+	return *next, true
+}
+
+func (w *MaintenanceWindow) duration() time.Duration {
+	if w.End == nil {
+		return 0
+	}
+	return w.End.Sub(w.Start)
+}
+
+func (w *MaintenanceWindow) occurrence(n int) time.Time {
+	switch w.Recurrence {
+	case MaintenanceRecurrenceDaily:
+		return w.Start.AddDate(0, 0, n)
+	case MaintenanceRecurrenceWeekly:
+		return w.Start.AddDate(0, 0, 7*n)
+	case MaintenanceRecurrenceMonthly:
+		return w.Start.AddDate(0, n, 0)
+	default:
+		return w.Start
+	}
+}
+
+// lastOccurrence returns the index of the latest occurrence that starts at or before the given point in time.
+func (w *MaintenanceWindow) lastOccurrence(t time.Time) (int, bool) {
+	if t.Before(w.Start) {
+		return 0, false
+	}
+
+	// estimate the index, then correct it for daylight saving time and different month lengths
+	var n int
+	switch w.Recurrence {
+	case MaintenanceRecurrenceDaily:
+		n = int(t.Sub(w.Start) / oneDay)
+	case MaintenanceRecurrenceWeekly:
+		n = int(t.Sub(w.Start) / (7 * oneDay))
+	case MaintenanceRecurrenceMonthly:
+		local := t.In(w.Start.Location())
+		n = (local.Year()-w.Start.Year())*12 + int(local.Month()) - int(w.Start.Month())
+	default:
+		return 0, true
+	}
+	for n > 0 && w.occurrence(n).After(t) {
+		n--
+	}
+	for !w.occurrence(n + 1).After(t) {
+		n++
+	}
+
+	return n, true
+}
+
+func (w *MaintenanceWindow) sameTiming(o *MaintenanceWindow) bool {
+	if w.Action != o.Action || w.Recurrence != o.Recurrence || !w.Start.Equal(o.Start) {
+		return false
+	}
+	if w.End == nil || o.End == nil {
+		return w.End == nil && o.End == nil
+	}
+	return w.End.Equal(*o.End)
+}
+
+// MaintenanceNotice announces an upcoming maintenance window to the owners of the affected peers.
+type MaintenanceNotice struct {
+	Interface Interface
+	Window    MaintenanceWindow
+	Start     time.Time  // start of the upcoming occurrence
+	End       *time.Time // end of the upcoming occurrence, nil if the interface is not enabled automatically
+}
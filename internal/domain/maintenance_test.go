@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceSchedule_Validate(t *testing.T) {
+	var schedule *MaintenanceSchedule
+	assert.NoError(t, schedule.Validate())
+
+	start := time.Date(2024, 1, 7, 2, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	valid := &MaintenanceSchedule{Windows: []MaintenanceWindow{
+		{Action: MaintenanceActionDisable, Start: start, End: &end, Recurrence: MaintenanceRecurrenceWeekly},
+		{Action: MaintenanceActionEnable, Start: start},
+	}}
+	assert.NoError(t, valid.Validate())
+
+	tooLong := start.Add(25 * time.Hour)
+	before := start.Add(-time.Hour)
+	tests := []MaintenanceWindow{
+		{Action: "reboot", Start: start},
+		{Action: MaintenanceActionDisable},
+		{Action: MaintenanceActionDisable, Start: start, Recurrence: "yearly"},
+		{Action: MaintenanceActionDisable, Start: start, End: &before},
+		{Action: MaintenanceActionEnable, Start: start, End: &end},
+		{Action: MaintenanceActionDisable, Start: start, End: &tooLong, Recurrence: MaintenanceRecurrenceDaily},
+		{Action: MaintenanceActionDisable, Start: start.AddDate(0, 0, 24), Recurrence: MaintenanceRecurrenceMonthly},
+	}
+	for _, tt := range tests {
+		s := MaintenanceSchedule{Windows: []MaintenanceWindow{tt}}
+		assert.ErrorIs(t, s.Validate(), ErrInvalidData, "window %+v", tt)
+	}
+}
+
+func TestMaintenanceWindow_DueAction(t *testing.T) {
+	// 2024-01-07 is a Sunday
+	start := time.Date(2024, 1, 7, 2, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	created := start.Add(-time.Hour)
+	w := MaintenanceWindow{
+		Action:     MaintenanceActionDisable,
+		Start:      start,
+		End:        &end,
+		Recurrence: MaintenanceRecurrenceWeekly,
+		LastRun:    &created,
+	}
+
+	_, _, due := w.DueAction(start.Add(-time.Minute))
+	assert.False(t, due)
+
+	action, at, due := w.DueAction(start.Add(time.Minute))
+	require.True(t, due)
+	assert.Equal(t, MaintenanceActionDisable, action)
+	assert.Equal(t, start, at)
+
+	w.LastRun = &at
+	_, _, due = w.DueAction(start.Add(time.Hour))
+	assert.False(t, due, "the action must only be executed once")
+
+	action, at, due = w.DueAction(end.Add(time.Minute))
+	require.True(t, due)
+	assert.Equal(t, MaintenanceActionEnable, action)
+	assert.Equal(t, end, at)
+
+	// the next week, after a missed start, the latest action wins
+	w.LastRun = &at
+	action, at, due = w.DueAction(start.AddDate(0, 0, 7).Add(time.Hour))
+	require.True(t, due)
+	assert.Equal(t, MaintenanceActionDisable, action)
+	assert.Equal(t, start.AddDate(0, 0, 7), at)
+	action, _, due = w.DueAction(end.AddDate(0, 0, 14).Add(time.Hour))
+	require.True(t, due)
+	assert.Equal(t, MaintenanceActionEnable, action)
+}
+
+func TestMaintenanceWindow_NextStart(t *testing.T) {
+	start := time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC)
+	once := MaintenanceWindow{Action: MaintenanceActionEnable, Start: start}
+	assert.Equal(t, &start, once.NextStart(start.Add(-time.Hour)))
+	assert.Nil(t, once.NextStart(start))
+
+	monthly := MaintenanceWindow{Action: MaintenanceActionDisable, Start: start, Recurrence: "monthly"}
+	assert.Equal(t, time.Date(2024, 3, 15, 22, 0, 0, 0, time.UTC),
+		*monthly.NextStart(time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2024, 2, 15, 22, 0, 0, 0, time.UTC),
+		*monthly.NextStart(time.Date(2024, 2, 15, 21, 0, 0, 0, time.UTC)))
+
+	end := start.Add(3 * time.Hour)
+	daily := MaintenanceWindow{Action: MaintenanceActionDisable, Start: start, End: &end, Recurrence: "daily"}
+	assert.Equal(t, end.AddDate(0, 0, 2), *daily.NextEnd(start.AddDate(0, 0, 2).Add(time.Hour)))
+	assert.Equal(t, end.AddDate(0, 0, 3), *daily.NextEnd(end.AddDate(0, 0, 2)))
+}
+
+func TestMaintenanceWindow_NotificationDue(t *testing.T) {
+	start := time.Date(2024, 1, 7, 2, 0, 0, 0, time.UTC)
+	w := MaintenanceWindow{Action: MaintenanceActionDisable, Start: start, Recurrence: "weekly"}
+
+	_, due := w.NotificationDue(start.Add(-25*time.Hour), 24*time.Hour)
+	assert.False(t, due)
+
+	next, due := w.NotificationDue(start.Add(-23*time.Hour), 24*time.Hour)
+	require.True(t, due)
+	assert.Equal(t, start, next)
+
+	w.Notified = &next
+	_, due = w.NotificationDue(start.Add(-time.Hour), 24*time.Hour)
+	assert.False(t, due, "owners are notified once per occurrence")
+
+	next, due = w.NotificationDue(start.AddDate(0, 0, 7).Add(-time.Hour), 24*time.Hour)
+	require.True(t, due)
+	assert.Equal(t, start.AddDate(0, 0, 7), next)
+
+	enable := MaintenanceWindow{Action: MaintenanceActionEnable, Start: start}
+	_, due = enable.NotificationDue(start.Add(-time.Hour), 24*time.Hour)
+	assert.False(t, due)
+}
+
+func TestMaintenanceSchedule_CarryState(t *testing.T) {
+	start := time.Date(2024, 1, 7, 2, 0, 0, 0, time.UTC)
+	lastRun := start
+	old := &MaintenanceSchedule{Windows: []MaintenanceWindow{
+		{Action: MaintenanceActionDisable, Start: start, Recurrence: "weekly", LastRun: &lastRun, Notified: &lastRun},
+	}}
+
+	now := start.AddDate(0, 0, 3)
+	updated := &MaintenanceSchedule{Windows: []MaintenanceWindow{
+		{Action: MaintenanceActionDisable, Start: start, Recurrence: "weekly", Description: "changed"},
+		{Action: MaintenanceActionDisable, Start: start, Recurrence: "daily"},
+	}}
+	updated.CarryState(old, now)
+
+	assert.Equal(t, &lastRun, updated.Windows[0].LastRun)
+	assert.Equal(t, &lastRun, updated.Windows[0].Notified)
+	assert.Equal(t, &now, updated.Windows[1].LastRun)
+	assert.Nil(t, updated.Windows[1].Notified)
+}
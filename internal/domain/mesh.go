@@ -0,0 +1,245 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+)
+
+const (
+	MeshTopologyFullMesh    MeshTopology = "full-mesh"
+	MeshTopologyHubAndSpoke MeshTopology = "hub-and-spoke"
+)
+
+type MeshIdentifier string
+
+type MeshTopology string
+
+// Mesh connects multiple interfaces, for example the interfaces of branch routers, with each other. The peer
+// entries of the links between the nodes are generated from the node interfaces and are not stored as peers.
+type Mesh struct {
+	BaseModel
+
+	Identifier          MeshIdentifier `gorm:"primaryKey;column:identifier"`
+	DisplayName         string         `gorm:"column:display_name"`
+	Topology            MeshTopology   `gorm:"column:topology"`
+	PersistentKeepalive int            `gorm:"column:persistent_keepalive"` // keep-alive interval of all peer entries
+	Nodes               []MeshNode     `gorm:"serializer:json"`
+}
+
+// MeshNode is an interface that is part of a mesh.
+type MeshNode struct {
+	InterfaceIdentifier InterfaceIdentifier // the interface of the node, it can belong to any backend
+	Hub                 bool                // only used for hub-and-spoke meshes
+	Endpoint            string              // the address other nodes connect to (host or host:port), defaults to PeerDefEndpoint
+	Networks            []string            // the subnets that are routed to the node, e.g. the LAN of a branch router
+}
+
+// MeshPeer is a peer entry that is generated for the link between two nodes of a mesh.
+type MeshPeer struct {
+	MeshIdentifier      MeshIdentifier
+	InterfaceIdentifier InterfaceIdentifier // the node interface that the peer entry is created on
+	RemoteInterface     InterfaceIdentifier // the node interface that the peer entry points to
+	PublicKey           string
+	Endpoint            string
+	AllowedIPs          []Cidr
+	PersistentKeepalive int
+}
+
+// Validate checks the mesh settings that do not depend on the node interfaces.
+func (m *Mesh) Validate() error {
+	if m.Identifier == "" {
+		return fmt.Errorf("identifier is required: %w", ErrInvalidData)
+	}
+	if m.PersistentKeepalive < 0 {
+		return fmt.Errorf("persistent keepalive must not be negative: %w", ErrInvalidData)
+	}
+	if len(m.Nodes) < 2 {
+		return fmt.Errorf("a mesh needs at least two nodes: %w", ErrInvalidData)
+	}
+
+	hubs := 0
+	for i, node := range m.Nodes {
+		if node.InterfaceIdentifier == "" {
+			return fmt.Errorf("node %d has no interface: %w", i, ErrInvalidData)
+		}
+		if m.NodeIndex(node.InterfaceIdentifier) != i {
+			return fmt.Errorf("interface %s is used by multiple nodes: %w", node.InterfaceIdentifier, ErrInvalidData)
+		}
+		if _, err := CidrsFromArray(node.Networks); err != nil {
+			return fmt.Errorf("invalid networks of node %s: %w", node.InterfaceIdentifier, ErrInvalidData)
+		}
+		if node.Hub {
+			hubs++
+		}
+	}
+
+	switch m.Topology {
+	case MeshTopologyFullMesh:
+	case MeshTopologyHubAndSpoke:
+		if hubs == 0 || hubs == len(m.Nodes) {
+			return fmt.Errorf("a hub-and-spoke mesh needs at least one hub and one spoke: %w", ErrInvalidData)
+		}
+	default:
+		return fmt.Errorf("unknown topology %q: %w", m.Topology, ErrInvalidData)
+	}
+
+	return nil
+}
+
+// NodeIndex returns the index of the node with the given interface, or -1 if the interface is not part of the mesh.
+func (m *Mesh) NodeIndex(id InterfaceIdentifier) int {
+	return slices.IndexFunc(m.Nodes, func(n MeshNode) bool {
+		return n.InterfaceIdentifier == id
+	})
+}
+
+// HasNode returns true if the given interface is part of the mesh.
+func (m *Mesh) HasNode(id InterfaceIdentifier) bool {
+	return m.NodeIndex(id) >= 0
+}
+
+// isLinked returns true if the two nodes are directly connected. In a hub-and-spoke mesh, spokes are only
+// connected to the hubs.
+func (m *Mesh) isLinked(a, b *MeshNode) bool {
+	if m.Topology == MeshTopologyHubAndSpoke {
+		return a.Hub || b.Hub
+	}
+	return true
+}
+
+// relayHub returns the hub that forwards the traffic between spokes. As WireGuard allows each network only once
+// per interface, all spoke-to-spoke traffic is routed through the first hub.
+func (m *Mesh) relayHub() InterfaceIdentifier {
+	if m.Topology != MeshTopologyHubAndSpoke {
+		return ""
+	}
+	for _, node := range m.Nodes {
+		if node.Hub {
+			return node.InterfaceIdentifier
+		}
+	}
+	return ""
+}
+
+// Peers generates the peer entries of all links of the mesh. Nodes without an entry in the interfaces map are
+// skipped, so that a missing interface does not break the links between the remaining nodes.
+func (m *Mesh) Peers(interfaces map[InterfaceIdentifier]*Interface) ([]MeshPeer, error) {
+	relay := m.relayHub()
+
+	var peers []MeshPeer
+	for i := range m.Nodes {
+		local := &m.Nodes[i]
+		if interfaces[local.InterfaceIdentifier] == nil {
+			continue
+		}
+
+		seen := make(map[string]InterfaceIdentifier)
+		for j := range m.Nodes {
+			remote := &m.Nodes[j]
+			remoteIface := interfaces[remote.InterfaceIdentifier]
+			if i == j || remoteIface == nil || !m.isLinked(local, remote) {
+				continue
+			}
+			if remoteIface.PublicKey == "" {
+				return nil, fmt.Errorf("interface %s has no public key: %w", remote.InterfaceIdentifier,
+					ErrInvalidData)
+			}
+
+			allowedIPs := nodeNetworks(remote, remoteIface)
+			if remote.InterfaceIdentifier == relay && !local.Hub {
+				// the other spokes are reachable through the relay hub
+				for k := range m.Nodes {
+					spoke := &m.Nodes[k]
+					if k == i || spoke.Hub || interfaces[spoke.InterfaceIdentifier] == nil {
+						continue
+					}
+					allowedIPs = append(allowedIPs, nodeNetworks(spoke, interfaces[spoke.InterfaceIdentifier])...)
+				}
+			}
+
+			for _, cidr := range allowedIPs {
+				if other, ok := seen[cidr.String()]; ok {
+					return nil, fmt.Errorf("network %s of node %s is also routed to node %s: %w", cidr,
+						remote.InterfaceIdentifier, other, ErrInvalidData)
+				}
+				seen[cidr.String()] = remote.InterfaceIdentifier
+			}
+
+			peers = append(peers, MeshPeer{
+				MeshIdentifier:      m.Identifier,
+				InterfaceIdentifier: local.InterfaceIdentifier,
+				RemoteInterface:     remote.InterfaceIdentifier,
+				PublicKey:           remoteIface.PublicKey,
+				Endpoint:            nodeEndpoint(remote, remoteIface),
+				AllowedIPs:          allowedIPs,
+				PersistentKeepalive: m.PersistentKeepalive,
+			})
+		}
+	}
+
+	return peers, nil
+}
+
+// nodeNetworks returns the tunnel addresses of the node interface and the networks behind the node.
+func nodeNetworks(node *MeshNode, iface *Interface) []Cidr {
+	networks := make([]Cidr, 0, len(iface.Addresses)+len(node.Networks))
+	for _, addr := range iface.Addresses {
+		networks = append(networks, addr.HostAddr())
+	}
+	extra, _ := CidrsFromArray(node.Networks)
+	for _, network := range extra {
+		networks = append(networks, network.NetworkAddr())
+	}
+	return networks
+}
+
+// nodeEndpoint returns the endpoint of the node. If no port is given, the listen port of the interface is used.
+func nodeEndpoint(node *MeshNode, iface *Interface) string {
+	endpoint := node.Endpoint
+	if endpoint == "" {
+		endpoint = iface.PeerDefEndpoint
+	}
+	if endpoint == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(endpoint); err == nil {
+		return endpoint
+	}
+	return net.JoinHostPort(endpoint, strconv.Itoa(iface.ListenPort))
+}
+
+// PeerName returns the name of the peer entry, it is used by backends that support named peers.
+func (p *MeshPeer) PeerName() string {
+	return fmt.Sprintf("mesh-%s-%s", p.MeshIdentifier, p.RemoteInterface)
+}
+
+// MergeToPhysicalPeer applies the generated settings to the physical peer.
+func (p *MeshPeer) MergeToPhysicalPeer(pp *PhysicalPeer) {
+	pp.Identifier = PeerIdentifier(p.PublicKey)
+	pp.PublicKey = p.PublicKey
+	pp.Endpoint = p.Endpoint
+	pp.AllowedIPs = p.AllowedIPs
+	pp.PersistentKeepalive = p.PersistentKeepalive
+
+	comment := fmt.Sprintf("managed by wg-portal mesh %s", p.MeshIdentifier)
+	switch pp.ImportSource {
+	case ControllerTypeMikrotik:
+		pp.SetExtras(MikrotikPeerExtras{
+			Name:            p.PeerName(),
+			Comment:         comment,
+			ClientEndpoint:  p.Endpoint,
+			ClientKeepalive: p.PersistentKeepalive,
+		})
+	case ControllerTypeLocal:
+		pp.SetExtras(LocalPeerExtras{})
+	case ControllerTypePfsense:
+		pp.SetExtras(PfsensePeerExtras{
+			Name:            p.PeerName(),
+			Comment:         comment,
+			ClientEndpoint:  p.Endpoint,
+			ClientKeepalive: p.PersistentKeepalive,
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func meshTestInterfaces(t *testing.T) map[InterfaceIdentifier]*Interface {
+	newIface := func(id InterfaceIdentifier, addr, endpoint string) *Interface {
+		cidr, err := CidrFromString(addr)
+		require.NoError(t, err)
+		kp, err := NewFreshKeypair()
+		require.NoError(t, err)
+		return &Interface{
+			Identifier:      id,
+			KeyPair:         kp,
+			ListenPort:      51820,
+			Addresses:       []Cidr{cidr},
+			PeerDefEndpoint: endpoint,
+		}
+	}
+
+	return map[InterfaceIdentifier]*Interface{
+		"hq":       newIface("hq", "10.100.0.1/24", "hq.example.com:51820"),
+		"branch-a": newIface("branch-a", "10.100.0.2/24", ""),
+		"branch-b": newIface("branch-b", "10.100.0.3/24", ""),
+	}
+}
+
+func meshPeer(t *testing.T, peers []MeshPeer, local, remote InterfaceIdentifier) *MeshPeer {
+	for i := range peers {
+		if peers[i].InterfaceIdentifier == local && peers[i].RemoteInterface == remote {
+			return &peers[i]
+		}
+	}
+	t.Fatalf("no peer for %s on %s", remote, local)
+	return nil
+}
+
+func TestMesh_Validate(t *testing.T) {
+	valid := func() *Mesh {
+		return &Mesh{
+			Identifier: "branches",
+			Topology:   MeshTopologyHubAndSpoke,
+			Nodes: []MeshNode{
+				{InterfaceIdentifier: "hq", Hub: true},
+				{InterfaceIdentifier: "branch-a", Networks: []string{"192.168.10.0/24"}},
+			},
+		}
+	}
+	require.NoError(t, valid().Validate())
+
+	tests := map[string]func(m *Mesh){
+		"no identifier":      func(m *Mesh) { m.Identifier = "" },
+		"unknown topology":   func(m *Mesh) { m.Topology = "ring" },
+		"negative keepalive": func(m *Mesh) { m.PersistentKeepalive = -1 },
+		"single node":        func(m *Mesh) { m.Nodes = m.Nodes[:1] },
+		"duplicate node":     func(m *Mesh) { m.Nodes[1].InterfaceIdentifier = "hq" },
+		"invalid network":    func(m *Mesh) { m.Nodes[1].Networks = []string{"192.168.10.0"} },
+		"no hub":             func(m *Mesh) { m.Nodes[0].Hub = false },
+		"no spoke":           func(m *Mesh) { m.Nodes[1].Hub = true },
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := valid()
+			modify(m)
+			err := m.Validate()
+			assert.True(t, errors.Is(err, ErrInvalidData), "got %v", err)
+		})
+	}
+}
+
+func TestMesh_Peers_FullMesh(t *testing.T) {
+	interfaces := meshTestInterfaces(t)
+	mesh := &Mesh{
+		Identifier:          "branches",
+		Topology:            MeshTopologyFullMesh,
+		PersistentKeepalive: 25,
+		Nodes: []MeshNode{
+			{InterfaceIdentifier: "hq", Networks: []string{"192.168.0.0/24"}},
+			{InterfaceIdentifier: "branch-a", Endpoint: "a.example.com", Networks: []string{"192.168.10.1/24"}},
+			{InterfaceIdentifier: "branch-b"},
+		},
+	}
+
+	peers, err := mesh.Peers(interfaces)
+	require.NoError(t, err)
+	assert.Len(t, peers, 6)
+
+	peer := meshPeer(t, peers, "branch-b", "branch-a")
+	assert.Equal(t, interfaces["branch-a"].PublicKey, peer.PublicKey)
+	assert.Equal(t, "a.example.com:51820", peer.Endpoint, "the listen port is added to endpoints without port")
+	assert.Equal(t, []string{"10.100.0.2/32", "192.168.10.0/24"}, CidrsToStringSlice(peer.AllowedIPs))
+	assert.Equal(t, 25, peer.PersistentKeepalive)
+
+	peer = meshPeer(t, peers, "branch-a", "hq")
+	assert.Equal(t, "hq.example.com:51820", peer.Endpoint, "the peer default endpoint is used as fallback")
+	assert.Equal(t, []string{"10.100.0.1/32", "192.168.0.0/24"}, CidrsToStringSlice(peer.AllowedIPs))
+
+	assert.Empty(t, meshPeer(t, peers, "hq", "branch-b").Endpoint)
+
+	// nodes of missing interfaces are skipped
+	delete(interfaces, "branch-b")
+	peers, err = mesh.Peers(interfaces)
+	require.NoError(t, err)
+	assert.Len(t, peers, 2)
+}
+
+func TestMesh_Peers_HubAndSpoke(t *testing.T) {
+	interfaces := meshTestInterfaces(t)
+	mesh := &Mesh{
+		Identifier: "branches",
+		Topology:   MeshTopologyHubAndSpoke,
+		Nodes: []MeshNode{
+			{InterfaceIdentifier: "hq", Hub: true, Networks: []string{"192.168.0.0/24"}},
+			{InterfaceIdentifier: "branch-a", Networks: []string{"192.168.10.0/24"}},
+			{InterfaceIdentifier: "branch-b", Networks: []string{"192.168.20.0/24"}},
+		},
+	}
+
+	peers, err := mesh.Peers(interfaces)
+	require.NoError(t, err)
+	assert.Len(t, peers, 4, "spokes are only linked to the hub")
+
+	peer := meshPeer(t, peers, "branch-a", "hq")
+	assert.Equal(t, []string{"10.100.0.1/32", "192.168.0.0/24", "10.100.0.3/32", "192.168.20.0/24"},
+		CidrsToStringSlice(peer.AllowedIPs), "other spokes are reached through the hub")
+
+	peer = meshPeer(t, peers, "hq", "branch-a")
+	assert.Equal(t, []string{"10.100.0.2/32", "192.168.10.0/24"}, CidrsToStringSlice(peer.AllowedIPs))
+
+	// overlapping networks can not be routed
+	mesh.Nodes[2].Networks = []string{"192.168.10.0/24"}
+	_, err = mesh.Peers(interfaces)
+	assert.ErrorIs(t, err, ErrInvalidData)
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	MobileConfigPlatformIos   MobileConfigPlatform = "ios"
+	MobileConfigPlatformMacOs MobileConfigPlatform = "macos"
+)
+
+// MobileConfigContentType is the MIME type that makes Apple devices offer the installation of a profile.
+const MobileConfigContentType = "application/x-apple-aspen-config"
+
+const (
+	OnDemandActionConnect    = "Connect"
+	OnDemandActionDisconnect = "Disconnect"
+
+	OnDemandInterfaceWifi     = "WiFi"
+	OnDemandInterfaceCellular = "Cellular"
+	OnDemandInterfaceEthernet = "Ethernet"
+)
+
+// MobileConfigPlatform is the Apple platform an .mobileconfig profile is generated for.
+// The WireGuard apps for iOS and macOS use different bundle identifiers, so a profile only works on one of them.
+type MobileConfigPlatform string
+
+// ParseMobileConfigPlatform validates the given platform name, an empty name defaults to iOS.
+func ParseMobileConfigPlatform(platform string) (MobileConfigPlatform, error) {
+	switch p := MobileConfigPlatform(strings.ToLower(strings.TrimSpace(platform))); p {
+	case "":
+		return MobileConfigPlatformIos, nil
+	case MobileConfigPlatformIos, MobileConfigPlatformMacOs:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown platform %s: %w", platform, ErrInvalidData)
+	}
+}
+
+// VpnSubType returns the bundle identifier of the WireGuard app for the platform.
+func (p MobileConfigPlatform) VpnSubType() string {
+	if p == MobileConfigPlatformMacOs {
+		return "com.wireguard.macos"
+	}
+	return "com.wireguard.ios"
+}
+
+// OnDemandPolicy defines when Apple devices activate the tunnel automatically.
+// It is stored per interface and embedded into the .mobileconfig profiles of all peers.
+type OnDemandPolicy struct {
+	TrustedSsids []string `json:"TrustedSsids"` // the tunnel is disconnected on these Wi-Fi networks
+	Wifi         bool     `json:"Wifi"`         // connect on all other Wi-Fi networks
+	Cellular     bool     `json:"Cellular"`     // connect on cellular networks, only used on iOS
+	Ethernet     bool     `json:"Ethernet"`     // connect on wired networks, only used on macOS
+}
+
+// OnDemandRule is a single entry of the OnDemandRules array of an Apple VPN payload.
+type OnDemandRule struct {
+	Action        string
+	InterfaceType string
+	Ssids         []string
+}
+
+// IsEnabled returns true if the tunnel should be activated on at least one network type.
+func (p *OnDemandPolicy) IsEnabled() bool {
+	return p != nil && (p.Wifi || p.Cellular || p.Ethernet)
+}
+
+// Validate checks the trusted SSIDs and removes empty entries.
+func (p *OnDemandPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	ssids := make([]string, 0, len(p.TrustedSsids))
+	for _, ssid := range p.TrustedSsids {
+		ssid = strings.TrimSpace(ssid)
+		if ssid == "" {
+			continue
+		}
+		if len(ssid) > 32 {
+			return fmt.Errorf("ssid %s is longer than 32 bytes: %w", ssid, ErrInvalidData)
+		}
+		ssids = append(ssids, ssid)
+	}
+	p.TrustedSsids = ssids
+
+	return nil
+}
+
+// Rules returns the on-demand rules for the given platform. Apple devices evaluate the rules in order,
+// so trusted networks are matched before the generic Wi-Fi rule.
+func (p *OnDemandPolicy) Rules(platform MobileConfigPlatform) []OnDemandRule {
+	if !p.IsEnabled() {
+		return nil
+	}
+
+	action := func(connect bool) string {
+		if connect {
+			return OnDemandActionConnect
+		}
+		return OnDemandActionDisconnect
+	}
+
+	rules := make([]OnDemandRule, 0, 3)
+	if len(p.TrustedSsids) > 0 {
+		rules = append(rules, OnDemandRule{
+			Action:        OnDemandActionDisconnect,
+			InterfaceType: OnDemandInterfaceWifi,
+			Ssids:         p.TrustedSsids,
+		})
+	}
+	rules = append(rules, OnDemandRule{Action: action(p.Wifi), InterfaceType: OnDemandInterfaceWifi})
+
+	switch platform {
+	case MobileConfigPlatformMacOs:
+		rules = append(rules, OnDemandRule{Action: action(p.Ethernet), InterfaceType: OnDemandInterfaceEthernet})
+	default:
+		rules = append(rules, OnDemandRule{Action: action(p.Cellular), InterfaceType: OnDemandInterfaceCellular})
+	}
+
+	return rules
+}
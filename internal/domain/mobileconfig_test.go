@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMobileConfigPlatform(t *testing.T) {
+	platform, err := ParseMobileConfigPlatform("")
+	require.NoError(t, err)
+	assert.Equal(t, MobileConfigPlatformIos, platform)
+
+	platform, err = ParseMobileConfigPlatform(" MacOS ")
+	require.NoError(t, err)
+	assert.Equal(t, MobileConfigPlatformMacOs, platform)
+	assert.Equal(t, "com.wireguard.macos", platform.VpnSubType())
+
+	_, err = ParseMobileConfigPlatform("android")
+	assert.ErrorIs(t, err, ErrInvalidData)
+}
+
+func TestOnDemandPolicy_Validate(t *testing.T) {
+	var nilPolicy *OnDemandPolicy
+	assert.NoError(t, nilPolicy.Validate())
+
+	p := &OnDemandPolicy{TrustedSsids: []string{" Office ", "", "Home"}}
+	require.NoError(t, p.Validate())
+	assert.Equal(t, []string{"Office", "Home"}, p.TrustedSsids)
+
+	p = &OnDemandPolicy{TrustedSsids: []string{strings.Repeat("x", 33)}}
+	assert.ErrorIs(t, p.Validate(), ErrInvalidData)
+}
+
+func TestOnDemandPolicy_Rules(t *testing.T) {
+	var nilPolicy *OnDemandPolicy
+	assert.Nil(t, nilPolicy.Rules(MobileConfigPlatformIos))
+	assert.Nil(t, (&OnDemandPolicy{TrustedSsids: []string{"Office"}}).Rules(MobileConfigPlatformIos))
+
+	p := &OnDemandPolicy{TrustedSsids: []string{"Office"}, Wifi: true, Cellular: true}
+
+	assert.Equal(t, []OnDemandRule{
+		{Action: OnDemandActionDisconnect, InterfaceType: OnDemandInterfaceWifi, Ssids: []string{"Office"}},
+		{Action: OnDemandActionConnect, InterfaceType: OnDemandInterfaceWifi},
+		{Action: OnDemandActionConnect, InterfaceType: OnDemandInterfaceCellular},
+	}, p.Rules(MobileConfigPlatformIos))
+
+	assert.Equal(t, []OnDemandRule{
+		{Action: OnDemandActionDisconnect, InterfaceType: OnDemandInterfaceWifi, Ssids: []string{"Office"}},
+		{Action: OnDemandActionConnect, InterfaceType: OnDemandInterfaceWifi},
+		{Action: OnDemandActionDisconnect, InterfaceType: OnDemandInterfaceEthernet},
+	}, p.Rules(MobileConfigPlatformMacOs))
+}
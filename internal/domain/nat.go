@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// NatPolicy defines the masquerading and forwarding rules between a server interface and an outbound interface of the
+// host. The rules are applied by capable controllers while the interface is up, they replace the usual
+// iptables commands in the PostUp and PostDown hooks.
+type NatPolicy struct {
+	OutboundInterface string `json:"OutboundInterface"` // the uplink of the host, for example eth0
+	Masquerade        bool   `json:"Masquerade"`        // masquerade peer traffic that leaves through the outbound interface
+	AllowForwarding   bool   `json:"AllowForwarding"`   // accept traffic from the peers to the outbound interface and the replies
+	IPv4              bool   `json:"IPv4"`              // apply the rules to IPv4 traffic
+	IPv6              bool   `json:"IPv6"`              // apply the rules to IPv6 traffic
+}
+
+// IsEnabled returns true if the policy contains at least one rule.
+func (p *NatPolicy) IsEnabled() bool {
+	return p != nil && (p.Masquerade || p.AllowForwarding) && (p.IPv4 || p.IPv6)
+}
+
+// Validate checks the outbound interface name and the address families of an enabled policy.
+func (p *NatPolicy) Validate() error {
+	if p == nil || (!p.Masquerade && !p.AllowForwarding) {
+		return nil
+	}
+
+	if !p.IPv4 && !p.IPv6 {
+		return fmt.Errorf("at least one address family must be selected: %w", ErrInvalidData)
+	}
+	if p.OutboundInterface == "" {
+		return fmt.Errorf("outbound interface is required: %w", ErrInvalidData)
+	}
+	if len(p.OutboundInterface) >= unix.IFNAMSIZ || strings.ContainsAny(p.OutboundInterface, " /:") {
+		return fmt.Errorf("invalid outbound interface %q: %w", p.OutboundInterface, ErrInvalidData)
+	}
+
+	return nil
+}
+
+// InterfaceNatRules are the masquerading and forwarding rules of an interface. They are applied by capable controllers.
+type InterfaceNatRules struct {
+	Interface InterfaceIdentifier
+	NatPolicy
+}
+
+// NewInterfaceNatRules returns the rules of the given interface. Client interfaces never get any rules.
+func NewInterfaceNatRules(iface *Interface) InterfaceNatRules {
+	rules := InterfaceNatRules{Interface: iface.Identifier}
+	if iface.NatPolicy != nil && iface.Type != InterfaceTypeClient {
+		rules.NatPolicy = *iface.NatPolicy
+	}
+
+	return rules
+}
+
+// IsEmpty returns true if no rule has to be applied.
+func (r InterfaceNatRules) IsEmpty() bool {
+	return !r.NatPolicy.IsEnabled()
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNatPolicy_Validate(t *testing.T) {
+	var policy *NatPolicy
+	assert.NoError(t, policy.Validate())
+	assert.False(t, policy.IsEnabled())
+
+	valid := &NatPolicy{OutboundInterface: "eth0", Masquerade: true, IPv4: true, IPv6: true}
+	assert.NoError(t, valid.Validate())
+	assert.True(t, valid.IsEnabled())
+
+	tests := []NatPolicy{
+		{Masquerade: true, IPv4: true},
+		{OutboundInterface: "eth0", AllowForwarding: true},
+		{OutboundInterface: "a-very-long-interface", Masquerade: true, IPv6: true},
+		{OutboundInterface: "eth0 eth1", Masquerade: true, IPv4: true},
+	}
+	for _, tt := range tests {
+		assert.ErrorIs(t, tt.Validate(), ErrInvalidData, "policy %+v", tt)
+	}
+}
+
+func TestNewInterfaceNatRules(t *testing.T) {
+	policy := &NatPolicy{OutboundInterface: "eth0", Masquerade: true, IPv4: true}
+
+	rules := NewInterfaceNatRules(&Interface{Identifier: "wg0", Type: InterfaceTypeServer, NatPolicy: policy})
+	assert.False(t, rules.IsEmpty())
+	assert.Equal(t, "eth0", rules.OutboundInterface)
+
+	rules = NewInterfaceNatRules(&Interface{Identifier: "wg1", Type: InterfaceTypeClient, NatPolicy: policy})
+	assert.True(t, rules.IsEmpty())
+}
@@ -53,6 +53,14 @@ type Peer struct {
 	ExpiresAt            *time.Time          `gorm:"column:expires_at"`         // expiry dates for peers
 	Notes                string              `form:"notes" binding:"omitempty"` // a note field for peers
 	AutomaticallyCreated bool                `gorm:"column:auto_created"`       // specifies if the peer was automatically created
+	AccessSchedule       *AccessSchedule     `gorm:"serializer:json"`           // optional time windows in which the peer is allowed to connect
+	ScheduleBlocked      *time.Time          `gorm:"column:schedule_blocked"`   // set while the peer is outside its access schedule
+	InactivityWarned     *time.Time          `gorm:"column:inactivity_warned"`  // set once the owner has been warned about the inactivity of the peer
+	BandwidthLimit       *BandwidthLimit     `gorm:"serializer:json"`           // optional rate limit, overrides the interface default
+	Acl                  *AccessControlList  `gorm:"serializer:json"`           // optional destination rules for traffic of the peer
+	DelegatedPrefix      string              `gorm:"column:delegated_prefix"`   // routed IPv6 prefix of the peer, part of the server side allowed IPs
+	DeletedAt            gorm.DeletedAt      `gorm:"index"`                     // set while the peer is in the recycle bin
+	ManagedBy            string              `gorm:"column:managed_by"`         // set if the peer is defined by the declarative state file
 
 	// Interface settings for the peer, used to generate the [interface] section in the peer config file
 	Interface PeerInterfaceConfig `gorm:"embedded"`
@@ -62,6 +70,54 @@ func (p *Peer) IsDisabled() bool {
 	return p.Disabled != nil
 }
 
+// IsDeleted returns true if the peer is in the recycle bin.
+func (p *Peer) IsDeleted() bool {
+	return p.DeletedAt.Valid
+}
+
+// IsScheduleBlocked returns true if the peer is currently outside its access schedule.
+// In contrast to IsDisabled, this state is managed automatically and does not change the Disabled flag.
+func (p *Peer) IsScheduleBlocked() bool {
+	return p.ScheduleBlocked != nil
+}
+
+// EffectiveAccessSchedule returns the access schedule of the peer. If the peer has no own schedule,
+// the schedule of the given owner is used.
+func (p *Peer) EffectiveAccessSchedule(owner *User) *AccessSchedule {
+	if !p.AccessSchedule.IsEmpty() {
+		return p.AccessSchedule
+	}
+	if owner != nil && !owner.AccessSchedule.IsEmpty() {
+		return owner.AccessSchedule
+	}
+	return nil
+}
+
+// UpdateScheduleState updates the schedule blocked state for the given point in time.
+// It returns true if the state has changed.
+func (p *Peer) UpdateScheduleState(schedule *AccessSchedule, now time.Time) bool {
+	blocked := !schedule.IsAllowed(now)
+	if blocked == p.IsScheduleBlocked() {
+		return false
+	}
+
+	if blocked {
+		p.ScheduleBlocked = &now
+	} else {
+		p.ScheduleBlocked = nil
+	}
+	return true
+}
+
+// LastActivity returns the time of the last handshake of the peer. If the peer never connected,
+// the creation date of the peer is returned instead.
+func (p *Peer) LastActivity(status *PeerStatus) (lastActivity time.Time, neverConnected bool) {
+	if status == nil || status.LastHandshake == nil || status.LastHandshake.Before(p.CreatedAt) {
+		return p.CreatedAt, status == nil || status.LastHandshake == nil
+	}
+	return *status.LastHandshake, false
+}
+
 func (p *Peer) IsExpired() bool {
 	if p.ExpiresAt == nil {
 		return false
@@ -84,8 +140,21 @@ func (p *Peer) CheckAliveAddress() string {
 	return ""
 }
 
+// DelegatedPrefixCidr returns the delegated prefix of the peer, or false if the peer has no valid prefix.
+func (p *Peer) DelegatedPrefixCidr() (Cidr, bool) {
+	if p.DelegatedPrefix == "" {
+		return Cidr{}, false
+	}
+	prefix, err := CidrFromString(p.DelegatedPrefix)
+	if err != nil {
+		return Cidr{}, false
+	}
+	return prefix, true
+}
+
 func (p *Peer) CopyCalculatedAttributes(src *Peer) {
 	p.BaseModel = src.BaseModel
+	p.ScheduleBlocked = src.ScheduleBlocked
 }
 
 func (p *Peer) GetConfigFileName() string {
@@ -109,6 +178,11 @@ func (p *Peer) GetConfigFileName() string {
 	return filename
 }
 
+// GetMobileConfigFileName returns the file name of the Apple configuration profile of the peer.
+func (p *Peer) GetMobileConfigFileName() string {
+	return strings.TrimSuffix(p.GetConfigFileName(), ".conf") + ".mobileconfig"
+}
+
 func (p *Peer) ApplyInterfaceDefaults(in *Interface) {
 	p.Endpoint.TrySetValue(in.PeerDefEndpoint)
 	p.EndpointPublicKey.TrySetValue(in.PublicKey)
@@ -123,6 +197,7 @@ func (p *Peer) ApplyInterfaceDefaults(in *Interface) {
 	p.Interface.PostUp.TrySetValue(in.PeerDefPostUp)
 	p.Interface.PreDown.TrySetValue(in.PeerDefPreDown)
 	p.Interface.PostDown.TrySetValue(in.PeerDefPostDown)
+	p.Interface.AmneziaParams = in.AmneziaParams.Clone()
 }
 
 func (p *Peer) GenerateDisplayName(prefix string) {
@@ -165,6 +240,8 @@ type PeerInterfaceConfig struct {
 	PostUp   ConfigOption[string] `gorm:"embedded;embeddedPrefix:iface_post_up_"`   // action that is executed after the device is up
 	PreDown  ConfigOption[string] `gorm:"embedded;embeddedPrefix:iface_pre_down_"`  // action that is executed before the device is down
 	PostDown ConfigOption[string] `gorm:"embedded;embeddedPrefix:iface_post_down_"` // action that is executed after the device is down
+
+	AmneziaParams *AmneziaParams `gorm:"serializer:json;column:iface_amnezia_params"` // obfuscation parameters of the interface, not editable per peer
 }
 
 func (p *PeerInterfaceConfig) AddressStr() string {
@@ -340,6 +417,9 @@ func MergeToPhysicalPeer(pp *PhysicalPeer, p *Peer) {
 		}
 		extraAllowedIPs, _ := CidrsFromString(p.ExtraAllowedIPsStr)
 		pp.AllowedIPs = append(allowedIPs, extraAllowedIPs...)
+		if prefix, ok := p.DelegatedPrefixCidr(); ok {
+			pp.AllowedIPs = append(pp.AllowedIPs, prefix)
+		}
 	case InterfaceTypeServer: // this means that the corresponding interface in wgportal is a client interface
 		allowedIPs, _ := CidrsFromString(p.AllowedIPsStr.GetValue())
 		extraAllowedIPs, _ := CidrsFromString(p.ExtraAllowedIPsStr)
@@ -353,6 +433,9 @@ func MergeToPhysicalPeer(pp *PhysicalPeer, p *Peer) {
 		}
 		extraAllowedIPs, _ := CidrsFromString(p.ExtraAllowedIPsStr)
 		pp.AllowedIPs = append(allowedIPs, extraAllowedIPs...)
+		if prefix, ok := p.DelegatedPrefixCidr(); ok {
+			pp.AllowedIPs = append(pp.AllowedIPs, prefix)
+		}
 		pp.Endpoint = p.Endpoint.GetValue()
 		pp.PersistentKeepalive = p.PersistentKeepalive.GetValue()
 	}
@@ -364,7 +447,7 @@ func MergeToPhysicalPeer(pp *PhysicalPeer, p *Peer) {
 			Name:            p.DisplayName,
 			Comment:         p.Notes,
 			IsResponder:     p.Interface.Type == InterfaceTypeClient,
-			Disabled:        p.IsDisabled(),
+			Disabled:        p.IsDisabled() || p.IsScheduleBlocked(),
 			ClientEndpoint:  p.Endpoint.GetValue(),
 			ClientAddress:   CidrsToString(p.Interface.Addresses),
 			ClientDns:       p.Interface.DnsStr.GetValue(),
@@ -373,7 +456,7 @@ func MergeToPhysicalPeer(pp *PhysicalPeer, p *Peer) {
 		pp.SetExtras(extras)
 	case ControllerTypeLocal:
 		extras := LocalPeerExtras{
-			Disabled: p.IsDisabled(),
+			Disabled: p.IsDisabled() || p.IsScheduleBlocked(),
 		}
 		pp.SetExtras(extras)
 	case ControllerTypePfsense:
@@ -381,7 +464,7 @@ func MergeToPhysicalPeer(pp *PhysicalPeer, p *Peer) {
 			Id:              "",
 			Name:            p.DisplayName,
 			Comment:         p.Notes,
-			Disabled:        p.IsDisabled(),
+			Disabled:        p.IsDisabled() || p.IsScheduleBlocked(),
 			ClientEndpoint:  p.Endpoint.GetValue(),
 			ClientAddress:   CidrsToString(p.Interface.Addresses),
 			ClientDns:       p.Interface.DnsStr.GetValue(),
@@ -394,6 +477,7 @@ func MergeToPhysicalPeer(pp *PhysicalPeer, p *Peer) {
 type PeerCreationRequest struct {
 	UserIdentifiers []string
 	Prefix          string
+	IpPool          string // optional ip pool of the interface to allocate the addresses from
 }
 
 // AfterFind is a GORM hook that automatically loads the associated User object
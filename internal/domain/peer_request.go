@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	PeerRequestStatePending  PeerRequestState = "pending"
+	PeerRequestStateApproved PeerRequestState = "approved"
+	PeerRequestStateRejected PeerRequestState = "rejected"
+)
+
+type PeerRequestIdentifier string
+
+type PeerRequestState string
+
+// PeerRequest is a request of a user for a new peer. The peer is created once an admin approves the request.
+type PeerRequest struct {
+	BaseModel
+
+	Identifier          PeerRequestIdentifier `gorm:"primaryKey;column:identifier"`
+	UserIdentifier      UserIdentifier        `gorm:"index;column:user_identifier"`      // the user that requested the peer
+	InterfaceIdentifier InterfaceIdentifier   `gorm:"index;column:interface_identifier"` // the interface of the requested peer
+	DisplayName         string                `gorm:"column:display_name"`               // the device name, used as display name of the new peer
+	Justification       string                `gorm:"column:justification"`              // why the user needs the peer
+
+	State          PeerRequestState `gorm:"index;column:state"`
+	DecidedBy      string           `gorm:"column:decided_by"`      // the admin that approved or rejected the request
+	DecidedAt      *time.Time       `gorm:"column:decided_at"`      // the time of the decision
+	DecisionReason string           `gorm:"column:decision_reason"` // optional comment of the admin, e.g. the reason for a rejection
+	PeerIdentifier PeerIdentifier   `gorm:"column:peer_identifier"` // the peer that has been created on approval
+}
+
+// IsPending returns true if no decision has been made for the request yet.
+func (r *PeerRequest) IsPending() bool {
+	return r.State == PeerRequestStatePending
+}
+
+// Validate checks the fields that are provided by the requesting user.
+func (r *PeerRequest) Validate() error {
+	if r.InterfaceIdentifier == "" {
+		return fmt.Errorf("interface is required: %w", ErrInvalidData)
+	}
+	if r.DisplayName == "" {
+		return fmt.Errorf("device name is required: %w", ErrInvalidData)
+	}
+	if len(r.DisplayName) > 64 {
+		return fmt.Errorf("device name must not exceed 64 characters: %w", ErrInvalidData)
+	}
+	if len(r.Justification) > 1024 {
+		return fmt.Errorf("justification must not exceed 1024 characters: %w", ErrInvalidData)
+	}
+
+	return nil
+}
+
+// Decide marks the request as approved or rejected by the given admin.
+func (r *PeerRequest) Decide(state PeerRequestState, admin UserIdentifier, reason string) {
+	now := time.Now()
+	r.State = state
+	r.DecidedBy = string(admin)
+	r.DecidedAt = &now
+	r.DecisionReason = reason
+}
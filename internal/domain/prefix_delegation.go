@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strings"
+)
+
+// maxDelegationIndexBits limits the number of prefixes of a delegation pool, so that prefix indexes fit into an uint64.
+const maxDelegationIndexBits = 32
+
+// PrefixDelegationPolicy defines the IPv6 pool from which every peer of an interface gets its own routed prefix.
+type PrefixDelegationPolicy struct {
+	Pool         string `json:"Pool"`         // IPv6 network that is split into the delegated prefixes, e.g. 2001:db8:100::/48
+	PrefixLength int    `json:"PrefixLength"` // length of the delegated prefixes, e.g. 56
+}
+
+// IsEmpty returns true if no delegation pool is set. An empty policy disables the delegation for new peers.
+func (p *PrefixDelegationPolicy) IsEmpty() bool {
+	return p == nil || p.Pool == ""
+}
+
+func (p *PrefixDelegationPolicy) Validate() error {
+	if p.IsEmpty() {
+		return nil
+	}
+
+	p.Pool = strings.TrimSpace(p.Pool)
+	pool, err := netip.ParsePrefix(p.Pool)
+	if err != nil || !pool.Addr().Is6() || pool.Addr().Is4In6() {
+		return fmt.Errorf("delegation pool %s is not an IPv6 network: %w", p.Pool, ErrInvalidData)
+	}
+	p.Pool = pool.Masked().String()
+
+	if p.PrefixLength <= pool.Bits() || p.PrefixLength > 128 {
+		return fmt.Errorf("prefix length must be between %d and 128: %w", pool.Bits()+1, ErrInvalidData)
+	}
+	if p.PrefixLength-pool.Bits() > maxDelegationIndexBits {
+		return fmt.Errorf("delegation pool %s contains more than 2^%d prefixes of length %d: %w", p.Pool,
+			maxDelegationIndexBits, p.PrefixLength, ErrInvalidData)
+	}
+
+	return nil
+}
+
+// PoolPrefix returns the parsed delegation pool. The policy must be valid.
+func (p *PrefixDelegationPolicy) PoolPrefix() netip.Prefix {
+	return netip.MustParsePrefix(p.Pool).Masked()
+}
+
+// Capacity returns the number of prefixes in the pool.
+func (p *PrefixDelegationPolicy) Capacity() uint64 {
+	return 1 << uint(p.PrefixLength-p.PoolPrefix().Bits())
+}
+
+// FirstPrefix returns the first prefix of the pool. Further prefixes are reached with Cidr.NextSubnet.
+func (p *PrefixDelegationPolicy) FirstPrefix() Cidr {
+	return CidrFromPrefix(netip.PrefixFrom(p.PoolPrefix().Addr(), p.PrefixLength))
+}
+
+// IsLastPrefix returns true if the prefix is the last one of the pool, so Cidr.NextSubnet would leave the pool.
+func (p *PrefixDelegationPolicy) IsLastPrefix(prefix Cidr) bool {
+	return prefix.BroadcastAddr().Addr == CidrFromPrefix(p.PoolPrefix()).BroadcastAddr().Addr
+}
+
+// IndexOf returns the position of the prefix within the pool. The index stays the same if the pool is renumbered.
+// The second return value is false if the prefix is not a prefix of this pool.
+func (p *PrefixDelegationPolicy) IndexOf(prefix Cidr) (uint64, bool) {
+	pool := p.PoolPrefix()
+	if prefix.NetLength != p.PrefixLength || !pool.Contains(prefix.Prefix().Addr()) ||
+		prefix.Prefix().Masked() != prefix.Prefix() {
+		return 0, false
+	}
+
+	base, addr := pool.Addr().As16(), prefix.Prefix().Addr().As16()
+	offset := new(big.Int).Sub(new(big.Int).SetBytes(addr[:]), new(big.Int).SetBytes(base[:]))
+
+	return offset.Rsh(offset, uint(128-p.PrefixLength)).Uint64(), true
+}
+
+// PrefixAt returns the prefix with the given index, or false if the pool is too small.
+func (p *PrefixDelegationPolicy) PrefixAt(index uint64) (Cidr, bool) {
+	if index >= p.Capacity() {
+		return Cidr{}, false
+	}
+
+	base := p.PoolPrefix().Addr().As16()
+	offset := new(big.Int).Lsh(new(big.Int).SetUint64(index), uint(128-p.PrefixLength))
+	sum := new(big.Int).Add(new(big.Int).SetBytes(base[:]), offset)
+
+	var a16 [16]byte
+	sum.FillBytes(a16[:])
+
+	return CidrFromPrefix(netip.PrefixFrom(netip.AddrFrom16(a16), p.PrefixLength)), true
+}
+
+// DelegatedPrefixAssignment is a delegated prefix together with the peer it belongs to.
+type DelegatedPrefixAssignment struct {
+	PeerIdentifier PeerIdentifier
+	DisplayName    string
+	Prefix         Cidr
+	Index          uint64 // position in the current pool, only valid if InPool is true
+	InPool         bool   // false if the prefix is not part of the current pool, e.g. after a manual change
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixDelegationPolicy_Validate(t *testing.T) {
+	var empty *PrefixDelegationPolicy
+	assert.NoError(t, empty.Validate())
+	assert.NoError(t, (&PrefixDelegationPolicy{}).Validate())
+
+	policy := &PrefixDelegationPolicy{Pool: " 2001:db8:100:1::/48", PrefixLength: 56}
+	require.NoError(t, policy.Validate())
+	assert.Equal(t, "2001:db8:100::/48", policy.Pool)
+
+	invalid := []PrefixDelegationPolicy{
+		{Pool: "10.0.0.0/8", PrefixLength: 16},
+		{Pool: "::ffff:10.0.0.0/104", PrefixLength: 112},
+		{Pool: "2001:db8::/48", PrefixLength: 48},
+		{Pool: "2001:db8::/48", PrefixLength: 129},
+		{Pool: "2001:db8::/32", PrefixLength: 128},
+		{Pool: "2001:db8::", PrefixLength: 56},
+	}
+	for _, p := range invalid {
+		assert.ErrorIs(t, p.Validate(), ErrInvalidData, p.Pool)
+	}
+}
+
+func TestPrefixDelegationPolicy_Prefixes(t *testing.T) {
+	policy := &PrefixDelegationPolicy{Pool: "2001:db8:100::/48", PrefixLength: 50}
+	require.NoError(t, policy.Validate())
+	assert.Equal(t, uint64(4), policy.Capacity())
+
+	var prefixes []string
+	for prefix := policy.FirstPrefix(); ; prefix = prefix.NextSubnet() {
+		prefixes = append(prefixes, prefix.String())
+		if policy.IsLastPrefix(prefix) {
+			break
+		}
+	}
+	assert.Equal(t, []string{
+		"2001:db8:100::/50", "2001:db8:100:4000::/50", "2001:db8:100:8000::/50", "2001:db8:100:c000::/50",
+	}, prefixes)
+
+	for i, str := range prefixes {
+		prefix, err := CidrFromString(str)
+		require.NoError(t, err)
+		index, ok := policy.IndexOf(prefix)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(i), index)
+
+		at, ok := policy.PrefixAt(uint64(i))
+		assert.True(t, ok)
+		assert.Equal(t, str, at.String())
+	}
+
+	_, ok := policy.PrefixAt(4)
+	assert.False(t, ok)
+	for _, str := range []string{"2001:db8:200::/50", "2001:db8:100::/56", "2001:db8:100::1/50"} {
+		prefix, err := CidrFromString(str)
+		require.NoError(t, err)
+		_, ok := policy.IndexOf(prefix)
+		assert.False(t, ok, str)
+	}
+}
+
+func TestInterface_GetAllowedIPs_DelegatedPrefix(t *testing.T) {
+	iface := &Interface{Type: InterfaceTypeServer}
+	addr, err := CidrFromString("10.0.0.2/32")
+	require.NoError(t, err)
+	peers := []Peer{{
+		Interface:       PeerInterfaceConfig{Addresses: []Cidr{addr}},
+		DelegatedPrefix: "2001:db8:100:100::/56",
+	}}
+
+	allowed := iface.GetAllowedIPs(peers)
+	assert.Equal(t, []string{"10.0.0.2/32", "2001:db8:100:100::/56"}, CidrsToStringSlice(allowed))
+}
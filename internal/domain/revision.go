@@ -0,0 +1,216 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RevisionObjectPeer      RevisionObjectType = "peer"
+	RevisionObjectInterface RevisionObjectType = "interface"
+)
+
+// hiddenRevisionFields are not shown in diffs, only the fact that they changed.
+var hiddenRevisionFields = []string{"PrivateKey", "PresharedKey"}
+
+type RevisionObjectType string
+
+// ConfigRevision is a snapshot of a peer or interface, stored each time the object is saved.
+type ConfigRevision struct {
+	Id         uint64             `gorm:"primaryKey;autoIncrement"`
+	ObjectType RevisionObjectType `gorm:"index:idx_revision_object;column:object_type"`
+	ObjectId   string             `gorm:"index:idx_revision_object;column:object_id"`
+	Version    uint64             `gorm:"column:version"` // increases with each revision of the object, starting at 1
+	CreatedAt  time.Time
+	CreatedBy  string
+	Snapshot   string `gorm:"serializer:encstr"` // the JSON encoded object, it contains private keys
+}
+
+// Peer decodes the snapshot of a peer revision.
+func (r *ConfigRevision) Peer() (*Peer, error) {
+	if r.ObjectType != RevisionObjectPeer {
+		return nil, fmt.Errorf("revision %d is not a peer revision: %w", r.Version, ErrInvalidData)
+	}
+
+	var peer Peer
+	if err := json.Unmarshal([]byte(r.Snapshot), &peer); err != nil {
+		return nil, fmt.Errorf("failed to decode peer revision %d: %w", r.Version, err)
+	}
+
+	return &peer, nil
+}
+
+// Interface decodes the snapshot of an interface revision.
+func (r *ConfigRevision) Interface() (*Interface, error) {
+	if r.ObjectType != RevisionObjectInterface {
+		return nil, fmt.Errorf("revision %d is not an interface revision: %w", r.Version, ErrInvalidData)
+	}
+
+	var iface Interface
+	if err := json.Unmarshal([]byte(r.Snapshot), &iface); err != nil {
+		return nil, fmt.Errorf("failed to decode interface revision %d: %w", r.Version, err)
+	}
+
+	return &iface, nil
+}
+
+// PeerSnapshot encodes the configuration of the peer for a revision. Runtime state that changes without a
+// configuration change, like the access schedule state, is left out, so that it does not create new revisions.
+func PeerSnapshot(peer *Peer) (string, error) {
+	p := *peer
+	p.UpdatedAt = time.Time{}
+	p.UpdatedBy = ""
+	p.User = nil
+	p.ScheduleBlocked = nil
+	p.InactivityWarned = nil
+	p.DeletedAt = gorm.DeletedAt{}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode peer %s: %w", peer.Identifier, err)
+	}
+
+	return string(data), nil
+}
+
+// InterfaceSnapshot encodes the configuration of the interface for a revision. The allowed LDAP users are left out,
+// they are materialised by the LDAP synchronization. Like for peers, the runtime state of the maintenance windows
+// is left out too.
+func InterfaceSnapshot(iface *Interface) (string, error) {
+	i := *iface
+	i.UpdatedAt = time.Time{}
+	i.UpdatedBy = ""
+	i.LdapAllowedUsers = nil
+	if i.MaintenanceSchedule != nil {
+		schedule := MaintenanceSchedule{Windows: make([]MaintenanceWindow, len(i.MaintenanceSchedule.Windows))}
+		for idx, w := range i.MaintenanceSchedule.Windows {
+			w.LastRun = nil
+			w.Notified = nil
+			schedule.Windows[idx] = w
+		}
+		i.MaintenanceSchedule = &schedule
+	}
+
+	data, err := json.Marshal(i)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode interface %s: %w", iface.Identifier, err)
+	}
+
+	return string(data), nil
+}
+
+// RevisionChange is a single field that differs between two revisions.
+type RevisionChange struct {
+	Field string // the path of the field, nested fields are separated by dots
+	From  string // the JSON encoded old value, empty if the field did not exist
+	To    string // the JSON encoded new value, empty if the field was removed
+}
+
+// DiffRevisions returns all fields that differ between the two revisions, sorted by field path.
+// Lists are compared as a whole. Private keys are hidden, only the fact that they changed is reported.
+func DiffRevisions(from, to *ConfigRevision) ([]RevisionChange, error) {
+	if from.ObjectType != to.ObjectType || from.ObjectId != to.ObjectId {
+		return nil, fmt.Errorf("revisions belong to different objects: %w", ErrInvalidData)
+	}
+
+	fromFields, err := flattenSnapshot(from.Snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode revision %d: %w", from.Version, err)
+	}
+	toFields, err := flattenSnapshot(to.Snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode revision %d: %w", to.Version, err)
+	}
+
+	return diffFields(fromFields, toFields), nil
+}
+
+// DiffSnapshots returns all fields that differ between the two JSON encoded objects, like DiffRevisions.
+func DiffSnapshots(from, to string) ([]RevisionChange, error) {
+	fromFields, err := flattenSnapshot(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	toFields, err := flattenSnapshot(to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	return diffFields(fromFields, toFields), nil
+}
+
+func diffFields(fromFields, toFields map[string]string) []RevisionChange {
+	var changes []RevisionChange
+	for field, fromValue := range fromFields {
+		if toValue, ok := toFields[field]; !ok || toValue != fromValue {
+			changes = append(changes, RevisionChange{Field: field, From: fromValue, To: toValue})
+		}
+	}
+	for field, toValue := range toFields {
+		if _, ok := fromFields[field]; !ok {
+			changes = append(changes, RevisionChange{Field: field, To: toValue})
+		}
+	}
+
+	for i, change := range changes {
+		name := change.Field[strings.LastIndex(change.Field, ".")+1:]
+		if slices.Contains(hiddenRevisionFields, name) {
+			changes[i].From = hideRevisionValue(change.From)
+			changes[i].To = hideRevisionValue(change.To)
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b RevisionChange) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+
+	return changes
+}
+
+// flattenSnapshot maps the path of each field of the JSON snapshot to its compact JSON value.
+func flattenSnapshot(snapshot string) (map[string]string, error) {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(snapshot), &root); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string)
+	var walk func(prefix string, obj map[string]json.RawMessage) error
+	walk = func(prefix string, obj map[string]json.RawMessage) error {
+		for key, raw := range obj {
+			var nested map[string]json.RawMessage
+			if json.Unmarshal(raw, &nested) == nil && nested != nil {
+				if err := walk(prefix+key+".", nested); err != nil {
+					return err
+				}
+				continue
+			}
+
+			var value bytes.Buffer
+			if err := json.Compact(&value, raw); err != nil {
+				return err
+			}
+			fields[prefix+key] = value.String()
+		}
+		return nil
+	}
+
+	if err := walk("", root); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func hideRevisionValue(value string) string {
+	if value == "" || value == `""` {
+		return value
+	}
+	return `"(hidden)"`
+}
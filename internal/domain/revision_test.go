@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerSnapshot_IgnoresRuntimeState(t *testing.T) {
+	peer := &Peer{Identifier: "peer1", DisplayName: "Peer 1"}
+	first, err := PeerSnapshot(peer)
+	require.NoError(t, err)
+
+	now := time.Now()
+	peer.UpdatedAt = now
+	peer.UpdatedBy = "admin"
+	peer.ScheduleBlocked = &now
+	second, err := PeerSnapshot(peer)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, "admin", peer.UpdatedBy, "the peer itself is not modified")
+}
+
+func TestInterfaceSnapshot_IgnoresMaintenanceState(t *testing.T) {
+	start := time.Date(2024, 1, 7, 2, 0, 0, 0, time.UTC)
+	iface := &Interface{Identifier: "wg0", MaintenanceSchedule: &MaintenanceSchedule{Windows: []MaintenanceWindow{
+		{Action: MaintenanceActionDisable, Start: start, Recurrence: MaintenanceRecurrenceWeekly},
+	}}}
+	first, err := InterfaceSnapshot(iface)
+	require.NoError(t, err)
+
+	now := time.Now()
+	iface.MaintenanceSchedule.Windows[0].LastRun = &now
+	iface.MaintenanceSchedule.Windows[0].Notified = &start
+	second, err := InterfaceSnapshot(iface)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, &now, iface.MaintenanceSchedule.Windows[0].LastRun, "the interface itself is not modified")
+
+	iface.MaintenanceSchedule.Windows[0].Description = "updates"
+	third, err := InterfaceSnapshot(iface)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, third)
+}
+
+func TestDiffRevisions(t *testing.T) {
+	snapshot := func(peer *Peer) string {
+		s, err := PeerSnapshot(peer)
+		require.NoError(t, err)
+		return s
+	}
+	from := &ConfigRevision{ObjectType: RevisionObjectPeer, ObjectId: "peer1", Version: 1, Snapshot: snapshot(&Peer{
+		Identifier:  "peer1",
+		DisplayName: "old",
+		Interface:   PeerInterfaceConfig{KeyPair: KeyPair{PrivateKey: "secret1"}, Mtu: NewConfigOption(1420, true)},
+	})}
+	to := &ConfigRevision{ObjectType: RevisionObjectPeer, ObjectId: "peer1", Version: 2, Snapshot: snapshot(&Peer{
+		Identifier:  "peer1",
+		DisplayName: "new",
+		Interface:   PeerInterfaceConfig{KeyPair: KeyPair{PrivateKey: "secret2"}, Mtu: NewConfigOption(1420, true)},
+	})}
+
+	changes, err := DiffRevisions(from, to)
+	require.NoError(t, err)
+	assert.Equal(t, []RevisionChange{
+		{Field: "DisplayName", From: `"old"`, To: `"new"`},
+		{Field: "Interface.PrivateKey", From: `"(hidden)"`, To: `"(hidden)"`},
+	}, changes)
+
+	changes, err = DiffRevisions(from, from)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	other := *to
+	other.ObjectId = "peer2"
+	_, err = DiffRevisions(from, &other)
+	assert.ErrorIs(t, err, ErrInvalidData)
+}
@@ -36,15 +36,16 @@ func LogSanitizeChange(
 }
 
 var reservedUserIdentifiers = map[string]struct{}{
-	"all":               {},
-	"new":               {},
-	"id":                {},
-	CtxSystemAdminId:    {},
-	CtxUnknownUserId:    {},
-	CtxSystemLdapSyncer: {},
-	CtxSystemWgImporter: {},
-	CtxSystemV1Migrator: {},
-	CtxSystemDBMigrator: {},
+	"all":                {},
+	"new":                {},
+	"id":                 {},
+	CtxSystemAdminId:     {},
+	CtxUnknownUserId:     {},
+	CtxSystemLdapSyncer:  {},
+	CtxSystemWgImporter:  {},
+	CtxSystemV1Migrator:  {},
+	CtxSystemDBMigrator:  {},
+	CtxSystemDeclarative: {},
 }
 
 // SanitizeString normalizes to NFC, trims leading and trailing whitespace, strips Unicode
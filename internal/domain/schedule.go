@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const accessWindowTimeLayout = "15:04"
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// AccessWindow is a single time range in which a connection is allowed.
+// If End is before Start, the window spans midnight and ends on the following day.
+type AccessWindow struct {
+	Weekdays []string `json:"Weekdays"` // lowercase three-letter weekday names (mon, tue, ...), empty means every day
+	Start    string   `json:"Start"`    // start time in 24h format, for example 08:00
+	End      string   `json:"End"`      // end time in 24h format, for example 17:30
+}
+
+// AccessSchedule restricts the time in which a peer is allowed to connect.
+// A schedule without windows does not restrict anything.
+type AccessSchedule struct {
+	TimeZone string         `json:"TimeZone"` // IANA time zone name, empty means UTC
+	Windows  []AccessWindow `json:"Windows"`
+}
+
+// IsEmpty returns true if the schedule does not contain any windows.
+func (s *AccessSchedule) IsEmpty() bool {
+	return s == nil || len(s.Windows) == 0
+}
+
+// Validate checks the time zone and all windows of the schedule.
+func (s *AccessSchedule) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	if _, err := time.LoadLocation(s.TimeZone); err != nil {
+		return fmt.Errorf("invalid time zone %q: %w", s.TimeZone, ErrInvalidData)
+	}
+
+	for i, w := range s.Windows {
+		for _, day := range w.Weekdays {
+			if _, ok := weekdayNames[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("window %d: invalid weekday %q: %w", i, day, ErrInvalidData)
+			}
+		}
+		start, err := time.Parse(accessWindowTimeLayout, w.Start)
+		if err != nil {
+			return fmt.Errorf("window %d: invalid start time %q: %w", i, w.Start, ErrInvalidData)
+		}
+		end, err := time.Parse(accessWindowTimeLayout, w.End)
+		if err != nil {
+			return fmt.Errorf("window %d: invalid end time %q: %w", i, w.End, ErrInvalidData)
+		}
+		if start.Equal(end) {
+			return fmt.Errorf("window %d: start and end time must differ: %w", i, ErrInvalidData)
+		}
+	}
+
+	return nil
+}
+
+// IsAllowed returns true if the given point in time lies within one of the schedule windows.
+// An empty or invalid schedule always allows access, invalid schedules are rejected on save.
+func (s *AccessSchedule) IsAllowed(t time.Time) bool {
+	if s.IsEmpty() {
+		return true
+	}
+
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return true
+	}
+	t = t.In(loc)
+	minuteOfDay := t.Hour()*60 + t.Minute()
+
+	for _, w := range s.Windows {
+		start, errStart := time.Parse(accessWindowTimeLayout, w.Start)
+		end, errEnd := time.Parse(accessWindowTimeLayout, w.End)
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+		startMinute := start.Hour()*60 + start.Minute()
+		endMinute := end.Hour()*60 + end.Minute()
+
+		if startMinute < endMinute {
+			if w.appliesTo(t.Weekday()) && minuteOfDay >= startMinute && minuteOfDay < endMinute {
+				return true
+			}
+			continue
+		}
+
+		// overnight window: the part before midnight belongs to the start day, the rest to the day before
+		if w.appliesTo(t.Weekday()) && minuteOfDay >= startMinute {
+			return true
+		}
+		if w.appliesTo((t.Weekday()+6)%7) && minuteOfDay < endMinute {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w AccessWindow) appliesTo(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, name := range w.Weekdays {
+		if d, ok := weekdayNames[strings.ToLower(name)]; ok && d == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessSchedule_Validate(t *testing.T) {
+	var schedule *AccessSchedule
+	assert.NoError(t, schedule.Validate())
+
+	valid := &AccessSchedule{
+		TimeZone: "Europe/Vienna",
+		Windows:  []AccessWindow{{Weekdays: []string{"mon", "Fri"}, Start: "22:00", End: "06:00"}},
+	}
+	assert.NoError(t, valid.Validate())
+
+	tests := []AccessSchedule{
+		{TimeZone: "Mars/Olympus", Windows: []AccessWindow{{Start: "08:00", End: "17:00"}}},
+		{Windows: []AccessWindow{{Weekdays: []string{"monday"}, Start: "08:00", End: "17:00"}}},
+		{Windows: []AccessWindow{{Start: "8", End: "17:00"}}},
+		{Windows: []AccessWindow{{Start: "08:00", End: "25:00"}}},
+		{Windows: []AccessWindow{{Start: "08:00", End: "08:00"}}},
+	}
+	for _, tt := range tests {
+		assert.ErrorIs(t, tt.Validate(), ErrInvalidData, "schedule %+v", tt)
+	}
+}
+
+func TestAccessSchedule_IsAllowed(t *testing.T) {
+	var empty *AccessSchedule
+	assert.True(t, empty.IsAllowed(time.Now()))
+
+	officeHours := &AccessSchedule{
+		Windows: []AccessWindow{{Weekdays: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "17:00"}},
+	}
+	// 2024-01-01 is a Monday
+	assert.True(t, officeHours.IsAllowed(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)))
+	assert.True(t, officeHours.IsAllowed(time.Date(2024, 1, 1, 16, 59, 0, 0, time.UTC)))
+	assert.False(t, officeHours.IsAllowed(time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)))
+	assert.False(t, officeHours.IsAllowed(time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC)))
+}
+
+func TestAccessSchedule_IsAllowed_Overnight(t *testing.T) {
+	nightShift := &AccessSchedule{
+		Windows: []AccessWindow{{Weekdays: []string{"fri"}, Start: "22:00", End: "06:00"}},
+	}
+	// 2024-01-05 is a Friday
+	assert.True(t, nightShift.IsAllowed(time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC)))
+	assert.True(t, nightShift.IsAllowed(time.Date(2024, 1, 6, 5, 59, 0, 0, time.UTC)))
+	assert.False(t, nightShift.IsAllowed(time.Date(2024, 1, 6, 23, 0, 0, 0, time.UTC)))
+	assert.False(t, nightShift.IsAllowed(time.Date(2024, 1, 5, 5, 0, 0, 0, time.UTC)))
+}
+
+func TestAccessSchedule_IsAllowed_TimeZone(t *testing.T) {
+	schedule := &AccessSchedule{
+		TimeZone: "America/New_York",
+		Windows:  []AccessWindow{{Start: "09:00", End: "10:00"}},
+	}
+	// 14:30 UTC is 09:30 in New York during winter time
+	assert.True(t, schedule.IsAllowed(time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC)))
+	assert.False(t, schedule.IsAllowed(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)))
+}
+
+func TestPeer_UpdateScheduleState(t *testing.T) {
+	owner := &User{
+		AccessSchedule: &AccessSchedule{Windows: []AccessWindow{{Start: "08:00", End: "17:00"}}},
+	}
+	peer := &Peer{}
+	schedule := peer.EffectiveAccessSchedule(owner)
+	assert.Equal(t, owner.AccessSchedule, schedule)
+
+	night := time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)
+	assert.True(t, peer.UpdateScheduleState(schedule, night))
+	assert.True(t, peer.IsScheduleBlocked())
+	assert.False(t, peer.IsDisabled(), "schedule must not change the disabled state")
+	assert.False(t, peer.UpdateScheduleState(schedule, night.Add(time.Hour)))
+
+	noon := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	assert.True(t, peer.UpdateScheduleState(schedule, noon))
+	assert.False(t, peer.IsScheduleBlocked())
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 const (
@@ -48,6 +49,8 @@ type User struct {
 	Authentications []UserAuthentication `gorm:"foreignKey:user_identifier"`
 	// synchronization behavior
 	PersistLocalChanges bool `gorm:"column:persist_local_changes"`
+	// set if the user is defined by the declarative state file
+	ManagedBy string `gorm:"column:managed_by"`
 
 	// optional fields
 	Firstname  string `form:"firstname" binding:"omitempty"`
@@ -63,6 +66,12 @@ type User struct {
 	Locked         *time.Time    `gorm:"index;column:locked"` // if this field is set, the user is locked and can no longer login (WireGuard peers still can connect)
 	LockedReason   string        // the reason why the user has been locked
 
+	// AccessSchedule is applied to all peers of the user that do not define their own schedule
+	AccessSchedule *AccessSchedule `gorm:"serializer:json"`
+
+	// Acl contains destination rules for all peers of the user, evaluated after the rules of the peer itself
+	Acl *AccessControlList `gorm:"serializer:json"`
+
 	// Passwordless authentication
 	WebAuthnId             string                   `gorm:"column:webauthn_id"`         // the webauthn id of the user, used for webauthn authentication
 	WebAuthnCredentialList []UserWebauthnCredential `gorm:"foreignKey:user_identifier"` // the webauthn credentials of the user, used for webauthn authentication
@@ -71,6 +80,9 @@ type User struct {
 	ApiToken        string `form:"api_token" binding:"omitempty" gorm:"serializer:encstr"`
 	ApiTokenCreated *time.Time
 
+	// set while the user is in the recycle bin, deleted users are hidden from all regular queries
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
 	LinkedPeerCount int `gorm:"-"`
 }
 
@@ -80,6 +92,11 @@ func (u *User) IsDisabled() bool {
 	return u.Disabled != nil
 }
 
+// IsDeleted returns true if the user is in the recycle bin.
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt.Valid
+}
+
 // IsLocked returns true if the user is locked. In such a case, no login is possible, WireGuard connections still work.
 func (u *User) IsLocked() bool {
 	return u.Locked != nil
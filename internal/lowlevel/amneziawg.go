@@ -0,0 +1,445 @@
+package lowlevel
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/mdlayher/genetlink"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	vnl "github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// AmneziaWgLinkType is the link type of devices that are created by the AmneziaWG kernel module.
+const AmneziaWgLinkType = "amneziawg"
+
+// The AmneziaWG kernel module uses the WireGuard netlink API with a different family name
+// and additional device attributes for the obfuscation parameters.
+const (
+	awgFamilyName = "amneziawg"
+	awgVersion    = 1
+
+	awgCmdGetDevice = 0
+	awgCmdSetDevice = 1
+
+	awgDeviceIfname     = 2
+	awgDevicePrivateKey = 3
+	awgDevicePublicKey  = 4
+	awgDeviceFlags      = 5
+	awgDeviceListenPort = 6
+	awgDeviceFwmark     = 7
+	awgDevicePeers      = 8
+	awgDeviceJc         = 9
+	awgDeviceJmin       = 10
+	awgDeviceJmax       = 11
+	awgDeviceS1         = 12
+	awgDeviceS2         = 13
+	awgDeviceH1         = 14
+	awgDeviceH2         = 15
+	awgDeviceH3         = 16
+	awgDeviceH4         = 17
+
+	awgDeviceFlagReplacePeers = 1
+
+	awgPeerPublicKey         = 1
+	awgPeerPresharedKey      = 2
+	awgPeerFlags             = 3
+	awgPeerEndpoint          = 4
+	awgPeerKeepalive         = 5
+	awgPeerLastHandshakeTime = 6
+	awgPeerRxBytes           = 7
+	awgPeerTxBytes           = 8
+	awgPeerAllowedIPs        = 9
+	awgPeerProtocolVersion   = 10
+
+	awgPeerFlagRemove            = 1
+	awgPeerFlagReplaceAllowedIPs = 2
+	awgPeerFlagUpdateOnly        = 4
+
+	awgAllowedIPFamily   = 1
+	awgAllowedIPAddr     = 2
+	awgAllowedIPCidrMask = 3
+)
+
+// AmneziaWgConfig contains the obfuscation parameters of an AmneziaWG device.
+type AmneziaWgConfig struct {
+	JunkPacketCount            uint16
+	JunkPacketMinSize          uint16
+	JunkPacketMaxSize          uint16
+	InitPacketJunkSize         uint16
+	ResponsePacketJunkSize     uint16
+	InitPacketMagicHeader      uint32
+	ResponsePacketMagicHeader  uint32
+	UnderloadPacketMagicHeader uint32
+	TransportPacketMagicHeader uint32
+}
+
+// AmneziaWgClient controls AmneziaWG kernel devices. It implements the same methods as the wgctrl client,
+// so it can be used as a drop-in replacement, and additionally handles the obfuscation parameters.
+type AmneziaWgClient struct {
+	conn   *genetlink.Conn
+	family genetlink.Family
+}
+
+// NewAmneziaWgClient connects to the netlink family of the AmneziaWG kernel module.
+func NewAmneziaWgClient() (*AmneziaWgClient, error) {
+	conn, err := genetlink.Dial(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial generic netlink: %w", err)
+	}
+
+	family, err := conn.GetFamily(awgFamilyName)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to find netlink family %s, is the kernel module loaded?: %w", awgFamilyName, err)
+	}
+
+	return &AmneziaWgClient{conn: conn, family: family}, nil
+}
+
+func (c *AmneziaWgClient) Close() error {
+	return c.conn.Close()
+}
+
+// Devices returns all AmneziaWG devices of the host.
+func (c *AmneziaWgClient) Devices() ([]*wgtypes.Device, error) {
+	links, err := vnl.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+
+	var devices []*wgtypes.Device
+	for _, link := range links {
+		if link.Type() != AmneziaWgLinkType {
+			continue
+		}
+
+		device, err := c.Device(link.Attrs().Name)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
+
+// Device returns the device with the given name. If the device does not exist, os.ErrNotExist is returned.
+func (c *AmneziaWgClient) Device(name string) (*wgtypes.Device, error) {
+	device, _, err := c.getDevice(name)
+	return device, err
+}
+
+// Obfuscation returns the obfuscation parameters of the device with the given name.
+func (c *AmneziaWgClient) Obfuscation(name string) (AmneziaWgConfig, error) {
+	_, obfuscation, err := c.getDevice(name)
+	return obfuscation, err
+}
+
+// ConfigureDevice applies the given configuration to the device with the given name.
+func (c *AmneziaWgClient) ConfigureDevice(name string, cfg wgtypes.Config) error {
+	ae := netlink.NewAttributeEncoder()
+	ae.String(awgDeviceIfname, name)
+	if cfg.PrivateKey != nil {
+		ae.Bytes(awgDevicePrivateKey, cfg.PrivateKey[:])
+	}
+	if cfg.ListenPort != nil {
+		ae.Uint16(awgDeviceListenPort, uint16(*cfg.ListenPort))
+	}
+	if cfg.FirewallMark != nil {
+		ae.Uint32(awgDeviceFwmark, uint32(*cfg.FirewallMark))
+	}
+	if cfg.ReplacePeers {
+		ae.Uint32(awgDeviceFlags, awgDeviceFlagReplacePeers)
+	}
+	if len(cfg.Peers) > 0 {
+		ae.Nested(awgDevicePeers, func(nae *netlink.AttributeEncoder) error {
+			for i := range cfg.Peers {
+				nae.Nested(uint16(i), func(pae *netlink.AttributeEncoder) error {
+					encodePeerConfig(pae, cfg.Peers[i])
+					return nil
+				})
+			}
+			return nil
+		})
+	}
+
+	return c.setDevice(name, ae)
+}
+
+// ConfigureObfuscation applies the given obfuscation parameters to the device with the given name.
+func (c *AmneziaWgClient) ConfigureObfuscation(name string, cfg AmneziaWgConfig) error {
+	ae := netlink.NewAttributeEncoder()
+	ae.String(awgDeviceIfname, name)
+	ae.Uint16(awgDeviceJc, cfg.JunkPacketCount)
+	ae.Uint16(awgDeviceJmin, cfg.JunkPacketMinSize)
+	ae.Uint16(awgDeviceJmax, cfg.JunkPacketMaxSize)
+	ae.Uint16(awgDeviceS1, cfg.InitPacketJunkSize)
+	ae.Uint16(awgDeviceS2, cfg.ResponsePacketJunkSize)
+	ae.Uint32(awgDeviceH1, cfg.InitPacketMagicHeader)
+	ae.Uint32(awgDeviceH2, cfg.ResponsePacketMagicHeader)
+	ae.Uint32(awgDeviceH3, cfg.UnderloadPacketMagicHeader)
+	ae.Uint32(awgDeviceH4, cfg.TransportPacketMagicHeader)
+
+	return c.setDevice(name, ae)
+}
+
+func (c *AmneziaWgClient) setDevice(name string, ae *netlink.AttributeEncoder) error {
+	data, err := ae.Encode()
+	if err != nil {
+		return fmt.Errorf("failed to encode device %s: %w", name, err)
+	}
+
+	_, err = c.conn.Execute(genetlink.Message{
+		Header: genetlink.Header{Command: awgCmdSetDevice, Version: awgVersion},
+		Data:   data,
+	}, c.family.ID, netlink.Request|netlink.Acknowledge)
+	if err != nil {
+		return fmt.Errorf("failed to configure device %s: %w", name, mapDeviceError(err))
+	}
+
+	return nil
+}
+
+func (c *AmneziaWgClient) getDevice(name string) (*wgtypes.Device, AmneziaWgConfig, error) {
+	ae := netlink.NewAttributeEncoder()
+	ae.String(awgDeviceIfname, name)
+	data, err := ae.Encode()
+	if err != nil {
+		return nil, AmneziaWgConfig{}, fmt.Errorf("failed to encode device %s: %w", name, err)
+	}
+
+	msgs, err := c.conn.Execute(genetlink.Message{
+		Header: genetlink.Header{Command: awgCmdGetDevice, Version: awgVersion},
+		Data:   data,
+	}, c.family.ID, netlink.Request|netlink.Dump)
+	if err != nil {
+		return nil, AmneziaWgConfig{}, fmt.Errorf("failed to get device %s: %w", name, mapDeviceError(err))
+	}
+
+	device := &wgtypes.Device{Name: name, Type: wgtypes.LinuxKernel}
+	var obfuscation AmneziaWgConfig
+	for _, msg := range msgs {
+		if err := decodeDevice(msg.Data, device, &obfuscation); err != nil {
+			return nil, AmneziaWgConfig{}, fmt.Errorf("failed to decode device %s: %w", name, err)
+		}
+	}
+
+	return device, obfuscation, nil
+}
+
+// mapDeviceError returns os.ErrNotExist for missing devices, like the wgctrl client.
+func mapDeviceError(err error) error {
+	if errors.Is(err, unix.ENODEV) || errors.Is(err, unix.ENOTSUP) {
+		return os.ErrNotExist
+	}
+	return err
+}
+
+func encodePeerConfig(ae *netlink.AttributeEncoder, peer wgtypes.PeerConfig) {
+	ae.Bytes(awgPeerPublicKey, peer.PublicKey[:])
+
+	var flags uint32
+	if peer.Remove {
+		flags |= awgPeerFlagRemove
+	}
+	if peer.UpdateOnly {
+		flags |= awgPeerFlagUpdateOnly
+	}
+	if peer.ReplaceAllowedIPs {
+		flags |= awgPeerFlagReplaceAllowedIPs
+	}
+	if flags != 0 {
+		ae.Uint32(awgPeerFlags, flags)
+	}
+	if peer.Remove {
+		return
+	}
+
+	if peer.PresharedKey != nil {
+		ae.Bytes(awgPeerPresharedKey, peer.PresharedKey[:])
+	}
+	if peer.Endpoint != nil {
+		ae.Bytes(awgPeerEndpoint, encodeSockaddr(peer.Endpoint))
+	}
+	if peer.PersistentKeepaliveInterval != nil {
+		ae.Uint16(awgPeerKeepalive, uint16(peer.PersistentKeepaliveInterval.Seconds()))
+	}
+	if len(peer.AllowedIPs) > 0 {
+		ae.Nested(awgPeerAllowedIPs, func(nae *netlink.AttributeEncoder) error {
+			for i, ipNet := range peer.AllowedIPs {
+				nae.Nested(uint16(i), func(iae *netlink.AttributeEncoder) error {
+					family, addr := uint16(unix.AF_INET6), ipNet.IP.To16()
+					if ip4 := ipNet.IP.To4(); ip4 != nil {
+						family, addr = unix.AF_INET, ip4
+					}
+					ones, _ := ipNet.Mask.Size()
+
+					iae.Uint16(awgAllowedIPFamily, family)
+					iae.Bytes(awgAllowedIPAddr, addr)
+					iae.Uint8(awgAllowedIPCidrMask, uint8(ones))
+					return nil
+				})
+			}
+			return nil
+		})
+	}
+}
+
+// decodeDevice parses a device message. Large devices are split over multiple messages, in this case the
+// first peer of a message may continue the last peer of the previous message.
+func decodeDevice(data []byte, device *wgtypes.Device, obfuscation *AmneziaWgConfig) error {
+	ad, err := netlink.NewAttributeDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case awgDevicePrivateKey:
+			copy(device.PrivateKey[:], ad.Bytes())
+		case awgDevicePublicKey:
+			copy(device.PublicKey[:], ad.Bytes())
+		case awgDeviceListenPort:
+			device.ListenPort = int(ad.Uint16())
+		case awgDeviceFwmark:
+			device.FirewallMark = int(ad.Uint32())
+		case awgDeviceJc:
+			obfuscation.JunkPacketCount = ad.Uint16()
+		case awgDeviceJmin:
+			obfuscation.JunkPacketMinSize = ad.Uint16()
+		case awgDeviceJmax:
+			obfuscation.JunkPacketMaxSize = ad.Uint16()
+		case awgDeviceS1:
+			obfuscation.InitPacketJunkSize = ad.Uint16()
+		case awgDeviceS2:
+			obfuscation.ResponsePacketJunkSize = ad.Uint16()
+		case awgDeviceH1:
+			obfuscation.InitPacketMagicHeader = ad.Uint32()
+		case awgDeviceH2:
+			obfuscation.ResponsePacketMagicHeader = ad.Uint32()
+		case awgDeviceH3:
+			obfuscation.UnderloadPacketMagicHeader = ad.Uint32()
+		case awgDeviceH4:
+			obfuscation.TransportPacketMagicHeader = ad.Uint32()
+		case awgDevicePeers:
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				for nad.Next() {
+					var peer wgtypes.Peer
+					nad.Nested(func(pad *netlink.AttributeDecoder) error {
+						return decodePeer(pad, &peer)
+					})
+
+					last := len(device.Peers) - 1
+					if last >= 0 && device.Peers[last].PublicKey == peer.PublicKey {
+						device.Peers[last].AllowedIPs = append(device.Peers[last].AllowedIPs, peer.AllowedIPs...)
+						continue
+					}
+					device.Peers = append(device.Peers, peer)
+				}
+				return nad.Err()
+			})
+		}
+	}
+
+	return ad.Err()
+}
+
+func decodePeer(ad *netlink.AttributeDecoder, peer *wgtypes.Peer) error {
+	for ad.Next() {
+		switch ad.Type() {
+		case awgPeerPublicKey:
+			copy(peer.PublicKey[:], ad.Bytes())
+		case awgPeerPresharedKey:
+			copy(peer.PresharedKey[:], ad.Bytes())
+		case awgPeerEndpoint:
+			peer.Endpoint = decodeSockaddr(ad.Bytes())
+		case awgPeerKeepalive:
+			peer.PersistentKeepaliveInterval = time.Duration(ad.Uint16()) * time.Second
+		case awgPeerLastHandshakeTime:
+			if b := ad.Bytes(); len(b) == 16 {
+				sec := int64(nlenc.Uint64(b[:8]))
+				nsec := int64(nlenc.Uint64(b[8:]))
+				if sec != 0 || nsec != 0 {
+					peer.LastHandshakeTime = time.Unix(sec, nsec)
+				}
+			}
+		case awgPeerRxBytes:
+			peer.ReceiveBytes = int64(ad.Uint64())
+		case awgPeerTxBytes:
+			peer.TransmitBytes = int64(ad.Uint64())
+		case awgPeerProtocolVersion:
+			peer.ProtocolVersion = int(ad.Uint32())
+		case awgPeerAllowedIPs:
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				for nad.Next() {
+					nad.Nested(func(iad *netlink.AttributeDecoder) error {
+						var ip net.IP
+						var ones int
+						for iad.Next() {
+							switch iad.Type() {
+							case awgAllowedIPAddr:
+								ip = iad.Bytes()
+							case awgAllowedIPCidrMask:
+								ones = int(iad.Uint8())
+							}
+						}
+						if ip != nil {
+							peer.AllowedIPs = append(peer.AllowedIPs,
+								net.IPNet{IP: ip, Mask: net.CIDRMask(ones, len(ip)*8)})
+						}
+						return iad.Err()
+					})
+				}
+				return nad.Err()
+			})
+		}
+	}
+
+	return ad.Err()
+}
+
+// encodeSockaddr returns the endpoint as struct sockaddr_in or sockaddr_in6.
+func encodeSockaddr(addr *net.UDPAddr) []byte {
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		b := make([]byte, unix.SizeofSockaddrInet4)
+		nlenc.PutUint16(b[0:2], unix.AF_INET)
+		binary.BigEndian.PutUint16(b[2:4], uint16(addr.Port))
+		copy(b[4:8], ip4)
+		return b
+	}
+
+	b := make([]byte, unix.SizeofSockaddrInet6)
+	nlenc.PutUint16(b[0:2], unix.AF_INET6)
+	binary.BigEndian.PutUint16(b[2:4], uint16(addr.Port))
+	copy(b[8:24], addr.IP.To16())
+	return b
+}
+
+func decodeSockaddr(b []byte) *net.UDPAddr {
+	if len(b) < 4 {
+		return nil
+	}
+
+	port := int(binary.BigEndian.Uint16(b[2:4]))
+	switch nlenc.Uint16(b[0:2]) {
+	case unix.AF_INET:
+		if len(b) < 8 {
+			return nil
+		}
+		return &net.UDPAddr{IP: net.IP(append([]byte(nil), b[4:8]...)), Port: port}
+	case unix.AF_INET6:
+		if len(b) < 24 {
+			return nil
+		}
+		return &net.UDPAddr{IP: net.IP(append([]byte(nil), b[8:24]...)), Port: port}
+	default:
+		return nil
+	}
+}
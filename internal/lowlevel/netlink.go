@@ -24,6 +24,14 @@ type NetlinkClient interface {
 	RuleAdd(rule *netlink.Rule) error
 	RuleDel(rule *netlink.Rule) error
 	RuleList(family int) ([]netlink.Rule, error)
+	QdiscList(link netlink.Link) ([]netlink.Qdisc, error)
+	QdiscReplace(qdisc netlink.Qdisc) error
+	QdiscDel(qdisc netlink.Qdisc) error
+	ClassReplace(class netlink.Class) error
+	ClassDel(class netlink.Class) error
+	FilterAdd(filter netlink.Filter) error
+	FilterDel(filter netlink.Filter) error
+	FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error)
 }
 
 type NetlinkManager struct {
@@ -106,3 +114,35 @@ func (n NetlinkManager) RuleDel(rule *netlink.Rule) error {
 func (n NetlinkManager) RuleList(family int) ([]netlink.Rule, error) {
 	return netlink.RuleList(family)
 }
+
+func (n NetlinkManager) QdiscList(link netlink.Link) ([]netlink.Qdisc, error) {
+	return netlink.QdiscList(link)
+}
+
+func (n NetlinkManager) QdiscReplace(qdisc netlink.Qdisc) error {
+	return netlink.QdiscReplace(qdisc)
+}
+
+func (n NetlinkManager) QdiscDel(qdisc netlink.Qdisc) error {
+	return netlink.QdiscDel(qdisc)
+}
+
+func (n NetlinkManager) ClassReplace(class netlink.Class) error {
+	return netlink.ClassReplace(class)
+}
+
+func (n NetlinkManager) ClassDel(class netlink.Class) error {
+	return netlink.ClassDel(class)
+}
+
+func (n NetlinkManager) FilterAdd(filter netlink.Filter) error {
+	return netlink.FilterAdd(filter)
+}
+
+func (n NetlinkManager) FilterDel(filter netlink.Filter) error {
+	return netlink.FilterDel(filter)
+}
+
+func (n NetlinkManager) FilterList(link netlink.Link, parent uint32) ([]netlink.Filter, error) {
+	return netlink.FilterList(link, parent)
+}